@@ -0,0 +1,229 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/Azure/azure-storage-fuse/v2/component/file_cache"
+	"github.com/Azure/azure-storage-fuse/v2/component/xload"
+
+	"github.com/spf13/cobra"
+)
+
+type cacheWarmOptions struct {
+	MountPath   string
+	Manifest    string
+	Parallelism uint32
+}
+
+var warmOpts cacheWarmOptions
+
+const defaultWarmParallelism uint32 = 8
+const warmReadBufferSize int = 1024 * 1024
+
+// warmEntry : A file under the mount path to be warmed up
+type warmEntry struct {
+	path string
+	size int64
+}
+
+var cacheWarmCmd = &cobra.Command{
+	Use:               "warm",
+	Short:             "Download the files listed in a manifest into the cache of a mounted container",
+	Long:              "Download the files listed in a manifest into the cache of a mounted container.\nEach line of the manifest is a path or glob pattern relative to the mount path, a directory covers everything under it.",
+	SuggestFor:        []string{"wrm", "warmup"},
+	Example:           "blobfuse2 cache warm --mount=/mnt/blobfuse --manifest=files.txt --parallelism=16",
+	FlagErrorHandling: cobra.ExitOnError,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := validateCacheWarmOptions()
+		if err != nil {
+			return fmt.Errorf("failed to validate options [%s]", err.Error())
+		}
+
+		patterns, err := file_cache.ReadWarmManifest(warmOpts.Manifest)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest [%s]", err.Error())
+		}
+
+		entries := expandWarmManifest(cmd.OutOrStdout(), warmOpts.MountPath, patterns)
+		return warmCache(cmd.OutOrStdout(), warmOpts.MountPath, entries, warmOpts.Parallelism)
+	},
+}
+
+func validateCacheWarmOptions() error {
+	if warmOpts.MountPath == "" {
+		return errors.New("mount path not provided, check usage")
+	}
+
+	info, err := os.Stat(warmOpts.MountPath)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("mount path %s is not a directory", warmOpts.MountPath)
+	}
+
+	if warmOpts.Manifest == "" {
+		return errors.New("manifest not provided, check usage")
+	}
+
+	if warmOpts.Parallelism == 0 {
+		return errors.New("parallelism shall be greater than 0")
+	}
+
+	return nil
+}
+
+// expandWarmManifest : Resolve the manifest entries to the list of files under the mount path
+func expandWarmManifest(out io.Writer, mountPath string, patterns []string) []warmEntry {
+	seen := make(map[string]bool)
+	entries := make([]warmEntry, 0)
+
+	for _, pattern := range patterns {
+		pattern = strings.Trim(filepath.ToSlash(filepath.Clean(pattern)), "/")
+		matches, err := filepath.Glob(filepath.Join(mountPath, pattern))
+		if err != nil || len(matches) == 0 {
+			fmt.Fprintf(out, "No file matches %s\n", pattern)
+			continue
+		}
+
+		for _, match := range matches {
+			_ = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					fmt.Fprintf(out, "Failed to list %s [%s]\n", path, err.Error())
+					return nil
+				}
+
+				if !d.Type().IsRegular() || seen[path] {
+					return nil
+				}
+
+				info, err := d.Info()
+				if err != nil {
+					fmt.Fprintf(out, "Failed to stat %s [%s]\n", path, err.Error())
+					return nil
+				}
+
+				seen[path] = true
+				entries = append(entries, warmEntry{path: path, size: info.Size()})
+				return nil
+			})
+		}
+	}
+
+	return entries
+}
+
+// warmCache : Read the files through the mount path so that they land in whichever cache the mount uses.
+// Files larger than the space left in the cache, as reported by statfs on the mount path, are skipped.
+func warmCache(out io.Writer, mountPath string, entries []warmEntry, parallelism uint32) error {
+	budget := int64(-1)
+	statfs := &syscall.Statfs_t{}
+	if err := syscall.Statfs(mountPath, statfs); err == nil {
+		budget = int64(statfs.Bavail) * int64(statfs.Bsize)
+	}
+
+	pool := xload.NewThreadPool(parallelism, readWarmFile)
+	pool.Start()
+	defer pool.Stop()
+
+	responses := make(chan *xload.WorkItem, len(entries))
+	scheduled, skipped := 0, 0
+	for _, entry := range entries {
+		if budget >= 0 {
+			if entry.size > budget {
+				fmt.Fprintf(out, "Skipping %s of size %d, cache size limit reached\n", entry.path, entry.size)
+				skipped++
+				continue
+			}
+			budget -= entry.size
+		}
+
+		pool.Schedule(&xload.WorkItem{
+			CompName:        "cache warm",
+			Path:            entry.path,
+			DataLen:         uint64(entry.size),
+			ResponseChannel: responses,
+			Download:        true,
+		})
+		scheduled++
+	}
+
+	warmed, failed, bytes := 0, 0, uint64(0)
+	for i := 1; i <= scheduled; i++ {
+		item := <-responses
+		if item.Err != nil {
+			fmt.Fprintf(out, "[%d/%d] Failed to warm %s [%s]\n", i, scheduled, item.Path, item.Err.Error())
+			failed++
+			continue
+		}
+
+		warmed++
+		bytes += item.DataLen
+		fmt.Fprintf(out, "[%d/%d] Warmed %s\n", i, scheduled, item.Path)
+	}
+
+	fmt.Fprintf(out, "Cache warm up done, %d warmed (%d bytes), %d failed, %d skipped\n", warmed, bytes, failed, skipped)
+	if failed > 0 {
+		return fmt.Errorf("failed to warm %d files", failed)
+	}
+
+	return nil
+}
+
+// readWarmFile : Thread pool callback reading one file completely through the mount path
+func readWarmFile(item *xload.WorkItem) (int, error) {
+	f, err := os.Open(item.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n, err := io.CopyBuffer(io.Discard, f, make([]byte, warmReadBufferSize))
+	return int(n), err
+}
+
+func init() {
+	cacheWarmCmd.Flags().StringVar(&warmOpts.MountPath, "mount", "",
+		"Mount path of the container whose cache shall be warmed up")
+	cacheWarmCmd.Flags().StringVar(&warmOpts.Manifest, "manifest", "",
+		"File listing the paths or glob patterns, relative to the mount path, to be warmed up")
+	cacheWarmCmd.Flags().Uint32Var(&warmOpts.Parallelism, "parallelism", defaultWarmParallelism,
+		"Number of files downloaded in parallel")
+}
@@ -0,0 +1,141 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type cacheWarmTestSuite struct {
+	suite.Suite
+	assert    *assert.Assertions
+	mountPath string
+}
+
+func (suite *cacheWarmTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+
+	suite.mountPath = suite.T().TempDir()
+	for _, f := range []string{"a/b/file1", "a/c/file2", "d/data.csv", "d/data.txt"} {
+		path := filepath.Join(suite.mountPath, f)
+		suite.assert.NoError(os.MkdirAll(filepath.Dir(path), 0777))
+		suite.assert.NoError(os.WriteFile(path, []byte("warm data"), 0777))
+	}
+}
+
+func (suite *cacheWarmTestSuite) cleanupTest() {
+	resetCLIFlags(*cacheWarmCmd)
+	warmOpts = cacheWarmOptions{Parallelism: defaultWarmParallelism}
+}
+
+func (suite *cacheWarmTestSuite) writeManifest(content string) string {
+	manifest := filepath.Join(suite.T().TempDir(), "manifest")
+	suite.assert.NoError(os.WriteFile(manifest, []byte(content), 0777))
+	return manifest
+}
+
+func TestCacheWarm(t *testing.T) {
+	suite.Run(t, new(cacheWarmTestSuite))
+}
+
+func (suite *cacheWarmTestSuite) TestHelp() {
+	defer suite.cleanupTest()
+	_, err := executeCommandC(rootCmd, "cache", "warm", "-h")
+	suite.assert.NoError(err)
+}
+
+func (suite *cacheWarmTestSuite) TestWarm() {
+	defer suite.cleanupTest()
+	manifest := suite.writeManifest("# warm these\na\nd/*.csv\nmissing\n")
+
+	out, err := executeCommandC(rootCmd, "cache", "warm", "--mount", suite.mountPath, "--manifest", manifest, "--parallelism", "2")
+	suite.assert.NoError(err)
+	suite.assert.Contains(out, "No file matches missing")
+	suite.assert.Contains(out, "Warmed "+filepath.Join(suite.mountPath, "a/b/file1"))
+	suite.assert.Contains(out, "Warmed "+filepath.Join(suite.mountPath, "a/c/file2"))
+	suite.assert.Contains(out, "Warmed "+filepath.Join(suite.mountPath, "d/data.csv"))
+	suite.assert.NotContains(out, "data.txt")
+	suite.assert.Contains(out, "3 warmed (27 bytes), 0 failed, 0 skipped")
+}
+
+func (suite *cacheWarmTestSuite) TestWarmInvalidOptions() {
+	defer suite.cleanupTest()
+	manifest := suite.writeManifest("a\n")
+
+	_, err := executeCommandC(rootCmd, "cache", "warm", "--manifest", manifest)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "mount path not provided")
+	suite.cleanupTest()
+
+	_, err = executeCommandC(rootCmd, "cache", "warm", "--mount", suite.mountPath)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "manifest not provided")
+	suite.cleanupTest()
+
+	_, err = executeCommandC(rootCmd, "cache", "warm", "--mount", suite.mountPath, "--manifest", manifest, "--parallelism", "0")
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "parallelism shall be greater than 0")
+	suite.cleanupTest()
+
+	_, err = executeCommandC(rootCmd, "cache", "warm", "--mount", suite.mountPath, "--manifest", manifest+"_missing")
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "failed to read manifest")
+}
+
+func (suite *cacheWarmTestSuite) TestWarmSizeLimit() {
+	defer suite.cleanupTest()
+
+	out := new(bytes.Buffer)
+	entries := expandWarmManifest(out, suite.mountPath, []string{"a/b/file1", "d"})
+	suite.assert.Len(entries, 3)
+	entries = append(entries, warmEntry{path: filepath.Join(suite.mountPath, "huge"), size: 1 << 62})
+
+	err := warmCache(out, suite.mountPath, entries, 2)
+	suite.assert.NoError(err)
+	suite.assert.Contains(out.String(), "Skipping "+filepath.Join(suite.mountPath, "huge"))
+	suite.assert.Contains(out.String(), "3 warmed (27 bytes), 0 failed, 1 skipped")
+}
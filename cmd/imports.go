@@ -40,6 +40,7 @@ import (
 	_ "github.com/Azure/azure-storage-fuse/v2/component/custom"
 	_ "github.com/Azure/azure-storage-fuse/v2/component/entry_cache"
 	_ "github.com/Azure/azure-storage-fuse/v2/component/file_cache"
+	_ "github.com/Azure/azure-storage-fuse/v2/component/gofuse"
 	_ "github.com/Azure/azure-storage-fuse/v2/component/libfuse"
 	_ "github.com/Azure/azure-storage-fuse/v2/component/loopback"
 	_ "github.com/Azure/azure-storage-fuse/v2/component/xload"
@@ -0,0 +1,123 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/component/xload"
+
+	"github.com/spf13/cobra"
+)
+
+var xloadMountPath string
+
+// Section defining all the commands to control the xload transfer of a running mount
+var xloadCmd = &cobra.Command{
+	Use:               "xload",
+	Short:             "Check or control the xload transfer of a mounted container",
+	Long:              "Check or control the xload transfer of a mounted container through its control socket",
+	SuggestFor:        []string{"xlaod", "preload"},
+	Example:           "blobfuse2 xload status --mount=/mnt/blobfuse",
+	FlagErrorHandling: cobra.ExitOnError,
+}
+
+var xloadStatusCmd = newXloadCommand(xload.ControlStatus, "Show the progress of the xload transfer")
+var xloadPauseCmd = newXloadCommand(xload.ControlPause, "Pause the xload transfer, files opened through the mount are still downloaded")
+var xloadResumeCmd = newXloadCommand(xload.ControlResume, "Resume a paused xload transfer")
+var xloadCancelCmd = newXloadCommand(xload.ControlCancel, "Cancel the xload transfer for the rest of the mount, files opened through the mount are still downloaded")
+
+func newXloadCommand(command string, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:               command,
+		Short:             short,
+		Long:              short,
+		Example:           fmt.Sprintf("blobfuse2 xload %s --mount=/mnt/blobfuse", command),
+		FlagErrorHandling: cobra.ExitOnError,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if xloadMountPath == "" {
+				return errors.New("mount path not provided, check usage")
+			}
+
+			status, err := xload.SendControlCommand(common.ExpandPath(xloadMountPath), command)
+			if err != nil {
+				return fmt.Errorf("failed to %s xload [%s]", command, err.Error())
+			}
+
+			printXloadStatus(cmd.OutOrStdout(), status)
+			return nil
+		},
+	}
+}
+
+// printXloadStatus : Print the status returned by the mount in a human readable form
+func printXloadStatus(out io.Writer, status *xload.Status) {
+	if status == nil {
+		return
+	}
+
+	fmt.Fprintf(out, "Mode      : %s\n", status.Mode)
+	fmt.Fprintf(out, "State     : %s\n", status.State)
+	fmt.Fprintf(out, "Files     : %d done, %d failed, %d pending, %d total (%.2f%%)\n",
+		status.Done, status.Failed, status.Pending, status.Total, status.PercentCompleted)
+	fmt.Fprintf(out, "Bytes     : %d done, %d pending, %d total, %d transferred\n",
+		status.BytesDone, status.BytesPending, status.BytesTotal, status.BytesTransferred)
+	fmt.Fprintf(out, "Bandwidth : %.2f Mbps\n", status.BandwidthMbps)
+
+	if status.ETASeconds < 0 {
+		fmt.Fprintf(out, "ETA       : unknown\n")
+	} else {
+		fmt.Fprintf(out, "ETA       : %s\n", time.Duration(status.ETASeconds)*time.Second)
+	}
+
+	if len(status.RecentErrors) > 0 {
+		fmt.Fprintf(out, "Recent errors :\n")
+		for _, msg := range status.RecentErrors {
+			fmt.Fprintf(out, "  %s\n", msg)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(xloadCmd)
+
+	for _, cmd := range []*cobra.Command{xloadStatusCmd, xloadPauseCmd, xloadResumeCmd, xloadCancelCmd} {
+		cmd.Flags().StringVar(&xloadMountPath, "mount", "", "Mount path of the container running xload")
+		xloadCmd.AddCommand(cmd)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/component/xload"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type xloadCmdTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *xloadCmdTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+}
+
+func (suite *xloadCmdTestSuite) cleanupTest() {
+	for _, cmd := range []*cobra.Command{xloadStatusCmd, xloadPauseCmd, xloadResumeCmd, xloadCancelCmd} {
+		resetCLIFlags(*cmd)
+	}
+	xloadMountPath = ""
+}
+
+func TestXloadCmd(t *testing.T) {
+	suite.Run(t, new(xloadCmdTestSuite))
+}
+
+func (suite *xloadCmdTestSuite) TestHelp() {
+	defer suite.cleanupTest()
+	for _, command := range []string{"status", "pause", "resume", "cancel"} {
+		_, err := executeCommandC(rootCmd, "xload", command, "-h")
+		suite.assert.NoError(err)
+	}
+}
+
+func (suite *xloadCmdTestSuite) TestNoMountPath() {
+	defer suite.cleanupTest()
+	_, err := executeCommandC(rootCmd, "xload", "status")
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "mount path not provided")
+}
+
+func (suite *xloadCmdTestSuite) TestNotRunning() {
+	defer suite.cleanupTest()
+	_, err := executeCommandC(rootCmd, "xload", "pause", "--mount", suite.T().TempDir())
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "failed to pause xload")
+	suite.assert.Contains(err.Error(), "xload is not running")
+}
+
+func (suite *xloadCmdTestSuite) TestPrintStatus() {
+	out := &bytes.Buffer{}
+	printXloadStatus(out, &xload.Status{
+		Mode:             "preload",
+		State:            xload.STATE_PAUSED,
+		PercentCompleted: 40,
+		Total:            10,
+		Done:             3,
+		Failed:           1,
+		Pending:          6,
+		BytesTotal:       1000,
+		BytesDone:        400,
+		BytesPending:     600,
+		BytesTransferred: 300,
+		BandwidthMbps:    1.5,
+		ETASeconds:       90,
+		RecentErrors:     []string{"dir/file_1 [timeout]"},
+	})
+
+	suite.assert.Contains(out.String(), "State     : paused")
+	suite.assert.Contains(out.String(), "3 done, 1 failed, 6 pending, 10 total (40.00%)")
+	suite.assert.Contains(out.String(), "400 done, 600 pending, 1000 total, 300 transferred")
+	suite.assert.Contains(out.String(), "1.50 Mbps")
+	suite.assert.Contains(out.String(), "ETA       : 1m30s")
+	suite.assert.Contains(out.String(), "  dir/file_1 [timeout]")
+
+	out.Reset()
+	printXloadStatus(out, &xload.Status{ETASeconds: -1})
+	suite.assert.Contains(out.String(), "ETA       : unknown")
+	suite.assert.NotContains(out.String(), "Recent errors")
+}
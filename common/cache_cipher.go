@@ -0,0 +1,469 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// CacheKeySize is the size of the AES-256 key used to encrypt local caches
+const CacheKeySize = 32
+
+// CacheChunkSize is the amount of plain data encrypted as one unit in a cached file
+const CacheChunkSize = 64 * 1024
+
+// cacheFileIDSize is the size of the random id at the start of an encrypted file
+const cacheFileIDSize = 16
+
+var ErrCacheDataCorrupt = errors.New("cached data failed authentication")
+
+// CacheCipher encrypts data kept in local caches using AES-GCM.
+// A cached file starts with a random file id followed by chunks of CacheChunkSize which are encrypted independently,
+// so reading or writing at any offset touches only the chunks covering it. Each chunk is stored as
+// nonce | ciphertext | tag. The file id, index of the chunk and whether it is the last chunk of the file are
+// authenticated along with it, so chunks can not be swapped within a file or between files and a file
+// truncated at a chunk boundary does not authenticate.
+// A nil CacheCipher leaves the data as is for size calculations.
+type CacheCipher struct {
+	aead      cipher.AEAD
+	chunkSize int64
+}
+
+// NewCacheCipher creates a cipher with the given key, an ephemeral random key is generated if key is empty
+func NewCacheCipher(key []byte) (*CacheCipher, error) {
+	if len(key) == 0 {
+		key = make([]byte, CacheKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(key) != CacheKeySize {
+		return nil, fmt.Errorf("encryption key shall be %d bytes", CacheKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CacheCipher{aead: aead, chunkSize: CacheChunkSize}, nil
+}
+
+// ParseCacheKey decodes a base64 encoded cache encryption key
+func ParseCacheKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not base64 encoded")
+	}
+
+	if len(key) != CacheKeySize {
+		return nil, fmt.Errorf("encryption key shall be %d bytes", CacheKeySize)
+	}
+
+	return key, nil
+}
+
+func (c *CacheCipher) overhead() int64 {
+	return int64(c.aead.NonceSize() + c.aead.Overhead())
+}
+
+// Seal encrypts a complete buffer, id of the object holding the buffer and the position of the buffer in it are
+// authenticated along with it
+func (c *CacheCipher) Seal(data []byte, id []byte, index uint64) ([]byte, error) {
+	return c.seal(data, id, index, true)
+}
+
+// Open decrypts a buffer created by Seal
+func (c *CacheCipher) Open(data []byte, id []byte, index uint64) ([]byte, error) {
+	return c.open(data, id, index, true)
+}
+
+func (c *CacheCipher) seal(data []byte, id []byte, index uint64, final bool) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), int64(len(data))+c.overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, data, additionalData(id, index, final)), nil
+}
+
+func (c *CacheCipher) open(data []byte, id []byte, index uint64, final bool) ([]byte, error) {
+	if int64(len(data)) < c.overhead() {
+		return nil, ErrCacheDataCorrupt
+	}
+
+	nonceSize := c.aead.NonceSize()
+	plain, err := c.aead.Open(nil, data[:nonceSize], data[nonceSize:], additionalData(id, index, final))
+	if err != nil {
+		return nil, ErrCacheDataCorrupt
+	}
+
+	return plain, nil
+}
+
+// additionalData : Data authenticated along with a chunk, id | index | final flag
+func additionalData(id []byte, index uint64, final bool) []byte {
+	data := make([]byte, len(id)+9)
+	copy(data, id)
+	binary.BigEndian.PutUint64(data[len(id):], index)
+	if final {
+		data[len(data)-1] = 1
+	}
+	return data
+}
+
+// PlainSize converts the size of an encrypted file to the size of data it holds
+func (c *CacheCipher) PlainSize(size int64) int64 {
+	if c == nil {
+		return size
+	}
+
+	size -= cacheFileIDSize
+	if size <= 0 {
+		return 0
+	}
+
+	stored := c.chunkSize + c.overhead()
+	plain := (size / stored) * c.chunkSize
+	if rem := size % stored; rem > c.overhead() {
+		plain += rem - c.overhead()
+	}
+
+	return plain
+}
+
+// CipherSize converts the size of plain data to the size of the encrypted file holding it
+func (c *CacheCipher) CipherSize(size int64) int64 {
+	if c == nil {
+		return size
+	}
+
+	if size == 0 {
+		return 0
+	}
+
+	stored := cacheFileIDSize + (size/c.chunkSize)*(c.chunkSize+c.overhead())
+	if rem := size % c.chunkSize; rem > 0 {
+		stored += rem + c.overhead()
+	}
+
+	return stored
+}
+
+// Size returns the size of data held by an encrypted file
+func (c *CacheCipher) Size(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.PlainSize(info.Size()), nil
+}
+
+// fileID reads the id of an encrypted file, a new id is written to a file holding no data if create is set
+func (c *CacheCipher) fileID(f *os.File, create bool) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, cacheFileIDSize)
+	if info.Size() >= cacheFileIDSize {
+		_, err = f.ReadAt(id, 0)
+		return id, err
+	}
+
+	if !create {
+		return nil, nil
+	}
+
+	if _, err = io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+
+	_, err = f.WriteAt(id, 0)
+	return id, err
+}
+
+// lastChunk returns index of the last chunk of a file holding size bytes of data, -1 if it holds none
+func (c *CacheCipher) lastChunk(size int64) int64 {
+	return (size+c.chunkSize-1)/c.chunkSize - 1
+}
+
+// readChunk reads and decrypts one chunk of a file holding size bytes of data
+func (c *CacheCipher) readChunk(f *os.File, id []byte, index int64, size int64) ([]byte, error) {
+	length := min(c.chunkSize, size-index*c.chunkSize)
+	if length <= 0 {
+		return []byte{}, nil
+	}
+
+	data := make([]byte, length+c.overhead())
+	_, err := f.ReadAt(data, cacheFileIDSize+index*(c.chunkSize+c.overhead()))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.open(data, id, uint64(index), index == c.lastChunk(size))
+}
+
+// writeChunk encrypts and writes one chunk of a file
+func (c *CacheCipher) writeChunk(f *os.File, id []byte, index int64, plain []byte, final bool) error {
+	data, err := c.seal(plain, id, uint64(index), final)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteAt(data, cacheFileIDSize+index*(c.chunkSize+c.overhead()))
+	return err
+}
+
+// resealChunk encrypts a chunk again when it becomes or stops being the last chunk of the file
+func (c *CacheCipher) resealChunk(f *os.File, id []byte, index int64, size int64, final bool) error {
+	plain, err := c.readChunk(f, id, index, size)
+	if err != nil {
+		return err
+	}
+
+	return c.writeChunk(f, id, index, plain, final)
+}
+
+// ReadAt reads plain data at the given offset of an encrypted file
+func (c *CacheCipher) ReadAt(f *os.File, p []byte, off int64) (int, error) {
+	size, err := c.Size(f)
+	if err != nil {
+		return 0, err
+	}
+
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	id, err := c.fileID(f, false)
+	if err != nil {
+		return 0, err
+	}
+
+	end := min(off+int64(len(p)), size)
+	n := 0
+	for index := off / c.chunkSize; index*c.chunkSize < end; index++ {
+		plain, err := c.readChunk(f, id, index, size)
+		if err != nil {
+			return n, err
+		}
+
+		start := index * c.chunkSize
+		n += copy(p[n:], plain[max(off, start)-start:min(end, start+c.chunkSize)-start])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// WriteAt writes plain data at the given offset of an encrypted file, a gap after the current end is filled with zeros
+func (c *CacheCipher) WriteAt(f *os.File, p []byte, off int64) (int, error) {
+	size, err := c.Size(f)
+	if err != nil {
+		return 0, err
+	}
+
+	if off > size {
+		err = c.Truncate(f, off)
+		if err != nil {
+			return 0, err
+		}
+		size = off
+	}
+
+	id, err := c.fileID(f, true)
+	if err != nil {
+		return 0, err
+	}
+
+	end := off + int64(len(p))
+	newSize := max(size, end)
+	last := c.lastChunk(newSize)
+
+	// Last chunk of the file which is not written again is no longer the last one
+	if oldLast := c.lastChunk(size); oldLast >= 0 && oldLast < off/c.chunkSize && oldLast < last {
+		err = c.resealChunk(f, id, oldLast, size, false)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for index := off / c.chunkSize; index*c.chunkSize < end; index++ {
+		start := index * c.chunkSize
+
+		// Chunks partially overwritten need their existing data
+		plain := []byte{}
+		if start < size && (off > start || end < min(start+c.chunkSize, size)) {
+			plain, err = c.readChunk(f, id, index, size)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		length := max(int64(len(plain)), min(end, start+c.chunkSize)-start)
+		chunk := make([]byte, length)
+		copy(chunk, plain)
+		copy(chunk[max(off, start)-start:], p[max(off, start)-off:min(end, start+c.chunkSize)-off])
+
+		err = c.writeChunk(f, id, index, chunk, index == last)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Truncate changes the size of data held by an encrypted file, growing it with zeros
+func (c *CacheCipher) Truncate(f *os.File, size int64) error {
+	current, err := c.Size(f)
+	if err != nil {
+		return err
+	}
+
+	if size > current {
+		zeros := make([]byte, c.chunkSize)
+		for pos := current; pos < size; {
+			n := min(c.chunkSize-pos%c.chunkSize, size-pos)
+			_, err = c.WriteAt(f, zeros[:n], pos)
+			if err != nil {
+				return err
+			}
+			pos += n
+		}
+		return nil
+	}
+
+	// Chunk which becomes the last one is encrypted again with its new length and as the last chunk
+	if size > 0 && size < current {
+		id, err := c.fileID(f, false)
+		if err != nil {
+			return err
+		}
+
+		index := c.lastChunk(size)
+		plain, err := c.readChunk(f, id, index, current)
+		if err != nil {
+			return err
+		}
+
+		err = c.writeChunk(f, id, index, plain[:size-index*c.chunkSize], true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.Truncate(c.CipherSize(size))
+}
+
+// Encrypt writes all data from the reader into an empty encrypted file
+func (c *CacheCipher) Encrypt(dst *os.File, src io.Reader) error {
+	id, err := c.fileID(dst, true)
+	if err != nil {
+		return err
+	}
+
+	// Next chunk is read ahead to know whether the current one is the last
+	buf, next := make([]byte, c.chunkSize), make([]byte, c.chunkSize)
+	n, err := io.ReadFull(src, buf)
+	for index := int64(0); n > 0; index++ {
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		m := 0
+		if err == nil {
+			m, err = io.ReadFull(src, next)
+		}
+
+		if werr := c.writeChunk(dst, id, index, buf[:n], m == 0); werr != nil {
+			return werr
+		}
+
+		buf, next = next, buf
+		n = m
+	}
+
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	return nil
+}
+
+// Decrypt writes all data held by an encrypted file to the writer
+func (c *CacheCipher) Decrypt(dst io.Writer, src *os.File) error {
+	size, err := c.Size(src)
+	if err != nil {
+		return err
+	}
+
+	id, err := c.fileID(src, false)
+	if err != nil {
+		return err
+	}
+
+	for index := int64(0); index*c.chunkSize < size; index++ {
+		plain, err := c.readChunk(src, id, index, size)
+		if err != nil {
+			return err
+		}
+
+		_, err = dst.Write(plain)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
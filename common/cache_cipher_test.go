@@ -0,0 +1,296 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type cacheCipherTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	cipher *CacheCipher
+	dir    string
+}
+
+func (suite *cacheCipherTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.dir = suite.T().TempDir()
+
+	var err error
+	suite.cipher, err = NewCacheCipher(nil)
+	suite.assert.Nil(err)
+}
+
+func TestCacheCipher(t *testing.T) {
+	suite.Run(t, new(cacheCipherTestSuite))
+}
+
+func (suite *cacheCipherTestSuite) openFile() *os.File {
+	f, err := os.OpenFile(filepath.Join(suite.dir, randomString(8)), os.O_RDWR|os.O_CREATE, 0600)
+	suite.assert.Nil(err)
+	return f
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	return data
+}
+
+func (suite *cacheCipherTestSuite) TestSizes() {
+	for _, size := range []int64{0, 1, CacheChunkSize - 1, CacheChunkSize, CacheChunkSize + 1, 5*CacheChunkSize + 17} {
+		suite.assert.Equal(size, suite.cipher.PlainSize(suite.cipher.CipherSize(size)))
+	}
+
+	var c *CacheCipher
+	suite.assert.EqualValues(100, c.PlainSize(100))
+	suite.assert.EqualValues(100, c.CipherSize(100))
+}
+
+func (suite *cacheCipherTestSuite) TestParseKey() {
+	key := randomData(CacheKeySize)
+	parsed, err := ParseCacheKey(base64.StdEncoding.EncodeToString(key))
+	suite.assert.Nil(err)
+	suite.assert.Equal(key, parsed)
+
+	_, err = ParseCacheKey("not base64!")
+	suite.assert.NotNil(err)
+
+	_, err = ParseCacheKey(base64.StdEncoding.EncodeToString(randomData(16)))
+	suite.assert.NotNil(err)
+
+	_, err = NewCacheCipher(randomData(16))
+	suite.assert.NotNil(err)
+}
+
+func (suite *cacheCipherTestSuite) TestSealOpen() {
+	data := randomData(1000)
+	id := []byte("file")
+	sealed, err := suite.cipher.Seal(data, id, 3)
+	suite.assert.Nil(err)
+	suite.assert.NotContains(string(sealed), string(data))
+
+	plain, err := suite.cipher.Open(sealed, id, 3)
+	suite.assert.Nil(err)
+	suite.assert.Equal(data, plain)
+
+	// Data sealed at another index, for another object or with another key does not open
+	_, err = suite.cipher.Open(sealed, id, 4)
+	suite.assert.Equal(ErrCacheDataCorrupt, err)
+
+	_, err = suite.cipher.Open(sealed, []byte("other"), 3)
+	suite.assert.Equal(ErrCacheDataCorrupt, err)
+
+	other, _ := NewCacheCipher(nil)
+	_, err = other.Open(sealed, id, 3)
+	suite.assert.Equal(ErrCacheDataCorrupt, err)
+
+	_, err = suite.cipher.Open(sealed[:5], id, 3)
+	suite.assert.Equal(ErrCacheDataCorrupt, err)
+}
+
+func (suite *cacheCipherTestSuite) TestEncryptDecrypt() {
+	data := randomData(3*CacheChunkSize + 100)
+	f := suite.openFile()
+	defer f.Close()
+
+	err := suite.cipher.Encrypt(f, bytes.NewReader(data))
+	suite.assert.Nil(err)
+
+	size, err := suite.cipher.Size(f)
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(len(data), size)
+
+	raw, _ := os.ReadFile(f.Name())
+	suite.assert.False(bytes.Contains(raw, data[:64]))
+
+	var out bytes.Buffer
+	err = suite.cipher.Decrypt(&out, f)
+	suite.assert.Nil(err)
+	suite.assert.Equal(data, out.Bytes())
+}
+
+func (suite *cacheCipherTestSuite) TestReadAt() {
+	data := randomData(2*CacheChunkSize + 10)
+	f := suite.openFile()
+	defer f.Close()
+	suite.assert.Nil(suite.cipher.Encrypt(f, bytes.NewReader(data)))
+
+	buf := make([]byte, CacheChunkSize)
+	n, err := suite.cipher.ReadAt(f, buf, 100)
+	suite.assert.Nil(err)
+	suite.assert.Equal(CacheChunkSize, n)
+	suite.assert.Equal(data[100:100+CacheChunkSize], buf)
+
+	n, err = suite.cipher.ReadAt(f, buf, 2*CacheChunkSize)
+	suite.assert.Equal(io.EOF, err)
+	suite.assert.Equal(10, n)
+	suite.assert.Equal(data[2*CacheChunkSize:], buf[:n])
+
+	_, err = suite.cipher.ReadAt(f, buf, int64(len(data)))
+	suite.assert.Equal(io.EOF, err)
+}
+
+func (suite *cacheCipherTestSuite) TestWriteAt() {
+	data := randomData(2*CacheChunkSize + 10)
+	f := suite.openFile()
+	defer f.Close()
+	suite.assert.Nil(suite.cipher.Encrypt(f, bytes.NewReader(data)))
+
+	// Overwrite across a chunk boundary
+	update := randomData(200)
+	n, err := suite.cipher.WriteAt(f, update, CacheChunkSize-100)
+	suite.assert.Nil(err)
+	suite.assert.Equal(200, n)
+	copy(data[CacheChunkSize-100:], update)
+
+	// Write past the end leaving a gap
+	n, err = suite.cipher.WriteAt(f, update, int64(len(data))+50)
+	suite.assert.Nil(err)
+	suite.assert.Equal(200, n)
+	data = append(data, make([]byte, 50)...)
+	data = append(data, update...)
+
+	var out bytes.Buffer
+	suite.assert.Nil(suite.cipher.Decrypt(&out, f))
+	suite.assert.Equal(data, out.Bytes())
+}
+
+func (suite *cacheCipherTestSuite) TestTruncate() {
+	data := randomData(2*CacheChunkSize + 10)
+	f := suite.openFile()
+	defer f.Close()
+	suite.assert.Nil(suite.cipher.Encrypt(f, bytes.NewReader(data)))
+
+	err := suite.cipher.Truncate(f, CacheChunkSize+5)
+	suite.assert.Nil(err)
+	size, _ := suite.cipher.Size(f)
+	suite.assert.EqualValues(CacheChunkSize+5, size)
+
+	err = suite.cipher.Truncate(f, 2*CacheChunkSize+100)
+	suite.assert.Nil(err)
+
+	expected := append([]byte{}, data[:CacheChunkSize+5]...)
+	expected = append(expected, make([]byte, CacheChunkSize+95)...)
+
+	var out bytes.Buffer
+	suite.assert.Nil(suite.cipher.Decrypt(&out, f))
+	suite.assert.Equal(expected, out.Bytes())
+
+	suite.assert.Nil(suite.cipher.Truncate(f, 0))
+	size, _ = suite.cipher.Size(f)
+	suite.assert.EqualValues(0, size)
+}
+
+func (suite *cacheCipherTestSuite) TestCorruptChunk() {
+	f := suite.openFile()
+	defer f.Close()
+	suite.assert.Nil(suite.cipher.Encrypt(f, bytes.NewReader(randomData(100))))
+
+	b := make([]byte, 1)
+	_, err := f.ReadAt(b, 20)
+	suite.assert.Nil(err)
+	b[0] ^= 0xff
+	_, err = f.WriteAt(b, 20)
+	suite.assert.Nil(err)
+
+	buf := make([]byte, 10)
+	_, err = suite.cipher.ReadAt(f, buf, 0)
+	suite.assert.Equal(ErrCacheDataCorrupt, err)
+}
+
+func (suite *cacheCipherTestSuite) TestChunkFromOtherFile() {
+	first := suite.openFile()
+	defer first.Close()
+	second := suite.openFile()
+	defer second.Close()
+
+	suite.assert.Nil(suite.cipher.Encrypt(first, bytes.NewReader(randomData(2*CacheChunkSize))))
+	suite.assert.Nil(suite.cipher.Encrypt(second, bytes.NewReader(randomData(2*CacheChunkSize))))
+
+	// Copy the first chunk of one file over the first chunk of the other
+	chunk := make([]byte, CacheChunkSize+suite.cipher.overhead())
+	_, err := first.ReadAt(chunk, cacheFileIDSize)
+	suite.assert.Nil(err)
+	_, err = second.WriteAt(chunk, cacheFileIDSize)
+	suite.assert.Nil(err)
+
+	buf := make([]byte, 10)
+	_, err = suite.cipher.ReadAt(second, buf, 0)
+	suite.assert.Equal(ErrCacheDataCorrupt, err)
+}
+
+func (suite *cacheCipherTestSuite) TestTruncatedAtChunkBoundary() {
+	data := randomData(2 * CacheChunkSize)
+	f := suite.openFile()
+	defer f.Close()
+	suite.assert.Nil(suite.cipher.Encrypt(f, bytes.NewReader(data)))
+
+	// Dropping whole chunks from the end of the file is detected
+	suite.assert.Nil(f.Truncate(suite.cipher.CipherSize(CacheChunkSize)))
+
+	var out bytes.Buffer
+	suite.assert.Equal(ErrCacheDataCorrupt, suite.cipher.Decrypt(&out, f))
+}
+
+func (suite *cacheCipherTestSuite) TestGrowAndShrinkAtChunkBoundary() {
+	data := randomData(CacheChunkSize)
+	f := suite.openFile()
+	defer f.Close()
+	suite.assert.Nil(suite.cipher.Encrypt(f, bytes.NewReader(data)))
+
+	// Writing after a full last chunk and truncating back to it keep the file readable
+	update := randomData(100)
+	_, err := suite.cipher.WriteAt(f, update, CacheChunkSize)
+	suite.assert.Nil(err)
+
+	var out bytes.Buffer
+	suite.assert.Nil(suite.cipher.Decrypt(&out, f))
+	suite.assert.Equal(append(append([]byte{}, data...), update...), out.Bytes())
+
+	suite.assert.Nil(suite.cipher.Truncate(f, CacheChunkSize))
+	out.Reset()
+	suite.assert.Nil(suite.cipher.Decrypt(&out, f))
+	suite.assert.Equal(data, out.Bytes())
+}
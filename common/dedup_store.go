@@ -0,0 +1,340 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ioctl request to clone the extents of a file into another, supported by btrfs, xfs and a few other file systems
+const ficlone = 0x40049409
+
+// directory of the store holding files being linked or copied into place
+const dedupTmpDir = ".tmp"
+
+// temp files not changed for this long belong to a process which died midway and are removed when the store is opened
+var dedupTmpAge = 10 * time.Minute
+
+var ErrDedupMismatch = errors.New("content does not match the md5 sum")
+
+// DedupStore keeps a single copy of the content of files downloaded into local caches, keyed by the Content-MD5
+// of the blob. Files having the same content are hard linked to the copy in the store, so the content occupies
+// disk space once and is counted once by usage checks walking a cache path. The store shall be on the same file
+// system as the cache paths linked to it.
+// Hard linked files share their mode and times along with the content, and a file is given its own copy of the
+// content by Unshare before it is modified in place.
+// Files are linked or copied into place through temp files kept in the store, so cache paths never see them.
+type DedupStore struct {
+	path  string
+	locks *LockMap
+	seq   atomic.Uint64
+}
+
+// NewDedupStore opens the store at the given path, creating it if required
+func NewDedupStore(path string) (*DedupStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("dedup store path is empty")
+	}
+
+	err := os.MkdirAll(filepath.Join(path, dedupTmpDir), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &DedupStore{path: path, locks: NewLockMap()}
+	ds.sweepTemp()
+	return ds, nil
+}
+
+// sweepTemp : remove temp files left behind by a process which died while linking or copying a file
+func (ds *DedupStore) sweepTemp() {
+	entries, err := os.ReadDir(filepath.Join(ds.path, dedupTmpDir))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(ds.path, dedupTmpDir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		// linking a file does not change its modified time, so the age is taken from the change time
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if ok && time.Since(time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec)) >= dedupTmpAge {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// tempPath : unique location in the store to place a file before it is renamed to its destination
+func (ds *DedupStore) tempPath() string {
+	return filepath.Join(ds.path, dedupTmpDir, fmt.Sprintf("%d.%d", os.Getpid(), ds.seq.Add(1)))
+}
+
+// Path of the store
+func (ds *DedupStore) Path() string {
+	return ds.path
+}
+
+// entryPath : location of the content with the given md5 sum in the store
+func (ds *DedupStore) entryPath(md5 []byte) string {
+	key := hex.EncodeToString(md5)
+	return filepath.Join(ds.path, key[:2], key)
+}
+
+// Link places the content with the given md5 sum and size at dst, replacing dst if it exists.
+// Returns false if the store does not hold the content.
+func (ds *DedupStore) Link(md5 []byte, size int64, dst string) (bool, error) {
+	if ds == nil || len(md5) == 0 {
+		return false, nil
+	}
+
+	entry := ds.entryPath(md5)
+	lock := ds.locks.Get(entry)
+	lock.Lock()
+	defer lock.Unlock()
+
+	info, err := os.Stat(entry)
+	if err != nil || info.Size() != size {
+		return false, nil
+	}
+
+	if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(info, dstInfo) {
+		return true, nil
+	}
+
+	err = ds.replaceWithLink(entry, dst)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Add puts the content of the file at src in the store after checking it against the given md5 sum.
+// If the store already holds the content, src is replaced with a link to it so that a file downloaded
+// twice is kept once.
+// The whole file is read to check its md5 sum, so Add is meant to be called once right after a file is
+// downloaded and not each time it is closed. A file already linked to the store is not read again.
+func (ds *DedupStore) Add(md5 []byte, src string) error {
+	if ds == nil || len(md5) == 0 {
+		return nil
+	}
+
+	entry := ds.entryPath(md5)
+	if ds.linked(entry, src) {
+		return nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	sum, err := GetMD5(f)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(sum, md5) {
+		return ErrDedupMismatch
+	}
+
+	lock := ds.locks.Get(entry)
+	lock.Lock()
+	defer lock.Unlock()
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(entry)
+	if err == nil {
+		if os.SameFile(info, srcInfo) {
+			return nil
+		}
+
+		if info.Size() == srcInfo.Size() {
+			return ds.replaceWithLink(entry, src)
+		}
+
+		// content in the store does not have the expected size, so replace it
+		err = os.Remove(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.MkdirAll(filepath.Dir(entry), 0700)
+	if err != nil {
+		return err
+	}
+
+	return os.Link(src, entry)
+}
+
+// linked : check if the file at src is the given entry of the store
+func (ds *DedupStore) linked(entry string, src string) bool {
+	info, err := os.Stat(entry)
+	if err != nil {
+		return false
+	}
+
+	srcInfo, err := os.Stat(src)
+	return err == nil && os.SameFile(info, srcInfo)
+}
+
+// Prune removes the content which is no longer linked from any cache path and returns the number of bytes freed
+func (ds *DedupStore) Prune() (int64, error) {
+	if ds == nil {
+		return 0, nil
+	}
+
+	var freed int64
+	err := filepath.WalkDir(ds.path, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && d.Name() == dedupTmpDir {
+			// files being linked or copied into place are not content of the store
+			return filepath.SkipDir
+		}
+
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		lock := ds.locks.Get(path)
+		lock.Lock()
+		defer lock.Unlock()
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Nlink == 1 {
+			if os.Remove(path) == nil {
+				freed += info.Size()
+			}
+		}
+		return nil
+	})
+
+	return freed, err
+}
+
+// replaceWithLink : replace dst with a hard link to src
+func (ds *DedupStore) replaceWithLink(src string, dst string) error {
+	tmp := ds.tempPath()
+
+	err := os.Link(src, tmp)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, dst)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// Unshare gives the file at path its own copy of the content if it is linked to other files, so that it
+// can be modified in place. The copy is a reflink where the file system supports it and a full copy otherwise.
+func (ds *DedupStore) Unshare(path string) error {
+	if ds == nil {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink <= 1 || !info.Mode().IsRegular() {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := ds.tempPath()
+
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd())
+	if errno != 0 {
+		_, err = io.Copy(dst, src)
+	}
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chtimes(tmp, time.Unix(stat.Atim.Sec, stat.Atim.Nsec), info.ModTime())
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
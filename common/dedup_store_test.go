@@ -0,0 +1,246 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package common
+
+import (
+	"crypto/md5"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type dedupStoreTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	store  *DedupStore
+	dir    string
+}
+
+func (suite *dedupStoreTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.dir = suite.T().TempDir()
+
+	var err error
+	suite.store, err = NewDedupStore(filepath.Join(suite.dir, "store"))
+	suite.assert.Nil(err)
+}
+
+func TestDedupStore(t *testing.T) {
+	suite.Run(t, new(dedupStoreTestSuite))
+}
+
+func (suite *dedupStoreTestSuite) writeFile(name string, data []byte) string {
+	path := filepath.Join(suite.dir, name)
+	err := os.WriteFile(path, data, 0644)
+	suite.assert.Nil(err)
+	return path
+}
+
+func links(path string) uint64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return uint64(info.Sys().(*syscall.Stat_t).Nlink)
+}
+
+func (suite *dedupStoreTestSuite) TestNewDedupStore() {
+	_, err := NewDedupStore("")
+	suite.assert.NotNil(err)
+
+	info, err := os.Stat(suite.store.Path())
+	suite.assert.Nil(err)
+	suite.assert.True(info.IsDir())
+}
+
+func (suite *dedupStoreTestSuite) TestAddAndLink() {
+	data := randomData(4096)
+	sum := md5.Sum(data)
+	src := suite.writeFile("src", data)
+
+	found, err := suite.store.Link(sum[:], int64(len(data)), filepath.Join(suite.dir, "dst"))
+	suite.assert.Nil(err)
+	suite.assert.False(found)
+
+	err = suite.store.Add(sum[:], src)
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(2, links(src))
+
+	// adding the same file again does nothing
+	err = suite.store.Add(sum[:], src)
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(2, links(src))
+
+	dst := filepath.Join(suite.dir, "dst")
+	found, err = suite.store.Link(sum[:], int64(len(data)), dst)
+	suite.assert.Nil(err)
+	suite.assert.True(found)
+	suite.assert.EqualValues(3, links(src))
+
+	read, err := os.ReadFile(dst)
+	suite.assert.Nil(err)
+	suite.assert.Equal(data, read)
+
+	// content of a different size is not linked
+	found, err = suite.store.Link(sum[:], int64(len(data)+1), filepath.Join(suite.dir, "other"))
+	suite.assert.Nil(err)
+	suite.assert.False(found)
+}
+
+func (suite *dedupStoreTestSuite) TestAddReplacesDuplicate() {
+	data := randomData(1024)
+	sum := md5.Sum(data)
+	first := suite.writeFile("first", data)
+	second := suite.writeFile("second", data)
+
+	suite.assert.Nil(suite.store.Add(sum[:], first))
+	suite.assert.Nil(suite.store.Add(sum[:], second))
+
+	firstInfo, _ := os.Stat(first)
+	secondInfo, _ := os.Stat(second)
+	suite.assert.True(os.SameFile(firstInfo, secondInfo))
+	suite.assert.EqualValues(3, links(first))
+}
+
+func (suite *dedupStoreTestSuite) TestAddMismatch() {
+	data := randomData(1024)
+	src := suite.writeFile("src", data)
+	other := md5.Sum([]byte("other"))
+
+	err := suite.store.Add(other[:], src)
+	suite.assert.Equal(ErrDedupMismatch, err)
+	suite.assert.EqualValues(1, links(src))
+
+	// files without md5 sum are not deduplicated
+	suite.assert.Nil(suite.store.Add(nil, src))
+	found, err := suite.store.Link(nil, int64(len(data)), filepath.Join(suite.dir, "dst"))
+	suite.assert.Nil(err)
+	suite.assert.False(found)
+}
+
+func (suite *dedupStoreTestSuite) TestUnshareFile() {
+	data := randomData(8192)
+	sum := md5.Sum(data)
+	src := suite.writeFile("src", data)
+	suite.assert.Nil(suite.store.Add(sum[:], src))
+
+	dst := filepath.Join(suite.dir, "dst")
+	_, err := suite.store.Link(sum[:], int64(len(data)), dst)
+	suite.assert.Nil(err)
+
+	err = suite.store.Unshare(dst)
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(1, links(dst))
+	suite.assert.EqualValues(2, links(src))
+
+	// writing to the unshared file does not change the content of others
+	err = os.WriteFile(dst, []byte("changed"), 0644)
+	suite.assert.Nil(err)
+	read, err := os.ReadFile(src)
+	suite.assert.Nil(err)
+	suite.assert.Equal(data, read)
+
+	// files which are not linked and missing files are left as is
+	suite.assert.Nil(suite.store.Unshare(dst))
+	suite.assert.Nil(suite.store.Unshare(filepath.Join(suite.dir, "missing")))
+}
+
+func (suite *dedupStoreTestSuite) TestPrune() {
+	data := randomData(2048)
+	sum := md5.Sum(data)
+	src := suite.writeFile("src", data)
+	suite.assert.Nil(suite.store.Add(sum[:], src))
+
+	freed, err := suite.store.Prune()
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(0, freed)
+
+	suite.assert.Nil(os.Remove(src))
+	freed, err = suite.store.Prune()
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(len(data), freed)
+
+	found, err := suite.store.Link(sum[:], int64(len(data)), filepath.Join(suite.dir, "dst"))
+	suite.assert.Nil(err)
+	suite.assert.False(found)
+}
+
+func (suite *dedupStoreTestSuite) TestTempFiles() {
+	data := randomData(1024)
+	sum := md5.Sum(data)
+	suite.assert.Nil(suite.store.Add(sum[:], suite.writeFile("src", data)))
+	suite.assert.Nil(suite.store.Add(sum[:], suite.writeFile("second", data)))
+
+	dst := filepath.Join(suite.dir, "dst")
+	_, err := suite.store.Link(sum[:], int64(len(data)), dst)
+	suite.assert.Nil(err)
+	suite.assert.Nil(suite.store.Unshare(dst))
+
+	// files are linked and copied into place through the store, nothing else is left next to them
+	entries, err := os.ReadDir(suite.dir)
+	suite.assert.Nil(err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	suite.assert.ElementsMatch([]string{"store", "src", "second", "dst"}, names)
+
+	entries, err = os.ReadDir(filepath.Join(suite.store.Path(), dedupTmpDir))
+	suite.assert.Nil(err)
+	suite.assert.Empty(entries)
+}
+
+func (suite *dedupStoreTestSuite) TestSweepTemp() {
+	tmp := filepath.Join(suite.store.Path(), dedupTmpDir, "1.1")
+	suite.assert.Nil(os.WriteFile(tmp, []byte("partial"), 0644))
+
+	// temp file of a copy in progress is neither content of the store nor stale
+	_, err := suite.store.Prune()
+	suite.assert.Nil(err)
+	_, err = NewDedupStore(suite.store.Path())
+	suite.assert.Nil(err)
+	suite.assert.FileExists(tmp)
+
+	age := dedupTmpAge
+	defer func() { dedupTmpAge = age }()
+	dedupTmpAge = 0
+
+	_, err = NewDedupStore(suite.store.Path())
+	suite.assert.Nil(err)
+	suite.assert.NoFileExists(tmp)
+}
@@ -34,18 +34,22 @@
 package attr_cache
 
 import (
+	"container/list"
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
 	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+	"github.com/Azure/azure-storage-fuse/v2/internal/metastore"
 )
 
 // By default attr cache is valid for 120 seconds
@@ -57,8 +61,24 @@ type AttrCache struct {
 	cacheTimeout uint32
 	noSymlinks   bool
 	maxFiles     int
-	cacheMap     map[string]*attrCacheItem
+	maxMemory    int64
+	cacheMap     *attrMap
 	cacheLock    sync.RWMutex
+
+	lru       *list.List // Items in order of their last use, front is the most recent
+	lruLock   sync.Mutex
+	memoryUse int64 // Approximate memory held by items in cache map
+
+	diskPath    string
+	diskTimeout uint32
+	store       *metastore.Store // Listings persisted on disk across mounts
+
+	inventoryPath string
+	prefixPath    string // Subdirectory mounted, used to map names in the inventory report
+	inventoryStop chan struct{}
+	inventoryWG   sync.WaitGroup
+
+	head internal.Component // Head of the pipeline, changes found in storage are sent through it to the kernel
 }
 
 // Structure defining your config parameters
@@ -70,6 +90,16 @@ type AttrCacheOptions struct {
 	//maximum file attributes overall to be cached
 	MaxFiles int `config:"max-files" yaml:"max-files,omitempty"`
 
+	// maximum memory to be used for caching attributes
+	MaxMemoryMB uint32 `config:"max-memory-mb" yaml:"max-memory-mb,omitempty"`
+
+	// directory to persist listings in, so that they survive a remount
+	DiskCachePath    string `config:"disk-cache-path" yaml:"disk-cache-path,omitempty"`
+	DiskCacheTimeout uint32 `config:"disk-cache-timeout-sec" yaml:"disk-cache-timeout-sec,omitempty"`
+
+	// blob inventory report to pre-populate listings in the disk cache from
+	InventoryPath string `config:"inventory-path" yaml:"inventory-path,omitempty"`
+
 	// support v1
 	CacheOnList bool `config:"cache-on-list"`
 }
@@ -80,6 +110,11 @@ const compName = "attr_cache"
 // caching more means increased memory usage of the process
 const defaultMaxFiles = 5000000 // 5 million max files overall to be cached
 
+const MB = 1024 * 1024
+
+// By default listings persisted on disk are valid for an hour
+const defaultDiskCacheTimeout uint32 = (3600)
+
 // Verification to check satisfaction criteria with Component Interface
 var _ internal.Component = &AttrCache{}
 
@@ -106,7 +141,31 @@ func (ac *AttrCache) Start(ctx context.Context) error {
 	log.Trace("AttrCache::Start : Starting component %s", ac.Name())
 
 	// AttrCache : start code goes here
-	ac.cacheMap = make(map[string]*attrCacheItem)
+	ac.cacheMap = newAttrMap()
+	ac.lru = list.New()
+	ac.memoryUse = 0
+
+	if ac.diskPath != "" {
+		var container string
+		_ = config.UnmarshalKey("azstorage.container", &container)
+
+		var err error
+		ac.store, err = metastore.Open(ac.diskPath, container, time.Duration(ac.diskTimeout)*time.Second)
+		if err != nil {
+			log.Err("AttrCache::Start : failed to open disk cache at %s [%s]", ac.diskPath, err.Error())
+			return fmt.Errorf("failed to open disk cache for attr-cache")
+		}
+	}
+
+	// Import listings from the inventory report in background, anything not imported yet is listed from storage
+	if ac.inventoryPath != "" {
+		_ = config.UnmarshalKey("azstorage.subdirectory", &ac.prefixPath)
+		ac.prefixPath = strings.Trim(ac.prefixPath, "/")
+
+		ac.inventoryStop = make(chan struct{})
+		ac.inventoryWG.Add(1)
+		go ac.loadInventory()
+	}
 
 	return nil
 }
@@ -115,6 +174,15 @@ func (ac *AttrCache) Start(ctx context.Context) error {
 func (ac *AttrCache) Stop() error {
 	log.Trace("AttrCache::Stop : Stopping component %s", ac.Name())
 
+	if ac.inventoryStop != nil {
+		close(ac.inventoryStop)
+		ac.inventoryWG.Wait()
+		ac.inventoryStop = nil
+	}
+
+	ac.store.Close()
+	ac.store = nil
+
 	return nil
 }
 
@@ -155,12 +223,35 @@ func (ac *AttrCache) Configure(_ bool) error {
 		ac.maxFiles = defaultMaxFiles
 	}
 
+	ac.maxMemory = int64(conf.MaxMemoryMB) * MB
+
 	if config.IsSet(compName + ".no-symlinks") {
 		ac.noSymlinks = conf.NoSymlinks
 	}
 
-	log.Crit("AttrCache::Configure : cache-timeout %d, symlink %t, max-files %d",
-		ac.cacheTimeout, ac.noSymlinks, ac.maxFiles)
+	ac.diskPath = common.ExpandPath(conf.DiskCachePath)
+	ac.diskTimeout = defaultDiskCacheTimeout
+	if config.IsSet(compName + ".disk-cache-timeout-sec") {
+		ac.diskTimeout = conf.DiskCacheTimeout
+	}
+
+	if ac.diskPath != "" && ac.diskTimeout == 0 {
+		log.Err("AttrCache::Configure : disk-cache-timeout-sec can not be 0 when disk-cache-path is set")
+		return fmt.Errorf("config error in %s [disk-cache-timeout-sec can not be 0]", ac.Name())
+	}
+
+	ac.inventoryPath = conf.InventoryPath
+	if !strings.HasPrefix(ac.inventoryPath, "https://") && !strings.HasPrefix(ac.inventoryPath, "http://") {
+		ac.inventoryPath = common.ExpandPath(ac.inventoryPath)
+	}
+
+	if ac.inventoryPath != "" && ac.diskPath == "" {
+		log.Err("AttrCache::Configure : inventory-path requires disk-cache-path to be set")
+		return fmt.Errorf("config error in %s [inventory-path requires disk-cache-path]", ac.Name())
+	}
+
+	log.Crit("AttrCache::Configure : cache-timeout %d, symlink %t, max-files %d, max-memory-mb %d, disk-cache-path %s, disk-cache-timeout %d, inventory %t",
+		ac.cacheTimeout, ac.noSymlinks, ac.maxFiles, conf.MaxMemoryMB, ac.diskPath, ac.diskTimeout, ac.inventoryPath != "")
 
 	return nil
 }
@@ -171,7 +262,104 @@ func (ac *AttrCache) OnConfigChange() {
 	_ = ac.Configure(true)
 }
 
+// SetPipelineHead : Keep the head of the pipeline to let the fuse frontend know of changes found in storage
+func (ac *AttrCache) SetPipelineHead(head internal.Component) {
+	ac.head = head
+}
+
 // Helper Methods
+// notifyKernel : Let the fuse frontend drop what kernel has cached for a path found changed in storage
+func (ac *AttrCache) notifyKernel(path string) {
+	if ac.head == nil {
+		return
+	}
+
+	log.Debug("AttrCache::notifyKernel : %s changed in storage", path)
+	err := ac.head.InvalidatePath(internal.InvalidatePathOptions{Name: path, KernelOnly: true})
+	if err != nil {
+		log.Warn("AttrCache::notifyKernel : Failed to invalidate %s [%s]", path, err.Error())
+	}
+}
+
+// addItem: add an item to the cache evicting the least recently used items to stay within limits, caller shall hold the write lock
+func (ac *AttrCache) addItem(path string, item *attrCacheItem) {
+	ac.lruLock.Lock()
+	defer ac.lruLock.Unlock()
+
+	if old, found := ac.cacheMap.get(path); found {
+		ac.removeItem(path, old)
+	}
+
+	// Path of the attributes is retained only if it differs from the path item is cached at
+	attrPath := item.altPath
+	if attrPath == "" {
+		attrPath = item.path()
+	}
+	item.altPath = ""
+	if attrPath != path {
+		item.altPath = attrPath
+	}
+
+	ac.cacheMap.set(path, item)
+	item.memSize = item.memoryUsage()
+	item.node = ac.lru.PushFront(item)
+	ac.memoryUse += item.memSize
+
+	for ac.cacheMap.len() > ac.maxFiles || (ac.maxMemory > 0 && ac.memoryInUse() > ac.maxMemory) {
+		node := ac.lru.Back()
+		if node == nil || node == item.node {
+			break
+		}
+
+		victim := node.Value.(*attrCacheItem)
+		log.Debug("AttrCache::addItem : evicting %s", victim.path())
+		ac.removeItem(victim.path(), victim)
+	}
+}
+
+// memoryInUse: memory held by the cached items and the directories interned for their paths
+func (ac *AttrCache) memoryInUse() int64 {
+	return ac.memoryUse + ac.cacheMap.dirMemory
+}
+
+// resizeItem: account for attributes of a cached item replaced in place
+func (ac *AttrCache) resizeItem(item *attrCacheItem) {
+	ac.lruLock.Lock()
+	defer ac.lruLock.Unlock()
+
+	if item.node == nil {
+		return
+	}
+
+	size := item.memoryUsage()
+	ac.memoryUse += size - item.memSize
+	item.memSize = size
+}
+
+// removeItem: remove an item from the cache, caller shall hold the write lock and lru lock
+func (ac *AttrCache) removeItem(path string, item *attrCacheItem) {
+	if item == nil {
+		return
+	}
+
+	if item.node != nil {
+		ac.lru.Remove(item.node)
+		item.node = nil
+		ac.memoryUse -= item.memSize
+	}
+	ac.cacheMap.remove(path)
+}
+
+// touchItem: mark the item as most recently used
+func (ac *AttrCache) touchItem(item *attrCacheItem) {
+	ac.lruLock.Lock()
+	defer ac.lruLock.Unlock()
+
+	if item.node != nil {
+		ac.lru.MoveToFront(item.node)
+	}
+}
+
 // deleteDirectory: recursively marks a directory deleted
 // The deleteDir method marks deleted instead of invalidating so that if a request came in for a non-existent previously cached
 // file/dir we can directly serve that it is non-existent
@@ -184,11 +372,9 @@ func (ac *AttrCache) deleteDirectory(path string, time time.Time) {
 	// Add a trailing / so that we only delete child paths under the directory and not paths that have the same prefix
 	prefix := internal.ExtendDirName(path)
 
-	for key, value := range ac.cacheMap {
-		if strings.HasPrefix(key, prefix) {
-			value.markDeleted(time)
-		}
-	}
+	ac.cacheMap.under(prefix, func(value *attrCacheItem) {
+		value.markDeleted(time)
+	})
 
 	// We need to delete the path itself since we only handle children above.
 	ac.deletePath(path, time)
@@ -197,7 +383,7 @@ func (ac *AttrCache) deleteDirectory(path string, time time.Time) {
 // deletePath: deletes a path
 func (ac *AttrCache) deletePath(path string, time time.Time) {
 	// Keys in the cache map do not contain trailing /, truncate the path before referencing a key in the map.
-	value, found := ac.cacheMap[internal.TruncateDirName(path)]
+	value, found := ac.cacheMap.get(internal.TruncateDirName(path))
 	if found {
 		value.markDeleted(time)
 	}
@@ -213,11 +399,9 @@ func (ac *AttrCache) invalidateDirectory(path string) {
 	// Add a trailing / so that we only invalidate child paths under the directory and not paths that have the same prefix
 	prefix := internal.ExtendDirName(path)
 
-	for key, value := range ac.cacheMap {
-		if strings.HasPrefix(key, prefix) {
-			value.invalidate()
-		}
-	}
+	ac.cacheMap.under(prefix, func(value *attrCacheItem) {
+		value.invalidate()
+	})
 
 	// We need to invalidate the path itself since we only handle children above.
 	ac.invalidatePath(path)
@@ -225,23 +409,25 @@ func (ac *AttrCache) invalidateDirectory(path string) {
 
 // Copies the attr to the given path.
 func (ac *AttrCache) updateCacheEntry(path string, attr *internal.ObjAttr) {
-	cacheEntry, found := ac.cacheMap[path]
+	cacheEntry, found := ac.cacheMap.get(path)
 	if found {
-		// Copy the attr
-		cacheEntry.attr = attr
 		// Update the path inside the attr
-		cacheEntry.attr.Path = path
+		attr.Path = path
+		// Copy the attr
+		cacheEntry.setAttr(attr)
 		// Update the Existence of the entry
 		cacheEntry.attrFlag.Set(AttrFlagExists)
 		// Refresh the cache entry
 		cacheEntry.cachedAt = time.Now()
+		// Attributes copied may not be the size of those replaced
+		ac.resizeItem(cacheEntry)
 	}
 }
 
 // invalidatePath: invalidates a path
 func (ac *AttrCache) invalidatePath(path string) {
 	// Keys in the cache map do not contain trailing /, truncate the path before referencing a key in the map.
-	value, found := ac.cacheMap[internal.TruncateDirName(path)]
+	value, found := ac.cacheMap.get(internal.TruncateDirName(path))
 	if found {
 		value.invalidate()
 	}
@@ -254,6 +440,7 @@ func (ac *AttrCache) CreateDir(options internal.CreateDirOptions) error {
 	err := ac.NextComponent().CreateDir(options)
 
 	if err == nil || err == syscall.EEXIST {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.invalidatePath(options.Name)
@@ -269,6 +456,7 @@ func (ac *AttrCache) DeleteDir(options internal.DeleteDirOptions) error {
 	err := ac.NextComponent().DeleteDir(options)
 
 	if err == nil {
+		ac.store.InvalidateTree(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.deleteDirectory(options.Name, deletionTime)
@@ -281,9 +469,16 @@ func (ac *AttrCache) DeleteDir(options internal.DeleteDirOptions) error {
 func (ac *AttrCache) ReadDir(options internal.ReadDirOptions) (pathList []*internal.ObjAttr, err error) {
 	log.Trace("AttrCache::ReadDir : %s", options.Name)
 
+	if list, found := ac.store.GetListing(options.Name); found {
+		log.Debug("AttrCache::ReadDir : %s served from disk cache", options.Name)
+		ac.cacheAttributes(list)
+		return list, nil
+	}
+
 	pathList, err = ac.NextComponent().ReadDir(options)
 	if err == nil {
 		ac.cacheAttributes(pathList)
+		_ = ac.store.PutPage(options.Name, "", pathList, "")
 	}
 
 	return pathList, err
@@ -293,9 +488,16 @@ func (ac *AttrCache) ReadDir(options internal.ReadDirOptions) (pathList []*inter
 func (ac *AttrCache) StreamDir(options internal.StreamDirOptions) ([]*internal.ObjAttr, string, error) {
 	log.Trace("AttrCache::StreamDir : %s", options.Name)
 
+	if list, next, found := ac.store.GetPage(options.Name, options.Token); found {
+		log.Debug("AttrCache::StreamDir : %s served from disk cache", options.Name)
+		ac.cacheAttributes(list)
+		return list, next, nil
+	}
+
 	pathList, token, err := ac.NextComponent().StreamDir(options)
 	if err == nil {
 		ac.cacheAttributes(pathList)
+		_ = ac.store.PutPage(options.Name, options.Token, pathList, token)
 	}
 
 	return pathList, token, err
@@ -310,13 +512,8 @@ func (ac *AttrCache) cacheAttributes(pathList []*internal.ObjAttr) {
 		currTime := time.Now()
 
 		for _, attr := range pathList {
-			if len(ac.cacheMap) > ac.maxFiles {
-				log.Debug("AttrCache::cacheAttributes : %s skipping adding path to attribute cache because it is full", pathList)
-				break
-			}
-
 			ac.cacheLock.Lock()
-			ac.cacheMap[internal.TruncateDirName(attr.Path)] = newAttrCacheItem(attr, true, currTime)
+			ac.addItem(internal.TruncateDirName(attr.Path), newAttrCacheItem(attr, true, currTime))
 			ac.cacheLock.Unlock()
 		}
 
@@ -332,6 +529,8 @@ func (ac *AttrCache) RenameDir(options internal.RenameDirOptions) error {
 	err := ac.NextComponent().RenameDir(options)
 
 	if err == nil {
+		ac.store.InvalidateTree(options.Src)
+		ac.store.InvalidateTree(options.Dst)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.deleteDirectory(options.Src, deletionTime)
@@ -350,6 +549,7 @@ func (ac *AttrCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 	h, err := ac.NextComponent().CreateFile(options)
 
 	if err == nil {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.invalidatePath(options.Name)
@@ -364,6 +564,7 @@ func (ac *AttrCache) DeleteFile(options internal.DeleteFileOptions) error {
 
 	err := ac.NextComponent().DeleteFile(options)
 	if err == nil {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.deletePath(options.Name, time.Now())
@@ -378,6 +579,8 @@ func (ac *AttrCache) RenameFile(options internal.RenameFileOptions) error {
 	srcAttr := options.SrcAttr
 	err := ac.NextComponent().RenameFile(options)
 	if err == nil {
+		ac.store.Invalidate(options.Src)
+		ac.store.Invalidate(options.Dst)
 		// Copy source attribute to destination.
 		// LMT of Source will be modified by next component if the copy is success.
 		ac.cacheLock.RLock()
@@ -406,6 +609,7 @@ func (ac *AttrCache) WriteFile(options internal.WriteFileOptions) (int, error) {
 
 	size, err := ac.NextComponent().WriteFile(options)
 	if err == nil {
+		ac.store.Invalidate(options.Handle.Path)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		// TODO: Could we just update the size and mod time of the file here? Or can other attributes change here?
@@ -420,11 +624,12 @@ func (ac *AttrCache) TruncateFile(options internal.TruncateFileOptions) error {
 
 	err := ac.NextComponent().TruncateFile(options)
 	if err == nil {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 
 		// no need to truncate the name of the file
-		value, found := ac.cacheMap[options.Name]
+		value, found := ac.cacheMap.get(options.Name)
 		if found && value.valid() && value.exists() {
 			value.setSize(options.Size)
 		}
@@ -455,6 +660,7 @@ func (ac *AttrCache) CopyFromFile(options internal.CopyFromFileOptions) error {
 
 	err = ac.NextComponent().CopyFromFile(options)
 	if err == nil {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		// TODO: Could we just update the size and mod time of the file here? Or can other attributes change here?
@@ -469,6 +675,7 @@ func (ac *AttrCache) SyncFile(options internal.SyncFileOptions) error {
 
 	err := ac.NextComponent().SyncFile(options)
 	if err == nil {
+		ac.store.Invalidate(options.Handle.Path)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.invalidatePath(options.Handle.Path)
@@ -481,6 +688,7 @@ func (ac *AttrCache) SyncDir(options internal.SyncDirOptions) error {
 
 	err := ac.NextComponent().SyncDir(options)
 	if err == nil {
+		ac.store.InvalidateTree(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.invalidateDirectory(options.Name)
@@ -494,11 +702,12 @@ func (ac *AttrCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr
 	truncatedPath := internal.TruncateDirName(options.Name)
 
 	ac.cacheLock.RLock()
-	value, found := ac.cacheMap[truncatedPath]
+	value, found := ac.cacheMap.get(truncatedPath)
 	ac.cacheLock.RUnlock()
 
 	// Try to serve the request from the attribute cache
 	if found && value.valid() && time.Since(value.cachedAt).Seconds() < float64(ac.cacheTimeout) {
+		ac.touchItem(value)
 		if value.isDeleted() {
 			log.Debug("AttrCache::GetAttr : %s served from cache", options.Name)
 			// no entry if path does not exist
@@ -509,22 +718,46 @@ func (ac *AttrCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr
 		}
 	}
 
+	// Try to serve the request from the listing of the parent directory persisted on disk
+	if attr, exists, ok := ac.store.Lookup(truncatedPath); ok {
+		log.Debug("AttrCache::GetAttr : %s served from disk cache", options.Name)
+
+		ac.cacheLock.Lock()
+		defer ac.cacheLock.Unlock()
+
+		if !exists {
+			ac.addItem(truncatedPath, newAttrCacheItem(&internal.ObjAttr{Path: truncatedPath}, false, time.Now()))
+			return &internal.ObjAttr{}, syscall.ENOENT
+		}
+
+		ac.addItem(truncatedPath, newAttrCacheItem(attr, true, time.Now()))
+		return attr, nil
+	}
+
 	// Get the attributes from next component and cache them
 	pathAttr, err := ac.NextComponent().GetAttr(options)
+	if err == nil {
+		// Drop the persisted listing if it no longer matches the storage
+		ac.store.Verify(pathAttr)
+	}
 
 	ac.cacheLock.Lock()
-	defer ac.cacheLock.Unlock()
+	changed := false
 
 	if err == nil {
 		// Retrieved attributes so cache them
-		if len(ac.cacheMap) < ac.maxFiles {
-			ac.cacheMap[truncatedPath] = newAttrCacheItem(pathAttr, true, time.Now())
-		} else {
-			log.Debug("AttrCache::GetAttr : %s skipping adding to attribute cache because it is full", options.Name)
-		}
+		changed = found && value.changed(pathAttr, true)
+		ac.addItem(truncatedPath, newAttrCacheItem(pathAttr, true, time.Now()))
 	} else if err == syscall.ENOENT {
 		// Path does not exist so cache a no-entry item
-		ac.cacheMap[truncatedPath] = newAttrCacheItem(&internal.ObjAttr{}, false, time.Now())
+		changed = found && value.changed(nil, false)
+		ac.addItem(truncatedPath, newAttrCacheItem(&internal.ObjAttr{Path: truncatedPath}, false, time.Now()))
+	}
+	ac.cacheLock.Unlock()
+
+	// Expired attributes no longer match the storage, so kernel may be serving stale data as well
+	if changed {
+		ac.notifyKernel(truncatedPath)
 	}
 
 	return pathAttr, err
@@ -537,6 +770,7 @@ func (ac *AttrCache) CreateLink(options internal.CreateLinkOptions) error {
 	err := ac.NextComponent().CreateLink(options)
 
 	if err == nil {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		ac.invalidatePath(options.Name)
@@ -551,6 +785,7 @@ func (ac *AttrCache) FlushFile(options internal.FlushFileOptions) error {
 	log.Trace("AttrCache::FlushFile : %s", options.Handle.Path)
 	err := ac.NextComponent().FlushFile(options)
 	if err == nil {
+		ac.store.Invalidate(options.Handle.Path)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 
@@ -566,10 +801,11 @@ func (ac *AttrCache) Chmod(options internal.ChmodOptions) error {
 	err := ac.NextComponent().Chmod(options)
 
 	if err == nil {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 
-		value, found := ac.cacheMap[internal.TruncateDirName(options.Name)]
+		value, found := ac.cacheMap.get(internal.TruncateDirName(options.Name))
 		if found && value.valid() && value.exists() {
 			value.setMode(options.Mode)
 		}
@@ -592,6 +828,7 @@ func (ac *AttrCache) CommitData(options internal.CommitDataOptions) error {
 	log.Trace("AttrCache::CommitData : %s", options.Name)
 	err := ac.NextComponent().CommitData(options)
 	if err == nil {
+		ac.store.Invalidate(options.Name)
 		ac.cacheLock.RLock()
 		defer ac.cacheLock.RUnlock()
 		// TODO: Could we just update the size, etag, modtime of the file here?
@@ -600,6 +837,36 @@ func (ac *AttrCache) CommitData(options internal.CommitDataOptions) error {
 	return err
 }
 
+// InvalidatePath : Drop the cached attributes of a path changed by another client, unless they already match the change
+func (ac *AttrCache) InvalidatePath(options internal.InvalidatePathOptions) error {
+	log.Trace("AttrCache::InvalidatePath : %s", options.Name)
+
+	name := internal.TruncateDirName(options.Name)
+	if options.IsDir {
+		ac.store.InvalidateTree(name)
+	} else {
+		ac.store.Verify(&internal.ObjAttr{Path: name, ETag: options.ETag})
+	}
+
+	ac.cacheLock.RLock()
+	value, found := ac.cacheMap.get(name)
+	if options.IsDir {
+		ac.invalidateDirectory(name)
+	} else if found && !(options.ETag != "" && value.exists() && value.etag == options.ETag) {
+		value.invalidate()
+	}
+
+	// A blob in a new virtual directory makes the directories above it exist
+	for dir := filepath.Dir(name); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+		if value, found := ac.cacheMap.get(dir); found && (!value.exists() || value.isDeleted()) {
+			value.invalidate()
+		}
+	}
+	ac.cacheLock.RUnlock()
+
+	return ac.NextComponent().InvalidatePath(options)
+}
+
 // ------------------------- Factory -------------------------------------------
 
 // Pipeline will call this method to create your object, initialize your variables here
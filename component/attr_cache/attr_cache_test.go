@@ -94,32 +94,47 @@ func getPathAttr(path string, size int64, mode os.FileMode, metadata bool) *inte
 	}
 }
 
+// paths : Paths of all items in the cache map
+func (m *attrMap) paths() []string {
+	paths := make([]string, 0, len(m.items))
+	for _, item := range m.items {
+		paths = append(paths, item.path())
+	}
+	return paths
+}
+
+// item : Item cached at the path, nil if there is none
+func (m *attrMap) item(path string) *attrCacheItem {
+	item, _ := m.get(path)
+	return item
+}
+
 func addPathToCache(assert *assert.Assertions, attrCache *AttrCache, path string, metadata bool) {
 	path = internal.TruncateDirName(path)
-	attrCache.cacheMap[path] = newAttrCacheItem(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), metadata), true, time.Now())
-	assert.Contains(attrCache.cacheMap, path)
+	attrCache.cacheMap.set(path, newAttrCacheItem(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), metadata), true, time.Now()))
+	assert.Contains(attrCache.cacheMap.paths(), path)
 }
 
 func assertDeleted(suite *attrCacheTestSuite, path string) {
-	suite.assert.Contains(suite.attrCache.cacheMap, path)
-	suite.assert.EqualValues(suite.attrCache.cacheMap[path].attr, &internal.ObjAttr{})
-	suite.assert.True(suite.attrCache.cacheMap[path].valid())
-	suite.assert.False(suite.attrCache.cacheMap[path].exists())
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), path)
+	suite.assert.EqualValues(suite.attrCache.cacheMap.item(path).getAttr(), &internal.ObjAttr{})
+	suite.assert.True(suite.attrCache.cacheMap.item(path).valid())
+	suite.assert.False(suite.attrCache.cacheMap.item(path).exists())
 }
 
 func assertInvalid(suite *attrCacheTestSuite, path string) {
-	suite.assert.Contains(suite.attrCache.cacheMap, path)
-	suite.assert.EqualValues(suite.attrCache.cacheMap[path].attr, &internal.ObjAttr{})
-	suite.assert.False(suite.attrCache.cacheMap[path].valid())
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), path)
+	suite.assert.EqualValues(suite.attrCache.cacheMap.item(path).getAttr(), &internal.ObjAttr{})
+	suite.assert.False(suite.attrCache.cacheMap.item(path).valid())
 }
 
 func assertUntouched(suite *attrCacheTestSuite, path string) {
-	suite.assert.Contains(suite.attrCache.cacheMap, path)
-	suite.assert.NotEqualValues(suite.attrCache.cacheMap[path].attr, &internal.ObjAttr{})
-	suite.assert.EqualValues(suite.attrCache.cacheMap[path].attr.Size, defaultSize)
-	suite.assert.EqualValues(suite.attrCache.cacheMap[path].attr.Mode, defaultMode)
-	suite.assert.True(suite.attrCache.cacheMap[path].valid())
-	suite.assert.True(suite.attrCache.cacheMap[path].exists())
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), path)
+	suite.assert.NotEqualValues(suite.attrCache.cacheMap.item(path).getAttr(), &internal.ObjAttr{})
+	suite.assert.EqualValues(suite.attrCache.cacheMap.item(path).getAttr().Size, defaultSize)
+	suite.assert.EqualValues(suite.attrCache.cacheMap.item(path).getAttr().Mode, defaultMode)
+	suite.assert.True(suite.attrCache.cacheMap.item(path).valid())
+	suite.assert.True(suite.attrCache.cacheMap.item(path).exists())
 }
 
 // This method is used when we transfer the attributes from the src to dst, and mark src as invalid
@@ -130,8 +145,8 @@ func assertAttributesTransferred(suite *attrCacheTestSuite, srcAttr *internal.Ob
 	suite.assert.EqualValues(srcAttr.Atime, dstAttr.Atime)
 	suite.assert.EqualValues(srcAttr.Mtime, dstAttr.Mtime)
 	suite.assert.EqualValues(srcAttr.Ctime, dstAttr.Ctime)
-	suite.assert.True(suite.attrCache.cacheMap[dstAttr.Path].exists())
-	suite.assert.True(suite.attrCache.cacheMap[dstAttr.Path].valid())
+	suite.assert.True(suite.attrCache.cacheMap.item(dstAttr.Path).exists())
+	suite.assert.True(suite.attrCache.cacheMap.item(dstAttr.Path).valid())
 }
 
 // If next component changes the times of the attribute.
@@ -252,6 +267,327 @@ func (suite *attrCacheTestSuite) TestConfigMaxFiles() {
 	suite.assert.EqualValues(suite.attrCache.maxFiles, maxFiles)
 }
 
+// Tests that max files is a strict limit and least recently used paths are evicted first
+func (suite *attrCacheTestSuite) TestMaxFilesEviction() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default attr cache generated
+	config := "attr_cache:\n  timeout-sec: 120\n  max-files: 3"
+	suite.setupTestHelper(config) // setup a new attr cache with a custom config (clean up will occur after the test as usual)
+
+	for _, path := range []string{"a", "b", "c"} {
+		suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: path}).Return(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), false), nil)
+		_, err := suite.attrCache.GetAttr(internal.GetAttrOptions{Name: path})
+		suite.assert.NoError(err)
+	}
+
+	// Serving a from cache makes b the least recently used path
+	_, err := suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "a"})
+	suite.assert.NoError(err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "d"}).Return(nil, syscall.ENOENT)
+	_, err = suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "d"})
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	suite.assert.Len(suite.attrCache.cacheMap.paths(), 3)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), "b")
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), "a")
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), "c")
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), "d")
+
+	// Listing evicts older paths as well
+	aAttr := generateNestedPathAttr("e", defaultSize, fs.FileMode(defaultMode))
+	suite.mock.EXPECT().ReadDir(gomock.Any()).Return(aAttr, nil)
+	_, err = suite.attrCache.ReadDir(internal.ReadDirOptions{Name: "e"})
+	suite.assert.NoError(err)
+	suite.assert.Len(suite.attrCache.cacheMap.paths(), 3)
+	suite.assert.Equal(3, suite.attrCache.lru.Len())
+}
+
+// Tests memory limit of the cache
+func (suite *attrCacheTestSuite) TestMaxMemoryEviction() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default attr cache generated
+	config := "attr_cache:\n  timeout-sec: 120\n  max-memory-mb: 1"
+	suite.setupTestHelper(config) // setup a new attr cache with a custom config (clean up will occur after the test as usual)
+	suite.assert.EqualValues(MB, suite.attrCache.maxMemory)
+
+	value := strings.Repeat("x", 100*1024)
+	for i := 0; i < 20; i++ {
+		path := fmt.Sprintf("file%d", i)
+		attr := getPathAttr(path, defaultSize, fs.FileMode(defaultMode), true)
+		attr.Metadata = map[string]*string{"key": &value}
+		suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: path}).Return(attr, nil)
+		_, err := suite.attrCache.GetAttr(internal.GetAttrOptions{Name: path})
+		suite.assert.NoError(err)
+		suite.assert.LessOrEqual(suite.attrCache.memoryInUse(), suite.attrCache.maxMemory)
+	}
+
+	suite.assert.LessOrEqual(suite.attrCache.cacheMap.len(), 10)
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), "file19")
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), "file0")
+
+	// Memory is given back when paths are evicted
+	suite.attrCache.cacheLock.Lock()
+	for i := 0; i < 20; i++ {
+		path := fmt.Sprintf("small%d", i)
+		suite.attrCache.addItem(path, newAttrCacheItem(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), false), true, time.Now()))
+	}
+	suite.attrCache.cacheLock.Unlock()
+	suite.assert.Less(suite.attrCache.memoryInUse(), int64(MB))
+}
+
+// Tests memory accounted for a cached item follows attributes copied over it
+func (suite *attrCacheTestSuite) TestRenameFileMemoryUse() {
+	defer suite.cleanupTest()
+	src := "a"
+	dst := "b"
+
+	suite.attrCache.cacheLock.Lock()
+	for _, path := range []string{src, dst} {
+		suite.attrCache.addItem(path, newAttrCacheItem(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), false), true, time.Now()))
+	}
+	suite.attrCache.cacheLock.Unlock()
+	before := suite.attrCache.memoryUse
+
+	value := strings.Repeat("x", 1024)
+	options := internal.RenameFileOptions{Src: src, Dst: dst, SrcAttr: suite.attrCache.cacheMap.item(src).getAttr()}
+	options.SrcAttr.Metadata = map[string]*string{"key": &value}
+	suite.mock.EXPECT().RenameFile(options).Return(nil)
+	suite.assert.NoError(suite.attrCache.RenameFile(options))
+
+	item := suite.attrCache.cacheMap.item(dst)
+	suite.assert.Equal(item.memoryUsage(), item.memSize)
+	suite.assert.Greater(suite.attrCache.memoryUse, before+1024)
+
+	// Memory is given back in full when the item goes away
+	suite.attrCache.cacheLock.Lock()
+	suite.attrCache.lruLock.Lock()
+	for _, path := range suite.attrCache.cacheMap.paths() {
+		suite.attrCache.removeItem(path, suite.attrCache.cacheMap.item(path))
+	}
+	suite.attrCache.lruLock.Unlock()
+	suite.attrCache.cacheLock.Unlock()
+	suite.assert.EqualValues(0, suite.attrCache.memoryInUse())
+}
+
+// Tests directories of cached paths are kept once and accounted while any item refers to them
+func (suite *attrCacheTestSuite) TestInternedPaths() {
+	defer suite.cleanupTest()
+
+	suite.attrCache.cacheLock.Lock()
+	for _, path := range []string{"dir/sub/a", "dir/sub/b", "dir/c", "d"} {
+		suite.attrCache.addItem(path, newAttrCacheItem(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), false), true, time.Now()))
+	}
+	suite.attrCache.cacheLock.Unlock()
+
+	a, b := suite.attrCache.cacheMap.item("dir/sub/a"), suite.attrCache.cacheMap.item("dir/sub/b")
+	suite.assert.Same(a.key.dir, b.key.dir)
+	suite.assert.Equal("a", a.key.name)
+	suite.assert.Empty(a.altPath)
+	suite.assert.Equal("dir/sub/a", a.getAttr().Path)
+	suite.assert.Equal("a", a.getAttr().Name)
+	suite.assert.Len(suite.attrCache.cacheMap.dirs, 3)
+	suite.assert.EqualValues(3*dirOverhead+int64(len("dir/sub")+len("dir")), suite.attrCache.cacheMap.dirMemory)
+
+	// Children of a directory are found through the interned directories
+	suite.attrCache.cacheLock.Lock()
+	suite.attrCache.deleteDirectory("dir", time.Now())
+	suite.attrCache.cacheLock.Unlock()
+	suite.assert.True(a.isDeleted())
+	suite.assert.True(suite.attrCache.cacheMap.item("dir/c").isDeleted())
+	suite.assert.False(suite.attrCache.cacheMap.item("d").isDeleted())
+
+	// Directory is released along with the last item under it
+	suite.attrCache.cacheLock.Lock()
+	suite.attrCache.lruLock.Lock()
+	suite.attrCache.removeItem("dir/sub/a", a)
+	suite.assert.Len(suite.attrCache.cacheMap.dirs, 3)
+	suite.attrCache.removeItem("dir/sub/b", b)
+	suite.attrCache.lruLock.Unlock()
+	suite.attrCache.cacheLock.Unlock()
+	suite.assert.Len(suite.attrCache.cacheMap.dirs, 2)
+	suite.assert.EqualValues(2*dirOverhead+int64(len("dir")), suite.attrCache.cacheMap.dirMemory)
+
+	// Path of attributes listed with a trailing / is served as it was listed
+	suite.attrCache.cacheLock.Lock()
+	suite.attrCache.addItem("e", newAttrCacheItem(&internal.ObjAttr{Path: "e/", Flags: internal.NewDirBitMap()}, true, time.Now()))
+	suite.attrCache.cacheLock.Unlock()
+	suite.assert.Equal("e/", suite.attrCache.cacheMap.item("e").getAttr().Path)
+}
+
+// Tests metadata is packed into a single string and unpacked as it was
+func (suite *attrCacheTestSuite) TestEncodeMetadata() {
+	value := strings.Repeat("v", 300)
+	empty := ""
+	metadata := map[string]*string{"key": &value, "empty": &empty, "none": nil}
+
+	encoded := encodeMetadata(metadata)
+	suite.assert.Less(len(encoded), len(value)+32)
+	suite.assert.Equal(metadata, decodeMetadata(encoded))
+
+	suite.assert.Empty(encodeMetadata(nil))
+	suite.assert.Empty(encodeMetadata(map[string]*string{}))
+	suite.assert.Nil(decodeMetadata(""))
+}
+
+// Tests attributes are kept in compact form and served back unchanged
+func (suite *attrCacheTestSuite) TestCompactItem() {
+	defer suite.cleanupTest()
+	value := "value"
+	attr := &internal.ObjAttr{
+		Path:     "a/b/c",
+		Name:     "c",
+		Size:     1234,
+		Mode:     0644,
+		Mtime:    time.Unix(1700000000, 5),
+		Atime:    time.Unix(1700000001, 0),
+		Ctime:    time.Unix(1700000002, 0),
+		Flags:    internal.NewFileBitMap(),
+		ETag:     "etag",
+		MD5:      []byte{1, 2, 3},
+		Metadata: map[string]*string{"key": &value},
+	}
+
+	item := newAttrCacheItem(attr, true, time.Now())
+	served := item.getAttr()
+	suite.assert.Equal(attr.Path, served.Path)
+	suite.assert.Equal(attr.Name, served.Name)
+	suite.assert.Equal(attr.Size, served.Size)
+	suite.assert.Equal(attr.Mode, served.Mode)
+	suite.assert.True(attr.Mtime.Equal(served.Mtime))
+	suite.assert.True(attr.Atime.Equal(served.Atime))
+	suite.assert.True(attr.Ctime.Equal(served.Ctime))
+	suite.assert.True(served.Crtime.IsZero())
+	suite.assert.Equal(attr.Flags, served.Flags)
+	suite.assert.Equal(attr.ETag, served.ETag)
+	suite.assert.Equal(attr.MD5, served.MD5)
+	suite.assert.Equal(attr.Metadata, served.Metadata)
+
+	// Empty metadata is not retained
+	attr.Metadata = map[string]*string{}
+	item = newAttrCacheItem(attr, true, time.Now())
+	suite.assert.Empty(item.metadata)
+
+	item.invalidate()
+	suite.assert.EqualValues(&internal.ObjAttr{}, item.getAttr())
+	suite.assert.Equal("a/b/c", item.path())
+}
+
+// Tests directory invalidation when some of its children were evicted
+func (suite *attrCacheTestSuite) TestInvalidateDirectoryAfterEviction() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default attr cache generated
+	config := "attr_cache:\n  timeout-sec: 120\n  max-files: 2"
+	suite.setupTestHelper(config) // setup a new attr cache with a custom config (clean up will occur after the test as usual)
+
+	for _, path := range []string{"dir/a", "dir/b", "dir/c"} {
+		suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: path}).Return(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), false), nil)
+		_, err := suite.attrCache.GetAttr(internal.GetAttrOptions{Name: path})
+		suite.assert.NoError(err)
+	}
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), "dir/a")
+
+	suite.mock.EXPECT().DeleteDir(internal.DeleteDirOptions{Name: "dir"}).Return(nil)
+	err := suite.attrCache.DeleteDir(internal.DeleteDirOptions{Name: "dir"})
+	suite.assert.NoError(err)
+	assertDeleted(suite, "dir/b")
+	assertDeleted(suite, "dir/c")
+
+	// Evicted child is looked up again instead of being served stale
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dir/a"}).Return(nil, syscall.ENOENT)
+	_, err = suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/a"})
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+// Tests listings persisted on disk are served after a remount
+func (suite *attrCacheTestSuite) TestDiskCache() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default attr cache generated
+	config := fmt.Sprintf("attr_cache:\n  timeout-sec: 120\n  disk-cache-path: %s", suite.T().TempDir())
+	suite.setupTestHelper(config) // setup a new attr cache with a custom config (clean up will occur after the test as usual)
+	suite.assert.NotNil(suite.attrCache.store)
+
+	first := []*internal.ObjAttr{getPathAttr("dir/a", defaultSize, fs.FileMode(defaultMode), false), getPathAttr("dir/b", defaultSize, fs.FileMode(defaultMode), false)}
+	second := []*internal.ObjAttr{getPathAttr("dir/c", defaultSize, fs.FileMode(defaultMode), false)}
+	suite.mock.EXPECT().StreamDir(internal.StreamDirOptions{Name: "dir"}).Return(first, "next", nil)
+	suite.mock.EXPECT().StreamDir(internal.StreamDirOptions{Name: "dir", Token: "next"}).Return(second, "", nil)
+
+	list, token, err := suite.attrCache.StreamDir(internal.StreamDirOptions{Name: "dir"})
+	suite.assert.NoError(err)
+	suite.assert.Equal("next", token)
+	suite.assert.Len(list, 2)
+	_, _, err = suite.attrCache.StreamDir(internal.StreamDirOptions{Name: "dir", Token: "next"})
+	suite.assert.NoError(err)
+
+	// Remount, nothing below is served by the next component
+	suite.cleanupTest()
+	suite.setupTestHelper(config)
+	suite.assert.Empty(suite.attrCache.cacheMap.paths())
+
+	list, token, err = suite.attrCache.StreamDir(internal.StreamDirOptions{Name: "dir"})
+	suite.assert.NoError(err)
+	suite.assert.Equal("next", token)
+	suite.assert.Len(list, 2)
+	suite.assert.Equal("dir/a", list[0].Path)
+
+	suite.attrCache.cacheMap = newAttrMap()
+	attr, err := suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/c"})
+	suite.assert.NoError(err)
+	suite.assert.Equal("dir/c", attr.Path)
+
+	_, err = suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/d"})
+	suite.assert.Equal(syscall.ENOENT, err)
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), "dir/d")
+
+	pathList, err := suite.attrCache.ReadDir(internal.ReadDirOptions{Name: "dir"})
+	suite.assert.NoError(err)
+	suite.assert.Len(pathList, 3)
+
+	// Changes through the mount drop the persisted listing
+	suite.mock.EXPECT().DeleteFile(internal.DeleteFileOptions{Name: "dir/a"}).Return(nil)
+	err = suite.attrCache.DeleteFile(internal.DeleteFileOptions{Name: "dir/a"})
+	suite.assert.NoError(err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dir/e"}).Return(nil, syscall.ENOENT)
+	_, err = suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/e"})
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+// Tests a persisted listing is dropped when storage returns a different etag
+func (suite *attrCacheTestSuite) TestDiskCacheVerify() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default attr cache generated
+	config := fmt.Sprintf("attr_cache:\n  timeout-sec: 120\n  disk-cache-path: %s", suite.T().TempDir())
+	suite.setupTestHelper(config) // setup a new attr cache with a custom config (clean up will occur after the test as usual)
+
+	attr := getPathAttr("dir/a", defaultSize, fs.FileMode(defaultMode), false)
+	attr.ETag = "old"
+	suite.mock.EXPECT().StreamDir(internal.StreamDirOptions{Name: "dir"}).Return([]*internal.ObjAttr{attr}, "next", nil)
+	_, _, err := suite.attrCache.StreamDir(internal.StreamDirOptions{Name: "dir"})
+	suite.assert.NoError(err)
+
+	changed := getPathAttr("dir/a", defaultSize, fs.FileMode(defaultMode), false)
+	changed.ETag = "new"
+	suite.attrCache.cacheMap = newAttrMap()
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dir/a"}).Return(changed, nil)
+	_, err = suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/a"})
+	suite.assert.NoError(err)
+
+	suite.mock.EXPECT().StreamDir(internal.StreamDirOptions{Name: "dir"}).Return([]*internal.ObjAttr{changed}, "", nil)
+	list, _, err := suite.attrCache.StreamDir(internal.StreamDirOptions{Name: "dir"})
+	suite.assert.NoError(err)
+	suite.assert.Equal("new", list[0].ETag)
+}
+
+// Tests disk cache timeout can not be zero
+func (suite *attrCacheTestSuite) TestDiskCacheConfigError() {
+	defer suite.cleanupTest()
+	_ = config.ReadConfigFromReader(strings.NewReader("attr_cache:\n  disk-cache-path: /tmp\n  disk-cache-timeout-sec: 0"))
+	attrCache := NewAttrCacheComponent()
+	err := attrCache.Configure(true)
+	suite.assert.Error(err)
+}
+
 func (suite *attrCacheTestSuite) TestConfigZero() {
 	defer suite.cleanupTest()
 	suite.cleanupTest() // clean up the default attr cache generated
@@ -282,7 +618,7 @@ func (suite *attrCacheTestSuite) TestCreateDir() {
 
 			err := suite.attrCache.CreateDir(options)
 			suite.assert.NotNil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Success
 			// Entry Does Not Already Exist
@@ -290,7 +626,7 @@ func (suite *attrCacheTestSuite) TestCreateDir() {
 
 			err = suite.attrCache.CreateDir(options)
 			suite.assert.Nil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Entry Already Exists
 			addPathToCache(suite.assert, suite.attrCache, path, false)
@@ -321,7 +657,7 @@ func (suite *attrCacheTestSuite) TestDeleteDir() {
 
 			err := suite.attrCache.DeleteDir(options)
 			suite.assert.NotNil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Success
 			// Entry Does Not Already Exist
@@ -329,7 +665,7 @@ func (suite *attrCacheTestSuite) TestDeleteDir() {
 
 			err = suite.attrCache.DeleteDir(options)
 			suite.assert.Nil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Entry Already Exists
 			a, ab, ac := addDirectoryToCache(suite.assert, suite.attrCache, path, false)
@@ -372,20 +708,20 @@ func (suite *attrCacheTestSuite) TestReadDirDoesNotExist() {
 			// Entries Do Not Already Exist
 			suite.mock.EXPECT().ReadDir(options).Return(aAttr, nil)
 
-			suite.assert.Empty(suite.attrCache.cacheMap) // cacheMap should be empty before call
+			suite.assert.Empty(suite.attrCache.cacheMap.paths()) // cacheMap should be empty before call
 			returnedAttr, err := suite.attrCache.ReadDir(options)
 			suite.assert.Nil(err)
 			suite.assert.Equal(aAttr, returnedAttr)
-			suite.assert.Equal(len(suite.attrCache.cacheMap), len(aAttr))
+			suite.assert.Equal(suite.attrCache.cacheMap.len(), len(aAttr))
 
 			// Entries should now be in the cache
 			for _, p := range aAttr {
-				suite.assert.Contains(suite.attrCache.cacheMap, p.Path)
-				suite.assert.NotEqualValues(suite.attrCache.cacheMap[p.Path].attr, &internal.ObjAttr{})
-				suite.assert.EqualValues(suite.attrCache.cacheMap[p.Path].attr.Size, size) // new size should be set
-				suite.assert.EqualValues(suite.attrCache.cacheMap[p.Path].attr.Mode, mode) // new mode should be set
-				suite.assert.True(suite.attrCache.cacheMap[p.Path].valid())
-				suite.assert.True(suite.attrCache.cacheMap[p.Path].exists())
+				suite.assert.Contains(suite.attrCache.cacheMap.paths(), p.Path)
+				suite.assert.NotEqualValues(suite.attrCache.cacheMap.item(p.Path).getAttr(), &internal.ObjAttr{})
+				suite.assert.EqualValues(suite.attrCache.cacheMap.item(p.Path).getAttr().Size, size) // new size should be set
+				suite.assert.EqualValues(suite.attrCache.cacheMap.item(p.Path).getAttr().Mode, mode) // new mode should be set
+				suite.assert.True(suite.attrCache.cacheMap.item(p.Path).valid())
+				suite.assert.True(suite.attrCache.cacheMap.item(p.Path).exists())
 			}
 		})
 	}
@@ -410,7 +746,7 @@ func (suite *attrCacheTestSuite) TestReadDirExists() {
 			// Entries Already Exist
 			a, ab, ac := addDirectoryToCache(suite.assert, suite.attrCache, path, false)
 
-			suite.assert.NotEmpty(suite.attrCache.cacheMap) // cacheMap should NOT be empty before read dir call and values should be untouched
+			suite.assert.NotEmpty(suite.attrCache.cacheMap.paths()) // cacheMap should NOT be empty before read dir call and values should be untouched
 			for _, p := range aAttr {
 				assertUntouched(suite, p.Path)
 			}
@@ -422,12 +758,12 @@ func (suite *attrCacheTestSuite) TestReadDirExists() {
 			// a paths should now be updated in the cache
 			for p := a.Front(); p != nil; p = p.Next() {
 				pString := p.Value.(string)
-				suite.assert.Contains(suite.attrCache.cacheMap, pString)
-				suite.assert.NotEqualValues(suite.attrCache.cacheMap[pString].attr, &internal.ObjAttr{})
-				suite.assert.EqualValues(suite.attrCache.cacheMap[pString].attr.Size, size) // new size should be set
-				suite.assert.EqualValues(suite.attrCache.cacheMap[pString].attr.Mode, mode) // new mode should be set
-				suite.assert.True(suite.attrCache.cacheMap[pString].valid())
-				suite.assert.True(suite.attrCache.cacheMap[pString].exists())
+				suite.assert.Contains(suite.attrCache.cacheMap.paths(), pString)
+				suite.assert.NotEqualValues(suite.attrCache.cacheMap.item(pString).getAttr(), &internal.ObjAttr{})
+				suite.assert.EqualValues(suite.attrCache.cacheMap.item(pString).getAttr().Size, size) // new size should be set
+				suite.assert.EqualValues(suite.attrCache.cacheMap.item(pString).getAttr().Mode, mode) // new mode should be set
+				suite.assert.True(suite.attrCache.cacheMap.item(pString).valid())
+				suite.assert.True(suite.attrCache.cacheMap.item(pString).exists())
 			}
 
 			// ab and ac paths should be untouched
@@ -456,7 +792,7 @@ func (suite *attrCacheTestSuite) TestReadDirError() {
 
 			_, err := suite.attrCache.ReadDir(options)
 			suite.assert.NotNil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 		})
 	}
 }
@@ -488,8 +824,8 @@ func (suite *attrCacheTestSuite) TestRenameDir() {
 
 			err := suite.attrCache.RenameDir(options)
 			suite.assert.NotNil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedSrc)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedDst)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedSrc)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedDst)
 
 			// Success
 			// Entry Does Not Already Exist
@@ -497,8 +833,8 @@ func (suite *attrCacheTestSuite) TestRenameDir() {
 
 			err = suite.attrCache.RenameDir(options)
 			suite.assert.Nil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedSrc)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedDst)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedSrc)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedDst)
 
 			// Entry Already Exists
 			a, ab, ac := addDirectoryToCache(suite.assert, suite.attrCache, input.src, false)
@@ -538,7 +874,7 @@ func (suite *attrCacheTestSuite) TestCreateFile() {
 
 	_, err := suite.attrCache.CreateFile(options)
 	suite.assert.NotNil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Success
 	// Entry Does Not Already Exist
@@ -546,7 +882,7 @@ func (suite *attrCacheTestSuite) TestCreateFile() {
 
 	_, err = suite.attrCache.CreateFile(options)
 	suite.assert.Nil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Entry Already Exists
 	addPathToCache(suite.assert, suite.attrCache, path, false)
@@ -569,7 +905,7 @@ func (suite *attrCacheTestSuite) TestDeleteFile() {
 
 	err := suite.attrCache.DeleteFile(options)
 	suite.assert.NotNil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Success
 	// Entry Does Not Already Exist
@@ -577,7 +913,7 @@ func (suite *attrCacheTestSuite) TestDeleteFile() {
 
 	err = suite.attrCache.DeleteFile(options)
 	suite.assert.Nil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Entry Already Exists
 	addPathToCache(suite.assert, suite.attrCache, path, false)
@@ -603,7 +939,7 @@ func (suite *attrCacheTestSuite) TestSyncFile() {
 
 	err := suite.attrCache.SyncFile(options)
 	suite.assert.NotNil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Success
 	// Entry Does Not Already Exist
@@ -611,7 +947,7 @@ func (suite *attrCacheTestSuite) TestSyncFile() {
 
 	err = suite.attrCache.SyncFile(options)
 	suite.assert.Nil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Entry Already Exists
 	addPathToCache(suite.assert, suite.attrCache, path, false)
@@ -639,7 +975,7 @@ func (suite *attrCacheTestSuite) TestSyncDir() {
 
 			err := suite.attrCache.SyncDir(options)
 			suite.assert.NotNil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Success
 			// Entry Does Not Already Exist
@@ -647,7 +983,7 @@ func (suite *attrCacheTestSuite) TestSyncDir() {
 
 			err = suite.attrCache.SyncDir(options)
 			suite.assert.Nil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Entry Already Exists
 			a, ab, ac := addDirectoryToCache(suite.assert, suite.attrCache, path, false)
@@ -683,8 +1019,8 @@ func (suite *attrCacheTestSuite) TestRenameFile() {
 
 	err := suite.attrCache.RenameFile(options)
 	suite.assert.NotNil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, src)
-	suite.assert.NotContains(suite.attrCache.cacheMap, dst)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), src)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), dst)
 
 	// Success
 	// Entry Does Not Already Exist
@@ -692,16 +1028,16 @@ func (suite *attrCacheTestSuite) TestRenameFile() {
 
 	err = suite.attrCache.RenameFile(options)
 	suite.assert.Nil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, src)
-	suite.assert.NotContains(suite.attrCache.cacheMap, dst)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), src)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), dst)
 
 	// Src, Dst Entry Already Exists
 	addPathToCache(suite.assert, suite.attrCache, src, false)
 	addPathToCache(suite.assert, suite.attrCache, dst, false)
-	options.SrcAttr = suite.attrCache.cacheMap[src].attr
+	options.SrcAttr = suite.attrCache.cacheMap.item(src).getAttr()
 	options.SrcAttr.Size = 1
 	options.SrcAttr.Mode = 2
-	options.DstAttr = suite.attrCache.cacheMap[dst].attr
+	options.DstAttr = suite.attrCache.cacheMap.item(dst).getAttr()
 	options.DstAttr.Size = 3
 	options.DstAttr.Mode = 4
 	srcAttrCopy := *options.SrcAttr
@@ -710,7 +1046,7 @@ func (suite *attrCacheTestSuite) TestRenameFile() {
 	err = suite.attrCache.RenameFile(options)
 	suite.assert.Nil(err)
 	assertDeleted(suite, src)
-	modifiedDstAttr := suite.attrCache.cacheMap[dst].attr
+	modifiedDstAttr := suite.attrCache.cacheMap.item(dst).getAttr()
 	assertSrcAttributeTimeChanged(suite, options.SrcAttr, srcAttrCopy)
 	// Check the attributes of the dst are same as the src.
 	assertAttributesTransferred(suite, options.SrcAttr, modifiedDstAttr)
@@ -718,16 +1054,16 @@ func (suite *attrCacheTestSuite) TestRenameFile() {
 	// Src Entry Exist and Dst Entry Don't Exist
 	addPathToCache(suite.assert, suite.attrCache, src, false)
 	// Add negative entry to cache for Dst
-	suite.attrCache.cacheMap[dst] = newAttrCacheItem(&internal.ObjAttr{}, false, time.Now())
-	options.SrcAttr = suite.attrCache.cacheMap[src].attr
-	options.DstAttr = suite.attrCache.cacheMap[dst].attr
+	suite.attrCache.cacheMap.set(dst, newAttrCacheItem(&internal.ObjAttr{}, false, time.Now()))
+	options.SrcAttr = suite.attrCache.cacheMap.item(src).getAttr()
+	options.DstAttr = suite.attrCache.cacheMap.item(dst).getAttr()
 	options.SrcAttr.Size = 1
 	options.SrcAttr.Mode = 2
 	suite.mock.EXPECT().RenameFile(options).Return(nil)
 	err = suite.attrCache.RenameFile(options)
 	suite.assert.Nil(err)
 	assertDeleted(suite, src)
-	modifiedDstAttr = suite.attrCache.cacheMap[dst].attr
+	modifiedDstAttr = suite.attrCache.cacheMap.item(dst).getAttr()
 	assertSrcAttributeTimeChanged(suite, options.SrcAttr, srcAttrCopy)
 	assertAttributesTransferred(suite, options.SrcAttr, modifiedDstAttr)
 }
@@ -748,7 +1084,7 @@ func (suite *attrCacheTestSuite) TestWriteFileError() {
 
 	_, err := suite.attrCache.WriteFile(options)
 	suite.assert.NotNil(err)
-	suite.assert.Contains(suite.attrCache.cacheMap, path) // GetAttr call will add this to the cache
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), path) // GetAttr call will add this to the cache
 }
 
 func (suite *attrCacheTestSuite) TestWriteFileDoesNotExist() {
@@ -766,7 +1102,7 @@ func (suite *attrCacheTestSuite) TestWriteFileDoesNotExist() {
 
 	_, err := suite.attrCache.WriteFile(options)
 	suite.assert.Nil(err)
-	suite.assert.Contains(suite.attrCache.cacheMap, path) // GetAttr call will add this to the cache
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), path) // GetAttr call will add this to the cache
 }
 
 func (suite *attrCacheTestSuite) TestWriteFileExists() {
@@ -799,7 +1135,7 @@ func (suite *attrCacheTestSuite) TestTruncateFile() {
 
 	err := suite.attrCache.TruncateFile(options)
 	suite.assert.NotNil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Success
 	// Entry Does Not Already Exist
@@ -807,7 +1143,7 @@ func (suite *attrCacheTestSuite) TestTruncateFile() {
 
 	err = suite.attrCache.TruncateFile(options)
 	suite.assert.Nil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Entry Already Exists
 	addPathToCache(suite.assert, suite.attrCache, path, false)
@@ -815,13 +1151,13 @@ func (suite *attrCacheTestSuite) TestTruncateFile() {
 
 	err = suite.attrCache.TruncateFile(options)
 	suite.assert.Nil(err)
-	// suite.assert.Contains(suite.attrCache.cacheMap, path)
-	// suite.assert.NotEqualValues(suite.attrCache.cacheMap[path].attr, &internal.ObjAttr{})
-	// suite.assert.EqualValues(suite.attrCache.cacheMap[path].attr.Size, size) // new size should be set
-	// suite.assert.EqualValues(suite.attrCache.cacheMap[path].attr.Mode, defaultMode)
-	// suite.assert.True(suite.attrCache.cacheMap[path].valid())
-	// suite.assert.True(suite.attrCache.cacheMap[path].exists())
-	suite.assert.False(suite.attrCache.cacheMap[path].valid())
+	// suite.assert.Contains(suite.attrCache.cacheMap.paths(), path)
+	// suite.assert.NotEqualValues(suite.attrCache.cacheMap.item(path).getAttr(), &internal.ObjAttr{})
+	// suite.assert.EqualValues(suite.attrCache.cacheMap.item(path).getAttr().Size, size) // new size should be set
+	// suite.assert.EqualValues(suite.attrCache.cacheMap.item(path).getAttr().Mode, defaultMode)
+	// suite.assert.True(suite.attrCache.cacheMap.item(path).valid())
+	// suite.assert.True(suite.attrCache.cacheMap.item(path).exists())
+	suite.assert.False(suite.attrCache.cacheMap.item(path).valid())
 }
 
 // Tests CopyFromFile
@@ -836,7 +1172,7 @@ func (suite *attrCacheTestSuite) TestCopyFromFileError() {
 
 	err := suite.attrCache.CopyFromFile(options)
 	suite.assert.NotNil(err)
-	suite.assert.Contains(suite.attrCache.cacheMap, path) // GetAttr call will add this to the cache
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), path) // GetAttr call will add this to the cache
 }
 
 func (suite *attrCacheTestSuite) TestCopyFromFileDoesNotExist() {
@@ -851,7 +1187,7 @@ func (suite *attrCacheTestSuite) TestCopyFromFileDoesNotExist() {
 
 	err := suite.attrCache.CopyFromFile(options)
 	suite.assert.Nil(err)
-	suite.assert.Contains(suite.attrCache.cacheMap, path) // GetAttr call will add this to the cache
+	suite.assert.Contains(suite.attrCache.cacheMap.paths(), path) // GetAttr call will add this to the cache
 }
 
 func (suite *attrCacheTestSuite) TestCopyFromFileExists() {
@@ -981,7 +1317,7 @@ func (suite *attrCacheTestSuite) TestGetAttrDoesNotExist() {
 			// attributes should not be accessible so call the mock
 			suite.mock.EXPECT().GetAttr(options).Return(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), false), nil)
 
-			suite.assert.Empty(suite.attrCache.cacheMap) // cacheMap should be empty before call
+			suite.assert.Empty(suite.attrCache.cacheMap.paths()) // cacheMap should be empty before call
 			_, err := suite.attrCache.GetAttr(options)
 			suite.assert.Nil(err)
 			assertUntouched(suite, truncatedPath) // item added to cache after
@@ -1006,11 +1342,56 @@ func (suite *attrCacheTestSuite) TestGetAttrOtherError() {
 			result, err := suite.attrCache.GetAttr(options)
 			suite.assert.Equal(err, os.ErrNotExist)
 			suite.assert.EqualValues(result, &internal.ObjAttr{})
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 		})
 	}
 }
 
+func (suite *attrCacheTestSuite) TestGetAttrChangedNotifiesKernel() {
+	defer suite.cleanupTest()
+	path := "a"
+	suite.attrCache.SetPipelineHead(suite.mock)
+	options := internal.GetAttrOptions{Name: path}
+	expired := time.Now().Add(-time.Hour)
+
+	// Expired attributes which still match the storage
+	addPathToCache(suite.assert, suite.attrCache, path, true)
+	suite.attrCache.cacheMap.item(path).cachedAt = expired
+	suite.mock.EXPECT().GetAttr(options).Return(suite.attrCache.cacheMap.item(path).getAttr(), nil)
+	_, err := suite.attrCache.GetAttr(options)
+	suite.assert.NoError(err)
+
+	// Size changed in storage
+	suite.attrCache.cacheMap.item(path).cachedAt = expired
+	suite.mock.EXPECT().GetAttr(options).Return(getPathAttr(path, 1024, fs.FileMode(defaultMode), true), nil)
+	suite.mock.EXPECT().InvalidatePath(internal.InvalidatePathOptions{Name: path, KernelOnly: true}).Return(nil)
+	_, err = suite.attrCache.GetAttr(options)
+	suite.assert.NoError(err)
+
+	// Etag changed in storage
+	suite.attrCache.cacheMap.item(path).cachedAt = expired
+	suite.attrCache.cacheMap.item(path).etag = "old"
+	attr := getPathAttr(path, 1024, fs.FileMode(defaultMode), true)
+	attr.ETag = "new"
+	suite.mock.EXPECT().GetAttr(options).Return(attr, nil)
+	suite.mock.EXPECT().InvalidatePath(internal.InvalidatePathOptions{Name: path, KernelOnly: true}).Return(nil)
+	_, err = suite.attrCache.GetAttr(options)
+	suite.assert.NoError(err)
+
+	// Deleted in storage
+	suite.attrCache.cacheMap.item(path).cachedAt = expired
+	suite.mock.EXPECT().GetAttr(options).Return(nil, syscall.ENOENT)
+	suite.mock.EXPECT().InvalidatePath(internal.InvalidatePathOptions{Name: path, KernelOnly: true}).Return(nil)
+	_, err = suite.attrCache.GetAttr(options)
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	// Invalidated attributes hold nothing to compare against
+	suite.attrCache.cacheMap.item(path).invalidate()
+	suite.mock.EXPECT().GetAttr(options).Return(getPathAttr(path, 1024, fs.FileMode(defaultMode), true), nil)
+	_, err = suite.attrCache.GetAttr(options)
+	suite.assert.NoError(err)
+}
+
 func (suite *attrCacheTestSuite) TestGetAttrEnonetError() {
 	defer suite.cleanupTest()
 	var paths = []string{"a", "a/"}
@@ -1028,11 +1409,11 @@ func (suite *attrCacheTestSuite) TestGetAttrEnonetError() {
 			result, err := suite.attrCache.GetAttr(options)
 			suite.assert.Equal(err, syscall.ENOENT)
 			suite.assert.EqualValues(result, &internal.ObjAttr{})
-			suite.assert.Contains(suite.attrCache.cacheMap, truncatedPath)
-			suite.assert.EqualValues(suite.attrCache.cacheMap[truncatedPath].attr, &internal.ObjAttr{})
-			suite.assert.True(suite.attrCache.cacheMap[truncatedPath].valid())
-			suite.assert.False(suite.attrCache.cacheMap[truncatedPath].exists())
-			suite.assert.NotNil(suite.attrCache.cacheMap[truncatedPath].cachedAt)
+			suite.assert.Contains(suite.attrCache.cacheMap.paths(), truncatedPath)
+			suite.assert.EqualValues(suite.attrCache.cacheMap.item(truncatedPath).getAttr(), &internal.ObjAttr{})
+			suite.assert.True(suite.attrCache.cacheMap.item(truncatedPath).valid())
+			suite.assert.False(suite.attrCache.cacheMap.item(truncatedPath).exists())
+			suite.assert.NotNil(suite.attrCache.cacheMap.item(truncatedPath).cachedAt)
 		})
 	}
 }
@@ -1051,7 +1432,7 @@ func (suite *attrCacheTestSuite) TestCacheTimeout() {
 	// attributes should not be accessible so call the mock
 	suite.mock.EXPECT().GetAttr(options).Return(getPathAttr(path, defaultSize, fs.FileMode(defaultMode), true), nil)
 
-	suite.assert.Empty(suite.attrCache.cacheMap) // cacheMap should be empty before call
+	suite.assert.Empty(suite.attrCache.cacheMap.paths()) // cacheMap should be empty before call
 	_, err := suite.attrCache.GetAttr(options)
 	suite.assert.Nil(err)
 	assertUntouched(suite, path) // item added to cache after
@@ -1082,8 +1463,8 @@ func (suite *attrCacheTestSuite) TestCreateLink() {
 
 	err := suite.attrCache.CreateLink(options)
 	suite.assert.NotNil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, link)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), link)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Success
 	// Entry Does Not Already Exist
@@ -1091,8 +1472,8 @@ func (suite *attrCacheTestSuite) TestCreateLink() {
 
 	err = suite.attrCache.CreateLink(options)
 	suite.assert.Nil(err)
-	suite.assert.NotContains(suite.attrCache.cacheMap, link)
-	suite.assert.NotContains(suite.attrCache.cacheMap, path)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), link)
+	suite.assert.NotContains(suite.attrCache.cacheMap.paths(), path)
 
 	// Entry Already Exists
 	addPathToCache(suite.assert, suite.attrCache, link, false)
@@ -1124,7 +1505,7 @@ func (suite *attrCacheTestSuite) TestChmod() {
 
 			err := suite.attrCache.Chmod(options)
 			suite.assert.NotNil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Success
 			// Entry Does Not Already Exist
@@ -1132,7 +1513,7 @@ func (suite *attrCacheTestSuite) TestChmod() {
 
 			err = suite.attrCache.Chmod(options)
 			suite.assert.Nil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Entry Already Exists
 			addPathToCache(suite.assert, suite.attrCache, path, false)
@@ -1140,12 +1521,12 @@ func (suite *attrCacheTestSuite) TestChmod() {
 
 			err = suite.attrCache.Chmod(options)
 			suite.assert.Nil(err)
-			suite.assert.Contains(suite.attrCache.cacheMap, truncatedPath)
-			suite.assert.NotEqualValues(suite.attrCache.cacheMap[truncatedPath].attr, &internal.ObjAttr{})
-			suite.assert.EqualValues(suite.attrCache.cacheMap[truncatedPath].attr.Size, defaultSize)
-			suite.assert.EqualValues(suite.attrCache.cacheMap[truncatedPath].attr.Mode, mode) // new mode should be set
-			suite.assert.True(suite.attrCache.cacheMap[truncatedPath].valid())
-			suite.assert.True(suite.attrCache.cacheMap[truncatedPath].exists())
+			suite.assert.Contains(suite.attrCache.cacheMap.paths(), truncatedPath)
+			suite.assert.NotEqualValues(suite.attrCache.cacheMap.item(truncatedPath).getAttr(), &internal.ObjAttr{})
+			suite.assert.EqualValues(suite.attrCache.cacheMap.item(truncatedPath).getAttr().Size, defaultSize)
+			suite.assert.EqualValues(suite.attrCache.cacheMap.item(truncatedPath).getAttr().Mode, mode) // new mode should be set
+			suite.assert.True(suite.attrCache.cacheMap.item(truncatedPath).valid())
+			suite.assert.True(suite.attrCache.cacheMap.item(truncatedPath).exists())
 		})
 	}
 }
@@ -1171,7 +1552,7 @@ func (suite *attrCacheTestSuite) TestChown() {
 
 			err := suite.attrCache.Chown(options)
 			suite.assert.NotNil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Success
 			// Entry Does Not Already Exist
@@ -1179,7 +1560,7 @@ func (suite *attrCacheTestSuite) TestChown() {
 
 			err = suite.attrCache.Chown(options)
 			suite.assert.Nil(err)
-			suite.assert.NotContains(suite.attrCache.cacheMap, truncatedPath)
+			suite.assert.NotContains(suite.attrCache.cacheMap.paths(), truncatedPath)
 
 			// Entry Already Exists
 			addPathToCache(suite.assert, suite.attrCache, path, false)
@@ -1192,6 +1573,57 @@ func (suite *attrCacheTestSuite) TestChown() {
 	}
 }
 
+// Tests InvalidatePath
+func (suite *attrCacheTestSuite) TestInvalidatePath() {
+	defer suite.cleanupTest()
+
+	addPathToCache(suite.assert, suite.attrCache, "a", false)
+	suite.attrCache.cacheMap.item("a").etag = "etag1"
+	addPathToCache(suite.assert, suite.attrCache, "b", false)
+	addPathToCache(suite.assert, suite.attrCache, "c", false)
+	addPathToCache(suite.assert, suite.attrCache, "dir", false)
+	suite.attrCache.cacheMap.item("dir").markDeleted(time.Now())
+	addPathToCache(suite.assert, suite.attrCache, "dir/sub", false)
+	suite.attrCache.cacheMap.item("dir/sub").markDeleted(time.Now())
+
+	// Same etag as the cached one, nothing changed
+	options := internal.InvalidatePathOptions{Name: "a", ETag: "etag1"}
+	suite.mock.EXPECT().InvalidatePath(options).Return(nil)
+	suite.assert.NoError(suite.attrCache.InvalidatePath(options))
+	assertUntouched(suite, "a")
+
+	options = internal.InvalidatePathOptions{Name: "a", ETag: "etag2"}
+	suite.mock.EXPECT().InvalidatePath(options).Return(nil)
+	suite.assert.NoError(suite.attrCache.InvalidatePath(options))
+	assertInvalid(suite, "a")
+
+	// Deleted
+	options = internal.InvalidatePathOptions{Name: "b"}
+	suite.mock.EXPECT().InvalidatePath(options).Return(nil)
+	suite.assert.NoError(suite.attrCache.InvalidatePath(options))
+	assertInvalid(suite, "b")
+	assertUntouched(suite, "c")
+
+	// New blob makes its parent directories exist
+	options = internal.InvalidatePathOptions{Name: "dir/sub/file", ETag: "etag"}
+	suite.mock.EXPECT().InvalidatePath(options).Return(nil)
+	suite.assert.NoError(suite.attrCache.InvalidatePath(options))
+	suite.assert.False(suite.attrCache.cacheMap.item("dir").valid())
+	suite.assert.False(suite.attrCache.cacheMap.item("dir/sub").valid())
+	assertUntouched(suite, "c")
+
+	// Directory renamed or deleted
+	addPathToCache(suite.assert, suite.attrCache, "tree", false)
+	addPathToCache(suite.assert, suite.attrCache, "tree/x", false)
+	addPathToCache(suite.assert, suite.attrCache, "treex", false)
+	options = internal.InvalidatePathOptions{Name: "tree", IsDir: true}
+	suite.mock.EXPECT().InvalidatePath(options).Return(nil)
+	suite.assert.NoError(suite.attrCache.InvalidatePath(options))
+	assertInvalid(suite, "tree")
+	assertInvalid(suite, "tree/x")
+	assertUntouched(suite, "treex")
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestAttrCacheTestSuite(t *testing.T) {
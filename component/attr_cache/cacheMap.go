@@ -34,8 +34,13 @@
 package attr_cache
 
 import (
+	"container/list"
+	"encoding/binary"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
+	"unsafe"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
@@ -48,19 +53,151 @@ const (
 	AttrFlagValid
 )
 
+// Approximate memory taken by an item including its slot in the cache map, besides the strings it refers to
+const itemOverhead = int64(unsafe.Sizeof(attrCacheItem{})+unsafe.Sizeof(list.Element{})+unsafe.Sizeof(attrKey{})) + 48
+
+// Approximate memory taken by an interned directory including its slot in the directory map, besides its path
+const dirOverhead = int64(unsafe.Sizeof(attrDir{})+unsafe.Sizeof("")) + 48
+
+// attrDir : Directory part of the paths of cached items, interned so that it is kept once for all items under it
+type attrDir struct {
+	path string
+	refs int
+}
+
+// attrKey : Path of a cached item split into its interned directory and its name
+type attrKey struct {
+	dir  *attrDir
+	name string
+}
+
+// attrMap : Items of the attr cache keyed by path.
+// A path is held as its interned directory and its name, so the memory taken by a path is that of its name.
+type attrMap struct {
+	dirs      map[string]*attrDir
+	items     map[attrKey]*attrCacheItem
+	dirMemory int64 // memory held by the interned directories
+}
+
+func newAttrMap() *attrMap {
+	return &attrMap{
+		dirs:  make(map[string]*attrDir),
+		items: make(map[attrKey]*attrCacheItem),
+	}
+}
+
+// splitPath : Directory and name of a path, directory is empty for paths at the root
+func splitPath(path string) (string, string) {
+	idx := strings.LastIndex(path, "/")
+	if idx < 0 {
+		return "", path
+	}
+	return path[:idx], path[idx+1:]
+}
+
+// key : Key of the given path, returns false if no item is cached under its directory
+func (m *attrMap) key(path string) (attrKey, bool) {
+	dir, name := splitPath(path)
+	d, found := m.dirs[dir]
+	if !found {
+		return attrKey{}, false
+	}
+	return attrKey{dir: d, name: name}, true
+}
+
+func (m *attrMap) get(path string) (*attrCacheItem, bool) {
+	key, found := m.key(path)
+	if !found {
+		return nil, false
+	}
+	item, found := m.items[key]
+	return item, found
+}
+
+// set : Cache the item at the given path replacing the item cached there earlier
+func (m *attrMap) set(path string, item *attrCacheItem) {
+	m.remove(path)
+
+	dir, name := splitPath(path)
+	d, found := m.dirs[dir]
+	if !found {
+		// strings are cloned so that the paths they were cut from are not retained
+		d = &attrDir{path: strings.Clone(dir)}
+		m.dirs[d.path] = d
+		m.dirMemory += dirOverhead + int64(len(d.path))
+	}
+	d.refs++
+
+	item.key = attrKey{dir: d, name: strings.Clone(name)}
+	m.items[item.key] = item
+}
+
+// remove : Drop the item cached at the given path, the directory is released once no item refers to it
+func (m *attrMap) remove(path string) {
+	key, found := m.key(path)
+	if !found {
+		return
+	}
+
+	if _, found = m.items[key]; !found {
+		return
+	}
+
+	delete(m.items, key)
+	key.dir.refs--
+	if key.dir.refs == 0 {
+		delete(m.dirs, key.dir.path)
+		m.dirMemory -= dirOverhead + int64(len(key.dir.path))
+	}
+}
+
+func (m *attrMap) len() int {
+	return len(m.items)
+}
+
+// under : Call fn for every item below the given directory prefix, which shall end with a /
+func (m *attrMap) under(prefix string, fn func(item *attrCacheItem)) {
+	dir := strings.TrimSuffix(prefix, "/")
+	for key, item := range m.items {
+		if key.dir.path == dir || strings.HasPrefix(key.dir.path, prefix) {
+			fn(item)
+		}
+	}
+}
+
 // attrCacheItem : Structure of each item in attr cache
+// Attributes are kept in a compact form instead of a complete ObjAttr. Path is held by the key of the item
+// in the cache map and metadata is kept encoded in a single string, so the ObjAttr is built only when
+// attributes are served.
 type attrCacheItem struct {
-	attr     *internal.ObjAttr
-	cachedAt time.Time
+	key      attrKey
+	altPath  string // path of the attributes if it differs from the path item is cached at, e.g. with a trailing /
+	mtime    int64  // times are kept in unix nano seconds, 0 for an unset time
+	atime    int64
+	ctime    int64
+	crtime   int64
+	size     int64
+	mode     os.FileMode
+	flags    common.BitMap16 // property flags of the object
 	attrFlag common.BitMap16
+	md5      []byte
+	etag     string
+	metadata string // encoded metadata, empty when object has no metadata
+	cachedAt time.Time
+	node     *list.Element // position in LRU list of the cache
+	memSize  int64         // memory accounted for this item
 }
 
 func newAttrCacheItem(attr *internal.ObjAttr, exists bool, cachedAt time.Time) *attrCacheItem {
 	item := &attrCacheItem{
-		attr:     attr,
 		attrFlag: 0,
 		cachedAt: cachedAt,
 	}
+	if attr != nil {
+		// key is set once the item is cached, till then the path of the attributes is served
+		item.key.name = attr.Path
+	}
+	item.setAttr(attr)
 
 	item.attrFlag.Set(AttrFlagValid)
 	if exists {
@@ -70,6 +207,109 @@ func newAttrCacheItem(attr *internal.ObjAttr, exists bool, cachedAt time.Time) *
 	return item
 }
 
+func toUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// setAttr : Keep the given attributes in compact form
+func (value *attrCacheItem) setAttr(attr *internal.ObjAttr) {
+	if attr == nil {
+		attr = &internal.ObjAttr{}
+	}
+
+	value.altPath = ""
+	if attr.Path != "" && attr.Path != value.path() {
+		value.altPath = attr.Path
+	}
+	value.mtime = toUnixNano(attr.Mtime)
+	value.atime = toUnixNano(attr.Atime)
+	value.ctime = toUnixNano(attr.Ctime)
+	value.crtime = toUnixNano(attr.Crtime)
+	value.size = attr.Size
+	value.mode = attr.Mode
+	value.flags = attr.Flags
+	value.md5 = attr.MD5
+	value.etag = attr.ETag
+	value.metadata = encodeMetadata(attr.Metadata)
+}
+
+// clearAttr : Drop the attributes, only the path is retained
+func (value *attrCacheItem) clearAttr() {
+	value.setAttr(&internal.ObjAttr{})
+}
+
+// path : Path the item is cached at
+func (value *attrCacheItem) path() string {
+	if value.key.dir == nil || value.key.dir.path == "" {
+		return value.key.name
+	}
+	return value.key.dir.path + "/" + value.key.name
+}
+
+// memoryUsage : Approximate memory held by this item, its directory is accounted by the cache map
+func (value *attrCacheItem) memoryUsage() int64 {
+	return itemOverhead + int64(len(value.key.name)+len(value.altPath)+len(value.md5)+len(value.etag)+len(value.metadata))
+}
+
+// encodeMetadata : Pack metadata into a single string as length prefixed keys and values, a nil value has no length
+func encodeMetadata(metadata map[string]*string) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+
+	buf := make([]byte, 0, 64)
+	for k, v := range metadata {
+		buf = binary.AppendUvarint(buf, uint64(len(k)))
+		buf = append(buf, k...)
+		if v == nil {
+			buf = append(buf, 0)
+			continue
+		}
+		buf = append(buf, 1)
+		buf = binary.AppendUvarint(buf, uint64(len(*v)))
+		buf = append(buf, *v...)
+	}
+	return string(buf)
+}
+
+// decodeMetadata : Unpack metadata packed by encodeMetadata, returns nil for empty metadata
+func decodeMetadata(encoded string) map[string]*string {
+	if encoded == "" {
+		return nil
+	}
+
+	metadata := make(map[string]*string)
+	next := func() string {
+		n, size := binary.Uvarint([]byte(encoded[:min(len(encoded), binary.MaxVarintLen64)]))
+		field := encoded[size : size+int(n)]
+		encoded = encoded[size+int(n):]
+		return field
+	}
+
+	for encoded != "" {
+		k := next()
+		hasValue := encoded[0] == 1
+		encoded = encoded[1:]
+		if !hasValue {
+			metadata[k] = nil
+			continue
+		}
+		v := next()
+		metadata[k] = &v
+	}
+	return metadata
+}
+
 func (value *attrCacheItem) valid() bool {
 	return value.attrFlag.IsSet(AttrFlagValid)
 }
@@ -82,16 +322,61 @@ func (value *attrCacheItem) markDeleted(deletedTime time.Time) {
 	value.attrFlag.Clear(AttrFlagExists)
 	value.attrFlag.Set(AttrFlagValid)
 	value.cachedAt = deletedTime
-	value.attr = &internal.ObjAttr{}
+	value.clearAttr()
 }
 
 func (value *attrCacheItem) invalidate() {
 	value.attrFlag.Clear(AttrFlagValid)
-	value.attr = &internal.ObjAttr{}
+	value.clearAttr()
 }
 
+// getAttr : Build the attributes of this item, an empty ObjAttr is returned if the item holds no attributes
 func (value *attrCacheItem) getAttr() *internal.ObjAttr {
-	return value.attr
+	if !value.valid() || !value.exists() {
+		return &internal.ObjAttr{}
+	}
+
+	path := value.altPath
+	if path == "" {
+		path = value.path()
+	}
+
+	return &internal.ObjAttr{
+		Path:     path,
+		Name:     filepath.Base(path),
+		Mtime:    fromUnixNano(value.mtime),
+		Atime:    fromUnixNano(value.atime),
+		Ctime:    fromUnixNano(value.ctime),
+		Crtime:   fromUnixNano(value.crtime),
+		Size:     value.size,
+		Mode:     value.mode,
+		Flags:    value.flags,
+		MD5:      value.md5,
+		ETag:     value.etag,
+		Metadata: decodeMetadata(value.metadata),
+	}
+}
+
+// changed : Whether the attributes fetched from storage differ from the ones held by this item.
+// Invalidated items hold nothing to compare against, so they are never reported as changed.
+func (value *attrCacheItem) changed(attr *internal.ObjAttr, exists bool) bool {
+	if !value.valid() {
+		return false
+	}
+
+	if value.exists() != exists {
+		return true
+	}
+
+	if !exists {
+		return false
+	}
+
+	if value.etag != "" && attr.ETag != "" {
+		return value.etag != attr.ETag
+	}
+
+	return value.size != attr.Size || value.mtime != toUnixNano(attr.Mtime)
 }
 
 func (value *attrCacheItem) isDeleted() bool {
@@ -99,13 +384,13 @@ func (value *attrCacheItem) isDeleted() bool {
 }
 
 func (value *attrCacheItem) setSize(size int64) {
-	value.attr.Mtime = time.Now()
-	value.attr.Size = size
+	value.mtime = time.Now().UnixNano()
+	value.size = size
 	value.cachedAt = time.Now()
 }
 
 func (value *attrCacheItem) setMode(mode os.FileMode) {
-	value.attr.Mode = mode
-	value.attr.Ctime = time.Now()
+	value.mode = mode
+	value.ctime = time.Now().UnixNano()
 	value.cachedAt = time.Now()
 }
@@ -0,0 +1,673 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package attr_cache
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/metastore"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Columns of a blob inventory report used to build listings, names are matched case insensitively
+const (
+	invName          = "name"
+	invCreationTime  = "creation-time"
+	invLastModified  = "last-modified"
+	invETag          = "etag"
+	invContentLength = "content-length"
+	invContentMD5    = "content-md5"
+	invIsFolder      = "hdi_isfolder"
+	invMetadata      = "metadata"
+	invDeleted       = "deleted"
+	invSnapshot      = "snapshot"
+	invCurrent       = "iscurrentversion"
+)
+
+// inventoryDir : Children of a directory collected while the report is read
+type inventoryDir struct {
+	path     string
+	children []*internal.ObjAttr
+	dirs     map[string]bool // Sub directories already added to children
+}
+
+// inventoryLoader : Imports listings from a blob inventory report into the metadata store.
+// Reports list blobs sorted by name so the directories being filled form a stack, a directory
+// is complete and imported as soon as a name outside of it is read.
+type inventoryLoader struct {
+	store  *metastore.Store
+	prefix string // Subdirectory mounted, names outside of it are skipped
+	asOf   time.Time
+	stop   <-chan struct{}
+
+	columns  map[string]int
+	stack    []*inventoryDir
+	imported map[string]bool // Directories imported so far, to detect reports not sorted by name
+	revisit  map[string]bool // Directories seen again after being imported
+
+	blobs int
+	dirs  int
+}
+
+// inventoryClient : Reports are downloaded in background, a server which stops answering must not hold the load forever
+var inventoryClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+	},
+}
+
+// inventoryIdleTimeout : Download of a report is abandoned when no data arrives for this long
+var inventoryIdleTimeout = time.Minute
+
+// inventoryManifest : Fields of the manifest written by blob inventory along with the report files
+type inventoryManifest struct {
+	StartTime      string `json:"inventoryStartTime"`
+	CompletionTime string `json:"inventoryCompletionTime"`
+	Status         string `json:"status"`
+}
+
+// idleReader : Body of a download which is cancelled when a read blocks for longer than inventoryIdleTimeout
+type idleReader struct {
+	io.ReadCloser
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.timer.Reset(inventoryIdleTimeout)
+	defer r.timer.Stop()
+	return r.ReadCloser.Read(p)
+}
+
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.ReadCloser.Close()
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+func isParquet(source string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.SplitN(source, "?", 2)[0]), ".parquet")
+}
+
+// download : Get a url, the request is cancelled with ctx
+func download(ctx context.Context, source string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid url")
+	}
+
+	resp, err := inventoryClient.Do(req)
+	if err != nil {
+		cancel()
+		// Error carries the url, drop it so that the sas token is not logged
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	timer := time.AfterFunc(inventoryIdleTimeout, cancel)
+	timer.Stop()
+	return &idleReader{ReadCloser: resp.Body, timer: timer, cancel: cancel}, nil
+}
+
+// manifestPath : Manifest is named after the inventory rule, which is also the name of the directory holding the report files
+func manifestPath(source string) string {
+	path, query, found := strings.Cut(source, "?")
+	dir := path[:strings.LastIndex(path, "/")+1]
+	manifest := dir + filepath.Base(dir) + "-manifest.json"
+	if found {
+		manifest += "?" + query
+	}
+	return manifest
+}
+
+// readManifest : Time of the report, which is when the inventory run started as changes made during the run may be missing from it
+func readManifest(ctx context.Context, source string) (time.Time, error) {
+	var r io.ReadCloser
+	var err error
+
+	manifest := manifestPath(source)
+	if isURL(manifest) {
+		r, err = download(ctx, manifest)
+	} else {
+		r, err = os.Open(manifest)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to open inventory manifest [%s]", err.Error())
+	}
+	defer r.Close()
+
+	var m inventoryManifest
+	err = json.NewDecoder(r).Decode(&m)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read inventory manifest [%s]", err.Error())
+	}
+
+	// A report of a run which did not complete does not list everything
+	if m.Status != "" && !strings.EqualFold(m.Status, "Succeeded") {
+		return time.Time{}, fmt.Errorf("inventory run did not succeed [%s]", m.Status)
+	}
+
+	asOf := m.StartTime
+	if asOf == "" {
+		asOf = m.CompletionTime
+	}
+	t, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("inventory manifest has no valid start time")
+	}
+
+	return t, nil
+}
+
+// openInventory : Open a csv report from a local path or a url
+func openInventory(ctx context.Context, source string) (io.ReadCloser, error) {
+	if isURL(source) {
+		r, err := download(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download inventory report [%s]", err.Error())
+		}
+		return r, nil
+	}
+
+	return os.Open(source)
+}
+
+// openParquetInventory : Open a parquet report, which is read out of order so a report at a url is downloaded to tempDir first
+func openParquetInventory(ctx context.Context, source string, tempDir string) (*os.File, error) {
+	if !isURL(source) {
+		return os.Open(source)
+	}
+
+	r, err := download(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download inventory report [%s]", err.Error())
+	}
+	defer r.Close()
+
+	f, err := os.CreateTemp(tempDir, "inventory-*.parquet")
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing else uses the copy, it goes away when closed
+	_ = os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to download inventory report [%s]", err.Error())
+	}
+
+	return f, nil
+}
+
+// loadCSV : Import listings from a report in csv format
+func (l *inventoryLoader) loadCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read inventory header [%s]", err.Error())
+	}
+
+	// Header is overwritten by the next read
+	return l.load(append([]string(nil), header...), reader.Read)
+}
+
+// parquetColumn : Leaf column of a parquet report and the field of the row it is part of
+type parquetColumn struct {
+	field  int
+	name   string // Name of the leaf, key and value of a map field
+	nested bool
+	typ    parquet.Type
+}
+
+// loadParquet : Import listings from a report in parquet format, values are converted to the text used by csv reports
+func (l *inventoryLoader) loadParquet(r io.ReaderAt, size int64) error {
+	f, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read inventory report [%s]", err.Error())
+	}
+
+	schema := f.Schema()
+	header := make([]string, 0)
+	leaves := make([]parquetColumn, 0)
+	for _, path := range schema.Columns() {
+		if len(header) == 0 || header[len(header)-1] != path[0] {
+			header = append(header, path[0])
+		}
+		leaf, _ := schema.Lookup(path...)
+		leaves = append(leaves, parquetColumn{field: len(header) - 1, name: path[len(path)-1], nested: len(path) > 1, typ: leaf.Node.Type()})
+	}
+
+	reader := parquet.NewReader(f)
+	defer reader.Close()
+
+	rows := make([]parquet.Row, 1)
+	record := make([]string, len(header))
+	next := func() ([]string, error) {
+		n, err := reader.ReadRows(rows)
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+
+		for i := range record {
+			record[i] = ""
+		}
+
+		// Values of a map field come as a list of keys followed by a list of values
+		keys := make(map[int][]string)
+		values := make(map[int][]string)
+		for _, value := range rows[0] {
+			column := leaves[value.Column()]
+			if value.IsNull() {
+				continue
+			}
+
+			text := parquetText(column, value)
+			if !column.nested {
+				record[column.field] = text
+			} else if column.name == "key" {
+				keys[column.field] = append(keys[column.field], text)
+			} else if column.name == "value" {
+				values[column.field] = append(values[column.field], text)
+			}
+		}
+
+		for field := range keys {
+			if len(keys[field]) != len(values[field]) {
+				continue
+			}
+			m := make(map[string]string, len(keys[field]))
+			for i, key := range keys[field] {
+				m[key] = values[field][i]
+			}
+			data, err := json.Marshal(m)
+			if err == nil {
+				record[field] = string(data)
+			}
+		}
+		return record, nil
+	}
+
+	return l.load(header, next)
+}
+
+// parquetText : Text form of a parquet value as it appears in csv reports
+func parquetText(column parquetColumn, value parquet.Value) string {
+	lt := column.typ.LogicalType()
+
+	switch value.Kind() {
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		// Binary md5 is base64 encoded in csv reports
+		if lt == nil && strings.EqualFold(column.name, invContentMD5) {
+			return base64.StdEncoding.EncodeToString(value.ByteArray())
+		}
+		return string(value.ByteArray())
+	case parquet.Int64:
+		if lt != nil && lt.Timestamp != nil {
+			unit := time.Nanosecond
+			if lt.Timestamp.Unit.Millis != nil {
+				unit = time.Millisecond
+			} else if lt.Timestamp.Unit.Micros != nil {
+				unit = time.Microsecond
+			}
+			return time.Unix(0, value.Int64()*int64(unit)).UTC().Format(time.RFC3339Nano)
+		}
+		return strconv.FormatInt(value.Int64(), 10)
+	case parquet.Int32:
+		return strconv.FormatInt(int64(value.Int32()), 10)
+	case parquet.Boolean:
+		return strconv.FormatBool(value.Boolean())
+	default:
+		return value.String()
+	}
+}
+
+// load : Read the rows of the report and import listings of all directories in it
+func (l *inventoryLoader) load(header []string, next func() ([]string, error)) error {
+	l.columns = make(map[string]int)
+	for i, column := range header {
+		l.columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, found := l.columns[invName]; !found {
+		return fmt.Errorf("inventory report has no %s column", invName)
+	}
+
+	l.imported = make(map[string]bool)
+	l.revisit = make(map[string]bool)
+	l.stack = []*inventoryDir{{path: "", dirs: make(map[string]bool)}}
+
+	for {
+		select {
+		case <-l.stop:
+			return fmt.Errorf("inventory load stopped")
+		default:
+		}
+
+		record, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read inventory report [%s]", err.Error())
+		}
+
+		attr := l.parse(record)
+		if attr != nil {
+			l.add(attr)
+		}
+	}
+
+	// Whatever is still open is complete now
+	for len(l.stack) > 0 {
+		l.flush()
+	}
+
+	// Listings of a report not sorted by name are incomplete, those are listed from storage instead
+	for dir := range l.revisit {
+		log.Warn("AttrCache::loadInventory : %s is not contiguous in inventory report, dropping its listing", dir)
+		l.store.InvalidateTree(dir)
+	}
+
+	return nil
+}
+
+func (l *inventoryLoader) field(record []string, column string) string {
+	i, found := l.columns[column]
+	if !found || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// parse : Attributes of the blob in a row, nil if the row does not describe a current blob in the mounted path
+func (l *inventoryLoader) parse(record []string) *internal.ObjAttr {
+	if strings.EqualFold(l.field(record, invDeleted), "true") ||
+		l.field(record, invSnapshot) != "" ||
+		strings.EqualFold(l.field(record, invCurrent), "false") {
+		return nil
+	}
+
+	name := strings.Trim(l.field(record, invName), "/")
+	if l.prefix != "" {
+		if !strings.HasPrefix(name, l.prefix+"/") {
+			return nil
+		}
+		name = strings.TrimPrefix(name, l.prefix+"/")
+	}
+	if name == "" {
+		return nil
+	}
+
+	attr := &internal.ObjAttr{
+		Path:  name,
+		Name:  filepath.Base(name),
+		Flags: internal.NewFileBitMap(),
+		ETag:  strings.Trim(l.field(record, invETag), `"`),
+	}
+
+	attr.Size, _ = strconv.ParseInt(l.field(record, invContentLength), 10, 64)
+	attr.Mtime, _ = time.Parse(time.RFC3339Nano, l.field(record, invLastModified))
+	attr.Crtime, _ = time.Parse(time.RFC3339Nano, l.field(record, invCreationTime))
+	if attr.Crtime.IsZero() {
+		attr.Crtime = attr.Mtime
+	}
+	attr.Atime = attr.Mtime
+	attr.Ctime = attr.Mtime
+	attr.MD5, _ = base64.StdEncoding.DecodeString(l.field(record, invContentMD5))
+
+	if value := l.field(record, invMetadata); strings.HasPrefix(value, "{") {
+		metadata := make(map[string]string)
+		if json.Unmarshal([]byte(value), &metadata) == nil && len(metadata) > 0 {
+			attr.Metadata = make(map[string]*string, len(metadata))
+			for k, v := range metadata {
+				val := v
+				attr.Metadata[k] = &val
+			}
+		}
+	}
+
+	if strings.EqualFold(l.field(record, invIsFolder), "true") {
+		attr.Flags = internal.NewDirBitMap()
+		attr.Mode = os.ModeDir
+	}
+	attr.Flags.Set(internal.PropFlagModeDefault)
+
+	return attr
+}
+
+// add : Add the blob to the listing of its directory, closing directories it is not part of
+func (l *inventoryLoader) add(attr *internal.ObjAttr) {
+	parent := filepath.Dir(attr.Path)
+	if parent == "." {
+		parent = ""
+	}
+
+	// Directories which are not ancestors of this blob are complete
+	for len(l.stack) > 1 {
+		top := l.stack[len(l.stack)-1].path
+		if parent == top || strings.HasPrefix(parent, top+"/") {
+			break
+		}
+		l.flush()
+	}
+
+	// Open the directories between the last open one and the parent of this blob
+	for top := l.stack[len(l.stack)-1]; top.path != parent; top = l.stack[len(l.stack)-1] {
+		rest := strings.TrimPrefix(parent, top.path)
+		rest = strings.TrimPrefix(rest, "/")
+		name := strings.SplitN(rest, "/", 2)[0]
+		path := name
+		if top.path != "" {
+			path = top.path + "/" + name
+		}
+
+		l.addDir(top, path, nil)
+		l.open(path)
+	}
+
+	top := l.stack[len(l.stack)-1]
+	if attr.IsDir() {
+		l.addDir(top, attr.Path, attr)
+	} else {
+		top.children = append(top.children, attr)
+		l.blobs++
+	}
+}
+
+// addDir : Add a directory to the listing of its parent, the marker blob replaces a directory added for a prefix
+func (l *inventoryLoader) addDir(parent *inventoryDir, path string, attr *internal.ObjAttr) {
+	if attr == nil {
+		if parent.dirs[path] {
+			return
+		}
+		attr = &internal.ObjAttr{
+			Path:  path,
+			Name:  filepath.Base(path),
+			Size:  4096,
+			Mode:  os.ModeDir,
+			Mtime: l.asOf,
+			Flags: internal.NewDirBitMap(),
+		}
+		attr.Atime = attr.Mtime
+		attr.Ctime = attr.Mtime
+		attr.Crtime = attr.Mtime
+		attr.Flags.Set(internal.PropFlagModeDefault)
+	} else if parent.dirs[path] {
+		for i, child := range parent.children {
+			if child.Path == path {
+				parent.children[i] = attr
+				return
+			}
+		}
+	}
+
+	parent.dirs[path] = true
+	parent.children = append(parent.children, attr)
+}
+
+func (l *inventoryLoader) open(path string) {
+	if l.imported[path] {
+		l.revisit[path] = true
+	}
+	l.stack = append(l.stack, &inventoryDir{path: path, dirs: make(map[string]bool)})
+}
+
+// flush : Import the listing of the innermost open directory
+func (l *inventoryLoader) flush() {
+	top := l.stack[len(l.stack)-1]
+	l.stack = l.stack[:len(l.stack)-1]
+
+	if l.imported[top.path] {
+		l.revisit[top.path] = true
+		return
+	}
+
+	err := l.store.Import(top.path, top.children, l.asOf)
+	if err != nil {
+		log.Err("AttrCache::loadInventory : Failed to import listing of %s [%s]", top.path, err.Error())
+		return
+	}
+
+	l.imported[top.path] = true
+	l.dirs++
+}
+
+// loadInventory : Pre-populate listings in the disk cache from the configured inventory report
+func (ac *AttrCache) loadInventory() {
+	defer ac.inventoryWG.Done()
+
+	// Query of a url may carry a sas token, keep it out of the logs
+	source := strings.SplitN(ac.inventoryPath, "?", 2)[0]
+
+	// Downloads are abandoned when the component stops
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ac.inventoryStop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	asOf, err := readManifest(ctx, ac.inventoryPath)
+	if err != nil {
+		log.Err("AttrCache::loadInventory : Failed to get time of inventory report %s [%s]", source, err.Error())
+		return
+	}
+
+	loader := &inventoryLoader{
+		store:  ac.store,
+		prefix: ac.prefixPath,
+		asOf:   asOf,
+		stop:   ac.inventoryStop,
+	}
+
+	start := time.Now()
+	if isParquet(ac.inventoryPath) {
+		var f *os.File
+		f, err = openParquetInventory(ctx, ac.inventoryPath, ac.diskPath)
+		if err != nil {
+			log.Err("AttrCache::loadInventory : Failed to open inventory report %s [%s]", source, err.Error())
+			return
+		}
+		defer f.Close()
+
+		var info os.FileInfo
+		info, err = f.Stat()
+		if err == nil {
+			err = loader.loadParquet(f, info.Size())
+		}
+	} else {
+		var r io.ReadCloser
+		r, err = openInventory(ctx, ac.inventoryPath)
+		if err != nil {
+			log.Err("AttrCache::loadInventory : Failed to open inventory report %s [%s]", source, err.Error())
+			return
+		}
+		defer r.Close()
+
+		err = loader.loadCSV(r)
+	}
+
+	if err != nil {
+		log.Err("AttrCache::loadInventory : Failed to load inventory report %s [%s]", source, err.Error())
+		return
+	}
+
+	log.Info("AttrCache::loadInventory : Imported %d blobs in %d directories from report of %v in %v",
+		loader.blobs, loader.dirs, asOf, time.Since(start))
+}
@@ -0,0 +1,396 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package attr_cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/metastore"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type inventoryTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	store  *metastore.Store
+}
+
+const inventoryHeader = "Name,Creation-Time,Last-Modified,Etag,Content-Length,Content-MD5,hdi_isfolder,Deleted\n"
+
+var inventoryRows = []string{
+	"a.txt,2024-01-01T00:00:00.0000000Z,2024-01-02T00:00:00.0000000Z,0x1,10,,,",
+	"dir,2024-01-01T00:00:00.0000000Z,2024-01-02T00:00:00.0000000Z,0x2,0,,true,",
+	"dir/b.txt,2024-01-01T00:00:00.0000000Z,2024-01-02T00:00:00.0000000Z,0x3,20,AAECAw==,,",
+	"dir/sub/c.txt,2024-01-01T00:00:00.0000000Z,2024-01-02T00:00:00.0000000Z,0x4,30,,,",
+	"dir/sub/deleted.txt,2024-01-01T00:00:00.0000000Z,2024-01-02T00:00:00.0000000Z,0x5,30,,,true",
+	"dir2/d.txt,2024-01-01T00:00:00.0000000Z,2024-01-02T00:00:00.0000000Z,0x6,40,,,",
+}
+
+func (suite *inventoryTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+
+	suite.assert = assert.New(suite.T())
+	suite.store, err = metastore.Open(suite.T().TempDir(), "container", time.Hour)
+	suite.assert.NoError(err)
+}
+
+func (suite *inventoryTestSuite) TearDownTest() {
+	suite.store.Close()
+}
+
+func (suite *inventoryTestSuite) load(prefix string, rows []string) *inventoryLoader {
+	loader := &inventoryLoader{
+		store:  suite.store,
+		prefix: prefix,
+		asOf:   time.Now(),
+		stop:   make(chan struct{}),
+	}
+
+	err := loader.loadCSV(strings.NewReader(inventoryHeader + strings.Join(rows, "\n") + "\n"))
+	suite.assert.NoError(err)
+	return loader
+}
+
+func (suite *inventoryTestSuite) listing(dir string) []string {
+	list, found := suite.store.GetListing(dir)
+	suite.assert.True(found, dir)
+
+	names := make([]string, 0)
+	for _, attr := range list {
+		names = append(names, attr.Path)
+	}
+	return names
+}
+
+func (suite *inventoryTestSuite) TestLoad() {
+	loader := suite.load("", inventoryRows)
+	suite.assert.Equal(4, loader.blobs)
+	suite.assert.Equal(4, loader.dirs)
+
+	suite.assert.Equal([]string{"a.txt", "dir", "dir2"}, suite.listing(""))
+	suite.assert.Equal([]string{"dir/b.txt", "dir/sub"}, suite.listing("dir"))
+	suite.assert.Equal([]string{"dir/sub/c.txt"}, suite.listing("dir/sub"))
+	suite.assert.Equal([]string{"dir2/d.txt"}, suite.listing("dir2"))
+
+	attr, exists, ok := suite.store.Lookup("dir/b.txt")
+	suite.assert.True(ok)
+	suite.assert.True(exists)
+	suite.assert.EqualValues(20, attr.Size)
+	suite.assert.Equal("0x3", attr.ETag)
+	suite.assert.Equal([]byte{0, 1, 2, 3}, attr.MD5)
+	suite.assert.True(attr.Mtime.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	suite.assert.True(attr.IsModeDefault())
+
+	attr, exists, _ = suite.store.Lookup("dir")
+	suite.assert.True(exists)
+	suite.assert.True(attr.IsDir())
+	suite.assert.Equal("0x2", attr.ETag)
+
+	attr, exists, _ = suite.store.Lookup("dir2")
+	suite.assert.True(exists)
+	suite.assert.True(attr.IsDir())
+
+	// Deleted blobs are skipped and missing names are not trusted
+	_, _, ok = suite.store.Lookup("dir/sub/deleted.txt")
+	suite.assert.False(ok)
+}
+
+func (suite *inventoryTestSuite) TestLoadSubdirectory() {
+	suite.load("dir", inventoryRows)
+
+	suite.assert.Equal([]string{"b.txt", "sub"}, suite.listing(""))
+	suite.assert.Equal([]string{"sub/c.txt"}, suite.listing("sub"))
+	_, found := suite.store.GetListing("dir2")
+	suite.assert.False(found)
+}
+
+func (suite *inventoryTestSuite) TestLoadUnsorted() {
+	rows := []string{
+		"dir/a.txt,,,,1,,,",
+		"other/b.txt,,,,1,,,",
+		"dir/c.txt,,,,1,,,",
+	}
+	suite.load("", rows)
+
+	_, found := suite.store.GetListing("dir")
+	suite.assert.False(found)
+	suite.assert.Equal([]string{"other/b.txt"}, suite.listing("other"))
+}
+
+func (suite *inventoryTestSuite) TestLoadErrors() {
+	loader := &inventoryLoader{store: suite.store, stop: make(chan struct{})}
+	err := loader.loadCSV(strings.NewReader("Size,Etag\n1,2\n"))
+	suite.assert.Error(err)
+
+	stop := make(chan struct{})
+	close(stop)
+	loader = &inventoryLoader{store: suite.store, stop: stop}
+	err = loader.loadCSV(strings.NewReader(inventoryHeader + inventoryRows[0] + "\n"))
+	suite.assert.Error(err)
+
+	_, err = openInventory(context.Background(), "/nonexistent/report.csv")
+	suite.assert.Error(err)
+	_, err = openParquetInventory(context.Background(), "/nonexistent/report.parquet", suite.T().TempDir())
+	suite.assert.Error(err)
+
+	err = loader.loadParquet(strings.NewReader("not parquet"), 11)
+	suite.assert.Error(err)
+}
+
+// parquetRow : Columns of a blob inventory report in parquet format
+type parquetRow struct {
+	Name          string            `parquet:"Name"`
+	CreationTime  int64             `parquet:"Creation-Time,timestamp(millisecond)"`
+	LastModified  int64             `parquet:"Last-Modified,timestamp(millisecond)"`
+	Etag          string            `parquet:"Etag"`
+	ContentLength int64             `parquet:"Content-Length"`
+	ContentMD5    []byte            `parquet:"Content-MD5"`
+	IsFolder      string            `parquet:"hdi_isfolder,optional"`
+	Metadata      map[string]string `parquet:"Metadata,optional"`
+	Deleted       bool              `parquet:"Deleted"`
+}
+
+func (suite *inventoryTestSuite) TestLoadParquet() {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	modified := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC).UnixMilli()
+	rows := []parquetRow{
+		{Name: "a.txt", CreationTime: created, LastModified: modified, Etag: "0x1", ContentLength: 10, Metadata: map[string]string{"key": "value"}},
+		{Name: "dir", CreationTime: created, LastModified: modified, Etag: "0x2", IsFolder: "true"},
+		{Name: "dir/b.txt", CreationTime: created, LastModified: modified, Etag: "0x3", ContentLength: 20, ContentMD5: []byte{0, 1, 2, 3}},
+		{Name: "dir/deleted.txt", CreationTime: created, LastModified: modified, Etag: "0x4", Deleted: true},
+	}
+
+	report := filepath.Join(suite.T().TempDir(), "report.parquet")
+	suite.assert.NoError(parquet.WriteFile(report, rows))
+
+	f, err := openParquetInventory(context.Background(), report, suite.T().TempDir())
+	suite.assert.NoError(err)
+	defer f.Close()
+	info, err := f.Stat()
+	suite.assert.NoError(err)
+
+	loader := &inventoryLoader{store: suite.store, asOf: time.Now(), stop: make(chan struct{})}
+	suite.assert.NoError(loader.loadParquet(f, info.Size()))
+	suite.assert.Equal(2, loader.blobs)
+
+	suite.assert.Equal([]string{"a.txt", "dir"}, suite.listing(""))
+	suite.assert.Equal([]string{"dir/b.txt"}, suite.listing("dir"))
+
+	attr, exists, _ := suite.store.Lookup("a.txt")
+	suite.assert.True(exists)
+	suite.assert.EqualValues(10, attr.Size)
+	suite.assert.True(attr.Mtime.Equal(time.UnixMilli(modified)))
+	suite.assert.True(attr.Crtime.Equal(time.UnixMilli(created)))
+	suite.assert.Equal("value", *attr.Metadata["key"])
+
+	attr, exists, _ = suite.store.Lookup("dir/b.txt")
+	suite.assert.True(exists)
+	suite.assert.Equal([]byte{0, 1, 2, 3}, attr.MD5)
+
+	attr, exists, _ = suite.store.Lookup("dir")
+	suite.assert.True(exists)
+	suite.assert.True(attr.IsDir())
+}
+
+func (suite *inventoryTestSuite) TestManifest() {
+	dir := filepath.Join(suite.T().TempDir(), "rule")
+	suite.assert.NoError(os.Mkdir(dir, 0700))
+	report := filepath.Join(dir, "rule_1.csv")
+	manifest := filepath.Join(dir, "rule-manifest.json")
+	suite.assert.Equal(manifest, manifestPath(report))
+	suite.assert.Equal("https://host/c/rule/rule-manifest.json?sig=x", manifestPath("https://host/c/rule/rule_1.csv?sig=x"))
+
+	// Report is as old as the start of the inventory run
+	suite.assert.NoError(os.WriteFile(manifest, []byte(`{"inventoryStartTime":"2024-01-03T00:00:00Z","inventoryCompletionTime":"2024-01-03T01:00:00Z","status":"Succeeded"}`), 0600))
+	asOf, err := readManifest(context.Background(), report)
+	suite.assert.NoError(err)
+	suite.assert.True(asOf.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)))
+
+	suite.assert.NoError(os.WriteFile(manifest, []byte(`{"inventoryCompletionTime":"2024-01-03T01:00:00Z"}`), 0600))
+	asOf, err = readManifest(context.Background(), report)
+	suite.assert.NoError(err)
+	suite.assert.True(asOf.Equal(time.Date(2024, 1, 3, 1, 0, 0, 0, time.UTC)))
+
+	suite.assert.NoError(os.WriteFile(manifest, []byte(`{"inventoryStartTime":"2024-01-03T00:00:00Z","status":"Failed"}`), 0600))
+	_, err = readManifest(context.Background(), report)
+	suite.assert.Error(err)
+
+	suite.assert.NoError(os.WriteFile(manifest, []byte(`{"status":"Succeeded"}`), 0600))
+	_, err = readManifest(context.Background(), report)
+	suite.assert.Error(err)
+
+	suite.assert.NoError(os.Remove(manifest))
+	_, err = readManifest(context.Background(), report)
+	suite.assert.Error(err)
+}
+
+func (suite *inventoryTestSuite) TestOpenURL() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rule/report.csv":
+			fmt.Fprint(w, inventoryHeader)
+		case "/rule/report.parquet":
+			suite.assert.NoError(parquet.Write(w, []parquetRow{{Name: "a.txt"}}))
+		case "/rule/rule-manifest.json":
+			fmt.Fprint(w, `{"inventoryStartTime":"2024-01-03T00:00:00Z","status":"Succeeded"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	asOf, err := readManifest(context.Background(), server.URL+"/rule/report.csv?sig=secret")
+	suite.assert.NoError(err)
+	suite.assert.True(asOf.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)))
+
+	r, err := openInventory(context.Background(), server.URL+"/rule/report.csv?sig=secret")
+	suite.assert.NoError(err)
+	r.Close()
+
+	// Parquet report is downloaded to a file which is gone once closed
+	tempDir := suite.T().TempDir()
+	f, err := openParquetInventory(context.Background(), server.URL+"/rule/report.parquet?sig=secret", tempDir)
+	suite.assert.NoError(err)
+	info, err := f.Stat()
+	suite.assert.NoError(err)
+	loader := &inventoryLoader{store: suite.store, asOf: asOf, stop: make(chan struct{})}
+	suite.assert.NoError(loader.loadParquet(f, info.Size()))
+	suite.assert.Equal(1, loader.blobs)
+	f.Close()
+	entries, _ := os.ReadDir(tempDir)
+	suite.assert.Empty(entries)
+
+	_, err = openInventory(context.Background(), server.URL+"/missing.csv?sig=secret")
+	suite.assert.Error(err)
+	suite.assert.NotContains(err.Error(), "secret")
+
+	_, err = readManifest(context.Background(), server.URL+"/missing/report.csv?sig=secret")
+	suite.assert.Error(err)
+	suite.assert.NotContains(err.Error(), "secret")
+}
+
+func (suite *inventoryTestSuite) TestDownloadCancelled() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, inventoryHeader)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	// Stalled download is given up after the idle timeout
+	timeout := inventoryIdleTimeout
+	inventoryIdleTimeout = 100 * time.Millisecond
+	defer func() { inventoryIdleTimeout = timeout }()
+
+	r, err := openInventory(context.Background(), server.URL+"/report.csv")
+	suite.assert.NoError(err)
+	_, err = io.ReadAll(r)
+	suite.assert.Error(err)
+	r.Close()
+
+	// Download is given up when the component stops
+	inventoryIdleTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err = openInventory(ctx, server.URL+"/report.csv")
+	suite.assert.NoError(err)
+	time.AfterFunc(100*time.Millisecond, cancel)
+	_, err = io.ReadAll(r)
+	suite.assert.Error(err)
+	r.Close()
+}
+
+// Tests attr cache serves attributes imported from an inventory report
+func (suite *attrCacheTestSuite) TestInventory() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default attr cache generated
+
+	dir := filepath.Join(suite.T().TempDir(), "rule")
+	suite.assert.NoError(os.Mkdir(dir, 0700))
+	report := filepath.Join(dir, "rule_1.csv")
+	suite.assert.NoError(os.WriteFile(report, []byte(inventoryHeader+strings.Join(inventoryRows, "\n")+"\n"), 0600))
+	manifest := fmt.Sprintf(`{"inventoryStartTime":"%s","status":"Succeeded"}`, time.Now().UTC().Format(time.RFC3339))
+	suite.assert.NoError(os.WriteFile(filepath.Join(dir, "rule-manifest.json"), []byte(manifest), 0600))
+
+	config := fmt.Sprintf("attr_cache:\n  timeout-sec: 120\n  disk-cache-path: %s\n  inventory-path: %s", suite.T().TempDir(), report)
+	suite.setupTestHelper(config) // setup a new attr cache with a custom config (clean up will occur after the test as usual)
+	suite.attrCache.inventoryWG.Wait()
+
+	attr, err := suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/sub/c.txt"})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(30, attr.Size)
+
+	list, token, err := suite.attrCache.StreamDir(internal.StreamDirOptions{Name: "dir"})
+	suite.assert.NoError(err)
+	suite.assert.Empty(token)
+	suite.assert.Len(list, 2)
+
+	// Names missing from the report are looked up in storage
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dir/new.txt"}).Return(nil, syscall.ENOENT)
+	_, err = suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/new.txt"})
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+// Tests inventory report can not be used without disk cache
+func (suite *attrCacheTestSuite) TestInventoryConfigError() {
+	defer suite.cleanupTest()
+	_ = config.ReadConfigFromReader(strings.NewReader("attr_cache:\n  inventory-path: /tmp/report.csv"))
+	attrCache := NewAttrCacheComponent()
+	err := attrCache.Configure(true)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "inventory-path")
+}
+
+func TestInventoryTestSuite(t *testing.T) {
+	suite.Run(t, new(inventoryTestSuite))
+}
@@ -0,0 +1,386 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Minimal reader of avro object container files, the format events of the blob change feed are stored in.
+// Only what is needed to decode the records is supported: no schema resolution and no codecs other than null and deflate.
+
+var errAvroCorrupt = errors.New("corrupt avro data")
+
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+const avroSyncSize = 16
+
+type avroSchema struct {
+	kind    string
+	fields  []avroField   // record
+	items   *avroSchema   // array
+	values  *avroSchema   // map
+	union   []*avroSchema // union
+	symbols []string      // enum
+	size    int           // fixed
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// parseAvroSchema : Parse the json form of a schema, named types are registered so that later references resolve
+func parseAvroSchema(def any, named map[string]*avroSchema, namespace string) (*avroSchema, error) {
+	switch t := def.(type) {
+	case string:
+		switch t {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroSchema{kind: t}, nil
+		}
+		if s, found := named[t]; found {
+			return s, nil
+		}
+		if s, found := named[namespace+"."+t]; found {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown avro type %s", t)
+
+	case []any:
+		s := &avroSchema{kind: "union"}
+		for _, branch := range t {
+			b, err := parseAvroSchema(branch, named, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.union = append(s.union, b)
+		}
+		return s, nil
+
+	case map[string]any:
+		kind, _ := t["type"].(string)
+		if ns, ok := t["namespace"].(string); ok {
+			namespace = ns
+		}
+
+		s := &avroSchema{kind: kind}
+		switch kind {
+		case "record", "error", "enum", "fixed":
+			name, _ := t["name"].(string)
+			named[name] = s
+			if namespace != "" {
+				named[namespace+"."+name] = s
+			}
+		}
+
+		switch kind {
+		case "record", "error":
+			s.kind = "record"
+			fields, _ := t["fields"].([]any)
+			for _, f := range fields {
+				field, _ := f.(map[string]any)
+				name, _ := field["name"].(string)
+				fs, err := parseAvroSchema(field["type"], named, namespace)
+				if err != nil {
+					return nil, err
+				}
+				s.fields = append(s.fields, avroField{name: name, schema: fs})
+			}
+
+		case "enum":
+			symbols, _ := t["symbols"].([]any)
+			for _, sym := range symbols {
+				name, _ := sym.(string)
+				s.symbols = append(s.symbols, name)
+			}
+
+		case "fixed":
+			size, _ := t["size"].(float64)
+			s.size = int(size)
+
+		case "array":
+			items, err := parseAvroSchema(t["items"], named, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.items = items
+
+		case "map":
+			values, err := parseAvroSchema(t["values"], named, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.values = values
+
+		default:
+			// Primitive type in object form, possibly with a logical type which is ignored
+			return parseAvroSchema(kind, named, namespace)
+		}
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("invalid avro schema")
+}
+
+type avroDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *avroDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errAvroCorrupt
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// long : Zigzag encoded variable length integer
+func (d *avroDecoder) long() (int64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errAvroCorrupt
+	}
+	d.pos += n
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	return d.next(int(n))
+}
+
+// blockCount : Item count of the next block of an array or map, negative counts are followed by the block size
+func (d *avroDecoder) blockCount() (int64, error) {
+	n, err := d.long()
+	if err == nil && n < 0 {
+		n = -n
+		_, err = d.long()
+	}
+	return n, err
+}
+
+func (d *avroDecoder) decode(s *avroSchema) (any, error) {
+	switch s.kind {
+	case "null":
+		return nil, nil
+
+	case "boolean":
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+
+	case "int", "long":
+		return d.long()
+
+	case "float":
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+
+	case "double":
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+
+	case "bytes":
+		return d.bytes()
+
+	case "string":
+		b, err := d.bytes()
+		return string(b), err
+
+	case "fixed":
+		return d.next(s.size)
+
+	case "enum":
+		i, err := d.long()
+		if err != nil || i < 0 || int(i) >= len(s.symbols) {
+			return nil, errAvroCorrupt
+		}
+		return s.symbols[i], nil
+
+	case "union":
+		i, err := d.long()
+		if err != nil || i < 0 || int(i) >= len(s.union) {
+			return nil, errAvroCorrupt
+		}
+		return d.decode(s.union[i])
+
+	case "record":
+		record := make(map[string]any, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.decode(f.schema)
+			if err != nil {
+				return nil, err
+			}
+			record[f.name] = v
+		}
+		return record, nil
+
+	case "array":
+		list := make([]any, 0)
+		for {
+			n, err := d.blockCount()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return list, nil
+			}
+			for ; n > 0; n-- {
+				v, err := d.decode(s.items)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+		}
+
+	case "map":
+		m := make(map[string]any)
+		for {
+			n, err := d.blockCount()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return m, nil
+			}
+			for ; n > 0; n-- {
+				k, err := d.bytes()
+				if err != nil {
+					return nil, err
+				}
+				v, err := d.decode(s.values)
+				if err != nil {
+					return nil, err
+				}
+				m[string(k)] = v
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported avro type %s", s.kind)
+}
+
+// readAvroFile : Decode all records of an object container file, on error records of the blocks before it are returned
+func readAvroFile(data []byte) ([]any, error) {
+	if !bytes.HasPrefix(data, avroMagic) {
+		return nil, errAvroCorrupt
+	}
+
+	d := &avroDecoder{data: data, pos: len(avroMagic)}
+	meta, err := d.decode(&avroSchema{kind: "map", values: &avroSchema{kind: "bytes"}})
+	if err != nil {
+		return nil, err
+	}
+
+	header := meta.(map[string]any)
+	def, _ := header["avro.schema"].([]byte)
+	codec, _ := header["avro.codec"].([]byte)
+
+	var schemaDef any
+	err = json.Unmarshal(def, &schemaDef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema [%s]", err.Error())
+	}
+
+	schema, err := parseAvroSchema(schemaDef, make(map[string]*avroSchema), "")
+	if err != nil {
+		return nil, err
+	}
+
+	sync, err := d.next(avroSyncSize)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]any, 0)
+	for d.pos < len(d.data) {
+		count, err := d.long()
+		if err != nil {
+			return records, err
+		}
+
+		block, err := d.bytes()
+		if err != nil {
+			return records, err
+		}
+
+		switch string(codec) {
+		case "", "null":
+		case "deflate":
+			block, err = io.ReadAll(flate.NewReader(bytes.NewReader(block)))
+			if err != nil {
+				return records, err
+			}
+		default:
+			return records, fmt.Errorf("unsupported avro codec %s", codec)
+		}
+
+		marker, err := d.next(avroSyncSize)
+		if err != nil || !bytes.Equal(marker, sync) {
+			return records, errAvroCorrupt
+		}
+
+		bd := &avroDecoder{data: block}
+		for ; count > 0; count-- {
+			record, err := bd.decode(schema)
+			if err != nil {
+				return records, err
+			}
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,288 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type avroTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (s *avroTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+}
+
+// Schema of change feed events, trimmed to the fields used
+const changeFeedSchema = `{
+	"type": "record", "name": "BlobChangeEvent", "namespace": "com.microsoft.azure.storage.blob",
+	"fields": [
+		{"name": "schemaVersion", "type": "int"},
+		{"name": "topic", "type": "string"},
+		{"name": "subject", "type": "string"},
+		{"name": "eventType", "type": {"type": "enum", "name": "BlobChangeEventType", "symbols": ["UnspecifiedEventType", "BlobCreated", "BlobDeleted", "BlobPropertiesUpdated", "BlobSnapshotCreated", "BlobTierChanged"]}},
+		{"name": "eventTime", "type": "string"},
+		{"name": "id", "type": "string"},
+		{"name": "data", "type": {
+			"type": "record", "name": "BlobChangeEventData",
+			"fields": [
+				{"name": "api", "type": "string"},
+				{"name": "etag", "type": "string"},
+				{"name": "contentLength", "type": "long"},
+				{"name": "recursive", "type": ["null", "boolean"]},
+				{"name": "ratio", "type": "double"},
+				{"name": "tags", "type": {"type": "array", "items": "string"}},
+				{"name": "storageDiagnostics", "type": {"type": "map", "values": "string"}},
+				{"name": "checksum", "type": {"type": "fixed", "name": "MD5", "size": 4}},
+				{"name": "previous", "type": ["null", "BlobChangeEventData"]}
+			]
+		}}
+	]
+}`
+
+// avroEncoder : Encoder of the avro binary form, used to generate change feed chunks in tests
+type avroEncoder struct {
+	bytes.Buffer
+}
+
+func (e *avroEncoder) long(v int64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, uint64((v<<1)^(v>>63)))
+	e.Write(b[:n])
+}
+
+func (e *avroEncoder) bytes(b []byte) {
+	e.long(int64(len(b)))
+	e.Write(b)
+}
+
+func (e *avroEncoder) encode(s *avroSchema, v any) {
+	switch s.kind {
+	case "null":
+	case "boolean":
+		if v.(bool) {
+			e.WriteByte(1)
+		} else {
+			e.WriteByte(0)
+		}
+	case "int", "long":
+		e.long(v.(int64))
+	case "double":
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v.(float64)))
+		e.Write(b)
+	case "string":
+		e.bytes([]byte(v.(string)))
+	case "bytes":
+		e.bytes(v.([]byte))
+	case "fixed":
+		e.Write(v.([]byte))
+	case "enum":
+		for i, sym := range s.symbols {
+			if sym == v.(string) {
+				e.long(int64(i))
+			}
+		}
+	case "union":
+		if v == nil {
+			e.long(0)
+		} else {
+			e.long(1)
+			e.encode(s.union[1], v)
+		}
+	case "record":
+		record := v.(map[string]any)
+		for _, f := range s.fields {
+			e.encode(f.schema, record[f.name])
+		}
+	case "array":
+		list := v.([]any)
+		if len(list) > 0 {
+			// Negative count with block size as writers may use
+			block := &avroEncoder{}
+			for _, item := range list {
+				block.encode(s.items, item)
+			}
+			e.long(-int64(len(list)))
+			e.long(int64(block.Len()))
+			e.Write(block.Bytes())
+		}
+		e.long(0)
+	case "map":
+		m := v.(map[string]any)
+		if len(m) > 0 {
+			e.long(int64(len(m)))
+			for k, val := range m {
+				e.bytes([]byte(k))
+				e.encode(s.values, val)
+			}
+		}
+		e.long(0)
+	}
+}
+
+// writeAvroFile : Object container file with the records split in blocks of given size
+func writeAvroFile(schemaDef string, codec string, perBlock int, records ...any) []byte {
+	var def any
+	_ = json.Unmarshal([]byte(schemaDef), &def)
+	schema, err := parseAvroSchema(def, make(map[string]*avroSchema), "")
+	if err != nil {
+		panic(err)
+	}
+
+	sync := []byte("0123456789abcdef")
+	e := &avroEncoder{}
+	e.Write(avroMagic)
+	header := map[string]any{"avro.schema": []byte(schemaDef)}
+	if codec != "" {
+		header["avro.codec"] = []byte(codec)
+	}
+	e.encode(&avroSchema{kind: "map", values: &avroSchema{kind: "bytes"}}, header)
+	e.Write(sync)
+
+	for i := 0; i < len(records); i += perBlock {
+		end := min(i+perBlock, len(records))
+		block := &avroEncoder{}
+		for _, record := range records[i:end] {
+			block.encode(schema, record)
+		}
+
+		data := block.Bytes()
+		if codec == "deflate" {
+			var buf bytes.Buffer
+			w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+			_, _ = w.Write(data)
+			_ = w.Close()
+			data = buf.Bytes()
+		}
+
+		e.long(int64(end - i))
+		e.bytes(data)
+		e.Write(sync)
+	}
+
+	return e.Bytes()
+}
+
+func changeEvent(eventType string, subject string, etag string) map[string]any {
+	return map[string]any{
+		"schemaVersion": int64(3),
+		"topic":         "/subscriptions/id/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/account",
+		"subject":       subject,
+		"eventType":     eventType,
+		"eventTime":     "2024-01-01T00:00:00.0000000Z",
+		"id":            "id",
+		"data": map[string]any{
+			"api":                "PutBlob",
+			"etag":               etag,
+			"contentLength":      int64(10),
+			"recursive":          nil,
+			"ratio":              0.5,
+			"tags":               []any{"a", "b"},
+			"storageDiagnostics": map[string]any{"bid": "x"},
+			"checksum":           []byte{1, 2, 3, 4},
+			"previous":           nil,
+		},
+	}
+}
+
+func (s *avroTestSuite) TestRead() {
+	for _, codec := range []string{"", "null", "deflate"} {
+		first := changeEvent("BlobCreated", "/blobServices/default/containers/c/blobs/a", "0x1")
+		second := changeEvent("BlobDeleted", "/blobServices/default/containers/c/blobs/b", "0x2")
+		second["data"].(map[string]any)["recursive"] = true
+		second["data"].(map[string]any)["previous"] = first["data"]
+
+		records, err := readAvroFile(writeAvroFile(changeFeedSchema, codec, 1, first, second, first))
+		s.assert.NoError(err, codec)
+		s.assert.Len(records, 3)
+
+		record := records[1].(map[string]any)
+		s.assert.Equal("BlobDeleted", record["eventType"])
+		s.assert.Equal("/blobServices/default/containers/c/blobs/b", record["subject"])
+		s.assert.EqualValues(3, record["schemaVersion"])
+
+		data := record["data"].(map[string]any)
+		s.assert.Equal("0x2", data["etag"])
+		s.assert.EqualValues(10, data["contentLength"])
+		s.assert.Equal(true, data["recursive"])
+		s.assert.Equal(0.5, data["ratio"])
+		s.assert.Equal([]any{"a", "b"}, data["tags"])
+		s.assert.Equal(map[string]any{"bid": "x"}, data["storageDiagnostics"])
+		s.assert.Equal([]byte{1, 2, 3, 4}, data["checksum"])
+		s.assert.Equal("0x1", data["previous"].(map[string]any)["etag"])
+	}
+}
+
+func (s *avroTestSuite) TestReadTruncated() {
+	event := changeEvent("BlobCreated", "/blobServices/default/containers/c/blobs/a", "0x1")
+	data := writeAvroFile(changeFeedSchema, "", 2, event, event, event)
+
+	// Block being appended is incomplete, records of the blocks before it are returned
+	records, err := readAvroFile(data[:len(data)-5])
+	s.assert.Error(err)
+	s.assert.Len(records, 2)
+
+	_, err = readAvroFile([]byte("not avro"))
+	s.assert.Error(err)
+
+	_, err = readAvroFile(writeAvroFile(changeFeedSchema, "snappy", 1, event))
+	s.assert.Error(err)
+}
+
+func (s *avroTestSuite) TestSchemaErrors() {
+	_, err := parseAvroSchema("unknown", make(map[string]*avroSchema), "")
+	s.assert.Error(err)
+
+	_, err = parseAvroSchema(float64(1), make(map[string]*avroSchema), "")
+	s.assert.Error(err)
+
+	schema, err := parseAvroSchema(map[string]any{"type": "string", "logicalType": "uuid"}, make(map[string]*avroSchema), "")
+	s.assert.NoError(err)
+	s.assert.Equal("string", schema.kind)
+}
+
+func TestAvroTestSuite(t *testing.T) {
+	suite.Run(t, new(avroTestSuite))
+}
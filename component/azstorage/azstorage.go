@@ -41,6 +41,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
@@ -58,6 +59,10 @@ type AzStorage struct {
 	stConfig    AzStorageConfig
 	startTime   time.Time
 	listBlocked bool
+
+	head       internal.Component // Head of the pipeline, changes from the change feed are sent through it
+	changeFeed *changeFeed
+	webhook    *eventWebhook
 }
 
 const compName = "azstorage"
@@ -186,16 +191,80 @@ func (az *AzStorage) Start(ctx context.Context) error {
 	// create stats collector for azstorage
 	azStatsCollector = stats_manager.NewStatsCollector(az.Name())
 
+	// Invalidate caches of the pipeline for blobs changed by other clients
+	if az.stConfig.changeFeed {
+		client := az.changeFeedClient()
+		if client == nil {
+			log.Err("AzStorage::Start : change feed is not supported for this account type")
+			return fmt.Errorf("change feed is not supported for this account type")
+		}
+
+		az.changeFeed = newChangeFeed(&containerFeedSource{client: client}, az.stConfig.container, az.stConfig.prefixPath,
+			time.Duration(az.stConfig.changeFeedPoll)*time.Second, az.stConfig.changeFeedCheckpoint)
+		az.changeFeed.invalidate = az.invalidate
+		az.changeFeed.start()
+	}
+
+	if az.stConfig.webhookAddress != "" {
+		az.webhook = newEventWebhook(az.stConfig.container, az.stConfig.prefixPath, az.stConfig.webhookSecret)
+		az.webhook.invalidate = az.invalidate
+		err := az.webhook.start(az.stConfig.webhookAddress, az.stConfig.webhookCertFile, az.stConfig.webhookKeyFile)
+		if err != nil {
+			az.webhook = nil
+			if az.changeFeed != nil {
+				az.changeFeed.close()
+				az.changeFeed = nil
+			}
+			return fmt.Errorf("failed to start event webhook [%s]", err.Error())
+		}
+	}
+
 	return nil
 }
 
 // Stop : Disconnect all running operations here
 func (az *AzStorage) Stop() error {
 	log.Trace("AzStorage::Stop : Stopping component %s", az.Name())
+	if az.changeFeed != nil {
+		az.changeFeed.close()
+		az.changeFeed = nil
+	}
+	if az.webhook != nil {
+		az.webhook.close()
+		az.webhook = nil
+	}
 	azStatsCollector.Destroy()
 	return nil
 }
 
+// SetPipelineHead : Keep the head of the pipeline to send invalidations from the change feed and webhook through
+func (az *AzStorage) SetPipelineHead(head internal.Component) {
+	az.head = head
+}
+
+// changeFeedClient : Client of the change feed container of the account
+func (az *AzStorage) changeFeedClient() *container.Client {
+	switch conn := az.storage.(type) {
+	case *BlockBlob:
+		return conn.Service.NewContainerClient(changeFeedContainer)
+	case *Datalake:
+		return conn.BlockBlob.Service.NewContainerClient(changeFeedContainer)
+	}
+	return nil
+}
+
+// invalidate : Send a change made by another client through the pipeline
+func (az *AzStorage) invalidate(options internal.InvalidatePathOptions) {
+	if az.head == nil {
+		return
+	}
+
+	err := az.head.InvalidatePath(options)
+	if err != nil {
+		log.Warn("AzStorage::invalidate : Failed to invalidate %s [%s]", options.Name, err.Error())
+	}
+}
+
 // ------------------------- Container listing -------------------------------------------
 func (az *AzStorage) ListContainers() ([]string, error) {
 	return az.storage.ListContainers()
@@ -344,6 +413,44 @@ func (az *AzStorage) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 	return new_list, *new_marker, nil
 }
 
+// ListPrefix : List one page of objects whose path begins with the given prefix
+func (az *AzStorage) ListPrefix(options internal.ListPrefixOptions) ([]*internal.ObjAttr, string, error) {
+	log.Trace("AzStorage::ListPrefix : Prefix %s, recursive %v", options.Prefix, options.Recursive)
+
+	if az.listBlocked {
+		diff := time.Since(az.startTime)
+		if diff.Seconds() > float64(az.stConfig.cancelListForSeconds) {
+			az.listBlocked = false
+			log.Info("AzStorage::ListPrefix : Unblocked List API")
+		} else {
+			log.Info("AzStorage::ListPrefix : Blocked List API for %d more seconds", int(az.stConfig.cancelListForSeconds)-int(diff.Seconds()))
+			return make([]*internal.ObjAttr, 0), "", nil
+		}
+	}
+
+	var marker *string
+	if options.Token != "" {
+		marker = &options.Token
+	}
+
+	new_list, new_marker, err := az.storage.ListPrefix(options.Prefix, marker, options.Count, options.Recursive)
+	if err != nil {
+		log.Err("AzStorage::ListPrefix : Failed to list prefix %s [%s]", options.Prefix, err)
+		return new_list, "", err
+	}
+
+	if new_marker == nil {
+		new_marker = to.Ptr("")
+	}
+
+	log.Debug("AzStorage::ListPrefix : Retrieved %d objects for prefix %s, next-marker %s", len(new_list), options.Prefix, *new_marker)
+
+	// increment streamdir call count
+	azStatsCollector.UpdateStats(stats_manager.Increment, streamDir, (int64)(1))
+
+	return new_list, *new_marker, nil
+}
+
 func (az *AzStorage) RenameDir(options internal.RenameDirOptions) error {
 	log.Trace("AzStorage::RenameDir : %s to %s", options.Src, options.Dst)
 	options.Src = internal.TruncateDirName(options.Src)
@@ -515,6 +622,9 @@ func (az *AzStorage) CopyToFile(options internal.CopyToFileOptions) error {
 
 func (az *AzStorage) CopyFromFile(options internal.CopyFromFileOptions) error {
 	log.Trace("AzStorage::CopyFromFile : Upload file %s", options.Name)
+	if options.File == nil && options.Reader != nil {
+		return az.storage.WriteFromReader(options.Name, options.Metadata, options.Reader)
+	}
 	return az.storage.WriteFromFile(options.Name, options.Metadata, options.File)
 }
 
@@ -638,6 +638,73 @@ func (bb *BlockBlob) List(prefix string, marker *string, count int32) ([]*intern
 	return blobList, listBlob.NextMarker, nil
 }
 
+// ListPrefix : Get a list of blobs whose name begins with the given prefix
+// Unlike List the prefix is used as is, so it may end in the middle of a name. This allows a directory to be split
+// into character ranges which are listed in parallel. If recursive is set the listing is flat and returns every
+// blob under the prefix, otherwise blobs are grouped by directory same as List.
+func (bb *BlockBlob) ListPrefix(prefix string, marker *string, count int32, recursive bool) ([]*internal.ObjAttr, *string, error) {
+	log.Trace("BlockBlob::ListPrefix : prefix %s, recursive %v", prefix, recursive)
+
+	if count == 0 {
+		count = common.MaxDirListCount
+	}
+
+	// filepath.Join would clean up the trailing '/' and '.' characters which are valid parts of a raw prefix
+	listPath := prefix
+	if bb.Config.prefixPath != "" {
+		listPath = bb.Config.prefixPath + "/" + prefix
+	}
+
+	if !recursive {
+		pager := bb.Container.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{
+			Marker:     marker,
+			MaxResults: &count,
+			Prefix:     &listPath,
+			Include:    bb.listDetails,
+		})
+
+		listBlob, err := pager.NextPage(context.Background())
+		if err != nil {
+			log.Err("BlockBlob::ListPrefix : Failed to list the container with the prefix %s [%s]", listPath, err.Error())
+			return nil, nil, err
+		}
+
+		blobList, dirList, err := bb.processBlobItems(listBlob.Segment.BlobItems)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = bb.processBlobPrefixes(listBlob.Segment.BlobPrefixes, dirList, &blobList)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return blobList, listBlob.NextMarker, nil
+	}
+
+	pager := bb.Container.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Marker:     marker,
+		MaxResults: &count,
+		Prefix:     &listPath,
+		Include:    bb.listDetails,
+	})
+
+	listBlob, err := pager.NextPage(context.Background())
+	if err != nil {
+		log.Err("BlockBlob::ListPrefix : Failed to list the container with the prefix %s [%s]", listPath, err.Error())
+		return nil, nil, err
+	}
+
+	// Flat listing does not return prefixes, so directories without a marker blob are not part of the result.
+	// Callers are expected to derive such directories from the paths of the blobs inside them.
+	blobList, _, err := bb.processBlobItems(listBlob.Segment.BlobItems)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return blobList, listBlob.NextMarker, nil
+}
+
 func (bb *BlockBlob) getListPath(prefix string) string {
 	listPath := filepath.Join(bb.Config.prefixPath, prefix)
 	if (prefix != "" && prefix[len(prefix)-1] == '/') || (prefix == "" && bb.Config.prefixPath != "") {
@@ -1121,6 +1188,40 @@ func (bb *BlockBlob) WriteFromFile(name string, metadata map[string]*string, fi
 	return nil
 }
 
+// WriteFromReader : Upload all data of a reader to a blob
+func (bb *BlockBlob) WriteFromReader(name string, metadata map[string]*string, reader io.Reader) error {
+	log.Trace("BlockBlob::WriteFromReader : name %s", name)
+	blobClient := bb.Container.NewBlockBlobClient(filepath.Join(bb.Config.prefixPath, name))
+
+	defer log.TimeTrack(time.Now(), "BlockBlob::WriteFromReader", name)
+
+	_, err := blobClient.UploadStream(context.Background(), reader, &blockblob.UploadStreamOptions{
+		BlockSize:   bb.Config.blockSize,
+		Concurrency: int(bb.Config.maxConcurrency),
+		Metadata:    metadata,
+		AccessTier:  bb.Config.defaultTier,
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType: to.Ptr(getContentType(name)),
+		},
+		CPKInfo: bb.blobCPKOpt,
+	})
+
+	if err != nil {
+		serr := storeBlobErrToErr(err)
+		if serr == BlobIsUnderLease {
+			log.Err("BlockBlob::WriteFromReader : %s is under a lease, can not update file [%s]", name, err.Error())
+			return syscall.EIO
+		} else if serr == InvalidPermission {
+			log.Err("BlockBlob::WriteFromReader : Insufficient permissions for %s [%s]", name, err.Error())
+			return syscall.EACCES
+		}
+		log.Err("BlockBlob::WriteFromReader : Failed to upload blob %s [%s]", name, err.Error())
+		return err
+	}
+
+	return nil
+}
+
 // WriteFromBuffer : Upload from a buffer to a blob
 func (bb *BlockBlob) WriteFromBuffer(name string, metadata map[string]*string, data []byte) error {
 	log.Trace("BlockBlob::WriteFromBuffer : name %s", name)
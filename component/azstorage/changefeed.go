@@ -0,0 +1,415 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// Change feed of the account is kept in this container, events of an hour form a segment
+// whose events are spread over shards of avro files appended to while the segment is open:
+//
+//	meta/segments.json                          : time until which segments can be read
+//	idx/segments/YYYY/MM/DD/HH00/meta.json      : status and shards of a segment
+//	log/<shard>/YYYY/MM/DD/HH00/<chunk>.avro    : events
+const changeFeedContainer = "$blobchangefeed"
+
+const defaultChangeFeedPollSec = 30
+
+// Events which change data or properties of a blob, others like tier changes are not of interest to the caches
+var changeFeedEvents = map[string]bool{
+	"BlobCreated":           true,
+	"BlobDeleted":           true,
+	"BlobPropertiesUpdated": true,
+}
+
+// changeFeedSource : Access to the blobs of the change feed container
+type changeFeedSource interface {
+	read(name string) ([]byte, error)
+	list(prefix string) ([]string, error)
+}
+
+// containerFeedSource : Change feed container read through the blob endpoint of the account
+type containerFeedSource struct {
+	client *container.Client
+}
+
+func (s *containerFeedSource) read(name string) ([]byte, error) {
+	resp, err := s.client.NewBlobClient(name).DownloadStream(context.Background(), nil)
+	if err != nil {
+		if storeBlobErrToErr(err) == ErrFileNotFound {
+			return nil, syscall.ENOENT
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (s *containerFeedSource) list(prefix string) ([]string, error) {
+	names := make([]string, 0)
+	pager := s.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix: to.Ptr(prefix),
+	})
+	for pager.More() {
+		resp, err := pager.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, blobInfo := range resp.Segment.BlobItems {
+			names = append(names, *blobInfo.Name)
+		}
+	}
+	return names, nil
+}
+
+// chunkCursor : Last chunk of a shard read and the number of events consumed from it
+type chunkCursor struct {
+	Chunk  string
+	Events int
+}
+
+// changeFeedCursor : Position of the consumer, persisted so that a remount resumes from it
+type changeFeedCursor struct {
+	Segment time.Time               // Begin of the segment being read
+	Shards  map[string]*chunkCursor // Keyed by the path of the shard
+}
+
+type segmentMeta struct {
+	Status         string   `json:"status"`
+	ChunkFilePaths []string `json:"chunkFilePaths"`
+}
+
+type segmentsMeta struct {
+	LastConsumable time.Time `json:"lastConsumable"`
+}
+
+// changeFeed : Consumer of the change feed which invalidates caches of the pipeline for blobs changed by other clients
+type changeFeed struct {
+	source     changeFeedSource
+	container  string
+	prefix     string
+	poll       time.Duration
+	checkpoint string
+	cursor     changeFeedCursor
+	invalidate func(internal.InvalidatePathOptions)
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// changeFeedCheckpoint : Default checkpoint file, one per container and subdirectory mounted
+func changeFeedCheckpoint(account string, container string, prefix string) string {
+	hash := sha256.Sum256([]byte(account + "/" + container + "/" + prefix))
+	return filepath.Join(common.ExpandPath(common.DefaultWorkDir), "changefeed_"+hex.EncodeToString(hash[:8])+".json")
+}
+
+func newChangeFeed(source changeFeedSource, container string, prefix string, poll time.Duration, checkpoint string) *changeFeed {
+	cf := &changeFeed{
+		source:     source,
+		container:  container,
+		prefix:     strings.Trim(prefix, "/"),
+		poll:       poll,
+		checkpoint: checkpoint,
+	}
+
+	// Resume from the checkpoint, otherwise start with the current segment as older changes are not cached
+	err := cf.load()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("changeFeed::newChangeFeed : Failed to load checkpoint %s [%s]", checkpoint, err.Error())
+		}
+		cf.cursor = changeFeedCursor{Segment: time.Now().UTC().Truncate(time.Hour)}
+	}
+
+	return cf
+}
+
+func (cf *changeFeed) load() error {
+	data, err := os.ReadFile(cf.checkpoint)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &cf.cursor)
+}
+
+// save : Persist the cursor through a temp file so that a crash never leaves a partial checkpoint
+func (cf *changeFeed) save() error {
+	data, err := json.Marshal(&cf.cursor)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(cf.checkpoint), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := cf.checkpoint + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, cf.checkpoint)
+}
+
+// start : Poll the change feed in background until stopped
+func (cf *changeFeed) start() {
+	cf.stop = make(chan struct{})
+	cf.wg.Add(1)
+
+	go func() {
+		defer cf.wg.Done()
+
+		ticker := time.NewTicker(cf.poll)
+		defer ticker.Stop()
+
+		for {
+			err := cf.consume()
+			if err != nil {
+				log.Warn("changeFeed::start : Failed to read change feed [%s]", err.Error())
+			}
+
+			select {
+			case <-cf.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (cf *changeFeed) close() {
+	if cf.stop != nil {
+		close(cf.stop)
+		cf.wg.Wait()
+		cf.stop = nil
+	}
+}
+
+func (cf *changeFeed) stopped() bool {
+	select {
+	case <-cf.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// consume : Process the events published since the cursor and move the cursor past them
+func (cf *changeFeed) consume() error {
+	data, err := cf.source.read("meta/segments.json")
+	if err != nil {
+		return err
+	}
+
+	meta := segmentsMeta{}
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := cf.save()
+		if err != nil {
+			log.Warn("changeFeed::consume : Failed to save checkpoint %s [%s]", cf.checkpoint, err.Error())
+		}
+	}()
+
+	for !cf.cursor.Segment.After(meta.LastConsumable) && !cf.stopped() {
+		finalized, err := cf.readSegment(cf.cursor.Segment, meta.LastConsumable)
+		if err != nil {
+			return err
+		}
+
+		if !finalized {
+			// Events are still being added to this segment, continue with it on next poll
+			break
+		}
+
+		cf.cursor.Segment = cf.cursor.Segment.Add(time.Hour)
+		cf.cursor.Shards = nil
+	}
+
+	return nil
+}
+
+// readSegment : Process new events of a segment, returns true once the segment is complete and fully processed
+func (cf *changeFeed) readSegment(begin time.Time, lastConsumable time.Time) (bool, error) {
+	name := fmt.Sprintf("idx/segments/%04d/%02d/%02d/%02d00/meta.json", begin.Year(), begin.Month(), begin.Day(), begin.Hour())
+	data, err := cf.source.read(name)
+	if err == syscall.ENOENT {
+		// No segment for an hour without changes, unless it is yet to be published
+		return !begin.Add(time.Hour).After(lastConsumable), nil
+	} else if err != nil {
+		return false, err
+	}
+
+	meta := segmentMeta{}
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return false, err
+	}
+
+	if cf.cursor.Shards == nil {
+		cf.cursor.Shards = make(map[string]*chunkCursor)
+	}
+
+	for _, shard := range meta.ChunkFilePaths {
+		shard = strings.TrimPrefix(shard, changeFeedContainer+"/")
+		err = cf.readShard(shard)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return meta.Status == "Finalized", nil
+}
+
+// readShard : Process events of the chunks of a shard after the cursor
+func (cf *changeFeed) readShard(shard string) error {
+	chunks, err := cf.source.list(shard)
+	if err != nil {
+		return err
+	}
+	sort.Strings(chunks)
+
+	cursor, found := cf.cursor.Shards[shard]
+	if !found {
+		cursor = &chunkCursor{}
+		cf.cursor.Shards[shard] = cursor
+	}
+
+	for _, chunk := range chunks {
+		if chunk < cursor.Chunk || cf.stopped() {
+			continue
+		}
+
+		data, err := cf.source.read(chunk)
+		if err != nil {
+			return err
+		}
+
+		// Chunk being appended to may end with an incomplete block, events before it are consumed
+		events, err := readAvroFile(data)
+		if err != nil {
+			log.Debug("changeFeed::readShard : %s read till event %d [%s]", chunk, len(events), err.Error())
+		}
+
+		skip := 0
+		if chunk == cursor.Chunk {
+			skip = cursor.Events
+		}
+		for i := skip; i < len(events); i++ {
+			cf.process(events[i])
+		}
+
+		cursor.Chunk = chunk
+		cursor.Events = max(skip, len(events))
+	}
+
+	return nil
+}
+
+// process : Invalidate the blob an event is about if it is in the mounted container
+func (cf *changeFeed) process(event any) {
+	record, ok := event.(map[string]any)
+	if !ok {
+		return
+	}
+
+	eventType, _ := record["eventType"].(string)
+	if !changeFeedEvents[eventType] {
+		return
+	}
+
+	subject, _ := record["subject"].(string)
+	name, ok := subjectPath(subject, cf.container, cf.prefix)
+	if !ok {
+		return
+	}
+
+	options := internal.InvalidatePathOptions{Name: name}
+	if eventType != "BlobDeleted" {
+		if data, ok := record["data"].(map[string]any); ok {
+			etag, _ := data["etag"].(string)
+			options.ETag = strings.Trim(etag, `"`)
+		}
+	}
+
+	log.Debug("changeFeed::process : %s %s", eventType, name)
+	if cf.invalidate != nil {
+		cf.invalidate(options)
+	}
+}
+
+// subjectPath : Path in the mount of the blob an event subject refers to, false if it is outside the mounted container and prefix
+func subjectPath(subject string, container string, prefix string) (string, bool) {
+	blobs := "/blobServices/default/containers/" + container + "/blobs/"
+	if !strings.HasPrefix(subject, blobs) {
+		return "", false
+	}
+	return trimMountPrefix(strings.TrimPrefix(subject, blobs), prefix)
+}
+
+// trimMountPrefix : Strip the subdirectory mounted from the name of a blob, false if the blob is not under it
+func trimMountPrefix(name string, prefix string) (string, bool) {
+	if prefix == "" {
+		return name, true
+	}
+	if !strings.HasPrefix(name, prefix+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(name, prefix+"/"), true
+}
@@ -0,0 +1,290 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// memFeedSource : Change feed container held in memory
+type memFeedSource struct {
+	sync.Mutex
+	blobs map[string][]byte
+	err   error
+}
+
+func (m *memFeedSource) read(name string) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+	data, found := m.blobs[name]
+	if !found {
+		return nil, syscall.ENOENT
+	}
+	return data, nil
+}
+
+func (m *memFeedSource) list(prefix string) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	names := make([]string, 0)
+	for name := range m.blobs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (m *memFeedSource) put(name string, data []byte) {
+	m.Lock()
+	defer m.Unlock()
+	m.blobs[name] = data
+}
+
+type changeFeedTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	source      *memFeedSource
+	checkpoint  string
+	invalidated []internal.InvalidatePathOptions
+	lock        sync.Mutex
+}
+
+func (s *changeFeedTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+
+	s.assert = assert.New(s.T())
+	s.source = &memFeedSource{blobs: make(map[string][]byte)}
+	s.checkpoint = filepath.Join(s.T().TempDir(), "checkpoint.json")
+	s.invalidated = nil
+}
+
+func (s *changeFeedTestSuite) newFeed(prefix string) *changeFeed {
+	cf := newChangeFeed(s.source, "container", prefix, time.Hour, s.checkpoint)
+	cf.invalidate = func(options internal.InvalidatePathOptions) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.invalidated = append(s.invalidated, options)
+	}
+	return cf
+}
+
+func (s *changeFeedTestSuite) names() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := make([]string, 0)
+	for _, options := range s.invalidated {
+		names = append(names, options.Name)
+	}
+	return names
+}
+
+func (s *changeFeedTestSuite) publish(lastConsumable time.Time) {
+	data, _ := json.Marshal(map[string]any{"version": 0, "lastConsumable": lastConsumable})
+	s.source.put("meta/segments.json", data)
+}
+
+// segment : Add a segment with one shard, returns the path of the shard
+func (s *changeFeedTestSuite) segment(begin time.Time, status string) string {
+	path := fmt.Sprintf("%04d/%02d/%02d/%02d00", begin.Year(), begin.Month(), begin.Day(), begin.Hour())
+	shard := "log/00/" + path + "/"
+	data, _ := json.Marshal(map[string]any{
+		"version":        0,
+		"begin":          begin,
+		"intervalSecs":   3600,
+		"status":         status,
+		"chunkFilePaths": []string{changeFeedContainer + "/" + shard},
+	})
+	s.source.put("idx/segments/"+path+"/meta.json", data)
+	return shard
+}
+
+func event(eventType string, container string, name string) any {
+	return changeEvent(eventType, "/blobServices/default/containers/"+container+"/blobs/"+name, "\"0x"+name+"\"")
+}
+
+func (s *changeFeedTestSuite) TestConsume() {
+	begin := time.Now().UTC().Truncate(time.Hour)
+	s.publish(begin)
+	shard := s.segment(begin, "Publishing")
+
+	s.source.put(shard+"00000.avro", writeAvroFile(changeFeedSchema, "", 2,
+		event("BlobCreated", "container", "a"),
+		event("BlobTierChanged", "container", "b"),
+		event("BlobDeleted", "other", "c"),
+		event("BlobDeleted", "container", "dir/d"),
+	))
+
+	cf := s.newFeed("")
+	s.assert.NoError(cf.consume())
+	s.assert.Equal([]string{"a", "dir/d"}, s.names())
+	s.assert.Equal("0xa", s.invalidated[0].ETag)
+	s.assert.Empty(s.invalidated[1].ETag)
+
+	// Events appended to the chunk and a new chunk are processed once
+	s.source.put(shard+"00000.avro", writeAvroFile(changeFeedSchema, "", 2,
+		event("BlobCreated", "container", "a"),
+		event("BlobTierChanged", "container", "b"),
+		event("BlobDeleted", "other", "c"),
+		event("BlobDeleted", "container", "dir/d"),
+		event("BlobPropertiesUpdated", "container", "e"),
+	))
+	s.source.put(shard+"00001.avro", writeAvroFile(changeFeedSchema, "deflate", 2, event("BlobCreated", "container", "f")))
+	s.assert.NoError(cf.consume())
+	s.assert.Equal([]string{"a", "dir/d", "e", "f"}, s.names())
+	s.assert.Equal(begin, cf.cursor.Segment)
+
+	// Cursor moves to the next segment once this one is finalized
+	s.segment(begin, "Finalized")
+	next := begin.Add(time.Hour)
+	s.publish(next)
+	nextShard := s.segment(next, "Publishing")
+	s.source.put(nextShard+"00000.avro", writeAvroFile(changeFeedSchema, "", 1, event("BlobCreated", "container", "g")))
+
+	s.assert.NoError(cf.consume())
+	s.assert.Equal([]string{"a", "dir/d", "e", "f", "g"}, s.names())
+	s.assert.Equal(next, cf.cursor.Segment)
+}
+
+func (s *changeFeedTestSuite) TestCheckpoint() {
+	begin := time.Now().UTC().Truncate(time.Hour)
+	s.publish(begin)
+	shard := s.segment(begin, "Publishing")
+	s.source.put(shard+"00000.avro", writeAvroFile(changeFeedSchema, "", 1, event("BlobCreated", "container", "a")))
+
+	cf := s.newFeed("")
+	s.assert.NoError(cf.consume())
+	s.assert.FileExists(s.checkpoint)
+
+	// Remount resumes after the events already processed
+	s.source.put(shard+"00000.avro", writeAvroFile(changeFeedSchema, "", 1,
+		event("BlobCreated", "container", "a"), event("BlobCreated", "container", "b")))
+	s.invalidated = nil
+	cf = s.newFeed("")
+	s.assert.NoError(cf.consume())
+	s.assert.Equal([]string{"b"}, s.names())
+}
+
+func (s *changeFeedTestSuite) TestSkipEmptySegments() {
+	begin := time.Now().UTC().Truncate(time.Hour).Add(-3 * time.Hour)
+	data, _ := json.Marshal(&changeFeedCursor{Segment: begin})
+	s.assert.NoError(writeCheckpoint(s.checkpoint, data))
+
+	last := begin.Add(2 * time.Hour)
+	s.publish(last)
+	shard := s.segment(last, "Publishing")
+	s.source.put(shard+"00000.avro", writeAvroFile(changeFeedSchema, "", 1, event("BlobCreated", "container", "a")))
+
+	cf := s.newFeed("")
+	s.assert.Equal(begin, cf.cursor.Segment)
+	s.assert.NoError(cf.consume())
+	s.assert.Equal(last, cf.cursor.Segment)
+	s.assert.Equal([]string{"a"}, s.names())
+}
+
+func (s *changeFeedTestSuite) TestPrefix() {
+	begin := time.Now().UTC().Truncate(time.Hour)
+	s.publish(begin)
+	shard := s.segment(begin, "Publishing")
+	s.source.put(shard+"00000.avro", writeAvroFile(changeFeedSchema, "", 1,
+		event("BlobCreated", "container", "sub/a"),
+		event("BlobCreated", "container", "subway/b"),
+		event("BlobCreated", "container", "c"),
+	))
+
+	cf := s.newFeed("/sub/")
+	s.assert.NoError(cf.consume())
+	s.assert.Equal([]string{"a"}, s.names())
+}
+
+func (s *changeFeedTestSuite) TestErrors() {
+	cf := s.newFeed("")
+	s.assert.Error(cf.consume())
+
+	s.source.put("meta/segments.json", []byte("not json"))
+	s.assert.Error(cf.consume())
+
+	s.source.err = errors.New("failed")
+	s.assert.Error(cf.consume())
+}
+
+func (s *changeFeedTestSuite) TestStartStop() {
+	begin := time.Now().UTC().Truncate(time.Hour)
+	s.publish(begin)
+	shard := s.segment(begin, "Publishing")
+	s.source.put(shard+"00000.avro", writeAvroFile(changeFeedSchema, "", 1, event("BlobCreated", "container", "a")))
+
+	cf := s.newFeed("")
+	cf.start()
+	s.assert.Eventually(func() bool { return len(s.names()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	cf.close()
+	cf.close()
+}
+
+func writeCheckpoint(path string, data []byte) error {
+	cf := &changeFeed{checkpoint: path}
+	err := json.Unmarshal(data, &cf.cursor)
+	if err != nil {
+		return err
+	}
+	return cf.save()
+}
+
+func TestChangeFeedTestSuite(t *testing.T) {
+	suite.Run(t, new(changeFeedTestSuite))
+}
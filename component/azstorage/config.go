@@ -40,6 +40,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/vibhansa-msft/blobfilter"
@@ -195,6 +196,13 @@ type AzStorageOptions struct {
 	PreserveACL             bool   `config:"preserve-acl" yaml:"preserve-acl"`
 	Filter                  string `config:"filter" yaml:"filter"`
 	UserAssertion           string `config:"user-assertion" yaml:"user-assertions"`
+	ChangeFeed              bool   `config:"changefeed" yaml:"changefeed,omitempty"`
+	ChangeFeedPollSec       uint32 `config:"changefeed-poll-sec" yaml:"changefeed-poll-sec,omitempty"`
+	ChangeFeedCheckpoint    string `config:"changefeed-checkpoint" yaml:"changefeed-checkpoint,omitempty"`
+	WebhookAddress          string `config:"webhook-address" yaml:"webhook-address,omitempty"`
+	WebhookSecret           string `config:"webhook-secret" yaml:"webhook-secret,omitempty"`
+	WebhookCertFile         string `config:"webhook-cert-file" yaml:"webhook-cert-file,omitempty"`
+	WebhookKeyFile          string `config:"webhook-key-file" yaml:"webhook-key-file,omitempty"`
 
 	// v1 support
 	UseAdls        bool   `config:"use-adls" yaml:"-"`
@@ -537,7 +545,36 @@ func ParseAndValidateConfig(az *AzStorage, opt AzStorageOptions) error {
 	log.Crit("ParseAndValidateConfig : Retry Config: retry-count %d, max-timeout %d, backoff-time %d, max-delay %d, preserve-acl: %v",
 		az.stConfig.maxRetries, az.stConfig.maxTimeout, az.stConfig.backoffTime, az.stConfig.maxRetryDelay, az.stConfig.preserveACL)
 
+	// Change feed based invalidation of caches
+	az.stConfig.changeFeed = opt.ChangeFeed
+	az.stConfig.changeFeedPoll = defaultChangeFeedPollSec
+	if opt.ChangeFeedPollSec != 0 {
+		az.stConfig.changeFeedPoll = opt.ChangeFeedPollSec
+	}
+	az.stConfig.changeFeedCheckpoint = common.ExpandPath(opt.ChangeFeedCheckpoint)
+	if az.stConfig.changeFeedCheckpoint == "" {
+		az.stConfig.changeFeedCheckpoint = changeFeedCheckpoint(az.stConfig.authConfig.AccountName, az.stConfig.container, az.stConfig.prefixPath)
+	}
+
+	// Event Grid webhook based invalidation of caches
+	az.stConfig.webhookAddress = opt.WebhookAddress
+	az.stConfig.webhookSecret = opt.WebhookSecret
+	az.stConfig.webhookCertFile = common.ExpandPath(opt.WebhookCertFile)
+	az.stConfig.webhookKeyFile = common.ExpandPath(opt.WebhookKeyFile)
+	if az.stConfig.webhookAddress != "" {
+		if az.stConfig.webhookSecret == "" {
+			return errors.New("webhook-secret not provided")
+		}
+		if (az.stConfig.webhookCertFile == "") != (az.stConfig.webhookKeyFile == "") {
+			return errors.New("webhook-cert-file and webhook-key-file must be provided together")
+		}
+	}
+
 	log.Crit("ParseAndValidateConfig : Telemetry : %s, honour-ACL %v", az.stConfig.telemetry, az.stConfig.honourACL)
+	log.Crit("ParseAndValidateConfig : changefeed %v, changefeed-poll-sec %d, changefeed-checkpoint %s",
+		az.stConfig.changeFeed, az.stConfig.changeFeedPoll, az.stConfig.changeFeedCheckpoint)
+	log.Crit("ParseAndValidateConfig : webhook-address %s, webhook-TLS %v",
+		az.stConfig.webhookAddress, az.stConfig.webhookCertFile != "")
 
 	return nil
 }
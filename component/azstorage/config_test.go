@@ -437,6 +437,32 @@ func (s *configTestSuite) TestSASRefresh() {
 	assert.Nil(err)
 }
 
+func (s *configTestSuite) TestWebhook() {
+	defer config.ResetConfig()
+	assert := assert.New(s.T())
+	az := &AzStorage{}
+	opt := AzStorageOptions{}
+	opt.AccountName = "abcd"
+	opt.Container = "abcd"
+	opt.WebhookAddress = "127.0.0.1:8095"
+
+	err := ParseAndValidateConfig(az, opt)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "webhook-secret not provided")
+
+	opt.WebhookSecret = "secret"
+	opt.WebhookCertFile = "cert.pem"
+	err = ParseAndValidateConfig(az, opt)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "must be provided together")
+
+	opt.WebhookKeyFile = "key.pem"
+	err = ParseAndValidateConfig(az, opt)
+	assert.Nil(err)
+	assert.Equal("127.0.0.1:8095", az.stConfig.webhookAddress)
+	assert.Equal("secret", az.stConfig.webhookSecret)
+}
+
 func TestConfigTestSuite(t *testing.T) {
 	suite.Run(t, new(configTestSuite))
 }
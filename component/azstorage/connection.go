@@ -34,6 +34,7 @@
 package azstorage
 
 import (
+	"io"
 	"os"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
@@ -85,6 +86,15 @@ type AzStorageConfig struct {
 
 	// Blob filters
 	filter *blobfilter.BlobFilter
+
+	// Change feed based invalidation of caches
+	changeFeed           bool
+	changeFeedPoll       uint32
+	changeFeedCheckpoint string
+	webhookAddress       string
+	webhookSecret        string
+	webhookCertFile      string
+	webhookKeyFile       string
 }
 
 type AzStorageConnection struct {
@@ -118,12 +128,14 @@ type AzConnection interface {
 
 	// Standard operations to be supported by any account type
 	List(prefix string, marker *string, count int32) ([]*internal.ObjAttr, *string, error)
+	ListPrefix(prefix string, marker *string, count int32, recursive bool) ([]*internal.ObjAttr, *string, error)
 
 	ReadToFile(name string, offset int64, count int64, fi *os.File) error
 	ReadBuffer(name string, offset int64, len int64) ([]byte, error)
 	ReadInBuffer(name string, offset int64, len int64, data []byte, etag *string) error
 
 	WriteFromFile(name string, metadata map[string]*string, fi *os.File) error
+	WriteFromReader(name string, metadata map[string]*string, reader io.Reader) error
 	WriteFromBuffer(name string, metadata map[string]*string, data []byte) error
 	Write(options internal.WriteFileOptions) error
 	GetFileBlockOffsets(name string) (*common.BlockOffsetList, error)
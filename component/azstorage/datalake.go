@@ -37,6 +37,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -457,6 +458,11 @@ func (dl *Datalake) List(prefix string, marker *string, count int32) ([]*interna
 	return dl.BlockBlob.List(prefix, marker, count)
 }
 
+// ListPrefix : Get a list of path whose name begins with the given prefix
+func (dl *Datalake) ListPrefix(prefix string, marker *string, count int32, recursive bool) ([]*internal.ObjAttr, *string, error) {
+	return dl.BlockBlob.ListPrefix(prefix, marker, count, recursive)
+}
+
 // ReadToFile : Download a file to a local file
 func (dl *Datalake) ReadToFile(name string, offset int64, count int64, fi *os.File) (err error) {
 	return dl.BlockBlob.ReadToFile(name, offset, count, fi)
@@ -474,6 +480,20 @@ func (dl *Datalake) ReadInBuffer(name string, offset int64, len int64, data []by
 
 // WriteFromFile : Upload local file to file
 func (dl *Datalake) WriteFromFile(name string, metadata map[string]*string, fi *os.File) (err error) {
+	return dl.preserveACL(name, func() error {
+		return dl.BlockBlob.WriteFromFile(name, metadata, fi)
+	})
+}
+
+// WriteFromReader : Upload all data of a reader to a file
+func (dl *Datalake) WriteFromReader(name string, metadata map[string]*string, reader io.Reader) error {
+	return dl.preserveACL(name, func() error {
+		return dl.BlockBlob.WriteFromReader(name, metadata, reader)
+	})
+}
+
+// preserveACL : Upload a file retaining its permissions and ACL
+func (dl *Datalake) preserveACL(name string, upload func() error) error {
 	// File in DataLake may have permissions and ACL set. Just uploading the file will override them.
 	// So, we need to get the existing permissions and ACL and set them back after uploading the file.
 
@@ -491,7 +511,7 @@ func (dl *Datalake) WriteFromFile(name string, metadata map[string]*string, fi *
 	}
 
 	// Upload the file, which will override the permissions and ACL
-	retCode := dl.BlockBlob.WriteFromFile(name, metadata, fi)
+	retCode := upload()
 
 	if acl != "" {
 		// Cannot set both permissions and ACL in one call. ACL includes permission as well so just setting those back
@@ -0,0 +1,262 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// Event Grid delivers a batch of at most 1 MB, leave some room for the envelope
+const maxWebhookBody = 2 * 1024 * 1024
+
+// Header and query parameter which carry the shared secret configured on the event subscription
+const (
+	webhookSecretHeader = "X-Webhook-Secret"
+	webhookSecretQuery  = "secret"
+)
+
+// webhookEvent : Blob storage event in CloudEvents v1.0 schema
+type webhookEvent struct {
+	Type    string `json:"type"`
+	Subject string `json:"subject"`
+	Data    struct {
+		ETag           string `json:"eTag"`
+		SourceURL      string `json:"sourceUrl"`
+		DestinationURL string `json:"destinationUrl"`
+		Recursive      string `json:"recursive"`
+	} `json:"data"`
+}
+
+// eventWebhook : Local HTTP listener which invalidates caches of the pipeline for blob events pushed by Event Grid
+type eventWebhook struct {
+	container string
+	prefix    string
+	secret    string
+
+	invalidate func(internal.InvalidatePathOptions)
+
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+func newEventWebhook(container string, prefix string, secret string) *eventWebhook {
+	return &eventWebhook{
+		container: container,
+		prefix:    strings.Trim(prefix, "/"),
+		secret:    secret,
+	}
+}
+
+// start : Listen on the given address, with TLS when a certificate is configured
+func (wh *eventWebhook) start(address string, certFile string, keyFile string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Err("eventWebhook::start : Failed to listen on %s [%s]", address, err.Error())
+		return err
+	}
+
+	wh.listener = listener
+	wh.server = &http.Server{
+		Handler:           wh,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	wh.wg.Add(1)
+	go func() {
+		defer wh.wg.Done()
+
+		var serveErr error
+		if certFile != "" {
+			serveErr = wh.server.ServeTLS(listener, certFile, keyFile)
+		} else {
+			serveErr = wh.server.Serve(listener)
+		}
+		if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			log.Err("eventWebhook::start : Listener on %s failed [%s]", address, serveErr.Error())
+		}
+	}()
+
+	log.Info("eventWebhook::start : Listening for events on %s", listener.Addr().String())
+	return nil
+}
+
+func (wh *eventWebhook) close() {
+	if wh.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = wh.server.Shutdown(ctx)
+	wh.wg.Wait()
+	wh.server = nil
+}
+
+func (wh *eventWebhook) authorized(r *http.Request) bool {
+	secret := r.Header.Get(webhookSecretHeader)
+	if secret == "" {
+		secret = r.URL.Query().Get(webhookSecretQuery)
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(wh.secret)) == 1
+}
+
+func (wh *eventWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !wh.authorized(r) {
+		log.Warn("eventWebhook::ServeHTTP : Rejected %s request from %s with invalid secret", r.Method, r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		// CloudEvents abuse protection handshake done by Event Grid when the subscription is created
+		origin := r.Header.Get("WebHook-Request-Origin")
+		if origin == "" {
+			http.Error(w, "missing WebHook-Request-Origin", http.StatusBadRequest)
+			return
+		}
+		log.Info("eventWebhook::ServeHTTP : Validated subscription from %s", origin)
+		w.Header().Set("WebHook-Allowed-Origin", origin)
+		w.Header().Set("WebHook-Allowed-Rate", "*")
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPost:
+		events, err := decodeWebhookEvents(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			log.Err("eventWebhook::ServeHTTP : Failed to decode events from %s [%s]", r.RemoteAddr, err.Error())
+			http.Error(w, "invalid events", http.StatusBadRequest)
+			return
+		}
+		for i := range events {
+			wh.process(&events[i])
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		w.Header().Set("Allow", "OPTIONS, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeWebhookEvents : Events are delivered one per request or as a json array in batched mode
+func decodeWebhookEvents(body io.Reader) ([]webhookEvent, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) > 0 && data[0] == '[' {
+		events := make([]webhookEvent, 0)
+		err = json.Unmarshal(data, &events)
+		return events, err
+	}
+
+	event := webhookEvent{}
+	err = json.Unmarshal(data, &event)
+	if err != nil {
+		return nil, err
+	}
+	return []webhookEvent{event}, nil
+}
+
+// process : Map an event to the paths of the mount it changed
+func (wh *eventWebhook) process(event *webhookEvent) {
+	options := make([]internal.InvalidatePathOptions, 0, 2)
+
+	switch strings.TrimPrefix(event.Type, "Microsoft.Storage.") {
+	case "BlobCreated", "BlobPropertiesUpdated":
+		if name, ok := subjectPath(event.Subject, wh.container, wh.prefix); ok {
+			options = append(options, internal.InvalidatePathOptions{Name: name, ETag: strings.Trim(event.Data.ETag, `"`)})
+		}
+
+	case "BlobDeleted", "DirectoryCreated":
+		if name, ok := subjectPath(event.Subject, wh.container, wh.prefix); ok {
+			options = append(options, internal.InvalidatePathOptions{Name: name})
+		}
+
+	case "DirectoryDeleted":
+		if name, ok := subjectPath(event.Subject, wh.container, wh.prefix); ok {
+			options = append(options, internal.InvalidatePathOptions{Name: name, IsDir: event.Data.Recursive != "false"})
+		}
+
+	case "BlobRenamed", "DirectoryRenamed":
+		isDir := event.Type == "Microsoft.Storage.DirectoryRenamed"
+		if name, ok := wh.urlPath(event.Data.SourceURL); ok {
+			options = append(options, internal.InvalidatePathOptions{Name: name, IsDir: isDir})
+		}
+		if name, ok := wh.urlPath(event.Data.DestinationURL); ok {
+			options = append(options, internal.InvalidatePathOptions{Name: name, IsDir: isDir})
+		}
+
+	default:
+		log.Debug("eventWebhook::process : Ignoring event %s for %s", event.Type, event.Subject)
+		return
+	}
+
+	for _, opt := range options {
+		log.Debug("eventWebhook::process : %s %s", event.Type, opt.Name)
+		if wh.invalidate != nil {
+			wh.invalidate(opt)
+		}
+	}
+}
+
+// urlPath : Path in the mount of a blob or directory url, false if it is outside the mounted container and prefix
+func (wh *eventWebhook) urlPath(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	name, found := strings.CutPrefix(u.Path, "/"+wh.container+"/")
+	if !found {
+		return "", false
+	}
+	return trimMountPrefix(name, wh.prefix)
+}
@@ -0,0 +1,194 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type webhookTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	webhook     *eventWebhook
+	invalidated []internal.InvalidatePathOptions
+	lock        sync.Mutex
+}
+
+func (s *webhookTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+
+	s.assert = assert.New(s.T())
+	s.invalidated = nil
+	s.webhook = s.newWebhook("")
+}
+
+func (s *webhookTestSuite) newWebhook(prefix string) *eventWebhook {
+	wh := newEventWebhook("container", prefix, "secret")
+	wh.invalidate = func(options internal.InvalidatePathOptions) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.invalidated = append(s.invalidated, options)
+	}
+	return wh
+}
+
+func (s *webhookTestSuite) post(body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/?secret=secret", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/cloudevents-batch+json; charset=utf-8")
+	rec := httptest.NewRecorder()
+	s.webhook.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func blobEvent(eventType string, container string, name string, data string) string {
+	return `{"specversion":"1.0","type":"Microsoft.Storage.` + eventType + `","source":"/subscriptions/id/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/account",` +
+		`"subject":"/blobServices/default/containers/` + container + `/blobs/` + name + `","id":"1","time":"2024-01-01T00:00:00Z","data":` + data + `}`
+}
+
+func (s *webhookTestSuite) TestHandshake() {
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("WebHook-Request-Origin", "eventgrid.azure.net")
+	req.Header.Set(webhookSecretHeader, "secret")
+	rec := httptest.NewRecorder()
+	s.webhook.ServeHTTP(rec, req)
+	s.assert.Equal(http.StatusOK, rec.Code)
+	s.assert.Equal("eventgrid.azure.net", rec.Header().Get("WebHook-Allowed-Origin"))
+
+	// Origin is required
+	req = httptest.NewRequest(http.MethodOptions, "/?secret=secret", nil)
+	rec = httptest.NewRecorder()
+	s.webhook.ServeHTTP(rec, req)
+	s.assert.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *webhookTestSuite) TestSecret() {
+	for _, target := range []string{"/", "/?secret=wrong", "/?secret=secre"} {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(blobEvent("BlobCreated", "container", "a", `{}`)))
+		rec := httptest.NewRecorder()
+		s.webhook.ServeHTTP(rec, req)
+		s.assert.Equal(http.StatusUnauthorized, rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("WebHook-Request-Origin", "eventgrid.azure.net")
+	rec := httptest.NewRecorder()
+	s.webhook.ServeHTTP(rec, req)
+	s.assert.Equal(http.StatusUnauthorized, rec.Code)
+	s.assert.Empty(s.invalidated)
+}
+
+func (s *webhookTestSuite) TestEvents() {
+	s.assert.Equal(http.StatusOK, s.post(blobEvent("BlobCreated", "container", "dir/a", `{"eTag":"0x8D1","url":"https://account.blob.core.windows.net/container/dir/a"}`)))
+	s.assert.Equal([]internal.InvalidatePathOptions{{Name: "dir/a", ETag: "0x8D1"}}, s.invalidated)
+
+	s.invalidated = nil
+	body := "[" + strings.Join([]string{
+		blobEvent("BlobDeleted", "container", "b", `{}`),
+		blobEvent("BlobTierChanged", "container", "c", `{}`),
+		blobEvent("BlobCreated", "other", "d", `{"eTag":"0x8D2"}`),
+		blobEvent("DirectoryDeleted", "container", "e", `{"recursive":"true"}`),
+		blobEvent("DirectoryCreated", "container", "f", `{}`),
+		blobEvent("BlobRenamed", "container", "h", `{"sourceUrl":"https://account.dfs.core.windows.net/container/g","destinationUrl":"https://account.dfs.core.windows.net/container/h%20i"}`),
+		blobEvent("DirectoryRenamed", "container", "k", `{"sourceUrl":"https://account.dfs.core.windows.net/container/j","destinationUrl":"https://account.dfs.core.windows.net/other/k"}`),
+	}, ",") + "]"
+	s.assert.Equal(http.StatusOK, s.post(body))
+	s.assert.Equal([]internal.InvalidatePathOptions{
+		{Name: "b"},
+		{Name: "e", IsDir: true},
+		{Name: "f"},
+		{Name: "g"},
+		{Name: "h i"},
+		{Name: "j", IsDir: true},
+	}, s.invalidated)
+}
+
+func (s *webhookTestSuite) TestPrefix() {
+	s.webhook = s.newWebhook("/sub/")
+	body := "[" + strings.Join([]string{
+		blobEvent("BlobDeleted", "container", "sub/a", `{}`),
+		blobEvent("BlobDeleted", "container", "subway/b", `{}`),
+		blobEvent("BlobRenamed", "container", "sub/d", `{"sourceUrl":"https://account.dfs.core.windows.net/container/c","destinationUrl":"https://account.dfs.core.windows.net/container/sub/d"}`),
+	}, ",") + "]"
+	s.assert.Equal(http.StatusOK, s.post(body))
+	s.assert.Equal([]internal.InvalidatePathOptions{{Name: "a"}, {Name: "d"}}, s.invalidated)
+}
+
+func (s *webhookTestSuite) TestInvalidRequest() {
+	s.assert.Equal(http.StatusBadRequest, s.post("not json"))
+	s.assert.Equal(http.StatusBadRequest, s.post(`[{"type":1}]`))
+
+	req := httptest.NewRequest(http.MethodGet, "/?secret=secret", nil)
+	rec := httptest.NewRecorder()
+	s.webhook.ServeHTTP(rec, req)
+	s.assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+	s.assert.Empty(s.invalidated)
+}
+
+func (s *webhookTestSuite) TestStartStop() {
+	s.assert.NoError(s.webhook.start("127.0.0.1:0", "", ""))
+
+	url := "http://" + s.webhook.listener.Addr().String() + "/?secret=secret"
+	resp, err := http.Post(url, "application/cloudevents+json", strings.NewReader(blobEvent("BlobDeleted", "container", "a", `{}`)))
+	s.assert.NoError(err)
+	resp.Body.Close()
+	s.assert.Equal(http.StatusOK, resp.StatusCode)
+	s.assert.Equal([]internal.InvalidatePathOptions{{Name: "a"}}, s.invalidated)
+
+	// Address in use
+	other := s.newWebhook("")
+	s.assert.Error(other.start(s.webhook.listener.Addr().String(), "", ""))
+
+	s.webhook.close()
+	s.webhook.close()
+	_, err = http.Post(url, "application/cloudevents+json", strings.NewReader("{}"))
+	s.assert.Error(err)
+}
+
+func TestWebhookTestSuite(t *testing.T) {
+	suite.Run(t, new(webhookTestSuite))
+}
@@ -86,9 +86,14 @@ type BlockCache struct {
 	prefetchOnOpen  bool            // Start prefetching on file open call instead of waiting for first read
 	consistency     bool            // Flag to indicate if strong data consistency is enabled
 	stream          *Stream
-	lazyWrite       bool           // Flag to indicate if lazy write is enabled
-	fileCloseOpt    sync.WaitGroup // Wait group to wait for all async close operations to complete
-	cleanupOnStart  bool           // Clear temp directory on startup
+	lazyWrite       bool                // Flag to indicate if lazy write is enabled
+	fileCloseOpt    sync.WaitGroup      // Wait group to wait for all async close operations to complete
+	cleanupOnStart  bool                // Clear temp directory on startup
+	sharedPath      string              // Path of the block cache shared by all mounts on this host
+	sharedSize      uint64              // Size of disk space all mounts together can use in shared cache
+	sharedGID       int                 // Group given access to the shared cache, -1 to keep it private to this user
+	sharedCache     *sharedCache        // Block cache shared by all mounts on this host
+	cipher          *common.CacheCipher // Cipher to encrypt blocks stored on disk
 }
 
 // Structure defining your config parameters
@@ -103,6 +108,11 @@ type BlockCacheOptions struct {
 	PrefetchOnOpen bool    `config:"prefetch-on-open" yaml:"prefetch-on-open,omitempty"`
 	Consistency    bool    `config:"consistency" yaml:"consistency,omitempty"`
 	CleanupOnStart bool    `config:"cleanup-on-start" yaml:"cleanup-on-start,omitempty"`
+	SharedPath     string  `config:"shared-path" yaml:"shared-path,omitempty"`
+	SharedSize     uint64  `config:"shared-size-mb" yaml:"shared-size-mb,omitempty"`
+	SharedGroup    string  `config:"shared-group" yaml:"shared-group,omitempty"`
+	Encryption     bool    `config:"encryption" yaml:"encryption,omitempty"`
+	EncryptionKey  string  `config:"encryption-key" yaml:"encryption-key,omitempty"`
 }
 
 const (
@@ -164,6 +174,23 @@ func (bc *BlockCache) Start(ctx context.Context) error {
 		}
 	}
 
+	// If shared caching is enabled then attach to the cache shared by other mounts on this host
+	if bc.sharedPath != "" {
+		var account, endpoint, container string
+		_ = config.UnmarshalKey("azstorage.account-name", &account)
+		_ = config.UnmarshalKey("azstorage.endpoint", &endpoint)
+		_ = config.UnmarshalKey("azstorage.container", &container)
+
+		var err error
+		namespace := fmt.Sprintf("%s@%s/%s", account, endpoint, container)
+		bc.sharedCache, err = newSharedCache(bc.sharedPath, namespace, bc.blockSize, bc.sharedSize, bc.sharedGID)
+		if err != nil {
+			log.Err("BlockCache::Start : failed to init shared cache [%s]", err.Error())
+			return fmt.Errorf("failed to start shared cache for block-cache")
+		}
+		bc.sharedCache.cipher = bc.cipher
+	}
+
 	return nil
 }
 
@@ -186,6 +213,11 @@ func (bc *BlockCache) Stop() error {
 		_ = common.TempCacheCleanup(bc.tmpPath)
 	}
 
+	// Shared cache is not cleaned up as other mounts may still be using it
+	if bc.sharedCache != nil {
+		bc.sharedCache.close()
+	}
+
 	return nil
 }
 
@@ -332,6 +364,40 @@ func (bc *BlockCache) Configure(_ bool) error {
 		}
 	}
 
+	bc.sharedPath = common.ExpandPath(conf.SharedPath)
+	bc.sharedGID = -1
+	if bc.sharedPath != "" {
+		if bc.sharedPath == bc.mntPath || bc.sharedPath == bc.tmpPath {
+			log.Err("BlockCache: config error [shared-path is same as mount path or tmp-path]")
+			return fmt.Errorf("config error in %s error [shared-path is same as mount path or tmp-path]", bc.Name())
+		}
+
+		if conf.SharedGroup != "" {
+			bc.sharedGID, err = lookupGroup(conf.SharedGroup)
+			if err != nil {
+				log.Err("BlockCache: config error [invalid shared-group %s]", conf.SharedGroup)
+				return fmt.Errorf("config error in %s [invalid shared-group %s]", bc.Name(), conf.SharedGroup)
+			}
+		}
+
+		err = os.MkdirAll(bc.sharedPath, os.FileMode(0700))
+		if err != nil {
+			log.Err("BlockCache: config error creating shared-path [%s]", err.Error())
+			return fmt.Errorf("config error in %s [%s]", bc.Name(), err.Error())
+		}
+
+		bc.sharedSize = bc.getDefaultDiskSize(bc.sharedPath)
+		if config.IsSet(compName + ".shared-size-mb") {
+			bc.sharedSize = conf.SharedSize * _1MB
+		}
+	}
+
+	err = bc.configureEncryption(conf)
+	if err != nil {
+		log.Err("BlockCache: config error [%s]", err.Error())
+		return fmt.Errorf("config error in %s [%s]", bc.Name(), err.Error())
+	}
+
 	if (uint64(bc.prefetch) * uint64(bc.blockSize)) > bc.memSize {
 		log.Err("BlockCache::Configure : config error [memory limit too low for configured prefetch]")
 		return fmt.Errorf("config error in %s [memory limit too low for configured prefetch]", bc.Name())
@@ -345,12 +411,73 @@ func (bc *BlockCache) Configure(_ bool) error {
 		}
 	}
 
-	log.Crit("BlockCache::Configure : block size %v, mem size %v, worker %v, prefetch %v, disk path %v, max size %v, disk timeout %v, prefetch-on-open %t, maxDiskUsageHit %v, noPrefetch %v, consistency %v",
-		bc.blockSize, bc.memSize, bc.workers, bc.prefetch, bc.tmpPath, bc.diskSize, bc.diskTimeout, bc.prefetchOnOpen, bc.maxDiskUsageHit, bc.noPrefetch, bc.consistency)
+	log.Crit("BlockCache::Configure : block size %v, mem size %v, worker %v, prefetch %v, disk path %v, max size %v, disk timeout %v, prefetch-on-open %t, maxDiskUsageHit %v, noPrefetch %v, consistency %v, shared path %v, shared size %v, encryption %v",
+		bc.blockSize, bc.memSize, bc.workers, bc.prefetch, bc.tmpPath, bc.diskSize, bc.diskTimeout, bc.prefetchOnOpen, bc.maxDiskUsageHit, bc.noPrefetch, bc.consistency, bc.sharedPath, bc.sharedSize, bc.cipher != nil)
+
+	if bc.sharedPath != "" {
+		log.Crit("BlockCache::Configure : shared group %v", conf.SharedGroup)
+	}
 
 	return nil
 }
 
+// configureEncryption : Create the cipher used to encrypt blocks stored on disk
+func (bc *BlockCache) configureEncryption(conf BlockCacheOptions) error {
+	bc.cipher = nil
+	if !conf.Encryption {
+		return nil
+	}
+
+	var key []byte
+	var err error
+	if conf.EncryptionKey != "" {
+		key, err = common.ParseCacheKey(conf.EncryptionKey)
+		if err != nil {
+			return err
+		}
+	} else if bc.sharedPath != "" {
+		// Other mounts can read blocks from shared cache only if they all use the same key
+		return fmt.Errorf("encryption-key is required to encrypt shared cache")
+	}
+
+	bc.cipher, err = common.NewCacheCipher(key)
+	return err
+}
+
+// readDiskBlock : Read a block of the given file from its file in disk cache
+func (bc *BlockCache) readDiskBlock(f *os.File, path string, block *Block) (int, error) {
+	if bc.cipher == nil {
+		return f.Read(block.data)
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err = bc.cipher.Open(data, []byte(path), uint64(block.id))
+	if err != nil {
+		return 0, err
+	}
+
+	return copy(block.data, data), nil
+}
+
+// writeDiskBlock : Write first n bytes of a block of the given file to its file in disk cache
+func (bc *BlockCache) writeDiskBlock(f *os.File, path string, block *Block, n int) error {
+	data := block.data[:n]
+	if bc.cipher != nil {
+		var err error
+		data, err = bc.cipher.Seal(data, []byte(path), uint64(block.id))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := f.Write(data)
+	return err
+}
+
 func (bc *BlockCache) getDefaultDiskSize(path string) uint64 {
 	var stat syscall.Statfs_t
 	err := syscall.Statfs(path, &stat)
@@ -1023,7 +1150,7 @@ func (bc *BlockCache) download(item *workItem) {
 				_ = os.Remove(localPath)
 			} else {
 				var successfulRead bool = true
-				numberOfBytes, err := f.Read(item.block.data)
+				numberOfBytes, err := bc.readDiskBlock(f, item.handle.Path, item.block)
 				if err != nil {
 					log.Err("BlockCache::download : Failed to read data from disk cache %s [%s]", fileName, err.Error())
 					successfulRead = false
@@ -1053,6 +1180,15 @@ func (bc *BlockCache) download(item *workItem) {
 		}
 	}
 
+	// Check whether any other mount on this host has already downloaded this block
+	if bc.sharedCache != nil {
+		n, found := bc.sharedCache.get(item.handle.Path, item.ETag, uint64(item.block.id), item.block.data)
+		if found && uint64(n) == bc.getBlockSize(uint64(item.handle.Size), item.block) {
+			item.block.Ready(BlockStatusDownloaded)
+			return
+		}
+	}
+
 	var etag string
 	// If file does not exists then download the block from the container
 	n, err := bc.NextComponent().ReadInBuffer(internal.ReadInBufferOptions{
@@ -1092,6 +1228,13 @@ func (bc *BlockCache) download(item *workItem) {
 			item.block.Ready(BlockStatusDownloadFailed)
 			return
 		}
+	} else {
+		etag = item.ETag
+	}
+
+	// Share this block with other mounts on this host
+	if bc.sharedCache != nil {
+		bc.sharedCache.put(item.handle.Path, etag, uint64(item.block.id), item.block.data[:n])
 	}
 
 	if bc.tmpPath != "" {
@@ -1104,7 +1247,7 @@ func (bc *BlockCache) download(item *workItem) {
 		// Dump this block to local disk cache
 		f, err := os.Create(localPath)
 		if err == nil {
-			_, err := f.Write(item.block.data[:n])
+			err := bc.writeDiskBlock(f, item.handle.Path, item.block, n)
 			if err != nil {
 				log.Err("BlockCache::download : Failed to write %s to disk [%v]", localPath, err.Error())
 				_ = os.Remove(localPath)
@@ -1526,7 +1669,7 @@ func (bc *BlockCache) upload(item *workItem) {
 		// Dump this block to local disk cache
 		f, err := os.Create(localPath)
 		if err == nil {
-			_, err := f.Write(item.block.data[0:blockSize])
+			err := bc.writeDiskBlock(f, item.handle.Path, item.block, int(blockSize))
 			if err != nil {
 				log.Err("BlockCache::upload : Failed to write %s to disk [%v]", localPath, err.Error())
 				_ = os.Remove(localPath)
@@ -1868,6 +2011,10 @@ func (bc *BlockCache) DeleteFile(options internal.DeleteFileOptions) error {
 		return err
 	}
 
+	if bc.sharedCache != nil {
+		bc.sharedCache.invalidate(options.Name)
+	}
+
 	localPath := filepath.Join(bc.tmpPath, options.Name)
 	files, err := filepath.Glob(localPath + "*")
 	if err == nil {
@@ -1899,6 +2046,10 @@ func (bc *BlockCache) RenameFile(options internal.RenameFileOptions) error {
 		return err
 	}
 
+	if bc.sharedCache != nil {
+		bc.sharedCache.invalidate(options.Src)
+	}
+
 	localSrcPath := filepath.Join(bc.tmpPath, options.Src)
 	localDstPath := filepath.Join(bc.tmpPath, options.Dst)
 
@@ -1998,4 +2149,13 @@ func init() {
 
 	strongConsistency := config.AddBoolFlag("block-cache-strong-consistency", false, "Enable strong data consistency for block cache.")
 	config.BindPFlag(compName+".consistency", strongConsistency)
+
+	blockCacheSharedPath := config.AddStringFlag("block-cache-shared-path", "", "Path to store blocks shared by all mounts on this host.")
+	config.BindPFlag(compName+".shared-path", blockCacheSharedPath)
+
+	blockCacheSharedMb := config.AddUint64Flag("block-cache-shared-size", 0, "Size (in MB) of total disk capacity that all mounts together can use in shared block-cache.")
+	config.BindPFlag(compName+".shared-size-mb", blockCacheSharedMb)
+
+	blockCacheSharedGroup := config.AddStringFlag("block-cache-shared-group", "", "Group whose members can use the shared block-cache.")
+	config.BindPFlag(compName+".shared-group", blockCacheSharedGroup)
 }
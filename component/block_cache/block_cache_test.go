@@ -371,6 +371,89 @@ func (suite *blockCacheTestSuite) TestManualConfig() {
 	suite.assert.NotNil(tobj.blockCache.blockPool)
 }
 
+func (suite *blockCacheTestSuite) TestSharedCacheConfig() {
+	sharedPath := getFakeStoragePath("shared_cache")
+	defer os.RemoveAll(sharedPath)
+
+	cfg := fmt.Sprintf("read-only: true\n\nblock_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10\n  shared-path: %s\n  shared-size-mb: 10", sharedPath)
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.Nil(err)
+	suite.assert.Equal(tobj.blockCache.sharedPath, sharedPath)
+	suite.assert.EqualValues(tobj.blockCache.sharedSize, 10*_1MB)
+	suite.assert.NotNil(tobj.blockCache.sharedCache)
+
+	_, err = os.Stat(filepath.Join(sharedPath, sharedLockFile))
+	suite.assert.Nil(err)
+}
+
+func (suite *blockCacheTestSuite) TestSharedCacheInvalidGroup() {
+	sharedPath := getFakeStoragePath("shared_cache")
+	defer os.RemoveAll(sharedPath)
+
+	cfg := fmt.Sprintf("read-only: true\n\nblock_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10\n  shared-path: %s\n  shared-group: no-such-group-for-blobfuse", sharedPath)
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "invalid shared-group")
+}
+
+func (suite *blockCacheTestSuite) TestEncryptedDiskCache() {
+	disk_cache_path := getFakeStoragePath("fake_storage")
+	defer os.RemoveAll(disk_cache_path)
+
+	cfg := fmt.Sprintf("read-only: true\n\nblock_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10\n  path: %s\n  disk-size-mb: 50\n  encryption: true", disk_cache_path)
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+
+	suite.assert.Nil(err)
+	suite.assert.NotNil(tobj.blockCache.cipher)
+
+	fileName := getTestFileName(suite.T().Name())
+	data := make([]byte, 2*_1MB+100)
+	_, _ = r.Read(data)
+	os.WriteFile(filepath.Join(tobj.fake_storage_path, fileName), data, 0777)
+
+	for i := 0; i < 2; i++ {
+		// Second pass reads the blocks back from disk
+		h, err := tobj.blockCache.OpenFile(internal.OpenFileOptions{Name: fileName})
+		suite.assert.Nil(err)
+
+		buf := make([]byte, len(data))
+		n, err := tobj.blockCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: h, Offset: 0, Data: buf})
+		suite.assert.Equal(io.EOF, err)
+		suite.assert.Equal(len(data), n)
+		suite.assert.Equal(data, buf)
+		suite.assert.Nil(tobj.blockCache.CloseFile(internal.CloseFileOptions{Handle: h}))
+	}
+
+	block, err := os.ReadFile(filepath.Join(disk_cache_path, fileName+"::0"))
+	suite.assert.Nil(err)
+	suite.assert.Greater(len(block), int(_1MB))
+	suite.assert.False(bytes.Contains(block, data[:100]))
+}
+
+func (suite *blockCacheTestSuite) TestEncryptedSharedCacheConfig() {
+	sharedPath := getFakeStoragePath("shared_cache")
+	defer os.RemoveAll(sharedPath)
+
+	// Shared cache needs a key known to all mounts
+	cfg := fmt.Sprintf("read-only: true\n\nblock_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10\n  shared-path: %s\n  encryption: true", sharedPath)
+	tobj, err := setupPipeline(cfg)
+	defer tobj.cleanupPipeline()
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "encryption-key is required")
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, common.CacheKeySize))
+	cfg = fmt.Sprintf("%s\n  encryption-key: %s", cfg, key)
+	tobj2, err := setupPipeline(cfg)
+	defer tobj2.cleanupPipeline()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(tobj2.blockCache.sharedCache.cipher)
+}
+
 func (suite *blockCacheTestSuite) TestOpenFileFail() {
 	tobj, err := setupPipeline("")
 	defer tobj.cleanupPipeline()
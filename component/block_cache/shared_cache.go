@@ -0,0 +1,480 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+)
+
+const (
+	sharedLockFile  = ".lock"
+	sharedUsageFile = ".usage"
+	sharedBlocksDir = "blocks"
+	sharedTmpSuffix = ".tmp"
+)
+
+// sharedCache is a block cache shared by all the mounts on this host.
+// Blocks are stored under <path>/blocks/<account+container+blob hash>/<etag>/<block size>/<index> so that any mount
+// reading the same version of a blob with the same block size can reuse a block downloaded by another mount.
+// Capacity is enforced across processes using an flock on <path>/.lock and a usage counter in <path>/.usage
+type sharedCache struct {
+	path      string     // Root directory of the shared cache
+	namespace string     // Storage account and container, part of the key so that mounts of different containers do not collide
+	blockSize uint64     // Block size of this mount, blocks of another size hold different ranges of the blob
+	gid       int        // Group sharing the cache with this user, -1 if only this user can access it
+	maxSize   uint64     // Max bytes all mounts together can store in the shared cache
+	lockFile  *os.File   // File used to take cross-process lock
+	mu        sync.Mutex // flock is per open file so goroutines of this process need their own lock
+
+	cipher *common.CacheCipher // Cipher to encrypt blocks, all mounts sharing the cache use the same key
+}
+
+// lookupGroup resolves a group name or id to its id
+func lookupGroup(group string) (int, error) {
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		g, err = user.LookupGroupId(group)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return strconv.Atoi(g.Gid)
+}
+
+// newSharedCache opens or creates the shared cache at the given path.
+// Cache is accessible only to this user unless gid is given, in which case members of that group can use it too.
+func newSharedCache(path string, namespace string, blockSize uint64, maxSize uint64, gid int) (*sharedCache, error) {
+	if path == "" || blockSize == 0 || maxSize == 0 {
+		return nil, fmt.Errorf("invalid shared cache config")
+	}
+
+	sc := &sharedCache{
+		path:      path,
+		namespace: namespace,
+		blockSize: blockSize,
+		maxSize:   maxSize,
+		gid:       gid,
+	}
+
+	err := sc.mkdirAll(filepath.Join(path, sharedBlocksDir))
+	if err != nil {
+		log.Err("sharedCache::newSharedCache : Failed to create %s [%s]", path, err.Error())
+		return nil, err
+	}
+
+	// Cache created by an older version may be open to everyone
+	err = sc.setPerm(path, true)
+	if err != nil {
+		log.Err("sharedCache::newSharedCache : Failed to set permissions of %s [%s]", path, err.Error())
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(path, sharedLockFile), os.O_CREATE|os.O_RDWR, sc.fileMode())
+	if err != nil {
+		log.Err("sharedCache::newSharedCache : Failed to open lock file in %s [%s]", path, err.Error())
+		return nil, err
+	}
+	sc.lockFile = f
+
+	err = sc.setPerm(f.Name(), false)
+	if err != nil {
+		log.Err("sharedCache::newSharedCache : Failed to set permissions of lock file in %s [%s]", path, err.Error())
+		f.Close()
+		return nil, err
+	}
+
+	// First mount on this host (or a crashed one) may have left the usage counter missing
+	err = sc.lock()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	defer sc.unlock()
+
+	if _, err = os.Stat(filepath.Join(path, sharedUsageFile)); os.IsNotExist(err) {
+		usage, _ := sc.scan()
+		sc.setUsage(usage)
+	}
+
+	return sc, nil
+}
+
+func (sc *sharedCache) dirMode() os.FileMode {
+	if sc.gid >= 0 {
+		return 0770
+	}
+	return 0700
+}
+
+func (sc *sharedCache) fileMode() os.FileMode {
+	if sc.gid >= 0 {
+		return 0660
+	}
+	return 0600
+}
+
+// setPerm applies the mode and group of the shared cache to a file or directory created in it.
+// Mode is set explicitly as the umask of the process may have dropped group permissions.
+func (sc *sharedCache) setPerm(path string, dir bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	mode := sc.fileMode()
+	if dir {
+		mode = sc.dirMode()
+	}
+
+	if sc.gid >= 0 {
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Gid) != sc.gid {
+			err = os.Chown(path, -1, sc.gid)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if info.Mode().Perm() != mode {
+		return os.Chmod(path, mode)
+	}
+
+	return nil
+}
+
+// mkdirAll creates a directory in the shared cache along with its parents
+func (sc *sharedCache) mkdirAll(path string) error {
+	err := os.MkdirAll(path, sc.dirMode())
+	if err != nil {
+		return err
+	}
+
+	for dir := path; strings.HasPrefix(dir, sc.path) && dir != sc.path; dir = filepath.Dir(dir) {
+		err = sc.setPerm(dir, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile writes a file in the shared cache
+func (sc *sharedCache) writeFile(path string, data []byte) error {
+	err := os.WriteFile(path, data, sc.fileMode())
+	if err != nil {
+		return err
+	}
+
+	return sc.setPerm(path, false)
+}
+
+// close releases the lock file. Cached blocks are left on disk for other mounts.
+func (sc *sharedCache) close() {
+	if sc.lockFile != nil {
+		_ = sc.lockFile.Close()
+		sc.lockFile = nil
+	}
+}
+
+func (sc *sharedCache) lock() error {
+	sc.mu.Lock()
+	err := syscall.Flock(int(sc.lockFile.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		sc.mu.Unlock()
+		log.Err("sharedCache::lock : Failed to lock %s [%s]", sc.path, err.Error())
+	}
+	return err
+}
+
+func (sc *sharedCache) unlock() {
+	_ = syscall.Flock(int(sc.lockFile.Fd()), syscall.LOCK_UN)
+	sc.mu.Unlock()
+}
+
+// blobDir returns the directory holding all cached versions of a blob
+func (sc *sharedCache) blobDir(name string) string {
+	hash := sha256.Sum256([]byte(sc.namespace + "/" + name))
+	return filepath.Join(sc.path, sharedBlocksDir, hex.EncodeToString(hash[:16]))
+}
+
+// blockPath returns the location of a block for the given blob version
+func (sc *sharedCache) blockPath(name string, etag string, index uint64) string {
+	etag = strings.ReplaceAll(strings.Trim(etag, "\""), "/", "_")
+	return filepath.Join(sc.blobDir(name), etag, strconv.FormatUint(sc.blockSize, 10), strconv.FormatUint(index, 10))
+}
+
+// blobID returns the identity of a blob version authenticated along with its encrypted blocks
+func (sc *sharedCache) blobID(name string, etag string) []byte {
+	return []byte(sc.namespace + "/" + name + "/" + etag + "/" + strconv.FormatUint(sc.blockSize, 10))
+}
+
+// get reads a block from the shared cache. Returns false if the block is not present.
+func (sc *sharedCache) get(name string, etag string, index uint64, data []byte) (int, bool) {
+	if etag == "" {
+		return 0, false
+	}
+
+	localPath := sc.blockPath(name, etag, index)
+	f, err := os.Open(localPath)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	var n int
+	if sc.cipher != nil {
+		var sealed, plain []byte
+		sealed, err = io.ReadAll(f)
+		if err == nil {
+			plain, err = sc.cipher.Open(sealed, sc.blobID(name, etag), index)
+		}
+		n = copy(data, plain)
+	} else {
+		n, err = io.ReadFull(f, data)
+		if err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		log.Err("sharedCache::get : Failed to read %s [%s]", localPath, err.Error())
+		return 0, false
+	}
+
+	// Refresh the timestamp so that eviction treats this block as recently used
+	now := time.Now()
+	_ = os.Chtimes(localPath, now, now)
+
+	return n, true
+}
+
+// put stores a block in the shared cache, evicting least recently used blocks if capacity is exceeded
+func (sc *sharedCache) put(name string, etag string, index uint64, data []byte) {
+	if sc.cipher != nil {
+		var err error
+		data, err = sc.cipher.Seal(data, sc.blobID(name, etag), index)
+		if err != nil {
+			log.Err("sharedCache::put : Failed to encrypt block of %s [%s]", name, err.Error())
+			return
+		}
+	}
+
+	if etag == "" || uint64(len(data)) > sc.maxSize {
+		return
+	}
+
+	localPath := sc.blockPath(name, etag, index)
+	if _, err := os.Stat(localPath); err == nil {
+		// Some other mount has already cached this block
+		return
+	}
+
+	err := sc.mkdirAll(filepath.Dir(localPath))
+	if err != nil {
+		log.Err("sharedCache::put : error creating directory structure for %s [%s]", localPath, err.Error())
+		return
+	}
+
+	// Write to a temp file first and rename later so that readers never see a partial block
+	tmpPath := fmt.Sprintf("%s.%d%s", localPath, os.Getpid(), sharedTmpSuffix)
+	err = sc.writeFile(tmpPath, data)
+	if err != nil {
+		log.Err("sharedCache::put : Failed to write %s [%s]", tmpPath, err.Error())
+		_ = os.Remove(tmpPath)
+		return
+	}
+
+	err = sc.lock()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return
+	}
+	defer sc.unlock()
+
+	if _, err := os.Stat(localPath); err == nil {
+		_ = os.Remove(tmpPath)
+		return
+	}
+
+	usage := sc.getUsage()
+	if usage+uint64(len(data)) > sc.maxSize {
+		usage = sc.evict(uint64(len(data)))
+	}
+
+	err = os.Rename(tmpPath, localPath)
+	if err != nil {
+		log.Err("sharedCache::put : Failed to rename %s [%s]", tmpPath, err.Error())
+		_ = os.Remove(tmpPath)
+		return
+	}
+
+	sc.setUsage(usage + uint64(len(data)))
+}
+
+// invalidate removes all cached versions of the given blob. Must not be called with lock held.
+func (sc *sharedCache) invalidate(name string) {
+	dir := sc.blobDir(name)
+
+	err := sc.lock()
+	if err != nil {
+		return
+	}
+	defer sc.unlock()
+
+	var removed uint64
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, sharedTmpSuffix) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && os.Remove(path) == nil {
+			removed += uint64(info.Size())
+		}
+		return nil
+	})
+	_ = os.RemoveAll(dir)
+
+	usage := sc.getUsage()
+	if removed > usage {
+		removed = usage
+	}
+	sc.setUsage(usage - removed)
+}
+
+// evict removes least recently used blocks till there is room for the given bytes.
+// Returns the new usage. Must be called with lock held.
+func (sc *sharedCache) evict(needed uint64) uint64 {
+	type sharedBlock struct {
+		path  string
+		size  uint64
+		mtime time.Time
+	}
+
+	blocks := make([]sharedBlock, 0)
+	var usage uint64
+	_ = filepath.WalkDir(filepath.Join(sc.path, sharedBlocksDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, sharedTmpSuffix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		blocks = append(blocks, sharedBlock{path: path, size: uint64(info.Size()), mtime: info.ModTime()})
+		usage += uint64(info.Size())
+		return nil
+	})
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].mtime.Before(blocks[j].mtime)
+	})
+
+	// Evict down to the low watermark so that every put does not end up scanning the cache
+	target := (sc.maxSize * uint64(MIN_POOL_USAGE)) / 100
+	if needed > target {
+		target = 0
+	} else {
+		target -= needed
+	}
+
+	for _, b := range blocks {
+		if usage <= target {
+			break
+		}
+		if err := os.Remove(b.path); err != nil {
+			continue
+		}
+		_ = os.Remove(filepath.Dir(b.path))
+		_ = os.Remove(filepath.Dir(filepath.Dir(b.path)))
+		usage -= b.size
+	}
+
+	log.Info("sharedCache::evict : usage after eviction %v bytes, limit %v bytes", usage, sc.maxSize)
+	return usage
+}
+
+// scan computes actual bytes held in the shared cache
+func (sc *sharedCache) scan() (uint64, error) {
+	var usage uint64
+	err := filepath.WalkDir(filepath.Join(sc.path, sharedBlocksDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, sharedTmpSuffix) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			usage += uint64(info.Size())
+		}
+		return nil
+	})
+	return usage, err
+}
+
+// getUsage reads the usage counter. Must be called with lock held.
+func (sc *sharedCache) getUsage() uint64 {
+	data, err := os.ReadFile(filepath.Join(sc.path, sharedUsageFile))
+	if err != nil {
+		usage, _ := sc.scan()
+		return usage
+	}
+
+	usage, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		usage, _ = sc.scan()
+	}
+	return usage
+}
+
+// setUsage writes the usage counter. Must be called with lock held.
+func (sc *sharedCache) setUsage(usage uint64) {
+	err := sc.writeFile(filepath.Join(sc.path, sharedUsageFile), []byte(strconv.FormatUint(usage, 10)))
+	if err != nil {
+		log.Err("sharedCache::setUsage : Failed to update usage in %s [%s]", sc.path, err.Error())
+	}
+}
@@ -0,0 +1,277 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type sharedCacheTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	path   string
+}
+
+func (suite *sharedCacheTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.path = getFakeStoragePath("shared_cache")
+}
+
+func (suite *sharedCacheTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.path)
+}
+
+func (suite *sharedCacheTestSuite) TestInvalidConfig() {
+	sc, err := newSharedCache("", "account/container", _1MB, 100, -1)
+	suite.assert.Nil(sc)
+	suite.assert.NotNil(err)
+
+	sc, err = newSharedCache(suite.path, "account/container", _1MB, 0, -1)
+	suite.assert.Nil(sc)
+	suite.assert.NotNil(err)
+
+	sc, err = newSharedCache(suite.path, "account/container", 0, _1MB, -1)
+	suite.assert.Nil(sc)
+	suite.assert.NotNil(err)
+}
+
+func (suite *sharedCacheTestSuite) TestPutGet() {
+	sc, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer sc.close()
+
+	data := []byte("shared block data")
+	sc.put("dir/a.txt", "\"0x8DC\"", 3, data)
+
+	buf := make([]byte, 100)
+	n, found := sc.get("dir/a.txt", "\"0x8DC\"", 3, buf)
+	suite.assert.True(found)
+	suite.assert.Equal(len(data), n)
+	suite.assert.Equal(data, buf[:n])
+	suite.assert.Equal(uint64(len(data)), sc.getUsage())
+
+	// Different etag, index or container is a miss
+	_, found = sc.get("dir/a.txt", "\"0x8DD\"", 3, buf)
+	suite.assert.False(found)
+	_, found = sc.get("dir/a.txt", "\"0x8DC\"", 4, buf)
+	suite.assert.False(found)
+	_, found = sc.get("dir/a.txt", "", 3, buf)
+	suite.assert.False(found)
+
+	// Same container in another account or with another block size is a miss
+	for _, other := range []struct {
+		namespace string
+		blockSize uint64
+	}{{"account/other", _1MB}, {"other/container", _1MB}, {"account/container", 2 * _1MB}} {
+		sc, err := newSharedCache(suite.path, other.namespace, other.blockSize, _1MB, -1)
+		suite.assert.Nil(err)
+		_, found = sc.get("dir/a.txt", "\"0x8DC\"", 3, buf)
+		suite.assert.False(found)
+		sc.close()
+	}
+}
+
+func (suite *sharedCacheTestSuite) TestSharedAcrossInstances() {
+	sc1, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer sc1.close()
+
+	sc2, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer sc2.close()
+
+	data := make([]byte, 1024)
+	_, _ = r.Read(data)
+	sc1.put("a.txt", "etag1", 0, data)
+
+	// Second put of the same block is ignored and usage is counted once
+	sc2.put("a.txt", "etag1", 0, data)
+	suite.assert.Equal(uint64(1024), sc2.getUsage())
+
+	buf := make([]byte, 1024)
+	n, found := sc2.get("a.txt", "etag1", 0, buf)
+	suite.assert.True(found)
+	suite.assert.Equal(1024, n)
+	suite.assert.Equal(data, buf)
+}
+
+func (suite *sharedCacheTestSuite) TestEncrypted() {
+	sc, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer sc.close()
+	sc.cipher, err = common.NewCacheCipher(nil)
+	suite.assert.Nil(err)
+
+	data := []byte("shared block data")
+	sc.put("a.txt", "etag1", 0, data)
+
+	// Block on disk does not hold plain data
+	stored, err := os.ReadFile(sc.blockPath("a.txt", "etag1", 0))
+	suite.assert.Nil(err)
+	suite.assert.NotContains(string(stored), string(data))
+
+	buf := make([]byte, 100)
+	n, found := sc.get("a.txt", "etag1", 0, buf)
+	suite.assert.True(found)
+	suite.assert.Equal(data, buf[:n])
+
+	// Mount using another key can not read the block
+	other, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer other.close()
+	other.cipher, err = common.NewCacheCipher(nil)
+	suite.assert.Nil(err)
+	_, found = other.get("a.txt", "etag1", 0, buf)
+	suite.assert.False(found)
+}
+
+func (suite *sharedCacheTestSuite) TestEviction() {
+	sc, err := newSharedCache(suite.path, "account/container", _1MB, 10*1024, -1)
+	suite.assert.Nil(err)
+	defer sc.close()
+
+	data := make([]byte, 1024)
+	for i := uint64(0); i < 20; i++ {
+		sc.put("a.txt", "etag1", i, data)
+		suite.assert.LessOrEqual(sc.getUsage(), uint64(10*1024))
+	}
+
+	usage, err := sc.scan()
+	suite.assert.Nil(err)
+	suite.assert.Equal(usage, sc.getUsage())
+
+	// Latest block shall survive eviction
+	_, found := sc.get("a.txt", "etag1", 19, data)
+	suite.assert.True(found)
+
+	// Block bigger than the capacity is never stored
+	sc.put("b.txt", "etag1", 0, make([]byte, 20*1024))
+	_, found = sc.get("b.txt", "etag1", 0, data)
+	suite.assert.False(found)
+}
+
+func (suite *sharedCacheTestSuite) TestInvalidate() {
+	sc, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer sc.close()
+
+	data := make([]byte, 1024)
+	sc.put("a.txt", "etag1", 0, data)
+	sc.put("a.txt", "etag2", 0, data)
+	sc.put("b.txt", "etag1", 0, data)
+	suite.assert.Equal(uint64(3*1024), sc.getUsage())
+
+	sc.invalidate("a.txt")
+	suite.assert.Equal(uint64(1024), sc.getUsage())
+
+	_, found := sc.get("a.txt", "etag1", 0, data)
+	suite.assert.False(found)
+	_, found = sc.get("b.txt", "etag1", 0, data)
+	suite.assert.True(found)
+}
+
+func (suite *sharedCacheTestSuite) TestUsageRebuiltOnStart() {
+	sc, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	sc.put("a.txt", "etag1", 0, make([]byte, 512))
+	sc.close()
+
+	_ = os.Remove(filepath.Join(suite.path, sharedUsageFile))
+
+	sc, err = newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer sc.close()
+	suite.assert.Equal(uint64(512), sc.getUsage())
+}
+
+func (suite *sharedCacheTestSuite) checkPerm(path string, mode os.FileMode) {
+	info, err := os.Stat(path)
+	suite.assert.Nil(err)
+	suite.assert.Equal(mode, info.Mode().Perm(), path)
+}
+
+func (suite *sharedCacheTestSuite) TestPrivateByDefault() {
+	// Cache left open to everyone is made private
+	suite.assert.Nil(os.MkdirAll(suite.path, 0777))
+	suite.assert.Nil(os.Chmod(suite.path, 0777))
+
+	sc, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, -1)
+	suite.assert.Nil(err)
+	defer sc.close()
+	sc.put("a.txt", "etag1", 0, []byte("data"))
+
+	blockPath := sc.blockPath("a.txt", "etag1", 0)
+	suite.checkPerm(suite.path, 0700)
+	suite.checkPerm(filepath.Dir(blockPath), 0700)
+	suite.checkPerm(blockPath, 0600)
+	suite.checkPerm(filepath.Join(suite.path, sharedLockFile), 0600)
+	suite.checkPerm(filepath.Join(suite.path, sharedUsageFile), 0600)
+}
+
+func (suite *sharedCacheTestSuite) TestSharedWithGroup() {
+	gid := os.Getgid()
+	sc, err := newSharedCache(suite.path, "account/container", _1MB, _1MB, gid)
+	suite.assert.Nil(err)
+	defer sc.close()
+	sc.put("a.txt", "etag1", 0, []byte("data"))
+
+	blockPath := sc.blockPath("a.txt", "etag1", 0)
+	suite.checkPerm(suite.path, 0770)
+	suite.checkPerm(filepath.Dir(blockPath), 0770)
+	suite.checkPerm(blockPath, 0660)
+	suite.checkPerm(filepath.Join(suite.path, sharedUsageFile), 0660)
+
+	info, err := os.Stat(blockPath)
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(gid, info.Sys().(*syscall.Stat_t).Gid)
+
+	id, err := lookupGroup(strconv.Itoa(gid))
+	suite.assert.Nil(err)
+	suite.assert.Equal(gid, id)
+
+	_, err = lookupGroup("no-such-group-for-blobfuse")
+	suite.assert.NotNil(err)
+}
+
+func TestSharedCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(sharedCacheTestSuite))
+}
@@ -37,22 +37,32 @@ import (
 	"container/list"
 	"context"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/metastore"
 	"github.com/vibhansa-msft/tlru"
 )
 
 // Common structure for Component
 type EntryCache struct {
 	internal.BaseComponent
-	cacheTimeout uint32
-	pathLocks    *common.LockMap
-	pathLRU      *tlru.TLRU
-	pathMap      sync.Map
+	cacheTimeout    uint32
+	pathLocks       *common.LockMap
+	pathLRU         *tlru.TLRU
+	pathMap         sync.Map
+	diskPath        string
+	diskTimeout     uint32
+	store           *metastore.Store // Listings persisted on disk across mounts
+	shardedList     uint32           // Number of prefix shards listed in parallel for large directories, 0 to disable
+	shardedNonASCII bool             // Sharded list also picks up names starting with a non-ASCII character
 }
 
 type pathCacheItem struct {
@@ -63,9 +73,22 @@ type pathCacheItem struct {
 // By default entry cache is valid for 30 seconds
 const defaultEntryCacheTimeout uint32 = (30)
 
+// By default listings persisted on disk are valid for an hour
+const defaultDiskCacheTimeout uint32 = (3600)
+
+// Continuation tokens of listings fetched in shards, such pages can be served only from cache
+const shardedTokenPrefix = "sharded:"
+
+// Number of entries in each page of a listing fetched in shards
+var shardedPageSize = common.MaxDirListCount
+
 // Structure defining your config parameters
 type EntryCacheOptions struct {
-	Timeout uint32 `config:"timeout-sec" yaml:"timeout-sec,omitempty"`
+	Timeout          uint32 `config:"timeout-sec" yaml:"timeout-sec,omitempty"`
+	DiskCachePath    string `config:"disk-cache-path" yaml:"disk-cache-path,omitempty"`
+	DiskCacheTimeout uint32 `config:"disk-cache-timeout-sec" yaml:"disk-cache-timeout-sec,omitempty"`
+	ShardedList      uint32 `config:"sharded-list-parallelism" yaml:"sharded-list-parallelism,omitempty"`
+	ShardedNonASCII  bool   `config:"sharded-list-non-ascii" yaml:"sharded-list-non-ascii,omitempty"`
 }
 
 const compName = "entry_cache"
@@ -97,6 +120,17 @@ func (c *EntryCache) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start LRU for path caching [%s]", err.Error())
 	}
 
+	if c.diskPath != "" {
+		var container string
+		_ = config.UnmarshalKey("azstorage.container", &container)
+
+		c.store, err = metastore.Open(c.diskPath, container, time.Duration(c.diskTimeout)*time.Second)
+		if err != nil {
+			log.Err("EntryCache::Start : fail to open disk cache at %s [%s]", c.diskPath, err.Error())
+			return fmt.Errorf("failed to open disk cache [%s]", err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -109,6 +143,9 @@ func (c *EntryCache) Stop() error {
 		log.Err("EntryCache::Stop : fail to stop LRU for path caching [%s]", err.Error())
 	}
 
+	c.store.Close()
+	c.store = nil
+
 	return nil
 }
 
@@ -151,6 +188,23 @@ func (c *EntryCache) Configure(_ bool) error {
 
 	c.pathLocks = common.NewLockMap()
 
+	c.diskPath = common.ExpandPath(conf.DiskCachePath)
+	c.diskTimeout = defaultDiskCacheTimeout
+	if config.IsSet(compName + ".disk-cache-timeout-sec") {
+		c.diskTimeout = conf.DiskCacheTimeout
+	}
+
+	if c.diskPath != "" && c.diskTimeout == 0 {
+		log.Err("EntryCache::Configure : disk-cache-timeout-sec can not be 0 when disk-cache-path is set")
+		return fmt.Errorf("config error in %s [disk-cache-timeout-sec can not be 0]", c.Name())
+	}
+
+	c.shardedList = conf.ShardedList
+	c.shardedNonASCII = conf.ShardedNonASCII
+	if c.shardedList > 0 {
+		log.Info("EntryCache::Configure : directories spanning multiple pages are listed in %d parallel shards, non-ascii names %v", c.shardedList, c.shardedNonASCII)
+	}
+
 	return nil
 }
 
@@ -165,9 +219,41 @@ func (c *EntryCache) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 
 	pathEntry, found := c.pathMap.Load(pathKey)
 	if !found {
-		log.Debug("EntryCache::StreamDir : Cache not valid, fetch new list for path: %s, token %s", options.Name, options.Token)
-		pathList, token, err := c.NextComponent().StreamDir(options)
-		if err == nil && len(pathList) > 0 {
+		pathList, token, found := c.store.GetPage(options.Name, options.Token)
+		if found {
+			log.Debug("EntryCache::StreamDir : Serving list from disk cache for path: %s, token %s", options.Name, options.Token)
+		} else if strings.HasPrefix(options.Token, shardedTokenPrefix) {
+			// Pages of a sharded listing expired before the caller reached them, so list the directory again
+			log.Debug("EntryCache::StreamDir : Sharded list expired, fetch new list for path: %s, token %s", options.Name, options.Token)
+
+			var err error
+			pathList, token, err = c.listSharded(options.Name, options.Token)
+			if err != nil {
+				return pathList, token, err
+			}
+		} else {
+			log.Debug("EntryCache::StreamDir : Cache not valid, fetch new list for path: %s, token %s", options.Name, options.Token)
+
+			var err error
+			pathList, token, err = c.NextComponent().StreamDir(options)
+			if err != nil {
+				return pathList, token, err
+			}
+
+			if c.shardedList > 0 && options.Token == "" && token != "" {
+				// Directory spans multiple pages, so list all of it in parallel shards instead of page by page
+				shardedList, shardedToken, err := c.listSharded(options.Name, options.Token)
+				if err == nil && len(shardedList) > 0 {
+					pathList, token = shardedList, shardedToken
+				} else {
+					_ = c.store.PutPage(options.Name, options.Token, pathList, token)
+				}
+			} else {
+				_ = c.store.PutPage(options.Name, options.Token, pathList, token)
+			}
+		}
+
+		if len(pathList) > 0 {
 			item := pathCacheItem{
 				children:  pathList,
 				nextToken: token,
@@ -175,7 +261,7 @@ func (c *EntryCache) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 			c.pathMap.Store(pathKey, item)
 			c.pathLRU.Add(pathKey)
 		}
-		return pathList, token, err
+		return pathList, token, nil
 	} else {
 		log.Debug("EntryCache::StreamDir : Serving list from cache for path: %s, token %s", options.Name, options.Token)
 		item := pathEntry.(pathCacheItem)
@@ -183,6 +269,93 @@ func (c *EntryCache) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 	}
 }
 
+// listSharded : List the directory in parallel shards and cache the result split into pages.
+// Returns the page for the given token, pages are chained with synthetic tokens as the shards have no common token.
+func (c *EntryCache) listSharded(name string, token string) ([]*internal.ObjAttr, string, error) {
+	entries := make([]*internal.ObjAttr, 0)
+	err := internal.ShardedList(c.NextComponent(), internal.ShardedListOptions{
+		Name:        name,
+		Parallelism: int(c.shardedList),
+		NonASCII:    c.shardedNonASCII,
+		Callback: func(list []*internal.ObjAttr) error {
+			entries = append(entries, list...)
+			return nil
+		},
+	})
+	if err != nil {
+		log.Err("EntryCache::listSharded : Failed to list %s in shards [%s]", name, err.Error())
+		return nil, "", err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	log.Debug("EntryCache::listSharded : Listed %d entries of %s in shards", len(entries), name)
+
+	pathList := make([]*internal.ObjAttr, 0)
+	nextToken := ""
+	for start := 0; start < len(entries); start += shardedPageSize {
+		end := min(start+shardedPageSize, len(entries))
+
+		pageToken := ""
+		if start > 0 {
+			pageToken = fmt.Sprintf("%s%d", shardedTokenPrefix, start/shardedPageSize)
+		}
+		pageNext := ""
+		if end < len(entries) {
+			pageNext = fmt.Sprintf("%s%d", shardedTokenPrefix, end/shardedPageSize)
+		}
+
+		_ = c.store.PutPage(name, pageToken, entries[start:end], pageNext)
+
+		if pageToken == token {
+			// Page asked by the caller is cached by StreamDir under the lock of its key
+			pathList, nextToken = entries[start:end], pageNext
+			continue
+		}
+
+		pathKey := fmt.Sprintf("%s##%s", name, pageToken)
+		c.pathMap.Store(pathKey, pathCacheItem{
+			children:  entries[start:end],
+			nextToken: pageNext,
+		})
+		c.pathLRU.Add(pathKey)
+	}
+
+	return pathList, nextToken, nil
+}
+
+// InvalidatePath : Drop cached listings of the directory holding a path changed by another client
+func (c *EntryCache) InvalidatePath(options internal.InvalidatePathOptions) error {
+	log.Trace("EntryCache::InvalidatePath : %s", options.Name)
+
+	name := internal.TruncateDirName(options.Name)
+	dir := filepath.Dir(name)
+	if dir == "." {
+		dir = ""
+	}
+
+	c.pathMap.Range(func(key, _ any) bool {
+		pathKey := key.(string)
+		// Directories are listed with a trailing separator
+		if strings.HasPrefix(pathKey, dir+"##") || strings.HasPrefix(pathKey, dir+"/##") ||
+			(options.IsDir && strings.HasPrefix(pathKey, name+"/")) {
+			flock := c.pathLocks.Get(pathKey)
+			flock.Lock()
+			c.pathMap.Delete(pathKey)
+			flock.Unlock()
+		}
+		return true
+	})
+	if options.IsDir {
+		c.store.InvalidateTree(name)
+	}
+	c.store.Invalidate(options.Name)
+
+	return c.NextComponent().InvalidatePath(options)
+}
+
 // pathEvict : Callback when a node from cache expires
 func (c *EntryCache) pathEvict(node *list.Element) {
 	pathKey := node.Value.(string)
@@ -39,6 +39,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -212,6 +213,182 @@ func (suite *entryCacheTestSuite) TestCachedEntry() {
 
 }
 
+func (suite *entryCacheTestSuite) TestInvalidatePath() {
+	defer suite.cleanupTest()
+
+	err := os.MkdirAll(filepath.Join(suite.fake_storage_path, "dir"), 0777)
+	suite.assert.Nil(err)
+	h, err := os.Create(filepath.Join(suite.fake_storage_path, "dir", "testfile1"))
+	suite.assert.Nil(err)
+	h.Close()
+
+	_, _, err = suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "", Token: ""})
+	suite.assert.Nil(err)
+	_, _, err = suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "dir/", Token: ""})
+	suite.assert.Nil(err)
+
+	// Only the listing of the parent directory is dropped
+	err = suite.entryCache.InvalidatePath(internal.InvalidatePathOptions{Name: "dir/testfile2"})
+	suite.assert.Nil(err)
+	_, found := suite.entryCache.pathMap.Load("dir/##")
+	suite.assert.False(found)
+	_, found = suite.entryCache.pathMap.Load("##")
+	suite.assert.True(found)
+
+	err = suite.entryCache.InvalidatePath(internal.InvalidatePathOptions{Name: "testfile3"})
+	suite.assert.Nil(err)
+	_, found = suite.entryCache.pathMap.Load("##")
+	suite.assert.False(found)
+
+	// Listings under a renamed or deleted directory are dropped as well
+	_, _, err = suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "dir/", Token: ""})
+	suite.assert.Nil(err)
+	err = suite.entryCache.InvalidatePath(internal.InvalidatePathOptions{Name: "dir", IsDir: true})
+	suite.assert.Nil(err)
+	_, found = suite.entryCache.pathMap.Load("dir/##")
+	suite.assert.False(found)
+}
+
+func (suite *entryCacheTestSuite) TestDiskCache() {
+	defer suite.cleanupTest()
+	suite.cleanupTest()
+
+	diskPath := suite.T().TempDir()
+	config.ResetConfig()
+	configuration := fmt.Sprintf("read-only: true\n\nentry_cache:\n  timeout-sec: 7\n  disk-cache-path: %s\n\nloopbackfs:\n  path: %s",
+		diskPath, suite.fake_storage_path)
+	suite.setupTestHelper(configuration)
+	suite.assert.NotNil(suite.entryCache.store)
+
+	filePath := filepath.Join(suite.fake_storage_path, "testfile1")
+	h, err := os.Create(filePath)
+	suite.assert.Nil(err)
+	h.Close()
+
+	objs, token, err := suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "", Token: ""})
+	suite.assert.Nil(err)
+	suite.assert.Equal("", token)
+	suite.assert.Len(objs, 1)
+
+	// Listing survives a remount
+	suite.cleanupTest()
+	suite.setupTestHelper(configuration)
+
+	filePath = filepath.Join(suite.fake_storage_path, "testfile2")
+	h, err = os.Create(filePath)
+	suite.assert.Nil(err)
+	h.Close()
+
+	objs, token, err = suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "", Token: ""})
+	suite.assert.Nil(err)
+	suite.assert.Equal("", token)
+	suite.assert.Len(objs, 1)
+	suite.assert.Equal("testfile1", objs[0].Name)
+
+	_, found := suite.entryCache.pathMap.Load("##")
+	suite.assert.True(found)
+}
+
+// pagedLister returns the listing of the wrapped component two entries at a time
+type pagedLister struct {
+	internal.Component
+	calls int
+}
+
+func (pl *pagedLister) StreamDir(options internal.StreamDirOptions) ([]*internal.ObjAttr, string, error) {
+	pl.calls++
+	entries, _, err := pl.Component.StreamDir(internal.StreamDirOptions{Name: options.Name})
+	if err != nil {
+		return nil, "", err
+	}
+
+	start, _ := strconv.Atoi(options.Token)
+	if start+2 >= len(entries) {
+		return entries[start:], "", nil
+	}
+	return entries[start : start+2], strconv.Itoa(start + 2), nil
+}
+
+func (suite *entryCacheTestSuite) TestShardedList() {
+	defer suite.cleanupTest()
+	suite.cleanupTest()
+
+	pageSize := shardedPageSize
+	shardedPageSize = 3
+	defer func() { shardedPageSize = pageSize }()
+
+	config.ResetConfig()
+	configuration := fmt.Sprintf("read-only: true\n\nentry_cache:\n  timeout-sec: 7\n  sharded-list-parallelism: 4\n\nloopbackfs:\n  path: %s",
+		suite.fake_storage_path)
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	suite.loopback = newLoopbackFS()
+	lister := &pagedLister{Component: suite.loopback}
+	suite.entryCache = newEntryCache(lister)
+	suite.loopback.Start(context.Background())
+	err := suite.entryCache.Start(context.Background())
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(4, suite.entryCache.shardedList)
+
+	err = os.MkdirAll(filepath.Join(suite.fake_storage_path, "dir", "sub"), 0777)
+	suite.assert.Nil(err)
+	for _, name := range []string{"f5", "f1", "Z", "dir/a", "dir/sub/b", "f3", "f2", "f4"} {
+		h, err := os.Create(filepath.Join(suite.fake_storage_path, name))
+		suite.assert.Nil(err)
+		h.Close()
+	}
+
+	names := func(objs []*internal.ObjAttr) []string {
+		list := make([]string, 0)
+		for _, obj := range objs {
+			list = append(list, obj.Path)
+		}
+		return list
+	}
+
+	// first page spans multiple pages, so the whole directory is listed in shards and served sorted
+	objs, token, err := suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "", Token: ""})
+	suite.assert.Nil(err)
+	suite.assert.Equal([]string{"Z", "dir", "f1"}, names(objs))
+	suite.assert.Equal(shardedTokenPrefix+"1", token)
+	suite.assert.Equal(1, lister.calls)
+
+	objs, token, err = suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "", Token: token})
+	suite.assert.Nil(err)
+	suite.assert.Equal([]string{"f2", "f3", "f4"}, names(objs))
+	suite.assert.Equal(shardedTokenPrefix+"2", token)
+
+	_, found := suite.entryCache.pathMap.Load("##" + shardedTokenPrefix + "2")
+	suite.assert.True(found)
+
+	// an expired page of the sharded listing is fetched by listing the directory again
+	suite.entryCache.pathMap.Delete("##" + shardedTokenPrefix + "2")
+	objs, token, err = suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "", Token: token})
+	suite.assert.Nil(err)
+	suite.assert.Equal([]string{"f5"}, names(objs))
+	suite.assert.Equal("", token)
+	suite.assert.Equal(1, lister.calls)
+
+	// directories fitting in one page are listed as usual
+	objs, token, err = suite.entryCache.StreamDir(internal.StreamDirOptions{Name: "dir/", Token: ""})
+	suite.assert.Nil(err)
+	suite.assert.Equal([]string{"dir/a", "dir/sub"}, names(objs))
+	suite.assert.Equal("", token)
+	suite.assert.Equal(2, lister.calls)
+}
+
+func (suite *entryCacheTestSuite) TestDiskCacheConfigError() {
+	defer suite.cleanupTest()
+
+	config.ResetConfig()
+	configuration := fmt.Sprintf("read-only: true\n\nentry_cache:\n  disk-cache-path: %s\n  disk-cache-timeout-sec: 0", suite.T().TempDir())
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+
+	entryCache := NewEntryCacheComponent()
+	err := entryCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "disk-cache-timeout-sec")
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestEntryCacheTestSuite(t *testing.T) {
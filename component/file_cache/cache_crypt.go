@@ -0,0 +1,225 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+)
+
+// Amount of data moved to or from storage at a time for an encrypted file
+const cryptTransferSize = 64 * common.CacheChunkSize
+
+// configureEncryption: Create the cipher used to encrypt files in the local cache
+func (c *FileCache) configureEncryption(conf FileCacheOptions) error {
+	c.cipher = nil
+	if !conf.Encryption {
+		return nil
+	}
+
+	var key []byte
+	var err error
+	if conf.EncryptionKey != "" {
+		key, err = common.ParseCacheKey(conf.EncryptionKey)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Data encrypted with an ephemeral key can not be read after unmount, so nothing can be retained across mounts
+		if conf.IndexFile != "" || conf.UploadJournal != "" || conf.OfflineLog != "" {
+			return fmt.Errorf("encryption without a key can not be used with index-file, upload-journal or offline-log")
+		}
+		c.cleanupOnStart = true
+		c.allowNonEmpty = true
+		log.Info("FileCache::configureEncryption : using an ephemeral key, cache will be cleaned on start")
+	}
+
+	c.cipher, err = common.NewCacheCipher(key)
+	if err != nil {
+		return err
+	}
+
+	if conf.PartialThresholdMB != 0 {
+		log.Warn("FileCache::configureEncryption : partial-threshold-mb is not supported with encryption, ignoring it")
+	}
+
+	return nil
+}
+
+// localSize: Size of data held by a file in local cache
+func (fc *FileCache) localSize(info os.FileInfo) int64 {
+	return fc.cipher.PlainSize(info.Size())
+}
+
+// openFlags: Flags to open a local file with, encrypted files are read back for every partial write
+func (fc *FileCache) openFlags(flags int) int {
+	if fc.cipher == nil {
+		return flags
+	}
+	return flags&^(os.O_WRONLY|os.O_APPEND) | os.O_RDWR
+}
+
+// readLocal: Read from a local file through the handle
+func (fc *FileCache) readLocal(handle *handlemap.Handle, data []byte, offset int64) (int, error) {
+	if fc.cipher == nil {
+		// Removing f.ReadAt as it involves lot of house keeping and then calls syscall.Pread
+		// Instead we will call syscall directly for better perf
+		return syscall.Pread(handle.FD(), data, offset)
+	}
+
+	lock := fc.cryptLocks.Get(handle.Path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	n, err := fc.cipher.ReadAt(handle.GetFileObject(), data, offset)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// writeLocal: Write to a local file through the handle
+func (fc *FileCache) writeLocal(handle *handlemap.Handle, data []byte, offset int64) (int, error) {
+	if fc.cipher == nil {
+		// Removing f.WriteAt as it involves lot of house keeping and then calls syscall.Pwrite
+		// Instead we will call syscall directly for better perf
+		return syscall.Pwrite(handle.FD(), data, offset)
+	}
+
+	lock := fc.cryptLocks.Get(handle.Path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return fc.cipher.WriteAt(handle.GetFileObject(), data, offset)
+}
+
+// truncateLocal: Change the size of a local file
+func (fc *FileCache) truncateLocal(name string, localPath string, size int64) error {
+	if fc.cipher == nil {
+		return os.Truncate(localPath, size)
+	}
+
+	lock := fc.cryptLocks.Get(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	f, err := os.OpenFile(localPath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return fc.cipher.Truncate(f, size)
+}
+
+// downloadLocal: Download a file from storage into the local file
+func (fc *FileCache) downloadLocal(name string, f *os.File, size int64) error {
+	if fc.cipher == nil {
+		return fc.NextComponent().CopyToFile(
+			internal.CopyToFileOptions{
+				Name:   name,
+				Offset: 0,
+				Count:  size,
+				File:   f,
+			})
+	}
+
+	// Data is downloaded in ranges and encrypted in memory so that plain data never reaches the disk
+	buf := make([]byte, cryptTransferSize)
+	for offset := int64(0); offset < size; {
+		data := buf[:min(int64(len(buf)), size-offset)]
+		n, err := fc.NextComponent().ReadInBuffer(internal.ReadInBufferOptions{
+			Path:   name,
+			Size:   size,
+			Offset: offset,
+			Data:   data,
+		})
+		if err != nil {
+			return err
+		}
+
+		if n != len(data) {
+			log.Err("FileCache::downloadLocal : short read of %s at %d [%d != %d]", name, offset, n, len(data))
+			return syscall.EIO
+		}
+
+		_, err = fc.cipher.WriteAt(f, data, offset)
+		if err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+
+	return nil
+}
+
+// uploadLocal: Upload the local file to storage
+func (fc *FileCache) uploadLocal(name string, f *os.File) error {
+	if fc.cipher == nil {
+		return fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{Name: name, File: f})
+	}
+
+	lock := fc.cryptLocks.Get(name)
+	lock.Lock()
+	size, err := fc.cipher.Size(f)
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Data is decrypted in memory while it is uploaded so that plain data never reaches the disk
+	reader := io.NewSectionReader(&plainReader{cipher: fc.cipher, lock: lock, f: f}, 0, size)
+	return fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{Name: name, Reader: reader})
+}
+
+// plainReader: Reads plain data of an encrypted local file, holding the lock of the file for every read
+type plainReader struct {
+	cipher *common.CacheCipher
+	lock   *common.LockMapItem
+	f      *os.File
+}
+
+func (r *plainReader) ReadAt(p []byte, off int64) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.cipher.ReadAt(r.f, p, off)
+}
@@ -0,0 +1,94 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+// configureDedup: Open the store of downloaded content shared by cached files having the same md5 sum
+func (c *FileCache) configureDedup(path string) error {
+	if c.cipher != nil {
+		return fmt.Errorf("dedup-path can not be used with encryption")
+	}
+
+	if path == c.tmpPath || path == c.mountPath || c.isCachePath(path) {
+		return fmt.Errorf("dedup-path shall be outside tmp-path and mount path")
+	}
+
+	var err error
+	c.dedup, err = common.NewDedupStore(path)
+	return err
+}
+
+// linkContent: Place the content of the file from the dedup store, returns false if it has to be downloaded
+func (fc *FileCache) linkContent(name string, localPath string, attr *internal.ObjAttr) bool {
+	if fc.dedup == nil || attr == nil {
+		return false
+	}
+
+	found, err := fc.dedup.Link(attr.MD5, attr.Size, localPath)
+	if err != nil {
+		log.Warn("FileCache::linkContent : failed to link %s from dedup store [%s]", name, err.Error())
+		return false
+	}
+
+	if found {
+		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, dedupFiles, (int64)(1))
+	}
+	return found
+}
+
+// unshare: Give the cached file its own copy of the content before it is changed in place
+func (fc *FileCache) unshare(localPath string) error {
+	return fc.dedup.Unshare(localPath)
+}
+
+// shared: Check if the cached file shares its content with other files through the dedup store.
+// Mode and times of such a file belong to all the files linked to it, so they are served from storage attributes.
+func (fc *FileCache) shared(info os.FileInfo) bool {
+	if fc.dedup == nil {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Nlink > 1
+}
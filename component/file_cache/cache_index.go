@@ -0,0 +1,242 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// indexEntry : Properties of the blob at the time its local copy was downloaded or uploaded
+type indexEntry struct {
+	ETag  string    `json:"etag,omitempty"`
+	Mtime time.Time `json:"lmt"`
+	Size  int64     `json:"size"`
+
+	// File was restored from the persisted index and is not yet validated against storage
+	restored bool
+}
+
+// matches : Whether the blob in storage is still the one cached locally
+func (e *indexEntry) matches(attr *internal.ObjAttr) bool {
+	if e.ETag != "" && attr.ETag != "" {
+		return e.ETag == attr.ETag
+	}
+	return e.Size == attr.Size && e.Mtime.Equal(attr.Mtime)
+}
+
+// cacheIndex : Persisted index of files in local cache, used to retain the cache across restarts.
+// A nil index means persistence is disabled and all methods are no-op.
+type cacheIndex struct {
+	sync.Mutex
+	path    string
+	entries map[string]*indexEntry
+	cipher  *common.CacheCipher
+}
+
+func newCacheIndex(path string) *cacheIndex {
+	return &cacheIndex{
+		path:    path,
+		entries: make(map[string]*indexEntry),
+	}
+}
+
+func indexName(name string) string {
+	return strings.Trim(filepath.ToSlash(name), "/")
+}
+
+// load : Read the index persisted by last clean shutdown.
+// Index file is removed after reading so that a crash does not leave behind an index which is out of sync with the cache.
+func (ci *cacheIndex) load() error {
+	if ci == nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(ci.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	_ = os.Remove(ci.path)
+
+	entries := make(map[string]*indexEntry)
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return err
+	}
+
+	ci.Lock()
+	defer ci.Unlock()
+
+	for name, entry := range entries {
+		entry.restored = true
+		ci.entries[indexName(name)] = entry
+	}
+	return nil
+}
+
+// save : Persist the index, written to a temp file first so that a partial write never replaces a good index
+func (ci *cacheIndex) save() error {
+	if ci == nil {
+		return nil
+	}
+
+	ci.Lock()
+	data, err := json.Marshal(ci.entries)
+	ci.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := ci.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, ci.path)
+}
+
+func (ci *cacheIndex) set(name string, attr *internal.ObjAttr) {
+	if ci == nil {
+		return
+	}
+
+	ci.Lock()
+	defer ci.Unlock()
+	ci.entries[indexName(name)] = &indexEntry{
+		ETag:  attr.ETag,
+		Mtime: attr.Mtime,
+		Size:  attr.Size,
+	}
+}
+
+func (ci *cacheIndex) get(name string) (indexEntry, bool) {
+	if ci == nil {
+		return indexEntry{}, false
+	}
+
+	ci.Lock()
+	defer ci.Unlock()
+	entry, found := ci.entries[indexName(name)]
+	if !found {
+		return indexEntry{}, false
+	}
+	return *entry, true
+}
+
+func (ci *cacheIndex) remove(name string) {
+	if ci == nil {
+		return
+	}
+
+	ci.Lock()
+	defer ci.Unlock()
+	delete(ci.entries, indexName(name))
+}
+
+// isRestored : Whether the file was restored from persisted index and still needs validation
+func (ci *cacheIndex) isRestored(name string) bool {
+	entry, found := ci.get(name)
+	return found && entry.restored
+}
+
+// validated : File restored from persisted index matches storage
+func (ci *cacheIndex) validated(name string) {
+	if ci == nil {
+		return
+	}
+
+	ci.Lock()
+	defer ci.Unlock()
+	if entry, found := ci.entries[indexName(name)]; found {
+		entry.restored = false
+	}
+}
+
+// restore : Reconcile the loaded index with the files present in local cache.
+// Files not present in the index are removed, as their state can not be trusted, unless keep says otherwise.
+// Names of valid files are returned.
+func (ci *cacheIndex) restore(tmpPath string, keep func(string) bool) []string {
+	if ci == nil {
+		return nil
+	}
+
+	ci.Lock()
+	defer ci.Unlock()
+
+	found := make(map[string]bool)
+	_ = filepath.WalkDir(tmpPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		name := indexName(strings.TrimPrefix(path, tmpPath))
+		if keep != nil && keep(name) {
+			delete(ci.entries, name)
+			return nil
+		}
+
+		entry, ok := ci.entries[name]
+
+		info, err := d.Info()
+		if !ok || err != nil || !d.Type().IsRegular() || ci.cipher.PlainSize(info.Size()) != entry.Size {
+			log.Debug("cacheIndex::restore : Removing %s not matching the index", path)
+			_ = deleteFile(path)
+			return nil
+		}
+
+		found[name] = true
+		return nil
+	})
+
+	names := make([]string, 0, len(found))
+	for name := range ci.entries {
+		if found[name] {
+			names = append(names, name)
+		} else {
+			delete(ci.entries, name)
+		}
+	}
+	return names
+}
@@ -56,6 +56,22 @@ type cachePolicyConfig struct {
 	fileLocks *common.LockMap
 
 	policyTrace bool
+
+	pinList *pinList
+
+	rangeMaps *rangeMapList
+
+	index *cacheIndex
+
+	uploads *uploadQueue
+
+	offline *offlineLog
+
+	tiers *tierList
+
+	quotas *quotaTracker
+
+	dedup *common.DedupStore
 }
 
 type cachePolicy interface {
@@ -75,6 +91,12 @@ type cachePolicy interface {
 
 // getUsagePercentage:  The current cache usage as a percentage of the maxSize
 func getUsagePercentage(path string, maxSize float64) float64 {
+	_, usagePercent := getCacheUsage(path, maxSize)
+	return usagePercent
+}
+
+// getCacheUsage: The current cache usage in MB and as a percentage of the maxSize
+func getCacheUsage(path string, maxSize float64) (float64, float64) {
 	var currSize float64
 	var usagePercent float64
 	var err error
@@ -99,7 +121,7 @@ func getUsagePercentage(path string, maxSize float64) float64 {
 	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, cacheUsage, fmt.Sprintf("%f MB", currSize))
 	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, usgPer, fmt.Sprintf("%f%%", usagePercent))
 
-	return usagePercent
+	return currSize, usagePercent
 }
 
 // Delete a given file
@@ -0,0 +1,359 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+// DirQuotaOptions : Limit on the cache usage of files under a top level directory
+type DirQuotaOptions struct {
+	Directory string  `config:"directory" yaml:"directory,omitempty"`
+	MaxSizeMB float64 `config:"max-size-mb" yaml:"max-size-mb,omitempty"`
+}
+
+// UserQuotaOptions : Limit on the cache usage of files brought into the cache by a user
+type UserQuotaOptions struct {
+	Uid       uint32  `config:"uid" yaml:"uid"`
+	MaxSizeMB float64 `config:"max-size-mb" yaml:"max-size-mb,omitempty"`
+}
+
+// quotaRecord : Space a cached file takes, its length and the user it is charged to
+type quotaRecord struct {
+	size   int64
+	length int64
+	uid    uint32
+	owned  bool
+}
+
+// quotaLimit : Quota key a file is charged to and its limit in bytes
+type quotaLimit struct {
+	key   string
+	limit int64
+}
+
+// quotaTracker : Cache usage per top level directory and per user.
+// Usage is updated as files enter, grow and leave the cache so the cache directory is never walked.
+// A file is charged with the blocks it takes in the cache to the quota of its top level directory and of the user who brought it into the cache.
+type quotaTracker struct {
+	sync.Mutex
+
+	dirLimits   map[string]int64
+	userLimits  map[uint32]int64
+	defaultDir  int64
+	defaultUser int64
+
+	usage   map[string]int64
+	records map[string]*quotaRecord
+}
+
+// newQuotaTracker : Create the tracker from config, returns nil if no quota is configured
+func newQuotaTracker(conf FileCacheOptions) (*quotaTracker, error) {
+	if len(conf.DirQuotas) == 0 && len(conf.UserQuotas) == 0 && conf.DefaultDirQuotaMB == 0 && conf.DefaultUserQuotaMB == 0 {
+		return nil, nil
+	}
+
+	qt := &quotaTracker{
+		dirLimits:   make(map[string]int64),
+		userLimits:  make(map[uint32]int64),
+		defaultDir:  int64(conf.DefaultDirQuotaMB * MB),
+		defaultUser: int64(conf.DefaultUserQuotaMB * MB),
+		usage:       make(map[string]int64),
+		records:     make(map[string]*quotaRecord),
+	}
+
+	for _, quota := range conf.DirQuotas {
+		dir := normalizePinPattern(quota.Directory)
+		if dir == "" || dir == "." || strings.Contains(dir, "/") {
+			return nil, fmt.Errorf("quota directory %s shall be a top level directory", quota.Directory)
+		}
+		if quota.MaxSizeMB <= 0 {
+			return nil, fmt.Errorf("quota of directory %s shall be greater than 0", quota.Directory)
+		}
+		qt.dirLimits[dir] = int64(quota.MaxSizeMB * MB)
+	}
+
+	for _, quota := range conf.UserQuotas {
+		if quota.MaxSizeMB <= 0 {
+			return nil, fmt.Errorf("quota of uid %d shall be greater than 0", quota.Uid)
+		}
+		qt.userLimits[quota.Uid] = int64(quota.MaxSizeMB * MB)
+	}
+
+	return qt, nil
+}
+
+// quotaDir : Top level directory of a path, empty for files in the root of the container
+func quotaDir(name string) string {
+	name = strings.Trim(name, "/")
+	if idx := strings.Index(name, "/"); idx > 0 {
+		return name[:idx]
+	}
+	return ""
+}
+
+// limits : Quotas a file is charged to, keys without a limit are not tracked
+func (qt *quotaTracker) limits(name string, uid uint32, owned bool) []quotaLimit {
+	list := make([]quotaLimit, 0, 2)
+
+	if dir := quotaDir(name); dir != "" {
+		limit, found := qt.dirLimits[dir]
+		if !found {
+			limit = qt.defaultDir
+		}
+		if limit != 0 {
+			list = append(list, quotaLimit{key: "dir:" + dir, limit: limit})
+		}
+	}
+
+	if owned {
+		limit, found := qt.userLimits[uid]
+		if !found {
+			limit = qt.defaultUser
+		}
+		if limit != 0 {
+			list = append(list, quotaLimit{key: fmt.Sprintf("uid:%d", uid), limit: limit})
+		}
+	}
+
+	return list
+}
+
+// admit : Check that the given user can bring a file taking size bytes into the cache.
+// Fails once a quota it is charged to is used up or if the file does not fit in it, space taken by an older copy of the file is given back.
+func (qt *quotaTracker) admit(name string, uid uint32, size int64) error {
+	if qt == nil {
+		return nil
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	if rec, found := qt.records[name]; found {
+		size -= rec.size
+	}
+
+	for _, l := range qt.limits(name, uid, true) {
+		if qt.usage[l.key] >= l.limit || qt.usage[l.key]+size > l.limit {
+			return syscall.EDQUOT
+		}
+	}
+
+	return nil
+}
+
+// extend : Check that size bytes more of a cached file can be downloaded, fails if this exceeds any of its quotas
+func (qt *quotaTracker) extend(name string, size int64) error {
+	if qt == nil {
+		return nil
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	rec, found := qt.records[name]
+	if !found {
+		rec = &quotaRecord{}
+	}
+
+	for _, l := range qt.limits(name, rec.uid, rec.owned) {
+		if qt.usage[l.key]+size > l.limit {
+			return syscall.EDQUOT
+		}
+	}
+
+	return nil
+}
+
+// charge : Set the space taken and the length of a cached file, a file not tracked so far is charged to the given user
+func (qt *quotaTracker) charge(name string, uid uint32, size int64, length int64) {
+	if qt == nil {
+		return
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	rec, found := qt.records[name]
+	if !found {
+		rec = &quotaRecord{uid: uid, owned: true}
+	}
+	rec.length = length
+	qt.apply(name, rec, size)
+}
+
+// resize : Set the space taken and the length of a cached file, a file not tracked so far is charged to its directory only
+func (qt *quotaTracker) resize(name string, size int64, length int64) {
+	if qt == nil {
+		return
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	rec, found := qt.records[name]
+	if !found {
+		rec = &quotaRecord{}
+	}
+	rec.length = length
+	qt.apply(name, rec, size)
+}
+
+// grow : Charge a write from offset to end, fails if the growth of the file exceeds any of its quotas.
+// Only data written past the end of the file is charged, a gap left before it takes no space.
+func (qt *quotaTracker) grow(name string, offset int64, end int64) error {
+	if qt == nil {
+		return nil
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	rec, found := qt.records[name]
+	if !found {
+		rec = &quotaRecord{}
+	}
+
+	if end <= rec.length {
+		return nil
+	}
+
+	delta := end - max(offset, rec.length)
+	for _, l := range qt.limits(name, rec.uid, rec.owned) {
+		if qt.usage[l.key]+delta > l.limit {
+			return syscall.EDQUOT
+		}
+	}
+
+	rec.length = end
+	qt.apply(name, rec, rec.size+delta)
+	return nil
+}
+
+// release : Drop the charge of a file which has left the cache
+func (qt *quotaTracker) release(name string) {
+	if qt == nil {
+		return
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	if rec, found := qt.records[name]; found {
+		qt.apply(name, rec, 0)
+		delete(qt.records, name)
+	}
+}
+
+// rename : Move the charge of a file to its new name, which may belong to a different directory quota
+func (qt *quotaTracker) rename(src string, dst string) {
+	if qt == nil {
+		return
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	if rec, found := qt.records[dst]; found {
+		qt.apply(dst, rec, 0)
+		delete(qt.records, dst)
+	}
+
+	rec, found := qt.records[src]
+	if !found {
+		return
+	}
+
+	size := rec.size
+	qt.apply(src, rec, 0)
+	delete(qt.records, src)
+	qt.apply(dst, rec, size)
+}
+
+// apply : Update the usage of the quotas a file is charged to with its new size
+func (qt *quotaTracker) apply(name string, rec *quotaRecord, size int64) {
+	delta := size - rec.size
+	rec.size = size
+	qt.records[name] = rec
+
+	if delta == 0 {
+		return
+	}
+
+	for _, l := range qt.limits(name, rec.uid, rec.owned) {
+		qt.usage[l.key] += delta
+		if qt.usage[l.key] == 0 {
+			delete(qt.usage, l.key)
+		}
+		fileCacheStatsCollector.UpdateStats(stats_manager.Replace, quotaUsage+" "+l.key, fmt.Sprintf("%f MB", float64(qt.usage[l.key])/MB))
+	}
+}
+
+// list : Current usage in bytes per quota key
+func (qt *quotaTracker) list() map[string]int64 {
+	usage := make(map[string]int64)
+	if qt == nil {
+		return usage
+	}
+
+	qt.Lock()
+	defer qt.Unlock()
+
+	for key, size := range qt.usage {
+		usage[key] = size
+	}
+	return usage
+}
+
+// chargedSize : Space a cached file takes in the cache.
+// Sparse files are charged only the blocks allocated to them, content linked from the dedup store is shared among the files linking it.
+func (fc *FileCache) chargedSize(info os.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+
+	size := stat.Blocks * 512
+	if fc.dedup != nil && stat.Nlink > 1 {
+		// One of the links is held by the dedup store itself
+		size /= int64(stat.Nlink - 1)
+	}
+	return size
+}
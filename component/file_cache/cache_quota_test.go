@@ -0,0 +1,182 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type cacheQuotaTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	quotas *quotaTracker
+}
+
+func (suite *cacheQuotaTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+
+	var err error
+	suite.quotas, err = newQuotaTracker(FileCacheOptions{
+		DirQuotas:  []DirQuotaOptions{{Directory: "/jobs/", MaxSizeMB: 1}},
+		UserQuotas: []UserQuotaOptions{{Uid: 1000, MaxSizeMB: 2}},
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(suite.quotas)
+}
+
+func (suite *cacheQuotaTestSuite) TestNilTracker() {
+	quotas, err := newQuotaTracker(FileCacheOptions{})
+	suite.assert.NoError(err)
+	suite.assert.Nil(quotas)
+
+	suite.assert.NoError(quotas.admit("jobs/a", 1000, 0))
+	suite.assert.NoError(quotas.grow("jobs/a", 0, MB))
+	quotas.charge("jobs/a", 1000, MB, MB)
+	quotas.resize("jobs/a", MB, MB)
+	quotas.rename("jobs/a", "jobs/b")
+	quotas.release("jobs/b")
+	suite.assert.Empty(quotas.list())
+}
+
+func (suite *cacheQuotaTestSuite) TestInvalidConfig() {
+	_, err := newQuotaTracker(FileCacheOptions{DirQuotas: []DirQuotaOptions{{Directory: "a/b", MaxSizeMB: 1}}})
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "shall be a top level directory")
+
+	_, err = newQuotaTracker(FileCacheOptions{DirQuotas: []DirQuotaOptions{{Directory: "a", MaxSizeMB: 0}}})
+	suite.assert.Error(err)
+
+	_, err = newQuotaTracker(FileCacheOptions{UserQuotas: []UserQuotaOptions{{Uid: 1, MaxSizeMB: 0}}})
+	suite.assert.Error(err)
+}
+
+func (suite *cacheQuotaTestSuite) TestQuotaDir() {
+	suite.assert.Equal("", quotaDir("file"))
+	suite.assert.Equal("a", quotaDir("a/file"))
+	suite.assert.Equal("a", quotaDir("/a/b/c/file"))
+}
+
+func (suite *cacheQuotaTestSuite) TestDirQuota() {
+	suite.quotas.charge("jobs/a", 1, 0, 0)
+	suite.assert.NoError(suite.quotas.grow("jobs/a", 0, MB/2))
+	suite.assert.NoError(suite.quotas.grow("jobs/a", 0, MB/4)) // write inside the file does not grow it
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB / 2}, suite.quotas.list())
+
+	suite.quotas.charge("jobs/b", 1, 0, 0)
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.grow("jobs/b", 0, MB))
+	suite.assert.NoError(suite.quotas.grow("jobs/b", 0, MB/2))
+
+	// Directory quota is used up, no new file can be created in it
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.admit("jobs/c", 1, 0))
+	suite.assert.NoError(suite.quotas.admit("other/c", 1, 0))
+	suite.assert.NoError(suite.quotas.admit("c", 1, 0))
+
+	suite.quotas.release("jobs/a")
+	suite.assert.NoError(suite.quotas.admit("jobs/c", 1, 0))
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB / 2}, suite.quotas.list())
+}
+
+func (suite *cacheQuotaTestSuite) TestSparseQuota() {
+	// Gap left by a write past the end of the file takes no space
+	suite.quotas.charge("jobs/a", 1, 0, 0)
+	suite.assert.NoError(suite.quotas.grow("jobs/a", 4*MB, 4*MB+MB/4))
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB / 4}, suite.quotas.list())
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.grow("jobs/a", 8*MB, 9*MB))
+
+	// File cached in ranges is charged the ranges downloaded, not its length
+	suite.quotas.resize("jobs/a", MB/2, 8*MB)
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB / 2}, suite.quotas.list())
+	suite.assert.NoError(suite.quotas.extend("jobs/a", MB/2))
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.extend("jobs/a", MB))
+}
+
+func (suite *cacheQuotaTestSuite) TestAdmitSize() {
+	suite.assert.NoError(suite.quotas.admit("jobs/a", 1, MB))
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.admit("jobs/a", 1, MB+1))
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.admit("a", 1000, 3*MB))
+
+	// Space taken by the older copy of the file is given back when it is downloaded again
+	suite.quotas.charge("jobs/a", 1, MB/2, MB/2)
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.admit("jobs/b", 1, 3*MB/4))
+	suite.assert.NoError(suite.quotas.admit("jobs/a", 1, 3*MB/4))
+}
+
+func (suite *cacheQuotaTestSuite) TestUserQuota() {
+	suite.quotas.charge("a/file1", 1000, MB, MB)
+	suite.quotas.charge("b/file2", 1000, MB, MB)
+	suite.assert.Equal(map[string]int64{"uid:1000": 2 * MB}, suite.quotas.list())
+	suite.assert.Equal(syscall.EDQUOT, suite.quotas.admit("c/file3", 1000, 0))
+	suite.assert.NoError(suite.quotas.admit("c/file3", 1001, 0))
+
+	// File stays charged to the user who brought it into the cache
+	suite.quotas.charge("a/file1", 1001, 2*MB, 2*MB)
+	suite.assert.Equal(map[string]int64{"uid:1000": 3 * MB}, suite.quotas.list())
+
+	suite.quotas.resize("a/file1", 0, 0)
+	suite.assert.Equal(map[string]int64{"uid:1000": MB}, suite.quotas.list())
+}
+
+func (suite *cacheQuotaTestSuite) TestDefaultQuota() {
+	quotas, err := newQuotaTracker(FileCacheOptions{DefaultDirQuotaMB: 1, DefaultUserQuotaMB: 3})
+	suite.assert.NoError(err)
+
+	quotas.charge("x/file", 5, MB, MB)
+	quotas.charge("y/file", 5, MB, MB)
+	suite.assert.Equal(map[string]int64{"dir:x": MB, "dir:y": MB, "uid:5": 2 * MB}, quotas.list())
+	suite.assert.Equal(syscall.EDQUOT, quotas.admit("x/other", 6, 0))
+	suite.assert.NoError(quotas.admit("z/other", 5, 0))
+	suite.assert.Equal(syscall.EDQUOT, quotas.grow("z/other", 0, 2*MB))
+}
+
+func (suite *cacheQuotaTestSuite) TestRename() {
+	suite.quotas.charge("jobs/a", 1000, MB/2, MB/2)
+	suite.quotas.charge("other/b", 1000, MB/4, MB/4)
+
+	suite.quotas.rename("jobs/a", "other/b")
+	suite.assert.Equal(map[string]int64{"uid:1000": MB / 2}, suite.quotas.list())
+
+	suite.quotas.rename("other/b", "jobs/c")
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB / 2, "uid:1000": MB / 2}, suite.quotas.list())
+
+	suite.quotas.release("jobs/c")
+	suite.assert.Empty(suite.quotas.list())
+}
+
+func TestCacheQuotaTestSuite(t *testing.T) {
+	suite.Run(t, new(cacheQuotaTestSuite))
+}
@@ -0,0 +1,427 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+// CacheTierOptions : Config of one tier of the local cache
+type CacheTierOptions struct {
+	Path          string  `config:"path" yaml:"path,omitempty"`
+	MaxSizeMB     float64 `config:"max-size-mb" yaml:"max-size-mb,omitempty"`
+	HighThreshold uint32  `config:"high-threshold" yaml:"high-threshold,omitempty"`
+	LowThreshold  uint32  `config:"low-threshold" yaml:"low-threshold,omitempty"`
+}
+
+// applyTopTier : First of the configured tiers is the temp path, its settings are the regular cache settings
+func applyTopTier(conf *FileCacheOptions) error {
+	if len(conf.Tiers) == 0 {
+		return nil
+	}
+
+	top := conf.Tiers[0]
+	if conf.TmpPath != "" && common.ExpandPath(conf.TmpPath) != common.ExpandPath(top.Path) {
+		return fmt.Errorf("path shall not be set along with tiers")
+	}
+
+	conf.TmpPath = top.Path
+	if top.MaxSizeMB != 0 {
+		conf.MaxSizeMB = top.MaxSizeMB
+	}
+	if top.HighThreshold != 0 {
+		conf.HighThreshold = top.HighThreshold
+	}
+	if top.LowThreshold != 0 {
+		conf.LowThreshold = top.LowThreshold
+	}
+	return nil
+}
+
+// cacheTier : A slower tier where files evicted from the tier above are kept
+type cacheTier struct {
+	path          string
+	maxSize       int64
+	highThreshold int64
+	lowThreshold  int64
+	usage         int64
+
+	// Files in this tier, most recently demoted at the front
+	lru   *list.List
+	files map[string]*list.Element
+}
+
+// tierFile : A file held in a cache tier
+type tierFile struct {
+	name string
+	size int64
+}
+
+// tierList : Slower tiers of the local cache in order, the fastest tier being the temp path itself.
+// Files evicted from the temp path are demoted to the first tier and from there down the list as each tier fills up.
+// A file in a slower tier is promoted back to the temp path when it is opened and has not changed in storage.
+// A nil list means tiering is disabled and all methods are no-op.
+type tierList struct {
+	sync.Mutex
+	tiers  []*cacheTier
+	cipher *common.CacheCipher
+}
+
+func newTierList(confs []CacheTierOptions) (*tierList, error) {
+	if len(confs) == 0 {
+		return nil, nil
+	}
+
+	tl := &tierList{tiers: make([]*cacheTier, 0, len(confs))}
+	for _, conf := range confs {
+		path := common.ExpandPath(conf.Path)
+		if path == "" {
+			return nil, fmt.Errorf("path of a cache tier is not set")
+		}
+
+		err := os.MkdirAll(path, os.FileMode(0755))
+		if err != nil {
+			return nil, err
+		}
+
+		maxSizeMB := conf.MaxSizeMB
+		if maxSizeMB == 0 {
+			var stat syscall.Statfs_t
+			err = syscall.Statfs(path, &stat)
+			if err != nil {
+				return nil, err
+			}
+			maxSizeMB = (0.8 * float64(stat.Bavail) * float64(stat.Bsize)) / MB
+		}
+
+		high, low := conf.HighThreshold, conf.LowThreshold
+		if high == 0 {
+			high = defaultMaxThreshold
+		}
+		if low == 0 {
+			low = defaultMinThreshold
+		}
+		if low >= high {
+			return nil, fmt.Errorf("low-threshold of cache tier %s shall be less than high-threshold", path)
+		}
+
+		maxSize := int64(maxSizeMB * MB)
+		tl.tiers = append(tl.tiers, &cacheTier{
+			path:          path,
+			maxSize:       maxSize,
+			highThreshold: maxSize * int64(high) / 100,
+			lowThreshold:  maxSize * int64(low) / 100,
+			lru:           list.New(),
+			files:         make(map[string]*list.Element),
+		})
+	}
+
+	return tl, nil
+}
+
+// paths : Paths of all the tiers
+func (tl *tierList) paths() []string {
+	if tl == nil {
+		return nil
+	}
+
+	paths := make([]string, 0, len(tl.tiers))
+	for _, tier := range tl.tiers {
+		paths = append(paths, tier.path)
+	}
+	return paths
+}
+
+// cleanup : Remove all the files from the tiers
+func (tl *tierList) cleanup() {
+	if tl == nil {
+		return
+	}
+
+	tl.Lock()
+	defer tl.Unlock()
+
+	for _, tier := range tl.tiers {
+		err := common.TempCacheCleanup(tier.path)
+		if err != nil {
+			log.Err("tierList::cleanup : failed to cleanup %s [%s]", tier.path, err.Error())
+		}
+		tier.usage = 0
+		tier.lru.Init()
+		tier.files = make(map[string]*list.Element)
+	}
+}
+
+// demote : Move a file evicted from the temp path to the first tier
+func (tl *tierList) demote(name string, localPath string) bool {
+	if tl == nil {
+		return false
+	}
+
+	tl.Lock()
+	defer tl.Unlock()
+
+	err := tl.moveTo(0, name, localPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Err("tierList::demote : failed to demote %s to %s [%s]", name, tl.tiers[0].path, err.Error())
+		}
+		return false
+	}
+
+	log.Debug("tierList::demote : %s demoted to %s", name, tl.tiers[0].path)
+	fileCacheStatsCollector.UpdateStats(stats_manager.Increment, tierDemoted, (int64)(1))
+	return true
+}
+
+// moveTo : Move the file to the given tier and make space in it by pushing the oldest files further down, caller shall hold the lock
+func (tl *tierList) moveTo(idx int, name string, src string) error {
+	tier := tl.tiers[idx]
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if info.Size() > tier.maxSize {
+		return fmt.Errorf("file larger than the tier")
+	}
+
+	err = moveFile(src, filepath.Join(tier.path, name))
+	if err != nil {
+		return err
+	}
+
+	tl.drop(tier, name)
+	tier.files[name] = tier.lru.PushFront(&tierFile{name: name, size: info.Size()})
+	tier.usage += info.Size()
+
+	if tier.usage <= tier.highThreshold {
+		return nil
+	}
+
+	// Tier is full, push the least recently demoted files to the next tier or remove them if this is the last tier
+	for tier.usage > tier.lowThreshold && tier.lru.Len() > 0 {
+		file := tier.lru.Back().Value.(*tierFile)
+		localPath := filepath.Join(tier.path, file.name)
+		tl.drop(tier, file.name)
+
+		if idx+1 < len(tl.tiers) && tl.moveTo(idx+1, file.name, localPath) == nil {
+			continue
+		}
+
+		err = deleteFile(localPath)
+		if err != nil && !os.IsNotExist(err) {
+			log.Err("tierList::moveTo : failed to delete %s [%s]", localPath, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// drop : Stop tracking the file in the tier, caller shall hold the lock
+func (tl *tierList) drop(tier *cacheTier, name string) {
+	elem, found := tier.files[name]
+	if !found {
+		return
+	}
+
+	tier.usage -= elem.Value.(*tierFile).size
+	tier.lru.Remove(elem)
+	delete(tier.files, name)
+}
+
+// promote : Move the file back to the temp path if it has not changed in storage since it was cached
+func (tl *tierList) promote(name string, localPath string, attr *internal.ObjAttr) bool {
+	if tl == nil || attr == nil {
+		return false
+	}
+
+	tl.Lock()
+	defer tl.Unlock()
+
+	for _, tier := range tl.tiers {
+		if _, found := tier.files[name]; !found {
+			continue
+		}
+
+		tierPath := filepath.Join(tier.path, name)
+		tl.drop(tier, name)
+
+		// Local copy carries the last modified time of the blob it was downloaded from
+		info, err := os.Stat(tierPath)
+		if err != nil || tl.cipher.PlainSize(info.Size()) != attr.Size || !info.ModTime().Equal(attr.Mtime) {
+			log.Info("tierList::promote : %s changed in storage since it was cached", name)
+			_ = deleteFile(tierPath)
+			return false
+		}
+
+		err = os.MkdirAll(filepath.Dir(localPath), os.FileMode(0755))
+		if err == nil {
+			err = moveFile(tierPath, localPath)
+		}
+		if err != nil {
+			log.Err("tierList::promote : failed to promote %s from %s [%s]", name, tier.path, err.Error())
+			_ = deleteFile(tierPath)
+			return false
+		}
+
+		log.Debug("tierList::promote : %s promoted from %s", name, tier.path)
+		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, tierPromoted, (int64)(1))
+		return true
+	}
+
+	return false
+}
+
+// remove : Drop the copies of the files held in any tier
+func (tl *tierList) remove(names ...string) {
+	if tl == nil {
+		return
+	}
+
+	tl.Lock()
+	defer tl.Unlock()
+
+	for _, name := range names {
+		for _, tier := range tl.tiers {
+			if _, found := tier.files[name]; found {
+				tl.drop(tier, name)
+				_ = deleteFile(filepath.Join(tier.path, name))
+			}
+		}
+	}
+}
+
+// contains : Whether the file is held in any tier
+func (tl *tierList) contains(name string) bool {
+	if tl == nil {
+		return false
+	}
+
+	tl.Lock()
+	defer tl.Unlock()
+
+	for _, tier := range tl.tiers {
+		if _, found := tier.files[name]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// capacity : Combined size and usage of all the tiers in bytes
+func (tl *tierList) capacity() (int64, int64) {
+	if tl == nil {
+		return 0, 0
+	}
+
+	tl.Lock()
+	defer tl.Unlock()
+
+	var size, usage int64
+	for _, tier := range tl.tiers {
+		size += tier.maxSize
+		usage += tier.usage
+	}
+	return size, usage
+}
+
+// moveFile : Move a file, copying it across file systems if required
+func moveFile(src string, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), os.FileMode(0755))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm()|0200)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err1 := out.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+
+	// Last modified time is used to validate the file on promotion, so retain it
+	_ = os.Chmod(dst, info.Mode().Perm())
+	stat := info.Sys().(*syscall.Stat_t)
+	_ = os.Chtimes(dst, time.Unix(stat.Atim.Sec, stat.Atim.Nsec), info.ModTime())
+
+	return os.Remove(src)
+}
+
+// isTierPath : Whether the path is inside any of the tiers
+func (tl *tierList) isTierPath(path string) bool {
+	for _, p := range tl.paths() {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
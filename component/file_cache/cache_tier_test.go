@@ -0,0 +1,213 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type cacheTierTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	dir    string
+}
+
+func (suite *cacheTierTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.dir = suite.T().TempDir()
+}
+
+// createFile : Create a file of given size in the temp path and return its attributes
+func (suite *cacheTierTestSuite) createFile(name string, size int) *internal.ObjAttr {
+	path := filepath.Join(suite.dir, "tmp", name)
+	err := os.MkdirAll(filepath.Dir(path), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(path, make([]byte, size), 0666)
+	suite.assert.NoError(err)
+
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(path, mtime, mtime)
+	suite.assert.NoError(err)
+	return &internal.ObjAttr{Path: name, Size: int64(size), Mtime: mtime}
+}
+
+func (suite *cacheTierTestSuite) tierPath(idx int, name string) string {
+	return filepath.Join(suite.dir, "tier"+string(rune('0'+idx)), name)
+}
+
+func (suite *cacheTierTestSuite) newTiers(sizeMB ...float64) *tierList {
+	confs := make([]CacheTierOptions, 0)
+	for i, size := range sizeMB {
+		confs = append(confs, CacheTierOptions{Path: filepath.Join(suite.dir, "tier"+string(rune('1'+i))), MaxSizeMB: size})
+	}
+
+	tl, err := newTierList(confs)
+	suite.assert.NoError(err)
+	return tl
+}
+
+func (suite *cacheTierTestSuite) TestNilList() {
+	var tl *tierList = nil
+	suite.assert.False(tl.demote("a", "a"))
+	suite.assert.False(tl.promote("a", "a", &internal.ObjAttr{}))
+	suite.assert.False(tl.contains("a"))
+	tl.remove("a")
+	tl.cleanup()
+
+	size, usage := tl.capacity()
+	suite.assert.Zero(size)
+	suite.assert.Zero(usage)
+
+	tl, err := newTierList(nil)
+	suite.assert.NoError(err)
+	suite.assert.Nil(tl)
+}
+
+func (suite *cacheTierTestSuite) TestInvalidConfig() {
+	_, err := newTierList([]CacheTierOptions{{MaxSizeMB: 1}})
+	suite.assert.Error(err)
+
+	_, err = newTierList([]CacheTierOptions{{Path: suite.dir, HighThreshold: 50, LowThreshold: 60}})
+	suite.assert.Error(err)
+}
+
+func (suite *cacheTierTestSuite) TestApplyTopTier() {
+	conf := FileCacheOptions{}
+	suite.assert.NoError(applyTopTier(&conf))
+	suite.assert.Empty(conf.TmpPath)
+
+	conf.Tiers = []CacheTierOptions{{Path: "/fast", MaxSizeMB: 10, HighThreshold: 90, LowThreshold: 70}, {Path: "/slow"}}
+	suite.assert.NoError(applyTopTier(&conf))
+	suite.assert.Equal("/fast", conf.TmpPath)
+	suite.assert.EqualValues(10, conf.MaxSizeMB)
+	suite.assert.EqualValues(90, conf.HighThreshold)
+	suite.assert.EqualValues(70, conf.LowThreshold)
+
+	conf.TmpPath = "/other"
+	suite.assert.Error(applyTopTier(&conf))
+}
+
+func (suite *cacheTierTestSuite) TestDemotePromote() {
+	tl := suite.newTiers(1)
+	attr := suite.createFile("dir/a", 1024)
+	localPath := filepath.Join(suite.dir, "tmp", "dir/a")
+
+	suite.assert.True(tl.demote("dir/a", localPath))
+	suite.assert.NoFileExists(localPath)
+	suite.assert.FileExists(suite.tierPath(1, "dir/a"))
+	suite.assert.True(tl.contains("dir/a"))
+
+	size, usage := tl.capacity()
+	suite.assert.EqualValues(MB, size)
+	suite.assert.EqualValues(1024, usage)
+
+	suite.assert.True(tl.promote("dir/a", localPath, attr))
+	suite.assert.FileExists(localPath)
+	suite.assert.NoFileExists(suite.tierPath(1, "dir/a"))
+	suite.assert.False(tl.contains("dir/a"))
+
+	_, usage = tl.capacity()
+	suite.assert.EqualValues(0, usage)
+
+	// File not in any tier and a missing file can not be demoted
+	suite.assert.False(tl.promote("dir/a", localPath, attr))
+	suite.assert.False(tl.demote("dir/b", filepath.Join(suite.dir, "tmp", "dir/b")))
+}
+
+func (suite *cacheTierTestSuite) TestPromoteChanged() {
+	tl := suite.newTiers(1)
+	attr := suite.createFile("a", 1024)
+	localPath := filepath.Join(suite.dir, "tmp", "a")
+	suite.assert.True(tl.demote("a", localPath))
+
+	changed := *attr
+	changed.Mtime = changed.Mtime.Add(time.Minute)
+	suite.assert.False(tl.promote("a", localPath, &changed))
+	suite.assert.NoFileExists(localPath)
+	suite.assert.NoFileExists(suite.tierPath(1, "a"))
+	suite.assert.False(tl.contains("a"))
+}
+
+func (suite *cacheTierTestSuite) TestCascade() {
+	// First tier holds 100 KB, so demoting more pushes the oldest files to the second tier
+	tl := suite.newTiers(0.1, 1)
+	for _, name := range []string{"a", "b", "c"} {
+		suite.createFile(name, 40*1024)
+		suite.assert.True(tl.demote(name, filepath.Join(suite.dir, "tmp", name)))
+	}
+
+	suite.assert.FileExists(suite.tierPath(2, "a"))
+	suite.assert.FileExists(suite.tierPath(2, "b"))
+	suite.assert.FileExists(suite.tierPath(1, "c"))
+	suite.assert.True(tl.contains("a"))
+
+	// A file larger than the tier is not demoted
+	suite.createFile("big", 200*1024)
+	suite.assert.False(tl.demote("big", filepath.Join(suite.dir, "tmp", "big")))
+
+	tl.remove("a", "c")
+	suite.assert.NoFileExists(suite.tierPath(2, "a"))
+	suite.assert.NoFileExists(suite.tierPath(1, "c"))
+	_, usage := tl.capacity()
+	suite.assert.EqualValues(40*1024, usage)
+
+	tl.cleanup()
+	suite.assert.NoFileExists(suite.tierPath(2, "b"))
+	_, usage = tl.capacity()
+	suite.assert.EqualValues(0, usage)
+}
+
+func (suite *cacheTierTestSuite) TestLastTierEviction() {
+	tl := suite.newTiers(0.1)
+	for _, name := range []string{"a", "b", "c"} {
+		suite.createFile(name, 40*1024)
+		suite.assert.True(tl.demote(name, filepath.Join(suite.dir, "tmp", name)))
+	}
+
+	// Oldest files are removed once the last tier is full
+	suite.assert.NoFileExists(suite.tierPath(1, "a"))
+	suite.assert.False(tl.contains("a"))
+	suite.assert.True(tl.contains("c"))
+}
+
+func TestCacheTier(t *testing.T) {
+	suite.Run(t, new(cacheTierTestSuite))
+}
@@ -0,0 +1,258 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/component/xload"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+// ReadWarmManifest : Read the paths and glob patterns to be warmed up from a manifest file.
+// Each line holds one entry relative to the mount root, empty lines and lines starting with '#' are skipped.
+func ReadWarmManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+
+	return entries, scanner.Err()
+}
+
+// warmCache : Download the files listed in the warm-up manifest in background
+func (fc *FileCache) warmCache(manifest string) {
+	defer fc.pinWg.Done()
+
+	patterns, err := ReadWarmManifest(manifest)
+	if err != nil {
+		log.Err("FileCache::warmCache : Failed to read manifest %s [%s]", manifest, err.Error())
+		return
+	}
+
+	files := fc.expandWarmManifest(patterns)
+	log.Info("FileCache::warmCache : %d files to be warmed up from manifest %s", len(files), manifest)
+	if len(files) == 0 || fc.pinCtx.Err() != nil {
+		return
+	}
+
+	// Files which do not fit in the cache any more are skipped, files already cached do not add to the usage
+	budget := float64(-1)
+	if fc.warmLimit != 0 {
+		usage, _ := common.GetUsage(fc.tmpPath)
+		budget = fc.warmLimit - usage*MB
+	}
+
+	pool := xload.NewThreadPool(fc.warmParallelism, fc.warmFile)
+	pool.Start()
+	defer pool.Stop()
+
+	responses := make(chan *xload.WorkItem, len(files))
+	scheduled, skipped := 0, 0
+	for _, attr := range files {
+		if fc.pinCtx.Err() != nil {
+			break
+		}
+
+		if budget >= 0 && !fc.policy.IsCached(filepath.Join(fc.tmpPath, attr.Path)) {
+			if float64(attr.Size) > budget {
+				log.Warn("FileCache::warmCache : Skipping %s of size %d, cache size limit reached", attr.Path, attr.Size)
+				skipped++
+				continue
+			}
+			budget -= float64(attr.Size)
+		}
+
+		pool.Schedule(&xload.WorkItem{
+			CompName:        compName,
+			Path:            attr.Path,
+			DataLen:         uint64(attr.Size),
+			ResponseChannel: responses,
+			Download:        true,
+			Ctx:             fc.pinCtx,
+		})
+		scheduled++
+	}
+
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, warmPending, int64(scheduled))
+
+	warmed, failed, bytes := 0, 0, uint64(0)
+	step := max(scheduled/10, 1)
+	for i := 1; i <= scheduled; i++ {
+		item := <-responses
+		if item.Err != nil {
+			failed++
+		} else {
+			warmed++
+			bytes += item.DataLen
+			fileCacheStatsCollector.UpdateStats(stats_manager.Increment, warmedFiles, (int64)(1))
+		}
+		fileCacheStatsCollector.UpdateStats(stats_manager.Replace, warmPending, int64(scheduled-i))
+
+		if i%step == 0 || i == scheduled {
+			log.Info("FileCache::warmCache : Progress %d/%d files, %d MB warmed", i, scheduled, bytes/MB)
+		}
+	}
+
+	log.Info("FileCache::warmCache : Warm up from %s done, %d warmed, %d failed, %d skipped", manifest, warmed, failed, skipped)
+}
+
+// expandWarmManifest : Resolve the manifest entries to the list of files to be warmed up.
+// An entry covers the path it matches and, if that path is a directory, everything under it.
+func (fc *FileCache) expandWarmManifest(patterns []string) []*internal.ObjAttr {
+	matcher := newPinList(patterns)
+	seen := make(map[string]bool)
+	files := make([]*internal.ObjAttr, 0)
+
+	add := func(attr *internal.ObjAttr) {
+		if !seen[attr.Path] {
+			seen[attr.Path] = true
+			files = append(files, attr)
+		}
+	}
+
+	for _, pattern := range matcher.list() {
+		root := pinListRoot(pattern)
+		if root != "" {
+			attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: root})
+			if err != nil {
+				log.Err("FileCache::expandWarmManifest : Failed to get attr of %s [%s]", root, err.Error())
+				continue
+			}
+
+			if !attr.IsDir() {
+				add(attr)
+				continue
+			}
+		}
+
+		fc.listWarmDir(root, matcher, add)
+	}
+
+	return files
+}
+
+// listWarmDir : Recursively collect the files under the given directory matching the manifest
+func (fc *FileCache) listWarmDir(name string, matcher *pinList, add func(*internal.ObjAttr)) {
+	if name != "" {
+		name = name + "/"
+	}
+
+	attrs, err := fc.NextComponent().ReadDir(internal.ReadDirOptions{Name: name})
+	if err != nil {
+		log.Err("FileCache::listWarmDir : Failed to list %s [%s]", name, err.Error())
+		return
+	}
+
+	for _, attr := range attrs {
+		if fc.pinCtx.Err() != nil {
+			return
+		}
+
+		if attr.IsDir() {
+			fc.listWarmDir(attr.Path, matcher, add)
+		} else if matcher.isPinned(attr.Path) {
+			add(attr)
+		}
+	}
+}
+
+// warmFile : Thread pool callback to download one file of the manifest into the cache
+func (fc *FileCache) warmFile(item *xload.WorkItem) (int, error) {
+	if err := item.Ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	handle, err := fc.OpenFile(internal.OpenFileOptions{Name: item.Path, Flags: os.O_RDONLY, Mode: fc.defaultPermission})
+	if err != nil {
+		return 0, err
+	}
+
+	// Large files are cached in ranges, read them through so that every range gets downloaded
+	if fc.rangeMaps.get(item.Path) != nil {
+		err = fc.warmRanges(handle, item)
+	}
+
+	closeErr := fc.CloseFile(internal.CloseFileOptions{Handle: handle})
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int(item.DataLen), nil
+}
+
+// warmRanges : Download all ranges of a file cached in ranges
+func (fc *FileCache) warmRanges(handle *handlemap.Handle, item *xload.WorkItem) error {
+	data := make([]byte, fc.rangeSize)
+	for offset := int64(0); offset < int64(item.DataLen); {
+		if err := item.Ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := fc.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: offset, Data: data})
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if n == 0 {
+			break
+		}
+		offset += int64(n)
+	}
+
+	return nil
+}
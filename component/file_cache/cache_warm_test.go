@@ -0,0 +1,72 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type cacheWarmTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *cacheWarmTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *cacheWarmTestSuite) TestReadManifest() {
+	manifest := filepath.Join(suite.T().TempDir(), "manifest")
+	err := os.WriteFile(manifest, []byte("# comment\n\n  a/b  \nc/*.csv\n\t\n/d\n"), 0777)
+	suite.assert.NoError(err)
+
+	entries, err := ReadWarmManifest(manifest)
+	suite.assert.NoError(err)
+	suite.assert.Equal([]string{"a/b", "c/*.csv", "/d"}, entries)
+}
+
+func (suite *cacheWarmTestSuite) TestReadManifestMissing() {
+	entries, err := ReadWarmManifest(filepath.Join(suite.T().TempDir(), "manifest"))
+	suite.assert.True(os.IsNotExist(err))
+	suite.assert.Nil(entries)
+}
+
+func TestCacheWarmTestSuite(t *testing.T) {
+	suite.Run(t, new(cacheWarmTestSuite))
+}
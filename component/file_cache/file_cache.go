@@ -85,6 +85,37 @@ type FileCache struct {
 
 	lazyWrite    bool
 	fileCloseOpt sync.WaitGroup
+
+	pinList   *pinList
+	pinWg     sync.WaitGroup
+	pinCtx    context.Context
+	pinCancel context.CancelFunc
+
+	partialThreshold int64
+	rangeSize        int64
+	rangeMaps        *rangeMapList
+
+	index *cacheIndex
+
+	uploads        *uploadQueue
+	drainOnUnmount bool
+
+	offline *offlineLog
+
+	tiers *tierList
+
+	warmManifest    string
+	warmParallelism uint32
+	warmLimit       float64
+
+	quotas *quotaTracker
+
+	cipher     *common.CacheCipher
+	cryptLocks *common.LockMap
+
+	dedup *common.DedupStore
+
+	head internal.Component // Head of the pipeline, changes found in storage are sent through it to the kernel
 }
 
 // Structure defining your config parameters
@@ -115,6 +146,36 @@ type FileCacheOptions struct {
 
 	RefreshSec uint32 `config:"refresh-sec" yaml:"refresh-sec,omitempty"`
 	HardLimit  bool   `config:"hard-limit" yaml:"hard-limit,omitempty"`
+
+	Pin []string `config:"pin" yaml:"pin,omitempty"`
+
+	PartialThresholdMB uint64 `config:"partial-threshold-mb" yaml:"partial-threshold-mb,omitempty"`
+	RangeSizeMB        uint64 `config:"range-size-mb" yaml:"range-size-mb,omitempty"`
+
+	IndexFile string `config:"index-file" yaml:"index-file,omitempty"`
+
+	UploadJournal    string `config:"upload-journal" yaml:"upload-journal,omitempty"`
+	UploadRetries    uint32 `config:"upload-retries" yaml:"upload-retries,omitempty"`
+	UploadBackoffSec uint32 `config:"upload-retry-backoff-sec" yaml:"upload-retry-backoff-sec,omitempty"`
+	DrainOnUnmount   bool   `config:"drain-on-unmount" yaml:"drain-on-unmount,omitempty"`
+
+	OfflineLog           string `config:"offline-log" yaml:"offline-log,omitempty"`
+	ReconnectIntervalSec uint32 `config:"reconnect-interval-sec" yaml:"reconnect-interval-sec,omitempty"`
+
+	Tiers []CacheTierOptions `config:"tiers" yaml:"tiers,omitempty"`
+
+	WarmManifest    string `config:"warm-manifest" yaml:"warm-manifest,omitempty"`
+	WarmParallelism uint32 `config:"warm-parallelism" yaml:"warm-parallelism,omitempty"`
+
+	DirQuotas          []DirQuotaOptions  `config:"dir-quotas" yaml:"dir-quotas,omitempty"`
+	UserQuotas         []UserQuotaOptions `config:"user-quotas" yaml:"user-quotas,omitempty"`
+	DefaultDirQuotaMB  float64            `config:"default-dir-quota-mb" yaml:"default-dir-quota-mb,omitempty"`
+	DefaultUserQuotaMB float64            `config:"default-user-quota-mb" yaml:"default-user-quota-mb,omitempty"`
+
+	Encryption    bool   `config:"encryption" yaml:"encryption,omitempty"`
+	EncryptionKey string `config:"encryption-key" yaml:"encryption-key,omitempty"`
+
+	DedupPath string `config:"dedup-path" yaml:"dedup-path,omitempty"`
 }
 
 const (
@@ -124,6 +185,12 @@ const (
 	defaultMinThreshold     = 60
 	defaultFileCacheTimeout = 120
 	defaultCacheUpdateCount = 100
+	defaultRangeSizeMB      = 16
+	defaultUploadRetries    = 5
+	defaultUploadBackoffSec = 1
+	defaultReconnectSec     = 30
+	defaultWarmParallelism  = 8
+	rangeMapKey             = "rangeMap"
 	MB                      = 1024 * 1024
 )
 
@@ -148,6 +215,11 @@ func (c *FileCache) Priority() internal.ComponentPriority {
 	return internal.EComponentPriority.LevelMid()
 }
 
+// SetPipelineHead : Keep the head of the pipeline to let the fuse frontend know of changes found in storage
+func (c *FileCache) SetPipelineHead(head internal.Component) {
+	c.head = head
+}
+
 // Start : Pipeline calls this method to start the component functionality
 //
 //	this shall not block the call otherwise pipeline will not start
@@ -155,7 +227,7 @@ func (c *FileCache) Start(ctx context.Context) error {
 	log.Trace("Starting component : %s", c.Name())
 
 	if c.cleanupOnStart {
-		err := common.TempCacheCleanup(c.tmpPath)
+		err := c.tempCacheCleanup()
 		if err != nil {
 			return fmt.Errorf("error in %s error [fail to cleanup temp cache]", c.Name())
 		}
@@ -165,6 +237,9 @@ func (c *FileCache) Start(ctx context.Context) error {
 		return fmt.Errorf("config error in %s error [cache policy missing]", c.Name())
 	}
 
+	// Files in slower tiers are not tracked across mounts
+	c.tiers.cleanup()
+
 	err := c.policy.StartPolicy()
 	if err != nil {
 		return fmt.Errorf("config error in %s error [fail to start policy]", c.Name())
@@ -173,6 +248,41 @@ func (c *FileCache) Start(ctx context.Context) error {
 	// create stats collector for file cache
 	fileCacheStatsCollector = stats_manager.NewStatsCollector(c.Name())
 
+	// Register the files retained from last mount, these are validated against storage on first open
+	if c.index != nil {
+		err = c.index.load()
+		if err != nil {
+			log.Err("FileCache::Start : failed to load index %s [%s]", c.index.path, err.Error())
+		}
+
+		names := c.index.restore(c.tmpPath, c.retained)
+		for _, name := range names {
+			c.policy.CacheValid(filepath.Join(c.tmpPath, name))
+			c.chargeRetained(name)
+		}
+		log.Info("FileCache::Start : %d files restored from index %s", len(names), c.index.path)
+	}
+
+	// Resume the uploads and offline operations left pending by last mount
+	for _, name := range append(c.uploads.list(), c.offline.list()...) {
+		c.policy.CacheValid(filepath.Join(c.tmpPath, name))
+		c.chargeRetained(name)
+	}
+	c.uploads.start()
+	c.offline.start()
+
+	// Pre-download pinned files in background so that mount is not blocked
+	c.pinCtx, c.pinCancel = context.WithCancel(context.Background())
+	if !c.pinList.empty() {
+		c.pinWg.Add(1)
+		go c.downloadPinned(c.pinList.list())
+	}
+
+	if c.warmManifest != "" {
+		c.pinWg.Add(1)
+		go c.warmCache(c.warmManifest)
+	}
+
 	return nil
 }
 
@@ -180,14 +290,49 @@ func (c *FileCache) Start(ctx context.Context) error {
 func (c *FileCache) Stop() error {
 	log.Trace("Stopping component : %s", c.Name())
 
+	// Stop any pre-download of pinned files in progress
+	if c.pinCancel != nil {
+		c.pinCancel()
+		c.pinWg.Wait()
+	}
+
 	// Wait for all async upload to complete if any
 	if c.lazyWrite {
 		log.Info("FileCache::Stop : Waiting for async close to complete")
 		c.fileCloseOpt.Wait()
 	}
 
+	if c.drainOnUnmount {
+		c.uploads.drain()
+	}
+	c.uploads.stop()
+	c.offline.stop()
+
 	_ = c.policy.ShutdownPolicy()
-	_ = common.TempCacheCleanup(c.tmpPath)
+
+	if c.index != nil {
+		// Partially cached files can not be retained as their range maps are not persisted
+		c.rangeMaps.files.Range(func(key, _ any) bool {
+			c.index.remove(key.(string))
+			return true
+		})
+
+		err := c.index.save()
+		if err == nil {
+			log.Info("FileCache::Stop : Cache retained with index %s", c.index.path)
+		} else {
+			log.Err("FileCache::Stop : failed to save index %s [%s]", c.index.path, err.Error())
+			_ = c.tempCacheCleanup()
+		}
+	} else {
+		_ = c.tempCacheCleanup()
+	}
+	c.tiers.cleanup()
+
+	// Content linked only from the files removed above is of no use anymore
+	if _, err := c.dedup.Prune(); err != nil {
+		log.Err("FileCache::Stop : failed to prune dedup store [%s]", err.Error())
+	}
 
 	fileCacheStatsCollector.Destroy()
 
@@ -231,6 +376,12 @@ func (c *FileCache) Configure(_ bool) error {
 		return fmt.Errorf("config error in %s [%s]", c.Name(), err.Error())
 	}
 
+	err = applyTopTier(&conf)
+	if err != nil {
+		log.Err("FileCache: config error [%s]", err.Error())
+		return fmt.Errorf("config error in %s error [%s]", c.Name(), err.Error())
+	}
+
 	c.createEmptyFile = conf.CreateEmptyFile
 	if config.IsSet(compName + ".file-cache-timeout-in-seconds") {
 		c.cacheTimeout = float64(conf.V1Timeout)
@@ -283,6 +434,12 @@ func (c *FileCache) Configure(_ bool) error {
 		return fmt.Errorf("config error in %s error [tmp-path is same as mount path]", c.Name())
 	}
 
+	err = c.configureEncryption(conf)
+	if err != nil {
+		log.Err("FileCache: config error [%s]", err.Error())
+		return fmt.Errorf("config error in %s error [%s]", c.Name(), err.Error())
+	}
+
 	// Extract values from 'conf' and store them as you wish here
 	_, err = os.Stat(c.tmpPath)
 	if os.IsNotExist(err) {
@@ -303,11 +460,71 @@ func (c *FileCache) Configure(_ bool) error {
 		c.maxCacheSize = (0.8 * float64(stat.Bavail) * float64(stat.Bsize)) / (MB)
 	}
 
-	if config.IsSet(compName+".max-size-mb") && conf.MaxSizeMB != 0 {
+	if (config.IsSet(compName+".max-size-mb") || len(conf.Tiers) > 0) && conf.MaxSizeMB != 0 {
 		c.maxCacheSize = conf.MaxSizeMB
 	}
 
-	if !isLocalDirEmpty(c.tmpPath) && !c.allowNonEmpty {
+	c.tiers = nil
+	if len(conf.Tiers) > 1 {
+		c.tiers, err = newTierList(conf.Tiers[1:])
+		if err == nil {
+			seen := []string{c.tmpPath}
+			for _, path := range c.tiers.paths() {
+				for _, other := range seen {
+					if path == other || strings.HasPrefix(path, other+"/") || strings.HasPrefix(other, path+"/") {
+						err = fmt.Errorf("cache tiers shall not overlap with each other or mount path")
+					}
+				}
+				if c.isCachePath(path) || path == c.mountPath {
+					err = fmt.Errorf("cache tiers shall not overlap with each other or mount path")
+				}
+				seen = append(seen, path)
+			}
+		}
+
+		if err != nil {
+			log.Err("FileCache: config error [%s]", err.Error())
+			return fmt.Errorf("config error in %s error [%s]", c.Name(), err.Error())
+		}
+		c.tiers.cipher = c.cipher
+	}
+
+	if conf.IndexFile != "" {
+		indexPath := common.ExpandPath(conf.IndexFile)
+		info, err := os.Stat(indexPath)
+		if (err == nil && info.IsDir()) || c.isCachePath(indexPath) {
+			log.Err("FileCache: config error [index-file shall be a file outside tmp-path and mount path]")
+			return fmt.Errorf("config error in %s error [index-file shall be a file outside tmp-path and mount path]", c.Name())
+		}
+		c.index = newCacheIndex(indexPath)
+		c.index.cipher = c.cipher
+	}
+
+	c.uploads = nil
+	c.drainOnUnmount = conf.DrainOnUnmount
+	if conf.UploadJournal != "" {
+		if !c.lazyWrite {
+			log.Warn("FileCache::Configure : upload-journal is used only with lazy-write, ignoring it")
+		} else {
+			err = c.configureUploadQueue(conf)
+			if err != nil {
+				log.Err("FileCache: config error [%s]", err.Error())
+				return fmt.Errorf("config error in %s error [%s]", c.Name(), err.Error())
+			}
+		}
+	}
+
+	c.offline = nil
+	if conf.OfflineLog != "" {
+		err = c.configureOfflineLog(conf)
+		if err != nil {
+			log.Err("FileCache: config error [%s]", err.Error())
+			return fmt.Errorf("config error in %s error [%s]", c.Name(), err.Error())
+		}
+	}
+
+	// With a persisted index, pending uploads or offline operations, files left in temp directory by last mount are retained
+	if !isLocalDirEmpty(c.tmpPath) && !c.allowNonEmpty && c.index == nil && len(c.uploads.list()) == 0 && len(c.offline.list()) == 0 {
 		log.Err("FileCache: config error %s directory is not empty", c.tmpPath)
 		return fmt.Errorf("config error in %s [%s]", c.Name(), "temp directory not empty")
 	}
@@ -324,6 +541,33 @@ func (c *FileCache) Configure(_ bool) error {
 		c.defaultPermission = common.DefaultFilePermissionBits
 	}
 
+	c.pinList = newPinList(conf.Pin)
+
+	c.partialThreshold = 0
+	if c.cipher == nil {
+		c.partialThreshold = int64(conf.PartialThresholdMB * MB)
+	}
+	c.rangeSize = int64(defaultRangeSizeMB * MB)
+	if config.IsSet(compName+".range-size-mb") && conf.RangeSizeMB != 0 {
+		c.rangeSize = int64(conf.RangeSizeMB * MB)
+	}
+	c.rangeMaps = newRangeMapList()
+
+	c.quotas, err = newQuotaTracker(conf)
+	if err != nil {
+		log.Err("FileCache: config error [%s]", err.Error())
+		return fmt.Errorf("config error in %s error [%s]", c.Name(), err.Error())
+	}
+
+	c.dedup = nil
+	if conf.DedupPath != "" {
+		err = c.configureDedup(common.ExpandPath(conf.DedupPath))
+		if err != nil {
+			log.Err("FileCache: config error [%s]", err.Error())
+			return fmt.Errorf("config error in %s error [%s]", c.Name(), err.Error())
+		}
+	}
+
 	cacheConfig := c.GetPolicyConfig(conf)
 	c.policy = NewLRUPolicy(cacheConfig)
 
@@ -350,8 +594,25 @@ func (c *FileCache) Configure(_ bool) error {
 		c.diskHighWaterMark = (((conf.MaxSizeMB * MB) * float64(cacheConfig.highThreshold)) / 100)
 	}
 
-	log.Crit("FileCache::Configure : create-empty %t, cache-timeout %d, tmp-path %s, max-size-mb %d, high-mark %d, low-mark %d, refresh-sec %v, max-eviction %v, hard-limit %v, policy %s, allow-non-empty-temp %t, cleanup-on-start %t, policy-trace %t, offload-io %t, sync-to-flush %t, ignore-sync %t, defaultPermission %v, diskHighWaterMark %v, maxCacheSize %v, mountPath %v",
-		c.createEmptyFile, int(c.cacheTimeout), c.tmpPath, int(cacheConfig.maxSizeMB), int(cacheConfig.highThreshold), int(cacheConfig.lowThreshold), c.refreshSec, cacheConfig.maxEviction, c.hardLimit, conf.Policy, c.allowNonEmpty, c.cleanupOnStart, c.policyTrace, c.offloadIO, c.syncToFlush, c.syncToDelete, c.defaultPermission, c.diskHighWaterMark, c.maxCacheSize, c.mountPath)
+	c.warmManifest = ""
+	if conf.WarmManifest != "" {
+		c.warmManifest = common.ExpandPath(conf.WarmManifest)
+		if _, err := os.Stat(c.warmManifest); err != nil {
+			log.Err("FileCache: config error [warm-manifest %s not readable]", c.warmManifest)
+			return fmt.Errorf("config error in %s error [warm-manifest %s not readable]", c.Name(), c.warmManifest)
+		}
+	}
+
+	c.warmParallelism = defaultWarmParallelism
+	if config.IsSet(compName+".warm-parallelism") && conf.WarmParallelism != 0 {
+		c.warmParallelism = conf.WarmParallelism
+	}
+
+	// Warm up stops filling the cache at the high threshold so that it does not trigger eviction
+	c.warmLimit = ((conf.MaxSizeMB * MB) * float64(cacheConfig.highThreshold)) / 100
+
+	log.Crit("FileCache::Configure : create-empty %t, cache-timeout %d, tmp-path %s, max-size-mb %d, high-mark %d, low-mark %d, refresh-sec %v, max-eviction %v, hard-limit %v, policy %s, allow-non-empty-temp %t, cleanup-on-start %t, policy-trace %t, offload-io %t, sync-to-flush %t, ignore-sync %t, defaultPermission %v, diskHighWaterMark %v, maxCacheSize %v, mountPath %v, pin %v, partial-threshold-mb %v, range-size-mb %v, index-file %v, upload-journal %v, drain-on-unmount %v, offline-log %v, tiers %v, warm-manifest %v, warm-parallelism %v, dir-quotas %v, user-quotas %v, default-dir-quota-mb %v, default-user-quota-mb %v, encryption %v, dedup-path %v",
+		c.createEmptyFile, int(c.cacheTimeout), c.tmpPath, int(cacheConfig.maxSizeMB), int(cacheConfig.highThreshold), int(cacheConfig.lowThreshold), c.refreshSec, cacheConfig.maxEviction, c.hardLimit, conf.Policy, c.allowNonEmpty, c.cleanupOnStart, c.policyTrace, c.offloadIO, c.syncToFlush, c.syncToDelete, c.defaultPermission, c.diskHighWaterMark, c.maxCacheSize, c.mountPath, c.pinList.list(), conf.PartialThresholdMB, c.rangeSize/MB, conf.IndexFile, conf.UploadJournal, c.drainOnUnmount, conf.OfflineLog, c.tiers.paths(), c.warmManifest, c.warmParallelism, conf.DirQuotas, conf.UserQuotas, conf.DefaultDirQuotaMB, conf.DefaultUserQuotaMB, c.cipher != nil, conf.DedupPath)
 
 	return nil
 }
@@ -366,6 +627,11 @@ func (c *FileCache) OnConfigChange() {
 		log.Err("FileCache: config error [invalid config attributes]")
 	}
 
+	err = applyTopTier(&conf)
+	if err != nil {
+		log.Err("FileCache::OnConfigChange : config error [%s]", err.Error())
+	}
+
 	c.createEmptyFile = conf.CreateEmptyFile
 	c.cacheTimeout = float64(conf.Timeout)
 	c.policyTrace = conf.EnablePolicyTrace
@@ -374,6 +640,22 @@ func (c *FileCache) OnConfigChange() {
 	c.syncToFlush = conf.SyncToFlush
 	c.syncToDelete = !conf.SyncNoOp
 	_ = c.policy.UpdateConfig(c.GetPolicyConfig(conf))
+
+	// Pin list can be changed at runtime, only the newly pinned paths are downloaded
+	pins := newPinList(conf.Pin).list()
+	pinned := make(map[string]bool, len(pins))
+	for _, pattern := range pins {
+		pinned[pattern] = true
+	}
+	for _, pattern := range c.pinList.list() {
+		if !pinned[pattern] {
+			_ = c.Unpin(pattern)
+		}
+	}
+	for _, pattern := range pins {
+		// Patterns pinned already are left as they are
+		_ = c.Pin(pattern)
+	}
 }
 
 func (c *FileCache) StatFs() (*syscall.Statfs_t, bool, error) {
@@ -389,6 +671,11 @@ func (c *FileCache) StatFs() (*syscall.Statfs_t, bool, error) {
 	usage, _ := common.GetUsage(c.tmpPath)
 	usage = usage * MB
 
+	// Capacity of the slower tiers adds to the cache
+	tierSize, tierUsage := c.tiers.capacity()
+	maxCacheSize += float64(tierSize)
+	usage += float64(tierUsage)
+
 	available := maxCacheSize - usage
 	statfs := &syscall.Statfs_t{}
 	err := syscall.Statfs("/", statfs)
@@ -424,6 +711,14 @@ func (c *FileCache) GetPolicyConfig(conf FileCacheOptions) cachePolicyConfig {
 		maxSizeMB:     conf.MaxSizeMB,
 		fileLocks:     c.fileLocks,
 		policyTrace:   conf.EnablePolicyTrace,
+		pinList:       c.pinList,
+		rangeMaps:     c.rangeMaps,
+		index:         c.index,
+		uploads:       c.uploads,
+		offline:       c.offline,
+		tiers:         c.tiers,
+		quotas:        c.quotas,
+		dedup:         c.dedup,
 	}
 
 	return cacheConfig
@@ -523,11 +818,23 @@ func (fc *FileCache) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAt
 	// 3. Path in storage and in local cache (this could result in dirty properties on the service if we recently wrote to the file)
 
 	// To cover case 1, grab all entries from storage
-	attrs, err := fc.NextComponent().ReadDir(options)
-	if err != nil {
+	var attrs []*internal.ObjAttr
+	var err error = syscall.ENOTCONN
+	if !fc.offline.isDisconnected() {
+		attrs, err = fc.NextComponent().ReadDir(options)
+	}
+
+	if err == nil {
+		fc.offline.observe(attrs...)
+	} else if fc.offline.failed(err) {
+		// Storage is not reachable, list the last known state of the directory
+		log.Info("FileCache::ReadDir : serving %s from last known state [%s]", options.Name, err.Error())
+		attrs = fc.offline.listing(options.Name)
+	} else {
 		log.Err("FileCache::ReadDir : error fetching storage attributes [%s]", err.Error())
 		// TODO : Should we return here if the directory failed to be read from storage?
 	}
+	attrs = fc.offline.merge(options.Name, attrs, true)
 
 	// Create a mapping from path to index in the storage attributes array, so we can handle case 3 (conflicting attributes)
 	var pathToIndex = make(map[string]int)
@@ -558,12 +865,15 @@ func (fc *FileCache) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAt
 					// If file is under download then taking size or mod time from it will be incorrect.
 					if !fc.fileLocks.Locked(entryPath) {
 						log.Debug("FileCache::ReadDir : updating %s from local cache", entryPath)
-						attrs[idx].Size = info.Size()
-						attrs[idx].Mtime = info.ModTime()
+						attrs[idx].Size = fc.localSize(info)
+						if !fc.shared(info) {
+							attrs[idx].Mtime = info.ModTime()
+						}
 					}
 				} else if !fc.createEmptyFile { // Case 2 (file only in local cache) so create a new attributes and add them to the storage attributes
 					log.Debug("FileCache::ReadDir : serving %s from local cache", entryPath)
 					attr := newObjAttr(entryPath, info)
+					attr.Size = fc.localSize(info)
 					attrs = append(attrs, attr)
 					pathToIndex[attr.Path] = len(attrs) - 1 // append adds to the end of an array
 				}
@@ -578,7 +888,19 @@ func (fc *FileCache) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAt
 
 // StreamDir : Add local files to the list retrieved from storage container
 func (fc *FileCache) StreamDir(options internal.StreamDirOptions) ([]*internal.ObjAttr, string, error) {
+	if fc.offline.isDisconnected() {
+		// Storage is not reachable, complete listing is built from the last known state in one go
+		attrs, err := fc.ReadDir(internal.ReadDirOptions{Name: options.Name})
+		return attrs, "", err
+	}
+
 	attrs, token, err := fc.NextComponent().StreamDir(options)
+	if fc.offline.failed(err) {
+		attrs, err := fc.ReadDir(internal.ReadDirOptions{Name: options.Name})
+		return attrs, "", err
+	}
+	fc.offline.observe(attrs...)
+	attrs = fc.offline.merge(options.Name, attrs, token == "")
 
 	if token == "" {
 		// This is the last set of objects retrieved from container so we need to add local files here
@@ -596,6 +918,11 @@ func (fc *FileCache) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 				if err == nil && !info.IsDir() &&
 					!fc.fileLocks.Locked(entryPath) {
 
+					// Path changed by a pending offline operation is already part of the list
+					if _, found := fc.offline.pendingState(entryPath); found {
+						continue
+					}
+
 					// This is an overhead for streamdir for now
 					// As list is paginated we have no way to know whether this particular item exists both in local cache
 					// and container or not. So we rely on getAttr to tell if entry was cached then it exists in storage too
@@ -604,6 +931,7 @@ func (fc *FileCache) StreamDir(options internal.StreamDirOptions) ([]*internal.O
 					if err != nil && (err == syscall.ENOENT || os.IsNotExist(err)) {
 						log.Debug("FileCache::StreamDir : serving %s from local cache", entryPath)
 						attr := newObjAttr(entryPath, info)
+						attr.Size = fc.localSize(info)
 						attrs = append(attrs, attr)
 					}
 				}
@@ -706,12 +1034,28 @@ func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 	flock.Lock()
 	defer flock.Unlock()
 
+	err := fc.quotas.admit(options.Name, options.Uid, 0)
+	if err != nil {
+		log.Err("FileCache::CreateFile : quota exceeded for %s by uid %d", options.Name, options.Uid)
+		return nil, err
+	}
+
 	// createEmptyFile was added to optionally support immutable containers. If customers do not care about immutability they can set this to true.
 	if fc.createEmptyFile {
 		// We tried moving CreateFile to a separate thread for better perf.
 		// However, before it is created in storage, if GetAttr is called, the call will fail since the file
 		// does not exist in storage yet, failing the whole CreateFile sequence in FUSE.
-		_, err := fc.NextComponent().CreateFile(options)
+		var err error = nil
+		offline := fc.offline.bypass()
+		if !offline {
+			_, err = fc.NextComponent().CreateFile(options)
+			offline = fc.offline.failed(err)
+		}
+
+		if offline {
+			err = fc.offline.record(offlineOpCreate, options.Name, "")
+		}
+
 		if err != nil {
 			log.Err("FileCache::CreateFile : Failed to create file %s", options.Name)
 			return nil, err
@@ -722,7 +1066,7 @@ func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 	localPath := filepath.Join(fc.tmpPath, options.Name)
 	fc.policy.CacheValid(localPath)
 
-	err := os.MkdirAll(filepath.Dir(localPath), fc.defaultPermission)
+	err = os.MkdirAll(filepath.Dir(localPath), fc.defaultPermission)
 	if err != nil {
 		log.Err("FileCache::CreateFile : unable to create local directory %s [%s]", options.Name, err.Error())
 		return nil, err
@@ -734,6 +1078,16 @@ func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 		log.Err("FileCache::CreateFile : error opening local file %s [%s]", options.Name, err.Error())
 		return nil, err
 	}
+
+	// Local file is truncated so whatever was cached for this name earlier is gone
+	fc.rangeMaps.remove(options.Name)
+	fc.index.remove(options.Name)
+	fc.tiers.remove(options.Name)
+
+	// New file is charged to the user creating it
+	fc.quotas.release(options.Name)
+	fc.quotas.charge(options.Name, options.Uid, 0, 0)
+
 	// The user might change permissions WHILE creating the file therefore we need to account for that
 	if options.Mode != common.DefaultFilePermissionBits {
 		fc.missedChmodList.LoadOrStore(options.Name, true)
@@ -745,7 +1099,8 @@ func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 	handle := handlemap.NewHandle(options.Name)
 	handle.UnixFD = uint64(f.Fd())
 
-	if !fc.offloadIO {
+	// Encrypted files can not be read directly by libfuse
+	if !fc.offloadIO && fc.cipher == nil {
 		handle.Flags.Set(handlemap.HandleFlagCached)
 	}
 	log.Info("FileCache::CreateFile : file=%s, fd=%d", options.Name, f.Fd())
@@ -804,8 +1159,20 @@ func (fc *FileCache) DeleteFile(options internal.DeleteFileOptions) error {
 	flock.Lock()
 	defer flock.Unlock()
 
-	err := fc.NextComponent().DeleteFile(options)
-	err = fc.validateStorageError(options.Name, err, "DeleteFile", false)
+	var err error = nil
+	offline := fc.offline.bypass()
+	if !offline {
+		err = fc.NextComponent().DeleteFile(options)
+		offline = fc.offline.failed(err)
+	}
+
+	if offline {
+		err = fc.recordOffline(offlineOpDelete, options.Name, "")
+	} else {
+		err = fc.validateStorageError(options.Name, err, "DeleteFile", false)
+		fc.offline.forget(options.Name)
+	}
+
 	if err != nil {
 		log.Err("FileCache::DeleteFile : error  %s [%s]", options.Name, err.Error())
 		return err
@@ -816,12 +1183,29 @@ func (fc *FileCache) DeleteFile(options internal.DeleteFileOptions) error {
 	if err != nil && !os.IsNotExist(err) {
 		log.Err("FileCache::DeleteFile : failed to delete local file %s [%s]", localPath, err.Error())
 	}
+	fc.rangeMaps.remove(options.Name)
+	fc.index.remove(options.Name)
+	fc.uploads.remove(options.Name)
+	fc.tiers.remove(options.Name)
+	fc.quotas.release(options.Name)
 
 	fc.policy.CachePurge(localPath)
 
 	return nil
 }
 
+// notifyKernel : Let the fuse frontend drop what kernel has cached for a file found changed in storage
+func (fc *FileCache) notifyKernel(name string) {
+	if fc.head == nil {
+		return
+	}
+
+	err := fc.head.InvalidatePath(internal.InvalidatePathOptions{Name: name, KernelOnly: true})
+	if err != nil {
+		log.Warn("FileCache::notifyKernel : Failed to invalidate %s [%s]", name, err.Error())
+	}
+}
+
 // isDownloadRequired: Whether or not the file needs to be downloaded to local cache.
 func (fc *FileCache) isDownloadRequired(localPath string, blobPath string, flock *common.LockMapItem) (bool, bool, *internal.ObjAttr, error) {
 	fileExists := false
@@ -850,6 +1234,11 @@ func (fc *FileCache) isDownloadRequired(localPath string, blobPath string, flock
 		// and hence last change time on local disk will then represent the download time.
 
 		lmt = finfo.ModTime()
+		if fc.shared(finfo) {
+			// Modified time of a file linked from dedup store belongs to the file first downloaded with this content,
+			// time it was linked is when this file was downloaded
+			lmt = time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec)
+		}
 		if time.Since(finfo.ModTime()).Seconds() > fc.cacheTimeout &&
 			time.Since(time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec)).Seconds() > fc.cacheTimeout {
 			log.Debug("FileCache::isDownloadRequired : %s not valid as per time checks", localPath)
@@ -872,11 +1261,28 @@ func (fc *FileCache) isDownloadRequired(localPath string, blobPath string, flock
 		downloadRequired = false
 	}
 
+	if fileExists && fc.index.isRestored(blobPath) {
+		// File was retained from last mount, use it only if it has not changed in storage since then
+		downloadRequired = !fc.revalidate(localPath, blobPath)
+	}
+
+	if fileExists && fc.offline.pending(blobPath) {
+		// Local copy holds changes which are not replayed to storage yet
+		return false, fileExists, nil, nil
+	}
+
 	err = nil // reset err variable
 	var attr *internal.ObjAttr = nil
 	if downloadRequired ||
 		(fc.refreshSec != 0 && time.Since(flock.DownloadTime()).Seconds() > float64(fc.refreshSec)) {
-		attr, err = fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: blobPath})
+		if fc.offline.isDisconnected() {
+			err = syscall.ENOTCONN
+		} else {
+			attr, err = fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: blobPath})
+			if err == nil {
+				fc.offline.observe(attr)
+			}
+		}
 		if err != nil {
 			log.Err("FileCache::isDownloadRequired : Failed to get attr of %s [%s]", blobPath, err.Error())
 		}
@@ -893,6 +1299,9 @@ func (fc *FileCache) isDownloadRequired(localPath string, blobPath string, flock
 				blobPath, attr.Mtime, lmt, attr.Size, stat.Size)
 			downloadRequired = true
 
+			// Kernel may still hold pages of the old content of this file
+			fc.notifyKernel(blobPath)
+
 			// As we have decided to continue using old file, we reset the timer to check again after refresh time interval
 			flock.SetDownloadTime()
 		} else {
@@ -903,126 +1312,530 @@ func (fc *FileCache) isDownloadRequired(localPath string, blobPath string, flock
 	return downloadRequired, fileExists, attr, err
 }
 
-// OpenFile: Makes the file available in the local cache for further file operations.
-func (fc *FileCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
-	log.Trace("FileCache::OpenFile : name=%s, flags=%d, mode=%s", options.Name, options.Flags, options.Mode)
+// configureUploadQueue: Create the durable upload queue and resume uploads left pending by last mount
+func (c *FileCache) configureUploadQueue(conf FileCacheOptions) error {
+	journalPath := common.ExpandPath(conf.UploadJournal)
+	if c.isCachePath(journalPath) {
+		return fmt.Errorf("upload-journal shall be a file outside tmp-path and mount path")
+	}
+
+	retries := uint32(defaultUploadRetries)
+	if config.IsSet(compName + ".upload-retries") {
+		retries = conf.UploadRetries
+	}
+
+	backoff := uint32(defaultUploadBackoffSec)
+	if config.IsSet(compName+".upload-retry-backoff-sec") && conf.UploadBackoffSec != 0 {
+		backoff = conf.UploadBackoffSec
+	}
 
-	localPath := filepath.Join(fc.tmpPath, options.Name)
-	var f *os.File
 	var err error
+	c.uploads, err = newUploadQueue(journalPath, retries, time.Duration(backoff)*time.Second, c.retryUpload)
+	return err
+}
 
-	flock := fc.fileLocks.Get(options.Name)
-	flock.Lock()
-	defer flock.Unlock()
+// configureOfflineLog: Create the log of operations done while storage is not reachable and replay the ones left by last mount
+func (c *FileCache) configureOfflineLog(conf FileCacheOptions) error {
+	logPath := common.ExpandPath(conf.OfflineLog)
+	if c.isCachePath(logPath) {
+		return fmt.Errorf("offline-log shall be a file outside tmp-path and mount path")
+	}
 
-	fc.policy.CacheValid(localPath)
-	downloadRequired, fileExists, attr, err := fc.isDownloadRequired(localPath, options.Name, flock)
+	interval := uint32(defaultReconnectSec)
+	if config.IsSet(compName+".reconnect-interval-sec") && conf.ReconnectIntervalSec != 0 {
+		interval = conf.ReconnectIntervalSec
+	}
 
-	// return err in case of authorization permission mismatch
-	if err != nil && err == syscall.EACCES {
-		return nil, err
+	var err error
+	c.offline, err = newOfflineLog(logPath, time.Duration(interval)*time.Second, c.replayOffline)
+	return err
+}
+
+// chargeRetained: Charge a file retained from last mount to the quota of its directory
+func (c *FileCache) chargeRetained(name string) {
+	info, err := os.Stat(filepath.Join(c.tmpPath, name))
+	if err == nil {
+		c.quotas.resize(name, c.chargedSize(info), c.localSize(info))
 	}
+}
 
-	if downloadRequired {
-		log.Debug("FileCache::OpenFile : Need to re-download %s", options.Name)
+// isCachePath: Whether the path is inside the temp directory or the mount path
+func (c *FileCache) isCachePath(path string) bool {
+	return strings.HasPrefix(path, c.tmpPath+"/") || (c.mountPath != "" && strings.HasPrefix(path, c.mountPath+"/"))
+}
 
-		fileSize := int64(0)
-		if attr != nil {
-			fileSize = int64(attr.Size)
+// recordOffline: Log an operation on an existing file while storage is not reachable
+func (fc *FileCache) recordOffline(op string, name string, dst string) error {
+	_, err := os.Stat(filepath.Join(fc.tmpPath, name))
+	if err != nil {
+		if attr, _ := fc.offline.get(name); attr == nil {
+			return syscall.ENOENT
 		}
+	}
 
-		if fileExists {
-			log.Debug("FileCache::OpenFile : Delete cached file %s", options.Name)
+	return fc.offline.record(op, name, dst)
+}
 
-			err := deleteFile(localPath)
-			if err != nil && !os.IsNotExist(err) {
-				log.Err("FileCache::OpenFile : Failed to delete old file %s", options.Name)
-			}
-		} else {
-			// Create the file if if doesn't already exist.
-			err := os.MkdirAll(filepath.Dir(localPath), fc.defaultPermission)
-			if err != nil {
-				log.Err("FileCache::OpenFile : error creating directory structure for file %s [%s]", options.Name, err.Error())
-				return nil, err
-			}
-		}
+// replayOffline: Replay the operations done while storage was not reachable, in the order these were done.
+// An operation is replayed only if the path in storage is in the same state as when the operation was done,
+// otherwise the change in storage is retained and data written locally is uploaded under a conflict name.
+func (fc *FileCache) replayOffline() error {
+	ops := fc.offline.pendingOps()
+	if len(ops) == 0 {
+		return nil
+	}
 
-		// Open the file in write mode.
-		f, err = os.OpenFile(localPath, os.O_CREATE|os.O_RDWR, options.Mode)
-		if err != nil {
-			log.Err("FileCache::OpenFile : error creating new file %s [%s]", options.Name, err.Error())
-			return nil, err
+	log.Info("FileCache::replayOffline : Replaying %d operations", len(ops))
+	conflicts := fc.offline.conflictList()
+
+	for _, op := range ops {
+		if fc.offline.conflicted(op.Name, op.Dst) {
+			log.Warn("FileCache::replayOffline : Skipping %s of %s as an earlier operation on it conflicted", op.Op, op.Name)
+			fc.offline.replayed(op, op.Name, remoteState{}, true)
+			continue
 		}
 
-		if options.Flags&os.O_TRUNC != 0 {
-			fileSize = 0
+		cur, err := fc.remoteState(op.Name)
+		if err != nil {
+			return err
 		}
 
-		if fileSize > 0 {
-			if fc.diskHighWaterMark != 0 {
-				currSize, err := common.GetUsage(fc.tmpPath)
-				if err != nil {
-					log.Err("FileCache::OpenFile : error getting current usage of cache [%s]", err.Error())
-				} else {
-					if (currSize + float64(fileSize)) > fc.diskHighWaterMark {
-						log.Err("FileCache::OpenFile : cache size limit reached [%f] failed to open %s", fc.maxCacheSize, options.Name)
-						return nil, syscall.ENOSPC
-					}
+		base := fc.offline.baseState(op)
+		if !base.matches(cur) && (op.Op != offlineOpDelete || cur.exists) {
+			log.Err("FileCache::replayOffline : %s of %s conflicts with a change in storage", op.Op, op.Name)
+			if op.Op == offlineOpWrite {
+				name := fmt.Sprintf("%s.conflict-%s", op.Name, time.Now().Format("20060102T150405"))
+				err = fc.replayWrite(op.Name, name)
+				if isConnectivityError(err) {
+					return err
 				}
-
-			}
-			// Download/Copy the file from storage to the local file.
-			err = fc.NextComponent().CopyToFile(
-				internal.CopyToFileOptions{
-					Name:   options.Name,
-					Offset: 0,
-					Count:  fileSize,
-					File:   f,
-				})
-			if err != nil {
-				// File was created locally and now download has failed so we need to delete it back from local cache
-				log.Err("FileCache::OpenFile : error downloading file from storage %s [%s]", options.Name, err.Error())
-				_ = f.Close()
-				_ = os.Remove(localPath)
-				return nil, err
+				log.Warn("FileCache::replayOffline : Local data of %s saved as %s", op.Name, name)
 			}
+
+			fc.offline.replayed(op, op.Name, cur, true)
+			conflicts = append(conflicts, op.Name, op.Dst)
+			continue
 		}
 
-		// Update the last download time of this file
-		flock.SetDownloadTime()
+		target := op.Name
+		switch op.Op {
+		case offlineOpCreate:
+			_, err = fc.NextComponent().CreateFile(internal.CreateFileOptions{Name: op.Name, Mode: fc.defaultPermission})
+		case offlineOpWrite:
+			err = fc.replayWrite(op.Name, op.Name)
+		case offlineOpRename:
+			err = fc.NextComponent().RenameFile(internal.RenameFileOptions{Src: op.Name, Dst: op.Dst})
+			target = op.Dst
+		case offlineOpDelete:
+			if cur.exists {
+				err = fc.NextComponent().DeleteFile(internal.DeleteFileOptions{Name: op.Name})
+			}
+		}
 
-		log.Debug("FileCache::OpenFile : Download of %s is complete", options.Name)
-		f.Close()
+		if err != nil {
+			if isConnectivityError(err) {
+				return err
+			}
 
-		// After downloading the file, update the modified times and mode of the file.
-		fileMode := fc.defaultPermission
-		if attr != nil && !attr.IsModeDefault() {
-			fileMode = attr.Mode
+			// Storage rejected the operation so retrying it will not help
+			log.Err("FileCache::replayOffline : %s of %s failed [%s]", op.Op, op.Name, err.Error())
+			fc.offline.replayed(op, op.Name, cur, true)
+			conflicts = append(conflicts, op.Name, op.Dst)
+			continue
 		}
 
-		// If user has selected some non default mode in config then every local file shall be created with that mode only
-		err = os.Chmod(localPath, fileMode)
+		state, err := fc.remoteState(target)
 		if err != nil {
-			log.Err("FileCache::OpenFile : Failed to change mode of file %s [%s]", options.Name, err.Error())
+			// Operation is done, so state is known except for the ETag
+			state = remoteState{exists: op.Op != offlineOpDelete}
 		}
-		// TODO: When chown is supported should we update that?
+		fc.offline.replayed(op, target, state, false)
+	}
 
-		if attr != nil {
-			// chtimes shall be the last api otherwise calling chmod/chown will update the last change time
-			err = os.Chtimes(localPath, attr.Atime, attr.Mtime)
-			if err != nil {
-				log.Err("FileCache::OpenFile : Failed to change times of file %s [%s]", options.Name, err.Error())
-			}
+	// Local copies of the conflicted paths do not match storage, remove them so that next open downloads them again
+	for _, name := range conflicts {
+		if name != "" {
+			fc.dropLocal(name)
 		}
-
-		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, dlFiles, (int64)(1))
-	} else {
-		log.Debug("FileCache::OpenFile : %s will be served from cache", options.Name)
-		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, cacheServed, (int64)(1))
 	}
 
-	// Open the file and grab a shared lock to prevent deletion by the cache policy.
-	f, err = os.OpenFile(localPath, options.Flags, options.Mode)
+	log.Info("FileCache::replayOffline : Replay of %d operations complete", len(ops))
+	return nil
+}
+
+// remoteState: Whether the path exists in storage along with its ETag
+func (fc *FileCache) remoteState(name string) (remoteState, error) {
+	attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		if err == syscall.ENOENT || os.IsNotExist(err) {
+			return remoteState{}, nil
+		}
+		return remoteState{}, err
+	}
+
+	fc.offline.observe(attr)
+	return remoteState{exists: true, etag: attr.ETag}, nil
+}
+
+// followPath: Local path holding the data of the file after the given operations, empty if the data is gone
+func followPath(ops []*offlineOp, name string) string {
+	for _, op := range ops {
+		if op.Op == offlineOpRename && op.Dst == name {
+			return ""
+		}
+
+		if op.Name == name {
+			switch op.Op {
+			case offlineOpDelete:
+				return ""
+			case offlineOpRename:
+				name = op.Dst
+			}
+		}
+	}
+	return name
+}
+
+// replayWrite: Upload the data written to a file, which is under replay, to storage under the given name
+func (fc *FileCache) replayWrite(src string, name string) error {
+	for attempt := 0; ; attempt++ {
+		// Data is at the path where later operations have moved the file, these may be logged while replay is on
+		ops := fc.offline.pendingOps()
+		if len(ops) > 0 {
+			ops = ops[1:]
+		}
+
+		localName := followPath(ops, src)
+		if localName == "" {
+			log.Info("FileCache::replayWrite : Data of %s was removed by a later operation", src)
+			return nil
+		}
+
+		flock := fc.fileLocks.Get(localName)
+		flock.Lock()
+
+		f, err := os.Open(filepath.Join(fc.tmpPath, localName))
+		if err != nil {
+			flock.Unlock()
+			if os.IsNotExist(err) && fc.offline.pending(localName) && attempt < 3 {
+				// File was renamed after its path was resolved
+				continue
+			}
+			log.Err("FileCache::replayWrite : Failed to open %s [%s]", localName, err.Error())
+			return nil
+		}
+
+		err = fc.uploadLocal(name, f)
+		_ = f.Close()
+		flock.Unlock()
+		return err
+	}
+}
+
+// dropLocal: Remove the local copy of a file unless it is in use
+func (fc *FileCache) dropLocal(name string) {
+	flock := fc.fileLocks.Get(name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	if flock.Count() > 0 || fc.retained(name) {
+		return
+	}
+
+	localPath := filepath.Join(fc.tmpPath, name)
+	err := deleteFile(localPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("FileCache::dropLocal : failed to delete local file %s [%s]", localPath, err.Error())
+	}
+	fc.rangeMaps.remove(name)
+	fc.index.remove(name)
+	fc.tiers.remove(name)
+	fc.quotas.release(name)
+	fc.policy.CachePurge(localPath)
+}
+
+// retryUpload: Upload a file from local cache for which an earlier upload has failed
+func (fc *FileCache) retryUpload(name string) error {
+	flock := fc.fileLocks.Get(name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	localPath := filepath.Join(fc.tmpPath, name)
+	f, err := os.Open(localPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Warn("FileCache::retryUpload : %s no longer exists in local cache", name)
+			return nil
+		}
+		return err
+	}
+
+	handle := handlemap.NewHandle(name)
+	handle.SetFileObject(f)
+	handle.Flags.Set(handlemap.HandleFlagDirty)
+	if rm := fc.rangeMaps.get(name); rm != nil {
+		handle.SetValue(rangeMapKey, rm)
+	}
+
+	err = fc.FlushFile(internal.FlushFileOptions{Handle: handle, CloseInProgress: true})
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	// File is in sync with storage now so it can follow the regular eviction
+	if flock.Count() == 0 {
+		fc.policy.CacheInvalidate(localPath)
+	}
+	return nil
+}
+
+// retained: Whether the local copy of the file is the latest data which is not in storage yet
+func (fc *FileCache) retained(name string) bool {
+	return fc.uploads.pending(name) || fc.offline.pending(name)
+}
+
+// tempCacheCleanup: Clean up the temp directory, retaining the files with pending uploads or offline operations
+func (fc *FileCache) tempCacheCleanup() error {
+	if len(fc.uploads.list()) == 0 && len(fc.offline.list()) == 0 {
+		return common.TempCacheCleanup(fc.tmpPath)
+	}
+
+	return filepath.WalkDir(fc.tmpPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		if !fc.retained(indexName(strings.TrimPrefix(path, fc.tmpPath))) {
+			_ = os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// revalidate: Validate a file restored from the persisted index against storage
+func (fc *FileCache) revalidate(localPath string, blobPath string) bool {
+	entry, _ := fc.index.get(blobPath)
+
+	attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: blobPath})
+	if err != nil || !entry.matches(attr) {
+		log.Info("FileCache::revalidate : %s changed in storage since it was cached", blobPath)
+		fc.index.remove(blobPath)
+		return false
+	}
+
+	// Reset the change time so that the file is considered freshly downloaded.
+	// Times of a file linked from dedup store are shared with other files and are left as is.
+	if info, err := os.Stat(localPath); err == nil && fc.shared(info) {
+		log.Debug("FileCache::revalidate : %s is valid in local cache", blobPath)
+		return true
+	}
+
+	err = os.Chtimes(localPath, time.Now(), attr.Mtime)
+	if err != nil {
+		log.Err("FileCache::revalidate : Failed to change times of file %s [%s]", blobPath, err.Error())
+	}
+
+	log.Debug("FileCache::revalidate : %s is valid in local cache", blobPath)
+	fc.index.validated(blobPath)
+	return true
+}
+
+// OpenFile: Makes the file available in the local cache for further file operations.
+func (fc *FileCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
+	log.Trace("FileCache::OpenFile : name=%s, flags=%d, mode=%s", options.Name, options.Flags, options.Mode)
+
+	localPath := filepath.Join(fc.tmpPath, options.Name)
+	var f *os.File
+	var err error
+
+	flock := fc.fileLocks.Get(options.Name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	fc.policy.CacheValid(localPath)
+	downloadRequired, fileExists, attr, err := fc.isDownloadRequired(localPath, options.Name, flock)
+
+	// return err in case of authorization permission mismatch
+	if err != nil && err == syscall.EACCES {
+		return nil, err
+	}
+
+	if fc.offline.failed(err) || fc.offline.pending(options.Name) {
+		if !fileExists {
+			log.Err("FileCache::OpenFile : %s is not in local cache while storage is not reachable", options.Name)
+			return nil, syscall.EIO
+		}
+
+		// Serve the local copy till storage is reachable again
+		log.Info("FileCache::OpenFile : %s served from local cache in disconnected mode", options.Name)
+		downloadRequired = false
+	}
+
+	if downloadRequired && !fileExists && options.Flags&os.O_TRUNC == 0 && fc.tiers.promote(options.Name, localPath, attr) {
+		// File was demoted to a slower tier and has not changed in storage since then
+		log.Debug("FileCache::OpenFile : %s promoted from slower tier", options.Name)
+		downloadRequired = false
+		flock.SetDownloadTime()
+	}
+
+	if downloadRequired {
+		log.Debug("FileCache::OpenFile : Need to re-download %s", options.Name)
+
+		linked := false
+
+		fileSize := int64(0)
+		if attr != nil {
+			fileSize = int64(attr.Size)
+		}
+
+		// Space needed for the download is checked once its size is known, files cached in ranges as ranges are downloaded
+		err = fc.quotas.admit(options.Name, options.Uid, 0)
+		if err != nil {
+			log.Err("FileCache::OpenFile : quota exceeded for %s by uid %d", options.Name, options.Uid)
+			return nil, err
+		}
+
+		// Ranges downloaded earlier are no longer valid
+		fc.rangeMaps.remove(options.Name)
+		fc.tiers.remove(options.Name)
+
+		if fileExists {
+			log.Debug("FileCache::OpenFile : Delete cached file %s", options.Name)
+
+			err := deleteFile(localPath)
+			if err != nil && !os.IsNotExist(err) {
+				log.Err("FileCache::OpenFile : Failed to delete old file %s", options.Name)
+			}
+		} else {
+			// Create the file if if doesn't already exist.
+			err := os.MkdirAll(filepath.Dir(localPath), fc.defaultPermission)
+			if err != nil {
+				log.Err("FileCache::OpenFile : error creating directory structure for file %s [%s]", options.Name, err.Error())
+				return nil, err
+			}
+		}
+
+		// Open the file in write mode.
+		f, err = os.OpenFile(localPath, os.O_CREATE|os.O_RDWR, options.Mode)
+		if err != nil {
+			log.Err("FileCache::OpenFile : error creating new file %s [%s]", options.Name, err.Error())
+			return nil, err
+		}
+
+		if options.Flags&os.O_TRUNC != 0 {
+			fileSize = 0
+		}
+
+		if fc.partialThreshold != 0 && fileSize >= fc.partialThreshold {
+			// Large files are not downloaded upfront, create a sparse file and download ranges as they are accessed
+			err = f.Truncate(fileSize)
+			if err != nil {
+				log.Err("FileCache::OpenFile : error creating sparse file %s [%s]", options.Name, err.Error())
+				_ = f.Close()
+				_ = os.Remove(localPath)
+				return nil, err
+			}
+
+			fc.rangeMaps.set(options.Name, newRangeMap(fileSize, fc.rangeSize, attr.Mtime, attr.ETag))
+			fc.uncacheHandles(options.Name)
+			log.Info("FileCache::OpenFile : %s of size %d will be cached in ranges", options.Name, fileSize)
+		} else if fileSize > 0 && fc.linkContent(options.Name, localPath, attr) {
+			// Same content was downloaded for another file, linking it takes no additional space in the cache
+			log.Debug("FileCache::OpenFile : %s linked from dedup store", options.Name)
+			linked = true
+		} else if fileSize > 0 {
+			if fc.diskHighWaterMark != 0 {
+				currSize, err := common.GetUsage(fc.tmpPath)
+				if err != nil {
+					log.Err("FileCache::OpenFile : error getting current usage of cache [%s]", err.Error())
+				} else {
+					if (currSize + float64(fileSize)) > fc.diskHighWaterMark {
+						log.Err("FileCache::OpenFile : cache size limit reached [%f] failed to open %s", fc.maxCacheSize, options.Name)
+						return nil, syscall.ENOSPC
+					}
+				}
+
+			}
+
+			err = fc.quotas.admit(options.Name, options.Uid, fileSize)
+			if err != nil {
+				log.Err("FileCache::OpenFile : quota exceeded for %s of size %d by uid %d", options.Name, fileSize, options.Uid)
+				_ = f.Close()
+				_ = os.Remove(localPath)
+				return nil, err
+			}
+
+			// Download/Copy the file from storage to the local file.
+			err = fc.downloadLocal(options.Name, f, fileSize)
+			if err != nil {
+				// File was created locally and now download has failed so we need to delete it back from local cache
+				log.Err("FileCache::OpenFile : error downloading file from storage %s [%s]", options.Name, err.Error())
+				_ = f.Close()
+				_ = os.Remove(localPath)
+				return nil, err
+			}
+		}
+
+		// Update the last download time of this file
+		flock.SetDownloadTime()
+		if attr != nil && options.Flags&os.O_TRUNC == 0 && fc.rangeMaps.get(options.Name) == nil {
+			fc.index.set(options.Name, attr)
+		} else {
+			fc.index.remove(options.Name)
+		}
+
+		log.Debug("FileCache::OpenFile : Download of %s is complete", options.Name)
+		f.Close()
+
+		// After downloading the file, update the modified times and mode of the file.
+		fileMode := fc.defaultPermission
+		if attr != nil && !attr.IsModeDefault() {
+			fileMode = attr.Mode
+		}
+
+		// Linked file shares its inode with other files, so its mode and times are served from storage attributes
+		if !linked {
+			// If user has selected some non default mode in config then every local file shall be created with that mode only
+			err = os.Chmod(localPath, fileMode)
+			if err != nil {
+				log.Err("FileCache::OpenFile : Failed to change mode of file %s [%s]", options.Name, err.Error())
+			}
+			// TODO: When chown is supported should we update that?
+
+			if attr != nil {
+				// chtimes shall be the last api otherwise calling chmod/chown will update the last change time
+				err = os.Chtimes(localPath, attr.Atime, attr.Mtime)
+				if err != nil {
+					log.Err("FileCache::OpenFile : Failed to change times of file %s [%s]", options.Name, err.Error())
+				}
+			}
+
+			// Content is added to the dedup store only after its mode and times are set, as the store links the inode
+			if fc.dedup != nil && attr != nil && fileSize > 0 && fc.rangeMaps.get(options.Name) == nil {
+				err = fc.dedup.Add(attr.MD5, localPath)
+				if err != nil {
+					log.Warn("FileCache::OpenFile : failed to add %s to dedup store [%s]", options.Name, err.Error())
+				}
+			}
+		}
+
+		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, dlFiles, (int64)(1))
+	} else {
+		log.Debug("FileCache::OpenFile : %s will be served from cache", options.Name)
+		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, cacheServed, (int64)(1))
+	}
+
+	if options.Flags&syscall.O_ACCMODE != os.O_RDONLY || options.Flags&os.O_TRUNC != 0 {
+		err = fc.unshare(localPath)
+		if err != nil {
+			log.Err("FileCache::OpenFile : error unsharing content of %s [%s]", options.Name, err.Error())
+			return nil, err
+		}
+	}
+
+	// Open the file and grab a shared lock to prevent deletion by the cache policy.
+	f, err = os.OpenFile(localPath, fc.openFlags(options.Flags), options.Mode)
 	if err != nil {
 		log.Err("FileCache::OpenFile : error opening cached file %s [%s]", options.Name, err.Error())
 		return nil, err
@@ -1037,14 +1850,26 @@ func (fc *FileCache) OpenFile(options internal.OpenFileOptions) (*handlemap.Hand
 	}
 	inf, err := f.Stat()
 	if err == nil {
-		handle.Size = inf.Size()
+		handle.Size = fc.localSize(inf)
+		fc.quotas.charge(options.Name, options.Uid, fc.chargedSize(inf), handle.Size)
 	}
 
 	handle.UnixFD = uint64(f.Fd())
-	if !fc.offloadIO {
+	rm := fc.rangeMaps.get(options.Name)
+	// Encrypted files can not be read directly by libfuse, nor can files whose ranges are not all downloaded
+	if !fc.offloadIO && fc.cipher == nil && rm == nil {
 		handle.Flags.Set(handlemap.HandleFlagCached)
 	}
 
+	if rm != nil {
+		if options.Flags&os.O_TRUNC != 0 {
+			rm.Lock()
+			rm.resize(0)
+			rm.Unlock()
+		}
+		handle.SetValue(rangeMapKey, rm)
+	}
+
 	log.Info("FileCache::OpenFile : file=%s, fd=%d", options.Name, f.Fd())
 	handle.SetFileObject(f)
 
@@ -1080,10 +1905,23 @@ func (fc *FileCache) closeFileInternal(options internal.CloseFileOptions, flock
 
 	localPath := filepath.Join(fc.tmpPath, options.Handle.Path)
 
+	dirty := options.Handle.Dirty()
+	var item *uploadItem = nil
+	if dirty {
+		// Journal the upload before it starts so that it is not lost if this process dies midway
+		item = fc.uploads.add(options.Handle.Path, getRangeMap(options.Handle) != nil)
+	}
+
 	err := fc.FlushFile(internal.FlushFileOptions{Handle: options.Handle, CloseInProgress: true}) //nolint
+	fc.uploads.complete(item, err)
 	if err != nil {
-		log.Err("FileCache::closeFileInternal : failed to flush file %s", options.Handle.Path)
-		return err
+		if item == nil {
+			log.Err("FileCache::closeFileInternal : failed to flush file %s", options.Handle.Path)
+			return err
+		}
+
+		// Upload will be retried in background, local copy is retained till then
+		log.Err("FileCache::closeFileInternal : failed to flush file %s, upload will be retried [%s]", options.Handle.Path, err.Error())
 	}
 
 	f := options.Handle.GetFileObject()
@@ -1092,6 +1930,13 @@ func (fc *FileCache) closeFileInternal(options internal.CloseFileOptions, flock
 		return syscall.EBADF
 	}
 
+	if dirty && fc.quotas != nil {
+		// Writes are charged by their size, the file is charged the blocks it takes once closed
+		if info, err := f.Stat(); err == nil {
+			fc.quotas.resize(options.Handle.Path, fc.chargedSize(info), fc.localSize(info))
+		}
+	}
+
 	err = f.Close()
 	if err != nil {
 		log.Err("FileCache::closeFileInternal : error closing file %s(%d) [%s]", options.Handle.Path, int(f.Fd()), err.Error())
@@ -1129,16 +1974,29 @@ func (fc *FileCache) ReadFile(options internal.ReadFileOptions) ([]byte, error)
 		return nil, syscall.EBADF
 	}
 
+	if rm := getRangeMap(options.Handle); rm != nil {
+		// Entire file is being read so all ranges need to be available locally
+		rm.Lock()
+		defer rm.Unlock()
+
+		err := fc.fetchRanges(options.Handle.Path, rm, rm.missing(0, rm.size))
+		if err != nil {
+			log.Err("FileCache::ReadFile : error downloading ranges of %s [%s]", options.Handle.Path, err.Error())
+			return nil, err
+		}
+	}
+
 	// Get file info so we know the size of data we expect to read.
 	info, err := f.Stat()
 	if err != nil {
 		log.Err("FileCache::ReadFile : error stat %s [%s] ", options.Handle.Path, err.Error())
 		return nil, err
 	}
-	data := make([]byte, info.Size())
-	bytesRead, err := f.Read(data)
+	size := fc.localSize(info)
+	data := make([]byte, size)
+	bytesRead, err := fc.readLocal(options.Handle, data, 0)
 
-	if int64(bytesRead) != info.Size() {
+	if int64(bytesRead) != size {
 		log.Err("FileCache::ReadFile : error [couldn't read entire file] %s", options.Handle.Path)
 		return nil, syscall.EIO
 	}
@@ -1166,9 +2024,11 @@ func (fc *FileCache) ReadInBuffer(options internal.ReadInBufferOptions) (int, er
 		fc.policy.CacheValid(localPath)
 	}
 
-	// Removing f.ReadAt as it involves lot of house keeping and then calls syscall.Pread
-	// Instead we will call syscall directly for better perf
-	return syscall.Pread(options.Handle.FD(), options.Data, options.Offset)
+	if rm := getRangeMap(options.Handle); rm != nil {
+		return fc.readRanges(options, rm)
+	}
+
+	return fc.readLocal(options.Handle, options.Data, options.Offset)
 }
 
 // WriteFile: Write to the local file
@@ -1195,6 +2055,12 @@ func (fc *FileCache) WriteFile(options internal.WriteFileOptions) (int, error) {
 		}
 	}
 
+	err := fc.quotas.grow(options.Handle.Path, options.Offset, options.Offset+int64(len(options.Data)))
+	if err != nil {
+		log.Err("FileCache::WriteFile : quota exceeded for %s", options.Handle.Path)
+		return 0, err
+	}
+
 	// Read and write operations are very frequent so updating cache policy for every read is a costly operation
 	// Update cache policy every 1K operations (includes both read and write) instead
 	options.Handle.OptCnt++
@@ -1203,13 +2069,32 @@ func (fc *FileCache) WriteFile(options internal.WriteFileOptions) (int, error) {
 		fc.policy.CacheValid(localPath)
 	}
 
-	// Removing f.WriteAt as it involves lot of house keeping and then calls syscall.Pwrite
-	// Instead we will call syscall directly for better perf
-	bytesWritten, err := syscall.Pwrite(options.Handle.FD(), options.Data, options.Offset)
+	rm := getRangeMap(options.Handle)
+	if rm != nil {
+		// Ranges which are partially overwritten need their existing contents before the write
+		rm.Lock()
+		defer rm.Unlock()
+
+		err := fc.fetchRanges(options.Handle.Path, rm, rm.missingForWrite(options.Offset, int64(len(options.Data))))
+		if err != nil {
+			log.Err("FileCache::WriteFile : error downloading ranges of %s [%s]", options.Handle.Path, err.Error())
+			return 0, err
+		}
+	}
+
+	bytesWritten, err := fc.writeLocal(options.Handle, options.Data, options.Offset)
 
 	if err == nil {
+		if !options.Handle.Dirty() {
+			// Local file no longer matches storage till it is flushed
+			fc.index.remove(options.Handle.Path)
+		}
+
 		// Mark the handle dirty so the file is written back to storage on FlushFile.
 		options.Handle.Flags.Set(handlemap.HandleFlagDirty)
+		if rm != nil {
+			rm.written(options.Offset, int64(bytesWritten))
+		}
 	} else {
 		log.Err("FileCache::WriteFile : failed to write %s [%s]", options.Handle.Path, err.Error())
 	}
@@ -1292,53 +2177,98 @@ func (fc *FileCache) FlushFile(options internal.FlushFileOptions) error {
 			return syscall.EIO
 		}
 
-		// Write to storage
-		// Create a new handle for the SDK to use to upload (read local file)
-		// The local handle can still be used for read and write.
-		var orgMode fs.FileMode
-		modeChanged := false
-
-		uploadHandle, err := os.Open(localPath)
-		if err != nil {
-			if os.IsPermission(err) {
-				info, _ := os.Stat(localPath)
-				orgMode = info.Mode()
-				newMode := orgMode | 0444
-				err = os.Chmod(localPath, newMode)
-				if err == nil {
-					modeChanged = true
-					uploadHandle, err = os.Open(localPath)
-					log.Info("FileCache::FlushFile : read mode added to file %s", options.Handle.Path)
-				}
-			}
+		// For a partially cached file upload only the ranges modified locally
+		rm := getRangeMap(options.Handle)
+		uploaded := false
+		if rm != nil {
+			rm.Lock()
+			defer rm.Unlock()
 
+			uploaded, err = fc.uploadRanges(options.Handle.Path, rm)
 			if err != nil {
-				log.Err("FileCache::FlushFile : error [unable to open upload handle] %s [%s]", options.Handle.Path, err.Error())
+				log.Err("FileCache::FlushFile : %s range upload failed [%s]", options.Handle.Path, err.Error())
 				return err
 			}
 		}
-		err = fc.NextComponent().CopyFromFile(
-			internal.CopyFromFileOptions{
-				Name: options.Handle.Path,
-				File: uploadHandle,
-			})
 
-		uploadHandle.Close()
+		offline := rm == nil && fc.offline.bypass()
+		if !uploaded && !offline {
+			// Write to storage
+			// Create a new handle for the SDK to use to upload (read local file)
+			// The local handle can still be used for read and write.
+			var orgMode fs.FileMode
+			modeChanged := false
+
+			uploadHandle, err := os.Open(localPath)
+			if err != nil {
+				if os.IsPermission(err) {
+					info, _ := os.Stat(localPath)
+					orgMode = info.Mode()
+					newMode := orgMode | 0444
+					err = os.Chmod(localPath, newMode)
+					if err == nil {
+						modeChanged = true
+						uploadHandle, err = os.Open(localPath)
+						log.Info("FileCache::FlushFile : read mode added to file %s", options.Handle.Path)
+					}
+				}
+
+				if err != nil {
+					log.Err("FileCache::FlushFile : error [unable to open upload handle] %s [%s]", options.Handle.Path, err.Error())
+					return err
+				}
+			}
+			err = fc.uploadLocal(options.Handle.Path, uploadHandle)
+
+			uploadHandle.Close()
+
+			if modeChanged {
+				err1 := os.Chmod(localPath, orgMode)
+				if err1 != nil {
+					log.Err("FileCache::FlushFile : Failed to remove read mode from file %s [%s]", options.Handle.Path, err1.Error())
+				}
+			}
 
-		if modeChanged {
-			err1 := os.Chmod(localPath, orgMode)
-			if err1 != nil {
-				log.Err("FileCache::FlushFile : Failed to remove read mode from file %s [%s]", options.Handle.Path, err1.Error())
+			if err != nil {
+				// A partially cached file can be uploaded only when its missing ranges can be downloaded
+				offline = rm == nil && fc.offline.failed(err)
+				if !offline {
+					log.Err("FileCache::FlushFile : %s upload failed [%s]", options.Handle.Path, err.Error())
+					return err
+				}
 			}
 		}
 
-		if err != nil {
-			log.Err("FileCache::FlushFile : %s upload failed [%s]", options.Handle.Path, err.Error())
-			return err
+		if offline {
+			// Local file holds the data till storage is reachable again
+			err = fc.offline.record(offlineOpWrite, options.Handle.Path, "")
+			if err != nil {
+				log.Err("FileCache::FlushFile : %s failed to log write [%s]", options.Handle.Path, err.Error())
+				return err
+			}
 		}
 
+		if rm != nil {
+			// Ranges dropped later are downloaded from the version just uploaded
+			etag := ""
+			attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Handle.Path})
+			if err == nil {
+				etag = attr.ETag
+			}
+			rm.uploaded(etag)
+		}
 		options.Handle.Flags.Clear(handlemap.HandleFlagDirty)
 
+		if (fc.index != nil || fc.offline != nil) && rm == nil && !offline {
+			// Record the properties of uploaded blob so that local copy can be retained across restarts
+			// and conflicts can be detected for the changes done while storage is not reachable
+			attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: options.Handle.Path})
+			if err == nil {
+				fc.index.set(options.Handle.Path, attr)
+				fc.offline.observe(attr)
+			}
+		}
+
 		// If chmod was done on the file before it was uploaded to container then setting up mode would have been missed
 		// Such file names are added to this map and here post upload we try to set the mode correctly
 		_, found := fc.missedChmodList.Load(options.Handle.Path)
@@ -1365,6 +2295,260 @@ func (fc *FileCache) FlushFile(options internal.FlushFileOptions) error {
 	return nil
 }
 
+// getRangeMap : Range map of the file if it is cached partially
+func getRangeMap(handle *handlemap.Handle) *rangeMap {
+	val, found := handle.GetValue(rangeMapKey)
+	if !found {
+		return nil
+	}
+	return val.(*rangeMap)
+}
+
+// readRanges : Read from a partially cached file, downloading the missing ranges first
+func (fc *FileCache) readRanges(options internal.ReadInBufferOptions, rm *rangeMap) (int, error) {
+	length := int64(len(options.Data))
+
+	rm.RLock()
+	if len(rm.missing(options.Offset, length)) == 0 {
+		defer rm.RUnlock()
+		return syscall.Pread(options.Handle.FD(), options.Data, options.Offset)
+	}
+	rm.RUnlock()
+
+	rm.Lock()
+	defer rm.Unlock()
+
+	err := fc.fetchRanges(options.Handle.Path, rm, rm.missing(options.Offset, length))
+	if err != nil {
+		log.Err("FileCache::readRanges : error downloading ranges of %s [%s]", options.Handle.Path, err.Error())
+		return 0, err
+	}
+
+	return syscall.Pread(options.Handle.FD(), options.Data, options.Offset)
+}
+
+// fetchRanges : Download the given ranges of a partially cached file, caller shall hold the lock of the range map
+func (fc *FileCache) fetchRanges(name string, rm *rangeMap, list []int64) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	if rm.stale {
+		log.Err("FileCache::fetchRanges : %s changed in storage after it was opened", name)
+		return syscall.ESTALE
+	}
+
+	localPath := filepath.Join(fc.tmpPath, name)
+	f, err := openRangeFile(localPath)
+	if err != nil {
+		log.Err("FileCache::fetchRanges : error opening local file %s [%s]", localPath, err.Error())
+		return err
+	}
+	defer f.Close()
+
+	needed := int64(0)
+	for _, idx := range list {
+		start, end := rm.bounds(idx)
+		needed += max(min(end, rm.remoteSize)-start, 0)
+	}
+
+	err = fc.quotas.extend(name, needed)
+	if err != nil {
+		log.Err("FileCache::fetchRanges : quota exceeded downloading %d bytes of %s", needed, name)
+		return err
+	}
+
+	for _, idx := range list {
+		start, end := rm.bounds(idx)
+		end = min(end, rm.remoteSize)
+
+		if fc.diskHighWaterMark != 0 {
+			currSize, err := common.GetUsage(fc.tmpPath)
+			if err != nil {
+				log.Err("FileCache::fetchRanges : error getting current usage of cache [%s]", err.Error())
+			} else if (currSize + float64(end-start)) > fc.diskHighWaterMark {
+				log.Err("FileCache::fetchRanges : cache size limit reached [%f] failed to download %s", fc.maxCacheSize, name)
+				return syscall.ENOSPC
+			}
+		}
+
+		data := make([]byte, end-start)
+		etag := ""
+		n, err := fc.NextComponent().ReadInBuffer(internal.ReadInBufferOptions{
+			Path:   name,
+			Size:   rm.remoteSize,
+			Offset: start,
+			Data:   data,
+			Etag:   &etag,
+		})
+		if err != nil {
+			log.Err("FileCache::fetchRanges : error downloading range %d of %s [%s]", idx, name, err.Error())
+			return err
+		}
+
+		if !rm.matches(etag) {
+			// Ranges downloaded earlier belong to an older version of the file, none of them can be used
+			log.Err("FileCache::fetchRanges : %s changed in storage [%s != %s]", name, etag, rm.etag)
+			fc.invalidateRanges(name, rm)
+			return syscall.ESTALE
+		}
+
+		if n != len(data) {
+			log.Err("FileCache::fetchRanges : short read for range %d of %s [%d != %d]", idx, name, n, len(data))
+			return syscall.EIO
+		}
+
+		_, err = f.WriteAt(data, start)
+		if err != nil {
+			log.Err("FileCache::fetchRanges : error writing range %d of %s [%s]", idx, name, err.Error())
+			return err
+		}
+
+		rm.present.set(idx)
+		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, dlRanges, (int64)(1))
+	}
+
+	if info, err := f.Stat(); err == nil {
+		fc.quotas.resize(name, fc.chargedSize(info), fc.localSize(info))
+	}
+
+	err = rm.resetTimes(localPath)
+	if err != nil {
+		log.Err("FileCache::fetchRanges : Failed to change times of file %s [%s]", name, err.Error())
+	}
+
+	return nil
+}
+
+// invalidateRanges : Drop a partially cached file which has changed in storage, caller shall hold the lock of the range map.
+// Open handles keep the ranges already read, while the next open downloads the file again.
+func (fc *FileCache) invalidateRanges(name string, rm *rangeMap) {
+	rm.stale = true
+	if fc.rangeMaps.get(name) == rm {
+		fc.rangeMaps.remove(name)
+	}
+
+	localPath := filepath.Join(fc.tmpPath, name)
+	err := deleteFile(localPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("FileCache::invalidateRanges : failed to delete local file %s [%s]", localPath, err.Error())
+	}
+	fc.index.remove(name)
+}
+
+// uploadRanges : Upload only the modified ranges of a partially cached file, caller shall hold the lock of the range map
+// Blocks in storage which hold no modified data are retained, blocks overlapping a modified range are staged again.
+// If the block list can not be read, whole file is downloaded and false is returned so that caller uploads it instead.
+func (fc *FileCache) uploadRanges(name string, rm *rangeMap) (bool, error) {
+	if rm.stale {
+		// Unmodified ranges of the local file do not match the blocks in storage any more
+		log.Err("FileCache::uploadRanges : %s changed in storage after it was opened", name)
+		return false, syscall.ESTALE
+	}
+
+	list, err := fc.NextComponent().GetCommittedBlockList(name)
+	if err != nil || list == nil {
+		log.Info("FileCache::uploadRanges : Block list of %s is not available, uploading complete file", name)
+		return false, fc.fetchRanges(name, rm, rm.missing(0, rm.size))
+	}
+
+	if !rm.aligned(*list) || (len(*list) == 0 && rm.remoteSize > 0) {
+		// Blocks written by other tools do not match the ranges, each block overlapping a modified range is staged again in full
+		log.Info("FileCache::uploadRanges : Block list of %s does not match the ranges, staging modified blocks again", name)
+	}
+
+	localPath := filepath.Join(fc.tmpPath, name)
+	f, err := os.Open(localPath)
+	if err != nil {
+		log.Info("FileCache::uploadRanges : Unable to open %s [%s], uploading complete file", localPath, err.Error())
+		return false, fc.fetchRanges(name, rm, rm.missing(0, rm.size))
+	}
+	defer f.Close()
+
+	// All block ids of a blob shall be of same length
+	idLength := int64(common.BlockIDLength)
+	if len(*list) > 0 && common.GetIdLength((*list)[0].Id) > 0 {
+		idLength = common.GetIdLength((*list)[0].Id)
+	}
+
+	ids := make([]string, 0, max(int64(len(*list)), rm.count()))
+	staged := 0
+
+	// stage : Upload the given region of the local file in blocks which end at range boundaries
+	stage := func(start int64, end int64) error {
+		for start < end {
+			blockEnd := min(end, (start/rm.rangeSize+1)*rm.rangeSize)
+			err := fc.fetchRanges(name, rm, rm.missing(start, blockEnd-start))
+			if err != nil {
+				return err
+			}
+
+			data := make([]byte, blockEnd-start)
+			_, err = f.ReadAt(data, start)
+			if err != nil {
+				log.Err("FileCache::uploadRanges : error reading offset %d of %s [%s]", start, name, err.Error())
+				return err
+			}
+
+			id := common.GetBlockID(idLength)
+			err = fc.NextComponent().StageData(internal.StageDataOptions{
+				Name:   name,
+				Id:     id,
+				Data:   data,
+				Offset: uint64(start),
+			})
+			if err != nil {
+				log.Err("FileCache::uploadRanges : error staging offset %d of %s [%s]", start, name, err.Error())
+				return err
+			}
+
+			ids = append(ids, id)
+			staged++
+			start = blockEnd
+		}
+		return nil
+	}
+
+	// Blocks in storage which hold no modified data are retained as they are
+	offset := int64(0)
+	for _, block := range *list {
+		if block.Offset != offset || offset >= rm.size {
+			break
+		}
+
+		end := block.Offset + int64(block.Size)
+		if end <= rm.remoteSize && end <= rm.size && !rm.modified(block.Offset, end) {
+			ids = append(ids, block.Id)
+		} else {
+			end = min(end, rm.size)
+			err = stage(block.Offset, end)
+			if err != nil {
+				return false, err
+			}
+		}
+		offset = end
+	}
+
+	// Data beyond the blocks in storage
+	err = stage(offset, rm.size)
+	if err != nil {
+		return false, err
+	}
+
+	err = fc.NextComponent().CommitData(internal.CommitDataOptions{
+		Name:      name,
+		List:      ids,
+		BlockSize: uint64(rm.rangeSize),
+	})
+	if err != nil {
+		log.Err("FileCache::uploadRanges : error committing %s [%s]", name, err.Error())
+		return false, err
+	}
+
+	log.Info("FileCache::uploadRanges : %s staged %d of %d blocks", name, staged, len(ids))
+	return true, nil
+}
+
 // GetAttr: Consolidate attributes from storage and local cache
 func (fc *FileCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
 	log.Trace("FileCache::GetAttr : %s", options.Name)
@@ -1376,17 +2560,40 @@ func (fc *FileCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr
 
 	// To cover case 1, get attributes from storage
 	var exists bool
-	attrs, err := fc.NextComponent().GetAttr(options)
+	var attrs *internal.ObjAttr
+	var err error
+
+	if pending, found := fc.offline.pendingState(options.Name); found {
+		// Path was changed by an operation which is not replayed to storage yet
+		attrs, exists = pending, pending != nil
+	} else if fc.offline.isDisconnected() {
+		err = syscall.ENOTCONN
+	} else {
+		attrs, err = fc.NextComponent().GetAttr(options)
+		if err == nil {
+			fc.offline.observe(attrs)
+		}
+		exists = err == nil
+	}
+
+	var storageErr error = nil
 	if err != nil {
 		if err == syscall.ENOENT || os.IsNotExist(err) {
 			log.Debug("FileCache::GetAttr : %s does not exist in storage", options.Name)
 			exists = false
+		} else if fc.offline.failed(err) {
+			// Storage is not reachable, use the last known state of the path
+			storageErr = err
+			var known bool
+			attrs, known = fc.offline.get(options.Name)
+			exists = attrs != nil
+			if known {
+				storageErr = nil
+			}
 		} else {
 			log.Err("FileCache::GetAttr : Failed to get attr of %s [%s]", options.Name, err.Error())
 			return &internal.ObjAttr{}, err
 		}
-	} else {
-		exists = true
 	}
 
 	// To cover cases 2 and 3, grab the attributes from the local cache
@@ -1399,8 +2606,10 @@ func (fc *FileCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr
 			// If file is under download then taking size or mod time from it will be incorrect.
 			if !fc.fileLocks.Locked(options.Name) {
 				log.Debug("FileCache::GetAttr : updating %s from local cache", options.Name)
-				attrs.Size = info.Size()
-				attrs.Mtime = info.ModTime()
+				attrs.Size = fc.localSize(info)
+				if !fc.shared(info) {
+					attrs.Mtime = info.ModTime()
+				}
 			} else {
 				log.Debug("FileCache::GetAttr : %s is locked, use storage attributes", options.Name)
 			}
@@ -1412,11 +2621,16 @@ func (fc *FileCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr
 				log.Debug("FileCache::GetAttr : serving %s attr from local cache", options.Name)
 				exists = true
 				attrs = newObjAttr(options.Name, info)
+				attrs.Size = fc.localSize(info)
 			}
 		}
 	}
 
 	if !exists {
+		if storageErr != nil {
+			log.Err("FileCache::GetAttr : %s not known while storage is not reachable [%s]", options.Name, storageErr.Error())
+			return &internal.ObjAttr{}, storageErr
+		}
 		return &internal.ObjAttr{}, syscall.ENOENT
 	}
 
@@ -1435,8 +2649,20 @@ func (fc *FileCache) RenameFile(options internal.RenameFileOptions) error {
 	dflock.Lock()
 	defer dflock.Unlock()
 
-	err := fc.NextComponent().RenameFile(options)
-	err = fc.validateStorageError(options.Src, err, "RenameFile", false)
+	var err error = nil
+	offline := fc.offline.bypass()
+	if !offline {
+		err = fc.NextComponent().RenameFile(options)
+		offline = fc.offline.failed(err)
+	}
+
+	if offline {
+		err = fc.recordOffline(offlineOpRename, options.Src, options.Dst)
+	} else {
+		err = fc.validateStorageError(options.Src, err, "RenameFile", false)
+		fc.offline.forget(options.Src, options.Dst)
+	}
+
 	if err != nil {
 		log.Err("FileCache::RenameFile : %s failed to rename file [%s]", options.Src, err.Error())
 		return err
@@ -1454,6 +2680,22 @@ func (fc *FileCache) RenameFile(options internal.RenameFileOptions) error {
 		log.Err("FileCache::RenameFile : %s failed to rename local file %s [%s]", localSrcPath, err.Error())
 	}
 
+	if err == nil {
+		fc.rangeMaps.rename(options.Src, options.Dst)
+		fc.uploads.rename(options.Src, options.Dst)
+		fc.quotas.rename(options.Src, options.Dst)
+	} else {
+		fc.rangeMaps.remove(options.Dst)
+		fc.uploads.remove(options.Src)
+		fc.quotas.release(options.Src)
+		fc.quotas.release(options.Dst)
+	}
+
+	// Renamed blob has new properties, so neither name can be retained across restarts
+	fc.index.remove(options.Src)
+	fc.index.remove(options.Dst)
+	fc.tiers.remove(options.Src, options.Dst)
+
 	if err != nil {
 		// If there was a problem in local rename then delete the destination file
 		// it might happen that dest file was already there and local rename failed
@@ -1517,14 +2759,28 @@ func (fc *FileCache) TruncateFile(options internal.TruncateFileOptions) error {
 	if err == nil || os.IsExist(err) {
 		fc.policy.CacheValid(localPath)
 
-		if info.Size() != options.Size {
-			err = os.Truncate(localPath, options.Size)
+		if fc.localSize(info) != options.Size {
+			err = fc.unshare(localPath)
+			if err == nil {
+				err = fc.truncateLocal(options.Name, localPath, options.Size)
+			}
 			if err != nil {
 				log.Err("FileCache::TruncateFile : error truncating cached file %s [%s]", localPath, err.Error())
 				return err
 			}
 		}
+
+		if rm := fc.rangeMaps.get(options.Name); rm != nil {
+			rm.Lock()
+			rm.resize(options.Size)
+			rm.Unlock()
+		}
+		fc.index.remove(options.Name)
+		if info, err = os.Stat(localPath); err == nil {
+			fc.quotas.resize(options.Name, fc.chargedSize(info), options.Size)
+		}
 	}
+	fc.tiers.remove(options.Name)
 
 	return nil
 }
@@ -1552,7 +2808,10 @@ func (fc *FileCache) Chmod(options internal.ChmodOptions) error {
 		fc.policy.CacheValid(localPath)
 
 		if info.Mode() != options.Mode {
-			err = os.Chmod(localPath, options.Mode)
+			err = fc.unshare(localPath)
+			if err == nil {
+				err = os.Chmod(localPath, options.Mode)
+			}
 			if err != nil {
 				log.Err("FileCache::Chmod : error changing mode on the cached path %s [%s]", localPath, err.Error())
 				return err
@@ -1581,7 +2840,10 @@ func (fc *FileCache) Chown(options internal.ChownOptions) error {
 	if err == nil || os.IsExist(err) {
 		fc.policy.CacheValid(localPath)
 
-		err = os.Chown(localPath, options.Owner, options.Group)
+		err = fc.unshare(localPath)
+		if err == nil {
+			err = os.Chown(localPath, options.Owner, options.Group)
+		}
 		if err != nil {
 			log.Err("FileCache::Chown : error changing owner on the cached path %s [%s]", localPath, err.Error())
 			return err
@@ -1598,13 +2860,168 @@ func (fc *FileCache) FileUsed(name string) error {
 	return nil
 }
 
+// InvalidatePath : Drop the local copy of a file changed by another client so that next open downloads it again.
+// Files which are open or have local changes not in storage yet are retained.
+func (fc *FileCache) InvalidatePath(options internal.InvalidatePathOptions) error {
+	log.Trace("FileCache::InvalidatePath : %s", options.Name)
+
+	if options.IsDir {
+		localDir := filepath.Join(fc.tmpPath, internal.TruncateDirName(options.Name))
+		_ = filepath.WalkDir(localDir, func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				fc.purgeLocal(indexName(strings.TrimPrefix(path, fc.tmpPath)))
+			}
+			return nil
+		})
+	} else {
+		fc.purgeLocal(options.Name)
+	}
+
+	return fc.NextComponent().InvalidatePath(options)
+}
+
+// purgeLocal : Remove the local copy of a file unless it is open or has changes not uploaded yet
+func (fc *FileCache) purgeLocal(name string) {
+	flock := fc.fileLocks.Get(name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	if flock.Count() != 0 || fc.retained(name) {
+		return
+	}
+
+	localPath := filepath.Join(fc.tmpPath, name)
+	err := deleteFile(localPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("FileCache::purgeLocal : failed to delete local file %s [%s]", localPath, err.Error())
+	}
+	fc.rangeMaps.remove(name)
+	fc.index.remove(name)
+	fc.tiers.remove(name)
+	fc.quotas.release(name)
+	fc.policy.CachePurge(localPath)
+}
+
+// uncacheHandles : Reads of open handles of a file cached in ranges must go through file cache to fetch missing ranges
+func (fc *FileCache) uncacheHandles(name string) {
+	handlemap.GetHandles().Range(func(_, value any) bool {
+		handle := value.(*handlemap.Handle)
+		if handle.Path == name && handle.Cached() {
+			log.Debug("FileCache::uncacheHandles : %s handle %d no longer read directly", name, handle.ID)
+			handle.Flags.Clear(handlemap.HandleFlagCached)
+		}
+		return true
+	})
+}
+
+// Pin : Pin a path or glob pattern so that matching files are never evicted from the cache
+func (fc *FileCache) Pin(pattern string) error {
+	log.Trace("FileCache::Pin : %s", pattern)
+
+	if !fc.pinList.add(pattern) {
+		return os.ErrExist
+	}
+
+	if fc.pinCtx != nil && fc.pinCtx.Err() == nil {
+		fc.pinWg.Add(1)
+		go fc.downloadPinned([]string{normalizePinPattern(pattern)})
+	}
+
+	return nil
+}
+
+// Unpin : Remove a path or glob pattern from pin list, matching files become evictable again
+func (fc *FileCache) Unpin(pattern string) error {
+	log.Trace("FileCache::Unpin : %s", pattern)
+
+	if !fc.pinList.remove(pattern) {
+		return os.ErrNotExist
+	}
+
+	return nil
+}
+
+// PinList : Get the list of pinned paths and patterns
+func (fc *FileCache) PinList() []string {
+	return fc.pinList.list()
+}
+
+// downloadPinned : Bring all files matching given pin patterns into the local cache
+func (fc *FileCache) downloadPinned(patterns []string) {
+	defer fc.pinWg.Done()
+
+	for _, pattern := range patterns {
+		root := pinListRoot(pattern)
+		log.Info("FileCache::downloadPinned : Downloading files pinned by %s", pattern)
+
+		if root != "" {
+			attr, err := fc.NextComponent().GetAttr(internal.GetAttrOptions{Name: root})
+			if err != nil {
+				log.Err("FileCache::downloadPinned : Failed to get attr of %s [%s]", root, err.Error())
+				continue
+			}
+
+			if !attr.IsDir() {
+				fc.downloadPinnedFile(root)
+				continue
+			}
+		}
+
+		fc.downloadPinnedDir(root)
+	}
+}
+
+// downloadPinnedDir : Recursively download pinned files under the given directory
+func (fc *FileCache) downloadPinnedDir(name string) {
+	if name != "" {
+		name = name + "/"
+	}
+
+	attrs, err := fc.NextComponent().ReadDir(internal.ReadDirOptions{Name: name})
+	if err != nil {
+		log.Err("FileCache::downloadPinnedDir : Failed to list %s [%s]", name, err.Error())
+		return
+	}
+
+	for _, attr := range attrs {
+		if fc.pinCtx.Err() != nil {
+			return
+		}
+
+		if attr.IsDir() {
+			fc.downloadPinnedDir(attr.Path)
+		} else if fc.pinList.isPinned(attr.Path) {
+			fc.downloadPinnedFile(attr.Path)
+		}
+	}
+}
+
+// downloadPinnedFile : Open and close the file so that it gets downloaded and registered with the cache policy
+func (fc *FileCache) downloadPinnedFile(name string) {
+	if fc.pinCtx.Err() != nil {
+		return
+	}
+
+	handle, err := fc.OpenFile(internal.OpenFileOptions{Name: name, Flags: os.O_RDONLY, Mode: fc.defaultPermission})
+	if err != nil {
+		log.Err("FileCache::downloadPinnedFile : Failed to download %s [%s]", name, err.Error())
+		return
+	}
+
+	err = fc.CloseFile(internal.CloseFileOptions{Handle: handle})
+	if err != nil {
+		log.Err("FileCache::downloadPinnedFile : Failed to close %s [%s]", name, err.Error())
+	}
+}
+
 // ------------------------- Factory -------------------------------------------
 
 // Pipeline will call this method to create your object, initialize your variables here
 // << DO NOT DELETE ANY AUTO GENERATED CODE HERE >>
 func NewFileCacheComponent() internal.Component {
 	comp := &FileCache{
-		fileLocks: common.NewLockMap(),
+		fileLocks:  common.NewLockMap(),
+		cryptLocks: common.NewLockMap(),
 	}
 	comp.SetName(compName)
 	config.AddConfigChangeEventListener(comp)
@@ -1667,6 +3084,15 @@ func init() {
 	hardLimit := config.AddBoolFlag("hard-limit", false, "File cache limits are hard limits or not.")
 	config.BindPFlag(compName+".hard-limit", hardLimit)
 
+	partialThreshold := config.AddUint64Flag("file-cache-partial-threshold", 0, "Files of this size (in MB) or larger are cached in ranges instead of being downloaded completely on open.")
+	config.BindPFlag(compName+".partial-threshold-mb", partialThreshold)
+
+	rangeSize := config.AddUint64Flag("file-cache-range-size", defaultRangeSizeMB, "Size (in MB) of each range downloaded or uploaded for partially cached files.")
+	config.BindPFlag(compName+".range-size-mb", rangeSize)
+
+	warmManifest := config.AddStringFlag("file-cache-warm-manifest", "", "File listing the paths or glob patterns to be downloaded into the cache in background after mount.")
+	config.BindPFlag(compName+".warm-manifest", warmManifest)
+
 	config.RegisterFlagCompletionFunc("tmp-path", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveDefault
 	})
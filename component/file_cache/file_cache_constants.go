@@ -38,4 +38,26 @@ const (
 	usgPer      = "Usage Percent"
 	dlFiles     = "Files Downloaded"
 	cacheServed = "Files served from cache"
+	pinUsage    = "Pinned Usage"
+	evictUsage  = "Evictable Usage"
+	dlRanges    = "Ranges Downloaded"
+	evRanges    = "Ranges Evicted"
+
+	uploadsPending  = "Uploads Pending"
+	uploadsInFlight = "Uploads In Flight"
+	uploadsFailed   = "Uploads Failed"
+
+	offlineOpsPending = "Offline Operations Pending"
+	offlineConflicts  = "Offline Conflicts"
+
+	tierDemoted  = "Files Demoted"
+	tierPromoted = "Files Promoted"
+
+	warmedFiles = "Files Warmed"
+	warmPending = "Warm-up Pending"
+
+	quotaUsage = "Quota Usage"
+
+	dedupFiles = "Files Deduplicated"
+	dedupFreed = "Dedup Bytes Pruned"
 )
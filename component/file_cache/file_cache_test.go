@@ -36,15 +36,20 @@ package file_cache
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/fs"
 	"math"
 	"math/rand"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
@@ -810,6 +815,41 @@ func (suite *fileCacheTestSuite) TestDeleteFile() {
 	suite.assert.True(os.IsNotExist(err))
 }
 
+func (suite *fileCacheTestSuite) TestInvalidatePath() {
+	defer suite.cleanupTest()
+	path := "file_invalidate"
+
+	handle, _ := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+
+	// Open files are retained
+	err := suite.fileCache.InvalidatePath(internal.InvalidatePathOptions{Name: path, ETag: "etag"})
+	suite.assert.Nil(err)
+	suite.assert.FileExists(suite.cache_path + "/" + path)
+
+	suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.FileExists(suite.cache_path + "/" + path)
+
+	err = suite.fileCache.InvalidatePath(internal.InvalidatePathOptions{Name: path, ETag: "etag"})
+	suite.assert.Nil(err)
+	suite.assert.NoFileExists(suite.cache_path + "/" + path)
+	// Copy in storage is not touched
+	suite.assert.FileExists(suite.fake_storage_path + "/" + path)
+
+	// Files under a renamed or deleted directory
+	suite.fileCache.CreateDir(internal.CreateDirOptions{Name: "dir_invalidate", Mode: 0777})
+	for _, name := range []string{"dir_invalidate/a", "dir_invalidate/b"} {
+		handle, _ = suite.fileCache.CreateFile(internal.CreateFileOptions{Name: name, Mode: 0777})
+		suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	}
+	handle, _ = suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "dir_invalidate/b", Flags: os.O_RDWR, Mode: 0777})
+
+	err = suite.fileCache.InvalidatePath(internal.InvalidatePathOptions{Name: "dir_invalidate", IsDir: true})
+	suite.assert.Nil(err)
+	suite.assert.NoFileExists(suite.cache_path + "/dir_invalidate/a")
+	suite.assert.FileExists(suite.cache_path + "/dir_invalidate/b")
+	suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+}
+
 // Case 2 Test cover when the file does not exist in storage but it exists in the local cache.
 // This can happen if createEmptyFile is false and the file hasn't been flushed yet.
 func (suite *fileCacheTestSuite) TestDeleteFileCase2() {
@@ -1806,6 +1846,8 @@ func (suite *fileCacheTestSuite) TestReadFileWithRefresh() {
 	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  create-empty-file: %t\n  timeout-sec: 1000\n  refresh-sec: 10\n\nloopbackfs:\n  path: %s",
 		suite.cache_path, createEmptyFile, suite.fake_storage_path)
 	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+	head := &invalidationRecorder{}
+	suite.fileCache.SetPipelineHead(head)
 
 	path := "file42"
 	err := os.WriteFile(suite.fake_storage_path+"/"+path, []byte("test data"), 0777)
@@ -1835,6 +1877,7 @@ func (suite *fileCacheTestSuite) TestReadFileWithRefresh() {
 	suite.assert.Equal(9, n)
 	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: f})
 	suite.assert.Nil(err)
+	suite.assert.Empty(head.invalidated)
 
 	// Now wait for 5 seconds and we shall get the updated content on next read
 	err = os.WriteFile(suite.fake_storage_path+"/"+path, []byte("test data123456"), 0777)
@@ -1848,6 +1891,20 @@ func (suite *fileCacheTestSuite) TestReadFileWithRefresh() {
 	suite.assert.Equal(15, n)
 	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: f})
 	suite.assert.Nil(err)
+
+	// Kernel is asked to drop the old content
+	suite.assert.Equal([]internal.InvalidatePathOptions{{Name: path, KernelOnly: true}}, head.invalidated)
+}
+
+// invalidationRecorder : Pipeline head which records the invalidations sent through it
+type invalidationRecorder struct {
+	internal.BaseComponent
+	invalidated []internal.InvalidatePathOptions
+}
+
+func (r *invalidationRecorder) InvalidatePath(options internal.InvalidatePathOptions) error {
+	r.invalidated = append(r.invalidated, options)
+	return nil
 }
 
 func (suite *fileCacheTestSuite) TestHardLimitOnSize() {
@@ -1999,6 +2056,1474 @@ func (suite *fileCacheTestSuite) TestDeleteDirectory() {
 	suite.assert.True(empty)
 }
 
+func (suite *fileCacheTestSuite) TestPinDownloadOnMount() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	suite.createRemoteDirectoryStructure()
+	files := []string{"a/b/c/d/file1", "a/b/e/f/file2", "h/i/j/k/data.csv", "h/i/j/k/data.txt", "h/l/m/n/file3"}
+	for _, f := range files {
+		err := os.WriteFile(filepath.Join(suite.fake_storage_path, f), []byte("pinned data"), 0777)
+		suite.assert.NoError(err)
+	}
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 0\n  pin:\n    - a/b\n    - h/*/j/k/*.csv\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+	suite.assert.ElementsMatch([]string{"a/b", "h/*/j/k/*.csv"}, suite.fileCache.PinList())
+
+	expected := []string{"a/b/c/d/file1", "a/b/e/f/file2", "h/i/j/k/data.csv"}
+	for _, f := range expected {
+		localPath := filepath.Join(suite.cache_path, f)
+		_, err := os.Stat(localPath)
+		for i := 0; i < 10 && err != nil; i++ {
+			time.Sleep(time.Second)
+			_, err = os.Stat(localPath)
+		}
+		suite.assert.NoError(err)
+		suite.assert.True(suite.fileCache.policy.IsCached(localPath))
+	}
+
+	// Files which are not pinned are not downloaded
+	_, err := os.Stat(filepath.Join(suite.cache_path, "h/i/j/k/data.txt"))
+	suite.assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(suite.cache_path, "h/l/m/n/file3"))
+	suite.assert.True(os.IsNotExist(err))
+}
+
+func (suite *fileCacheTestSuite) TestPinOnConfigChange() {
+	defer suite.cleanupTest()
+	path := "pinned/file"
+	localPath := filepath.Join(suite.cache_path, path)
+
+	err := os.MkdirAll(filepath.Join(suite.fake_storage_path, "pinned"), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("pinned data"), 0777)
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.fileCache.Pin("other"))
+
+	// Newly pinned paths are downloaded, paths no longer in config are unpinned
+	config.ReadConfigFromReader(strings.NewReader(fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 0\n  pin:\n    - pinned/\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)))
+	suite.fileCache.OnConfigChange()
+	suite.assert.Equal([]string{"pinned"}, suite.fileCache.PinList())
+
+	_, err = os.Stat(localPath)
+	for i := 0; i < 10 && err != nil; i++ {
+		time.Sleep(time.Second)
+		_, err = os.Stat(localPath)
+	}
+	suite.assert.NoError(err)
+
+	config.ReadConfigFromReader(strings.NewReader(fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 0\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)))
+	suite.fileCache.OnConfigChange()
+	suite.assert.Empty(suite.fileCache.PinList())
+}
+
+func (suite *fileCacheTestSuite) TestPinUnpinRuntime() {
+	defer suite.cleanupTest()
+	path := "pinned/file"
+	localPath := filepath.Join(suite.cache_path, path)
+
+	err := os.MkdirAll(filepath.Join(suite.fake_storage_path, "pinned"), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("pinned data"), 0777)
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.Pin("pinned")
+	suite.assert.NoError(err)
+	err = suite.fileCache.Pin("pinned/")
+	suite.assert.True(os.IsExist(err))
+
+	_, err = os.Stat(localPath)
+	for i := 0; i < 10 && err != nil; i++ {
+		time.Sleep(time.Second)
+		_, err = os.Stat(localPath)
+	}
+	suite.assert.NoError(err)
+
+	// timeout is 0 but pinned file stays in cache after close
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	time.Sleep(time.Second)
+	_, err = os.Stat(localPath)
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.Unpin("pinned")
+	suite.assert.NoError(err)
+	err = suite.fileCache.Unpin("pinned")
+	suite.assert.True(os.IsNotExist(err))
+
+	// Once unpinned, file is evicted on close
+	handle, err = suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	_, err = os.Stat(localPath)
+	for i := 0; i < 10 && !os.IsNotExist(err); i++ {
+		time.Sleep(time.Second)
+		_, err = os.Stat(localPath)
+	}
+	suite.assert.True(os.IsNotExist(err))
+}
+
+func (suite *fileCacheTestSuite) setupPartialCache(name string, size int) []byte {
+	suite.cleanupTest() // teardown the default file cache generated
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 0\n  partial-threshold-mb: 2\n  range-size-mb: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	err := os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, name), data, 0777)
+	suite.assert.NoError(err)
+	return data
+}
+
+// etagStorage : Storage which reports the given etag for every download
+type etagStorage struct {
+	internal.Component
+	etag string
+}
+
+func (s *etagStorage) ReadInBuffer(options internal.ReadInBufferOptions) (int, error) {
+	n, err := s.Component.ReadInBuffer(options)
+	if options.Etag != nil {
+		*options.Etag = s.etag
+	}
+	return n, err
+}
+
+func (suite *fileCacheTestSuite) TestPartialCacheChangedInStorage() {
+	defer suite.cleanupTest()
+	path := "partial_changed"
+	data := suite.setupPartialCache(path, 4*MB)
+	storage := &etagStorage{Component: suite.loopback, etag: "v1"}
+	suite.assert.NoError(suite.fileCache.Stop())
+	suite.fileCache = newTestFileCache(storage)
+	suite.assert.NoError(suite.fileCache.Start(context.Background()))
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	rm := getRangeMap(handle)
+	suite.assert.NotNil(rm)
+
+	output := make([]byte, 100)
+	_, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 0, Data: output})
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:100], output)
+
+	// Ranges of another version of the file are not mixed into the local copy
+	storage.etag = "v2"
+	_, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 3 * MB, Data: output})
+	suite.assert.Equal(syscall.ESTALE, err)
+	suite.assert.True(rm.stale)
+	suite.assert.Nil(suite.fileCache.rangeMaps.get(path))
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, path))
+
+	_, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 2 * MB, Data: output})
+	suite.assert.Equal(syscall.ESTALE, err)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	// Next open starts over with the new version
+	handle, err = suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 3 * MB, Data: output})
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[3*MB:3*MB+100], output)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+}
+
+func (suite *fileCacheTestSuite) TestPartialCacheRead() {
+	defer suite.cleanupTest()
+	path := "partial_read"
+	data := suite.setupPartialCache(path, 4*MB)
+	suite.assert.EqualValues(2*MB, suite.fileCache.partialThreshold)
+	suite.assert.EqualValues(MB, suite.fileCache.rangeSize)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(4*MB, handle.Size)
+
+	rm := getRangeMap(handle)
+	suite.assert.NotNil(rm)
+	suite.assert.False(rm.present.any())
+
+	// Read across ranges 1 and 2 only
+	output := make([]byte, 100)
+	n, err := suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 2*MB - 50, Data: output})
+	suite.assert.NoError(err)
+	suite.assert.Equal(100, n)
+	suite.assert.Equal(data[2*MB-50:2*MB+50], output)
+
+	suite.assert.False(rm.present.isSet(0))
+	suite.assert.True(rm.present.isSet(1))
+	suite.assert.True(rm.present.isSet(2))
+	suite.assert.False(rm.present.isSet(3))
+
+	// Local file is sparse, only downloaded ranges occupy disk
+	info, err := os.Stat(filepath.Join(suite.cache_path, path))
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(4*MB, info.Size())
+	suite.assert.LessOrEqual(info.Sys().(*syscall.Stat_t).Blocks*512, int64(3*MB))
+
+	// Drop ranges while file is open and read again
+	suite.fileCache.policy.(*lruPolicy).evictRanges(filepath.Join(suite.cache_path, path), path)
+	suite.assert.False(rm.present.any())
+
+	n, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 2*MB - 50, Data: output})
+	suite.assert.NoError(err)
+	suite.assert.Equal(100, n)
+	suite.assert.Equal(data[2*MB-50:2*MB+50], output)
+
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
+// Tests handle of a file cached in ranges is never read directly from the local file, which has holes for ranges not downloaded
+func (suite *fileCacheTestSuite) TestPartialCacheNotReadDirectly() {
+	defer suite.cleanupTest()
+	path := "partial_direct"
+	suite.cleanupTest() // teardown the default file cache generated
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  timeout-sec: 0\n  partial-threshold-mb: 2\n  range-size-mb: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+	suite.assert.False(suite.fileCache.offloadIO)
+
+	data := make([]byte, 4*MB)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	suite.assert.NoError(os.MkdirAll(suite.fake_storage_path, 0777))
+	suite.assert.NoError(os.WriteFile(filepath.Join(suite.fake_storage_path, path), data, 0777))
+
+	// Handle opened before the file is cached in ranges stops being read directly
+	other := handlemap.NewHandle(path)
+	other.Flags.Set(handlemap.HandleFlagCached)
+	handlemap.Add(other)
+	defer handlemap.Delete(other.ID)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(getRangeMap(handle))
+	suite.assert.False(handle.Cached())
+	suite.assert.False(other.Cached())
+
+	output := make([]byte, 100)
+	_, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 3 * MB, Data: output})
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[3*MB:3*MB+100], output)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	// Files downloaded in full are still read directly
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "small"), data[:MB], 0777)
+	suite.assert.NoError(err)
+	handle, err = suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "small", Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.True(handle.Cached())
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+}
+
+func (suite *fileCacheTestSuite) TestPartialCacheSmallFile() {
+	defer suite.cleanupTest()
+	path := "partial_small"
+	suite.setupPartialCache(path, MB)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.Nil(getRangeMap(handle))
+
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) TestPartialCacheWrite() {
+	defer suite.cleanupTest()
+	path := "partial_write"
+	data := suite.setupPartialCache(path, 4*MB)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	rm := getRangeMap(handle)
+	suite.assert.NotNil(rm)
+
+	// Partial write needs the range to be downloaded first
+	update := []byte("updated data")
+	n, err := suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: MB + 10, Data: update})
+	suite.assert.NoError(err)
+	suite.assert.Equal(len(update), n)
+	copy(data[MB+10:], update)
+
+	// Extend the file beyond its current size
+	n, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 5 * MB, Data: update})
+	suite.assert.NoError(err)
+	suite.assert.Equal(len(update), n)
+	data = append(data, make([]byte, MB)...)
+	data = append(data, update...)
+
+	suite.assert.True(rm.dirty.isSet(1))
+	suite.assert.True(rm.dirty.isSet(5))
+	suite.assert.False(rm.present.isSet(0))
+	suite.assert.False(rm.present.isSet(3))
+
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	// Unmodified ranges were never downloaded
+	suite.assert.False(rm.present.isSet(0))
+	suite.assert.False(rm.present.isSet(3))
+	suite.assert.False(rm.dirty.any())
+
+	output, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, output)
+}
+
+// blockStorage : Storage which keeps blobs as lists of blocks of any size
+type blockStorage struct {
+	internal.Component
+	path      string
+	committed internal.CommittedBlockList
+	data      map[string][]byte
+	staged    map[string][]byte
+}
+
+func newBlockStorage(next internal.Component, path string, name string, sizes []int64) *blockStorage {
+	s := &blockStorage{Component: next, path: path, data: make(map[string][]byte), staged: make(map[string][]byte)}
+	content, _ := os.ReadFile(filepath.Join(path, name))
+	offset := int64(0)
+	for _, size := range sizes {
+		id := common.GetBlockID(common.BlockIDLength)
+		s.committed = append(s.committed, internal.CommittedBlock{Id: id, Offset: offset, Size: uint64(size)})
+		s.data[id] = content[offset : offset+size]
+		offset += size
+	}
+	return s
+}
+
+func (s *blockStorage) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
+	list := append(internal.CommittedBlockList{}, s.committed...)
+	return &list, nil
+}
+
+func (s *blockStorage) StageData(options internal.StageDataOptions) error {
+	s.staged[options.Id] = append([]byte{}, options.Data...)
+	return nil
+}
+
+func (s *blockStorage) CommitData(options internal.CommitDataOptions) error {
+	content := make([]byte, 0)
+	committed := internal.CommittedBlockList{}
+	data := make(map[string][]byte)
+	for _, id := range options.List {
+		block, found := s.staged[id]
+		if !found {
+			block = s.data[id]
+		}
+		committed = append(committed, internal.CommittedBlock{Id: id, Offset: int64(len(content)), Size: uint64(len(block))})
+		data[id] = block
+		content = append(content, block...)
+	}
+	s.committed, s.data = committed, data
+	return os.WriteFile(filepath.Join(s.path, options.Name), content, 0777)
+}
+
+// Tests blocks which do not line up with the ranges are staged again only where they hold modified data
+func (suite *fileCacheTestSuite) TestPartialCacheWriteUnalignedBlocks() {
+	defer suite.cleanupTest()
+	path := "partial_unaligned"
+	data := suite.setupPartialCache(path, 4*MB)
+	storage := newBlockStorage(suite.loopback, suite.fake_storage_path, path, []int64{3 * MB / 2, 3 * MB / 2, MB})
+	suite.assert.NoError(suite.fileCache.Stop())
+	suite.fileCache = newTestFileCache(storage)
+	suite.assert.NoError(suite.fileCache.Start(context.Background()))
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	rm := getRangeMap(handle)
+	suite.assert.NotNil(rm)
+
+	// Range 1 is modified, it overlaps the first two blocks
+	update := []byte("updated data")
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: MB + 10, Data: update})
+	suite.assert.NoError(err)
+	copy(data[MB+10:], update)
+
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	// Last block is retained, the range it covers was never downloaded
+	staged := 0
+	for _, block := range storage.staged {
+		staged += len(block)
+	}
+	suite.assert.Equal(3*MB, staged)
+	last := storage.committed[len(storage.committed)-1]
+	_, restaged := storage.staged[last.Id]
+	suite.assert.False(restaged)
+	suite.assert.EqualValues(MB, last.Size)
+	suite.assert.False(rm.present.isSet(3))
+	suite.assert.False(rm.dirty.any())
+
+	output, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, output)
+}
+
+func (suite *fileCacheTestSuite) TestPartialCacheTruncateOnOpen() {
+	defer suite.cleanupTest()
+	path := "partial_trunc"
+	suite.setupPartialCache(path, 4*MB)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(getRangeMap(handle))
+
+	handle2, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: path, Flags: os.O_RDWR | os.O_TRUNC, Mode: 0777})
+	suite.assert.NoError(err)
+	rm := getRangeMap(handle2)
+	suite.assert.NotNil(rm)
+	suite.assert.EqualValues(0, rm.size)
+
+	update := []byte("new data")
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle2, Offset: 0, Data: update})
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle2})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	output, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal(update, output)
+}
+
+func (suite *fileCacheTestSuite) TestIndexRetainedOnRestart() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	indexFile := filepath.Join(home_dir, "file_cache_index"+randomString(8))
+	defer os.Remove(indexFile)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 120\n  index-file: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, indexFile, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	err := os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+
+	files := []string{"unchanged", "changed"}
+	for _, f := range files {
+		err = os.WriteFile(filepath.Join(suite.fake_storage_path, f), []byte("remote data"), 0777)
+		suite.assert.NoError(err)
+
+		handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: f, Flags: os.O_RDONLY, Mode: 0777})
+		suite.assert.NoError(err)
+		err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+		suite.assert.NoError(err)
+	}
+
+	// Unmount retains the cache along with the index
+	suite.loopback.Stop()
+	err = suite.fileCache.Stop()
+	suite.assert.NoError(err)
+
+	_, err = os.Stat(indexFile)
+	suite.assert.NoError(err)
+	for _, f := range files {
+		_, err = os.Stat(filepath.Join(suite.cache_path, f))
+		suite.assert.NoError(err)
+	}
+
+	// Mark the local copy so that we know whether it was served or downloaded again
+	err = os.WriteFile(filepath.Join(suite.cache_path, "unchanged"), []byte("local  data"), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "changed"), []byte("new remote data"), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.cache_path, "stray"), []byte("stray data"), 0777)
+	suite.assert.NoError(err)
+
+	// Remount with the same cache directory
+	suite.setupTestHelper(config)
+
+	_, err = os.Stat(indexFile)
+	suite.assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(suite.cache_path, "stray"))
+	suite.assert.True(os.IsNotExist(err))
+	suite.assert.True(suite.fileCache.policy.IsCached(filepath.Join(suite.cache_path, "unchanged")))
+	suite.assert.True(suite.fileCache.index.isRestored("unchanged"))
+
+	expected := map[string]string{"unchanged": "local  data", "changed": "new remote data"}
+	for f, data := range expected {
+		handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: f, Flags: os.O_RDONLY, Mode: 0777})
+		suite.assert.NoError(err)
+
+		output, err := suite.fileCache.ReadFile(internal.ReadFileOptions{Handle: handle})
+		suite.assert.NoError(err)
+		suite.assert.Equal(data, string(output))
+
+		err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+		suite.assert.NoError(err)
+	}
+	suite.assert.False(suite.fileCache.index.isRestored("unchanged"))
+}
+
+func (suite *fileCacheTestSuite) TestIndexInvalidPath() {
+	defer suite.cleanupTest()
+	configuration := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  index-file: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, filepath.Join(suite.cache_path, "index"), suite.fake_storage_path)
+
+	fileCache := NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err := fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "[index-file shall be a file outside tmp-path and mount path]")
+}
+
+func (suite *fileCacheTestSuite) TestUploadRetriedAfterRestart() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	journal := filepath.Join(home_dir, "file_cache_journal"+randomString(8))
+	defer os.Remove(journal)
+
+	config := fmt.Sprintf("lazy-write: true\n\nfile_cache:\n  path: %s\n  timeout-sec: 0\n  upload-journal: %s\n  upload-retry-backoff-sec: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, journal, suite.fake_storage_path)
+	suite.setupTestHelper(config)
+	suite.assert.NotNil(suite.fileCache.uploads)
+
+	// Block the upload by making the parent directory in storage a file
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, "dir"), []byte("blocker"), 0777)
+	suite.assert.NoError(err)
+	err = os.MkdirAll(filepath.Join(suite.cache_path, "dir"), 0777)
+	suite.assert.NoError(err)
+
+	file := "dir/file"
+	data := []byte("lazy data")
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: file, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.fileCache.fileCloseOpt.Wait()
+
+	// Failed upload is queued and local copy is not evicted
+	suite.assert.True(suite.fileCache.uploads.pending(file))
+	time.Sleep(2 * time.Second)
+	suite.assert.FileExists(filepath.Join(suite.cache_path, file))
+
+	// Unmount retains the file with pending upload
+	suite.loopback.Stop()
+	err = suite.fileCache.Stop()
+	suite.assert.NoError(err)
+	suite.assert.FileExists(filepath.Join(suite.cache_path, file))
+
+	err = os.Remove(filepath.Join(suite.fake_storage_path, "dir"))
+	suite.assert.NoError(err)
+	err = os.MkdirAll(filepath.Join(suite.fake_storage_path, "dir"), 0777)
+	suite.assert.NoError(err)
+
+	// Remount resumes the upload
+	suite.setupTestHelper(config)
+	suite.assert.Equal([]string{file}, suite.fileCache.uploads.list())
+	suite.fileCache.uploads.drain()
+	suite.assert.False(suite.fileCache.uploads.pending(file))
+
+	output, err := os.ReadFile(filepath.Join(suite.fake_storage_path, file))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, output)
+}
+
+func (suite *fileCacheTestSuite) TestUploadJournalIgnoredWithoutLazyWrite() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	journal := filepath.Join(home_dir, "file_cache_journal"+randomString(8))
+	defer os.Remove(journal)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  upload-journal: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, journal, suite.fake_storage_path)
+	suite.setupTestHelper(config)
+	suite.assert.Nil(suite.fileCache.uploads)
+}
+
+// unreachableStorage : Storage which fails all calls with a network error while it is down
+type unreachableStorage struct {
+	internal.Component
+	down   atomic.Bool
+	leased atomic.Bool // Deletes fail with EIO as for a blob under lease
+}
+
+var errUnreachable = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+func (s *unreachableStorage) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	if s.down.Load() {
+		return nil, errUnreachable
+	}
+	return s.Component.GetAttr(options)
+}
+
+func (s *unreachableStorage) ReadDir(options internal.ReadDirOptions) ([]*internal.ObjAttr, error) {
+	if s.down.Load() {
+		return nil, errUnreachable
+	}
+	return s.Component.ReadDir(options)
+}
+
+func (s *unreachableStorage) StreamDir(options internal.StreamDirOptions) ([]*internal.ObjAttr, string, error) {
+	if s.down.Load() {
+		return nil, "", errUnreachable
+	}
+	return s.Component.StreamDir(options)
+}
+
+func (s *unreachableStorage) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
+	if s.down.Load() {
+		return nil, errUnreachable
+	}
+	return s.Component.CreateFile(options)
+}
+
+func (s *unreachableStorage) DeleteFile(options internal.DeleteFileOptions) error {
+	if s.down.Load() {
+		return errUnreachable
+	}
+	if s.leased.Load() {
+		return syscall.EIO
+	}
+	return s.Component.DeleteFile(options)
+}
+
+func (s *unreachableStorage) RenameFile(options internal.RenameFileOptions) error {
+	if s.down.Load() {
+		return errUnreachable
+	}
+	return s.Component.RenameFile(options)
+}
+
+func (s *unreachableStorage) CopyFromFile(options internal.CopyFromFileOptions) error {
+	if s.down.Load() {
+		return errUnreachable
+	}
+	return s.Component.CopyFromFile(options)
+}
+
+func (s *unreachableStorage) setupOffline(suite *fileCacheTestSuite, offlineLog string) {
+	cfg := fmt.Sprintf("file_cache:\n  path: %s\n  timeout-sec: 0\n  offline-log: %s\n  reconnect-interval-sec: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, offlineLog, suite.fake_storage_path)
+	config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.loopback = newLoopbackFS()
+	s.Component = suite.loopback
+	suite.fileCache = newTestFileCache(s)
+	suite.assert.NotNil(suite.fileCache.offline)
+
+	_ = suite.loopback.Start(context.Background())
+	err := suite.fileCache.Start(context.Background())
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) waitForReplay() {
+	for i := 0; i < 50 && suite.fileCache.offline.bypass(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	suite.assert.False(suite.fileCache.offline.bypass())
+}
+
+func (suite *fileCacheTestSuite) TestOfflineReplay() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	storage := &unreachableStorage{}
+	offlineLog := filepath.Join(home_dir, "file_cache_offline"+randomString(8))
+	defer os.Remove(offlineLog)
+	storage.setupOffline(suite, offlineLog)
+
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, "existing"), []byte("remote data"), 0777)
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "existing"})
+	suite.assert.NoError(err)
+
+	storage.down.Store(true)
+
+	// Changes are applied to local cache and logged
+	data := []byte("offline data")
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "new", Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.assert.True(suite.fileCache.offline.isDisconnected())
+	suite.assert.FileExists(filepath.Join(suite.cache_path, "new"))
+
+	err = suite.fileCache.RenameFile(internal.RenameFileOptions{Src: "new", Dst: "renamed"})
+	suite.assert.NoError(err)
+	err = suite.fileCache.DeleteFile(internal.DeleteFileOptions{Name: "existing"})
+	suite.assert.NoError(err)
+	err = suite.fileCache.DeleteFile(internal.DeleteFileOptions{Name: "unknown"})
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	// Local pending state is merged with the last known state of storage
+	attr, err := suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "renamed"})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+	_, err = suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "existing"})
+	suite.assert.Equal(syscall.ENOENT, err)
+	_, err = suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "new"})
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	attrs, err := suite.fileCache.ReadDir(internal.ReadDirOptions{Name: ""})
+	suite.assert.NoError(err)
+	suite.assert.Len(attrs, 1)
+	suite.assert.Equal("renamed", attrs[0].Path)
+
+	// Storage is untouched till connectivity returns
+	_, err = os.Stat(filepath.Join(suite.fake_storage_path, "existing"))
+	suite.assert.NoError(err)
+
+	storage.down.Store(false)
+	suite.waitForReplay()
+
+	output, err := os.ReadFile(filepath.Join(suite.fake_storage_path, "renamed"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, output)
+	_, err = os.Stat(filepath.Join(suite.fake_storage_path, "new"))
+	suite.assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(suite.fake_storage_path, "existing"))
+	suite.assert.True(os.IsNotExist(err))
+}
+
+func (suite *fileCacheTestSuite) TestOfflineLeaseErrorNotQueued() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	storage := &unreachableStorage{}
+	offlineLog := filepath.Join(home_dir, "file_cache_offline"+randomString(8))
+	defer os.Remove(offlineLog)
+	storage.setupOffline(suite, offlineLog)
+
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, "leased"), []byte("remote data"), 0777)
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "leased"})
+	suite.assert.NoError(err)
+
+	// Error of storage is returned to the caller instead of being queued for replay
+	storage.leased.Store(true)
+	err = suite.fileCache.DeleteFile(internal.DeleteFileOptions{Name: "leased"})
+	suite.assert.Equal(syscall.EIO, err)
+	suite.assert.False(suite.fileCache.offline.isDisconnected())
+	suite.assert.False(suite.fileCache.offline.pending("leased"))
+
+	_, err = os.Stat(filepath.Join(suite.fake_storage_path, "leased"))
+	suite.assert.NoError(err)
+}
+
+func (suite *fileCacheTestSuite) TestOfflineConflict() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	storage := &unreachableStorage{}
+	offlineLog := filepath.Join(home_dir, "file_cache_offline"+randomString(8))
+	defer os.Remove(offlineLog)
+	storage.setupOffline(suite, offlineLog)
+
+	storage.down.Store(true)
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "file", Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("local data")})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	// Same file is created in storage by someone else meanwhile
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "file"), []byte("remote data"), 0777)
+	suite.assert.NoError(err)
+
+	storage.down.Store(false)
+	suite.waitForReplay()
+
+	// Change in storage is retained and local data is saved under a conflict name
+	output, err := os.ReadFile(filepath.Join(suite.fake_storage_path, "file"))
+	suite.assert.NoError(err)
+	suite.assert.Equal("remote data", string(output))
+
+	matches, _ := filepath.Glob(filepath.Join(suite.fake_storage_path, "file.conflict-*"))
+	suite.assert.Len(matches, 1)
+	output, err = os.ReadFile(matches[0])
+	suite.assert.NoError(err)
+	suite.assert.Equal("local data", string(output))
+
+	_, err = os.Stat(filepath.Join(suite.cache_path, "file"))
+	suite.assert.True(os.IsNotExist(err))
+}
+
+func (suite *fileCacheTestSuite) TestOfflineLogRetainedOnRestart() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	storage := &unreachableStorage{}
+	offlineLog := filepath.Join(home_dir, "file_cache_offline"+randomString(8))
+	defer os.Remove(offlineLog)
+	storage.setupOffline(suite, offlineLog)
+
+	storage.down.Store(true)
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "file", Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("local data")})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	// Unmount retains the file with pending operation
+	suite.loopback.Stop()
+	err = suite.fileCache.Stop()
+	suite.assert.NoError(err)
+	suite.assert.FileExists(filepath.Join(suite.cache_path, "file"))
+
+	// Remount replays the operation once storage is reachable
+	storage.down.Store(false)
+	storage.setupOffline(suite, offlineLog)
+	suite.assert.True(suite.fileCache.offline.pending("file"))
+	suite.waitForReplay()
+
+	output, err := os.ReadFile(filepath.Join(suite.fake_storage_path, "file"))
+	suite.assert.NoError(err)
+	suite.assert.Equal("local data", string(output))
+}
+
+func (suite *fileCacheTestSuite) TestTieredCache() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	slowPath := filepath.Join(home_dir, "file_cache_slow"+randomString(8))
+	defer os.RemoveAll(slowPath)
+
+	config := fmt.Sprintf("file_cache:\n  timeout-sec: 0\n  tiers:\n    - path: %s\n      max-size-mb: 10\n    - path: %s\n      max-size-mb: 20\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, slowPath, suite.fake_storage_path)
+	suite.setupTestHelper(config)
+	suite.assert.Equal(suite.cache_path, suite.fileCache.tmpPath)
+	suite.assert.EqualValues(10, suite.fileCache.maxCacheSize)
+	suite.assert.Equal([]string{slowPath}, suite.fileCache.tiers.paths())
+
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, "file"), []byte("remote data"), 0777)
+	suite.assert.NoError(err)
+
+	// Evicted file is moved to the slow tier
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "file", Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	suite.assert.Eventually(func() bool {
+		return suite.fileCache.tiers.contains("file")
+	}, 5*time.Second, 10*time.Millisecond)
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, "file"))
+	suite.assert.FileExists(filepath.Join(slowPath, "file"))
+
+	// Combined capacity of all tiers is reported
+	stat, ret, err := suite.fileCache.StatFs()
+	suite.assert.NoError(err)
+	suite.assert.True(ret)
+	suite.assert.EqualValues(30*MB, stat.Blocks*uint64(stat.Frsize))
+
+	// Open promotes the file back instead of downloading it
+	handle, err = suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "file", Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.False(suite.fileCache.tiers.contains("file"))
+	suite.assert.NoFileExists(filepath.Join(slowPath, "file"))
+
+	output, err := suite.fileCache.ReadFile(internal.ReadFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.assert.Equal("remote data", string(output))
+	err = suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	// Deleting the file drops its copy from the slow tier too
+	suite.assert.Eventually(func() bool {
+		return suite.fileCache.tiers.contains("file")
+	}, 5*time.Second, 10*time.Millisecond)
+	err = suite.fileCache.DeleteFile(internal.DeleteFileOptions{Name: "file"})
+	suite.assert.NoError(err)
+	suite.assert.False(suite.fileCache.tiers.contains("file"))
+	suite.assert.NoFileExists(filepath.Join(slowPath, "file"))
+}
+
+func (suite *fileCacheTestSuite) TestTieredCachePathConflict() {
+	defer suite.cleanupTest()
+	configuration := fmt.Sprintf("file_cache:\n  path: %s\n  tiers:\n    - path: %s_other\n    - path: %s_slow\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.cache_path, suite.cache_path, suite.fake_storage_path)
+
+	fileCache := NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err := fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "[path shall not be set along with tiers]")
+
+	configuration = fmt.Sprintf("file_cache:\n  tiers:\n    - path: %s\n    - path: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, filepath.Join(suite.cache_path, "slow"), suite.fake_storage_path)
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err = fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "[cache tiers shall not overlap with each other or mount path]")
+}
+
+func (suite *fileCacheTestSuite) TestWarmManifest() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	suite.createRemoteDirectoryStructure()
+	files := []string{"a/b/c/d/file1", "a/b/e/f/file2", "h/i/j/k/data.csv", "h/i/j/k/data.txt", "h/l/m/n/file3"}
+	for _, f := range files {
+		err := os.WriteFile(filepath.Join(suite.fake_storage_path, f), []byte("warm data"), 0777)
+		suite.assert.NoError(err)
+	}
+
+	manifest := filepath.Join(home_dir, "file_cache_warm"+randomString(8))
+	defer os.Remove(manifest)
+	err := os.WriteFile(manifest, []byte("# directories cover everything under them\na/b\n\nh/*/j/k/*.csv\nmissing/file\n"), 0777)
+	suite.assert.NoError(err)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  warm-manifest: %s\n  warm-parallelism: 2\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, manifest, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+	suite.fileCache.pinWg.Wait()
+
+	for _, f := range []string{"a/b/c/d/file1", "a/b/e/f/file2", "h/i/j/k/data.csv"} {
+		localPath := filepath.Join(suite.cache_path, f)
+		data, err := os.ReadFile(localPath)
+		suite.assert.NoError(err)
+		suite.assert.Equal("warm data", string(data))
+		suite.assert.True(suite.fileCache.policy.IsCached(localPath))
+	}
+
+	// Files not listed in the manifest are not downloaded
+	_, err = os.Stat(filepath.Join(suite.cache_path, "h/i/j/k/data.txt"))
+	suite.assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(suite.cache_path, "h/l/m/n/file3"))
+	suite.assert.True(os.IsNotExist(err))
+}
+
+func (suite *fileCacheTestSuite) TestWarmManifestSizeLimit() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	err := os.MkdirAll(filepath.Join(suite.fake_storage_path, "warm"), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "warm", "large"), make([]byte, 2*MB), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "warm", "small"), make([]byte, 1024), 0777)
+	suite.assert.NoError(err)
+
+	manifest := filepath.Join(home_dir, "file_cache_warm"+randomString(8))
+	defer os.Remove(manifest)
+	err = os.WriteFile(manifest, []byte("warm/*\n"), 0777)
+	suite.assert.NoError(err)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  max-size-mb: 1\n  warm-manifest: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, manifest, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+	suite.fileCache.pinWg.Wait()
+
+	// Only the file fitting under the high threshold of the cache is downloaded
+	suite.assert.FileExists(filepath.Join(suite.cache_path, "warm", "small"))
+	_, err = os.Stat(filepath.Join(suite.cache_path, "warm", "large"))
+	suite.assert.True(os.IsNotExist(err))
+}
+
+func (suite *fileCacheTestSuite) TestWarmManifestRanges() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	err := os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "large"), make([]byte, 3*MB), 0777)
+	suite.assert.NoError(err)
+
+	manifest := filepath.Join(home_dir, "file_cache_warm"+randomString(8))
+	defer os.Remove(manifest)
+	err = os.WriteFile(manifest, []byte("large\n"), 0777)
+	suite.assert.NoError(err)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  partial-threshold-mb: 1\n  range-size-mb: 1\n  warm-manifest: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, manifest, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+	suite.fileCache.pinWg.Wait()
+
+	// Every range of a file cached in ranges is downloaded
+	rm := suite.fileCache.rangeMaps.get("large")
+	suite.assert.NotNil(rm)
+	suite.assert.Empty(rm.missing(0, 3*MB))
+}
+
+func (suite *fileCacheTestSuite) TestWarmManifestMissing() {
+	defer suite.cleanupTest()
+	configuration := fmt.Sprintf("file_cache:\n  path: %s\n  warm-manifest: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, filepath.Join(home_dir, "file_cache_warm"+randomString(8)), suite.fake_storage_path)
+
+	fileCache := NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err := fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "not readable]")
+}
+
+func (suite *fileCacheTestSuite) TestDirQuota() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  dir-quotas:\n    - directory: jobs\n      max-size-mb: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	suite.assert.NoError(suite.fileCache.CreateDir(internal.CreateDirOptions{Name: "jobs", Mode: 0777}))
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "jobs/file1", Mode: 0777})
+	suite.assert.NoError(err)
+
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 0, Data: make([]byte, MB)})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: MB, Data: make([]byte, 1)})
+	suite.assert.Equal(syscall.EDQUOT, err)
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB}, suite.fileCache.quotas.list())
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	// Quota of the directory is used up while other directories are not limited
+	_, err = suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "jobs/file2", Mode: 0777})
+	suite.assert.Equal(syscall.EDQUOT, err)
+	handle, err = suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "file3", Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 0, Data: make([]byte, 2*MB)})
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	// Deleting a file gives its space back
+	suite.assert.NoError(suite.fileCache.DeleteFile(internal.DeleteFileOptions{Name: "jobs/file1"}))
+	suite.assert.Empty(suite.fileCache.quotas.list())
+	handle, err = suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "jobs/file2", Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+}
+
+func (suite *fileCacheTestSuite) TestUserQuota() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	err := os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "remote"), make([]byte, MB), 0777)
+	suite.assert.NoError(err)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  timeout-sec: 0\n  user-quotas:\n    - uid: 1000\n      max-size-mb: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	// Downloaded file is charged to the user who opened it
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "remote", Flags: os.O_RDWR, Mode: 0777, Uid: 1000})
+	suite.assert.NoError(err)
+	suite.assert.Equal(map[string]int64{"uid:1000": MB}, suite.fileCache.quotas.list())
+
+	_, err = suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "local", Mode: 0777, Uid: 1000})
+	suite.assert.Equal(syscall.EDQUOT, err)
+	other, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "local", Mode: 0777, Uid: 1001})
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: other}))
+
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: MB, Data: []byte("more")})
+	suite.assert.Equal(syscall.EDQUOT, err)
+
+	// File evicted from the cache is no longer charged
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+	for i := 0; i < 10 && len(suite.fileCache.quotas.list()) != 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	suite.assert.Empty(suite.fileCache.quotas.list())
+}
+
+func (suite *fileCacheTestSuite) TestQuotaRename() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  default-dir-quota-mb: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	suite.assert.NoError(suite.fileCache.CreateDir(internal.CreateDirOptions{Name: "a", Mode: 0777}))
+	suite.assert.NoError(suite.fileCache.CreateDir(internal.CreateDirOptions{Name: "b", Mode: 0777}))
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "a/file", Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("quota")})
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+	info, err := os.Stat(filepath.Join(suite.cache_path, "a/file"))
+	suite.assert.NoError(err)
+	allocated := info.Sys().(*syscall.Stat_t).Blocks * 512
+	suite.assert.Equal(map[string]int64{"dir:a": allocated}, suite.fileCache.quotas.list())
+
+	// Local copy is renamed only if the destination directory is in the cache
+	handle, err = suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "b/other", Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	err = suite.fileCache.RenameFile(internal.RenameFileOptions{Src: "a/file", Dst: "b/file"})
+	suite.assert.NoError(err)
+	suite.assert.Equal(map[string]int64{"dir:b": allocated}, suite.fileCache.quotas.list())
+
+	err = suite.fileCache.TruncateFile(internal.TruncateFileOptions{Name: "b/file", Size: 0})
+	suite.assert.NoError(err)
+	suite.assert.Empty(suite.fileCache.quotas.list())
+}
+
+func (suite *fileCacheTestSuite) TestQuotaDownload() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	err := os.MkdirAll(filepath.Join(suite.fake_storage_path, "jobs"), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "jobs/large"), make([]byte, 4*MB), 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "jobs/small"), make([]byte, 3*MB/2), 0777)
+	suite.assert.NoError(err)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  partial-threshold-mb: 2\n  range-size-mb: 1\n  dir-quotas:\n    - directory: jobs\n      max-size-mb: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	// File which does not fit in the quota is not downloaded at all
+	_, err = suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "jobs/small", Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.Equal(syscall.EDQUOT, err)
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, "jobs/small"))
+
+	// Sparse file is charged only the ranges downloaded into it
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "jobs/large", Flags: os.O_RDONLY, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.Empty(suite.fileCache.quotas.list())
+
+	data := make([]byte, 100)
+	_, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 0, Data: data})
+	suite.assert.NoError(err)
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB}, suite.fileCache.quotas.list())
+
+	_, err = suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 2 * MB, Data: data})
+	suite.assert.Equal(syscall.EDQUOT, err)
+	suite.assert.Equal(map[string]int64{"dir:jobs": MB}, suite.fileCache.quotas.list())
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+}
+
+func (suite *fileCacheTestSuite) TestQuotaConfigError() {
+	defer suite.cleanupTest()
+	configuration := fmt.Sprintf("file_cache:\n  path: %s\n  dir-quotas:\n    - directory: a/b\n      max-size-mb: 1\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+
+	fileCache := NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err := fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "[quota directory a/b shall be a top level directory]")
+}
+
+func (suite *fileCacheTestSuite) TestEncryption() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	data := make([]byte, 3*common.CacheChunkSize+100)
+	_, _ = rand.Read(data)
+	err := os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "remote"), data, 0777)
+	suite.assert.NoError(err)
+
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  timeout-sec: 0\n  encryption: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "remote", Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	suite.assert.False(handle.Cached())
+	suite.assert.EqualValues(len(data), handle.Size)
+
+	// Local copy does not hold plain data
+	local, err := os.ReadFile(filepath.Join(suite.cache_path, "remote"))
+	suite.assert.NoError(err)
+	suite.assert.False(bytes.Contains(local, data[:100]))
+
+	attr, err := suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "remote"})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+
+	buf := make([]byte, 200)
+	n, err := suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: common.CacheChunkSize - 100, Data: buf})
+	suite.assert.NoError(err)
+	suite.assert.Equal(200, n)
+	suite.assert.Equal(data[common.CacheChunkSize-100:common.CacheChunkSize+100], buf)
+
+	// Writes and truncate reach storage as plain data
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 10, Data: []byte("encrypted")})
+	suite.assert.NoError(err)
+	copy(data[10:], "encrypted")
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, "remote"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, remote)
+
+	handle, err = suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "remote", Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	err = suite.fileCache.TruncateFile(internal.TruncateFileOptions{Name: "remote", Size: 1000})
+	suite.assert.NoError(err)
+	read, err := suite.fileCache.ReadFile(internal.ReadFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:1000], read)
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	remote, err = os.ReadFile(filepath.Join(suite.fake_storage_path, "remote"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:1000], remote)
+}
+
+func (suite *fileCacheTestSuite) TestEncryptionNewFile() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, common.CacheKeySize))
+	config := fmt.Sprintf("file_cache:\n  path: %s\n  offload-io: true\n  encryption: true\n  encryption-key: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, key, suite.fake_storage_path)
+	suite.setupTestHelper(config) // setup a new file cache with a custom config (teardown will occur after the test as usual)
+
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: "file", Mode: 0777})
+	suite.assert.NoError(err)
+
+	// Write leaving a gap which reads back as zeros
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 100, Data: []byte("data")})
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle}))
+
+	expected := append(make([]byte, 100), []byte("data")...)
+	buf := make([]byte, 200)
+	n, err := suite.fileCache.ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 0, Data: buf})
+	suite.assert.NoError(err)
+	suite.assert.Equal(expected, buf[:n])
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, "file"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(expected, remote)
+
+	// No staging file is left behind
+	entries, err := os.ReadDir(suite.cache_path)
+	suite.assert.NoError(err)
+	suite.assert.Len(entries, 1)
+}
+
+// streamOnlyStorage : Storage which fails transfers through a local file, as those need plain data on disk
+type streamOnlyStorage struct {
+	internal.Component
+}
+
+func (s *streamOnlyStorage) CopyToFile(options internal.CopyToFileOptions) error {
+	return errors.New("download into a local file")
+}
+
+func (s *streamOnlyStorage) CopyFromFile(options internal.CopyFromFileOptions) error {
+	if options.File != nil {
+		return errors.New("upload from a local file")
+	}
+	return s.Component.CopyFromFile(options)
+}
+
+func (suite *fileCacheTestSuite) TestEncryptionStreamsData() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+
+	data := make([]byte, cryptTransferSize+common.CacheChunkSize+100)
+	_, _ = rand.Read(data)
+	err := os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, "remote"), data, 0777)
+	suite.assert.NoError(err)
+
+	cfg := fmt.Sprintf("file_cache:\n  path: %s\n  timeout-sec: 0\n  encryption: true\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+	config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.loopback = newLoopbackFS()
+	suite.fileCache = newTestFileCache(&streamOnlyStorage{Component: suite.loopback})
+	_ = suite.loopback.Start(context.Background())
+	suite.assert.NoError(suite.fileCache.Start(context.Background()))
+
+	// Download is encrypted in memory
+	handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: "remote", Flags: os.O_RDWR, Mode: 0777})
+	suite.assert.NoError(err)
+	read, err := suite.fileCache.ReadFile(internal.ReadFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, read)
+
+	// Upload is decrypted in memory
+	_, err = suite.fileCache.WriteFile(internal.WriteFileOptions{Handle: handle, Offset: cryptTransferSize, Data: []byte("encrypted")})
+	suite.assert.NoError(err)
+	copy(data[cryptTransferSize:], "encrypted")
+	suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, "remote"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data, remote)
+}
+
+func (suite *fileCacheTestSuite) TestEncryptionConfigError() {
+	defer suite.cleanupTest()
+	configuration := fmt.Sprintf("file_cache:\n  path: %s\n  encryption: true\n  encryption-key: abc\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, suite.fake_storage_path)
+
+	fileCache := NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err := fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "encryption key")
+
+	// Ephemeral key does not allow anything to be retained across mounts
+	configuration = fmt.Sprintf("file_cache:\n  path: %s\n  encryption: true\n  index-file: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, filepath.Join(suite.fake_storage_path, "index"), suite.fake_storage_path)
+
+	fileCache = NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err = fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "encryption without a key")
+}
+
+func (suite *fileCacheTestSuite) TestDedupLinkContent() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+	dedupPath := suite.fake_storage_path + "_dedup"
+	defer os.RemoveAll(dedupPath)
+	configuration := fmt.Sprintf("file_cache:\n  path: %s\n  timeout-sec: 0\n  dedup-path: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, dedupPath, suite.fake_storage_path)
+	suite.setupTestHelper(configuration)
+	suite.assert.NotNil(suite.fileCache.dedup)
+
+	data := []byte("shared content")
+	sum := md5.Sum(data)
+	first := filepath.Join(suite.cache_path, "first")
+	second := filepath.Join(suite.cache_path, "second")
+	err := os.WriteFile(first, data, 0644)
+	suite.assert.NoError(err)
+	err = suite.fileCache.dedup.Add(sum[:], first)
+	suite.assert.NoError(err)
+
+	// Size mismatch means the content is not the same
+	suite.assert.False(suite.fileCache.linkContent("second", second, &internal.ObjAttr{MD5: sum[:], Size: 1}))
+	suite.assert.NoFileExists(second)
+
+	suite.assert.True(suite.fileCache.linkContent("second", second, &internal.ObjAttr{MD5: sum[:], Size: int64(len(data))}))
+	firstInfo, _ := os.Stat(first)
+	secondInfo, _ := os.Stat(second)
+	suite.assert.True(os.SameFile(firstInfo, secondInfo))
+
+	// Changing the file in place shall not touch the shared content
+	err = suite.fileCache.unshare(second)
+	suite.assert.NoError(err)
+	secondInfo, _ = os.Stat(second)
+	suite.assert.False(os.SameFile(firstInfo, secondInfo))
+	err = os.WriteFile(second, []byte("changed"), 0644)
+	suite.assert.NoError(err)
+	content, _ := os.ReadFile(first)
+	suite.assert.Equal(data, content)
+}
+
+// md5Storage : Storage which returns the md5 sum of content in attributes
+type md5Storage struct {
+	internal.Component
+	path string
+}
+
+func (s *md5Storage) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := s.Component.GetAttr(options)
+	if err == nil && !attr.IsDir() {
+		data, _ := os.ReadFile(filepath.Join(s.path, options.Name))
+		sum := md5.Sum(data)
+		attr.MD5 = sum[:]
+	}
+	return attr, err
+}
+
+func (suite *fileCacheTestSuite) TestDedupLinkedModeAndTimes() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // teardown the default file cache generated
+	dedupPath := suite.fake_storage_path + "_dedup"
+	defer os.RemoveAll(dedupPath)
+
+	data := []byte("shared content")
+	firstTime := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	secondTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err := os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.NoError(err)
+	for name, mtime := range map[string]time.Time{"first": firstTime, "second": secondTime} {
+		err = os.WriteFile(filepath.Join(suite.fake_storage_path, name), data, 0777)
+		suite.assert.NoError(err)
+		err = os.Chtimes(filepath.Join(suite.fake_storage_path, name), mtime, mtime)
+		suite.assert.NoError(err)
+	}
+
+	cfg := fmt.Sprintf("file_cache:\n  path: %s\n  timeout-sec: 60\n  dedup-path: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, dedupPath, suite.fake_storage_path)
+	config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.loopback = newLoopbackFS()
+	suite.fileCache = newTestFileCache(&md5Storage{Component: suite.loopback, path: suite.fake_storage_path})
+	_ = suite.loopback.Start(context.Background())
+	suite.assert.NoError(suite.fileCache.Start(context.Background()))
+
+	for _, name := range []string{"first", "second"} {
+		handle, err := suite.fileCache.OpenFile(internal.OpenFileOptions{Name: name, Flags: os.O_RDONLY, Mode: 0777})
+		suite.assert.NoError(err)
+		suite.assert.NoError(suite.fileCache.CloseFile(internal.CloseFileOptions{Handle: handle}))
+	}
+
+	firstInfo, err := os.Stat(filepath.Join(suite.cache_path, "first"))
+	suite.assert.NoError(err)
+	secondInfo, err := os.Stat(filepath.Join(suite.cache_path, "second"))
+	suite.assert.NoError(err)
+	suite.assert.True(os.SameFile(firstInfo, secondInfo))
+
+	// Opening the linked file does not change times of the shared inode
+	suite.assert.True(firstInfo.ModTime().Equal(firstTime))
+
+	// Each linked file reports its own modified time
+	attr, err := suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "first"})
+	suite.assert.NoError(err)
+	suite.assert.True(attr.Mtime.Equal(firstTime))
+	attr, err = suite.fileCache.GetAttr(internal.GetAttrOptions{Name: "second"})
+	suite.assert.NoError(err)
+	suite.assert.True(attr.Mtime.Equal(secondTime))
+}
+
+func (suite *fileCacheTestSuite) TestDedupConfigError() {
+	defer suite.cleanupTest()
+	configuration := fmt.Sprintf("file_cache:\n  path: %s\n  dedup-path: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, filepath.Join(suite.cache_path, "dedup"), suite.fake_storage_path)
+
+	fileCache := NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err := fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "outside tmp-path")
+
+	configuration = fmt.Sprintf("file_cache:\n  path: %s\n  encryption: true\n  encryption-key: %s\n  dedup-path: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path, base64.StdEncoding.EncodeToString(make([]byte, common.CacheKeySize)), suite.fake_storage_path+"_dedup", suite.fake_storage_path)
+
+	fileCache = NewFileCacheComponent()
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	err = fileCache.Configure(true)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "encryption")
+}
+
 func (suite *fileCacheTestSuite) createLocalDirectoryStructure() {
 	err := os.MkdirAll(filepath.Join(suite.cache_path, "a", "b", "c", "d"), 0777)
 	suite.assert.NoError(err)
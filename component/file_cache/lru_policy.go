@@ -34,6 +34,7 @@
 package file_cache
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -41,6 +42,7 @@ import (
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
 )
 
 type lruNode struct {
@@ -163,6 +165,27 @@ func (p *lruPolicy) UpdateConfig(c cachePolicyConfig) error {
 	p.lowThreshold = c.lowThreshold
 	p.maxEviction = c.maxEviction
 	p.policyTrace = c.policyTrace
+	if c.pinList != nil {
+		p.pinList = c.pinList
+	}
+	if c.rangeMaps != nil {
+		p.rangeMaps = c.rangeMaps
+	}
+	if c.index != nil {
+		p.index = c.index
+	}
+	if c.uploads != nil {
+		p.uploads = c.uploads
+	}
+	if c.offline != nil {
+		p.offline = c.offline
+	}
+	if c.tiers != nil {
+		p.tiers = c.tiers
+	}
+	if c.quotas != nil {
+		p.quotas = c.quotas
+	}
 	return nil
 }
 
@@ -186,6 +209,11 @@ func (p *lruPolicy) CacheInvalidate(name string) {
 	// will be clean so we we need to try deleting the file.
 	_, found := p.nodeMap.Load(name)
 	if p.cacheTimeout == 0 || !found {
+		if p.isPinned(name) {
+			// Pinned files stay in cache even if timeout is 0
+			p.CacheValid(name)
+			return
+		}
 		p.CachePurge(name)
 	}
 }
@@ -289,11 +317,13 @@ func (p *lruPolicy) clearCache() {
 			p.updateMarker()
 			p.printNodes()
 			p.deleteExpiredNodes()
+			p.pruneDedup()
 
 		case <-p.diskUsageMonitor:
 			// File cache timeout has not occurred so just monitor the cache usage
 			cleanupCount := 0
-			pUsage := getUsagePercentage(p.tmpPath, p.maxSizeMB)
+			currSize, pUsage := getCacheUsage(p.tmpPath, p.maxSizeMB)
+			p.updatePinnedUsage(currSize)
 			if pUsage > p.highThreshold {
 				continueDeletion := true
 				for continueDeletion {
@@ -304,6 +334,8 @@ func (p *lruPolicy) clearCache() {
 					p.printNodes()
 					p.deleteExpiredNodes()
 
+					p.pruneDedup()
+
 					pUsage := getUsagePercentage(p.tmpPath, p.maxSizeMB)
 					if pUsage < p.lowThreshold || cleanupCount >= 3 {
 						log.Info("lruPolicy::ClearCache : Threshold stabilized %f > %f", pUsage, p.lowThreshold)
@@ -395,9 +427,9 @@ func (p *lruPolicy) deleteExpiredNodes() {
 		node.prev = nil
 	}
 
+	collected := make([]*lruNode, 0)
 	for ; node != nil && count < p.maxEviction; node = node.next {
-		delItems = append(delItems, node)
-		node.deleted = true
+		collected = append(collected, node)
 		count++
 	}
 
@@ -409,14 +441,45 @@ func (p *lruPolicy) deleteExpiredNodes() {
 	if node != nil {
 		node.prev = p.lastMarker
 	}
+
+	// Collected items are taken out of the list, pinned ones are linked back at head right away so that
+	// pin list changing while the rest are deleted can not leave them out of the list
+	for _, item := range collected {
+		item.prev = nil
+		item.next = nil
+		if p.isPinned(item.name) {
+			item.deleted = false
+			item.next = p.head
+			p.head.prev = item
+			p.head = item
+		} else {
+			item.deleted = true
+			delItems = append(delItems, item)
+		}
+	}
 	p.Unlock()
 
 	log.Debug("lruPolicy::deleteExpiredNodes : List generated %d items", count)
 
 	for _, item := range delItems {
+		if item.deleted && p.isPinned(item.name) {
+			// Pinned after it was collected
+			p.cacheValidate(item.name)
+			continue
+		}
+
 		if item.deleted {
 			p.removeNode(item.name)
 			p.deleteItem(item.name)
+			continue
+		}
+
+		// Item validated again after it was collected is linked back by that, any other skipped item is put back at head
+		p.Lock()
+		linked := item == p.head || item.prev != nil
+		p.Unlock()
+		if !linked {
+			p.cacheValidate(item.name)
 		}
 	}
 
@@ -446,24 +509,146 @@ func (p *lruPolicy) deleteItem(name string) {
 	flock.Lock()
 	defer flock.Unlock()
 
+	// File with a pending upload or offline operation is the only copy of the latest data, it can not be removed
+	if p.uploads.pending(azPath) || p.offline.pending(azPath) {
+		log.Info("lruPolicy::DeleteItem : File has pending upload %s", name)
+		p.CacheValid(name)
+		return
+	}
+
 	// Check if there are any open handles to this file or not
 	if flock.Count() > 0 {
 		log.Warn("lruPolicy::DeleteItem : File in use %s", name)
+		// File can not be removed but if it is cached partially then its unmodified ranges can be dropped
+		p.evictRanges(name, azPath)
 		p.CacheValid(name)
 		return
 	}
 
+	// Move the file to a slower tier instead of removing it, partially cached files are not moved as their ranges are not tracked there
+	if (p.rangeMaps == nil || p.rangeMaps.get(azPath) == nil) && p.tiers.demote(azPath, name) {
+		p.index.remove(azPath)
+		p.quotas.release(azPath)
+		return
+	}
+
 	// There are no open handles for this file so its safe to remove this
 	err := deleteFile(name)
 	if err != nil && !os.IsNotExist(err) {
 		log.Err("lruPolicy::DeleteItem : failed to delete local file %s [%s]", name, err.Error())
 	}
 
+	if p.rangeMaps != nil {
+		p.rangeMaps.remove(azPath)
+	}
+	p.index.remove(azPath)
+	p.quotas.release(azPath)
+
 	// File was deleted so try clearing its parent directory
 	// TODO: Delete directories up the path recursively that are "safe to delete". Ensure there is no race between this code and code that creates directories (like OpenFile)
 	// This might require something like hierarchical locking.
 }
 
+// evictRanges : Release the disk space held by ranges of a partially cached file which are not modified locally
+func (p *lruPolicy) evictRanges(name string, azPath string) {
+	if p.rangeMaps == nil || p.isPinned(name) {
+		return
+	}
+
+	rm := p.rangeMaps.get(azPath)
+	if rm == nil {
+		return
+	}
+
+	rm.Lock()
+	defer rm.Unlock()
+
+	list := rm.evictable()
+	if len(list) == 0 {
+		return
+	}
+
+	f, err := openRangeFile(name)
+	if err != nil {
+		log.Err("lruPolicy::evictRanges : failed to open local file %s [%s]", name, err.Error())
+		return
+	}
+	defer f.Close()
+
+	count := 0
+	for _, idx := range list {
+		start, end := rm.bounds(idx)
+		err = punchRange(f, start, end-start)
+		if err != nil {
+			log.Err("lruPolicy::evictRanges : failed to release range %d of %s [%s]", idx, name, err.Error())
+			break
+		}
+
+		rm.present.clear(idx)
+		count++
+	}
+
+	err = rm.resetTimes(name)
+	if err != nil {
+		log.Err("lruPolicy::evictRanges : failed to change times of file %s [%s]", name, err.Error())
+	}
+
+	log.Info("lruPolicy::evictRanges : Released %d ranges of %s", count, name)
+	fileCacheStatsCollector.UpdateStats(stats_manager.Increment, evRanges, (int64)(count))
+}
+
+// isPinned : Check whether the given local path is pinned in cache
+func (p *lruPolicy) isPinned(name string) bool {
+	if p.pinList == nil {
+		return false
+	}
+
+	return p.pinList.isPinned(strings.TrimPrefix(name, p.tmpPath))
+}
+
+// pruneDedup : Free the content in dedup store which is no longer linked from any cached file
+func (p *lruPolicy) pruneDedup() {
+	if p.dedup == nil {
+		return
+	}
+
+	freed, err := p.dedup.Prune()
+	if err != nil {
+		log.Err("lruPolicy::pruneDedup : failed to prune dedup store [%s]", err.Error())
+		return
+	}
+
+	if freed > 0 {
+		log.Debug("lruPolicy::pruneDedup : freed %d bytes from dedup store", freed)
+		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, dedupFreed, freed)
+	}
+}
+
+// updatePinnedUsage : Report usage of pinned files separately from the usage that eviction can free up
+func (p *lruPolicy) updatePinnedUsage(currSize float64) {
+	if p.pinList == nil || p.pinList.empty() {
+		return
+	}
+
+	var pinned int64
+	p.nodeMap.Range(func(key, value any) bool {
+		node := value.(*lruNode)
+		if !node.deleted && p.isPinned(node.name) {
+			info, err := os.Stat(node.name)
+			if err == nil {
+				pinned += info.Size()
+			}
+		}
+		return true
+	})
+
+	pinnedMB := float64(pinned) / MB
+	log.Debug("lruPolicy::updatePinnedUsage : pinned usage : %f MB", pinnedMB)
+
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, pinUsage, fmt.Sprintf("%f MB", pinnedMB))
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, evictUsage, fmt.Sprintf("%f MB", currSize-pinnedMB))
+}
+
 func (p *lruPolicy) printNodes() {
 	if !p.policyTrace {
 		return
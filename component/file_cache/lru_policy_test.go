@@ -270,6 +270,93 @@ func (suite *lruPolicyTestSuite) TestMaxEviction() {
 	}
 }
 
+func (suite *lruPolicyTestSuite) TestPinnedNotEvicted() {
+	defer suite.cleanupTest()
+	suite.cleanupTest()
+
+	config := cachePolicyConfig{
+		tmpPath:       cache_path,
+		cacheTimeout:  1,
+		maxEviction:   defaultMaxEviction,
+		maxSizeMB:     0,
+		highThreshold: defaultMaxThreshold,
+		lowThreshold:  defaultMinThreshold,
+		fileLocks:     &common.LockMap{},
+		pinList:       newPinList([]string{"pinned"}),
+	}
+
+	suite.setupTestHelper(config)
+
+	pinned := filepath.Join(cache_path, "pinned", "temp")
+	unpinned := filepath.Join(cache_path, "temp")
+	suite.policy.CacheValid(pinned)
+	suite.policy.CacheValid(unpinned)
+
+	time.Sleep(5 * time.Second) // Wait for time > cacheTimeout, only the unpinned file shall be evicted
+
+	suite.assert.True(suite.policy.IsCached(pinned))
+	suite.assert.False(suite.policy.IsCached(unpinned))
+
+	// Unpinned file becomes evictable again
+	suite.policy.pinList.remove("pinned")
+	time.Sleep(5 * time.Second)
+	suite.assert.False(suite.policy.IsCached(pinned))
+}
+
+// Tests pinned items collected for eviction stay in the list and are evicted once unpinned
+func (suite *lruPolicyTestSuite) TestPinnedRelinkedOnExpiry() {
+	defer suite.cleanupTest()
+	suite.cleanupTest()
+
+	config := cachePolicyConfig{
+		tmpPath:       cache_path,
+		cacheTimeout:  3600,
+		maxEviction:   defaultMaxEviction,
+		maxSizeMB:     0,
+		highThreshold: defaultMaxThreshold,
+		lowThreshold:  defaultMinThreshold,
+		fileLocks:     &common.LockMap{},
+		pinList:       newPinList([]string{"pinned"}),
+	}
+	suite.setupTestHelper(config)
+
+	pinned := filepath.Join(cache_path, "pinned", "temp")
+	suite.policy.CacheValid(pinned)
+	val, found := suite.policy.nodeMap.Load(pinned)
+	suite.assert.True(found)
+	node := val.(*lruNode)
+
+	linked := func() bool {
+		suite.policy.Lock()
+		defer suite.policy.Unlock()
+		return node == suite.policy.head || node.prev != nil
+	}
+
+	// Node expires and is collected, it is linked back as it is pinned
+	suite.policy.updateMarker()
+	suite.policy.updateMarker()
+	suite.policy.deleteExpiredNodes()
+	suite.assert.True(suite.policy.IsCached(pinned))
+	suite.assert.True(linked())
+
+	// Once unpinned it is evicted on next expiry
+	suite.policy.pinList.remove("pinned")
+	suite.policy.updateMarker()
+	suite.policy.updateMarker()
+	suite.policy.deleteExpiredNodes()
+	suite.assert.False(suite.policy.IsCached(pinned))
+}
+
+func (suite *lruPolicyTestSuite) TestPinnedNotInvalidated() {
+	defer suite.cleanupTest()
+	suite.policy.pinList = newPinList([]string{"temp"})
+
+	suite.policy.CacheValid(filepath.Join(cache_path, "temp"))
+	suite.policy.CacheInvalidate(filepath.Join(cache_path, "temp")) // timeout is 0 but file is pinned
+
+	suite.assert.True(suite.policy.IsCached(filepath.Join(cache_path, "temp")))
+}
+
 func TestLRUPolicyTestSuite(t *testing.T) {
 	suite.Run(t, new(lruPolicyTestSuite))
 }
@@ -0,0 +1,732 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+const (
+	offlineOpCreate = "create"
+	offlineOpWrite  = "write"
+	offlineOpRename = "rename"
+	offlineOpDelete = "delete"
+	offlineOpDone   = "done"
+)
+
+// offlineOp : One operation applied to local cache while storage was not reachable
+type offlineOp struct {
+	Seq  uint64 `json:"seq"`
+	Op   string `json:"op"`
+	Name string `json:"name,omitempty"`
+	Dst  string `json:"dst,omitempty"`
+
+	// State of the object in storage when the operation was done locally, used to detect conflicts on replay.
+	// For a done record this is the state of the object after the operation was replayed.
+	Exists bool   `json:"exists,omitempty"`
+	ETag   string `json:"etag,omitempty"`
+
+	// Set in a done record if the operation was not replayed due to a conflict
+	Conflict bool `json:"conflict,omitempty"`
+}
+
+// remoteState : State of an object in storage
+type remoteState struct {
+	exists bool
+	etag   string
+}
+
+// matches : Whether the object in storage is the same as the one the operation was done on.
+// If ETag is not known only existence of the object is compared.
+func (s remoteState) matches(cur remoteState) bool {
+	if s.exists != cur.exists {
+		return false
+	}
+	return !s.exists || s.etag == "" || cur.etag == "" || s.etag == cur.etag
+}
+
+// offlineLog : Log of operations applied to local cache while storage was not reachable.
+// Operations are replayed in order once connectivity is restored. Till the log is drained, local cache holds the
+// latest state of the paths in the log and the last known state of storage is used for the rest.
+// A nil log means disconnected mode is disabled and all methods are no-op.
+type offlineLog struct {
+	sync.Mutex
+
+	path    string
+	journal *os.File
+	records int
+	seq     uint64
+	ops     []*offlineOp
+
+	// Number of pending operations referring to each path, such files shall not be evicted from local cache
+	refs map[string]int
+
+	// Changes done by pending operations on top of storage, nil value means path was deleted
+	overlay map[string]*internal.ObjAttr
+
+	// Last known attributes of paths in storage
+	remote map[string]*internal.ObjAttr
+
+	// State of paths in storage after replay of an operation, overrides the state recorded in later operations
+	expected map[string]remoteState
+
+	// Paths for which a replay conflict was detected, later operations on these are not replayed
+	conflicts map[string]bool
+
+	disconnected atomic.Bool
+	interval     time.Duration
+
+	// Method to replay the pending operations, called by the reconnect worker
+	replay func() error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newOfflineLog(path string, interval time.Duration, replay func() error) (*offlineLog, error) {
+	l := &offlineLog{
+		path:      path,
+		refs:      make(map[string]int),
+		overlay:   make(map[string]*internal.ObjAttr),
+		remote:    make(map[string]*internal.ObjAttr),
+		expected:  make(map[string]remoteState),
+		conflicts: make(map[string]bool),
+		interval:  interval,
+		replay:    replay,
+	}
+
+	err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.compact()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(l.ops) > 0 {
+		// Storage is assumed to be unreachable till the pending operations are replayed
+		l.disconnected.Store(true)
+	}
+
+	return l, nil
+}
+
+// isConnectivityError : Whether the error means storage is not reachable
+func isConnectivityError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return true
+	}
+
+	// EIO is not a connectivity error, storage returns it for failures like a blob under lease which replay can not fix.
+	// ENOTCONN is returned by this component for calls which need storage while it is disconnected.
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.ENOTCONN, syscall.ECONNREFUSED, syscall.ECONNRESET,
+			syscall.ENETUNREACH, syscall.EHOSTUNREACH, syscall.ETIMEDOUT:
+			return true
+		}
+		return false
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// load : Rebuild the pending operations from the log left behind by last mount
+func (l *offlineLog) load() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		op := &offlineOp{}
+		if json.Unmarshal(scanner.Bytes(), op) != nil {
+			// Last record may be partially written if the process died, ignore it
+			log.Warn("offlineLog::load : Skipping corrupt record in %s", l.path)
+			continue
+		}
+
+		l.seq = max(l.seq, op.Seq)
+		if op.Op == offlineOpDone {
+			if op.Seq == 0 {
+				// Progress of a replay which was interrupted
+				if op.Conflict {
+					l.conflicts[op.Name] = true
+				} else {
+					l.expected[op.Name] = remoteState{exists: op.Exists, etag: op.ETag}
+				}
+			} else if len(l.ops) > 0 && l.ops[0].Seq == op.Seq {
+				l.complete(op)
+			}
+		} else {
+			l.apply(op)
+		}
+	}
+
+	if len(l.ops) > 0 {
+		log.Info("offlineLog::load : %d operations pending from last mount", len(l.ops))
+	}
+
+	return scanner.Err()
+}
+
+// compact : Rewrite the log with only the pending operations and replay progress, caller shall hold the lock if log is in use
+func (l *offlineLog) compact() error {
+	tmpPath := l.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	records := make([]*offlineOp, 0, len(l.expected)+len(l.conflicts)+len(l.ops))
+	if len(l.ops) > 0 {
+		// Replay progress is carried as done records of an already replayed sequence number
+		for name, state := range l.expected {
+			records = append(records, &offlineOp{Op: offlineOpDone, Name: name, Exists: state.exists, ETag: state.etag})
+		}
+		for name := range l.conflicts {
+			records = append(records, &offlineOp{Op: offlineOpDone, Name: name, Conflict: true})
+		}
+	}
+	records = append(records, l.ops...)
+
+	for _, rec := range records {
+		data, _ := json.Marshal(rec)
+		_, _ = w.Write(append(data, '\n'))
+	}
+
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, l.path)
+	if err != nil {
+		return err
+	}
+
+	if l.journal != nil {
+		_ = l.journal.Close()
+	}
+
+	l.journal, err = os.OpenFile(l.path, os.O_APPEND|os.O_WRONLY, 0644)
+	l.records = len(records)
+	return err
+}
+
+// write : Append a record to the log, caller shall hold the lock
+func (l *offlineLog) write(op *offlineOp) error {
+	data, _ := json.Marshal(op)
+	_, err := l.journal.Write(append(data, '\n'))
+	if err == nil {
+		err = l.journal.Sync()
+	}
+	if err != nil {
+		log.Err("offlineLog::write : Failed to log %s of %s [%s]", op.Op, op.Name, err.Error())
+		return err
+	}
+
+	l.records++
+	if l.records > journalCompactSize && l.records > 2*len(l.ops) {
+		err = l.compact()
+		if err != nil {
+			log.Err("offlineLog::write : Failed to compact log %s [%s]", l.path, err.Error())
+		}
+	}
+	return nil
+}
+
+// apply : Add an operation to the pending list and update the local view of storage, caller shall hold the lock
+func (l *offlineLog) apply(op *offlineOp) {
+	l.ops = append(l.ops, op)
+	l.refs[op.Name]++
+
+	switch op.Op {
+	case offlineOpCreate, offlineOpWrite:
+		delete(l.overlay, op.Name)
+	case offlineOpDelete:
+		l.overlay[op.Name] = nil
+	case offlineOpRename:
+		l.refs[op.Dst]++
+		attr := l.lookup(op.Name)
+		if attr != nil {
+			renamed := *attr
+			renamed.Path = op.Dst
+			renamed.Name = filepath.Base(op.Dst)
+			l.overlay[op.Dst] = &renamed
+		} else {
+			delete(l.overlay, op.Dst)
+		}
+		l.overlay[op.Name] = nil
+	}
+}
+
+// complete : Remove the first pending operation and record the replay progress, caller shall hold the lock
+func (l *offlineLog) complete(done *offlineOp) {
+	op := l.ops[0]
+	l.ops = l.ops[1:]
+
+	for _, name := range []string{op.Name, op.Dst} {
+		if name == "" {
+			continue
+		}
+		l.refs[name]--
+		if l.refs[name] <= 0 {
+			delete(l.refs, name)
+		}
+	}
+
+	if done.Conflict {
+		l.conflicts[op.Name] = true
+		if op.Dst != "" {
+			l.conflicts[op.Dst] = true
+		}
+	} else {
+		l.expected[done.Name] = remoteState{exists: done.Exists, etag: done.ETag}
+		if op.Op == offlineOpRename {
+			l.expected[op.Name] = remoteState{}
+		}
+	}
+
+	if len(l.ops) == 0 {
+		l.overlay = make(map[string]*internal.ObjAttr)
+		l.expected = make(map[string]remoteState)
+		l.conflicts = make(map[string]bool)
+	}
+}
+
+// lookup : Attributes of the path as per the pending operations or the last known state of storage, caller shall hold the lock
+func (l *offlineLog) lookup(name string) *internal.ObjAttr {
+	if attr, found := l.overlay[name]; found {
+		return attr
+	}
+	return l.remote[name]
+}
+
+// record : Log an operation done on local cache while storage is not reachable
+func (l *offlineLog) record(op string, name string, dst string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	rec := &offlineOp{Op: op, Name: name, Dst: dst}
+	if attr := l.lookup(name); attr != nil {
+		rec.Exists = true
+		rec.ETag = attr.ETag
+	}
+
+	l.seq++
+	rec.Seq = l.seq
+	err := l.write(rec)
+	if err != nil {
+		return err
+	}
+
+	l.apply(rec)
+	log.Info("offlineLog::record : %s of %s logged for replay, %d operations pending", op, name, len(l.ops))
+	l.updateStats()
+	return nil
+}
+
+// pendingOps : Operations yet to be replayed
+func (l *offlineLog) pendingOps() []*offlineOp {
+	if l == nil {
+		return nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	return append([]*offlineOp(nil), l.ops...)
+}
+
+// baseState : State of the path in storage the operation shall be validated against before replay.
+// If an earlier operation on the path is already replayed then it is the state after that replay.
+func (l *offlineLog) baseState(op *offlineOp) remoteState {
+	l.Lock()
+	defer l.Unlock()
+
+	if state, found := l.expected[op.Name]; found {
+		return state
+	}
+	return remoteState{exists: op.Exists, etag: op.ETag}
+}
+
+// conflicted : Whether an earlier operation on any of the paths was not replayed due to a conflict
+func (l *offlineLog) conflicted(names ...string) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	for _, name := range names {
+		if l.conflicts[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// replayed : Record that the first pending operation was replayed, state is the state of target path after replay
+func (l *offlineLog) replayed(op *offlineOp, target string, state remoteState, conflict bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	if len(l.ops) == 0 || l.ops[0].Seq != op.Seq {
+		return
+	}
+
+	done := &offlineOp{Seq: op.Seq, Op: offlineOpDone, Name: target, Exists: state.exists, ETag: state.etag, Conflict: conflict}
+	_ = l.write(done)
+	l.complete(done)
+
+	if len(l.ops) == 0 {
+		// Nothing to carry forward, start with a fresh log
+		err := l.compact()
+		if err != nil {
+			log.Err("offlineLog::replayed : Failed to compact log %s [%s]", l.path, err.Error())
+		}
+	}
+
+	if conflict {
+		fileCacheStatsCollector.UpdateStats(stats_manager.Increment, offlineConflicts, (int64)(1))
+	}
+	l.updateStats()
+}
+
+// conflictList : Paths for which conflicts were detected in the current replay
+func (l *offlineLog) conflictList() []string {
+	l.Lock()
+	defer l.Unlock()
+
+	names := make([]string, 0, len(l.conflicts))
+	for name := range l.conflicts {
+		names = append(names, name)
+	}
+	return names
+}
+
+// bypass : Whether storage shall not be updated directly, either it is not reachable or earlier operations are yet to be replayed
+func (l *offlineLog) bypass() bool {
+	if l == nil {
+		return false
+	}
+
+	if l.disconnected.Load() {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	return len(l.ops) > 0
+}
+
+// isDisconnected : Whether storage is known to be unreachable
+func (l *offlineLog) isDisconnected() bool {
+	return l != nil && l.disconnected.Load()
+}
+
+// failed : Check the error returned by storage and switch to disconnected mode if storage is not reachable
+func (l *offlineLog) failed(err error) bool {
+	if l == nil || !isConnectivityError(err) {
+		return false
+	}
+
+	if !l.disconnected.Swap(true) {
+		log.Warn("offlineLog::failed : Storage is not reachable, switching to disconnected mode [%s]", err.Error())
+	}
+	return true
+}
+
+// observe : Record the attributes of paths as returned by storage
+func (l *offlineLog) observe(attrs ...*internal.ObjAttr) {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	for _, attr := range attrs {
+		if attr != nil {
+			l.remote[attr.Path] = attr
+		}
+	}
+}
+
+// forget : Path no longer exists in storage
+func (l *offlineLog) forget(names ...string) {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	for _, name := range names {
+		delete(l.remote, name)
+	}
+}
+
+// get : Attributes of the path as per the pending operations or the last known state of storage.
+// Second value is false if nothing is known about the path.
+func (l *offlineLog) get(name string) (*internal.ObjAttr, bool) {
+	if l == nil {
+		return nil, false
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	attr, found := l.overlay[name]
+	if !found {
+		attr, found = l.remote[name]
+	}
+
+	if attr == nil {
+		return nil, found
+	}
+
+	c := *attr
+	return &c, true
+}
+
+// pendingState : Attributes of the path if it was changed by a pending operation
+func (l *offlineLog) pendingState(name string) (*internal.ObjAttr, bool) {
+	if l == nil {
+		return nil, false
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	attr, found := l.overlay[name]
+	if attr == nil {
+		return nil, found
+	}
+
+	c := *attr
+	return &c, true
+}
+
+// listing : Children of a directory as per the last known state of storage
+func (l *offlineLog) listing(dir string) []*internal.ObjAttr {
+	if l == nil {
+		return nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	attrs := make([]*internal.ObjAttr, 0)
+	for path, attr := range l.remote {
+		if inDir(dir, path) {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// inDir : Whether the path is a direct child of the directory
+func inDir(dir string, path string) bool {
+	parent := filepath.Dir(path)
+	return parent == filepath.Clean(dir) || (parent == "." && (dir == "" || dir == "/"))
+}
+
+// merge : Apply the pending operations on a listing of a directory.
+// Paths created by pending operations are added only to the last page of a listing.
+func (l *offlineLog) merge(dir string, attrs []*internal.ObjAttr, last bool) []*internal.ObjAttr {
+	if l == nil {
+		return attrs
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	merged := make([]*internal.ObjAttr, 0, len(attrs))
+	listed := make(map[string]bool)
+	for _, attr := range attrs {
+		if pending, found := l.overlay[attr.Path]; found && pending == nil {
+			continue
+		}
+		listed[attr.Path] = true
+		merged = append(merged, attr)
+	}
+
+	if last {
+		for path, attr := range l.overlay {
+			if attr != nil && !listed[path] && inDir(dir, path) {
+				merged = append(merged, attr)
+			}
+		}
+	}
+
+	return merged
+}
+
+// pending : Whether the path is referred by a pending operation, such files shall not be evicted from local cache
+func (l *offlineLog) pending(name string) bool {
+	if l == nil {
+		return false
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	return l.refs[name] > 0
+}
+
+// list : Paths referred by pending operations
+func (l *offlineLog) list() []string {
+	if l == nil {
+		return nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	names := make([]string, 0, len(l.refs))
+	for name := range l.refs {
+		names = append(names, name)
+	}
+	return names
+}
+
+// updateStats : Publish the state of the log, caller shall hold the lock
+func (l *offlineLog) updateStats() {
+	if l.ctx == nil {
+		// Log is not started yet
+		return
+	}
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, offlineOpsPending, (int64)(len(l.ops)))
+}
+
+// start : Start the worker which replays the log once storage is reachable again
+func (l *offlineLog) start() {
+	if l == nil {
+		return
+	}
+
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	l.wg.Add(1)
+	go l.reconnectWorker()
+
+	l.Lock()
+	l.updateStats()
+	l.Unlock()
+}
+
+// stop : Stop the reconnect worker, pending operations stay in the log for next mount
+func (l *offlineLog) stop() {
+	if l == nil {
+		return
+	}
+
+	if l.cancel != nil {
+		l.cancel()
+		l.wg.Wait()
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if len(l.ops) > 0 {
+		log.Info("offlineLog::stop : %d operations pending, these will be replayed on next mount", len(l.ops))
+	}
+	_ = l.journal.Close()
+}
+
+// reconnectWorker : Periodically replay the log while storage is not reachable or operations are pending
+func (l *offlineLog) reconnectWorker() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !l.bypass() {
+			continue
+		}
+
+		err := l.replay()
+		if err != nil {
+			log.Debug("offlineLog::reconnectWorker : Storage still not reachable [%s]", err.Error())
+			continue
+		}
+
+		if l.disconnected.Swap(false) {
+			log.Info("offlineLog::reconnectWorker : Storage is reachable again, disconnected mode ended")
+		}
+	}
+}
@@ -0,0 +1,220 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type offlineLogTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	path   string
+}
+
+func (suite *offlineLogTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.path = filepath.Join(suite.T().TempDir(), "offline.log")
+}
+
+func (suite *offlineLogTestSuite) newLog() *offlineLog {
+	l, err := newOfflineLog(suite.path, time.Second, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(l)
+	return l
+}
+
+func (suite *offlineLogTestSuite) TestNilLog() {
+	var l *offlineLog = nil
+	suite.assert.NoError(l.record(offlineOpWrite, "a", ""))
+	suite.assert.False(l.bypass())
+	suite.assert.False(l.isDisconnected())
+	suite.assert.False(l.failed(syscall.EIO))
+	suite.assert.False(l.pending("a"))
+	suite.assert.Empty(l.pendingOps())
+	l.observe(&internal.ObjAttr{Path: "a"})
+	l.forget("a")
+	l.start()
+	l.stop()
+
+	_, found := l.get("a")
+	suite.assert.False(found)
+	attrs := []*internal.ObjAttr{{Path: "a"}}
+	suite.assert.Equal(attrs, l.merge("", attrs, true))
+}
+
+func (suite *offlineLogTestSuite) TestConnectivityError() {
+	suite.assert.False(isConnectivityError(nil))
+	suite.assert.False(isConnectivityError(syscall.ENOENT))
+	suite.assert.False(isConnectivityError(syscall.EACCES))
+	suite.assert.False(isConnectivityError(syscall.EIO))
+	suite.assert.True(isConnectivityError(syscall.ETIMEDOUT))
+	suite.assert.True(isConnectivityError(context.DeadlineExceeded))
+	suite.assert.True(isConnectivityError(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}))
+	suite.assert.True(isConnectivityError(&net.DNSError{Err: "no such host", IsTimeout: true}))
+}
+
+func (suite *offlineLogTestSuite) TestDisconnect() {
+	l := suite.newLog()
+	suite.assert.False(l.failed(errors.New("some error")))
+	suite.assert.False(l.bypass())
+
+	suite.assert.False(l.failed(syscall.EIO))
+	suite.assert.False(l.bypass())
+
+	suite.assert.True(l.failed(syscall.ECONNRESET))
+	suite.assert.True(l.isDisconnected())
+	suite.assert.True(l.bypass())
+	l.stop()
+}
+
+func (suite *offlineLogTestSuite) TestRecord() {
+	l := suite.newLog()
+	l.observe(&internal.ObjAttr{Path: "dir/a", Name: "a", ETag: "e1"}, &internal.ObjAttr{Path: "dir/b", Name: "b", ETag: "e2"})
+
+	suite.assert.NoError(l.record(offlineOpWrite, "dir/a", ""))
+	suite.assert.NoError(l.record(offlineOpRename, "dir/a", "dir/c"))
+	suite.assert.NoError(l.record(offlineOpDelete, "dir/b", ""))
+	suite.assert.NoError(l.record(offlineOpCreate, "dir/d", ""))
+	suite.assert.True(l.bypass())
+
+	ops := l.pendingOps()
+	suite.assert.Len(ops, 4)
+	suite.assert.True(ops[0].Exists)
+	suite.assert.Equal("e1", ops[0].ETag)
+	suite.assert.False(ops[3].Exists)
+
+	suite.assert.True(l.pending("dir/a"))
+	suite.assert.True(l.pending("dir/c"))
+	suite.assert.False(l.pending("dir/x"))
+
+	// Renamed path carries the attributes of the source and deleted paths are hidden
+	attr, found := l.get("dir/c")
+	suite.assert.True(found)
+	suite.assert.Equal("c", attr.Name)
+	attr, found = l.get("dir/b")
+	suite.assert.True(found)
+	suite.assert.Nil(attr)
+	_, found = l.get("dir/x")
+	suite.assert.False(found)
+
+	attrs := l.merge("dir", l.listing("dir"), true)
+	suite.assert.Len(attrs, 1)
+	suite.assert.Equal("dir/c", attrs[0].Path)
+	l.stop()
+
+	// Pending operations are loaded back on next mount
+	l = suite.newLog()
+	suite.assert.True(l.isDisconnected())
+	suite.assert.Len(l.pendingOps(), 4)
+	suite.assert.True(l.pending("dir/c"))
+	attr, found = l.get("dir/b")
+	suite.assert.True(found)
+	suite.assert.Nil(attr)
+	l.stop()
+}
+
+func (suite *offlineLogTestSuite) TestReplayProgress() {
+	l := suite.newLog()
+	suite.assert.NoError(l.record(offlineOpWrite, "a", ""))
+	suite.assert.NoError(l.record(offlineOpWrite, "b", ""))
+	suite.assert.NoError(l.record(offlineOpWrite, "a", ""))
+
+	ops := l.pendingOps()
+	l.replayed(ops[0], "a", remoteState{exists: true, etag: "e1"}, false)
+	l.replayed(ops[1], "b", remoteState{}, true)
+
+	// Replaying an operation out of order is ignored
+	l.replayed(ops[0], "a", remoteState{}, false)
+	suite.assert.Len(l.pendingOps(), 1)
+	l.stop()
+
+	// Progress of an interrupted replay is retained
+	l = suite.newLog()
+	ops = l.pendingOps()
+	suite.assert.Len(ops, 1)
+	suite.assert.Equal(remoteState{exists: true, etag: "e1"}, l.baseState(ops[0]))
+	suite.assert.True(l.conflicted("b"))
+	suite.assert.False(l.conflicted("a"))
+
+	l.replayed(ops[0], "a", remoteState{exists: true, etag: "e2"}, false)
+	suite.assert.Empty(l.pendingOps())
+	suite.assert.False(l.conflicted("b"))
+	suite.assert.False(l.pending("a"))
+	l.stop()
+
+	l = suite.newLog()
+	suite.assert.Empty(l.pendingOps())
+	suite.assert.False(l.isDisconnected())
+	l.stop()
+}
+
+func (suite *offlineLogTestSuite) TestStateMatches() {
+	absent := remoteState{}
+	suite.assert.True(absent.matches(remoteState{}))
+	suite.assert.False(absent.matches(remoteState{exists: true}))
+	suite.assert.True(remoteState{exists: true, etag: "e1"}.matches(remoteState{exists: true, etag: "e1"}))
+	suite.assert.False(remoteState{exists: true, etag: "e1"}.matches(remoteState{exists: true, etag: "e2"}))
+	suite.assert.True(remoteState{exists: true}.matches(remoteState{exists: true, etag: "e2"}))
+}
+
+func (suite *offlineLogTestSuite) TestFollowPath() {
+	ops := []*offlineOp{
+		{Op: offlineOpWrite, Name: "a"},
+		{Op: offlineOpRename, Name: "a", Dst: "b"},
+		{Op: offlineOpRename, Name: "b", Dst: "c"},
+	}
+	suite.assert.Equal("c", followPath(ops, "a"))
+	suite.assert.Equal("x", followPath(ops, "x"))
+
+	ops = append(ops, &offlineOp{Op: offlineOpDelete, Name: "c"})
+	suite.assert.Equal("", followPath(ops, "a"))
+
+	ops = []*offlineOp{{Op: offlineOpRename, Name: "x", Dst: "a"}}
+	suite.assert.Equal("", followPath(ops, "a"))
+}
+
+func TestOfflineLog(t *testing.T) {
+	suite.Run(t, new(offlineLogTestSuite))
+}
@@ -0,0 +1,168 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// pinList : Paths and glob patterns which shall never be evicted from the cache.
+// A pattern pins the path it matches and, if that path is a directory, everything under it.
+type pinList struct {
+	sync.RWMutex
+	patterns []string
+}
+
+func newPinList(patterns []string) *pinList {
+	pl := &pinList{}
+	pl.set(patterns)
+	return pl
+}
+
+func normalizePinPattern(pattern string) string {
+	return strings.Trim(filepath.ToSlash(filepath.Clean(pattern)), "/")
+}
+
+// set : Replace the complete pin list
+func (pl *pinList) set(patterns []string) {
+	list := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = normalizePinPattern(pattern)
+		if pattern != "" && pattern != "." {
+			list = append(list, pattern)
+		}
+	}
+
+	pl.Lock()
+	pl.patterns = list
+	pl.Unlock()
+}
+
+// add : Pin a new path or pattern, returns false if it was already pinned
+func (pl *pinList) add(pattern string) bool {
+	pattern = normalizePinPattern(pattern)
+	if pattern == "" || pattern == "." {
+		return false
+	}
+
+	pl.Lock()
+	defer pl.Unlock()
+
+	for _, p := range pl.patterns {
+		if p == pattern {
+			return false
+		}
+	}
+
+	pl.patterns = append(pl.patterns, pattern)
+	return true
+}
+
+// remove : Unpin a path or pattern, returns false if it was not pinned
+func (pl *pinList) remove(pattern string) bool {
+	pattern = normalizePinPattern(pattern)
+
+	pl.Lock()
+	defer pl.Unlock()
+
+	for i, p := range pl.patterns {
+		if p == pattern {
+			pl.patterns = append(pl.patterns[:i], pl.patterns[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// list : Get a copy of current pin list
+func (pl *pinList) list() []string {
+	pl.RLock()
+	defer pl.RUnlock()
+
+	return append([]string{}, pl.patterns...)
+}
+
+func (pl *pinList) empty() bool {
+	pl.RLock()
+	defer pl.RUnlock()
+
+	return len(pl.patterns) == 0
+}
+
+// isPinned : Check whether the given path (relative to mount root) or any of its parent directories is pinned
+func (pl *pinList) isPinned(path string) bool {
+	path = strings.Trim(filepath.ToSlash(path), "/")
+	if path == "" {
+		return false
+	}
+
+	pl.RLock()
+	defer pl.RUnlock()
+
+	for _, pattern := range pl.patterns {
+		glob := strings.ContainsAny(pattern, "*?[")
+		for candidate := path; candidate != "." && candidate != ""; candidate = filepath.Dir(candidate) {
+			if glob {
+				if matched, _ := filepath.Match(pattern, candidate); matched {
+					return true
+				}
+			} else if candidate == pattern {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// pinListRoot : Non glob parent directory of a pattern, used to list the container while pre-downloading pinned files
+func pinListRoot(pattern string) string {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return pattern
+	}
+
+	parts := strings.Split(pattern, "/")
+	root := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			break
+		}
+		root = append(root, part)
+	}
+
+	return strings.Join(root, "/")
+}
@@ -0,0 +1,112 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type pinListTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *pinListTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *pinListTestSuite) TestEmpty() {
+	pl := newPinList(nil)
+	suite.assert.True(pl.empty())
+	suite.assert.False(pl.isPinned("a.txt"))
+
+	pl = newPinList([]string{"", "/", "."})
+	suite.assert.True(pl.empty())
+}
+
+func (suite *pinListTestSuite) TestFileAndDirectory() {
+	pl := newPinList([]string{"ref/model.bin", "/datasets/imagenet/"})
+	suite.assert.ElementsMatch([]string{"ref/model.bin", "datasets/imagenet"}, pl.list())
+
+	suite.assert.True(pl.isPinned("ref/model.bin"))
+	suite.assert.True(pl.isPinned("/ref/model.bin"))
+	suite.assert.False(pl.isPinned("ref/model.bin2"))
+	suite.assert.False(pl.isPinned("ref"))
+
+	// Directories are pinned recursively
+	suite.assert.True(pl.isPinned("datasets/imagenet/train/0001.jpg"))
+	suite.assert.True(pl.isPinned("datasets/imagenet"))
+	suite.assert.False(pl.isPinned("datasets/imagenet2/a.jpg"))
+	suite.assert.False(pl.isPinned("datasets"))
+}
+
+func (suite *pinListTestSuite) TestGlob() {
+	pl := newPinList([]string{"ref/*.csv", "data/shard-*"})
+
+	suite.assert.True(pl.isPinned("ref/a.csv"))
+	suite.assert.False(pl.isPinned("ref/a.txt"))
+	suite.assert.False(pl.isPinned("ref/sub/a.csv"))
+
+	// Glob matching a directory pins everything under it
+	suite.assert.True(pl.isPinned("data/shard-01/part-0"))
+	suite.assert.True(pl.isPinned("data/shard-01"))
+	suite.assert.False(pl.isPinned("data/other/part-0"))
+}
+
+func (suite *pinListTestSuite) TestAddRemove() {
+	pl := newPinList(nil)
+
+	suite.assert.True(pl.add("a/b"))
+	suite.assert.False(pl.add("/a/b/"))
+	suite.assert.True(pl.isPinned("a/b/c"))
+
+	suite.assert.False(pl.remove("a"))
+	suite.assert.True(pl.remove("a/b/"))
+	suite.assert.False(pl.isPinned("a/b/c"))
+	suite.assert.True(pl.empty())
+}
+
+func (suite *pinListTestSuite) TestListRoot() {
+	suite.assert.Equal("a/b", pinListRoot("a/b"))
+	suite.assert.Equal("a", pinListRoot("a/*.csv"))
+	suite.assert.Equal("a/b", pinListRoot("a/b/c-?/d"))
+	suite.assert.Equal("", pinListRoot("*.csv"))
+}
+
+func TestPinListTestSuite(t *testing.T) {
+	suite.Run(t, new(pinListTestSuite))
+}
@@ -0,0 +1,345 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+const (
+	// fallocate flags to release the disk space held by a range of a sparse file
+	fallocKeepSize  = 0x01
+	fallocPunchHole = 0x02
+)
+
+// bitmap : Fixed width bit set, one bit per range of a file
+type bitmap []uint64
+
+func newBitmap(count int64) bitmap {
+	return make(bitmap, (count+63)/64)
+}
+
+func (b *bitmap) grow(count int64) {
+	if need := int((count + 63) / 64); need > len(*b) {
+		*b = append(*b, make([]uint64, need-len(*b))...)
+	}
+}
+
+func (b bitmap) set(idx int64) {
+	b[idx/64] |= 1 << (uint64(idx) % 64)
+}
+
+func (b bitmap) clear(idx int64) {
+	if int(idx/64) < len(b) {
+		b[idx/64] &^= 1 << (uint64(idx) % 64)
+	}
+}
+
+func (b bitmap) isSet(idx int64) bool {
+	if idx < 0 || int(idx/64) >= len(b) {
+		return false
+	}
+	return b[idx/64]&(1<<(uint64(idx)%64)) != 0
+}
+
+func (b bitmap) any() bool {
+	for _, w := range b {
+		if w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeMap : Tracks which ranges of a sparse local file are downloaded and which are modified locally
+// Callers must hold the lock of the map while using it.
+type rangeMap struct {
+	sync.RWMutex
+
+	rangeSize  int64     // Size of each range
+	size       int64     // Current size of the local file
+	remoteSize int64     // Size of the data in storage which backs the local file
+	mtime      time.Time // Last modified time of the file in storage
+	etag       string    // ETag of the file in storage which backs the local file, empty till it is known
+	stale      bool      // File changed in storage after it was opened, its ranges can no longer be downloaded
+
+	present bitmap // Ranges available in the local file
+	dirty   bitmap // Ranges modified locally and not yet uploaded
+}
+
+func newRangeMap(size int64, rangeSize int64, mtime time.Time, etag string) *rangeMap {
+	rm := &rangeMap{
+		rangeSize:  rangeSize,
+		size:       size,
+		remoteSize: size,
+		mtime:      mtime,
+		etag:       etag,
+	}
+
+	rm.present = newBitmap(rm.count())
+	rm.dirty = newBitmap(rm.count())
+	return rm
+}
+
+// count : Number of ranges needed to hold the current size of the file
+func (rm *rangeMap) count() int64 {
+	return (rm.size + rm.rangeSize - 1) / rm.rangeSize
+}
+
+// bounds : Start and end offset of the given range
+func (rm *rangeMap) bounds(idx int64) (int64, int64) {
+	start := idx * rm.rangeSize
+	return start, min(start+rm.rangeSize, rm.size)
+}
+
+// missing : Ranges overlapping the given region which are not yet downloaded
+func (rm *rangeMap) missing(offset int64, length int64) []int64 {
+	end := min(offset+length, rm.size)
+	if offset >= end {
+		return nil
+	}
+
+	list := make([]int64, 0)
+	for idx := offset / rm.rangeSize; idx <= (end-1)/rm.rangeSize; idx++ {
+		if !rm.present.isSet(idx) && idx*rm.rangeSize < rm.remoteSize {
+			list = append(list, idx)
+		}
+	}
+	return list
+}
+
+// missingForWrite : Ranges which need to be downloaded before the given region can be written
+// Ranges which are completely overwritten do not need their old contents.
+func (rm *rangeMap) missingForWrite(offset int64, length int64) []int64 {
+	end := offset + length
+	touched := make([]int64, 0)
+	if length > 0 {
+		for idx := offset / rm.rangeSize; idx <= (end-1)/rm.rangeSize; idx++ {
+			touched = append(touched, idx)
+		}
+	}
+
+	// Writing beyond end of file zero fills the range holding the current end of file
+	if offset > rm.size && rm.size%rm.rangeSize != 0 {
+		touched = append(touched, rm.size/rm.rangeSize)
+	}
+
+	list := make([]int64, 0)
+	for _, idx := range touched {
+		start := idx * rm.rangeSize
+		if rm.present.isSet(idx) || start >= rm.remoteSize {
+			continue
+		}
+
+		if offset <= start && end >= min(start+rm.rangeSize, rm.remoteSize) {
+			// Entire remote contents of this range are overwritten
+			continue
+		}
+		list = append(list, idx)
+	}
+	return list
+}
+
+// resize : Update the size of the file, ranges added at the end hold zeros and are marked modified
+func (rm *rangeMap) resize(size int64) {
+	oldCount := rm.count()
+	rm.size = size
+
+	newCount := rm.count()
+	rm.present.grow(newCount)
+	rm.dirty.grow(newCount)
+
+	for idx := oldCount; idx < newCount; idx++ {
+		rm.present.set(idx)
+		rm.dirty.set(idx)
+	}
+
+	for idx := newCount; idx < oldCount; idx++ {
+		rm.present.clear(idx)
+		rm.dirty.clear(idx)
+	}
+
+	rm.remoteSize = min(rm.remoteSize, size)
+}
+
+// written : Mark the ranges overlapping the given region as available and modified
+func (rm *rangeMap) written(offset int64, length int64) {
+	if length <= 0 {
+		return
+	}
+
+	if offset+length > rm.size {
+		rm.resize(offset + length)
+	}
+
+	for idx := offset / rm.rangeSize; idx <= (offset+length-1)/rm.rangeSize; idx++ {
+		rm.present.set(idx)
+		rm.dirty.set(idx)
+	}
+}
+
+// uploaded : Local file is now in sync with storage version having the given etag
+func (rm *rangeMap) uploaded(etag string) {
+	rm.dirty = newBitmap(rm.count())
+	rm.remoteSize = rm.size
+	rm.mtime = time.Now()
+	rm.etag = etag
+}
+
+// matches : Check if data downloaded with the given etag belongs to the version of the file backing the map.
+// First etag seen is recorded if it was not known when the map was created.
+func (rm *rangeMap) matches(etag string) bool {
+	if etag == "" {
+		return true
+	}
+
+	if rm.etag == "" {
+		rm.etag = etag
+	}
+	return rm.etag == etag
+}
+
+// resetTimes : Downloading or dropping ranges shall not change the modified time of the local file
+func (rm *rangeMap) resetTimes(localPath string) error {
+	if rm.dirty.any() || rm.mtime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(localPath, time.Now(), rm.mtime)
+}
+
+// evictable : Ranges which are downloaded and can be dropped from local file
+func (rm *rangeMap) evictable() []int64 {
+	list := make([]int64, 0)
+	for idx := int64(0); idx < rm.count(); idx++ {
+		if rm.present.isSet(idx) && !rm.dirty.isSet(idx) && idx*rm.rangeSize < rm.remoteSize {
+			list = append(list, idx)
+		}
+	}
+	return list
+}
+
+// modified : Whether any range overlapping the given region is modified locally
+func (rm *rangeMap) modified(start int64, end int64) bool {
+	for idx := start / rm.rangeSize; idx*rm.rangeSize < end; idx++ {
+		if rm.dirty.isSet(idx) {
+			return true
+		}
+	}
+	return false
+}
+
+// aligned : Whether the committed blocks of the file in storage match the ranges of this map
+func (rm *rangeMap) aligned(list internal.CommittedBlockList) bool {
+	for idx, block := range list {
+		if block.Offset != int64(idx)*rm.rangeSize {
+			return false
+		}
+
+		if (idx < len(list)-1 && int64(block.Size) != rm.rangeSize) || int64(block.Size) > rm.rangeSize {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeMapList : Range maps of all the files which are cached partially, keyed on file name
+type rangeMapList struct {
+	files sync.Map
+}
+
+func newRangeMapList() *rangeMapList {
+	return &rangeMapList{}
+}
+
+func (rl *rangeMapList) get(name string) *rangeMap {
+	val, found := rl.files.Load(trimRangeName(name))
+	if !found {
+		return nil
+	}
+	return val.(*rangeMap)
+}
+
+func (rl *rangeMapList) set(name string, rm *rangeMap) {
+	rl.files.Store(trimRangeName(name), rm)
+}
+
+func (rl *rangeMapList) remove(name string) {
+	rl.files.Delete(trimRangeName(name))
+}
+
+func (rl *rangeMapList) rename(src string, dst string) {
+	val, found := rl.files.LoadAndDelete(trimRangeName(src))
+	if found {
+		rl.files.Store(trimRangeName(dst), val)
+	} else {
+		rl.files.Delete(trimRangeName(dst))
+	}
+}
+
+func trimRangeName(name string) string {
+	return strings.Trim(name, "/")
+}
+
+// openRangeFile : Open local file for writing ranges, even if its mode does not allow writes
+func openRangeFile(localPath string) (*os.File, error) {
+	f, err := os.OpenFile(localPath, os.O_WRONLY, 0)
+	if err == nil || !os.IsPermission(err) {
+		return f, err
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(localPath, info.Mode()|0200)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err = os.OpenFile(localPath, os.O_WRONLY, 0)
+	_ = os.Chmod(localPath, info.Mode())
+	return f, err
+}
+
+// punchRange : Release the disk space held by the given region of a sparse file
+func punchRange(f *os.File, offset int64, length int64) error {
+	return syscall.Fallocate(int(f.Fd()), fallocPunchHole|fallocKeepSize, offset, length)
+}
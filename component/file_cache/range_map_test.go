@@ -0,0 +1,202 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type rangeMapTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *rangeMapTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *rangeMapTestSuite) TestBitmap() {
+	b := newBitmap(10)
+	suite.assert.Len(b, 1)
+	suite.assert.False(b.any())
+
+	b.set(3)
+	suite.assert.True(b.isSet(3))
+	suite.assert.False(b.isSet(4))
+	suite.assert.False(b.isSet(100))
+	suite.assert.True(b.any())
+
+	b.grow(130)
+	suite.assert.Len(b, 3)
+	b.set(129)
+	suite.assert.True(b.isSet(129))
+
+	b.clear(3)
+	b.clear(129)
+	b.clear(500)
+	suite.assert.False(b.any())
+}
+
+func (suite *rangeMapTestSuite) TestMissing() {
+	rm := newRangeMap(35, 10, time.Now(), "")
+	suite.assert.EqualValues(4, rm.count())
+
+	suite.assert.Equal([]int64{0, 1}, rm.missing(5, 10))
+	suite.assert.Equal([]int64{3}, rm.missing(30, 100))
+	suite.assert.Empty(rm.missing(35, 10))
+
+	rm.present.set(1)
+	suite.assert.Equal([]int64{0, 2}, rm.missing(0, 25))
+
+	start, end := rm.bounds(3)
+	suite.assert.EqualValues(30, start)
+	suite.assert.EqualValues(35, end)
+}
+
+func (suite *rangeMapTestSuite) TestMissingForWrite() {
+	rm := newRangeMap(35, 10, time.Now(), "")
+
+	// Partially overwritten ranges need their old contents
+	suite.assert.Equal([]int64{0, 1}, rm.missingForWrite(5, 10))
+
+	// Completely overwritten ranges do not
+	suite.assert.Empty(rm.missingForWrite(10, 10))
+	suite.assert.Equal([]int64{1}, rm.missingForWrite(0, 15))
+
+	// Last range is overwritten completely once all remote data is covered
+	suite.assert.Empty(rm.missingForWrite(30, 10))
+
+	// Writing after end of file needs the range holding the end of file
+	suite.assert.Equal([]int64{3}, rm.missingForWrite(50, 5))
+}
+
+func (suite *rangeMapTestSuite) TestWrittenAndResize() {
+	rm := newRangeMap(35, 10, time.Now(), "")
+
+	rm.written(12, 3)
+	suite.assert.True(rm.present.isSet(1))
+	suite.assert.True(rm.dirty.isSet(1))
+	suite.assert.EqualValues(35, rm.size)
+
+	// Extending the file adds modified ranges
+	rm.written(50, 5)
+	suite.assert.EqualValues(55, rm.size)
+	suite.assert.EqualValues(6, rm.count())
+	suite.assert.True(rm.present.isSet(4))
+	suite.assert.True(rm.dirty.isSet(5))
+	suite.assert.False(rm.present.isSet(3))
+	suite.assert.EqualValues(35, rm.remoteSize)
+
+	// Shrinking the file drops the ranges
+	rm.resize(15)
+	suite.assert.EqualValues(2, rm.count())
+	suite.assert.False(rm.dirty.isSet(4))
+	suite.assert.EqualValues(15, rm.remoteSize)
+
+	rm.uploaded("")
+	suite.assert.False(rm.dirty.any())
+	suite.assert.True(rm.present.isSet(1))
+}
+
+func (suite *rangeMapTestSuite) TestEvictable() {
+	rm := newRangeMap(35, 10, time.Now(), "")
+	rm.present.set(0)
+	rm.present.set(2)
+	rm.written(20, 1)
+	rm.written(40, 1)
+
+	suite.assert.Equal([]int64{0}, rm.evictable())
+}
+
+func (suite *rangeMapTestSuite) TestAligned() {
+	rm := newRangeMap(35, 10, time.Now(), "")
+
+	suite.assert.True(rm.aligned(internal.CommittedBlockList{}))
+	suite.assert.True(rm.aligned(internal.CommittedBlockList{
+		{Id: "a", Offset: 0, Size: 10},
+		{Id: "b", Offset: 10, Size: 10},
+		{Id: "c", Offset: 20, Size: 5},
+	}))
+	suite.assert.False(rm.aligned(internal.CommittedBlockList{
+		{Id: "a", Offset: 0, Size: 5},
+		{Id: "b", Offset: 5, Size: 10},
+	}))
+	suite.assert.False(rm.aligned(internal.CommittedBlockList{
+		{Id: "a", Offset: 0, Size: 20},
+	}))
+}
+
+func (suite *rangeMapTestSuite) TestList() {
+	rl := newRangeMapList()
+	rm := newRangeMap(35, 10, time.Now(), "")
+
+	rl.set("/dir/a", rm)
+	suite.assert.Equal(rm, rl.get("dir/a"))
+
+	rl.rename("dir/a", "dir/b")
+	suite.assert.Nil(rl.get("dir/a"))
+	suite.assert.Equal(rm, rl.get("dir/b"))
+
+	// Renaming a file which is not partially cached drops the destination
+	rl.set("dir/c", rm)
+	rl.rename("dir/a", "dir/c")
+	suite.assert.Nil(rl.get("dir/c"))
+
+	rl.remove("dir/b")
+	suite.assert.Nil(rl.get("dir/b"))
+}
+
+func (suite *rangeMapTestSuite) TestMatches() {
+	rm := newRangeMap(35, 10, time.Now(), "")
+	suite.assert.True(rm.matches(""))
+
+	// First etag seen is recorded
+	suite.assert.True(rm.matches("v1"))
+	suite.assert.Equal("v1", rm.etag)
+	suite.assert.True(rm.matches("v1"))
+	suite.assert.False(rm.matches("v2"))
+
+	rm.uploaded("v3")
+	suite.assert.True(rm.matches("v3"))
+	suite.assert.False(rm.matches("v1"))
+}
+
+func TestRangeMapTestSuite(t *testing.T) {
+	suite.Run(t, new(rangeMapTestSuite))
+}
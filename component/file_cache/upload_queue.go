@@ -0,0 +1,489 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+)
+
+const (
+	uploadOpAdd  = "add"
+	uploadOpDone = "done"
+	uploadOpFail = "fail"
+
+	maxUploadBackoff   = 10 * time.Minute
+	uploadPollInterval = time.Second
+	journalCompactSize = 1000
+)
+
+// uploadRecord : One entry in the upload journal
+type uploadRecord struct {
+	Op      string `json:"op"`
+	Name    string `json:"name"`
+	Partial bool   `json:"partial,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// uploadItem : A file waiting to be uploaded to storage
+type uploadItem struct {
+	name     string
+	partial  bool
+	attempts uint32
+	nextTry  time.Time
+	inFlight bool
+	failed   bool
+	lastErr  string
+}
+
+// uploadQueue : Durable queue of files to be uploaded, backed by an append only journal on local disk.
+// Journal is replayed on start so that uploads pending at the time of unmount or crash are resumed on next mount.
+// A nil queue means durable uploads are disabled and all methods are no-op.
+type uploadQueue struct {
+	sync.Mutex
+
+	path    string
+	journal *os.File
+	records int
+	items   map[string]*uploadItem
+
+	maxRetries uint32
+	backoff    time.Duration
+
+	// Method to upload a file, called by the retry worker
+	upload func(name string) error
+
+	signal chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newUploadQueue(path string, maxRetries uint32, backoff time.Duration, upload func(string) error) (*uploadQueue, error) {
+	q := &uploadQueue{
+		path:       path,
+		items:      make(map[string]*uploadItem),
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		upload:     upload,
+		signal:     make(chan struct{}, 1),
+	}
+
+	err := q.replay()
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.compact()
+	if err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// replay : Rebuild the queue from the journal left behind by last mount
+func (q *uploadQueue) replay() error {
+	f, err := os.Open(q.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rec := uploadRecord{}
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+			// Last record may be partially written if the process died, ignore it
+			log.Warn("uploadQueue::replay : Skipping corrupt record in %s", q.path)
+			continue
+		}
+
+		switch rec.Op {
+		case uploadOpAdd:
+			q.items[rec.Name] = &uploadItem{name: rec.Name, partial: rec.Partial}
+		case uploadOpDone:
+			delete(q.items, rec.Name)
+		case uploadOpFail:
+			if item, ok := q.items[rec.Name]; ok {
+				item.lastErr = rec.Error
+			}
+		}
+	}
+
+	for _, item := range q.items {
+		if item.partial {
+			// Ranges downloaded for a partially cached file are not persisted, so it can not be uploaded anymore
+			item.failed = true
+			item.lastErr = "ranges of partially cached file lost on restart"
+			log.Err("uploadQueue::replay : Unable to resume upload of %s [%s]", item.name, item.lastErr)
+		}
+	}
+
+	if len(q.items) > 0 {
+		log.Info("uploadQueue::replay : %d uploads pending from last mount", len(q.items))
+	}
+
+	return scanner.Err()
+}
+
+// compact : Rewrite the journal with only the pending items, caller shall hold the lock if queue is in use
+func (q *uploadQueue) compact() error {
+	tmpPath := q.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, item := range q.items {
+		data, _ := json.Marshal(uploadRecord{Op: uploadOpAdd, Name: item.name, Partial: item.partial})
+		_, _ = w.Write(append(data, '\n'))
+	}
+
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, q.path)
+	if err != nil {
+		return err
+	}
+
+	if q.journal != nil {
+		_ = q.journal.Close()
+	}
+
+	q.journal, err = os.OpenFile(q.path, os.O_APPEND|os.O_WRONLY, 0644)
+	q.records = len(q.items)
+	return err
+}
+
+// write : Append a record to the journal, caller shall hold the lock
+func (q *uploadQueue) write(rec uploadRecord) {
+	data, _ := json.Marshal(rec)
+	_, err := q.journal.Write(append(data, '\n'))
+	if err == nil {
+		err = q.journal.Sync()
+	}
+
+	if err != nil {
+		log.Err("uploadQueue::write : Failed to journal %s of %s [%s]", rec.Op, rec.Name, err.Error())
+		return
+	}
+
+	q.records++
+	if q.records > journalCompactSize && q.records > 2*len(q.items) {
+		err = q.compact()
+		if err != nil {
+			log.Err("uploadQueue::write : Failed to compact journal %s [%s]", q.path, err.Error())
+		}
+	}
+}
+
+// start : Start the worker which retries failed uploads
+func (q *uploadQueue) start() {
+	if q == nil {
+		return
+	}
+
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	q.wg.Add(1)
+	go q.retryWorker()
+	q.updateStats()
+}
+
+// stop : Stop the retry worker, pending items stay in the journal for next mount
+func (q *uploadQueue) stop() {
+	if q == nil {
+		return
+	}
+
+	if q.cancel != nil {
+		q.cancel()
+		q.wg.Wait()
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.items) > 0 {
+		log.Info("uploadQueue::stop : %d uploads pending, these will resume on next mount", len(q.items))
+	}
+
+	_ = q.journal.Close()
+}
+
+// add : Record a file which is about to be uploaded
+func (q *uploadQueue) add(name string, partial bool) *uploadItem {
+	if q == nil {
+		return nil
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	item := &uploadItem{name: name, partial: partial, inFlight: true}
+	q.items[name] = item
+	q.write(uploadRecord{Op: uploadOpAdd, Name: name, Partial: partial})
+	q.updateStats()
+	return item
+}
+
+// complete : Record the result of an upload attempt, a failed upload is retried with exponential backoff.
+// Result is ignored if the item was removed or queued again while this attempt was in progress.
+func (q *uploadQueue) complete(item *uploadItem, err error) {
+	if q == nil || item == nil {
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.items[item.name] != item {
+		return
+	}
+
+	if err == nil {
+		delete(q.items, item.name)
+		q.write(uploadRecord{Op: uploadOpDone, Name: item.name})
+		q.updateStats()
+		return
+	}
+
+	item.inFlight = false
+	item.attempts++
+	item.lastErr = err.Error()
+
+	if item.attempts > q.maxRetries {
+		log.Err("uploadQueue::complete : Giving up upload of %s after %d attempts [%s]", item.name, item.attempts, item.lastErr)
+		item.failed = true
+	} else {
+		delay := min(q.backoff<<(item.attempts-1), maxUploadBackoff)
+		item.nextTry = time.Now().Add(delay)
+		log.Warn("uploadQueue::complete : Upload of %s failed, retry %d in %v [%s]", item.name, item.attempts, delay, item.lastErr)
+	}
+
+	q.write(uploadRecord{Op: uploadOpFail, Name: item.name, Error: item.lastErr})
+	q.updateStats()
+}
+
+// remove : Upload of the file is not required any more
+func (q *uploadQueue) remove(name string) {
+	if q == nil {
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if _, found := q.items[name]; !found {
+		return
+	}
+
+	delete(q.items, name)
+	q.write(uploadRecord{Op: uploadOpDone, Name: name})
+	q.updateStats()
+}
+
+// rename : File with a pending upload was renamed
+func (q *uploadQueue) rename(src string, dst string) {
+	if q == nil {
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	item, found := q.items[src]
+	if !found {
+		return
+	}
+
+	delete(q.items, src)
+	q.write(uploadRecord{Op: uploadOpDone, Name: src})
+
+	item.name = dst
+	item.inFlight = false
+	q.items[dst] = item
+	q.write(uploadRecord{Op: uploadOpAdd, Name: dst, Partial: item.partial})
+}
+
+// pending : Whether the file has an upload pending, such files shall not be evicted from local cache
+func (q *uploadQueue) pending(name string) bool {
+	if q == nil {
+		return false
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	_, found := q.items[name]
+	return found
+}
+
+// list : Names of all the files with pending uploads
+func (q *uploadQueue) list() []string {
+	if q == nil {
+		return nil
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	names := make([]string, 0, len(q.items))
+	for name := range q.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// counts : Number of pending, in flight and failed uploads
+func (q *uploadQueue) counts() (int64, int64, int64) {
+	var pending, inFlight, failed int64
+	for _, item := range q.items {
+		if item.failed {
+			failed++
+		} else if item.inFlight {
+			inFlight++
+		} else {
+			pending++
+		}
+	}
+	return pending, inFlight, failed
+}
+
+// updateStats : Publish the state of the queue, caller shall hold the lock
+func (q *uploadQueue) updateStats() {
+	if q.ctx == nil {
+		// Queue is not started yet
+		return
+	}
+
+	pending, inFlight, failed := q.counts()
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, uploadsPending, pending)
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, uploadsInFlight, inFlight)
+	fileCacheStatsCollector.UpdateStats(stats_manager.Replace, uploadsFailed, failed)
+}
+
+// drain : Wait till all the uploads, except the ones which have failed permanently, are done
+func (q *uploadQueue) drain() {
+	if q == nil {
+		return
+	}
+
+	log.Info("uploadQueue::drain : Waiting for pending uploads to complete")
+	for {
+		q.Lock()
+		pending, inFlight, failed := q.counts()
+		q.Unlock()
+
+		if pending+inFlight == 0 {
+			log.Info("uploadQueue::drain : Upload queue drained, %d uploads failed", failed)
+			return
+		}
+
+		select {
+		case q.signal <- struct{}{}:
+		default:
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// retryWorker : Upload the files which are due for a retry
+func (q *uploadQueue) retryWorker() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(uploadPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.signal:
+		}
+
+		for _, item := range q.due() {
+			if q.ctx.Err() != nil {
+				return
+			}
+
+			err := q.upload(item.name)
+			if err == nil {
+				log.Info("uploadQueue::retryWorker : Upload of %s completed", item.name)
+			}
+			q.complete(item, err)
+		}
+	}
+}
+
+// due : Mark the items which are due for a retry as in flight and return them
+func (q *uploadQueue) due() []*uploadItem {
+	q.Lock()
+	defer q.Unlock()
+
+	now := time.Now()
+	list := make([]*uploadItem, 0)
+	for _, item := range q.items {
+		if !item.inFlight && !item.failed && !item.nextTry.After(now) {
+			item.inFlight = true
+			list = append(list, item)
+		}
+	}
+
+	if len(list) > 0 {
+		q.updateStats()
+	}
+	return list
+}
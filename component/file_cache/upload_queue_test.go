@@ -0,0 +1,210 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type uploadQueueTestSuite struct {
+	suite.Suite
+	assert  *assert.Assertions
+	dir     string
+	journal string
+}
+
+func (suite *uploadQueueTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.dir = suite.T().TempDir()
+	suite.journal = filepath.Join(suite.dir, "uploads.journal")
+}
+
+func (suite *uploadQueueTestSuite) newQueue(upload func(string) error) *uploadQueue {
+	q, err := newUploadQueue(suite.journal, 2, 10*time.Millisecond, upload)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(q)
+	return q
+}
+
+func (suite *uploadQueueTestSuite) TestNilQueue() {
+	var q *uploadQueue = nil
+	item := q.add("a", false)
+	suite.assert.Nil(item)
+	q.complete(item, nil)
+	q.remove("a")
+	q.rename("a", "b")
+	q.start()
+	q.drain()
+	q.stop()
+	suite.assert.False(q.pending("a"))
+	suite.assert.Empty(q.list())
+}
+
+func (suite *uploadQueueTestSuite) TestReplay() {
+	q := suite.newQueue(nil)
+	q.add("a", false)
+	q.add("b", false)
+	q.complete(q.add("c", false), nil)
+	q.complete(q.add("d", true), errors.New("failed"))
+	q.remove("b")
+	q.stop()
+
+	q = suite.newQueue(nil)
+	suite.assert.Equal([]string{"a", "d"}, q.list())
+	suite.assert.False(q.items["a"].inFlight)
+	suite.assert.False(q.items["a"].failed)
+
+	// Ranges of partially cached file are not retained across mounts
+	suite.assert.True(q.items["d"].failed)
+	q.stop()
+
+	// Journal is compacted on start
+	data, err := os.ReadFile(suite.journal)
+	suite.assert.NoError(err)
+	suite.assert.Equal(2, strings.Count(string(data), "\n"))
+}
+
+func (suite *uploadQueueTestSuite) TestCorruptRecord() {
+	err := os.WriteFile(suite.journal, []byte("{\"op\":\"add\",\"name\":\"a\"}\n{\"op\":\"add\",\"na"), 0644)
+	suite.assert.NoError(err)
+
+	q := suite.newQueue(nil)
+	suite.assert.Equal([]string{"a"}, q.list())
+	q.stop()
+}
+
+func (suite *uploadQueueTestSuite) TestBackoff() {
+	q := suite.newQueue(nil)
+	item := q.add("a", false)
+
+	q.complete(item, errors.New("failed"))
+	suite.assert.EqualValues(1, item.attempts)
+	suite.assert.False(item.inFlight)
+	suite.assert.False(item.failed)
+	suite.assert.Equal("failed", item.lastErr)
+	first := item.nextTry
+
+	q.complete(item, errors.New("failed"))
+	suite.assert.EqualValues(2, item.attempts)
+	suite.assert.False(item.failed)
+	suite.assert.True(item.nextTry.After(first))
+
+	// Retries exhausted, file is retained locally but not retried anymore
+	q.complete(item, errors.New("failed"))
+	suite.assert.True(item.failed)
+	suite.assert.True(q.pending("a"))
+
+	pending, inFlight, failed := q.counts()
+	suite.assert.EqualValues(0, pending)
+	suite.assert.EqualValues(0, inFlight)
+	suite.assert.EqualValues(1, failed)
+	q.stop()
+}
+
+func (suite *uploadQueueTestSuite) TestSupersededAttempt() {
+	q := suite.newQueue(nil)
+	old := q.add("a", false)
+	cur := q.add("a", false)
+
+	// Result of an older attempt shall not complete the newer one
+	q.complete(old, nil)
+	suite.assert.True(q.pending("a"))
+
+	q.complete(cur, nil)
+	suite.assert.False(q.pending("a"))
+	q.stop()
+}
+
+func (suite *uploadQueueTestSuite) TestRename() {
+	q := suite.newQueue(nil)
+	item := q.add("a", false)
+	q.complete(item, errors.New("failed"))
+
+	q.rename("a", "b")
+	q.rename("x", "y")
+	suite.assert.Equal([]string{"b"}, q.list())
+	q.stop()
+
+	q = suite.newQueue(nil)
+	suite.assert.Equal([]string{"b"}, q.list())
+	q.stop()
+}
+
+func (suite *uploadQueueTestSuite) TestCompaction() {
+	q := suite.newQueue(nil)
+	for i := 0; i < journalCompactSize; i++ {
+		q.complete(q.add("a", false), nil)
+	}
+	q.add("b", false)
+	q.stop()
+
+	data, err := os.ReadFile(suite.journal)
+	suite.assert.NoError(err)
+	suite.assert.Less(strings.Count(string(data), "\n"), journalCompactSize)
+
+	q = suite.newQueue(nil)
+	suite.assert.Equal([]string{"b"}, q.list())
+	q.stop()
+}
+
+func (suite *uploadQueueTestSuite) TestRetryWorker() {
+	var calls atomic.Int32
+	q := suite.newQueue(func(name string) error {
+		if calls.Add(1) == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	q.complete(q.add("a", false), errors.New("failed"))
+	q.start()
+	q.drain()
+	q.stop()
+
+	suite.assert.EqualValues(2, calls.Load())
+	suite.assert.False(q.pending("a"))
+}
+
+func TestUploadQueue(t *testing.T) {
+	suite.Run(t, new(uploadQueueTestSuite))
+}
@@ -0,0 +1,405 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gofuse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+/* NOTES:
+   - Component shall have a structure which inherits "internal.BaseComponent" to participate in pipeline
+   - Component shall register a name and its constructor to participate in pipeline  (add by default by generator)
+   - Order of calls : Constructor -> Configure -> Start ..... -> Stop
+   - To read any new setting from config file follow the Configure method default comments
+*/
+
+// Common structure for Component
+type Gofuse struct {
+	internal.BaseComponent
+	mountPath             string
+	dirPermission         uint
+	filePermission        uint
+	readOnly              bool
+	attributeExpiration   uint32
+	entryExpiration       uint32
+	negativeTimeout       uint32
+	allowOther            bool
+	allowRoot             bool
+	ownerUID              uint32
+	ownerGID              uint32
+	traceEnable           bool
+	disableWritebackCache bool
+	ignoreOpenFlags       bool
+	nonEmptyMount         bool
+	maxFuseThreads        uint32
+	directIO              bool
+	umask                 uint32
+
+	root   *node
+	server *fuse.Server
+}
+
+// Structure defining your config parameters
+type GofuseOptions struct {
+	mountPath               string
+	DefaultPermission       uint32 `config:"default-permission" yaml:"default-permission,omitempty"`
+	AttributeExpiration     uint32 `config:"attribute-expiration-sec" yaml:"attribute-expiration-sec,omitempty"`
+	EntryExpiration         uint32 `config:"entry-expiration-sec" yaml:"entry-expiration-sec,omitempty"`
+	NegativeEntryExpiration uint32 `config:"negative-entry-expiration-sec" yaml:"negative-entry-expiration-sec,omitempty"`
+	EnableFuseTrace         bool   `config:"fuse-trace" yaml:"fuse-trace,omitempty"`
+	allowOther              bool   `config:"allow-other" yaml:"-"`
+	allowRoot               bool   `config:"allow-root" yaml:"-"`
+	readOnly                bool   `config:"read-only" yaml:"-"`
+	DisableWritebackCache   bool   `config:"disable-writeback-cache" yaml:"disable-writeback-cache,omitempty"`
+	IgnoreOpenFlags         bool   `config:"ignore-open-flags" yaml:"ignore-open-flags,omitempty"`
+	nonEmptyMount           bool   `config:"nonempty" yaml:"nonempty,omitempty"`
+	Uid                     uint32 `config:"uid" yaml:"uid,omitempty"`
+	Gid                     uint32 `config:"gid" yaml:"gid,omitempty"`
+	MaxFuseThreads          uint32 `config:"max-fuse-threads" yaml:"max-fuse-threads,omitempty"`
+	DirectIO                bool   `config:"direct-io" yaml:"direct-io,omitempty"`
+	Umask                   uint32 `config:"umask" yaml:"umask,omitempty"`
+}
+
+const compName = "gofuse"
+const defaultEntryExpiration = 120
+const defaultAttrExpiration = 120
+const defaultNegativeEntryExpiration = 120
+const defaultMaxFuseThreads = 128
+
+var gofuseStatsCollector *stats_manager.StatsCollector
+
+var ignoreFiles = map[string]bool{
+	".Trash":           true,
+	".Trash-1000":      true,
+	".xdg-volume-info": true,
+	"autorun.inf":      true,
+}
+
+// Verification to check satisfaction criteria with Component Interface
+var _ internal.Component = &Gofuse{}
+
+func (gf *Gofuse) Name() string {
+	return compName
+}
+
+func (gf *Gofuse) SetName(name string) {
+	gf.BaseComponent.SetName(name)
+}
+
+func (gf *Gofuse) SetNextComponent(nc internal.Component) {
+	gf.BaseComponent.SetNextComponent(nc)
+}
+
+func (gf *Gofuse) Priority() internal.ComponentPriority {
+	return internal.EComponentPriority.Producer()
+}
+
+// Start : Pipeline calls this method to start the component functionality
+//
+//	like libfuse this serves the mount until it is unmounted
+func (gf *Gofuse) Start(ctx context.Context) error {
+	log.Trace("Gofuse::Start : Starting component %s", gf.Name())
+
+	// create stats collector for gofuse
+	gofuseStatsCollector = stats_manager.NewStatsCollector(gf.Name())
+
+	err := gf.initFuse()
+	if err != nil {
+		log.Err("Gofuse::Start : Failed to init fuse [%s]", err.Error())
+		return err
+	}
+
+	log.Info("Gofuse::Start : Notifying parent for successful mount")
+	if err := common.NotifyMountToParent(); err != nil {
+		log.Err("Gofuse::Start : Failed to notify parent, error: [%v]", err)
+	}
+
+	gf.server.Wait()
+	log.Info("Gofuse::Start : %s unmounted", gf.mountPath)
+	return nil
+}
+
+// Stop : Stop the component functionality and kill all threads started
+func (gf *Gofuse) Stop() error {
+	log.Trace("Gofuse::Stop : Stopping component %s", gf.Name())
+	_ = gf.destroyFuse()
+	gofuseStatsCollector.Destroy()
+	return nil
+}
+
+// Validate : Validate available config and convert them if required
+func (gf *Gofuse) Validate(opt *GofuseOptions) error {
+	gf.mountPath = opt.mountPath
+	gf.readOnly = opt.readOnly
+	gf.traceEnable = opt.EnableFuseTrace
+	gf.allowOther = opt.allowOther
+	gf.allowRoot = opt.allowRoot
+	gf.disableWritebackCache = opt.DisableWritebackCache
+	gf.ignoreOpenFlags = opt.IgnoreOpenFlags
+	gf.nonEmptyMount = opt.nonEmptyMount
+	gf.directIO = opt.DirectIO
+	gf.ownerGID = opt.Gid
+	gf.ownerUID = opt.Uid
+	gf.umask = opt.Umask
+
+	if opt.allowOther {
+		gf.dirPermission = uint(common.DefaultAllowOtherPermissionBits)
+		gf.filePermission = uint(common.DefaultAllowOtherPermissionBits)
+	} else {
+		if opt.DefaultPermission != 0 {
+			gf.dirPermission = uint(opt.DefaultPermission)
+			gf.filePermission = uint(opt.DefaultPermission)
+		} else {
+			gf.dirPermission = uint(common.DefaultDirectoryPermissionBits)
+			gf.filePermission = uint(common.DefaultFilePermissionBits)
+		}
+	}
+
+	if config.IsSet(compName+".entry-expiration-sec") || config.IsSet("lfuse.entry-expiration-sec") {
+		gf.entryExpiration = opt.EntryExpiration
+	} else {
+		gf.entryExpiration = defaultEntryExpiration
+	}
+
+	if config.IsSet(compName+".attribute-expiration-sec") || config.IsSet("lfuse.attribute-expiration-sec") {
+		gf.attributeExpiration = opt.AttributeExpiration
+	} else {
+		gf.attributeExpiration = defaultAttrExpiration
+	}
+
+	if config.IsSet(compName+".negative-entry-expiration-sec") || config.IsSet("lfuse.negative-entry-expiration-sec") {
+		gf.negativeTimeout = opt.NegativeEntryExpiration
+	} else {
+		gf.negativeTimeout = defaultNegativeEntryExpiration
+	}
+
+	if gf.directIO {
+		gf.negativeTimeout = 0
+		gf.attributeExpiration = 0
+		gf.entryExpiration = 0
+		log.Crit("Gofuse::Validate : DirectIO enabled, setting fuse timeouts to 0")
+	}
+
+	// umask is given in octal digits, e.g. 22 for 022, same as the umask option of libfuse
+	if gf.umask != 0 {
+		umask, err := strconv.ParseUint(fmt.Sprint(gf.umask), 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid umask %v", gf.umask)
+		}
+		gf.umask = uint32(umask)
+	}
+
+	if !(config.IsSet(compName+".uid") || config.IsSet(compName+".gid") ||
+		config.IsSet("lfuse.uid") || config.IsSet("lfuse.gid")) {
+		var err error
+		gf.ownerUID, gf.ownerGID, err = common.GetCurrentUser()
+		if err != nil {
+			log.Err("Gofuse::Validate : config error [unable to obtain current user info]")
+			return nil
+		}
+	}
+
+	if config.IsSet(compName + ".max-fuse-threads") {
+		gf.maxFuseThreads = opt.MaxFuseThreads
+	} else {
+		gf.maxFuseThreads = defaultMaxFuseThreads
+	}
+
+	log.Info("Gofuse::Validate : UID %v, GID %v", gf.ownerUID, gf.ownerGID)
+
+	return nil
+}
+
+// Configure : Pipeline will call this method after constructor so that you can read config and initialize yourself
+//
+//	Return failure if any config is not valid to exit the process
+func (gf *Gofuse) Configure(_ bool) error {
+	log.Trace("Gofuse::Configure : %s", gf.Name())
+	conf := GofuseOptions{IgnoreOpenFlags: true}
+	err := config.UnmarshalKey(gf.Name(), &conf)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("config error in %s [invalid config attributes]", gf.Name())
+	}
+
+	// Fuse options given through -o on command line are kept under lfuse for either frontend
+	err = config.UnmarshalKey("lfuse", &conf)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [invalid config attributes: %s]", err.Error())
+		return fmt.Errorf("config error in lfuse [invalid config attributes]")
+	}
+
+	err = config.UnmarshalKey("mount-path", &conf.mountPath)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [unable to obtain mount-path]")
+		return err
+	}
+	err = config.UnmarshalKey("read-only", &conf.readOnly)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [unable to obtain read-only]")
+		return err
+	}
+
+	err = config.UnmarshalKey("allow-other", &conf.allowOther)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [unable to obtain allow-other]")
+		return err
+	}
+
+	err = config.UnmarshalKey("allow-root", &conf.allowRoot)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [unable to obtain allow-root]")
+		return err
+	}
+
+	err = config.UnmarshalKey("nonempty", &conf.nonEmptyMount)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [unable to obtain nonempty]")
+		return err
+	}
+
+	err = gf.Validate(&conf)
+	if err != nil {
+		log.Err("Gofuse::Configure : config error [invalid config settings]")
+		return fmt.Errorf("%s config error %s", gf.Name(), err.Error())
+	}
+
+	// Fuse trace goes to stdout, so it is honoured only for a foreground mount
+	if !common.ForegroundMount {
+		gf.traceEnable = false
+	}
+
+	log.Crit("Gofuse::Configure : read-only %t, allow-other %t, allow-root %t, default-perm %d, entry-timeout %d, attr-time %d, negative-timeout %d, ignore-open-flags %t, nonempty %t, direct_io %t, max-fuse-threads %d, fuse-trace %t, disable-writeback-cache %t, dirPermission %v, mountPath %v, umask %v",
+		gf.readOnly, gf.allowOther, gf.allowRoot, gf.filePermission, gf.entryExpiration, gf.attributeExpiration, gf.negativeTimeout, gf.ignoreOpenFlags, gf.nonEmptyMount, gf.directIO, gf.maxFuseThreads, gf.traceEnable, gf.disableWritebackCache, gf.dirPermission, gf.mountPath, gf.umask)
+
+	return nil
+}
+
+// mountOptions : Options given to go-fuse for the mount, these are the same as the ones libfuse is started with
+func (gf *Gofuse) mountOptions() *fs.Options {
+	entryTimeout := time.Duration(gf.entryExpiration) * time.Second
+	attrTimeout := time.Duration(gf.attributeExpiration) * time.Second
+	negativeTimeout := time.Duration(gf.negativeTimeout) * time.Second
+
+	opts := &fs.Options{
+		EntryTimeout:    &entryTimeout,
+		AttrTimeout:     &attrTimeout,
+		NegativeTimeout: &negativeTimeout,
+		UID:             gf.ownerUID,
+		GID:             gf.ownerGID,
+		NullPermissions: true,
+	}
+
+	opts.FsName = "blobfuse2"
+	opts.Name = "blobfuse2"
+	opts.AllowOther = gf.allowOther
+	opts.Debug = gf.traceEnable
+	opts.MaxBackground = int(gf.maxFuseThreads)
+	opts.MaxWrite = fuse.MAX_KERNEL_WRITE
+	opts.MaxReadAhead = 4 * 1024 * 1024
+	opts.DisableXAttrs = true
+
+	if gf.allowRoot {
+		opts.Options = append(opts.Options, "allow_root")
+	}
+
+	if gf.readOnly {
+		opts.Options = append(opts.Options, "ro")
+	}
+
+	if gf.nonEmptyMount {
+		opts.Options = append(opts.Options, "nonempty")
+	}
+
+	return opts
+}
+
+// initFuse mounts the container on the mount path, the mount is served by go-fuse in background
+func (gf *Gofuse) initFuse() error {
+	log.Trace("Gofuse::initFuse : Mounting %s", gf.mountPath)
+
+	gf.root = &node{gf: gf}
+
+	var err error
+	gf.server, err = fs.Mount(gf.mountPath, gf.root, gf.mountOptions())
+	if err != nil {
+		log.Err("Gofuse::initFuse : failed to mount fuse [%s]", err.Error())
+		return fmt.Errorf("failed to mount fuse [%s]", err.Error())
+	}
+
+	log.Info("Gofuse::initFuse : Mounted with kernel protocol %v", gf.server.KernelSettings().Minor)
+	return nil
+}
+
+// destroyFuse unmounts the container if it is still mounted
+func (gf *Gofuse) destroyFuse() error {
+	log.Trace("Gofuse::destroyFuse : Destroying FUSE")
+	if gf.server == nil {
+		return nil
+	}
+
+	err := gf.server.Unmount()
+	if err != nil {
+		log.Debug("Gofuse::destroyFuse : unmount returned [%s]", err.Error())
+	}
+	gf.server = nil
+	return nil
+}
+
+// ------------------------- Factory -------------------------------------------
+
+// Pipeline will call this method to create your object, initialize your variables here
+// << DO NOT DELETE ANY AUTO GENERATED CODE HERE >>
+func NewGofuseComponent() internal.Component {
+	comp := &Gofuse{}
+	comp.SetName(compName)
+	return comp
+}
+
+// On init register this component to pipeline and supply your constructor
+func init() {
+	internal.AddComponent(compName, NewGofuseComponent)
+}
@@ -0,0 +1,901 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gofuse
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+	"github.com/Azure/azure-storage-fuse/v2/internal/stats_manager"
+
+	gofs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+/* --- IMPORTANT NOTE ---
+go-fuse keeps a tree of inodes for the paths the kernel has looked up, each inode is served by a node below.
+Operations are mapped to the pipeline exactly as libfuse_handler.go does, using the path of the inode.
+Unlike libfuse this tree lets us find the inode of a path and ask the kernel to drop what it has cached for it,
+which is done when a path is invalidated by another client.
+*/
+
+// node : A file, directory or symlink of the container
+type node struct {
+	gofs.Inode
+	gf *Gofuse
+}
+
+// fileHandle : Handle of an open file given back to go-fuse
+type fileHandle struct {
+	handle *handlemap.Handle
+}
+
+// dirHandle : Handle of an open directory, serves the listing a block of items at a time
+type dirHandle struct {
+	gf     *Gofuse
+	name   string
+	handle *handlemap.Handle
+	offset uint64 // index of next item to be returned
+	cache  dirChildCache
+}
+
+// To support pagination in readdir calls this structure holds a block of items for a given directory
+type dirChildCache struct {
+	sIndex   uint64              // start index of current block of items
+	eIndex   uint64              // End index of current block of items
+	token    string              // Token to get next block of items from container
+	children []*internal.ObjAttr // Slice holding current block of children
+}
+
+var _ = (gofs.NodeLookuper)((*node)(nil))
+var _ = (gofs.NodeGetattrer)((*node)(nil))
+var _ = (gofs.NodeSetattrer)((*node)(nil))
+var _ = (gofs.NodeOpendirHandler)((*node)(nil))
+var _ = (gofs.NodeMkdirer)((*node)(nil))
+var _ = (gofs.NodeRmdirer)((*node)(nil))
+var _ = (gofs.NodeStatfser)((*node)(nil))
+var _ = (gofs.NodeCreater)((*node)(nil))
+var _ = (gofs.NodeOpener)((*node)(nil))
+var _ = (gofs.NodeReader)((*node)(nil))
+var _ = (gofs.NodeWriter)((*node)(nil))
+var _ = (gofs.NodeFlusher)((*node)(nil))
+var _ = (gofs.NodeReleaser)((*node)(nil))
+var _ = (gofs.NodeFsyncer)((*node)(nil))
+var _ = (gofs.NodeUnlinker)((*node)(nil))
+var _ = (gofs.NodeRenamer)((*node)(nil))
+var _ = (gofs.NodeSymlinker)((*node)(nil))
+var _ = (gofs.NodeReadlinker)((*node)(nil))
+var _ = (gofs.FileReaddirenter)((*dirHandle)(nil))
+var _ = (gofs.FileSeekdirer)((*dirHandle)(nil))
+var _ = (gofs.FileReleasedirer)((*dirHandle)(nil))
+var _ = (gofs.FileFsyncdirer)((*dirHandle)(nil))
+
+// path : Name of this node in the container
+func (n *node) path() string {
+	return common.NormalizeObjectName(n.Path(nil))
+}
+
+// childPath : Name of a child of this node in the container
+func (n *node) childPath(name string) string {
+	parent := n.path()
+	if parent == "" {
+		return common.NormalizeObjectName(name)
+	}
+	return common.NormalizeObjectName(parent + "/" + name)
+}
+
+// callerUID : uid of the process calling the current operation, 0 when not called from fuse
+func callerUID(ctx context.Context) uint32 {
+	if caller, ok := fuse.FromContext(ctx); ok {
+		return caller.Uid
+	}
+	return 0
+}
+
+// errnoOf : Convert an error from the pipeline to the errno returned to the kernel
+func errnoOf(err error) syscall.Errno {
+	if os.IsNotExist(err) {
+		return syscall.ENOENT
+	} else if os.IsPermission(err) {
+		return syscall.EACCES
+	} else if os.IsExist(err) {
+		return syscall.EEXIST
+	} else if err == syscall.EDQUOT {
+		return syscall.EDQUOT
+	}
+	return syscall.EIO
+}
+
+// fileType : File type bits of the mode of an object
+func fileType(attr *internal.ObjAttr) uint32 {
+	if attr.IsDir() {
+		return syscall.S_IFDIR
+	} else if attr.IsSymlink() {
+		return syscall.S_IFLNK
+	}
+	return syscall.S_IFREG
+}
+
+func (gf *Gofuse) fillAttr(attr *internal.ObjAttr, out *fuse.Attr) {
+	out.Uid = gf.ownerUID
+	out.Gid = gf.ownerGID
+	out.Nlink = 1
+	out.Size = uint64(attr.Size)
+
+	// Populate mode
+	// Backing storage implementation has support for mode.
+	if !attr.IsModeDefault() {
+		out.Mode = uint32(attr.Mode.Perm())
+	} else {
+		if attr.IsDir() {
+			out.Mode = uint32(gf.dirPermission) & 0777
+		} else {
+			out.Mode = uint32(gf.filePermission) & 0777
+		}
+	}
+	out.Mode &^= gf.umask
+
+	if attr.IsDir() {
+		out.Nlink = 2
+		out.Size = 4096
+	}
+	out.Mode |= fileType(attr)
+
+	out.Atime = uint64(attr.Atime.Unix())
+	out.Atimensec = 0
+	out.Ctime = uint64(attr.Ctime.Unix())
+	out.Ctimensec = 0
+	out.Mtime = uint64(attr.Mtime.Unix())
+	out.Mtimensec = 0
+	out.Blksize = 4096
+	out.Blocks = (out.Size + 511) / 512
+}
+
+// fillRootAttr : Properties of the root are static, same as libfuse returns for it
+func (gf *Gofuse) fillRootAttr(out *fuse.Attr) {
+	now := uint64(time.Now().Unix())
+	out.Mode = syscall.S_IFDIR | 0777
+	out.Uid = gf.ownerUID
+	out.Gid = gf.ownerGID
+	out.Nlink = 2
+	out.Size = 4096
+	out.Mtime = now
+	out.Atime = now
+	out.Ctime = now
+}
+
+// newChild : Inode for a child of this node, an existing one is reused as long as the type of the object is the same
+func (n *node) newChild(ctx context.Context, name string, attr *internal.ObjAttr) *gofs.Inode {
+	mode := fileType(attr)
+	if child := n.GetChild(name); child != nil && child.StableAttr().Mode == mode {
+		return child
+	}
+	return n.NewInode(ctx, &node{gf: n.gf}, gofs.StableAttr{Mode: mode})
+}
+
+// Lookup gets attributes of a child and returns its inode
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	path := n.childPath(name)
+
+	// Check if the file is meant to be ignored
+	if ignore, found := ignoreFiles[path]; found && ignore {
+		return nil, syscall.ENOENT
+	}
+
+	attr, err := n.gf.NextComponent().GetAttr(internal.GetAttrOptions{Name: path})
+	if err != nil {
+		if err == syscall.ENOENT || os.IsNotExist(err) {
+			return nil, syscall.ENOENT
+		} else if err == syscall.EACCES {
+			return nil, syscall.EACCES
+		}
+		return nil, syscall.EIO
+	}
+
+	n.gf.fillAttr(attr, &out.Attr)
+	return n.newChild(ctx, name, attr), 0
+}
+
+// Getattr gets file attributes
+func (n *node) Getattr(ctx context.Context, f gofs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	name := n.path()
+
+	// Return the default configuration for the root
+	if name == "" {
+		n.gf.fillRootAttr(&out.Attr)
+		return 0
+	}
+
+	// Check if the file is meant to be ignored
+	if ignore, found := ignoreFiles[name]; found && ignore {
+		return syscall.ENOENT
+	}
+
+	attr, err := n.gf.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		if err == syscall.ENOENT {
+			return syscall.ENOENT
+		} else if err == syscall.EACCES {
+			return syscall.EACCES
+		}
+		return syscall.EIO
+	}
+
+	n.gf.fillAttr(attr, &out.Attr)
+	return 0
+}
+
+// Setattr changes size, permission bits, owner or times of a file
+func (n *node) Setattr(ctx context.Context, f gofs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if length, ok := in.GetSize(); ok {
+		if errno := n.truncate(int64(length)); errno != 0 {
+			return errno
+		}
+	}
+
+	if mode, ok := in.GetMode(); ok {
+		if errno := n.chmod(mode); errno != 0 {
+			return errno
+		}
+	}
+
+	// Owner and times are not supported by storage, these are accepted to allow chown and touch to work
+	return n.Getattr(ctx, f, out)
+}
+
+// Directory Operations
+
+// Mkdir creates a directory
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	path := n.childPath(name)
+	log.Trace("Gofuse::Mkdir : %s", path)
+
+	err := n.gf.NextComponent().CreateDir(internal.CreateDirOptions{Name: path, Mode: fs.FileMode(mode)})
+	if err != nil {
+		log.Err("Gofuse::Mkdir : Failed to create %s [%s]", path, err.Error())
+		if os.IsPermission(err) {
+			return nil, syscall.EACCES
+		} else if os.IsExist(err) {
+			return nil, syscall.EEXIST
+		}
+		return nil, syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(createDir, path, map[string]interface{}{md: fs.FileMode(mode)})
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, createDir, (int64)(1))
+
+	now := time.Now()
+	attr := &internal.ObjAttr{Path: path, Name: name, Mode: fs.FileMode(mode), Mtime: now, Atime: now, Ctime: now, Flags: internal.NewDirBitMap()}
+	n.gf.fillAttr(attr, &out.Attr)
+	return n.newChild(ctx, name, attr), 0
+}
+
+// OpendirHandle opens handle to this directory
+func (n *node) OpendirHandle(ctx context.Context, flags uint32) (gofs.FileHandle, uint32, syscall.Errno) {
+	name := n.path()
+	path := name
+	if path != "" {
+		path = path + "/"
+	}
+
+	log.Trace("Gofuse::OpendirHandle : %s", path)
+
+	handle := handlemap.NewHandle(path)
+	handlemap.Add(handle)
+
+	return &dirHandle{gf: n.gf, name: name, handle: handle}, 0, 0
+}
+
+// Readdirent returns the next item of the directory, fetching the next block of items when current one is consumed
+func (d *dirHandle) Readdirent(ctx context.Context) (*fuse.DirEntry, syscall.Errno) {
+	if d.offset == 0 ||
+		(d.offset >= d.cache.eIndex && d.cache.token != "") {
+		attrs, token, err := d.gf.NextComponent().StreamDir(internal.StreamDirOptions{
+			Name:   d.handle.Path,
+			Offset: d.offset,
+			Token:  d.cache.token,
+			Count:  common.MaxDirListCount,
+		})
+
+		if err != nil {
+			log.Err("Gofuse::Readdirent : Path %s, handle: %d, offset %d. Error in retrieval %s", d.handle.Path, d.handle.ID, d.offset, err.Error())
+			if os.IsNotExist(err) {
+				return nil, syscall.ENOENT
+			} else if os.IsPermission(err) {
+				return nil, syscall.EACCES
+			}
+			return nil, syscall.EIO
+		}
+
+		d.cache.sIndex = d.offset
+		d.cache.eIndex = d.offset + uint64(len(attrs))
+		d.cache.token = token
+		d.cache.children = attrs
+	}
+
+	if d.offset >= d.cache.eIndex {
+		// If offset is still beyond the end index limit then we are done iterating
+		return nil, 0
+	}
+
+	attr := d.cache.children[d.offset-d.cache.sIndex]
+	d.offset++
+
+	return &fuse.DirEntry{Name: attr.Name, Mode: fileType(attr), Off: d.offset}, 0
+}
+
+// Seekdir restarts the listing, kernel only seeks back to the start of the directory
+func (d *dirHandle) Seekdir(ctx context.Context, off uint64) syscall.Errno {
+	if off != 0 {
+		return syscall.ENOTSUP
+	}
+
+	d.offset = 0
+	d.cache = dirChildCache{}
+	return 0
+}
+
+// Releasedir releases handle to the directory
+func (d *dirHandle) Releasedir(ctx context.Context, releaseFlags uint32) {
+	log.Trace("Gofuse::Releasedir : %s, handle: %d", d.handle.Path, d.handle.ID)
+
+	d.handle.Cleanup()
+	handlemap.Delete(d.handle.ID)
+}
+
+// Fsyncdir synchronizes directory contents
+func (d *dirHandle) Fsyncdir(ctx context.Context, flags uint32) syscall.Errno {
+	log.Trace("Gofuse::Fsyncdir : %s", d.name)
+
+	err := d.gf.NextComponent().SyncDir(internal.SyncDirOptions{Name: d.name})
+	if err != nil {
+		log.Err("Gofuse::Fsyncdir : error syncing dir %s [%s]", d.name, err.Error())
+		return syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(syncDir, d.name, nil)
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, syncDir, (int64)(1))
+
+	return 0
+}
+
+// Rmdir deletes a directory, which must be empty.
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	path := n.childPath(name)
+	log.Trace("Gofuse::Rmdir : %s", path)
+
+	empty := n.gf.NextComponent().IsDirEmpty(internal.IsDirEmptyOptions{Name: path})
+	if !empty {
+		return syscall.ENOTEMPTY
+	}
+
+	err := n.gf.NextComponent().DeleteDir(internal.DeleteDirOptions{Name: path})
+	if err != nil {
+		log.Err("Gofuse::Rmdir : Failed to delete %s [%s]", path, err.Error())
+		if os.IsNotExist(err) {
+			return syscall.ENOENT
+		}
+		return syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(deleteDir, path, nil)
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, deleteDir, (int64)(1))
+
+	return 0
+}
+
+// File Operations
+
+// Statfs gets file system statistics
+func (n *node) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	log.Trace("Gofuse::Statfs : %s", n.path())
+
+	attr, populated, err := n.gf.NextComponent().StatFs()
+	if err != nil {
+		log.Err("Gofuse::Statfs : Failed to get stats %s [%s]", n.path(), err.Error())
+		return syscall.EIO
+	}
+
+	// if not populated then return stats of the root file system
+	if !populated {
+		attr = &syscall.Statfs_t{}
+		if err = syscall.Statfs("/", attr); err != nil {
+			return gofs.ToErrno(err)
+		}
+	}
+
+	out.FromStatfsT(attr)
+	return 0
+}
+
+// Create creates a file with the specified mode and then opens it.
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*gofs.Inode, gofs.FileHandle, uint32, syscall.Errno) {
+	path := n.childPath(name)
+	log.Trace("Gofuse::Create : %s", path)
+
+	handle, err := n.gf.NextComponent().CreateFile(internal.CreateFileOptions{Name: path, Mode: fs.FileMode(mode), Uid: callerUID(ctx)})
+	if err != nil {
+		log.Err("Gofuse::Create : Failed to create %s [%s]", path, err.Error())
+		return nil, nil, 0, errnoOf(err)
+	}
+
+	handlemap.Add(handle)
+	log.Trace("Gofuse::Create : %s, handle %d", path, handle.ID)
+
+	gofuseStatsCollector.PushEvents(createFile, path, map[string]interface{}{md: fs.FileMode(mode)})
+
+	// increment open file handles count
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, openHandles, (int64)(1))
+
+	now := time.Now()
+	attr := &internal.ObjAttr{Path: path, Name: name, Mode: fs.FileMode(mode), Mtime: now, Atime: now, Ctime: now}
+	n.gf.fillAttr(attr, &out.Attr)
+	return n.newChild(ctx, name, attr), &fileHandle{handle: handle}, n.gf.openFlags(), 0
+}
+
+// openFlags : Flags returned to the kernel for an open file
+func (gf *Gofuse) openFlags() uint32 {
+	// direct_io option is used to bypass the kernel cache. It disables the use of
+	// page cache (file content cache) in the kernel for the filesystem.
+	if gf.directIO {
+		return fuse.FOPEN_DIRECT_IO
+	}
+	return fuse.FOPEN_KEEP_CACHE
+}
+
+// Open opens a file
+func (n *node) Open(ctx context.Context, flags uint32) (gofs.FileHandle, uint32, syscall.Errno) {
+	name := n.path()
+	log.Trace("Gofuse::Open : %s", name)
+
+	// Mask out SYNC and DIRECT flags since write operation will fail
+	if flags&syscall.O_SYNC != 0 || flags&syscall.O_DIRECT != 0 {
+		log.Info("Gofuse::Open : Reset flags for open %s, flags %X", name, flags)
+		flags = flags &^ syscall.O_SYNC
+		flags = flags &^ syscall.O_DIRECT
+	}
+	if !n.gf.disableWritebackCache {
+		if flags&syscall.O_ACCMODE == syscall.O_WRONLY || flags&syscall.O_APPEND != 0 {
+			if n.gf.ignoreOpenFlags {
+				log.Warn("Gofuse::Open : Flags (%X) not supported to open %s when write back cache is on. Ignoring unsupported flags.", flags, name)
+				// O_ACCMODE disables both RDONLY, WRONLY and RDWR flags
+				flags = flags &^ (syscall.O_APPEND | syscall.O_ACCMODE)
+				flags = flags | syscall.O_RDWR
+			} else {
+				log.Err("Gofuse::Open : Flag (%X) not supported to open %s when write back cache is on. Pass --disable-writeback-cache=true or --ignore-open-flags=true via CLI", flags, name)
+				return nil, 0, syscall.EINVAL
+			}
+		}
+	}
+
+	handle, err := n.gf.NextComponent().OpenFile(
+		internal.OpenFileOptions{
+			Name:  name,
+			Flags: int(flags),
+			Mode:  fs.FileMode(n.gf.filePermission),
+			Uid:   callerUID(ctx),
+		})
+
+	if err != nil {
+		log.Err("Gofuse::Open : Failed to open %s [%s]", name, err.Error())
+		if os.IsNotExist(err) {
+			return nil, 0, syscall.ENOENT
+		} else if os.IsPermission(err) {
+			return nil, 0, syscall.EACCES
+		}
+		return nil, 0, syscall.EIO
+	}
+
+	handlemap.Add(handle)
+	log.Trace("Gofuse::Open : %s, handle %d", name, handle.ID)
+
+	// increment open file handles count
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, openHandles, (int64)(1))
+
+	return &fileHandle{handle: handle}, n.gf.openFlags(), 0
+}
+
+// Read reads data from an open file
+func (n *node) Read(ctx context.Context, f gofs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	handle := f.(*fileHandle).handle
+
+	var err error
+	var bytesRead int
+
+	if handle.Cached() {
+		bytesRead, err = syscall.Pread(handle.FD(), dest, off)
+	} else {
+		bytesRead, err = n.gf.NextComponent().ReadInBuffer(
+			internal.ReadInBufferOptions{
+				Handle: handle,
+				Offset: off,
+				Data:   dest,
+			})
+	}
+
+	if err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		log.Err("Gofuse::Read : error reading file %s, handle: %d [%s]", handle.Path, handle.ID, err.Error())
+		return nil, syscall.EIO
+	}
+
+	return fuse.ReadResultData(dest[:bytesRead]), 0
+}
+
+// Write writes data to an open file
+func (n *node) Write(ctx context.Context, f gofs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	handle := f.(*fileHandle).handle
+
+	bytesWritten, err := n.gf.NextComponent().WriteFile(
+		internal.WriteFileOptions{
+			Handle:   handle,
+			Offset:   off,
+			Data:     data,
+			Metadata: nil,
+		})
+
+	if err != nil {
+		log.Err("Gofuse::Write : error writing file %s, handle: %d [%s]", handle.Path, handle.ID, err.Error())
+		if err == syscall.EDQUOT {
+			return 0, syscall.EDQUOT
+		}
+		return 0, syscall.EIO
+	}
+
+	return uint32(bytesWritten), 0
+}
+
+// Flush possibly flushes cached data
+func (n *node) Flush(ctx context.Context, f gofs.FileHandle) syscall.Errno {
+	handle := f.(*fileHandle).handle
+	log.Trace("Gofuse::Flush : %s, handle: %d", handle.Path, handle.ID)
+
+	// If the file handle is not dirty, there is no need to flush
+	if !handle.Dirty() {
+		return 0
+	}
+
+	err := n.gf.NextComponent().FlushFile(internal.FlushFileOptions{Handle: handle})
+	if err != nil {
+		log.Err("Gofuse::Flush : error flushing file %s, handle: %d [%s]", handle.Path, handle.ID, err.Error())
+		if err == syscall.ENOENT {
+			return syscall.ENOENT
+		} else if err == syscall.EACCES {
+			return syscall.EACCES
+		}
+		return syscall.EIO
+	}
+
+	return 0
+}
+
+// truncate changes the size of a file
+func (n *node) truncate(off int64) syscall.Errno {
+	name := n.path()
+	log.Trace("Gofuse::truncate : %s size %d", name, off)
+
+	err := n.gf.NextComponent().TruncateFile(internal.TruncateFileOptions{Name: name, Size: off})
+	if err != nil {
+		log.Err("Gofuse::truncate : error truncating file %s [%s]", name, err.Error())
+		if os.IsNotExist(err) {
+			return syscall.ENOENT
+		}
+		return syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(truncateFile, name, map[string]interface{}{size: off})
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, truncateFile, (int64)(1))
+
+	return 0
+}
+
+// Release releases an open file
+func (n *node) Release(ctx context.Context, f gofs.FileHandle) syscall.Errno {
+	handle := f.(*fileHandle).handle
+	log.Trace("Gofuse::Release : %s, handle: %d", handle.Path, handle.ID)
+
+	err := n.gf.NextComponent().CloseFile(internal.CloseFileOptions{Handle: handle})
+	if err != nil {
+		log.Err("Gofuse::Release : error closing file %s, handle: %d [%s]", handle.Path, handle.ID, err.Error())
+		if err == syscall.ENOENT {
+			return syscall.ENOENT
+		} else if err == syscall.EACCES {
+			return syscall.EACCES
+		}
+		return syscall.EIO
+	}
+
+	handlemap.Delete(handle.ID)
+
+	// decrement open file handles count
+	gofuseStatsCollector.UpdateStats(stats_manager.Decrement, openHandles, (int64)(1))
+
+	return 0
+}
+
+// Unlink removes a file
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	path := n.childPath(name)
+	log.Trace("Gofuse::Unlink : %s", path)
+
+	err := n.gf.NextComponent().DeleteFile(internal.DeleteFileOptions{Name: path})
+	if err != nil {
+		log.Err("Gofuse::Unlink : error deleting file %s [%s]", path, err.Error())
+		if os.IsNotExist(err) {
+			return syscall.ENOENT
+		} else if os.IsPermission(err) {
+			return syscall.EACCES
+		}
+		return syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(deleteFile, path, nil)
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, deleteFile, (int64)(1))
+
+	return 0
+}
+
+// Rename renames a file or directory
+// https://man7.org/linux/man-pages/man2/rename.2.html
+// errors handled: EISDIR, ENOENT, ENOTDIR, ENOTEMPTY, EEXIST
+func (n *node) Rename(ctx context.Context, name string, newParent gofs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	srcPath := n.childPath(name)
+	dstPath := newParent.EmbeddedInode().Operations().(*node).childPath(newName)
+	log.Trace("Gofuse::Rename : %s -> %s", srcPath, dstPath)
+
+	// TODO: Support for RENAME_EXCHANGE
+	if flags&renameExchange != 0 {
+		return syscall.ENOTSUP
+	}
+
+	// ENOENT. Not covered: a directory component in dst does not exist
+	if srcPath == "" || dstPath == "" {
+		log.Err("Gofuse::Rename : src: [%s] or dst: [%s] is an empty string", srcPath, dstPath)
+		return syscall.ENOENT
+	}
+
+	srcAttr, srcErr := n.gf.NextComponent().GetAttr(internal.GetAttrOptions{Name: srcPath})
+	if os.IsNotExist(srcErr) {
+		log.Err("Gofuse::Rename : Failed to get attributes of %s [%s]", srcPath, srcErr.Error())
+		return syscall.ENOENT
+	}
+	dstAttr, dstErr := n.gf.NextComponent().GetAttr(internal.GetAttrOptions{Name: dstPath})
+
+	// EEXIST
+	if flags&renameNoReplace != 0 && (dstErr == nil || os.IsExist(dstErr)) {
+		return syscall.EEXIST
+	}
+
+	// EISDIR
+	if (dstErr == nil || os.IsExist(dstErr)) && dstAttr.IsDir() && !srcAttr.IsDir() {
+		log.Err("Gofuse::Rename : dst [%s] is an existing directory but src [%s] is not a directory", dstPath, srcPath)
+		return syscall.EISDIR
+	}
+
+	// ENOTDIR
+	if (dstErr == nil || os.IsExist(dstErr)) && !dstAttr.IsDir() && srcAttr.IsDir() {
+		log.Err("Gofuse::Rename : dst [%s] is an existing file but src [%s] is a directory", dstPath, srcPath)
+		return syscall.ENOTDIR
+	}
+
+	if srcAttr.IsDir() {
+		// ENOTEMPTY
+		if dstErr == nil || os.IsExist(dstErr) {
+			empty := n.gf.NextComponent().IsDirEmpty(internal.IsDirEmptyOptions{Name: dstPath})
+			if !empty {
+				return syscall.ENOTEMPTY
+			}
+		}
+
+		err := n.gf.NextComponent().RenameDir(internal.RenameDirOptions{
+			Src: srcPath,
+			Dst: dstPath,
+		})
+		if err != nil {
+			log.Err("Gofuse::Rename : error renaming directory %s -> %s [%s]", srcPath, dstPath, err.Error())
+			return syscall.EIO
+		}
+
+		gofuseStatsCollector.PushEvents(renameDir, srcPath, map[string]interface{}{source: srcPath, dest: dstPath})
+		gofuseStatsCollector.UpdateStats(stats_manager.Increment, renameDir, (int64)(1))
+
+	} else {
+		err := n.gf.NextComponent().RenameFile(internal.RenameFileOptions{
+			Src:     srcPath,
+			Dst:     dstPath,
+			SrcAttr: srcAttr,
+			DstAttr: dstAttr,
+		})
+		if err != nil {
+			log.Err("Gofuse::Rename : error renaming file %s -> %s [%s]", srcPath, dstPath, err.Error())
+			return syscall.EIO
+		}
+
+		gofuseStatsCollector.PushEvents(renameFile, srcPath, map[string]interface{}{source: srcPath, dest: dstPath})
+		gofuseStatsCollector.UpdateStats(stats_manager.Increment, renameFile, (int64)(1))
+	}
+
+	return 0
+}
+
+// Symlink Operations
+
+// Symlink creates a symbolic link
+func (n *node) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	path := n.childPath(name)
+	targetPath := common.NormalizeObjectName(target)
+	log.Trace("Gofuse::Symlink : Received for %s -> %s", path, targetPath)
+
+	err := n.gf.NextComponent().CreateLink(internal.CreateLinkOptions{Name: path, Target: targetPath})
+	if err != nil {
+		log.Err("Gofuse::Symlink : error linking file %s -> %s [%s]", path, targetPath, err.Error())
+		return nil, syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(createLink, path, map[string]interface{}{trgt: targetPath})
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, createLink, (int64)(1))
+
+	now := time.Now()
+	attr := &internal.ObjAttr{Path: path, Name: name, Size: int64(len(targetPath)), Mtime: now, Atime: now, Ctime: now}
+	attr.Flags.Set(internal.PropFlagSymlink)
+	attr.Flags.Set(internal.PropFlagModeDefault)
+	n.gf.fillAttr(attr, &out.Attr)
+	return n.newChild(ctx, name, attr), 0
+}
+
+// Readlink reads the target of a symbolic link
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	name := n.path()
+
+	linkSize := int64(0)
+	attr, err := n.gf.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err == nil && attr != nil {
+		linkSize = attr.Size
+	}
+
+	targetPath, err := n.gf.NextComponent().ReadLink(internal.ReadLinkOptions{Name: name, Size: linkSize})
+	if err != nil {
+		log.Err("Gofuse::Readlink : error reading link file %s [%s]", name, err.Error())
+		if os.IsNotExist(err) {
+			return nil, syscall.ENOENT
+		}
+		return nil, syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(readLink, name, map[string]interface{}{trgt: targetPath})
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, readLink, (int64)(1))
+
+	return []byte(targetPath), 0
+}
+
+// Fsync synchronizes file contents
+func (n *node) Fsync(ctx context.Context, f gofs.FileHandle, flags uint32) syscall.Errno {
+	fh, ok := f.(*fileHandle)
+	if !ok || fh == nil {
+		return syscall.EIO
+	}
+
+	handle := fh.handle
+	log.Trace("Gofuse::Fsync : %s, handle: %d", handle.Path, handle.ID)
+
+	err := n.gf.NextComponent().SyncFile(internal.SyncFileOptions{Handle: handle})
+	if err != nil {
+		log.Err("Gofuse::Fsync : error syncing file %s [%s]", handle.Path, err.Error())
+		return syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(syncFile, handle.Path, nil)
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, syncFile, (int64)(1))
+
+	return 0
+}
+
+// chmod changes permission bits of a file
+func (n *node) chmod(mode uint32) syscall.Errno {
+	name := n.path()
+	log.Trace("Gofuse::chmod : %s", name)
+
+	err := n.gf.NextComponent().Chmod(
+		internal.ChmodOptions{
+			Name: name,
+			Mode: fs.FileMode(mode),
+		})
+	if err != nil {
+		log.Err("Gofuse::chmod : error in chmod of %s [%s]", name, err.Error())
+		if os.IsNotExist(err) {
+			return syscall.ENOENT
+		} else if os.IsPermission(err) {
+			return syscall.EACCES
+		}
+		return syscall.EIO
+	}
+
+	gofuseStatsCollector.PushEvents(chmod, name, map[string]interface{}{md: fs.FileMode(mode)})
+	gofuseStatsCollector.UpdateStats(stats_manager.Increment, chmod, (int64)(1))
+
+	return 0
+}
+
+// InvalidatePath : Drop what the kernel has cached for a path changed by another client, then pass it down the pipeline.
+// Changes found by components below are only sent to kernel as those components already hold the new state.
+func (gf *Gofuse) InvalidatePath(options internal.InvalidatePathOptions) error {
+	if options.KernelOnly {
+		// Component may have found the change while serving a fuse call on the same path.
+		// Kernel holds locks of the path till that call returns, so notify it asynchronously to avoid a deadlock.
+		go gf.notify(options.Name)
+		return nil
+	}
+
+	gf.notify(options.Name)
+	return gf.NextComponent().InvalidatePath(options)
+}
+
+// notify : Invalidate the cached content and attributes of a path and its entry in the parent directory
+func (gf *Gofuse) notify(name string) {
+	if gf.server == nil || gf.root == nil {
+		return
+	}
+
+	parent := gf.root.EmbeddedInode()
+	elements := strings.Split(internal.TruncateDirName(common.NormalizeObjectName(name)), "/")
+	for _, element := range elements[:len(elements)-1] {
+		parent = parent.GetChild(element)
+		if parent == nil {
+			// Kernel has not looked up this path, nothing is cached for it
+			return
+		}
+	}
+
+	last := elements[len(elements)-1]
+	if child := parent.GetChild(last); child != nil {
+		if errno := child.NotifyContent(0, 0); errno != 0 && errno != syscall.ENOENT {
+			log.Debug("Gofuse::notify : Failed to invalidate content of %s [%s]", name, errno.Error())
+		}
+	}
+
+	if errno := parent.NotifyEntry(last); errno != 0 && errno != syscall.ENOENT {
+		log.Debug("Gofuse::notify : Failed to invalidate entry %s [%s]", name, errno.Error())
+	}
+}
@@ -0,0 +1,534 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gofuse
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/fusetest"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+
+	"github.com/golang/mock/gomock"
+	gofs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type gofuseTestSuite struct {
+	suite.Suite
+	assert   *assert.Assertions
+	gofuse   *Gofuse
+	root     *node
+	mockCtrl *gomock.Controller
+	mock     *internal.MockComponent
+}
+
+var emptyConfig = ""
+
+func newTestGofuse(next internal.Component, configuration string) *Gofuse {
+	config.ReadConfigFromReader(strings.NewReader(configuration))
+	gofuse := NewGofuseComponent()
+	gofuse.SetNextComponent(next)
+	gofuse.Configure(true)
+
+	return gofuse.(*Gofuse)
+}
+
+func (suite *gofuseTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+	suite.setupTestHelper(emptyConfig)
+}
+
+func (suite *gofuseTestSuite) setupTestHelper(config string) {
+	suite.assert = assert.New(suite.T())
+
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mock = internal.NewMockComponent(suite.mockCtrl)
+	suite.gofuse = newTestGofuse(suite.mock, config)
+
+	// Build the inode tree without mounting it
+	suite.root = &node{gf: suite.gofuse}
+	gofs.NewNodeFS(suite.root, suite.gofuse.mountOptions())
+}
+
+func (suite *gofuseTestSuite) cleanupTest() {
+	suite.mockCtrl.Finish()
+}
+
+// child : Add a node for the given name under the root, as if the kernel had looked it up
+func (suite *gofuseTestSuite) child(name string, mode uint32) *node {
+	n := &node{gf: suite.gofuse}
+	inode := suite.root.NewPersistentInode(context.Background(), n, gofs.StableAttr{Mode: mode})
+	suite.root.AddChild(name, inode, true)
+	return n
+}
+
+// Options shared with libfuse are tested by the frontend suite, these are the ones handed to go-fuse
+func (suite *gofuseTestSuite) TestMountOptions() {
+	defer suite.cleanupTest()
+	suite.assert.Equal(suite.gofuse.maxFuseThreads, uint32(defaultMaxFuseThreads))
+	suite.assert.Equal(uint32(fuse.FOPEN_KEEP_CACHE), suite.gofuse.openFlags())
+
+	suite.cleanupTest() // clean up the default gofuse generated
+	config := "allow-other: true\nread-only: true\ngofuse:\n  attribute-expiration-sec: 60\n  entry-expiration-sec: 60\n  negative-entry-expiration-sec: 60\n  fuse-trace: true\n  disable-writeback-cache: true\n  ignore-open-flags: false\n  direct-io: true\n"
+	suite.setupTestHelper(config) // setup a new gofuse with a custom config (clean up will occur after the test as usual)
+
+	opts := suite.gofuse.mountOptions()
+	suite.assert.True(opts.AllowOther)
+	suite.assert.Contains(opts.Options, "ro")
+	suite.assert.Zero(*opts.EntryTimeout)
+	suite.assert.Zero(*opts.AttrTimeout)
+	suite.assert.Zero(*opts.NegativeTimeout)
+	suite.assert.False(opts.Debug)
+	suite.assert.Equal(uint32(fuse.FOPEN_DIRECT_IO), suite.gofuse.openFlags())
+
+	suite.cleanupTest() // clean up the default gofuse generated
+	config = "foreground: true\ngofuse:\n  fuse-trace: true\n"
+	common.ForegroundMount = true
+	suite.setupTestHelper(config) // setup a new gofuse with a custom config (clean up will occur after the test as usual)
+	suite.assert.True(suite.gofuse.mountOptions().Debug)
+	common.ForegroundMount = false
+}
+
+func (suite *gofuseTestSuite) TestConfigFuseOptions() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default gofuse generated
+	// Options given through -o on command line
+	config := "allow-root: true\nnonempty: true\nlfuse:\n  attribute-expiration-sec: 10\n  uid: 1000\n  gid: 1001\n  umask: 22\n"
+	suite.setupTestHelper(config) // setup a new gofuse with a custom config (clean up will occur after the test as usual)
+
+	suite.assert.Equal(suite.gofuse.attributeExpiration, uint32(10))
+	suite.assert.Equal(suite.gofuse.entryExpiration, uint32(120))
+	suite.assert.Equal(suite.gofuse.ownerUID, uint32(1000))
+	suite.assert.Equal(suite.gofuse.ownerGID, uint32(1001))
+	suite.assert.Equal(suite.gofuse.umask, uint32(0022))
+
+	opts := suite.gofuse.mountOptions()
+	suite.assert.Contains(opts.Options, "allow_root")
+	suite.assert.Contains(opts.Options, "nonempty")
+
+	// umask is applied to the permissions of every file
+	out := &fuse.Attr{}
+	attr := &internal.ObjAttr{Mode: 0777}
+	suite.gofuse.fillAttr(attr, out)
+	suite.assert.Equal(uint32(syscall.S_IFREG|0755), out.Mode)
+	suite.assert.Equal(uint32(1000), out.Uid)
+	suite.assert.Equal(uint32(1001), out.Gid)
+}
+
+func (suite *gofuseTestSuite) TestGetAttrRoot() {
+	defer suite.cleanupTest()
+	out := &fuse.AttrOut{}
+	err := suite.root.Getattr(context.Background(), nil, out)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal(uint32(syscall.S_IFDIR|0777), out.Mode)
+	suite.assert.Equal(suite.gofuse.ownerUID, out.Uid)
+}
+
+func (suite *gofuseTestSuite) TestLookup() {
+	defer suite.cleanupTest()
+	name := "path"
+	attr := &internal.ObjAttr{Name: name, Size: 10, Mode: 0775, Flags: internal.NewDirBitMap()}
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(attr, nil)
+
+	out := &fuse.EntryOut{}
+	inode, err := suite.root.Lookup(context.Background(), name, out)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.True(inode.IsDir())
+	suite.assert.Equal(uint32(syscall.S_IFDIR|0775), out.Mode)
+	suite.assert.Equal(uint64(4096), out.Size)
+}
+
+func (suite *gofuseTestSuite) TestLookupNotExists() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(nil, syscall.ENOENT)
+
+	_, err := suite.root.Lookup(context.Background(), name, &fuse.EntryOut{})
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	// Ignored files are never looked up in storage
+	_, err = suite.root.Lookup(context.Background(), ".Trash", &fuse.EntryOut{})
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+func (suite *gofuseTestSuite) TestReadDir() {
+	defer suite.cleanupTest()
+	dir := suite.child("dir", syscall.S_IFDIR)
+
+	fh, _, err := dir.OpendirHandle(context.Background(), 0)
+	suite.assert.Equal(syscall.Errno(0), err)
+	d := fh.(*dirHandle)
+
+	first := []*internal.ObjAttr{{Name: "a", Flags: internal.NewDirBitMap()}}
+	second := []*internal.ObjAttr{{Name: "b"}, {Name: "c"}}
+	suite.mock.EXPECT().StreamDir(internal.StreamDirOptions{Name: "dir/", Count: common.MaxDirListCount}).Return(first, "token", nil)
+	suite.mock.EXPECT().StreamDir(internal.StreamDirOptions{Name: "dir/", Offset: 1, Token: "token", Count: common.MaxDirListCount}).Return(second, "", nil)
+
+	names := []string{}
+	for {
+		entry, err := d.Readdirent(context.Background())
+		suite.assert.Equal(syscall.Errno(0), err)
+		if entry == nil {
+			break
+		}
+		if entry.Name == "a" {
+			suite.assert.Equal(uint32(syscall.S_IFDIR), entry.Mode)
+		}
+		names = append(names, entry.Name)
+	}
+	suite.assert.Equal([]string{"a", "b", "c"}, names)
+
+	// Seeking back to the start lists the directory again
+	suite.assert.Equal(syscall.Errno(0), d.Seekdir(context.Background(), 0))
+	suite.mock.EXPECT().StreamDir(internal.StreamDirOptions{Name: "dir/", Count: common.MaxDirListCount}).Return(nil, "", syscall.ENOENT)
+	_, err = d.Readdirent(context.Background())
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	d.Releasedir(context.Background(), 0)
+	_, found := handlemap.Load(d.handle.ID)
+	suite.assert.False(found)
+}
+
+func (suite *gofuseTestSuite) TestReadWrite() {
+	defer suite.cleanupTest()
+	file := suite.child("path", syscall.S_IFREG)
+	handle := handlemap.NewHandle("path")
+	fh := &fileHandle{handle: handle}
+
+	data := []byte("data")
+	suite.mock.EXPECT().WriteFile(internal.WriteFileOptions{Handle: handle, Offset: 2, Data: data}).Return(len(data), nil)
+	written, err := file.Write(context.Background(), fh, data, 2)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal(uint32(len(data)), written)
+
+	suite.mock.EXPECT().WriteFile(gomock.Any()).Return(0, syscall.EDQUOT)
+	_, err = file.Write(context.Background(), fh, data, 2)
+	suite.assert.Equal(syscall.EDQUOT, err)
+
+	buf := make([]byte, 8)
+	suite.mock.EXPECT().ReadInBuffer(internal.ReadInBufferOptions{Handle: handle, Offset: 2, Data: buf}).Return(4, nil)
+	res, err := file.Read(context.Background(), fh, buf, 2)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal(4, res.Size())
+
+	suite.mock.EXPECT().ReadInBuffer(gomock.Any()).Return(0, errors.New("failed to read"))
+	_, err = file.Read(context.Background(), fh, buf, 2)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *gofuseTestSuite) TestFlushRelease() {
+	defer suite.cleanupTest()
+	file := suite.child("path", syscall.S_IFREG)
+	handle := handlemap.NewHandle("path")
+	handlemap.Add(handle)
+	fh := &fileHandle{handle: handle}
+
+	// Handle which is not dirty is not flushed
+	err := file.Flush(context.Background(), fh)
+	suite.assert.Equal(syscall.Errno(0), err)
+
+	handle.Flags.Set(handlemap.HandleFlagDirty)
+	suite.mock.EXPECT().FlushFile(internal.FlushFileOptions{Handle: handle}).Return(errors.New("failed to flush"))
+	err = file.Flush(context.Background(), fh)
+	suite.assert.Equal(syscall.EIO, err)
+
+	suite.mock.EXPECT().CloseFile(internal.CloseFileOptions{Handle: handle}).Return(nil)
+	err = file.Release(context.Background(), fh)
+	suite.assert.Equal(syscall.Errno(0), err)
+	_, found := handlemap.Load(handle.ID)
+	suite.assert.False(found)
+}
+
+func (suite *gofuseTestSuite) TestSymlinkEntry() {
+	defer suite.cleanupTest()
+	name := "path"
+	target := "target"
+	suite.mock.EXPECT().CreateLink(internal.CreateLinkOptions{Name: name, Target: target}).Return(nil)
+
+	out := &fuse.EntryOut{}
+	inode, err := suite.root.Symlink(context.Background(), target, name, out)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal(uint32(syscall.S_IFLNK), out.Mode&syscall.S_IFMT)
+	suite.assert.Equal(uint32(syscall.S_IFLNK), inode.Mode())
+}
+
+func (suite *gofuseTestSuite) TestSetattr() {
+	defer suite.cleanupTest()
+	name := "path"
+	file := suite.child(name, syscall.S_IFREG)
+	size := int64(1024)
+	mode := fs.FileMode(0775)
+	suite.mock.EXPECT().TruncateFile(internal.TruncateFileOptions{Name: name, Size: size}).Return(nil)
+	suite.mock.EXPECT().Chmod(internal.ChmodOptions{Name: name, Mode: mode}).Return(nil)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(&internal.ObjAttr{Size: size, Mode: mode}, nil)
+
+	// Setattr replies with the attributes of the file after the change
+	in := &fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_SIZE | fuse.FATTR_MODE
+	in.Size = uint64(size)
+	in.Mode = 0775
+	out := &fuse.AttrOut{}
+	err := file.Setattr(context.Background(), nil, in, out)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal(uint64(size), out.Size)
+	suite.assert.Equal(uint32(syscall.S_IFREG|0775), out.Mode)
+
+	// Failed truncate is not followed by the other changes
+	suite.mock.EXPECT().TruncateFile(internal.TruncateFileOptions{Name: name, Size: size}).Return(errors.New("failed to truncate file"))
+	err = file.Setattr(context.Background(), nil, in, out)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *gofuseTestSuite) TestRename() {
+	defer suite.cleanupTest()
+	dir := suite.child("dir", syscall.S_IFDIR)
+	srcAttr := &internal.ObjAttr{Name: "src"}
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "src"}).Return(srcAttr, nil)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dir/dst"}).Return(nil, syscall.ENOENT)
+	suite.mock.EXPECT().RenameFile(internal.RenameFileOptions{Src: "src", Dst: "dir/dst", SrcAttr: srcAttr}).Return(nil)
+
+	err := suite.root.Rename(context.Background(), "src", dir, "dst", 0)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *gofuseTestSuite) TestRenameErrors() {
+	defer suite.cleanupTest()
+	file := &internal.ObjAttr{Name: "file"}
+	dir := &internal.ObjAttr{Name: "dir", Flags: internal.NewDirBitMap()}
+
+	err := suite.root.Rename(context.Background(), "src", suite.root, "dst", renameExchange)
+	suite.assert.Equal(syscall.ENOTSUP, err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "src"}).Return(nil, syscall.ENOENT)
+	err = suite.root.Rename(context.Background(), "src", suite.root, "dst", 0)
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "src"}).Return(file, nil)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dst"}).Return(file, nil)
+	err = suite.root.Rename(context.Background(), "src", suite.root, "dst", renameNoReplace)
+	suite.assert.Equal(syscall.EEXIST, err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "src"}).Return(file, nil)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dst"}).Return(dir, nil)
+	err = suite.root.Rename(context.Background(), "src", suite.root, "dst", 0)
+	suite.assert.Equal(syscall.EISDIR, err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "src"}).Return(dir, nil)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dst"}).Return(file, nil)
+	err = suite.root.Rename(context.Background(), "src", suite.root, "dst", 0)
+	suite.assert.Equal(syscall.ENOTDIR, err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "src"}).Return(dir, nil)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: "dst"}).Return(dir, nil)
+	suite.mock.EXPECT().IsDirEmpty(internal.IsDirEmptyOptions{Name: "dst"}).Return(false)
+	err = suite.root.Rename(context.Background(), "src", suite.root, "dst", 0)
+	suite.assert.Equal(syscall.ENOTEMPTY, err)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestGofuseTestSuite(t *testing.T) {
+	suite.Run(t, new(gofuseTestSuite))
+}
+
+// frontend : Drives gofuse for the handler tests shared with libfuse
+type frontend struct {
+	gofuse *Gofuse
+	root   *node
+	mock   *internal.MockComponent
+}
+
+func (f *frontend) Setup(next *internal.MockComponent, config string) {
+	f.mock = next
+	f.gofuse = newTestGofuse(next, config)
+
+	// Build the inode tree without mounting it
+	f.root = &node{gf: f.gofuse}
+	gofs.NewNodeFS(f.root, f.gofuse.mountOptions())
+}
+
+func (f *frontend) Component() internal.Component {
+	return f.gofuse
+}
+
+func (f *frontend) Options() fusetest.Options {
+	return fusetest.Options{
+		MountPath:             f.gofuse.mountPath,
+		ReadOnly:              f.gofuse.readOnly,
+		TraceEnable:           f.gofuse.traceEnable,
+		AllowOther:            f.gofuse.allowOther,
+		AllowRoot:             f.gofuse.allowRoot,
+		DirPermission:         f.gofuse.dirPermission,
+		FilePermission:        f.gofuse.filePermission,
+		EntryExpiration:       f.gofuse.entryExpiration,
+		AttributeExpiration:   f.gofuse.attributeExpiration,
+		NegativeTimeout:       f.gofuse.negativeTimeout,
+		DisableWritebackCache: f.gofuse.disableWritebackCache,
+		IgnoreOpenFlags:       f.gofuse.ignoreOpenFlags,
+		DirectIO:              f.gofuse.directIO,
+	}
+}
+
+func (f *frontend) WritebackCache() bool {
+	return true
+}
+
+// node : Node of the given name under the root, added as if the kernel had looked it up
+func (f *frontend) node(name string, mode uint32) *node {
+	if inode := f.root.GetChild(name); inode != nil {
+		return inode.Operations().(*node)
+	}
+
+	n := &node{gf: f.gofuse}
+	inode := f.root.NewPersistentInode(context.Background(), n, gofs.StableAttr{Mode: mode})
+	f.root.AddChild(name, inode, true)
+	return n
+}
+
+func (f *frontend) Getattr(name string) (*syscall.Stat_t, syscall.Errno) {
+	out := &fuse.AttrOut{}
+	errno := f.node(name, syscall.S_IFREG).Getattr(context.Background(), nil, out)
+	return &syscall.Stat_t{
+		Mode: out.Mode,
+		Size: int64(out.Size),
+		Uid:  out.Uid,
+		Gid:  out.Gid,
+		Mtim: syscall.Timespec{Sec: int64(out.Mtime), Nsec: int64(out.Mtimensec)},
+	}, errno
+}
+
+func (f *frontend) Mkdir(name string, mode uint32) syscall.Errno {
+	_, errno := f.root.Mkdir(context.Background(), name, mode, &fuse.EntryOut{})
+	return errno
+}
+
+func (f *frontend) Rmdir(name string) syscall.Errno {
+	return f.root.Rmdir(context.Background(), name)
+}
+
+func (f *frontend) FsyncDir(name string) syscall.Errno {
+	fh, _, errno := f.node(name, syscall.S_IFDIR).OpendirHandle(context.Background(), 0)
+	if errno != 0 {
+		return errno
+	}
+	return fh.(*dirHandle).Fsyncdir(context.Background(), 0)
+}
+
+func (f *frontend) Create(name string, mode uint32) syscall.Errno {
+	inode, _, _, errno := f.root.Create(context.Background(), name, 0, mode, &fuse.EntryOut{})
+	if errno == 0 {
+		// Attach the new inode to its parent the way the bridge does after create
+		f.root.AddChild(name, inode, true)
+	}
+	return errno
+}
+
+func (f *frontend) Open(name string, flags int) (fusetest.File, syscall.Errno) {
+	fh, _, errno := f.node(name, syscall.S_IFREG).Open(context.Background(), uint32(flags))
+	if errno != 0 {
+		return nil, errno
+	}
+	return fh, 0
+}
+
+func (f *frontend) Fsync(name string, file fusetest.File) syscall.Errno {
+	fh, _ := file.(gofs.FileHandle)
+	return f.node(name, syscall.S_IFREG).Fsync(context.Background(), fh, 0)
+}
+
+func (f *frontend) Truncate(name string, size int64) syscall.Errno {
+	return f.node(name, syscall.S_IFREG).truncate(size)
+}
+
+func (f *frontend) Unlink(name string) syscall.Errno {
+	return f.root.Unlink(context.Background(), name)
+}
+
+func (f *frontend) Symlink(target string, name string) syscall.Errno {
+	_, errno := f.root.Symlink(context.Background(), target, name, &fuse.EntryOut{})
+	return errno
+}
+
+func (f *frontend) Readlink(name string) (string, syscall.Errno) {
+	target, errno := f.node(name, syscall.S_IFLNK).Readlink(context.Background())
+	return string(target), errno
+}
+
+func (f *frontend) Chmod(name string, mode uint32) syscall.Errno {
+	return f.node(name, syscall.S_IFREG).chmod(mode)
+}
+
+func (f *frontend) Chown(name string, uid uint32, gid uint32) syscall.Errno {
+	// go-fuse replies to setattr with the attributes of the file
+	f.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(&internal.ObjAttr{}, nil)
+
+	in := &fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_UID | fuse.FATTR_GID
+	in.Uid = uid
+	in.Gid = gid
+	return f.node(name, syscall.S_IFREG).Setattr(context.Background(), nil, in, &fuse.AttrOut{})
+}
+
+func (f *frontend) Utimens(name string) syscall.Errno {
+	// go-fuse replies to setattr with the attributes of the file
+	f.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(&internal.ObjAttr{}, nil)
+
+	in := &fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_ATIME | fuse.FATTR_MTIME
+	return f.node(name, syscall.S_IFREG).Setattr(context.Background(), nil, in, &fuse.AttrOut{})
+}
+
+func (f *frontend) Statfs() (*syscall.Statfs_t, syscall.Errno) {
+	out := &fuse.StatfsOut{}
+	errno := f.root.Statfs(context.Background(), out)
+	return &syscall.Statfs_t{Frsize: int64(out.Frsize), Blocks: out.Blocks, Bavail: out.Bavail, Bfree: out.Bfree}, errno
+}
+
+func TestGofuseFrontendSuite(t *testing.T) {
+	suite.Run(t, fusetest.NewFrontendSuite("gofuse", &frontend{}))
+}
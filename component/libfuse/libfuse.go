@@ -75,6 +75,8 @@ type Libfuse struct {
 	maxFuseThreads        uint32
 	directIO              bool
 	umask                 uint32
+	notifier              *notifyQueue // kernel invalidations waiting to be sent
+	nodeIds               *nodeIdCache // node ids of directories, used to invalidate entries in them
 }
 
 // To support pagination in readdir calls this structure holds a block of items for a given directory
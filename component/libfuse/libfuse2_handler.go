@@ -602,13 +602,15 @@ func libfuse_create(path *C.char, mode C.mode_t, fi *C.fuse_file_info_t) C.int {
 	name = common.NormalizeObjectName(name)
 	log.Trace("Libfuse::libfuse2_create : %s", name)
 
-	handle, err := fuseFS.NextComponent().CreateFile(internal.CreateFileOptions{Name: name, Mode: fs.FileMode(uint32(mode) & 0xffffffff)})
+	handle, err := fuseFS.NextComponent().CreateFile(internal.CreateFileOptions{Name: name, Mode: fs.FileMode(uint32(mode) & 0xffffffff), Uid: uint32(C.get_caller_uid())})
 	if err != nil {
 		log.Err("Libfuse::libfuse2_create : Failed to create %s [%s]", name, err.Error())
 		if os.IsExist(err) {
 			return -C.EEXIST
 		} else if os.IsPermission(err) {
 			return -C.EACCES
+		} else if err == syscall.EDQUOT {
+			return -C.EDQUOT
 		} else {
 			return -C.EIO
 		}
@@ -654,6 +656,7 @@ func libfuse_open(path *C.char, fi *C.fuse_file_info_t) C.int {
 			Name:  name,
 			Flags: int(int(fi.flags) & 0xffffffff),
 			Mode:  fs.FileMode(fuseFS.filePermission),
+			Uid:   uint32(C.get_caller_uid()),
 		})
 
 	if err != nil {
@@ -736,6 +739,9 @@ func libfuse_write(path *C.char, buf *C.char, size C.size_t, off C.off_t, fi *C.
 
 	if err != nil {
 		log.Err("Libfuse::libfuse2_write : error writing file %s, handle: %d [%s]", handle.Path, handle.ID, err.Error())
+		if err == syscall.EDQUOT {
+			return -C.EDQUOT
+		}
 		return -C.EIO
 	}
 
@@ -1097,6 +1103,16 @@ func libfuse2_utimens(path *C.char, tv *C.timespec_t) C.int {
 	return 0
 }
 
+// InvalidatePath : libfuse2 high level API can not send invalidation notifications,
+// kernel caches expire with the configured timeouts so the change is only passed down the pipeline
+func (lf *Libfuse) InvalidatePath(options internal.InvalidatePathOptions) error {
+	if options.KernelOnly {
+		return nil
+	}
+
+	return lf.NextComponent().InvalidatePath(options)
+}
+
 // blobfuse_cache_update refresh the file-cache policy for this file
 //
 //export blobfuse_cache_update
@@ -41,7 +41,6 @@ package libfuse
 // #include "libfuse_wrapper.h"
 import "C"
 import (
-	"errors"
 	"io/fs"
 	"strings"
 	"syscall"
@@ -51,6 +50,7 @@ import (
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/fusetest"
 	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
 
 	"github.com/golang/mock/gomock"
@@ -66,12 +66,6 @@ type libfuseTestSuite struct {
 	mock     *internal.MockComponent
 }
 
-type fileHandle struct {
-	fd  uint64
-	obj uint64
-}
-
-// Open and create call returns this kind of object
 var emptyConfig = ""
 var defaultSize = int64(0)
 var defaultMode = 0777
@@ -108,142 +102,8 @@ func (suite *libfuseTestSuite) cleanupTest() {
 	suite.mockCtrl.Finish()
 }
 
-func testMkDir(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(0775)
-	options := internal.CreateDirOptions{Name: name, Mode: mode}
-	suite.mock.EXPECT().CreateDir(options).Return(nil)
-
-	err := libfuse_mkdir(path, 0775)
-	suite.assert.Equal(C.int(0), err)
-}
-
-func testStatFs(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	path := C.CString("/")
-	defer C.free(unsafe.Pointer(path))
-	suite.mock.EXPECT().StatFs().Return(&syscall.Statfs_t{Frsize: 1,
-		Blocks: 2, Bavail: 3, Bfree: 4}, true, nil)
-	buf := &C.statvfs_t{}
-	libfuse_statfs(path, buf)
-
-	suite.assert.Equal(int(buf.f_frsize), 1)
-	suite.assert.Equal(int(buf.f_blocks), 2)
-	suite.assert.Equal(int(buf.f_bavail), 3)
-	suite.assert.Equal(int(buf.f_bfree), 4)
-}
-
-func testMkDirError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(0775)
-	options := internal.CreateDirOptions{Name: name, Mode: mode}
-	suite.mock.EXPECT().CreateDir(options).Return(errors.New("failed to create directory"))
-
-	err := libfuse_mkdir(path, 0775)
-	suite.assert.Equal(C.int(-C.EIO), err)
-}
-
-// TODO: ReadDir test
-
-func testRmDir(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	isDirEmptyOptions := internal.IsDirEmptyOptions{Name: name}
-	suite.mock.EXPECT().IsDirEmpty(isDirEmptyOptions).Return(true)
-	deleteDirOptions := internal.DeleteDirOptions{Name: name}
-	suite.mock.EXPECT().DeleteDir(deleteDirOptions).Return(nil)
-
-	err := libfuse_rmdir(path)
-	suite.assert.Equal(C.int(0), err)
-}
-
-func testRmDirNotEmpty(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	isDirEmptyOptions := internal.IsDirEmptyOptions{Name: name}
-	suite.mock.EXPECT().IsDirEmpty(isDirEmptyOptions).Return(false)
-
-	err := libfuse_rmdir(path)
-	suite.assert.Equal(C.int(-C.ENOTEMPTY), err)
-}
-
-func testRmDirError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	isDirEmptyOptions := internal.IsDirEmptyOptions{Name: name}
-	suite.mock.EXPECT().IsDirEmpty(isDirEmptyOptions).Return(true)
-	deleteDirOptions := internal.DeleteDirOptions{Name: name}
-	suite.mock.EXPECT().DeleteDir(deleteDirOptions).Return(errors.New("failed to delete directory"))
-
-	err := libfuse_rmdir(path)
-	suite.assert.Equal(C.int(-C.EIO), err)
-}
-
-func testCreate(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(0775)
-	info := &C.fuse_file_info_t{}
-	options := internal.CreateFileOptions{Name: name, Mode: mode}
-	suite.mock.EXPECT().CreateFile(options).Return(&handlemap.Handle{}, nil)
-
-	err := libfuse_create(path, 0775, info)
-	suite.assert.Equal(C.int(0), err)
-
-	option := internal.GetAttrOptions{Name: name}
-	suite.mock.EXPECT().GetAttr(option).Return(&internal.ObjAttr{}, nil)
-	stbuf := &C.stat_t{}
-	err = libfuse2_getattr(path, stbuf)
-	suite.assert.Equal(C.int(0), err)
-	suite.assert.Equal(stbuf.st_mtim.tv_nsec, C.long(0))
-	suite.assert.NotEqual(stbuf.st_mtim.tv_sec, C.long(0))
-}
-
-func testCreateError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(0775)
-	info := &C.fuse_file_info_t{}
-	options := internal.CreateFileOptions{Name: name, Mode: mode}
-	suite.mock.EXPECT().CreateFile(options).Return(&handlemap.Handle{}, errors.New("failed to create file"))
-
-	err := libfuse_create(path, 0775, info)
-	suite.assert.Equal(C.int(-C.EIO), err)
-}
-
-func testOpen(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR & 0xffffffff
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR
-	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err := libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-}
-
-func testOpenSyncDirectFlag(suite *libfuseTestSuite) {
+// Open shall clear the flags it does not pass down from what it hands back to libfuse
+func testOpenResetFlags(suite *libfuseTestSuite) {
 	defer suite.cleanupTest()
 	name := "path"
 	path := C.CString("/" + name)
@@ -261,427 +121,172 @@ func testOpenSyncDirectFlag(suite *libfuseTestSuite) {
 	suite.assert.Equal(C.int(0), info.flags&C.__O_DIRECT)
 }
 
-// fuse2 does not have writeback caching, so append flag is passed unchanged
-func testOpenAppendFlagDefault(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR | C.O_APPEND&0xffffffff
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR | C.O_APPEND
-	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err := libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-
-	flags = C.O_WRONLY | C.O_APPEND&0xffffffff
-	info = &C.fuse_file_info_t{}
-	info.flags = C.O_WRONLY | C.O_APPEND
-	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err = libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-}
-
-func testOpenAppendFlagDisableWritebackCache(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	suite.cleanupTest() // clean up the default libfuse generated
-	config := "libfuse:\n  disable-writeback-cache: true\n"
-	suite.setupTestHelper(config) // setup a new libfuse with a custom config (clean up will occur after the test as usual)
-	suite.assert.True(suite.libfuse.disableWritebackCache)
-
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR | C.O_APPEND&0xffffffff
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR | C.O_APPEND
-	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err := libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-
-	flags = C.O_WRONLY | C.O_APPEND&0xffffffff
-	info = &C.fuse_file_info_t{}
-	info.flags = C.O_WRONLY | C.O_APPEND
-	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err = libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-}
-
-func testOpenAppendFlagIgnoreAppendFlag(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	suite.cleanupTest() // clean up the default libfuse generated
-	config := "libfuse:\n  ignore-open-flags: true\n"
-	suite.setupTestHelper(config) // setup a new libfuse with a custom config (clean up will occur after the test as usual)
-	suite.assert.True(suite.libfuse.ignoreOpenFlags)
-
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR | C.O_APPEND&0xffffffff
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR | C.O_APPEND
-	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err := libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-
-	flags = C.O_WRONLY | C.O_APPEND&0xffffffff
-	info = &C.fuse_file_info_t{}
-	info.flags = C.O_WRONLY | C.O_APPEND
-	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err = libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-
-	flags = C.O_WRONLY & 0xffffffff
-	info = &C.fuse_file_info_t{}
-	info.flags = C.O_WRONLY
-	options = internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
-
-	err = libfuse_open(path, info)
-	suite.assert.Equal(C.int(0), err)
-}
-
-func testOpenNotExists(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR & 0xffffffff
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR
-	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, syscall.ENOENT)
-
-	err := libfuse_open(path, info)
-	suite.assert.Equal(C.int(-C.ENOENT), err)
+// frontend : Drives libfuse for the handler tests shared with gofuse
+type frontend struct {
+	libfuse *Libfuse
 }
 
-func testOpenError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR & 0xffffffff
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR
-	options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, errors.New("failed to open a file"))
-
-	err := libfuse_open(path, info)
-	suite.assert.Equal(C.int(-C.EIO), err)
+func (f *frontend) Setup(next *internal.MockComponent, config string) {
+	f.libfuse = newTestLibfuse(next, config)
+	fuseFS = f.libfuse
 }
 
-func testTruncate(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	size := int64(1024)
-	options := internal.TruncateFileOptions{Name: name, Size: size}
-	suite.mock.EXPECT().TruncateFile(options).Return(nil)
-
-	err := libfuse2_truncate(path, C.long(size))
-	suite.assert.Equal(C.int(0), err)
+func (f *frontend) Component() internal.Component {
+	return f.libfuse
 }
 
-func testTruncateError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	size := int64(1024)
-	options := internal.TruncateFileOptions{Name: name, Size: size}
-	suite.mock.EXPECT().TruncateFile(options).Return(errors.New("failed to truncate file"))
-
-	err := libfuse2_truncate(path, C.long(size))
-	suite.assert.Equal(C.int(-C.EIO), err)
+func (f *frontend) Options() fusetest.Options {
+	return fusetest.Options{
+		MountPath:             f.libfuse.mountPath,
+		ReadOnly:              f.libfuse.readOnly,
+		TraceEnable:           f.libfuse.traceEnable,
+		AllowOther:            f.libfuse.allowOther,
+		AllowRoot:             f.libfuse.allowRoot,
+		DirPermission:         f.libfuse.dirPermission,
+		FilePermission:        f.libfuse.filePermission,
+		EntryExpiration:       f.libfuse.entryExpiration,
+		AttributeExpiration:   f.libfuse.attributeExpiration,
+		NegativeTimeout:       f.libfuse.negativeTimeout,
+		DisableWritebackCache: f.libfuse.disableWritebackCache,
+		IgnoreOpenFlags:       f.libfuse.ignoreOpenFlags,
+		DirectIO:              f.libfuse.directIO,
+	}
 }
 
-func testUnlink(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	options := internal.DeleteFileOptions{Name: name}
-	suite.mock.EXPECT().DeleteFile(options).Return(nil)
-
-	err := libfuse_unlink(path)
-	suite.assert.Equal(C.int(0), err)
+// fuse2 does not have writeback caching, so write-only and append opens are passed unchanged
+func (f *frontend) WritebackCache() bool {
+	return false
 }
 
-func testUnlinkNotExists(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	options := internal.DeleteFileOptions{Name: name}
-	suite.mock.EXPECT().DeleteFile(options).Return(syscall.ENOENT)
-
-	err := libfuse_unlink(path)
-	suite.assert.Equal(C.int(-C.ENOENT), err)
+// errno : Convert the result of a libfuse callback to an errno
+func errno(res C.int) syscall.Errno {
+	return syscall.Errno(-res)
 }
 
-func testUnlinkError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Getattr(name string) (*syscall.Stat_t, syscall.Errno) {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	options := internal.DeleteFileOptions{Name: name}
-	suite.mock.EXPECT().DeleteFile(options).Return(errors.New("failed to delete file"))
 
-	err := libfuse_unlink(path)
-	suite.assert.Equal(C.int(-C.EIO), err)
+	stbuf := &C.stat_t{}
+	res := libfuse2_getattr(path, stbuf)
+	return &syscall.Stat_t{
+		Mode: uint32(stbuf.st_mode),
+		Size: int64(stbuf.st_size),
+		Uid:  uint32(stbuf.st_uid),
+		Gid:  uint32(stbuf.st_gid),
+		Mtim: syscall.Timespec{Sec: int64(stbuf.st_mtim.tv_sec), Nsec: int64(stbuf.st_mtim.tv_nsec)},
+	}, errno(res)
 }
 
-// Rename
-
-func testSymlink(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	target := "target"
+func (f *frontend) Mkdir(name string, mode uint32) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	t := C.CString(target)
-	defer C.free(unsafe.Pointer(t))
-	options := internal.CreateLinkOptions{Name: name, Target: target}
-	suite.mock.EXPECT().CreateLink(options).Return(nil)
-
-	err := libfuse_symlink(t, path)
-	suite.assert.Equal(C.int(0), err)
+	return errno(libfuse_mkdir(path, C.mode_t(mode)))
 }
 
-func testSymlinkError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	target := "target"
+func (f *frontend) Rmdir(name string) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	t := C.CString(target)
-	defer C.free(unsafe.Pointer(t))
-	options := internal.CreateLinkOptions{Name: name, Target: target}
-	suite.mock.EXPECT().CreateLink(options).Return(errors.New("failed to create link"))
-
-	err := libfuse_symlink(t, path)
-	suite.assert.Equal(C.int(-C.EIO), err)
+	return errno(libfuse_rmdir(path))
 }
 
-func testReadLink(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) FsyncDir(name string) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	options := internal.ReadLinkOptions{Name: name}
-	suite.mock.EXPECT().ReadLink(options).Return("target", nil)
-	attr := &internal.ObjAttr{}
-	getAttrOpt := internal.GetAttrOptions{Name: name}
-	suite.mock.EXPECT().GetAttr(getAttrOpt).Return(attr, nil)
-
-	// https://stackoverflow.com/questions/41953619/how-to-initialise-empty-c-cstring-in-cgo
-	buf := C.CString("")
-	err := libfuse_readlink(path, buf, 7)
-	suite.assert.Equal(C.int(0), err)
-	suite.assert.Equal("target", C.GoString(buf))
+	return errno(libfuse_fsyncdir(path, C.int(0), nil))
 }
 
-func testReadLinkNotExists(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Create(name string, mode uint32) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	options := internal.ReadLinkOptions{Name: name}
-	suite.mock.EXPECT().ReadLink(options).Return("", syscall.ENOENT)
-	attr := &internal.ObjAttr{}
-	getAttrOpt := internal.GetAttrOptions{Name: name}
-	suite.mock.EXPECT().GetAttr(getAttrOpt).Return(attr, nil)
-
-	buf := C.CString("")
-	err := libfuse_readlink(path, buf, 7)
-	suite.assert.Equal(C.int(-C.ENOENT), err)
-	suite.assert.NotEqual("target", C.GoString(buf))
+	return errno(libfuse_create(path, C.mode_t(mode), &C.fuse_file_info_t{}))
 }
 
-func testReadLinkError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Open(name string, flags int) (fusetest.File, syscall.Errno) {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	options := internal.ReadLinkOptions{Name: name}
-	suite.mock.EXPECT().ReadLink(options).Return("", errors.New("failed to read link"))
-	getAttrOpt := internal.GetAttrOptions{Name: name}
-	suite.mock.EXPECT().GetAttr(getAttrOpt).Return(nil, nil)
-
-	buf := C.CString("")
-	err := libfuse_readlink(path, buf, 7)
-	suite.assert.Equal(C.int(-C.EIO), err)
-	suite.assert.NotEqual("target", C.GoString(buf))
-}
 
-func testFsync(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
-	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR & 0xffffffff
 	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR
-	handle := &handlemap.Handle{}
-	openOptions := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(openOptions).Return(handle, nil)
-	libfuse_open(path, info)
-	suite.assert.NotEqual(C.ulong(0), info.fh)
-
-	// libfuse component will return back handle in form of an integer value
-	// that needs to be converted back to a pointer to a handle object
-	fobj := (*fileHandle)(unsafe.Pointer(uintptr(info.fh)))
-	handle = (*handlemap.Handle)(unsafe.Pointer(uintptr(fobj.obj)))
-
-	options := internal.SyncFileOptions{Handle: handle}
-	suite.mock.EXPECT().SyncFile(options).Return(nil)
-
-	err := libfuse_fsync(path, C.int(0), info)
-	suite.assert.Equal(C.int(0), err)
+	info.flags = C.int(flags)
+	res := libfuse_open(path, info)
+	if res != 0 {
+		return nil, errno(res)
+	}
+	return info, 0
 }
 
-func testFsyncHandleError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Fsync(name string, file fusetest.File) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR
 
-	err := libfuse_fsync(path, C.int(0), info)
-	suite.assert.Equal(C.int(-C.EIO), err)
+	// Call without an open handle carries no handle in the file info
+	info, ok := file.(*C.fuse_file_info_t)
+	if !ok {
+		info = &C.fuse_file_info_t{}
+		info.flags = C.O_RDWR
+	}
+	return errno(libfuse_fsync(path, C.int(0), info))
 }
 
-func testFsyncError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Truncate(name string, size int64) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(fuseFS.filePermission)
-	flags := C.O_RDWR & 0xffffffff
-	info := &C.fuse_file_info_t{}
-	info.flags = C.O_RDWR
-	handle := &handlemap.Handle{}
-
-	openOptions := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
-	suite.mock.EXPECT().OpenFile(openOptions).Return(handle, nil)
-	libfuse_open(path, info)
-	suite.assert.NotEqual(C.ulong(0), info.fh)
-
-	// libfuse component will return back handle in form of an integer value
-	// that needs to be converted back to a pointer to a handle object
-	fobj := (*fileHandle)(unsafe.Pointer(uintptr(info.fh)))
-	handle = (*handlemap.Handle)(unsafe.Pointer(uintptr(fobj.obj)))
-
-	options := internal.SyncFileOptions{Handle: handle}
-	suite.mock.EXPECT().SyncFile(options).Return(errors.New("failed to sync file"))
-
-	err := libfuse_fsync(path, C.int(0), info)
-	suite.assert.Equal(C.int(-C.EIO), err)
+	return errno(libfuse2_truncate(path, C.off_t(size)))
 }
 
-func testFsyncDir(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Unlink(name string) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	options := internal.SyncDirOptions{Name: name}
-	suite.mock.EXPECT().SyncDir(options).Return(nil)
-
-	err := libfuse_fsyncdir(path, C.int(0), nil)
-	suite.assert.Equal(C.int(0), err)
+	return errno(libfuse_unlink(path))
 }
 
-func testFsyncDirError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Symlink(target string, name string) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	options := internal.SyncDirOptions{Name: name}
-	suite.mock.EXPECT().SyncDir(options).Return(errors.New("failed to sync dir"))
-
-	err := libfuse_fsyncdir(path, C.int(0), nil)
-	suite.assert.Equal(C.int(-C.EIO), err)
+	t := C.CString(target)
+	defer C.free(unsafe.Pointer(t))
+	return errno(libfuse_symlink(t, path))
 }
 
-func testChmod(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Readlink(name string) (string, syscall.Errno) {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(0775)
-	options := internal.ChmodOptions{Name: name, Mode: mode}
-	suite.mock.EXPECT().Chmod(options).Return(nil)
 
-	err := libfuse2_chmod(path, 0775)
-	suite.assert.Equal(C.int(0), err)
+	size := C.size_t(4096)
+	buf := (*C.char)(C.calloc(1, size))
+	defer C.free(unsafe.Pointer(buf))
+	res := libfuse_readlink(path, buf, size)
+	return C.GoString(buf), errno(res)
 }
 
-func testChmodNotExists(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Chmod(name string, mode uint32) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(0775)
-	options := internal.ChmodOptions{Name: name, Mode: mode}
-	suite.mock.EXPECT().Chmod(options).Return(syscall.ENOENT)
-
-	err := libfuse2_chmod(path, 0775)
-	suite.assert.Equal(C.int(-C.ENOENT), err)
+	return errno(libfuse2_chmod(path, C.mode_t(mode)))
 }
 
-func testChmodError(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Chown(name string, uid uint32, gid uint32) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	mode := fs.FileMode(0775)
-	options := internal.ChmodOptions{Name: name, Mode: mode}
-	suite.mock.EXPECT().Chmod(options).Return(errors.New("failed to chmod"))
-
-	err := libfuse2_chmod(path, 0775)
-	suite.assert.Equal(C.int(-C.EIO), err)
+	return errno(libfuse2_chown(path, C.uid_t(uid), C.gid_t(gid)))
 }
 
-func testChown(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
+func (f *frontend) Utimens(name string) syscall.Errno {
 	path := C.CString("/" + name)
 	defer C.free(unsafe.Pointer(path))
-	group := C.uint(5)
-	owner := C.uint(4)
-
-	err := libfuse2_chown(path, owner, group)
-	suite.assert.Equal(C.int(0), err)
+	return errno(libfuse2_utimens(path, nil))
 }
 
-func testUtimens(suite *libfuseTestSuite) {
-	defer suite.cleanupTest()
-	name := "path"
-	path := C.CString("/" + name)
+func (f *frontend) Statfs() (*syscall.Statfs_t, syscall.Errno) {
+	path := C.CString("/")
 	defer C.free(unsafe.Pointer(path))
 
-	err := libfuse2_utimens(path, nil)
-	suite.assert.Equal(C.int(0), err)
+	buf := &C.statvfs_t{}
+	res := libfuse_statfs(path, buf)
+	return &syscall.Statfs_t{
+		Frsize: int64(buf.f_frsize),
+		Blocks: uint64(buf.f_blocks),
+		Bavail: uint64(buf.f_bavail),
+		Bfree:  uint64(buf.f_bfree),
+	}, errno(res)
 }
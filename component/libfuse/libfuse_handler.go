@@ -51,6 +51,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"syscall"
 	"unsafe"
 
@@ -264,6 +265,9 @@ func libfuse_init(conn *C.fuse_conn_info_t, cfg *C.fuse_config_t) (res unsafe.Po
 	}
 
 	C.populate_uid_gid()
+	C.save_fuse_instance()
+	fuseFS.nodeIds = newNodeIdCache()
+	fuseFS.notifier = newNotifyQueue(notifyQueueSize, notifyQueueWorkers, fuseFS.notify)
 
 	log.Info("Libfuse::libfuse_init : Kernel Caps : %d", conn.capable)
 
@@ -337,12 +341,22 @@ func libfuse_init(conn *C.fuse_conn_info_t, cfg *C.fuse_config_t) (res unsafe.Po
 		cfg.direct_io = C.int(1)
 	}
 
+	// Inode numbers reported to kernel shall be the node ids assigned by libfuse,
+	// as kernel invalidations resolve parent directories by the inode number reported for them.
+	cfg.use_ino = C.int(0)
+
 	return nil
 }
 
 //export libfuse_destroy
 func libfuse_destroy(data unsafe.Pointer) {
 	log.Trace("Libfuse::libfuse_destroy : destroy")
+
+	// Send what is already queued before kernel connection goes away
+	if fuseFS.notifier != nil {
+		fuseFS.notifier.stop()
+	}
+	C.clear_fuse_instance()
 }
 
 func (lf *Libfuse) fillStat(attr *internal.ObjAttr, stbuf *C.stat_t) {
@@ -640,13 +654,15 @@ func libfuse_create(path *C.char, mode C.mode_t, fi *C.fuse_file_info_t) C.int {
 	name = common.NormalizeObjectName(name)
 	log.Trace("Libfuse::libfuse_create : %s", name)
 
-	handle, err := fuseFS.NextComponent().CreateFile(internal.CreateFileOptions{Name: name, Mode: fs.FileMode(uint32(mode) & 0xffffffff)})
+	handle, err := fuseFS.NextComponent().CreateFile(internal.CreateFileOptions{Name: name, Mode: fs.FileMode(uint32(mode) & 0xffffffff), Uid: uint32(C.get_caller_uid())})
 	if err != nil {
 		log.Err("Libfuse::libfuse_create : Failed to create %s [%s]", name, err.Error())
 		if os.IsExist(err) {
 			return -C.EEXIST
 		} else if os.IsPermission(err) {
 			return -C.EACCES
+		} else if err == syscall.EDQUOT {
+			return -C.EDQUOT
 		} else {
 			return -C.EIO
 		}
@@ -706,6 +722,7 @@ func libfuse_open(path *C.char, fi *C.fuse_file_info_t) C.int {
 			Name:  name,
 			Flags: int(int(fi.flags) & 0xffffffff),
 			Mode:  fs.FileMode(fuseFS.filePermission),
+			Uid:   uint32(C.get_caller_uid()),
 		})
 
 	if err != nil {
@@ -790,6 +807,9 @@ func libfuse_write(path *C.char, buf *C.char, size C.size_t, off C.off_t, fi *C.
 
 	if err != nil {
 		log.Err("Libfuse::libfuse_write : error writing file %s, handle: %d [%s]", handle.Path, handle.ID, err.Error())
+		if err == syscall.EDQUOT {
+			return -C.EDQUOT
+		}
 		return -C.EIO
 	}
 
@@ -1163,6 +1183,83 @@ func libfuse_utimens(path *C.char, tv *C.timespec_t, fi *C.fuse_file_info_t) C.i
 	return 0
 }
 
+// InvalidatePath : Drop what the kernel has cached for a path changed in storage, then pass it down the pipeline.
+// Changes found by components below are only sent to kernel as those components already hold the new state.
+func (lf *Libfuse) InvalidatePath(options internal.InvalidatePathOptions) error {
+	// Component may have found the change while serving a fuse call on the same path.
+	// Kernel holds locks of the path till that call returns, so notifications are sent by the queue workers.
+	if lf.notifier != nil {
+		lf.notifier.push(internal.TruncateDirName(common.NormalizeObjectName(options.Name)))
+	}
+
+	if options.KernelOnly {
+		return nil
+	}
+	return lf.NextComponent().InvalidatePath(options)
+}
+
+// notify : Invalidate the cached content and attributes of a path and its entry in the parent directory.
+// This shall only be called from the notify queue workers and never from a fuse thread.
+func (lf *Libfuse) notify(name string) {
+	path := C.CString("/" + name)
+	defer C.free(unsafe.Pointer(path))
+
+	res := C.invalidate_path(path)
+	if res != 0 && res != -C.ENOENT {
+		log.Debug("Libfuse::notify : Failed to invalidate %s [%d]", name, int(res))
+	}
+
+	// Directory may have been replaced, so resolve the ids under it again
+	lf.nodeIds.forget(name)
+
+	if name == "" || !bool(C.has_fuse_instance()) {
+		return
+	}
+
+	// Kernel may hold a dentry for the path, or a negative one if the path did not exist, which fuse_invalidate_path
+	// does not drop. Dropping it needs the node id of the parent directory.
+	dir := filepath.Dir(name)
+	parent, err := lf.parentId(dir)
+	if err != nil {
+		log.Debug("Libfuse::notify : Failed to find parent of %s [%s]", name, err.Error())
+		return
+	}
+
+	base := filepath.Base(name)
+	entry := C.CString(base)
+	defer C.free(unsafe.Pointer(entry))
+
+	res = C.invalidate_entry(C.fuse_ino_t(parent), entry, C.size_t(len(base)))
+	if res == -C.ENOENT && dir != "." {
+		// Kernel no longer knows the parent by this id, resolve it again on next notification
+		lf.nodeIds.forget(dir)
+	} else if res != 0 && res != -C.ENOENT {
+		log.Debug("Libfuse::notify : Failed to invalidate entry of %s [%d]", name, int(res))
+	}
+}
+
+// parentId : Node id kernel knows the given directory by.
+// High level API does not expose node ids, but the inode number reported for a path is its node id as use_ino
+// is kept unset. Resolving it looks the directory up through the mount, so this is only safe off the fuse threads.
+func (lf *Libfuse) parentId(dir string) (uint64, error) {
+	if dir == "." {
+		return uint64(C.FUSE_ROOT_ID), nil
+	}
+
+	if id, found := lf.nodeIds.get(dir); found {
+		return id, nil
+	}
+
+	info, err := os.Stat(filepath.Join(lf.mountPath, dir))
+	if err != nil {
+		return 0, err
+	}
+
+	id := info.Sys().(*syscall.Stat_t).Ino
+	lf.nodeIds.set(dir, id)
+	return id, nil
+}
+
 // blobfuse_cache_update refresh the file-cache policy for this file
 //
 //export blobfuse_cache_update
@@ -51,6 +51,7 @@
 #include <fuse.h>
 #else
 #include <fuse3/fuse.h>
+#include <fuse3/fuse_lowlevel.h>
 #endif
 
 #include "libfuse_defs.h"
@@ -127,6 +128,54 @@ static int start_fuse(fuse_args_t *args, fuse_operations_t *opt)
     return fuse_main(args->argc, args->argv, opt, NULL);
 }
 
+#ifndef __FUSE2__
+// Fuse instance of the mount, kept to send cache invalidation notifications to kernel.
+// It is set and cleared by fuse threads while notifications are sent from other threads, so it is accessed atomically.
+static struct fuse *fuse_instance = NULL;
+
+// Save the fuse instance from context of init call
+static void save_fuse_instance()
+{
+    __atomic_store_n(&fuse_instance, fuse_get_context()->fuse, __ATOMIC_RELEASE);
+}
+
+static void clear_fuse_instance()
+{
+    __atomic_store_n(&fuse_instance, NULL, __ATOMIC_RELEASE);
+}
+
+static bool has_fuse_instance()
+{
+    return __atomic_load_n(&fuse_instance, __ATOMIC_ACQUIRE) != NULL;
+}
+
+// Ask kernel to drop cached data and attributes of the given path
+// Returns -ENOENT if kernel does not know the path, so there is nothing cached for it
+static int invalidate_path(const char *path)
+{
+    struct fuse *f = __atomic_load_n(&fuse_instance, __ATOMIC_ACQUIRE);
+    if (f == NULL)
+        return -ENOENT;
+
+    return fuse_invalidate_path(f, path);
+}
+
+// Ask kernel to drop the dentry of the given name in the parent directory, including a negative one
+// Returns -ENOENT if kernel does not have the entry cached
+static int invalidate_entry(fuse_ino_t parent, const char *name, size_t namelen)
+{
+    struct fuse *f = __atomic_load_n(&fuse_instance, __ATOMIC_ACQUIRE);
+    if (f == NULL)
+        return -ENOENT;
+
+    struct fuse_session *se = fuse_get_session(f);
+    if (se == NULL)
+        return -ENOENT;
+
+    return fuse_lowlevel_notify_inval_entry(se, parent, name, namelen);
+}
+#endif
+
 // This method is not declared in Go because we are just doing "/" statfs as dummy operation
 static int populate_statfs(const char *path, struct statvfs *stbuf)
 {
@@ -150,6 +199,13 @@ static void populate_uid_gid()
     }
 }
 
+// Get uid of the process calling the current operation, 0 when not called from fuse
+static uid_t get_caller_uid()
+{
+    struct fuse_context *ctx = fuse_get_context();
+    return ctx ? ctx->uid : 0;
+}
+
 // Properties for root (/) are static so just hardcoding them here
 static int get_root_properties(stat_t *stbuf)
 {
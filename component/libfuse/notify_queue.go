@@ -0,0 +1,161 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package libfuse
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+)
+
+// Number of paths waiting to be sent to kernel and number of workers sending them
+const (
+	notifyQueueSize    = 4096
+	notifyQueueWorkers = 4
+)
+
+// notifyQueue : Kernel invalidations waiting to be sent, served by a fixed number of workers.
+// Notifications are never sent from the fuse thread finding the change, as kernel may hold locks of the path
+// till that thread returns. A path already waiting is not queued again, and paths are dropped once the queue
+// is full as kernel drops what it caches for them anyway when its timeouts expire.
+type notifyQueue struct {
+	paths   chan string
+	lock    sync.Mutex
+	pending map[string]bool
+	closed  bool
+	send    func(name string)
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+func newNotifyQueue(size int, workers int, send func(name string)) *notifyQueue {
+	q := &notifyQueue{
+		paths:   make(chan string, size),
+		pending: make(map[string]bool),
+		send:    send,
+	}
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.serve()
+	}
+	return q
+}
+
+// push : Queue the path to be sent to kernel, returns false if the queue is full or stopped
+func (q *notifyQueue) push(name string) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return false
+	}
+
+	if q.pending[name] {
+		return true
+	}
+
+	select {
+	case q.paths <- name:
+		q.pending[name] = true
+		return true
+	default:
+		log.Warn("notifyQueue::push : queue is full, dropping invalidation of %s", name)
+		return false
+	}
+}
+
+func (q *notifyQueue) serve() {
+	defer q.wg.Done()
+
+	for name := range q.paths {
+		// A change found while this one is being sent is queued again
+		q.lock.Lock()
+		delete(q.pending, name)
+		q.lock.Unlock()
+
+		q.send(name)
+	}
+}
+
+// stop : Send the paths already queued and stop the workers
+func (q *notifyQueue) stop() {
+	q.once.Do(func() {
+		q.lock.Lock()
+		q.closed = true
+		close(q.paths)
+		q.lock.Unlock()
+		q.wg.Wait()
+	})
+}
+
+// nodeIdCache : Node ids of directories, used to invalidate entries in them.
+// An id is resolved once per directory and dropped when the directory itself changes or kernel no longer knows it.
+type nodeIdCache struct {
+	lock sync.Mutex
+	ids  map[string]uint64
+}
+
+func newNodeIdCache() *nodeIdCache {
+	return &nodeIdCache{ids: make(map[string]uint64)}
+}
+
+func (c *nodeIdCache) get(dir string) (uint64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	id, found := c.ids[dir]
+	return id, found
+}
+
+func (c *nodeIdCache) set(dir string, id uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.ids[dir] = id
+}
+
+// forget : Drop the id of the path and of all directories under it
+func (c *nodeIdCache) forget(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	prefix := name + "/"
+	for dir := range c.ids {
+		if dir == name || strings.HasPrefix(dir, prefix) {
+			delete(c.ids, dir)
+		}
+	}
+}
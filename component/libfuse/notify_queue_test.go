@@ -0,0 +1,133 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package libfuse
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type notifyQueueTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *notifyQueueTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *notifyQueueTestSuite) TestSendQueued() {
+	var lock sync.Mutex
+	sent := make([]string, 0)
+
+	q := newNotifyQueue(10, 2, func(name string) {
+		lock.Lock()
+		sent = append(sent, name)
+		lock.Unlock()
+	})
+
+	suite.assert.True(q.push("a"))
+	suite.assert.True(q.push("b/c"))
+	q.stop()
+
+	suite.assert.ElementsMatch([]string{"a", "b/c"}, sent)
+
+	// Paths are not accepted once the queue is stopped
+	suite.assert.False(q.push("d"))
+	q.stop()
+}
+
+func (suite *notifyQueueTestSuite) TestDedupAndFull() {
+	release := make(chan struct{})
+	started := make(chan string, 10)
+
+	q := newNotifyQueue(2, 1, func(name string) {
+		started <- name
+		<-release
+	})
+
+	// Worker picks the first path and blocks on it
+	suite.assert.True(q.push("a"))
+	suite.assert.Equal("a", <-started)
+
+	// Path already waiting is not queued again
+	suite.assert.True(q.push("b"))
+	suite.assert.True(q.push("b"))
+	suite.assert.True(q.push("c"))
+	suite.assert.Len(q.paths, 2)
+
+	// Path being sent can be queued again, but the queue is full
+	suite.assert.False(q.push("a"))
+
+	close(release)
+	q.stop()
+	suite.assert.Len(started, 2)
+	suite.assert.Empty(q.pending)
+}
+
+func (suite *notifyQueueTestSuite) TestNodeIds() {
+	c := newNodeIdCache()
+
+	c.set("a", 2)
+	c.set("a/b", 3)
+	c.set("a/b/c", 4)
+	c.set("ab", 5)
+
+	id, found := c.get("a/b")
+	suite.assert.True(found)
+	suite.assert.EqualValues(3, id)
+
+	// Forgetting a directory drops everything under it, but not its siblings
+	c.forget("a/b")
+	_, found = c.get("a/b")
+	suite.assert.False(found)
+	_, found = c.get("a/b/c")
+	suite.assert.False(found)
+
+	id, found = c.get("a")
+	suite.assert.True(found)
+	suite.assert.EqualValues(2, id)
+
+	c.forget("a")
+	id, found = c.get("ab")
+	suite.assert.True(found)
+	suite.assert.EqualValues(5, id)
+}
+
+func TestNotifyQueue(t *testing.T) {
+	suite.Run(t, new(notifyQueueTestSuite))
+}
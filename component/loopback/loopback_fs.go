@@ -210,6 +210,61 @@ func (lfs *LoopbackFS) StreamDir(options internal.StreamDirOptions) ([]*internal
 	return attrList, "", nil
 }
 
+// ListPrefix : list the entries whose relative path begins with the prefix, in one page
+func (lfs *LoopbackFS) ListPrefix(options internal.ListPrefixOptions) ([]*internal.ObjAttr, string, error) {
+	log.Trace("LoopbackFS::ListPrefix : prefix=%s, recursive=%v", options.Prefix, options.Recursive)
+	attrList := make([]*internal.ObjAttr, 0)
+
+	dir := options.Prefix[:strings.LastIndex(options.Prefix, "/")+1]
+	root := filepath.Join(lfs.path, dir)
+
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		name, _ := filepath.Rel(lfs.path, path)
+		if !strings.HasPrefix(name, options.Prefix) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		attr := &internal.ObjAttr{
+			Path:  name,
+			Name:  d.Name(),
+			Size:  info.Size(),
+			Mode:  info.Mode(),
+			Mtime: info.ModTime(),
+		}
+		attr.Flags.Set(internal.PropFlagModeDefault)
+		if d.IsDir() {
+			attr.Flags.Set(internal.PropFlagIsDir)
+		}
+		attrList = append(attrList, attr)
+
+		if d.IsDir() && !options.Recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("LoopbackFS::ListPrefix : error[%s]", err)
+		return nil, "", err
+	}
+
+	return attrList, "", nil
+}
+
 func computeMD5(path string) ([]byte, error) {
 	fh, err := os.Open(path)
 	if err != nil {
@@ -445,7 +500,14 @@ func (lfs *LoopbackFS) CopyFromFile(options internal.CopyFromFileOptions) error
 		log.Err("LoopbackFS::CopyFromFile : error opening [%s]", err)
 		return err
 	}
-	_, err = io.Copy(fdst, options.File)
+	defer fdst.Close()
+
+	var src io.Reader = options.File
+	if options.File == nil && options.Reader != nil {
+		src = options.Reader
+	}
+
+	_, err = io.Copy(fdst, src)
 	if err != nil {
 		log.Err("LoopbackFS::CopyFromFile : error copying [%s]", err)
 		return err
@@ -519,6 +581,9 @@ func (lfs *LoopbackFS) CommitData(options internal.CommitDataOptions) error {
 		}
 	}
 
+	// Size of the blob is decided by the blocks in the list
+	var blobSize int64 = 0
+
 	for idx, id := range options.List {
 		path := fmt.Sprintf("%s_%s", filepath.Join(lfs.path, options.Name), strings.ReplaceAll(id, "/", "_"))
 		info, err := os.Lstat(path)
@@ -548,9 +613,27 @@ func (lfs *LoopbackFS) CommitData(options internal.CommitDataOptions) error {
 			if err != nil {
 				return err
 			}
-		} else if !os.IsNotExist(err) {
+			blobSize = int64(idx*(int)(options.BlockSize)) + info.Size()
+		} else if os.IsNotExist(err) {
+			// Block is already committed, retain the existing data
+			blobSize = int64((idx + 1) * (int)(options.BlockSize))
+		} else {
+			return err
+		}
+	}
+
+	if len(options.List) > 0 {
+		info, err := blob.Stat()
+		if err != nil {
 			return err
 		}
+
+		if info.Size() > blobSize {
+			err = blob.Truncate(blobSize)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// delete the staged files
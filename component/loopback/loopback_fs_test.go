@@ -146,6 +146,41 @@ func (suite *LoopbackFSTestSuite) TestReadDir() {
 	assert.Equal(attr.Mode, info.Mode(), "ReadDir: File Mode not equal")
 }
 
+func (suite *LoopbackFSTestSuite) TestListPrefix() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	names := func(attrs []*internal.ObjAttr) []string {
+		list := make([]string, 0)
+		for _, attr := range attrs {
+			list = append(list, attr.Path)
+		}
+		return list
+	}
+
+	attrs, token, err := suite.lfs.ListPrefix(internal.ListPrefixOptions{Prefix: "e"})
+	assert.Nil(err, "ListPrefix: Failed")
+	assert.Empty(token)
+	assert.Equal([]string{dirEmpty}, names(attrs))
+	assert.True(attrs[0].IsDir())
+
+	attrs, _, err = suite.lfs.ListPrefix(internal.ListPrefixOptions{Prefix: "o"})
+	assert.Nil(err, "ListPrefix: Failed")
+	assert.Equal([]string{dirOne}, names(attrs))
+
+	attrs, _, err = suite.lfs.ListPrefix(internal.ListPrefixOptions{Prefix: "o", Recursive: true})
+	assert.Nil(err, "ListPrefix: Failed")
+	assert.Equal([]string{dirOne, fileLorem}, names(attrs))
+
+	attrs, _, err = suite.lfs.ListPrefix(internal.ListPrefixOptions{Prefix: "one/l"})
+	assert.Nil(err, "ListPrefix: Failed")
+	assert.Equal([]string{fileLorem}, names(attrs))
+
+	attrs, _, err = suite.lfs.ListPrefix(internal.ListPrefixOptions{Prefix: "missing/x"})
+	assert.Nil(err, "ListPrefix: Failed")
+	assert.Empty(attrs)
+}
+
 func (suite *LoopbackFSTestSuite) TestRenameDir() {
 	defer suite.cleanupTest()
 	assert := assert.New(suite.T())
@@ -0,0 +1,321 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+)
+
+// checkpoint journal operations
+const (
+	checkpointBegin = "begin" // download of a version of the file started
+	checkpointBlock = "block" // block at the given offset is written to the local file
+	checkpointDone  = "done"  // file is completely downloaded
+)
+
+// checkpointRecord : one line of the journal
+type checkpointRecord struct {
+	Op     string    `json:"op"`
+	Path   string    `json:"path"`
+	Size   int64     `json:"size,omitempty"`
+	ETag   string    `json:"etag,omitempty"`
+	MD5    []byte    `json:"md5,omitempty"`
+	Mtime  time.Time `json:"mtime,omitempty"`
+	Offset int64     `json:"offset,omitempty"`
+}
+
+// checkpointFile : progress of a file as per the journal
+type checkpointFile struct {
+	size    int64
+	etag    string
+	md5     []byte
+	mtime   time.Time
+	blocks  map[int64]bool // offsets of the blocks already written to local file
+	done    bool
+	current bool // local copy has been checked against the blob in this mount
+}
+
+// checkpoint : append only journal of the files and blocks downloaded by preload,
+// so that an interrupted preload resumes instead of starting from zero
+type checkpoint struct {
+	path  string
+	lock  sync.Mutex
+	fh    *os.File
+	files map[string]*checkpointFile
+}
+
+func newCheckpoint(path string) (*checkpoint, error) {
+	if path == "" {
+		return nil, fmt.Errorf("invalid parameters sent to create checkpoint")
+	}
+
+	cp := &checkpoint{
+		path:  path,
+		files: make(map[string]*checkpointFile),
+	}
+
+	err := cp.load()
+	if err != nil {
+		log.Err("checkpoint::newCheckpoint : Failed to load journal %s [%s]", path, err.Error())
+		return nil, err
+	}
+
+	// rewrite the journal with only the latest state of each file, so that it does not keep growing across mounts
+	err = cp.compact()
+	if err != nil {
+		log.Err("checkpoint::newCheckpoint : Failed to compact journal %s [%s]", path, err.Error())
+		return nil, err
+	}
+
+	cp.fh, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		log.Err("checkpoint::newCheckpoint : Failed to open journal %s [%s]", path, err.Error())
+		return nil, err
+	}
+
+	log.Info("checkpoint::newCheckpoint : Loaded %d files from journal %s", len(cp.files), path)
+	return cp, nil
+}
+
+func (cp *checkpoint) load() error {
+	fh, err := os.Open(cp.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		record := checkpointRecord{}
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			// last line is partial if the mount was killed while it was being written
+			log.Warn("checkpoint::load : Skipping invalid record in journal %s [%s]", cp.path, err.Error())
+			continue
+		}
+		cp.apply(&record)
+	}
+
+	return scanner.Err()
+}
+
+func (cp *checkpoint) apply(record *checkpointRecord) {
+	switch record.Op {
+	case checkpointBegin:
+		cp.files[record.Path] = &checkpointFile{
+			size:   record.Size,
+			etag:   record.ETag,
+			md5:    record.MD5,
+			mtime:  record.Mtime,
+			blocks: make(map[int64]bool),
+		}
+
+	case checkpointBlock:
+		if file, ok := cp.files[record.Path]; ok {
+			file.blocks[record.Offset] = true
+		}
+
+	case checkpointDone:
+		if file, ok := cp.files[record.Path]; ok {
+			file.done = true
+			file.blocks = nil
+		}
+	}
+}
+
+func (cp *checkpoint) compact() error {
+	buf := bytes.Buffer{}
+	for name, file := range cp.files {
+		records := []checkpointRecord{{Op: checkpointBegin, Path: name, Size: file.size, ETag: file.etag, MD5: file.md5, Mtime: file.mtime}}
+		if file.done {
+			records = append(records, checkpointRecord{Op: checkpointDone, Path: name})
+		} else {
+			for offset := range file.blocks {
+				records = append(records, checkpointRecord{Op: checkpointBlock, Path: name, Offset: offset})
+			}
+		}
+
+		for i := range records {
+			data, err := json.Marshal(&records[i])
+			if err != nil {
+				return err
+			}
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(cp.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmpFile := cp.path + ".tmp"
+	err = os.WriteFile(tmpFile, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, cp.path)
+}
+
+func (cp *checkpoint) write(record *checkpointRecord) {
+	if cp.fh == nil {
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Err("checkpoint::write : Failed to marshal record for %s [%s]", record.Path, err.Error())
+		return
+	}
+
+	_, err = cp.fh.Write(append(data, '\n'))
+	if err != nil {
+		log.Err("checkpoint::write : Failed to write record for %s [%s]", record.Path, err.Error())
+	}
+}
+
+// sameVersion : check if the journal entry was made for the current version of the blob
+func (file *checkpointFile) sameVersion(item *WorkItem) bool {
+	if file.size != int64(item.DataLen) {
+		return false
+	}
+
+	if file.etag != "" && item.ETag != "" {
+		return file.etag == item.ETag
+	} else if len(file.md5) > 0 && len(item.MD5) > 0 {
+		return bytes.Equal(file.md5, item.MD5)
+	}
+
+	return file.mtime.Equal(item.Mtime)
+}
+
+// isComplete : check if the file was completely downloaded for the current version of the blob
+func (cp *checkpoint) isComplete(item *WorkItem) bool {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	file, ok := cp.files[item.Path]
+	if !ok || !file.done || !file.sameVersion(item) {
+		return false
+	}
+
+	file.current = true
+	return true
+}
+
+// isCurrent : check if the local copy of the file has been downloaded or verified in this mount
+func (cp *checkpoint) isCurrent(name string) bool {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	file, ok := cp.files[name]
+	return ok && file.current
+}
+
+// begin : start the download of a file and return the offsets of the blocks which are already present
+// in local file, if an earlier download of the same version was interrupted
+func (cp *checkpoint) begin(item *WorkItem, resume bool) map[int64]bool {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	file, ok := cp.files[item.Path]
+	if resume && ok && !file.done && file.sameVersion(item) {
+		blocks := make(map[int64]bool, len(file.blocks))
+		for offset := range file.blocks {
+			blocks[offset] = true
+		}
+		return blocks
+	}
+
+	record := &checkpointRecord{Op: checkpointBegin, Path: item.Path, Size: int64(item.DataLen), ETag: item.ETag, MD5: item.MD5, Mtime: item.Mtime}
+	cp.apply(record)
+	cp.write(record)
+	return nil
+}
+
+// blockDone : record that the block at given offset is written to local file
+func (cp *checkpoint) blockDone(name string, offset int64) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	record := &checkpointRecord{Op: checkpointBlock, Path: name, Offset: offset}
+	cp.apply(record)
+	cp.write(record)
+}
+
+// fileDone : record that the file is completely downloaded
+func (cp *checkpoint) fileDone(name string) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	record := &checkpointRecord{Op: checkpointDone, Path: name}
+	cp.apply(record)
+	cp.write(record)
+
+	if file, ok := cp.files[name]; ok {
+		file.current = true
+	}
+}
+
+func (cp *checkpoint) close() error {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if cp.fh == nil {
+		return nil
+	}
+
+	err := cp.fh.Close()
+	cp.fh = nil
+	return err
+}
+
+// checkpointFilePath : default path of the journal for the given local path and container
+func checkpointFilePath(path string, container string) string {
+	return stateFilePath("xload_checkpoint", "journal", path, container)
+}
@@ -0,0 +1,167 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type checkpointTestSuite struct {
+	suite.Suite
+	assert  *assert.Assertions
+	journal string
+}
+
+func (suite *checkpointTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	suite.assert.Nil(err)
+
+	suite.journal = filepath.Join("/tmp/", "xcheckpoint_"+randomString(8), "xload.journal")
+}
+
+func (suite *checkpointTestSuite) TearDownTest() {
+	err := os.RemoveAll(filepath.Dir(suite.journal))
+	suite.assert.Nil(err)
+}
+
+func (suite *checkpointTestSuite) TestNewCheckpoint() {
+	cp, err := newCheckpoint("")
+	suite.assert.NotNil(err)
+	suite.assert.Nil(cp)
+
+	cp, err = newCheckpoint(suite.journal)
+	suite.assert.Nil(err)
+	suite.assert.NotNil(cp)
+	suite.assert.FileExists(suite.journal)
+	suite.assert.Empty(cp.files)
+
+	suite.assert.Nil(cp.close())
+	suite.assert.Nil(cp.close())
+}
+
+func (suite *checkpointTestSuite) TestResume() {
+	mtime := time.Now().Truncate(time.Second)
+	item := &WorkItem{Path: "dir/file", DataLen: 40, Mtime: mtime}
+
+	cp, err := newCheckpoint(suite.journal)
+	suite.assert.Nil(err)
+
+	suite.assert.Nil(cp.begin(item, true))
+	cp.blockDone(item.Path, 0)
+	cp.blockDone(item.Path, 20)
+	suite.assert.False(cp.isComplete(item))
+	suite.assert.False(cp.isCurrent(item.Path))
+
+	done := &WorkItem{Path: "done", DataLen: 10, ETag: "etag1"}
+	cp.begin(done, true)
+	cp.fileDone(done.Path)
+	suite.assert.True(cp.isCurrent(done.Path))
+	suite.assert.Nil(cp.close())
+
+	// mount killed while writing a record
+	fh, err := os.OpenFile(suite.journal, os.O_WRONLY|os.O_APPEND, 0644)
+	suite.assert.Nil(err)
+	_, err = fh.WriteString(`{"op":"block","path":"dir/fi`)
+	suite.assert.Nil(err)
+	suite.assert.Nil(fh.Close())
+
+	cp, err = newCheckpoint(suite.journal)
+	suite.assert.Nil(err)
+	defer cp.close()
+
+	// state is not current till the file is checked against the blob in this mount
+	suite.assert.False(cp.isCurrent(done.Path))
+	suite.assert.True(cp.isComplete(done))
+	suite.assert.True(cp.isCurrent(done.Path))
+	suite.assert.False(cp.isComplete(&WorkItem{Path: "done", DataLen: 10, ETag: "etag2"}))
+
+	// blocks are resumed only for the same version of the blob and only when local file is intact
+	suite.assert.Nil(cp.begin(&WorkItem{Path: "dir/file", DataLen: 40, Mtime: mtime.Add(time.Second)}, true))
+	suite.assert.Nil(cp.begin(item, true))
+
+	cp.blockDone(item.Path, 10)
+	blocks := cp.begin(item, true)
+	suite.assert.Equal(map[int64]bool{10: true}, blocks)
+
+	suite.assert.Nil(cp.begin(item, false))
+	suite.assert.Empty(cp.begin(item, true))
+}
+
+func (suite *checkpointTestSuite) TestCompact() {
+	item := &WorkItem{Path: "file", DataLen: 40, MD5: []byte("md5")}
+
+	cp, err := newCheckpoint(suite.journal)
+	suite.assert.Nil(err)
+	for i := 0; i < 10; i++ {
+		cp.begin(item, false)
+		cp.blockDone(item.Path, 0)
+		cp.blockDone(item.Path, 10)
+	}
+	suite.assert.Nil(cp.close())
+
+	before, err := os.Stat(suite.journal)
+	suite.assert.Nil(err)
+
+	cp, err = newCheckpoint(suite.journal)
+	suite.assert.Nil(err)
+	defer cp.close()
+
+	after, err := os.Stat(suite.journal)
+	suite.assert.Nil(err)
+	suite.assert.Less(after.Size(), before.Size())
+	suite.assert.Equal(map[int64]bool{0: true, 10: true}, cp.begin(item, true))
+}
+
+func (suite *checkpointTestSuite) TestCheckpointFilePath() {
+	first := checkpointFilePath("/tmp/xload", "container1")
+	suite.assert.Equal(first, checkpointFilePath("/tmp/xload", "container1"))
+	suite.assert.NotEqual(first, checkpointFilePath("/tmp/xload", "container2"))
+	suite.assert.NotEqual(first, syncStateFile("/tmp/xload", "container1"))
+	suite.assert.Equal(".journal", filepath.Ext(first))
+}
+
+func TestCheckpointSuite(t *testing.T) {
+	suite.Run(t, new(checkpointTestSuite))
+}
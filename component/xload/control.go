@@ -0,0 +1,293 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+)
+
+// Commands accepted on the control socket of a mount running xload
+const (
+	ControlStatus = "status"
+	ControlPause  = "pause"
+	ControlResume = "resume"
+	ControlCancel = "cancel"
+)
+
+// States of the transfer reported in the status
+const (
+	STATE_RUNNING   = "running"
+	STATE_PAUSED    = "paused"
+	STATE_CANCELLED = "cancelled"
+	STATE_COMPLETED = "completed"
+)
+
+const controlTimeout = 10 * time.Second
+
+// directory in the default work directory holding the control sockets, only the user who mounted can access it
+const controlDir = "xload_control"
+
+// Status : progress of xload reported on the control socket
+type Status struct {
+	Mode             string   `json:"Mode"`
+	State            string   `json:"State"`
+	Timestamp        string   `json:"Timestamp"`
+	PercentCompleted float64  `json:"PercentCompleted"`
+	Total            uint64   `json:"Total"`
+	Done             uint64   `json:"Done"`
+	Failed           uint64   `json:"Failed"`
+	Pending          uint64   `json:"Pending"`
+	BytesTotal       uint64   `json:"BytesTotal"`
+	BytesDone        uint64   `json:"BytesDone"`
+	BytesPending     uint64   `json:"BytesPending"`
+	BytesTransferred uint64   `json:"BytesTransferred"`
+	BandwidthMbps    float64  `json:"Bandwidth(Mbps)"`
+	ETASeconds       int64    `json:"ETASeconds"` // -1 if it can not be estimated yet
+	RecentErrors     []string `json:"RecentErrors,omitempty"`
+}
+
+type controlRequest struct {
+	Command string `json:"Command"`
+}
+
+type controlResponse struct {
+	Status *Status `json:"Status,omitempty"`
+	Error  string  `json:"Error,omitempty"`
+}
+
+// ControlSocketPath : path of the control socket of the mount, derived from the mount path so that the cli can find it
+func ControlSocketPath(mountPath string) string {
+	absPath, err := filepath.Abs(mountPath)
+	if err == nil {
+		mountPath = absPath
+	}
+	path := stateFilePath("xload_control", "sock", filepath.Clean(mountPath), "")
+	return filepath.Join(filepath.Dir(path), controlDir, filepath.Base(path))
+}
+
+// SendControlCommand : send the command to xload running in the given mount and return the status after it
+func SendControlCommand(mountPath string, command string) (*Status, error) {
+	conn, err := net.DialTimeout("unix", ControlSocketPath(mountPath), controlTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("xload is not running on %s [%s]", mountPath, err.Error())
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	err = json.NewEncoder(conn).Encode(&controlRequest{Command: command})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s command [%s]", command, err.Error())
+	}
+
+	resp := &controlResponse{}
+	err = json.NewDecoder(conn).Decode(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s command [%s]", command, err.Error())
+	}
+
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	return resp.Status, nil
+}
+
+// controlServer : serves the commands of the cli on a unix socket
+type controlServer struct {
+	path      string
+	uid       uint32
+	listener  net.Listener
+	handler   func(command string) (*Status, error)
+	waitGroup sync.WaitGroup
+}
+
+func newControlServer(path string, handler func(command string) (*Status, error)) (*controlServer, error) {
+	// only the user who mounted can control the transfer, so the socket is created inside a private directory
+	// and is never reachable by others, not even before its own mode is set
+	err := privateDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// socket left behind by a mount which did not exit cleanly
+	_ = os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return &controlServer{
+		path:     path,
+		uid:      uint32(os.Getuid()),
+		listener: listener,
+		handler:  handler,
+	}, nil
+}
+
+// privateDir : create the directory accessible only to the current user, fails if it is owned by someone else
+func privateDir(dir string) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok || stat.Uid != uint32(os.Getuid()) {
+		return fmt.Errorf("%s is not a directory owned by the current user", dir)
+	}
+
+	if info.Mode().Perm() != 0700 {
+		return os.Chmod(dir, 0700)
+	}
+	return nil
+}
+
+// allowed : check if the given user can control the transfer, that is the user who mounted or root
+func (cs *controlServer) allowed(uid uint32) bool {
+	return uid == cs.uid || uid == 0
+}
+
+// peerUid : user of the process on the other end of the connection
+func peerUid(conn net.Conn) (uint32, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, fmt.Errorf("not a unix socket connection")
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, credErr
+	}
+
+	return cred.Uid, nil
+}
+
+func (cs *controlServer) start() {
+	log.Info("controlServer::start : listening on %s", cs.path)
+	cs.waitGroup.Add(1)
+	go cs.serve()
+}
+
+func (cs *controlServer) stop() {
+	log.Info("controlServer::stop : closing %s", cs.path)
+	cs.listener.Close()
+	cs.waitGroup.Wait()
+}
+
+func (cs *controlServer) serve() {
+	defer cs.waitGroup.Done()
+
+	for {
+		conn, err := cs.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Err("controlServer::serve : failed to accept connection [%s]", err.Error())
+			continue
+		}
+
+		cs.waitGroup.Add(1)
+		go cs.handle(conn)
+	}
+}
+
+func (cs *controlServer) handle(conn net.Conn) {
+	defer cs.waitGroup.Done()
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	uid, err := peerUid(conn)
+	if err != nil || !cs.allowed(uid) {
+		log.Err("controlServer::handle : rejected connection from uid %d [%v]", uid, err)
+		_ = json.NewEncoder(conn).Encode(&controlResponse{Error: "permission denied"})
+		return
+	}
+
+	req := &controlRequest{}
+	err = json.NewDecoder(conn).Decode(req)
+	if err != nil {
+		log.Err("controlServer::handle : failed to read request [%s]", err.Error())
+		return
+	}
+
+	log.Info("controlServer::handle : received %s command", req.Command)
+
+	resp := &controlResponse{}
+	resp.Status, err = cs.handler(req.Command)
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	err = json.NewEncoder(conn).Encode(resp)
+	if err != nil {
+		log.Err("controlServer::handle : failed to send response [%s]", err.Error())
+	}
+}
@@ -0,0 +1,146 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type controlTestSuite struct {
+	suite.Suite
+	assert    *assert.Assertions
+	mountPath string
+}
+
+func (suite *controlTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	suite.assert.Nil(err)
+
+	suite.mountPath = filepath.Join("/tmp/", "xcontrol_"+randomString(8))
+}
+
+func (suite *controlTestSuite) TestControlSocketPath() {
+	path := ControlSocketPath(suite.mountPath)
+	suite.assert.Equal(path, ControlSocketPath(suite.mountPath+"/"))
+	suite.assert.Equal(path, ControlSocketPath(filepath.Join(suite.mountPath, "dir", "..")))
+	suite.assert.NotEqual(path, ControlSocketPath(suite.mountPath+"_1"))
+	suite.assert.Equal(".sock", filepath.Ext(path))
+}
+
+func (suite *controlTestSuite) TestSendControlCommand() {
+	_, err := SendControlCommand(suite.mountPath, ControlStatus)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "xload is not running")
+
+	commands := make([]string, 0)
+	cs, err := newControlServer(ControlSocketPath(suite.mountPath), func(command string) (*Status, error) {
+		commands = append(commands, command)
+		if command == "invalid" {
+			return nil, fmt.Errorf("invalid command %s", command)
+		}
+		return &Status{Mode: "preload", State: STATE_PAUSED, Total: 10, Done: 4, ETASeconds: -1}, nil
+	})
+	suite.assert.Nil(err)
+	cs.start()
+
+	st, err := SendControlCommand(suite.mountPath, ControlPause)
+	suite.assert.Nil(err)
+	suite.assert.NotNil(st)
+	suite.assert.Equal(STATE_PAUSED, st.State)
+	suite.assert.Equal(uint64(10), st.Total)
+	suite.assert.Equal(uint64(4), st.Done)
+	suite.assert.Equal(int64(-1), st.ETASeconds)
+
+	st, err = SendControlCommand(suite.mountPath, "invalid")
+	suite.assert.Nil(st)
+	suite.assert.NotNil(err)
+	suite.assert.Equal("invalid command invalid", err.Error())
+
+	cs.stop()
+	suite.assert.Equal([]string{ControlPause, "invalid"}, commands)
+
+	_, err = SendControlCommand(suite.mountPath, ControlStatus)
+	suite.assert.NotNil(err)
+	suite.assert.NoFileExists(ControlSocketPath(suite.mountPath))
+}
+
+func (suite *controlTestSuite) TestControlSocketPermissions() {
+	path := ControlSocketPath(suite.mountPath)
+	cs, err := newControlServer(path, func(command string) (*Status, error) {
+		return &Status{State: STATE_RUNNING}, nil
+	})
+	suite.assert.Nil(err)
+	cs.start()
+	defer cs.stop()
+
+	info, err := os.Stat(filepath.Dir(path))
+	suite.assert.Nil(err)
+	suite.assert.Equal(os.FileMode(0700), info.Mode().Perm())
+
+	info, err = os.Stat(path)
+	suite.assert.Nil(err)
+	suite.assert.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	// commands from other users are rejected
+	suite.assert.True(cs.allowed(cs.uid))
+	suite.assert.True(cs.allowed(0))
+	suite.assert.False(cs.allowed(cs.uid + 1))
+
+	cs.uid = uint32(os.Getuid()) + 1
+	if os.Getuid() != 0 {
+		_, err = SendControlCommand(suite.mountPath, ControlStatus)
+		suite.assert.NotNil(err)
+		suite.assert.Equal("permission denied", err.Error())
+	}
+
+	cs.uid = uint32(os.Getuid())
+	st, err := SendControlCommand(suite.mountPath, ControlStatus)
+	suite.assert.Nil(err)
+	suite.assert.Equal(STATE_RUNNING, st.State)
+}
+
+func TestControlSuite(t *testing.T) {
+	suite.Run(t, new(controlTestSuite))
+}
@@ -108,8 +108,7 @@ func (rdm *remoteDataManager) Process(item *WorkItem) (int, error) {
 		if item.Download {
 			return rdm.ReadData(item)
 		} else {
-			// return rdm.WriteData(item)
-			return 0, fmt.Errorf("uploads are currently not supported, path %v", item.Path)
+			return rdm.WriteData(item)
 		}
 	}
 }
@@ -131,8 +130,6 @@ func (rdm *remoteDataManager) ReadData(item *WorkItem) (int, error) {
 	return bytesTransferred, err
 }
 
-// uncomment this when the support for upload is added
-/*
 // WriteData writes data to the data manager
 func (rdm *remoteDataManager) WriteData(item *WorkItem) (int, error) {
 	// log.Debug("remoteDataManager::WriteData : Scheduling upload for %s offset %v", item.path, item.block.offset)
@@ -154,7 +151,6 @@ func (rdm *remoteDataManager) WriteData(item *WorkItem) (int, error) {
 
 	return bytesTransferred, err
 }
-*/
 
 // send stats to stats manager
 func (rdm *remoteDataManager) sendStats(path string, isDownload bool, bytesTransferred uint64, isSuccess bool) {
@@ -35,8 +35,10 @@ package xload
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/Azure/azure-storage-fuse/v2/common/config"
 	"github.com/Azure/azure-storage-fuse/v2/component/loopback"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -77,13 +79,28 @@ func (suite *dataManagerTestSuite) TestNewRemoteDataManager() {
 }
 
 func (suite *dataManagerTestSuite) TestProcessErrors() {
-	rdm := &remoteDataManager{}
+	config.ReadConfigFromReader(strings.NewReader("loopbackfs:\n  path: " + suite.T().TempDir() + "\n"))
+	defer config.ResetConfig()
+
+	remote := loopback.NewLoopbackFSComponent()
+	err := remote.Configure(true)
+	suite.assert.Nil(err)
+
+	statsMgr, err := NewStatsManager(1, false)
+	suite.assert.Nil(err)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 1,
+		remote:      remote,
+		statsMgr:    statsMgr,
+	})
+	suite.assert.Nil(err)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	item := &WorkItem{
 		CompName: DATA_MANAGER,
-		Path:     "test",
-		Block:    &Block{},
+		Path:     "dir/test", // parent directory does not exist so that the upload fails
+		Block:    &Block{Id: "id"},
 		Download: false,
 		Ctx:      ctx,
 	}
@@ -0,0 +1,225 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	smallestFirst = "smallest-first"
+	newestFirst   = "newest-first"
+)
+
+// normalizePattern : patterns are relative to the root of the container
+func normalizePattern(pattern string) string {
+	return strings.Trim(filepath.ToSlash(filepath.Clean(strings.TrimSpace(pattern))), "/")
+}
+
+func validPatterns(patterns []string) ([]string, error) {
+	list := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = normalizePattern(pattern)
+		if pattern == "" || pattern == "." {
+			continue
+		}
+
+		_, err := filepath.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s [%s]", pattern, err.Error())
+		}
+		list = append(list, pattern)
+	}
+
+	return list, nil
+}
+
+// matchPattern : check if the pattern matches the path or one of its parent directories.
+// A pattern without a separator is matched against each element of the path, so "*.tmp" matches at any depth.
+func matchPattern(pattern string, path string) bool {
+	anyDepth := !strings.Contains(pattern, "/")
+	for candidate := path; candidate != "." && candidate != "" && candidate != "/"; candidate = filepath.Dir(candidate) {
+		name := candidate
+		if anyDepth {
+			name = filepath.Base(candidate)
+		}
+
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mayContain : check if the directory is a parent of the paths the pattern can match
+func mayContain(pattern string, dir string) bool {
+	if !strings.Contains(pattern, "/") {
+		return true
+	}
+
+	patternParts := strings.Split(pattern, "/")
+	dirParts := strings.Split(dir, "/")
+	if len(dirParts) >= len(patternParts) {
+		return false
+	}
+
+	for i := range dirParts {
+		if matched, _ := filepath.Match(patternParts[i], dirParts[i]); !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// pathFilter : include and exclude rules applied by the lister on the listed paths
+type pathFilter struct {
+	include []string // if given, only the paths matching one of these are downloaded
+	exclude []string // paths matching one of these are never downloaded, even if included
+}
+
+func newPathFilter(include []string, exclude []string) (*pathFilter, error) {
+	var err error
+	pf := &pathFilter{}
+
+	pf.include, err = validPatterns(include)
+	if err != nil {
+		return nil, err
+	}
+
+	pf.exclude, err = validPatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pf.include) == 0 && len(pf.exclude) == 0 {
+		return nil, nil
+	}
+
+	return pf, nil
+}
+
+// keep : check if a listed file is to be downloaded or a listed directory is to be traversed
+func (pf *pathFilter) keep(path string, isDir bool) bool {
+	for _, pattern := range pf.exclude {
+		if matchPattern(pattern, path) {
+			return false
+		}
+	}
+
+	if len(pf.include) == 0 {
+		return true
+	}
+
+	for _, pattern := range pf.include {
+		if matchPattern(pattern, path) || (isDir && mayContain(pattern, path)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// priorityOrder : order in which the listed files are downloaded.
+// Files matching an earlier pattern are downloaded first, and files of the same rank are ordered by the keys.
+type priorityOrder struct {
+	patterns []string
+	keys     []string
+}
+
+func newPriorityOrder(list []string) (*priorityOrder, error) {
+	po := &priorityOrder{}
+
+	patterns := make([]string, 0, len(list))
+	for _, entry := range list {
+		switch strings.ToLower(strings.TrimSpace(entry)) {
+		case smallestFirst, newestFirst:
+			po.keys = append(po.keys, strings.ToLower(strings.TrimSpace(entry)))
+		default:
+			patterns = append(patterns, entry)
+		}
+	}
+
+	var err error
+	po.patterns, err = validPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(po.patterns) == 0 && len(po.keys) == 0 {
+		return nil, nil
+	}
+
+	return po, nil
+}
+
+func (po *priorityOrder) rank(path string) int {
+	for i, pattern := range po.patterns {
+		if matchPattern(pattern, path) {
+			return i
+		}
+	}
+
+	return len(po.patterns)
+}
+
+// less : check if the first item is to be downloaded before the second one
+func (po *priorityOrder) less(a *WorkItem, b *WorkItem) bool {
+	rankA, rankB := po.rank(a.Path), po.rank(b.Path)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+
+	for _, key := range po.keys {
+		switch key {
+		case smallestFirst:
+			if a.DataLen != b.DataLen {
+				return a.DataLen < b.DataLen
+			}
+		case newestFirst:
+			if !a.Mtime.Equal(b.Mtime) {
+				return a.Mtime.After(b.Mtime)
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,166 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type filterTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *filterTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *filterTestSuite) TestMatchPattern() {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.tmp", "a.tmp", true},
+		{"*.tmp", "dir/sub/a.tmp", true},
+		{"*.tmp", "dir/a.tmp.bak", false},
+		{"checkpoints", "run/checkpoints/1.ckpt", true},
+		{"checkpoints", "run/checkpoints_old/1.ckpt", false},
+		{"data/train", "data/train/0001.bin", true},
+		{"data/train", "other/data/train/0001.bin", false},
+		{"data/*/labels", "data/train/labels/1.txt", true},
+		{"data/*/labels", "data/train/images/1.png", false},
+		{"data/train/*.bin", "data/train/0001.bin", true},
+		{"data/train/*.bin", "data/train/sub/0001.bin", false},
+	}
+
+	for _, tt := range tests {
+		suite.assert.Equal(tt.match, matchPattern(tt.pattern, tt.path), "pattern %s path %s", tt.pattern, tt.path)
+	}
+}
+
+func (suite *filterTestSuite) TestNewPathFilter() {
+	pf, err := newPathFilter(nil, []string{"", "/"})
+	suite.assert.Nil(err)
+	suite.assert.Nil(pf)
+
+	pf, err = newPathFilter([]string{"data/["}, nil)
+	suite.assert.NotNil(err)
+	suite.assert.Nil(pf)
+
+	pf, err = newPathFilter(nil, []string{"[a"})
+	suite.assert.NotNil(err)
+	suite.assert.Nil(pf)
+
+	pf, err = newPathFilter([]string{"/data/train/"}, []string{"*.tmp"})
+	suite.assert.Nil(err)
+	suite.assert.Equal([]string{"data/train"}, pf.include)
+	suite.assert.Equal([]string{"*.tmp"}, pf.exclude)
+}
+
+func (suite *filterTestSuite) TestKeep() {
+	pf, err := newPathFilter([]string{"data/*/labels"}, nil)
+	suite.assert.Nil(err)
+
+	// directories leading to an included path are traversed
+	suite.assert.True(pf.keep("data", true))
+	suite.assert.True(pf.keep("data/train", true))
+	suite.assert.True(pf.keep("data/train/labels", true))
+	suite.assert.False(pf.keep("data/train/images", true))
+	suite.assert.False(pf.keep("logs", true))
+
+	pf, err = newPathFilter([]string{"data/*/labels", "models"}, []string{"*.tmp", "checkpoints"})
+	suite.assert.Nil(err)
+
+	// include without a separator can match at any depth
+	suite.assert.True(pf.keep("logs", true))
+	suite.assert.True(pf.keep("logs/models", true))
+	suite.assert.True(pf.keep("logs/models/a.bin", false))
+
+	suite.assert.True(pf.keep("data/train/labels/1.txt", false))
+	suite.assert.False(pf.keep("data/train/images/1.png", false))
+	suite.assert.False(pf.keep("data/file", false))
+
+	// exclude takes precedence over include
+	suite.assert.False(pf.keep("data/train/labels/1.tmp", false))
+	suite.assert.False(pf.keep("models/checkpoints", true))
+	suite.assert.False(pf.keep("models/checkpoints/1.ckpt", false))
+
+	pf, err = newPathFilter(nil, []string{"*.tmp"})
+	suite.assert.Nil(err)
+	suite.assert.True(pf.keep("any/path", false))
+	suite.assert.False(pf.keep("any/path.tmp", false))
+}
+
+func (suite *filterTestSuite) TestPriorityOrder() {
+	po, err := newPriorityOrder(nil)
+	suite.assert.Nil(err)
+	suite.assert.Nil(po)
+
+	po, err = newPriorityOrder([]string{"data/["})
+	suite.assert.NotNil(err)
+	suite.assert.Nil(po)
+
+	po, err = newPriorityOrder([]string{"data/val", "data/train", "Smallest-First", "newest-first"})
+	suite.assert.Nil(err)
+	suite.assert.Equal([]string{"data/val", "data/train"}, po.patterns)
+	suite.assert.Equal([]string{smallestFirst, newestFirst}, po.keys)
+
+	now := time.Now()
+	items := []*WorkItem{
+		{Path: "other/big", DataLen: 100},
+		{Path: "data/train/old", DataLen: 10, Mtime: now.Add(-time.Hour)},
+		{Path: "data/train/big", DataLen: 20},
+		{Path: "data/val/a", DataLen: 50},
+		{Path: "data/train/new", DataLen: 10, Mtime: now},
+		{Path: "other/small", DataLen: 1},
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return po.less(items[i], items[j]) })
+
+	order := make([]string, 0, len(items))
+	for _, item := range items {
+		order = append(order, item.Path)
+	}
+	suite.assert.Equal([]string{"data/val/a", "data/train/new", "data/train/old", "data/train/big", "other/small", "other/big"}, order)
+}
+
+func TestFilterSuite(t *testing.T) {
+	suite.Run(t, new(filterTestSuite))
+}
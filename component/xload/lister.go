@@ -37,6 +37,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
@@ -47,9 +48,11 @@ import (
 // verify that the below types implement the xcomponent interfaces
 var _ XComponent = &lister{}
 var _ XComponent = &remoteLister{}
+var _ XComponent = &localLister{}
 
 // verify that the below types implement the xenumerator interfaces
 var _ enumerator = &remoteLister{}
+var _ enumerator = &localLister{}
 
 type lister struct {
 	XBase
@@ -66,6 +69,9 @@ type enumerator interface {
 type remoteLister struct {
 	lister
 	listBlocked bool
+	filter      *pathFilter // include and exclude rules, nil if all paths are to be downloaded
+	shardedList uint32      // number of prefix shards listed in parallel, 0 if the container is listed directory by directory
+	nonASCII    bool        // sharded list also picks up names starting with a non-ASCII character
 }
 
 type remoteListerOptions struct {
@@ -74,6 +80,9 @@ type remoteListerOptions struct {
 	defaultPermission os.FileMode
 	remote            internal.Component
 	statsMgr          *StatsManager
+	filter            *pathFilter
+	shardedList       uint32
+	shardedNonASCII   bool
 }
 
 func newRemoteLister(opts *remoteListerOptions) (*remoteLister, error) {
@@ -90,6 +99,9 @@ func newRemoteLister(opts *remoteListerOptions) (*remoteLister, error) {
 			defaultPermission: opts.defaultPermission,
 		},
 		listBlocked: false,
+		filter:      opts.filter,
+		shardedList: opts.shardedList,
+		nonASCII:    opts.shardedNonASCII,
 	}
 
 	rl.SetName(LISTER)
@@ -149,6 +161,10 @@ func (rl *remoteLister) Process(item *WorkItem) (int, error) {
 		rl.listBlocked = true
 	}
 
+	if rl.shardedList > 0 && relPath == "" {
+		return rl.processSharded()
+	}
+
 	marker := ""
 	var cnt, iteration int
 	for {
@@ -162,15 +178,27 @@ func (rl *remoteLister) Process(item *WorkItem) (int, error) {
 		}
 
 		marker = new_marker
-		cnt += len(entries)
 		iteration++
+
+		if rl.filter != nil {
+			entries = rl.filterEntries(entries)
+		}
+		cnt += len(entries)
 		log.Debug("remoteLister::Process : count: %d , iterations: %d", cnt, iteration)
 
+		var size uint64
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				size += uint64(entry.Size)
+			}
+		}
+
 		// send number of items listed in current iteration to stats manager
 		rl.GetStatsManager().AddStats(&StatsItem{
 			Component:   LISTER,
 			Name:        relPath,
 			ListerCount: uint64(len(entries)),
+			Size:        size,
 		})
 
 		for _, entry := range entries {
@@ -211,6 +239,7 @@ func (rl *remoteLister) Process(item *WorkItem) (int, error) {
 					Atime:    entry.Atime,
 					Mtime:    entry.Mtime,
 					MD5:      entry.MD5,
+					ETag:     entry.ETag,
 				})
 			}
 		}
@@ -224,6 +253,118 @@ func (rl *remoteLister) Process(item *WorkItem) (int, error) {
 	return cnt, nil
 }
 
+// processSharded : list the whole container in one pass by splitting it into prefix shards which are listed in
+// parallel without a delimiter. Sub-directories are not scheduled for listing as their contents are already part
+// of the result, and directories without a marker blob are created from the paths of the files inside them.
+func (rl *remoteLister) processSharded() (int, error) {
+	log.Debug("remoteLister::processSharded : Listing container with %d parallel shards", rl.shardedList)
+
+	created := make(map[string]bool)
+	cnt := 0
+
+	err := internal.ShardedList(rl.GetRemote(), internal.ShardedListOptions{
+		Parallelism: int(rl.shardedList),
+		Recursive:   true,
+		NonASCII:    rl.nonASCII,
+		Callback: func(entries []*internal.ObjAttr) error {
+			if rl.filter != nil {
+				entries = rl.filterTree(entries)
+			}
+			cnt += len(entries)
+
+			var size uint64
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					size += uint64(entry.Size)
+				}
+			}
+
+			rl.GetStatsManager().AddStats(&StatsItem{
+				Component:   LISTER,
+				Name:        "",
+				ListerCount: uint64(len(entries)),
+				Size:        size,
+			})
+
+			for _, entry := range entries {
+				if entry.IsDir() {
+					if rl.mkdir(filepath.Join(rl.path, entry.Path)) == nil {
+						created[entry.Path] = true
+					}
+					continue
+				}
+
+				// parent directories may not have a marker blob, so create them before the file is downloaded
+				parent := filepath.Dir(entry.Path)
+				if parent != "." && !created[parent] {
+					err := os.MkdirAll(filepath.Join(rl.path, parent), rl.defaultPermission)
+					if err != nil {
+						log.Err("remoteLister::processSharded : Failed to create directory %s [%s]", parent, err.Error())
+					} else {
+						created[parent] = true
+					}
+				}
+
+				fileMode := rl.defaultPermission
+				if !entry.IsModeDefault() {
+					fileMode = entry.Mode
+				}
+
+				// send file to the splitter's channel for chunking
+				rl.GetNext().Schedule(&WorkItem{
+					CompName: rl.GetNext().GetName(),
+					Path:     entry.Path,
+					DataLen:  uint64(entry.Size),
+					Mode:     fileMode,
+					Atime:    entry.Atime,
+					Mtime:    entry.Mtime,
+					MD5:      entry.MD5,
+					ETag:     entry.ETag,
+				})
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		log.Err("remoteLister::processSharded : Sharded listing failed [%s]", err.Error())
+	}
+
+	log.Debug("remoteLister::processSharded : remote listing done, count: %d", cnt)
+	return cnt, nil
+}
+
+// filterTree : drop the entries of a flat listing which are not to be downloaded as per the include and exclude
+// rules, an entry is dropped also when any of its parent directories is
+func (rl *remoteLister) filterTree(entries []*internal.ObjAttr) []*internal.ObjAttr {
+	kept := make([]*internal.ObjAttr, 0, len(entries))
+	for _, entry := range entries {
+		keep := rl.filter.keep(entry.Path, entry.IsDir())
+		for dir := filepath.Dir(entry.Path); keep && dir != "."; dir = filepath.Dir(dir) {
+			keep = rl.filter.keep(dir, true)
+		}
+
+		if keep {
+			kept = append(kept, entry)
+		} else {
+			log.Debug("remoteLister::filterTree : Skipping %s as per the filters", entry.Path)
+		}
+	}
+	return kept
+}
+
+// filterEntries : drop the entries which are not to be downloaded as per the include and exclude rules
+func (rl *remoteLister) filterEntries(entries []*internal.ObjAttr) []*internal.ObjAttr {
+	kept := make([]*internal.ObjAttr, 0, len(entries))
+	for _, entry := range entries {
+		if rl.filter.keep(entry.Path, entry.IsDir()) {
+			kept = append(kept, entry)
+		} else {
+			log.Debug("remoteLister::filterEntries : Skipping %s as per the filters", entry.Path)
+		}
+	}
+	return kept
+}
+
 func (rl *remoteLister) mkdir(name string) error {
 	log.Debug("remoteLister::mkdir : Creating local path: %s, mode %v", name, rl.defaultPermission)
 	err := os.MkdirAll(name, rl.defaultPermission)
@@ -235,6 +376,168 @@ func (rl *remoteLister) mkdir(name string) error {
 		Dir:       true,
 		Success:   err == nil,
 		Download:  true,
+		Err:       err,
 	})
 	return err
 }
+
+// --------------------------------------------------------------------------------------------------------
+
+type localLister struct {
+	lister
+}
+
+type localListerOptions struct {
+	path              string
+	workerCount       uint32
+	defaultPermission os.FileMode
+	remote            internal.Component
+	statsMgr          *StatsManager
+}
+
+func newLocalLister(opts *localListerOptions) (*localLister, error) {
+	if opts == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil || opts.workerCount == 0 {
+		log.Err("lister::NewLocalLister : invalid parameters sent to create local lister")
+		return nil, fmt.Errorf("invalid parameters sent to create local lister")
+	}
+
+	log.Debug("lister::NewLocalLister : create new local lister for %s, default permission %v, workers %v", opts.path, opts.defaultPermission, opts.workerCount)
+
+	ll := &localLister{
+		lister: lister{
+			path:              opts.path,
+			defaultPermission: opts.defaultPermission,
+		},
+	}
+
+	ll.SetName(LISTER)
+	ll.SetWorkerCount(opts.workerCount)
+	ll.SetRemote(opts.remote)
+	ll.SetStatsManager(opts.statsMgr)
+	ll.Init()
+	return ll, nil
+}
+
+func (ll *localLister) Init() {
+	ll.SetThreadPool(NewThreadPool(ll.GetWorkerCount(), ll.Process))
+	if ll.GetThreadPool() == nil {
+		log.Err("localLister::Init : fail to init thread pool")
+	}
+}
+
+func (ll *localLister) Start() {
+	log.Debug("localLister::Start : start local lister for %s", ll.path)
+	ll.GetThreadPool().Start()
+	ll.Schedule(&WorkItem{CompName: ll.GetName()})
+}
+
+func (ll *localLister) Stop() {
+	log.Debug("localLister::Stop : stop local lister for %s", ll.path)
+	if ll.GetThreadPool() != nil {
+		ll.GetThreadPool().Stop()
+	}
+	ll.GetNext().Stop()
+}
+
+// list a local directory, create its sub-directories in the container and send its files for upload
+func (ll *localLister) Process(item *WorkItem) (int, error) {
+	relPath := item.Path
+	log.Debug("localLister::Process : Reading local dir %s", relPath)
+
+	entries, err := os.ReadDir(filepath.Join(ll.path, relPath))
+	if err != nil {
+		log.Err("localLister::Process : Local listing failed for %s [%s]", relPath, err.Error())
+		return 0, err
+	}
+
+	// only directories and regular files are uploaded, symlinks and special files are skipped
+	cnt := 0
+	var size uint64
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Type().IsRegular() {
+			cnt++
+			if info, err := entry.Info(); err == nil && !entry.IsDir() {
+				size += uint64(info.Size())
+			}
+		} else {
+			log.Warn("localLister::Process : Skipping %s as it is not a regular file", filepath.Join(relPath, entry.Name()))
+		}
+	}
+
+	// send number of items listed to stats manager
+	ll.GetStatsManager().AddStats(&StatsItem{
+		Component:   LISTER,
+		Name:        relPath,
+		ListerCount: uint64(cnt),
+		Size:        size,
+	})
+
+	for _, entry := range entries {
+		name := filepath.Join(relPath, entry.Name())
+		log.Debug("localLister::Process : Iterating: %s, Is directory: %v", name, entry.IsDir())
+
+		if entry.IsDir() {
+			// create the directory in container and then add it to the input channel of the listing component
+			// scheduling is done in a separate go routine as the channel of this component may be full
+			go func(name string) {
+				err := ll.mkdir(name)
+				if err != nil {
+					log.Err("localLister::Process : Failed to create directory %s [%s]", name, err.Error())
+					return
+				}
+
+				// push the directory to input pool for its listing
+				ll.Schedule(&WorkItem{
+					CompName: ll.GetName(),
+					Path:     name,
+				})
+			}(name)
+		} else if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				log.Err("localLister::Process : Failed to get info of %s [%s]", name, err.Error())
+				ll.GetStatsManager().AddStats(&StatsItem{
+					Component: SPLITTER,
+					Name:      name,
+					Success:   false,
+					Err:       err,
+				})
+				continue
+			}
+
+			// send file to the splitter's channel for chunking
+			ll.GetNext().Schedule(&WorkItem{
+				CompName: ll.GetNext().GetName(),
+				Path:     name,
+				DataLen:  uint64(info.Size()),
+				Mode:     info.Mode().Perm(),
+				Mtime:    info.ModTime(),
+			})
+		}
+	}
+
+	return cnt, nil
+}
+
+func (ll *localLister) mkdir(name string) error {
+	log.Debug("localLister::mkdir : Creating remote path: %s, mode %v", name, ll.defaultPermission)
+	err := ll.GetRemote().CreateDir(internal.CreateDirOptions{
+		Name: name,
+		Mode: ll.defaultPermission,
+	})
+	if err == syscall.EEXIST || os.IsExist(err) {
+		err = nil
+	}
+
+	// send stats for dir creation
+	ll.GetStatsManager().AddStats(&StatsItem{
+		Component: LISTER,
+		Name:      name,
+		Dir:       true,
+		Success:   err == nil,
+		Download:  false,
+		Err:       err,
+	})
+
+	return err
+}
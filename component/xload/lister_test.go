@@ -261,6 +261,129 @@ func (suite *listTestSuite) TestListerStartStop() {
 	suite.assert.Len(entries, 10)
 }
 
+func (suite *listTestSuite) TestListerFilter() {
+	tl, err := setupTestLister()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	filter, err := newPathFilter([]string{"dir_1", "dir_2", "file_1"}, []string{"file_23"})
+	suite.assert.Nil(err)
+
+	rl, err := newRemoteLister(&remoteListerOptions{
+		path:              tl.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+		filter:            filter,
+	})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(rl)
+
+	testComp := getTestcomponent()
+	rl.SetNext(testComp)
+
+	rl.Start()
+	time.Sleep(5 * time.Second)
+	rl.Stop()
+
+	// file_1 in root, all files of dir_1 and all files of dir_2 except file_23
+	suite.assert.Equal(int64(1+5+4), testComp.ctr.Load())
+
+	// directories which are not included are still traversed as they may have matching files
+	entries, err := os.ReadDir(tl.path)
+	suite.assert.Nil(err)
+	suite.assert.Len(entries, 10)
+}
+
+func (suite *listTestSuite) TestListerSharded() {
+	tl, err := setupTestLister()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	rl, err := newRemoteLister(&remoteListerOptions{
+		path:              tl.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+		shardedList:       4,
+	})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(rl)
+
+	testComp := getTestcomponent()
+	defer testComp.Stop()
+	rl.SetNext(testComp)
+
+	// the whole container is listed by the root item, so no sub-directory is scheduled for listing
+	cnt, err := rl.Process(&WorkItem{CompName: rl.GetName()})
+	suite.assert.Nil(err)
+	suite.assert.Equal(10+10+50, cnt)
+
+	time.Sleep(1 * time.Second)
+	suite.assert.Equal(int64(60), testComp.ctr.Load())
+
+	entries, err := os.ReadDir(tl.path)
+	suite.assert.Nil(err)
+	suite.assert.Len(entries, 10)
+	for _, entry := range entries {
+		suite.assert.True(entry.IsDir())
+	}
+}
+
+func (suite *listTestSuite) TestListerShardedFilter() {
+	tl, err := setupTestLister()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	filter, err := newPathFilter([]string{"dir_1", "file_1"}, []string{"dir_1/file_13"})
+	suite.assert.Nil(err)
+
+	rl, err := newRemoteLister(&remoteListerOptions{
+		path:              tl.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+		filter:            filter,
+		shardedList:       2,
+	})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(rl)
+
+	testComp := getTestcomponent()
+	defer testComp.Stop()
+	rl.SetNext(testComp)
+
+	// all directories as they may have matching files, four files of dir_1 and file_1 in root
+	cnt, err := rl.Process(&WorkItem{CompName: rl.GetName()})
+	suite.assert.Nil(err)
+	suite.assert.Equal(10+4+1, cnt)
+
+	time.Sleep(1 * time.Second)
+	suite.assert.Equal(int64(4+1), testComp.ctr.Load())
+
+	entries, err := os.ReadDir(tl.path)
+	suite.assert.Nil(err)
+	suite.assert.Len(entries, 10)
+}
+
 func (suite *listTestSuite) TestListerMkdir() {
 	tl, err := setupTestLister()
 	suite.assert.Nil(err)
@@ -292,6 +415,82 @@ func (suite *listTestSuite) TestListerMkdir() {
 	suite.assert.Len(entries, 5)
 }
 
+func (suite *listTestSuite) TestNewLocalLister() {
+	ll, err := newLocalLister(nil)
+	suite.assert.NotNil(err)
+	suite.assert.Nil(ll)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+
+	ll, err = newLocalLister(&localListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          nil,
+	})
+	suite.assert.NotNil(err)
+	suite.assert.Nil(ll)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+
+	statsMgr, err := NewStatsManager(1, false)
+	suite.assert.Nil(err)
+	suite.assert.NotNil(statsMgr)
+
+	ll, err = newLocalLister(&localListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          statsMgr,
+	})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(ll)
+}
+
+func (suite *listTestSuite) TestLocalListerStartStop() {
+	tl, err := setupTestLister()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	// list the files created for remote lister tests and create directories in an empty container
+	remote := newTestLoopback(tl.path)
+	ll, err := newLocalLister(&localListerOptions{
+		path:              lb_path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            remote,
+		statsMgr:          tl.stMgr,
+	})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(ll)
+
+	testComp := getTestcomponent()
+	ll.SetNext(testComp)
+
+	ll.Start()
+	suite.assert.Eventually(func() bool { return testComp.ctr.Load() == 60 }, 5*time.Second, 10*time.Millisecond)
+	ll.Stop()
+
+	entries, err := os.ReadDir(tl.path)
+	suite.assert.Nil(err)
+	suite.assert.Len(entries, 10)
+
+	_, err = ll.Process(&WorkItem{Path: "missing_dir"})
+	suite.assert.NotNil(err)
+}
+
+func newTestLoopback(path string) internal.Component {
+	config.ReadConfigFromReader(strings.NewReader(fmt.Sprintf("loopbackfs:\n  path: %s\n", path)))
+	remote := loopback.NewLoopbackFSComponent()
+	_ = remote.Configure(true)
+	return remote
+}
+
 func TestListSuite(t *testing.T) {
 	suite.Run(t, new(listTestSuite))
 }
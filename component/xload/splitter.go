@@ -35,7 +35,9 @@ package xload
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
+	"hash"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -50,6 +52,7 @@ import (
 // verify that the below types implement the xcomponent interfaces
 var _ XComponent = &splitter{}
 var _ XComponent = &downloadSplitter{}
+var _ XComponent = &uploadSplitter{}
 
 type splitter struct {
 	XBase
@@ -63,6 +66,10 @@ type splitter struct {
 
 type downloadSplitter struct {
 	splitter
+	checkpoint *checkpoint        // journal of downloaded files and blocks, nil if checkpointing is disabled
+	order      *priorityOrder     // order in which the listed files are downloaded, nil for listing order
+	tracker    *fileTracker       // files written on a read-write mount, nil if the mount is read-only
+	dedup      *common.DedupStore // store of downloaded content keyed by md5, nil if deduplication is disabled
 }
 
 type downloadSplitterOptions struct {
@@ -73,6 +80,10 @@ type downloadSplitterOptions struct {
 	statsMgr    *StatsManager
 	fileLocks   *common.LockMap
 	validateMD5 bool
+	checkpoint  *checkpoint
+	order       *priorityOrder
+	tracker     *fileTracker
+	dedup       *common.DedupStore
 }
 
 func newDownloadSplitter(opts *downloadSplitterOptions) (*downloadSplitter, error) {
@@ -90,6 +101,10 @@ func newDownloadSplitter(opts *downloadSplitterOptions) (*downloadSplitter, erro
 			fileLocks:   opts.fileLocks,
 			validateMD5: opts.validateMD5,
 		},
+		checkpoint: opts.checkpoint,
+		order:      opts.order,
+		tracker:    opts.tracker,
+		dedup:      opts.dedup,
 	}
 
 	ds.SetName(SPLITTER)
@@ -104,6 +119,8 @@ func (ds *downloadSplitter) Init() {
 	ds.SetThreadPool(NewThreadPool(ds.GetWorkerCount(), ds.Process))
 	if ds.GetThreadPool() == nil {
 		log.Err("downloadSplitter::Init : fail to init thread pool")
+	} else if ds.order != nil {
+		ds.GetThreadPool().SetOrder(ds.order.less)
 	}
 }
 
@@ -138,17 +155,59 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 		defer flock.Unlock()
 	}
 
+	// local copy has been written by the user, so it is newer than what was listed
+	if !item.Priority && ds.tracker != nil && ds.tracker.isModified(item.Path) {
+		log.Info("downloadSplitter::Process : %s is modified locally, skipping download", item.Path)
+		ds.GetStatsManager().AddStats(&StatsItem{
+			Component: SPLITTER,
+			Name:      item.Path,
+			Success:   true,
+			Download:  true,
+			Size:      item.DataLen,
+		})
+		return 0, nil
+	}
+
 	filePresent, isDir, size := isFilePresent(localPath)
 	if filePresent {
 		if isDir {
 			log.Err("downloadSplitter::Process : %s is a directory", item.Path)
 			return -1, fmt.Errorf("%s is a directory", item.Path)
-		} else if item.DataLen == uint64(size) {
+		} else if item.DataLen == uint64(size) && (ds.checkpoint == nil || ds.checkpoint.isComplete(item)) {
 			log.Debug("downloadSplitter::Process : %s will be served from local path, priority %v", item.Path, item.Priority)
+			if ds.tracker != nil && ds.checkpoint != nil {
+				// journal has confirmed that the local copy is of the listed version
+				ds.tracker.setETag(item.Path, item.ETag)
+			}
+
+			if !item.Priority {
+				// file listed by the lister is done as it is already present
+				ds.GetStatsManager().AddStats(&StatsItem{
+					Component: SPLITTER,
+					Name:      item.Path,
+					Success:   true,
+					Download:  true,
+					Size:      item.DataLen,
+				})
+			}
 			return int(size), nil
 		}
 	}
 
+	// content already downloaded for another path is linked in place instead of downloading it again
+	if ds.dedup != nil && item.DataLen > 0 && ds.linkContent(item, localPath) {
+		return 0, nil
+	}
+
+	// blocks already written to the local file by an interrupted download of the same version are not fetched again
+	var doneBlocks map[int64]bool
+	if ds.checkpoint != nil {
+		doneBlocks = ds.checkpoint.begin(item, filePresent && item.DataLen == uint64(size))
+		if len(doneBlocks) > 0 {
+			log.Info("downloadSplitter::Process : Resuming download of %s, %d blocks already present", item.Path, len(doneBlocks))
+		}
+	}
+
 	// TODO:: xload : should we delete the file if it already exists
 	// TODO:: xload : what should be the flags
 	// TODO:: xload : verify if the mode is set correctly
@@ -163,6 +222,14 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 
 	if item.DataLen == 0 {
 		log.Debug("downloadSplitter::Process : 0 byte file %s", item.Path)
+		if ds.checkpoint != nil {
+			ds.checkpoint.fileDone(item.Path)
+		}
+
+		if ds.tracker != nil {
+			ds.tracker.setETag(item.Path, item.ETag)
+		}
+
 		// send the status to stats manager
 		ds.GetStatsManager().AddStats(&StatsItem{
 			Component: SPLITTER,
@@ -187,8 +254,13 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 		return -1, fmt.Errorf("failed to truncate file %s [%s]", item.Path, err.Error())
 	}
 
-	numBlocks := ((item.DataLen - 1) / ds.blockPool.GetBlockSize()) + 1
-	offset := int64(0)
+	offsets := make([]int64, 0, ((item.DataLen-1)/ds.blockPool.GetBlockSize())+1)
+	for offset := int64(0); offset < int64(item.DataLen); offset += int64(ds.blockPool.GetBlockSize()) {
+		if !doneBlocks[offset] {
+			offsets = append(offsets, offset)
+		}
+	}
+	numBlocks := len(offsets)
 
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -198,21 +270,30 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 	defer cancel()
 
 	operationSuccess := true
+	var failure error // first error hit by the download, reported in the status
 	go func() {
 		defer wg.Done()
 
-		for i := 0; i < int(numBlocks); i++ {
+		for i := 0; i < numBlocks; i++ {
 			respSplitItem := <-responseChannel
 			if respSplitItem.Err != nil {
 				log.Err("downloadSplitter::Process : Failed to download data for file %s", item.Path)
+				if operationSuccess {
+					failure = respSplitItem.Err
+				}
 				operationSuccess = false
 				cancel() // cancel the context to stop download of other chunks
 			} else {
 				_, err := item.FileHandle.WriteAt(respSplitItem.Block.Data[:respSplitItem.DataLen], respSplitItem.Block.Offset)
 				if err != nil {
 					log.Err("downloadSplitter::Process : Failed to write data to file %s", item.Path)
+					if operationSuccess {
+						failure = err
+					}
 					operationSuccess = false
 					cancel() // cancel the context to stop download of other chunks
+				} else if ds.checkpoint != nil {
+					ds.checkpoint.blockDone(item.Path, respSplitItem.Block.Offset)
 				}
 			}
 
@@ -223,12 +304,12 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 		}
 	}()
 
-	for i := 0; i < int(numBlocks); i++ {
+	for _, offset := range offsets {
 		block := ds.blockPool.GetBlock(item.Priority)
 		if block == nil {
 			responseChannel <- &WorkItem{Err: fmt.Errorf("failed to get block from pool for file %s, offset %v", item.Path, offset)}
 		} else {
-			block.Index = i
+			block.Index = int(offset / int64(ds.blockPool.GetBlockSize()))
 			block.Offset = offset
 			block.Length = int64(ds.blockPool.GetBlockSize())
 
@@ -246,8 +327,6 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 			// log.Debug("downloadSplitter::Process : Scheduling download for %s offset %v", item.Path, offset)
 			ds.GetNext().Schedule(splitItem)
 		}
-
-		offset += int64(ds.blockPool.GetBlockSize())
 	}
 
 	wg.Wait()
@@ -265,6 +344,7 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 			// TODO:: xload : retry if md5 validation fails
 			log.Err("downloadSplitter::Process : unable to validate md5 for %s [%s]", item.Path, err.Error())
 			operationSuccess = false
+			failure = err
 		}
 	}
 
@@ -274,6 +354,8 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 		Name:      item.Path,
 		Success:   operationSuccess,
 		Download:  true,
+		Size:      item.DataLen,
+		Err:       failure,
 	})
 
 	if !operationSuccess {
@@ -288,10 +370,55 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 		return -1, fmt.Errorf("failed to download data for file %s", item.Path)
 	}
 
+	if ds.checkpoint != nil {
+		ds.checkpoint.fileDone(item.Path)
+	}
+
+	if ds.tracker != nil {
+		ds.tracker.setETag(item.Path, item.ETag)
+	}
+
+	if ds.dedup != nil {
+		err = ds.dedup.Add(item.MD5, localPath)
+		if err != nil {
+			log.Warn("downloadSplitter::Process : Failed to add %s to dedup store [%s]", item.Path, err.Error())
+		}
+	}
+
 	log.Debug("downloadSplitter::Process : Download completed for file %s, priority %v", item.Path, item.Priority)
 	return 0, nil
 }
 
+// linkContent : link the content of the file from the dedup store, returns false if it has to be downloaded
+func (ds *downloadSplitter) linkContent(item *WorkItem, localPath string) bool {
+	found, err := ds.dedup.Link(item.MD5, int64(item.DataLen), localPath)
+	if err != nil {
+		log.Warn("downloadSplitter::linkContent : Failed to link %s from dedup store [%s]", item.Path, err.Error())
+		return false
+	} else if !found {
+		return false
+	}
+
+	log.Debug("downloadSplitter::linkContent : %s linked from dedup store", item.Path)
+	if ds.checkpoint != nil {
+		ds.checkpoint.begin(item, false)
+		ds.checkpoint.fileDone(item.Path)
+	}
+
+	if ds.tracker != nil {
+		ds.tracker.setETag(item.Path, item.ETag)
+	}
+
+	ds.GetStatsManager().AddStats(&StatsItem{
+		Component: SPLITTER,
+		Name:      item.Path,
+		Success:   true,
+		Download:  true,
+		Size:      item.DataLen,
+	})
+	return true
+}
+
 func (ds *downloadSplitter) checkConsistency(item *WorkItem) error {
 	if item.MD5 == nil {
 		log.Warn("downloadSplitter::checkConsistency : Unable to get MD5Sum for blob %s", item.Path)
@@ -311,3 +438,242 @@ func (ds *downloadSplitter) checkConsistency(item *WorkItem) error {
 
 	return nil
 }
+
+// --------------------------------------------------------------------------------------------------------
+
+type uploadSplitter struct {
+	splitter
+}
+
+type uploadSplitterOptions struct {
+	blockPool   *BlockPool
+	path        string
+	workerCount uint32
+	remote      internal.Component
+	statsMgr    *StatsManager
+	fileLocks   *common.LockMap
+	validateMD5 bool
+}
+
+func newUploadSplitter(opts *uploadSplitterOptions) (*uploadSplitter, error) {
+	if opts == nil || opts.blockPool == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil || opts.fileLocks == nil || opts.workerCount == 0 {
+		log.Err("splitter::NewUploadSplitter : invalid parameters sent to create upload splitter")
+		return nil, fmt.Errorf("invalid parameters sent to create upload splitter")
+	}
+
+	log.Debug("splitter::NewUploadSplitter : create new upload splitter for %s, block size %v, workers %v", opts.path, opts.blockPool.GetBlockSize(), opts.workerCount)
+
+	us := &uploadSplitter{
+		splitter: splitter{
+			blockPool:   opts.blockPool,
+			path:        opts.path,
+			fileLocks:   opts.fileLocks,
+			validateMD5: opts.validateMD5,
+		},
+	}
+
+	us.SetName(SPLITTER)
+	us.SetWorkerCount(opts.workerCount)
+	us.SetRemote(opts.remote)
+	us.SetStatsManager(opts.statsMgr)
+	us.Init()
+	return us, nil
+}
+
+func (us *uploadSplitter) Init() {
+	us.SetThreadPool(NewThreadPool(us.GetWorkerCount(), us.Process))
+	if us.GetThreadPool() == nil {
+		log.Err("uploadSplitter::Init : fail to init thread pool")
+	}
+}
+
+func (us *uploadSplitter) Start() {
+	log.Debug("uploadSplitter::Start : start upload splitter for %s", us.path)
+	us.GetThreadPool().Start()
+}
+
+func (us *uploadSplitter) Stop() {
+	log.Debug("uploadSplitter::Stop : stop upload splitter for %s", us.path)
+	if us.GetThreadPool() != nil {
+		us.GetThreadPool().Stop()
+	}
+	us.GetNext().Stop()
+}
+
+// read the local file in chunks, stage each chunk as a block and then commit the block list
+func (us *uploadSplitter) Process(item *WorkItem) (int, error) {
+	log.Debug("uploadSplitter::Process : Splitting data for %s, size %v, mode %v, modified time %v", item.Path, item.DataLen,
+		item.Mode, item.Mtime.Format(time.DateTime))
+
+	flock := us.fileLocks.Get(item.Path)
+	flock.Lock()
+	defer flock.Unlock()
+
+	err := us.upload(item)
+
+	// send the upload status to stats manager
+	us.GetStatsManager().AddStats(&StatsItem{
+		Component: SPLITTER,
+		Name:      item.Path,
+		Success:   err == nil,
+		Download:  false,
+		Size:      item.DataLen,
+		Err:       err,
+	})
+
+	if err != nil {
+		log.Err("uploadSplitter::Process : Failed to upload file %s [%s]", item.Path, err.Error())
+		return -1, err
+	}
+
+	log.Debug("uploadSplitter::Process : Upload completed for file %s", item.Path)
+	return int(item.DataLen), nil
+}
+
+func (us *uploadSplitter) upload(item *WorkItem) error {
+	var err error
+	localPath := filepath.Join(us.path, item.Path)
+
+	item.FileHandle, err = os.OpenFile(localPath, os.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s [%s]", item.Path, err.Error())
+	}
+	defer item.FileHandle.Close()
+
+	blockSize := us.blockPool.GetBlockSize()
+	numBlocks := int((item.DataLen + blockSize - 1) / blockSize)
+	blockList := make([]string, numBlocks)
+
+	// md5 of local data is computed while reading it so that the file is not read again for validation
+	var localMD5 hash.Hash
+	if us.validateMD5 {
+		localMD5 = md5.New()
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	responseChannel := make(chan *WorkItem, numBlocks)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	operationSuccess := true
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < numBlocks; i++ {
+			respSplitItem := <-responseChannel
+			if respSplitItem.Err != nil {
+				log.Err("uploadSplitter::upload : Failed to upload data for file %s", item.Path)
+				operationSuccess = false
+				cancel() // cancel the context to stop upload of other chunks
+			}
+
+			if respSplitItem.Block != nil {
+				us.blockPool.Release(respSplitItem.Block)
+			}
+		}
+	}()
+
+	offset := int64(0)
+	for i := 0; i < numBlocks; i++ {
+		length := int64(min(blockSize, item.DataLen-uint64(offset)))
+
+		// blocks are taken from the pool only when previous ones are released, which bounds the memory used
+		block := us.blockPool.GetBlock(false)
+		if block == nil {
+			responseChannel <- &WorkItem{Err: fmt.Errorf("failed to get block from pool for file %s, offset %v", item.Path, offset)}
+		} else if ctx.Err() != nil {
+			responseChannel <- &WorkItem{Block: block, Err: ctx.Err()}
+		} else {
+			n, err := item.FileHandle.ReadAt(block.Data[:length], offset)
+			if int64(n) != length {
+				log.Err("uploadSplitter::upload : Failed to read %s at offset %v, file may have changed [%v]", item.Path, offset, err)
+				responseChannel <- &WorkItem{Block: block, Err: fmt.Errorf("failed to read %s at offset %v", item.Path, offset)}
+			} else {
+				if localMD5 != nil {
+					_, _ = localMD5.Write(block.Data[:length])
+				}
+
+				block.Index = i
+				block.Offset = offset
+				block.Length = length
+				block.Id = common.GetBlockID(common.BlockIDLength)
+				blockList[i] = block.Id
+
+				us.GetNext().Schedule(&WorkItem{
+					CompName:        us.GetNext().GetName(),
+					Path:            item.Path,
+					DataLen:         item.DataLen,
+					Block:           block,
+					ResponseChannel: responseChannel,
+					Download:        false,
+					Ctx:             ctx,
+				})
+			}
+		}
+
+		offset += length
+	}
+
+	wg.Wait()
+
+	if !operationSuccess {
+		return fmt.Errorf("failed to upload data for file %s", item.Path)
+	}
+
+	err = us.GetRemote().CommitData(internal.CommitDataOptions{
+		Name:      item.Path,
+		List:      blockList,
+		BlockSize: blockSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit blocks of %s [%s]", item.Path, err.Error())
+	}
+
+	if localMD5 != nil {
+		err = us.checkConsistency(item, localMD5.Sum(nil))
+		if err != nil {
+			// TODO:: xload : retry if md5 validation fails
+			return err
+		}
+	}
+
+	return nil
+}
+
+// read back the uploaded blob and compare its md5 with that of the local file
+func (us *uploadSplitter) checkConsistency(item *WorkItem, localMD5 []byte) error {
+	block := us.blockPool.GetBlock(false)
+	if block == nil {
+		return fmt.Errorf("failed to get block from pool to validate %s", item.Path)
+	}
+	defer us.blockPool.Release(block)
+
+	remoteMD5 := md5.New()
+	for offset := int64(0); offset < int64(item.DataLen); {
+		n, err := us.GetRemote().ReadInBuffer(internal.ReadInBufferOptions{
+			Offset: offset,
+			Data:   block.Data[:min(us.blockPool.GetBlockSize(), item.DataLen-uint64(offset))],
+			Path:   item.Path,
+			Size:   int64(item.DataLen),
+		})
+		if err != nil {
+			log.Err("uploadSplitter::checkConsistency : Failed to read %s at offset %v [%s]", item.Path, offset, err.Error())
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		_, _ = remoteMD5.Write(block.Data[:n])
+		offset += int64(n)
+	}
+
+	if !reflect.DeepEqual(remoteMD5.Sum(nil), localMD5) {
+		log.Err("uploadSplitter::checkConsistency : MD5Sum mismatch on upload for file %s", item.Path)
+		return fmt.Errorf("md5sum mismatch on upload for file %s", item.Path)
+	}
+
+	return nil
+}
@@ -41,6 +41,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -179,6 +180,22 @@ func (suite *splitterTestSuite) TestNewDownloadSplitter() {
 	})
 	suite.assert.Nil(err)
 	suite.assert.NotNil(ds)
+	suite.assert.Nil(ds.GetThreadPool().queue)
+
+	order, err := newPriorityOrder([]string{"smallest-first"})
+	suite.assert.Nil(err)
+
+	ds, err = newDownloadSplitter(&downloadSplitterOptions{
+		blockPool:   NewBlockPool(1, 1),
+		path:        "/home/user/random_path",
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    statsMgr,
+		fileLocks:   common.NewLockMap(),
+		order:       order,
+	})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(ds.GetThreadPool().queue)
 }
 
 func (suite *splitterTestSuite) TestProcessFilePresent() {
@@ -191,7 +208,7 @@ func (suite *splitterTestSuite) TestProcessFilePresent() {
 		suite.assert.Nil(err)
 	}()
 
-	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, false})
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, false, nil, nil, nil, nil})
 	suite.assert.Nil(err)
 	suite.assert.NotNil(ds)
 
@@ -210,6 +227,208 @@ func (suite *splitterTestSuite) TestProcessFilePresent() {
 	suite.assert.Equal(n, 36)
 }
 
+func (suite *splitterTestSuite) TestProcessCheckpoint() {
+	ts, err := setupTestSplitter()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	cp, err := newCheckpoint(filepath.Join(ts.path+"_journal", "xload.journal"))
+	suite.assert.Nil(err)
+	defer os.RemoveAll(ts.path + "_journal")
+	defer cp.close()
+
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, false, cp, nil, nil, nil})
+	suite.assert.Nil(err)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.Nil(err)
+	ds.SetNext(rdm)
+	rdm.Start()
+	defer rdm.Stop()
+
+	fileName := "file_4"
+	localFile := filepath.Join(ts.path, fileName)
+	info, err := os.Stat(filepath.Join(remote_path, fileName))
+	suite.assert.Nil(err)
+	item := func() *WorkItem {
+		return &WorkItem{Path: fileName, DataLen: uint64(info.Size()), Mode: 0644, Mtime: info.ModTime(), Atime: info.ModTime()}
+	}
+
+	// file present locally without a journal entry is downloaded again
+	err = os.WriteFile(localFile, make([]byte, info.Size()), 0644)
+	suite.assert.Nil(err)
+	_, err = ds.Process(item())
+	suite.assert.Nil(err)
+	remoteData, err := os.ReadFile(filepath.Join(remote_path, fileName))
+	suite.assert.Nil(err)
+	data, err := os.ReadFile(localFile)
+	suite.assert.Nil(err)
+	suite.assert.Equal(remoteData, data)
+
+	// completed file is served from local path
+	marker := []byte("0123456789")
+	fh, err := os.OpenFile(localFile, os.O_WRONLY, 0644)
+	suite.assert.Nil(err)
+	_, err = fh.WriteAt(marker, 0)
+	suite.assert.Nil(err)
+	suite.assert.Nil(fh.Close())
+
+	_, err = ds.Process(item())
+	suite.assert.Nil(err)
+	data, err = os.ReadFile(localFile)
+	suite.assert.Nil(err)
+	suite.assert.Equal(marker, data[:10])
+
+	// interrupted download fetches only the blocks which are not in the journal
+	cp.begin(item(), false)
+	cp.blockDone(fileName, 0)
+	_, err = ds.Process(item())
+	suite.assert.Nil(err)
+	data, err = os.ReadFile(localFile)
+	suite.assert.Nil(err)
+	suite.assert.Equal(marker, data[:10])
+	suite.assert.Equal(remoteData[10:], data[10:])
+
+	// blob changed after the interrupted download, so the file is downloaded again
+	cp.begin(item(), false)
+	cp.blockDone(fileName, 0)
+	changed := item()
+	changed.Mtime = changed.Mtime.Add(time.Second)
+	_, err = ds.Process(changed)
+	suite.assert.Nil(err)
+	data, err = os.ReadFile(localFile)
+	suite.assert.Nil(err)
+	suite.assert.Equal(remoteData, data)
+	suite.assert.True(cp.isCurrent(fileName))
+}
+
+func (suite *splitterTestSuite) TestProcessDedup() {
+	ts, err := setupTestSplitter()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	store, err := common.NewDedupStore(ts.path + "_dedup")
+	suite.assert.Nil(err)
+	defer os.RemoveAll(ts.path + "_dedup")
+
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, false, nil, nil, nil, store})
+	suite.assert.Nil(err)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.Nil(err)
+	ds.SetNext(rdm)
+	rdm.Start()
+	defer rdm.Stop()
+
+	fileName := "file_4"
+	remoteData, err := os.ReadFile(filepath.Join(remote_path, fileName))
+	suite.assert.Nil(err)
+	sum := md5.Sum(remoteData)
+	item := func(name string) *WorkItem {
+		return &WorkItem{Path: name, DataLen: uint64(len(remoteData)), Mode: 0644, MD5: sum[:]}
+	}
+
+	// downloaded file is added to the store
+	_, err = ds.Process(item(fileName))
+	suite.assert.Nil(err)
+	info, err := os.Stat(filepath.Join(ts.path, fileName))
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(2, info.Sys().(*syscall.Stat_t).Nlink)
+
+	// file with the same content is linked from the store, so it is not downloaded even if the blob does not exist
+	_, err = ds.Process(item("copy_of_file_4"))
+	suite.assert.Nil(err)
+	data, err := os.ReadFile(filepath.Join(ts.path, "copy_of_file_4"))
+	suite.assert.Nil(err)
+	suite.assert.Equal(remoteData, data)
+
+	copyInfo, err := os.Stat(filepath.Join(ts.path, "copy_of_file_4"))
+	suite.assert.Nil(err)
+	suite.assert.True(os.SameFile(info, copyInfo))
+
+	// file with content of a different md5 is not linked
+	other := item("other_file")
+	other.MD5 = []byte("0123456789abcdef")
+	_, _ = ds.Process(other)
+	otherInfo, err := os.Stat(filepath.Join(ts.path, "other_file"))
+	if err == nil {
+		suite.assert.False(os.SameFile(info, otherInfo))
+	}
+}
+
+func (suite *splitterTestSuite) TestProcessModified() {
+	ts, err := setupTestSplitter()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	tracker := newFileTracker()
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, false, nil, nil, tracker, nil})
+	suite.assert.Nil(err)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.Nil(err)
+	ds.SetNext(rdm)
+	rdm.Start()
+	defer rdm.Stop()
+
+	fileName := "file_4"
+	localFile := filepath.Join(ts.path, fileName)
+	info, err := os.Stat(filepath.Join(remote_path, fileName))
+	suite.assert.Nil(err)
+	item := &WorkItem{Path: fileName, DataLen: uint64(info.Size()), Mode: 0644, Mtime: info.ModTime(), Atime: info.ModTime(), ETag: "etag_1"}
+
+	// file written locally is not overwritten by the listed version
+	local := []byte("written on the mount")
+	err = os.WriteFile(localFile, local, 0644)
+	suite.assert.Nil(err)
+	tracker.markModified(fileName)
+
+	_, err = ds.Process(item)
+	suite.assert.Nil(err)
+	data, err := os.ReadFile(localFile)
+	suite.assert.Nil(err)
+	suite.assert.Equal(local, data)
+	suite.assert.Empty(tracker.etag(fileName))
+
+	// file which is not modified is downloaded and its etag is recorded
+	tracker.remove(fileName, false)
+	_, err = ds.Process(item)
+	suite.assert.Nil(err)
+	remoteData, err := os.ReadFile(filepath.Join(remote_path, fileName))
+	suite.assert.Nil(err)
+	data, err = os.ReadFile(localFile)
+	suite.assert.Nil(err)
+	suite.assert.Equal(remoteData, data)
+	suite.assert.Equal("etag_1", tracker.etag(fileName))
+}
+
 func (suite *splitterTestSuite) TestSplitterStartStop() {
 	ts, err := setupTestSplitter()
 	suite.assert.Nil(err)
@@ -230,7 +449,7 @@ func (suite *splitterTestSuite) TestSplitterStartStop() {
 	suite.assert.Nil(err)
 	suite.assert.NotNil(rl)
 
-	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, true})
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, true, nil, nil, nil, nil})
 	suite.assert.Nil(err)
 	suite.assert.NotNil(ds)
 
@@ -282,7 +501,7 @@ func (suite *splitterTestSuite) TestSplitterConsistency() {
 	suite.assert.Nil(err)
 	suite.assert.NotNil(rl)
 
-	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, true})
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{ts.blockPool, ts.path, 4, remote, ts.stMgr, ts.locks, true, nil, nil, nil, nil})
 	suite.assert.Nil(err)
 	suite.assert.NotNil(ds)
 
@@ -311,6 +530,141 @@ func (suite *splitterTestSuite) TestSplitterConsistency() {
 	validateMD5(ts.path, remote_path, suite.assert)
 }
 
+func (suite *splitterTestSuite) TestNewUploadSplitter() {
+	us, err := newUploadSplitter(nil)
+	suite.assert.NotNil(err)
+	suite.assert.Nil(us)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+
+	us, err = newUploadSplitter(&uploadSplitterOptions{})
+	suite.assert.NotNil(err)
+	suite.assert.Nil(us)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+
+	statsMgr, err := NewStatsManager(1, false)
+	suite.assert.Nil(err)
+	suite.assert.NotNil(statsMgr)
+
+	us, err = newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   NewBlockPool(1, 1),
+		path:        "/home/user/random_path",
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    statsMgr,
+		fileLocks:   common.NewLockMap(),
+	})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(us)
+}
+
+func (suite *splitterTestSuite) TestUploadStartStop() {
+	for _, validate := range []bool{false, true} {
+		ts, err := setupTestSplitter()
+		suite.assert.Nil(err)
+		suite.assert.NotNil(ts)
+
+		// upload the files created for download tests to an empty container
+		container := newTestLoopback(ts.path)
+
+		ll, err := newLocalLister(&localListerOptions{
+			path:              remote_path,
+			workerCount:       4,
+			defaultPermission: common.DefaultFilePermissionBits,
+			remote:            container,
+			statsMgr:          ts.stMgr,
+		})
+		suite.assert.Nil(err)
+
+		us, err := newUploadSplitter(&uploadSplitterOptions{ts.blockPool, remote_path, 4, container, ts.stMgr, ts.locks, validate})
+		suite.assert.Nil(err)
+
+		rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+			workerCount: 8,
+			remote:      container,
+			statsMgr:    ts.stMgr,
+		})
+		suite.assert.Nil(err)
+
+		ll.SetNext(us)
+		us.SetNext(rdm)
+
+		rdm.Start()
+		us.Start()
+		ll.Start()
+
+		suite.assert.Eventually(func() bool { return uploaded(remote_path, ts.path) }, 5*time.Second, 10*time.Millisecond)
+		ll.Stop()
+
+		validateMD5(ts.path, remote_path, suite.assert)
+
+		err = ts.cleanup()
+		suite.assert.Nil(err)
+	}
+}
+
+func (suite *splitterTestSuite) TestUploadErrors() {
+	ts, err := setupTestSplitter()
+	suite.assert.Nil(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.Nil(err)
+	}()
+
+	container := newTestLoopback(ts.path)
+	us, err := newUploadSplitter(&uploadSplitterOptions{ts.blockPool, remote_path, 4, container, ts.stMgr, ts.locks, true})
+	suite.assert.Nil(err)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 4,
+		remote:      container,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.Nil(err)
+	us.SetNext(rdm)
+	rdm.Start()
+	defer rdm.Stop()
+
+	// local file does not exist
+	n, err := us.Process(&WorkItem{Path: "missing_file", DataLen: 10})
+	suite.assert.NotNil(err)
+	suite.assert.Equal(-1, n)
+
+	// local file is smaller than the size given by the lister
+	n, err = us.Process(&WorkItem{Path: "file_2", DataLen: 40})
+	suite.assert.NotNil(err)
+	suite.assert.Equal(-1, n)
+
+	// parent directory is not created in container
+	n, err = us.Process(&WorkItem{Path: "dir_0/file_3", DataLen: 27})
+	suite.assert.NotNil(err)
+	suite.assert.Equal(-1, n)
+
+	n, err = us.Process(&WorkItem{Path: "file_3", DataLen: 27})
+	suite.assert.Nil(err)
+	suite.assert.Equal(27, n)
+	suite.assert.True(uploaded(filepath.Join(remote_path, "file_3"), filepath.Join(ts.path, "file_3")))
+}
+
+// check if all the files in the source tree are present in the destination with the same size
+func uploaded(src string, dst string) bool {
+	done := true
+	_ = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		dstInfo, err := os.Stat(filepath.Join(dst, strings.TrimPrefix(path, src)))
+		if err != nil || dstInfo.IsDir() != info.IsDir() || (!info.IsDir() && dstInfo.Size() != info.Size()) {
+			done = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return done
+}
+
 func validateMD5(localPath string, remotePath string, assert *assert.Assertions) {
 	entries, err := os.ReadDir(remotePath)
 	assert.Nil(err)
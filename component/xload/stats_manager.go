@@ -54,6 +54,10 @@ type StatsManager struct {
 	dirs            uint64          // number of directories processed
 	bytesDownloaded uint64          // total number of bytes downloaded
 	bytesUploaded   uint64          // total number of bytes uploaded
+	totalBytes      uint64          // total size of the files that have been scanned so far
+	processedBytes  uint64          // total size of the files that have been processed
+	recentErrors    []string        // most recent failures, oldest first
+	lock            sync.Mutex      // lock guarding the counters, as status is read while the stats are being processed
 	startTime       time.Time       // variable indicating the time at which the stats manager started
 	fileHandle      *os.File        // file where stats will be dumped
 	waitGroup       sync.WaitGroup  // wait group to wait for stats manager thread to finish
@@ -69,6 +73,8 @@ type StatsItem struct {
 	Success          bool   // flag to indicate if the file has been processed successfully or not
 	Download         bool   // flag to denote upload or download
 	BytesTransferred uint64 // bytes uploaded or downloaded for this file
+	Size             uint64 // size of the file processed, or total size of the files scanned by the lister
+	Err              error  // reason of the failure
 }
 
 type statsJSONData struct {
@@ -86,6 +92,7 @@ const (
 	STATS_MANAGER  = "STATS_MANAGER"
 	DURATION       = 4                        // time interval in seconds at which the stats will be dumped
 	JSON_FILE_NAME = "xload_stats_{PID}.json" // json file name where the stats manager will dump the stats
+	RECENT_ERRORS  = 10                       // number of failures kept for the status
 )
 
 func NewStatsManager(count uint32, isExportEnabled bool) (*StatsManager, error) {
@@ -145,40 +152,68 @@ func (sm *StatsManager) updateSuccessFailedCtr(isSuccess bool) {
 	}
 }
 
+// addRecentError : keep the failure for the status, dropping the oldest one once the limit is reached
+func (sm *StatsManager) addRecentError(item *StatsItem) {
+	msg := item.Name
+	if item.Err != nil {
+		msg = fmt.Sprintf("%s [%s]", item.Name, item.Err.Error())
+	}
+
+	if len(sm.recentErrors) == RECENT_ERRORS {
+		sm.recentErrors = sm.recentErrors[1:]
+	}
+	sm.recentErrors = append(sm.recentErrors, msg)
+}
+
 func (sm *StatsManager) statsProcessor() {
 	defer sm.waitGroup.Done()
 
 	for item := range sm.items {
-		switch item.Component {
-		case LISTER:
-			sm.totalFiles += item.ListerCount
-			// log.Debug("statsManager::statsProcessor : Directory listed %v, total number of files listed so far = %v", item.name, sm.totalFiles)
-			if item.Dir {
-				sm.dirs += 1
-				sm.updateSuccessFailedCtr(item.Success)
-			}
+		sm.processItem(item)
+	}
 
-		case SPLITTER:
-			// log.Debug("statsManager::statsProcessor : splitter: Name %v, success %v, download %v", item.name, item.success, item.download)
-			sm.updateSuccessFailedCtr(item.Success)
+	log.Debug("statsManager::statsProcessor : stats processor completed")
+}
 
-		case DATA_MANAGER:
-			// log.Debug("statsManager::statsProcessor : data manager: Name %v, success %v, download %v, bytes transferred %v", item.name, item.success, item.download, item.bytesTransferred)
-			if item.Download {
-				sm.bytesDownloaded += item.BytesTransferred
-			} else {
-				sm.bytesUploaded += item.BytesTransferred
+func (sm *StatsManager) processItem(item *StatsItem) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+
+	switch item.Component {
+	case LISTER:
+		sm.totalFiles += item.ListerCount
+		sm.totalBytes += item.Size
+		// log.Debug("statsManager::statsProcessor : Directory listed %v, total number of files listed so far = %v", item.name, sm.totalFiles)
+		if item.Dir {
+			sm.dirs += 1
+			sm.updateSuccessFailedCtr(item.Success)
+			if !item.Success {
+				sm.addRecentError(item)
 			}
+		}
 
-		case STATS_MANAGER:
-			sm.calculateBandwidth()
+	case SPLITTER:
+		// log.Debug("statsManager::statsProcessor : splitter: Name %v, success %v, download %v", item.name, item.success, item.download)
+		sm.updateSuccessFailedCtr(item.Success)
+		sm.processedBytes += item.Size
+		if !item.Success {
+			sm.addRecentError(item)
+		}
 
-		default:
-			log.Err("statsManager::statsProcessor : wrong component name used for sending stats")
+	case DATA_MANAGER:
+		// log.Debug("statsManager::statsProcessor : data manager: Name %v, success %v, download %v, bytes transferred %v", item.name, item.success, item.download, item.bytesTransferred)
+		if item.Download {
+			sm.bytesDownloaded += item.BytesTransferred
+		} else {
+			sm.bytesUploaded += item.BytesTransferred
 		}
-	}
 
-	log.Debug("statsManager::statsProcessor : stats processor completed")
+	case STATS_MANAGER:
+		sm.calculateBandwidth()
+
+	default:
+		log.Err("statsManager::statsProcessor : wrong component name used for sending stats")
+	}
 }
 
 func (sm *StatsManager) statsExporter() {
@@ -204,41 +239,89 @@ func (sm *StatsManager) calculateBandwidth() {
 	}
 
 	currTime := time.Now().UTC()
-	timeLapsed := currTime.Sub(sm.startTime).Seconds()
-	bytesTransferred := sm.bytesDownloaded + sm.bytesUploaded
-	filesProcessed := sm.success + sm.failed
-	filesPending := sm.totalFiles - filesProcessed
-	percentCompleted := (float64(filesProcessed) / float64(sm.totalFiles)) * 100
-	bandwidthMbps := float64(bytesTransferred*8) / (timeLapsed * float64(MB))
+	st := sm.progress(currTime)
 
 	log.Crit("statsManager::calculateBandwidth : timestamp %v, %.2f%%, %v Done, %v Failed, "+
 		"%v Pending, %v Total, Bytes transferred %v, Throughput (Mbps): %.2f",
-		currTime.Format(time.RFC1123), percentCompleted, sm.success, sm.failed,
-		filesPending, sm.totalFiles, bytesTransferred, bandwidthMbps)
+		st.Timestamp, st.PercentCompleted, st.Done, st.Failed,
+		st.Pending, st.Total, st.BytesTransferred, st.BandwidthMbps)
 
 	if sm.fileHandle != nil {
 		err := sm.marshalStatsData(&statsJSONData{
-			Timestamp:        currTime.Format(time.RFC1123),
-			PercentCompleted: RoundFloat(percentCompleted, 2),
-			Total:            sm.totalFiles,
-			Done:             sm.success,
-			Failed:           sm.failed,
-			Pending:          filesPending,
-			BytesTransferred: bytesTransferred,
-			BandwidthMbps:    RoundFloat(bandwidthMbps, 2),
+			Timestamp:        st.Timestamp,
+			PercentCompleted: RoundFloat(st.PercentCompleted, 2),
+			Total:            st.Total,
+			Done:             st.Done,
+			Failed:           st.Failed,
+			Pending:          st.Pending,
+			BytesTransferred: st.BytesTransferred,
+			BandwidthMbps:    RoundFloat(st.BandwidthMbps, 2),
 		}, true)
 		if err != nil {
 			log.Err("statsManager::calculateBandwidth : failed to write to json file [%v]", err.Error())
 		}
 	}
 
-	// TODO:: xload : determine more effective way to decide if the listing has completed and the stats exporter can be terminated
-	if sm.totalFiles == filesProcessed && sm.totalFiles != sm.dirs {
+	if st.State == STATE_COMPLETED {
 		sm.done <- true
 		return
 	}
 }
 
+// status : progress of the transfer so far
+func (sm *StatsManager) status() *Status {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+
+	return sm.progress(time.Now().UTC())
+}
+
+// progress : compute the progress from the counters, caller shall hold the lock or be the stats processor
+func (sm *StatsManager) progress(currTime time.Time) *Status {
+	timeLapsed := currTime.Sub(sm.startTime).Seconds()
+	bytesTransferred := sm.bytesDownloaded + sm.bytesUploaded
+	filesProcessed := sm.success + sm.failed
+
+	st := &Status{
+		State:            STATE_RUNNING,
+		Timestamp:        currTime.Format(time.RFC1123),
+		Total:            sm.totalFiles,
+		Done:             sm.success,
+		Failed:           sm.failed,
+		BytesTotal:       sm.totalBytes,
+		BytesDone:        sm.processedBytes,
+		BytesTransferred: bytesTransferred,
+		ETASeconds:       -1,
+		RecentErrors:     append([]string{}, sm.recentErrors...),
+	}
+
+	// files downloaded on open are counted even if the lister has not reached them yet
+	if sm.totalFiles > filesProcessed {
+		st.Pending = sm.totalFiles - filesProcessed
+	}
+	if sm.totalBytes > sm.processedBytes {
+		st.BytesPending = sm.totalBytes - sm.processedBytes
+	}
+
+	if sm.totalFiles > 0 {
+		st.PercentCompleted = (float64(filesProcessed) / float64(sm.totalFiles)) * 100
+	}
+
+	if timeLapsed > 0 {
+		st.BandwidthMbps = float64(bytesTransferred*8) / (timeLapsed * float64(MB))
+	}
+
+	// TODO:: xload : determine more effective way to decide if the listing has completed and the stats exporter can be terminated
+	if sm.totalFiles == filesProcessed && sm.totalFiles != sm.dirs {
+		st.State = STATE_COMPLETED
+		st.ETASeconds = 0
+	} else if bytesTransferred > 0 && timeLapsed > 0 {
+		st.ETASeconds = int64(float64(st.BytesPending) * timeLapsed / float64(bytesTransferred))
+	}
+
+	return st
+}
+
 func (sm *StatsManager) marshalStatsData(data *statsJSONData, seek bool) error {
 	if sm.fileHandle == nil {
 		return nil
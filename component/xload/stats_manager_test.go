@@ -130,6 +130,44 @@ func (suite *statsMgrTestSuite) TestStatsManagerStartStop() {
 	suite.assert.Greater(sm.bytesUploaded, uint64(0))
 }
 
+func (suite *statsMgrTestSuite) TestStatus() {
+	sm, err := NewStatsManager(4, false)
+	suite.assert.Nil(err)
+	suite.assert.NotNil(sm)
+	sm.startTime = time.Now().UTC().Add(-10 * time.Second)
+
+	st := sm.status()
+	suite.assert.Equal(STATE_RUNNING, st.State)
+	suite.assert.Equal(int64(-1), st.ETASeconds)
+
+	sm.processItem(&StatsItem{Component: LISTER, Name: "dir", ListerCount: 4, Size: 4 * MB})
+	sm.processItem(&StatsItem{Component: SPLITTER, Name: "file_0", Success: true, Download: true, Size: MB})
+	sm.processItem(&StatsItem{Component: DATA_MANAGER, Name: "file_0", Success: true, Download: true, BytesTransferred: MB})
+
+	for i := 0; i < RECENT_ERRORS+2; i++ {
+		sm.processItem(&StatsItem{Component: SPLITTER, Name: fmt.Sprintf("failed_%d", i), Download: true, Err: fmt.Errorf("error %d", i)})
+	}
+
+	st = sm.status()
+	suite.assert.Equal(uint64(4), st.Total)
+	suite.assert.Equal(uint64(1), st.Done)
+	suite.assert.Equal(uint64(RECENT_ERRORS+2), st.Failed)
+	suite.assert.Equal(uint64(0), st.Pending) // more files processed than listed
+	suite.assert.Equal(uint64(4*MB), st.BytesTotal)
+	suite.assert.Equal(uint64(MB), st.BytesDone)
+	suite.assert.Equal(uint64(3*MB), st.BytesPending)
+	suite.assert.Equal(uint64(MB), st.BytesTransferred)
+	suite.assert.Greater(st.BandwidthMbps, float64(0))
+
+	// 1 MB took 10 seconds, so 3 MB take 30 seconds more
+	suite.assert.InDelta(30, st.ETASeconds, 2)
+
+	// only the most recent errors are kept
+	suite.assert.Len(st.RecentErrors, RECENT_ERRORS)
+	suite.assert.Equal("failed_2 [error 2]", st.RecentErrors[0])
+	suite.assert.Equal(fmt.Sprintf("failed_%d [error %d]", RECENT_ERRORS+1, RECENT_ERRORS+1), st.RecentErrors[RECENT_ERRORS-1])
+}
+
 func TestStatsMgrSuite(t *testing.T) {
 	suite.Run(t, new(statsMgrTestSuite))
 }
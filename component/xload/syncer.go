@@ -0,0 +1,656 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// verify that the below types implement the xcomponent interfaces
+var _ XComponent = &syncer{}
+
+const SYNCER string = "SYNCER"
+
+// syncEntry : state of a file when it was last synced, used to find which side has changed since then
+type syncEntry struct {
+	LocalSize   int64
+	LocalMtime  time.Time
+	RemoteSize  int64
+	RemoteMtime time.Time
+	ETag        string
+}
+
+type syncState struct {
+	Version int
+	Files   map[string]*syncEntry
+}
+
+// syncAction : operation which brings a path in sync on both sides
+type syncAction int
+
+const (
+	syncSkip syncAction = iota
+	syncDownload
+	syncUpload
+	syncDeleteLocal
+	syncDeleteRemote
+)
+
+type syncPlan struct {
+	action syncAction
+	local  os.FileInfo
+	remote *internal.ObjAttr
+}
+
+type syncer struct {
+	XBase
+	path              string
+	stateFile         string
+	policy            SyncPolicy
+	deletes           bool
+	defaultPermission os.FileMode
+	downloader        XComponent
+	uploader          XComponent
+
+	lock  sync.Mutex
+	state map[string]*syncEntry // last synced state of each file
+	plans sync.Map              // pending operation of each path
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	done   chan struct{} // closed once the sync has completed
+}
+
+type syncerOptions struct {
+	path              string
+	stateFile         string
+	policy            SyncPolicy
+	deletes           bool
+	workerCount       uint32
+	defaultPermission os.FileMode
+	remote            internal.Component
+	statsMgr          *StatsManager
+	downloader        XComponent
+	uploader          XComponent
+}
+
+func newSyncer(opts *syncerOptions) (*syncer, error) {
+	if opts == nil || opts.path == "" || opts.stateFile == "" || opts.remote == nil || opts.statsMgr == nil || opts.workerCount == 0 ||
+		opts.downloader == nil || opts.uploader == nil || opts.policy == ESyncPolicy.INVALID_POLICY() {
+		log.Err("syncer::NewSyncer : invalid parameters sent to create syncer")
+		return nil, fmt.Errorf("invalid parameters sent to create syncer")
+	}
+
+	log.Debug("syncer::NewSyncer : create new syncer for %s, state %s, policy %v, deletes %v, workers %v", opts.path, opts.stateFile,
+		opts.policy.String(), opts.deletes, opts.workerCount)
+
+	s := &syncer{
+		path:              opts.path,
+		stateFile:         opts.stateFile,
+		policy:            opts.policy,
+		deletes:           opts.deletes,
+		defaultPermission: opts.defaultPermission,
+		downloader:        opts.downloader,
+		uploader:          opts.uploader,
+		state:             make(map[string]*syncEntry),
+		done:              make(chan struct{}),
+	}
+
+	s.SetName(SYNCER)
+	s.SetWorkerCount(opts.workerCount)
+	s.SetRemote(opts.remote)
+	s.SetStatsManager(opts.statsMgr)
+	s.Init()
+	return s, nil
+}
+
+// syncStateFile : default path of the state file for the given local path and container
+func syncStateFile(path string, container string) string {
+	return stateFilePath("xload_sync", "json", path, container)
+}
+
+func (s *syncer) Start() {
+	log.Debug("syncer::Start : start syncer for %s", s.path)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *syncer) Stop() {
+	log.Debug("syncer::Stop : stop syncer for %s", s.path)
+	if s.cancel != nil {
+		s.cancel()
+		s.wg.Wait()
+	}
+	s.GetNext().Stop()
+}
+
+// run : compare both sides, apply the operations in parallel and persist the new state
+func (s *syncer) run() {
+	defer s.wg.Done()
+	defer close(s.done)
+
+	err := waitForListTimeout()
+	if err != nil {
+		log.Err("syncer::run : unable to unmarshal block-list-on-mount-sec [%s]", err.Error())
+		return
+	}
+
+	err = s.loadState()
+	if err != nil {
+		log.Warn("syncer::run : Failed to load state from %s, all files will be compared [%s]", s.stateFile, err.Error())
+	}
+
+	localFiles, localDirs, err := s.listLocal()
+	if err != nil {
+		log.Err("syncer::run : Failed to list local path %s [%s]", s.path, err.Error())
+		return
+	}
+
+	// a partial listing would look like deleted files, so do not go ahead without a complete one
+	remoteFiles, remoteDirs, err := s.listRemote()
+	if err != nil {
+		log.Err("syncer::run : Failed to list container [%s]", err.Error())
+		return
+	}
+
+	s.syncDirs(localDirs, remoteDirs)
+	names := s.plan(localFiles, remoteFiles)
+
+	log.Info("syncer::run : %d local files, %d remote files, %d to be synced", len(localFiles), len(remoteFiles), len(names))
+	s.GetStatsManager().AddStats(&StatsItem{
+		Component:   LISTER,
+		Name:        s.path,
+		ListerCount: uint64(len(names)),
+	})
+
+	sem := make(chan struct{}, s.GetWorkerCount())
+	actions := sync.WaitGroup{}
+
+schedule:
+	for _, name := range names {
+		select {
+		case <-s.ctx.Done():
+			log.Info("syncer::run : Sync of %s cancelled", s.path)
+			break schedule
+		case sem <- struct{}{}:
+		}
+
+		actions.Add(1)
+		go func(name string) {
+			defer actions.Done()
+			defer func() { <-sem }()
+
+			_, err := s.Process(&WorkItem{CompName: s.GetName(), Path: name})
+			if err != nil {
+				log.Err("syncer::run : Failed to sync %s [%s]", name, err.Error())
+			}
+		}(name)
+	}
+	actions.Wait()
+
+	// operations which did not complete keep their old state, so they are retried next time
+	err = s.saveState()
+	if err != nil {
+		log.Err("syncer::run : Failed to save state to %s [%s]", s.stateFile, err.Error())
+	}
+
+	log.Info("syncer::run : Sync of %s completed", s.path)
+}
+
+func (s *syncer) listLocal() (map[string]os.FileInfo, map[string]bool, error) {
+	files := make(map[string]os.FileInfo)
+	dirs := make(map[string]bool)
+
+	err := filepath.WalkDir(s.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(s.path, path)
+		if err != nil || name == "." {
+			return err
+		}
+
+		if d.IsDir() {
+			dirs[name] = true
+		} else if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files[name] = info
+		} else {
+			log.Warn("syncer::listLocal : Skipping %s as it is not a regular file", name)
+		}
+		return nil
+	})
+
+	return files, dirs, err
+}
+
+func (s *syncer) listRemote() (map[string]*internal.ObjAttr, map[string]bool, error) {
+	files := make(map[string]*internal.ObjAttr)
+	dirs := make(map[string]bool)
+
+	queue := []string{""}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		marker := ""
+		for {
+			entries, newMarker, err := s.GetRemote().StreamDir(internal.StreamDirOptions{
+				Name:  dir,
+				Token: marker,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, entry := range entries {
+				if entry.IsDir() {
+					dirs[entry.Path] = true
+					queue = append(queue, entry.Path)
+				} else if !entry.IsSymlink() {
+					files[entry.Path] = entry
+				}
+			}
+
+			if newMarker == "" {
+				break
+			}
+			marker = newMarker
+		}
+	}
+
+	return files, dirs, nil
+}
+
+// syncDirs : create directories missing on either side, directories are never deleted
+func (s *syncer) syncDirs(localDirs map[string]bool, remoteDirs map[string]bool) {
+	for _, name := range sortedKeys(localDirs) {
+		if !remoteDirs[name] {
+			err := s.GetRemote().CreateDir(internal.CreateDirOptions{Name: name, Mode: s.defaultPermission})
+			if err != nil && err != syscall.EEXIST && !os.IsExist(err) {
+				log.Err("syncer::syncDirs : Failed to create directory %s in container [%s]", name, err.Error())
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(remoteDirs) {
+		if !localDirs[name] {
+			err := os.MkdirAll(filepath.Join(s.path, name), s.defaultPermission)
+			if err != nil {
+				log.Err("syncer::syncDirs : Failed to create local directory %s [%s]", name, err.Error())
+			}
+		}
+	}
+}
+
+// plan : decide the operation of each file and return the names of those which need one
+func (s *syncer) plan(localFiles map[string]os.FileInfo, remoteFiles map[string]*internal.ObjAttr) []string {
+	all := make(map[string]bool)
+	for name := range localFiles {
+		all[name] = true
+	}
+	for name := range remoteFiles {
+		all[name] = true
+	}
+
+	// files gone from both sides since last sync
+	for name := range s.state {
+		if !all[name] {
+			delete(s.state, name)
+		}
+	}
+
+	names := make([]string, 0)
+	for _, name := range sortedKeys(all) {
+		local, remote := localFiles[name], remoteFiles[name]
+
+		action := s.decide(name, local, remote, s.state[name])
+		log.Debug("syncer::plan : %s action %d", name, action)
+		if action != syncSkip {
+			s.plans.Store(name, &syncPlan{action: action, local: local, remote: remote})
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func (s *syncer) decide(name string, local os.FileInfo, remote *internal.ObjAttr, entry *syncEntry) syncAction {
+	if entry == nil {
+		if remote == nil {
+			return syncUpload
+		} else if local == nil {
+			return syncDownload
+		}
+	} else {
+		localChanged := local == nil || local.Size() != entry.LocalSize || !local.ModTime().Equal(entry.LocalMtime)
+		remoteChanged := remote == nil || remote.Size != entry.RemoteSize
+		if remote != nil && !remoteChanged {
+			if remote.ETag != "" && entry.ETag != "" {
+				remoteChanged = remote.ETag != entry.ETag
+			} else {
+				remoteChanged = remote.Mtime.Unix() != entry.RemoteMtime.Unix()
+			}
+		}
+
+		switch {
+		case !localChanged && !remoteChanged:
+			return syncSkip
+
+		case localChanged && !remoteChanged:
+			if local != nil {
+				return syncUpload
+			} else if s.deletes {
+				return syncDeleteRemote
+			}
+			return syncDownload
+
+		case remoteChanged && !localChanged:
+			if remote != nil {
+				return syncDownload
+			} else if s.deletes {
+				return syncDeleteLocal
+			}
+			return syncUpload
+
+		case local == nil && remote == nil:
+			delete(s.state, name)
+			return syncSkip
+		}
+	}
+
+	// present on both sides without history or changed on both sides since last sync
+	if local != nil && remote != nil && s.identical(name, local, remote) {
+		s.record(name, local, remote)
+		return syncSkip
+	}
+
+	return s.resolve(local, remote)
+}
+
+// identical : check if the local file and the blob have the same contents, using md5 when the blob has one
+func (s *syncer) identical(name string, local os.FileInfo, remote *internal.ObjAttr) bool {
+	if local.Size() != remote.Size {
+		return false
+	}
+
+	if len(remote.MD5) == 0 {
+		return local.ModTime().Unix() == remote.Mtime.Unix()
+	}
+
+	fh, err := os.Open(filepath.Join(s.path, name))
+	if err != nil {
+		log.Err("syncer::identical : Failed to open %s [%s]", name, err.Error())
+		return false
+	}
+	defer fh.Close()
+
+	localMD5, err := common.GetMD5(fh)
+	if err != nil {
+		log.Err("syncer::identical : Failed to generate MD5Sum for %s [%s]", name, err.Error())
+		return false
+	}
+
+	return reflect.DeepEqual(localMD5, remote.MD5)
+}
+
+// resolve : apply the conflict policy on a file changed on both sides
+func (s *syncer) resolve(local os.FileInfo, remote *internal.ObjAttr) syncAction {
+	var remoteWins bool
+	switch s.policy {
+	case ESyncPolicy.REMOTE_WINS():
+		remoteWins = true
+	case ESyncPolicy.LOCAL_WINS():
+		remoteWins = false
+	default:
+		// a file which still exists is considered newer than a deleted one
+		if local == nil || remote == nil {
+			remoteWins = local == nil
+		} else {
+			remoteWins = remote.Mtime.After(local.ModTime())
+		}
+	}
+
+	if remoteWins {
+		if remote != nil {
+			return syncDownload
+		} else if s.deletes {
+			return syncDeleteLocal
+		}
+		return syncUpload
+	}
+
+	if local != nil {
+		return syncUpload
+	} else if s.deletes {
+		return syncDeleteRemote
+	}
+	return syncDownload
+}
+
+// Process : apply the planned operation on a file
+func (s *syncer) Process(item *WorkItem) (int, error) {
+	value, found := s.plans.LoadAndDelete(item.Path)
+	if !found {
+		return 0, fmt.Errorf("no sync operation for %s", item.Path)
+	}
+
+	plan := value.(*syncPlan)
+	name := item.Path
+	localPath := filepath.Join(s.path, name)
+
+	var err error
+	switch plan.action {
+	case syncDownload:
+		log.Debug("syncer::Process : Downloading %s", name)
+		err = os.MkdirAll(filepath.Dir(localPath), s.defaultPermission)
+		if err != nil {
+			break
+		}
+
+		// download splitter serves a file of the same size from local path, so the stale copy is removed first
+		err = os.Remove(localPath)
+		if err != nil && !os.IsNotExist(err) {
+			break
+		}
+
+		mode := s.defaultPermission
+		if !plan.remote.IsModeDefault() {
+			mode = plan.remote.Mode
+		}
+
+		_, err = s.downloader.Process(&WorkItem{
+			CompName: s.downloader.GetName(),
+			Path:     name,
+			DataLen:  uint64(plan.remote.Size),
+			Mode:     mode,
+			Atime:    plan.remote.Atime,
+			Mtime:    plan.remote.Mtime,
+			MD5:      plan.remote.MD5,
+			ETag:     plan.remote.ETag,
+		})
+		if err == nil {
+			var info os.FileInfo
+			info, err = os.Stat(localPath)
+			if err == nil {
+				s.record(name, info, plan.remote)
+			}
+		}
+
+	case syncUpload:
+		log.Debug("syncer::Process : Uploading %s", name)
+		_, err = s.uploader.Process(&WorkItem{
+			CompName: s.uploader.GetName(),
+			Path:     name,
+			DataLen:  uint64(plan.local.Size()),
+			Mode:     plan.local.Mode().Perm(),
+			Mtime:    plan.local.ModTime(),
+		})
+		if err == nil {
+			var attr *internal.ObjAttr
+			attr, err = s.GetRemote().GetAttr(internal.GetAttrOptions{Name: name})
+			if err == nil {
+				s.record(name, plan.local, attr)
+			} else {
+				s.forget(name)
+			}
+		}
+
+	case syncDeleteLocal:
+		log.Debug("syncer::Process : Deleting local file %s", name)
+		err = os.Remove(localPath)
+		if err == nil || os.IsNotExist(err) {
+			err = nil
+			s.forget(name)
+		}
+		s.sendStats(name, true, err)
+
+	case syncDeleteRemote:
+		log.Debug("syncer::Process : Deleting %s from container", name)
+		err = s.GetRemote().DeleteFile(internal.DeleteFileOptions{Name: name})
+		if err == nil || err == syscall.ENOENT || os.IsNotExist(err) {
+			err = nil
+			s.forget(name)
+		}
+		s.sendStats(name, false, err)
+	}
+
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+func (s *syncer) sendStats(name string, isDownload bool, err error) {
+	s.GetStatsManager().AddStats(&StatsItem{
+		Component: SPLITTER,
+		Name:      name,
+		Success:   err == nil,
+		Download:  isDownload,
+		Err:       err,
+	})
+}
+
+func (s *syncer) record(name string, local os.FileInfo, remote *internal.ObjAttr) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.state[name] = &syncEntry{
+		LocalSize:   local.Size(),
+		LocalMtime:  local.ModTime(),
+		RemoteSize:  remote.Size,
+		RemoteMtime: remote.Mtime,
+		ETag:        remote.ETag,
+	}
+}
+
+func (s *syncer) forget(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.state, name)
+}
+
+func (s *syncer) loadState() error {
+	data, err := os.ReadFile(s.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	state := syncState{}
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return err
+	}
+
+	if state.Files != nil {
+		s.state = state.Files
+	}
+	return nil
+}
+
+// saveState : write through a temp file so that a crash never leaves behind a partial state
+func (s *syncer) saveState() error {
+	s.lock.Lock()
+	data, err := json.Marshal(&syncState{Version: 1, Files: s.state})
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.stateFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmpFile := s.stateFile + ".tmp"
+	err = os.WriteFile(tmpFile, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, s.stateFile)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,293 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type syncerTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	localPath   string
+	remotePath  string
+	stateFile   string
+	blockPool   *BlockPool
+	statsMgr    *StatsManager
+	fileLocks   *common.LockMap
+	syncPolicy  SyncPolicy
+	syncDeletes bool
+}
+
+func (suite *syncerTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	suite.assert.Nil(err)
+
+	root := filepath.Join("/tmp/", "xsyncer_"+randomString(8))
+	suite.localPath = filepath.Join(root, "local")
+	suite.remotePath = filepath.Join(root, "remote")
+	suite.stateFile = filepath.Join(root, "state.json")
+
+	suite.assert.Nil(os.MkdirAll(suite.localPath, 0777))
+	suite.assert.Nil(os.MkdirAll(suite.remotePath, 0777))
+
+	suite.blockPool = NewBlockPool(10, 20)
+	suite.fileLocks = common.NewLockMap()
+	suite.statsMgr, err = NewStatsManager(10, false)
+	suite.assert.Nil(err)
+	suite.statsMgr.Start()
+
+	suite.syncPolicy = ESyncPolicy.NEWEST_WINS()
+	suite.syncDeletes = false
+}
+
+func (suite *syncerTestSuite) TearDownTest() {
+	suite.statsMgr.Stop()
+	suite.blockPool.Terminate()
+
+	err := os.RemoveAll(filepath.Dir(suite.localPath))
+	suite.assert.Nil(err)
+}
+
+func (suite *syncerTestSuite) writeFile(path string, data string, mtime time.Time) {
+	suite.assert.Nil(os.MkdirAll(filepath.Dir(path), 0777))
+	suite.assert.Nil(os.WriteFile(path, []byte(data), 0644))
+	suite.assert.Nil(os.Chtimes(path, mtime, mtime))
+}
+
+func (suite *syncerTestSuite) assertFile(path string, data string) {
+	content, err := os.ReadFile(path)
+	suite.assert.Nil(err)
+	suite.assert.Equal(data, string(content))
+}
+
+// runSync : run one complete sync between the local and remote path of the suite
+func (suite *syncerTestSuite) runSync() {
+	remote := newTestLoopback(suite.remotePath)
+
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{suite.blockPool, suite.localPath, 4, remote, suite.statsMgr, suite.fileLocks, false, nil, nil, nil, nil})
+	suite.assert.Nil(err)
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{suite.blockPool, suite.localPath, 4, remote, suite.statsMgr, suite.fileLocks, false})
+	suite.assert.Nil(err)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 8,
+		remote:      remote,
+		statsMgr:    suite.statsMgr,
+	})
+	suite.assert.Nil(err)
+
+	sy, err := newSyncer(&syncerOptions{
+		path:              suite.localPath,
+		stateFile:         suite.stateFile,
+		policy:            suite.syncPolicy,
+		deletes:           suite.syncDeletes,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            remote,
+		statsMgr:          suite.statsMgr,
+		downloader:        ds,
+		uploader:          us,
+	})
+	suite.assert.Nil(err)
+
+	ds.SetNext(rdm)
+	us.SetNext(rdm)
+	sy.SetNext(rdm)
+
+	rdm.Start()
+	sy.Start()
+
+	select {
+	case <-sy.done:
+	case <-time.After(10 * time.Second):
+		suite.assert.Fail("sync did not complete")
+	}
+
+	sy.Stop()
+}
+
+func (suite *syncerTestSuite) TestNewSyncer() {
+	sy, err := newSyncer(nil)
+	suite.assert.NotNil(err)
+	suite.assert.Nil(sy)
+
+	remote := newTestLoopback(suite.remotePath)
+	opts := &syncerOptions{
+		path:        suite.localPath,
+		stateFile:   suite.stateFile,
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    suite.statsMgr,
+		downloader:  &XBase{},
+		uploader:    &XBase{},
+	}
+
+	// policy is not set
+	sy, err = newSyncer(opts)
+	suite.assert.NotNil(err)
+	suite.assert.Nil(sy)
+
+	opts.policy = ESyncPolicy.LOCAL_WINS()
+	sy, err = newSyncer(opts)
+	suite.assert.Nil(err)
+	suite.assert.NotNil(sy)
+	suite.assert.Equal(SYNCER, sy.GetName())
+}
+
+func (suite *syncerTestSuite) TestSyncStateFile() {
+	first := syncStateFile("/tmp/xload", "container1")
+	suite.assert.Equal(first, syncStateFile("/tmp/xload", "container1"))
+	suite.assert.NotEqual(first, syncStateFile("/tmp/xload", "container2"))
+	suite.assert.NotEqual(first, syncStateFile("/tmp/xload2", "container1"))
+	suite.assert.Equal(common.ExpandPath(common.DefaultWorkDir), filepath.Dir(first))
+}
+
+func (suite *syncerTestSuite) TestFirstSync() {
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	suite.writeFile(filepath.Join(suite.localPath, "local_file"), "local data", mtime)
+	suite.writeFile(filepath.Join(suite.localPath, "local_dir", "file"), "local dir data", mtime)
+	suite.assert.Nil(os.MkdirAll(filepath.Join(suite.localPath, "local_empty"), 0777))
+
+	suite.writeFile(filepath.Join(suite.remotePath, "remote_file"), "remote data", mtime)
+	suite.writeFile(filepath.Join(suite.remotePath, "remote_dir", "file"), "remote dir data", mtime)
+	suite.assert.Nil(os.MkdirAll(filepath.Join(suite.remotePath, "remote_empty"), 0777))
+
+	// same file on both sides is not transferred
+	suite.writeFile(filepath.Join(suite.localPath, "same"), "same data", mtime)
+	suite.writeFile(filepath.Join(suite.remotePath, "same"), "same data", mtime)
+
+	suite.runSync()
+
+	suite.assertFile(filepath.Join(suite.remotePath, "local_file"), "local data")
+	suite.assertFile(filepath.Join(suite.remotePath, "local_dir", "file"), "local dir data")
+	suite.assert.DirExists(filepath.Join(suite.remotePath, "local_empty"))
+
+	suite.assertFile(filepath.Join(suite.localPath, "remote_file"), "remote data")
+	suite.assertFile(filepath.Join(suite.localPath, "remote_dir", "file"), "remote dir data")
+	suite.assert.DirExists(filepath.Join(suite.localPath, "remote_empty"))
+
+	suite.assertFile(filepath.Join(suite.localPath, "same"), "same data")
+	suite.assertFile(filepath.Join(suite.remotePath, "same"), "same data")
+
+	suite.assert.FileExists(suite.stateFile)
+	validateMD5(suite.localPath, suite.remotePath, suite.assert)
+}
+
+func (suite *syncerTestSuite) TestSyncChanges() {
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	suite.writeFile(filepath.Join(suite.localPath, "file_1"), "data 1", mtime)
+	suite.writeFile(filepath.Join(suite.localPath, "file_2"), "data 2", mtime)
+	suite.runSync()
+
+	// one file changes locally and the other in container
+	suite.writeFile(filepath.Join(suite.localPath, "file_1"), "new local data 1", mtime.Add(time.Minute))
+	suite.writeFile(filepath.Join(suite.remotePath, "file_2"), "new remote data 2", mtime.Add(time.Minute))
+	suite.runSync()
+
+	suite.assertFile(filepath.Join(suite.remotePath, "file_1"), "new local data 1")
+	suite.assertFile(filepath.Join(suite.localPath, "file_1"), "new local data 1")
+	suite.assertFile(filepath.Join(suite.localPath, "file_2"), "new remote data 2")
+	suite.assertFile(filepath.Join(suite.remotePath, "file_2"), "new remote data 2")
+}
+
+func (suite *syncerTestSuite) TestConflictPolicy() {
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	policies := []struct {
+		policy SyncPolicy
+		data   string
+	}{
+		{ESyncPolicy.NEWEST_WINS(), "newer remote data"},
+		{ESyncPolicy.REMOTE_WINS(), "newer remote data"},
+		{ESyncPolicy.LOCAL_WINS(), "local data"},
+	}
+
+	for _, p := range policies {
+		suite.syncPolicy = p.policy
+		suite.assert.Nil(os.RemoveAll(suite.stateFile))
+		suite.assert.Nil(os.RemoveAll(filepath.Join(suite.remotePath, "file")))
+
+		suite.writeFile(filepath.Join(suite.localPath, "file"), "old data", mtime)
+		suite.runSync()
+
+		// file changes on both sides, container has the later change
+		suite.writeFile(filepath.Join(suite.localPath, "file"), "local data", mtime.Add(time.Minute))
+		suite.writeFile(filepath.Join(suite.remotePath, "file"), "newer remote data", mtime.Add(2*time.Minute))
+		suite.runSync()
+
+		suite.assertFile(filepath.Join(suite.localPath, "file"), p.data)
+		suite.assertFile(filepath.Join(suite.remotePath, "file"), p.data)
+	}
+}
+
+func (suite *syncerTestSuite) TestSyncDelete() {
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	suite.writeFile(filepath.Join(suite.localPath, "local_deleted"), "data 1", mtime)
+	suite.writeFile(filepath.Join(suite.localPath, "remote_deleted"), "data 2", mtime)
+	suite.runSync()
+
+	// deletions are not propagated by default, so the files are restored
+	suite.assert.Nil(os.Remove(filepath.Join(suite.localPath, "local_deleted")))
+	suite.assert.Nil(os.Remove(filepath.Join(suite.remotePath, "remote_deleted")))
+	suite.runSync()
+
+	suite.assertFile(filepath.Join(suite.localPath, "local_deleted"), "data 1")
+	suite.assertFile(filepath.Join(suite.remotePath, "remote_deleted"), "data 2")
+
+	suite.syncDeletes = true
+	suite.assert.Nil(os.Remove(filepath.Join(suite.localPath, "local_deleted")))
+	suite.assert.Nil(os.Remove(filepath.Join(suite.remotePath, "remote_deleted")))
+	suite.runSync()
+
+	suite.assert.NoFileExists(filepath.Join(suite.remotePath, "local_deleted"))
+	suite.assert.NoFileExists(filepath.Join(suite.localPath, "remote_deleted"))
+}
+
+func TestSyncerSuite(t *testing.T) {
+	suite.Run(t, new(syncerTestSuite))
+}
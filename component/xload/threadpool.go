@@ -34,6 +34,7 @@
 package xload
 
 import (
+	"container/heap"
 	"context"
 	"sync"
 
@@ -52,6 +53,14 @@ type ThreadPool struct {
 	priorityItems chan *WorkItem
 	workItems     chan *WorkItem
 
+	// Queue holding low priority requests when they are to be processed in a given order
+	queue *workQueue
+
+	// Workers do not pick low priority requests while paused, pauseChanged is closed when the state flips
+	pauseLock    sync.Mutex
+	paused       bool
+	pauseChanged chan struct{}
+
 	// context with cancellation method to close all the workers
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -72,16 +81,31 @@ func NewThreadPool(count uint32, callback func(*WorkItem) (int, error)) *ThreadP
 		callback:      callback,
 		priorityItems: make(chan *WorkItem, count*2),
 		workItems:     make(chan *WorkItem, count*4),
+		pauseChanged:  make(chan struct{}),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
 }
 
+// SetOrder makes the low priority requests to be processed in the given order instead of the order of scheduling.
+// It has to be called before the thread pool is started.
+func (threadPool *ThreadPool) SetOrder(less func(*WorkItem, *WorkItem) bool) {
+	threadPool.queue = newWorkQueue(less)
+
+	// workers receive the request only when they are free, so that a later but more important request can go ahead of it
+	threadPool.workItems = make(chan *WorkItem)
+}
+
 // Start all the workers and wait till they start receiving requests
 func (threadPool *ThreadPool) Start() {
 	// 10% threads will listne only on high priority channel
 	highPriority := (threadPool.worker * 10) / 100
 
+	if threadPool.queue != nil {
+		threadPool.waitGroup.Add(1)
+		go threadPool.feed()
+	}
+
 	for i := uint32(0); i < threadPool.worker; i++ {
 		threadPool.waitGroup.Add(1)
 		go threadPool.Do(i < highPriority)
@@ -96,17 +120,76 @@ func (threadPool *ThreadPool) Stop() {
 	close(threadPool.workItems)
 }
 
+// Pause stops the workers from picking low priority requests. Requests already picked run to completion
+// and high priority requests are still processed.
+func (threadPool *ThreadPool) Pause() {
+	threadPool.setPaused(true)
+}
+
+// Resume lets the workers pick low priority requests again
+func (threadPool *ThreadPool) Resume() {
+	threadPool.setPaused(false)
+}
+
+// Paused tells if the low priority requests are on hold
+func (threadPool *ThreadPool) Paused() bool {
+	paused, _ := threadPool.pauseState()
+	return paused
+}
+
+func (threadPool *ThreadPool) setPaused(paused bool) {
+	threadPool.pauseLock.Lock()
+	defer threadPool.pauseLock.Unlock()
+
+	if threadPool.paused == paused {
+		return
+	}
+
+	threadPool.paused = paused
+
+	// wake up the workers waiting on the old state
+	close(threadPool.pauseChanged)
+	threadPool.pauseChanged = make(chan struct{})
+}
+
+func (threadPool *ThreadPool) pauseState() (bool, chan struct{}) {
+	threadPool.pauseLock.Lock()
+	defer threadPool.pauseLock.Unlock()
+
+	return threadPool.paused, threadPool.pauseChanged
+}
+
 // Schedule the download of a block
 func (threadPool *ThreadPool) Schedule(item *WorkItem) {
 	// item.Priority specifies the priority of this task.
 	// true means high priority and false means low priority
 	if item.Priority {
 		threadPool.priorityItems <- item
+	} else if threadPool.queue != nil {
+		threadPool.queue.push(item)
 	} else {
 		threadPool.workItems <- item
 	}
 }
 
+// feed the queued requests to the workers in order
+func (threadPool *ThreadPool) feed() {
+	defer threadPool.waitGroup.Done()
+
+	for {
+		item := threadPool.queue.pop(threadPool.ctx)
+		if item == nil {
+			return
+		}
+
+		select {
+		case <-threadPool.ctx.Done(): // listen to cancellation signal
+			return
+		case threadPool.workItems <- item:
+		}
+	}
+}
+
 // Do is the core task to be executed by each worker thread
 func (threadPool *ThreadPool) Do(priority bool) {
 	defer threadPool.waitGroup.Done()
@@ -141,13 +224,21 @@ func (threadPool *ThreadPool) Do(priority bool) {
 				case item := <-threadPool.priorityItems:
 					threadPool.process(item)
 				default:
+					// a nil channel is never ready, so low priority requests are left alone while paused
+					paused, pauseChanged := threadPool.pauseState()
+					workItems := threadPool.workItems
+					if paused {
+						workItems = nil
+					}
+
 					select {
 					case <-threadPool.ctx.Done(): // listen to cancellation signal
 						return
 					case item := <-threadPool.priorityItems:
 						threadPool.process(item)
-					case item := <-threadPool.workItems:
+					case item := <-workItems:
 						threadPool.process(item)
+					case <-pauseChanged:
 					}
 				}
 			}
@@ -169,3 +260,92 @@ func (threadPool *ThreadPool) process(item *WorkItem) {
 		item.ResponseChannel <- item
 	}
 }
+
+// --------------------------------------------------------------------------------------------------------
+
+type queuedItem struct {
+	item *WorkItem
+	seq  uint64 // order of scheduling, used when the items are equal as per the order
+}
+
+type workHeap struct {
+	entries []queuedItem
+	less    func(*WorkItem, *WorkItem) bool
+}
+
+func (h *workHeap) Len() int { return len(h.entries) }
+
+func (h *workHeap) Less(i, j int) bool {
+	a, b := h.entries[i], h.entries[j]
+	if h.less(a.item, b.item) {
+		return true
+	} else if h.less(b.item, a.item) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (h *workHeap) Swap(i, j int) { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+
+func (h *workHeap) Push(x any) { h.entries = append(h.entries, x.(queuedItem)) }
+
+func (h *workHeap) Pop() any {
+	n := len(h.entries)
+	entry := h.entries[n-1]
+	h.entries[n-1] = queuedItem{}
+	h.entries = h.entries[:n-1]
+	return entry
+}
+
+// workQueue : unbounded queue of requests, handed out in the given order
+type workQueue struct {
+	lock   sync.Mutex
+	items  *workHeap
+	seq    uint64
+	notify chan struct{}
+}
+
+func newWorkQueue(less func(*WorkItem, *WorkItem) bool) *workQueue {
+	return &workQueue{
+		items:  &workHeap{less: less},
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (q *workQueue) push(item *WorkItem) {
+	q.lock.Lock()
+	q.seq++
+	heap.Push(q.items, queuedItem{item: item, seq: q.seq})
+	q.lock.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop the first item as per the order, waiting till one is available or the context is cancelled
+func (q *workQueue) pop(ctx context.Context) *WorkItem {
+	for {
+		q.lock.Lock()
+		if q.items.Len() > 0 {
+			entry := heap.Pop(q.items).(queuedItem)
+			q.lock.Unlock()
+			return entry.item
+		}
+		q.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-q.notify:
+		}
+	}
+}
+
+func (q *workQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.items.Len()
+}
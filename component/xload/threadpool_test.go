@@ -34,6 +34,7 @@
 package xload
 
 import (
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -134,6 +135,81 @@ func (suite *threadPoolTestSuite) TestPrioritySchedule() {
 	tp.Stop()
 }
 
+func (suite *threadPoolTestSuite) TestOrderedSchedule() {
+	suite.assert = assert.New(suite.T())
+
+	lock := sync.Mutex{}
+	processed := make([]uint64, 0)
+	r := func(i *WorkItem) (int, error) {
+		lock.Lock()
+		processed = append(processed, i.DataLen)
+		lock.Unlock()
+		return 0, nil
+	}
+
+	tp := NewThreadPool(1, r)
+	suite.assert.NotNil(tp)
+	tp.SetOrder(func(a *WorkItem, b *WorkItem) bool { return a.DataLen < b.DataLen })
+
+	// items scheduled before the start are all queued, so they are processed in order
+	for _, size := range []uint64{5, 3, 9, 1, 3, 7} {
+		tp.Schedule(&WorkItem{DataLen: size})
+	}
+	suite.assert.Equal(6, tp.queue.len())
+
+	tp.Start()
+	suite.assert.Eventually(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(processed) == 6
+	}, 5*time.Second, 10*time.Millisecond)
+	tp.Stop()
+
+	suite.assert.Equal([]uint64{1, 3, 3, 5, 7, 9}, processed)
+	suite.assert.Equal(0, tp.queue.len())
+}
+
+func (suite *threadPoolTestSuite) TestPauseResume() {
+	suite.assert = assert.New(suite.T())
+
+	lock := sync.Mutex{}
+	processed := make([]bool, 0)
+	r := func(i *WorkItem) (int, error) {
+		lock.Lock()
+		processed = append(processed, i.Priority)
+		lock.Unlock()
+		return 0, nil
+	}
+	count := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(processed)
+	}
+
+	tp := NewThreadPool(2, r)
+	suite.assert.NotNil(tp)
+	tp.Start()
+	defer tp.Stop()
+
+	tp.Pause()
+	suite.assert.True(tp.Paused())
+
+	// low priority requests wait while paused, high priority requests go through
+	tp.Schedule(&WorkItem{})
+	tp.Schedule(&WorkItem{})
+	tp.Schedule(&WorkItem{Priority: true})
+	suite.assert.Eventually(func() bool { return count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	suite.assert.Equal(1, count())
+	lock.Lock()
+	suite.assert.True(processed[0])
+	lock.Unlock()
+
+	tp.Resume()
+	suite.assert.False(tp.Paused())
+	suite.assert.Eventually(func() bool { return count() == 3 }, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestThreadPoolSuite(t *testing.T) {
 	suite.Run(t, new(threadPoolTestSuite))
 }
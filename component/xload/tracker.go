@@ -0,0 +1,145 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"strings"
+	"sync"
+)
+
+// trackedFile : state of a file in the local path of a read-write mount
+type trackedFile struct {
+	etag     string // etag of the blob the local copy was downloaded from or uploaded to
+	modified bool   // file was opened for write in this mount, so preload shall never overwrite it
+}
+
+// fileTracker : coordinates preload with the writes done on the mount
+type fileTracker struct {
+	lock  sync.RWMutex
+	files map[string]*trackedFile
+}
+
+func newFileTracker() *fileTracker {
+	return &fileTracker{
+		files: make(map[string]*trackedFile),
+	}
+}
+
+func (ft *fileTracker) get(name string) *trackedFile {
+	file, ok := ft.files[name]
+	if !ok {
+		file = &trackedFile{}
+		ft.files[name] = file
+	}
+	return file
+}
+
+// setETag : record the etag of the blob which the local copy matches
+func (ft *fileTracker) setETag(name string, etag string) {
+	ft.lock.Lock()
+	defer ft.lock.Unlock()
+
+	ft.get(name).etag = etag
+}
+
+// etag : etag of the blob which the local copy matches, empty if not known
+func (ft *fileTracker) etag(name string) string {
+	ft.lock.RLock()
+	defer ft.lock.RUnlock()
+
+	if file, ok := ft.files[name]; ok {
+		return file.etag
+	}
+	return ""
+}
+
+// markModified : record that the file is written in this mount, its local copy matches no blob till it is uploaded
+func (ft *fileTracker) markModified(name string) {
+	ft.lock.Lock()
+	defer ft.lock.Unlock()
+
+	file := ft.get(name)
+	file.modified = true
+	file.etag = ""
+}
+
+func (ft *fileTracker) isModified(name string) bool {
+	ft.lock.RLock()
+	defer ft.lock.RUnlock()
+
+	file, ok := ft.files[name]
+	return ok && file.modified
+}
+
+// remove : forget the file, or all files under it if it is a directory
+func (ft *fileTracker) remove(name string, isDir bool) {
+	ft.lock.Lock()
+	defer ft.lock.Unlock()
+
+	delete(ft.files, name)
+	if isDir {
+		prefix := name + "/"
+		for path := range ft.files {
+			if strings.HasPrefix(path, prefix) {
+				delete(ft.files, path)
+			}
+		}
+	}
+}
+
+// rename : move the state of the file, or of all files under it if it is a directory
+func (ft *fileTracker) rename(src string, dst string, isDir bool) {
+	ft.lock.Lock()
+	defer ft.lock.Unlock()
+
+	if file, ok := ft.files[src]; ok {
+		delete(ft.files, src)
+		ft.files[dst] = file
+	}
+
+	if isDir {
+		prefix := src + "/"
+		moved := make(map[string]*trackedFile)
+		for path, file := range ft.files {
+			if strings.HasPrefix(path, prefix) {
+				delete(ft.files, path)
+				moved[dst+"/"+strings.TrimPrefix(path, prefix)] = file
+			}
+		}
+
+		for path, file := range moved {
+			ft.files[path] = file
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package xload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type trackerTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *trackerTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+}
+
+func (suite *trackerTestSuite) TestETag() {
+	ft := newFileTracker()
+	suite.assert.Empty(ft.etag("file_0"))
+	suite.assert.False(ft.isModified("file_0"))
+
+	ft.setETag("file_0", "etag_1")
+	suite.assert.Equal("etag_1", ft.etag("file_0"))
+	suite.assert.False(ft.isModified("file_0"))
+
+	// modified file matches no blob till it is uploaded
+	ft.markModified("file_0")
+	suite.assert.True(ft.isModified("file_0"))
+	suite.assert.Empty(ft.etag("file_0"))
+
+	ft.setETag("file_0", "etag_2")
+	suite.assert.True(ft.isModified("file_0"))
+	suite.assert.Equal("etag_2", ft.etag("file_0"))
+}
+
+func (suite *trackerTestSuite) TestRemove() {
+	ft := newFileTracker()
+	ft.markModified("dir_0/file_0")
+	ft.markModified("dir_0/dir_1/file_1")
+	ft.markModified("dir_00/file_2")
+	ft.markModified("file_3")
+
+	ft.remove("file_3", false)
+	suite.assert.False(ft.isModified("file_3"))
+
+	ft.remove("dir_0", true)
+	suite.assert.False(ft.isModified("dir_0/file_0"))
+	suite.assert.False(ft.isModified("dir_0/dir_1/file_1"))
+	suite.assert.True(ft.isModified("dir_00/file_2"))
+}
+
+func (suite *trackerTestSuite) TestRename() {
+	ft := newFileTracker()
+	ft.markModified("dir_0/file_0")
+	ft.setETag("dir_0/dir_1/file_1", "etag_1")
+	ft.markModified("dir_00/file_2")
+
+	ft.rename("dir_0/file_0", "file_0", false)
+	suite.assert.False(ft.isModified("dir_0/file_0"))
+	suite.assert.True(ft.isModified("file_0"))
+
+	ft.rename("dir_0", "dir_2", true)
+	suite.assert.Empty(ft.etag("dir_0/dir_1/file_1"))
+	suite.assert.Equal("etag_1", ft.etag("dir_2/dir_1/file_1"))
+	suite.assert.True(ft.isModified("dir_00/file_2"))
+}
+
+func TestTrackerSuite(t *testing.T) {
+	suite.Run(t, new(trackerTestSuite))
+}
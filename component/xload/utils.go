@@ -35,12 +35,18 @@ package xload
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 
 	"time"
 
+	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/log"
 
 	"github.com/JeffreyRichter/enum/enum"
@@ -72,6 +78,7 @@ type WorkItem struct {
 	Priority        bool            // boolean flag to decide if this item needs to be processed on priority
 	Ctx             context.Context // context with cancellation method so that if download fails for one block, all other download operations will be cancelled
 	MD5             []byte          // content md5 of the blob which can be used to check the consistency of the download
+	ETag            string          // etag of the blob, used to check if a checkpointed download is of the same version
 }
 
 // xload mode enum
@@ -107,11 +114,52 @@ func (m *Mode) Parse(s string) error {
 	return err
 }
 
+// sync policy enum to resolve a path changed on both local path and container since last sync
+type SyncPolicy int
+
+var ESyncPolicy = SyncPolicy(0).INVALID_POLICY()
+
+func (SyncPolicy) INVALID_POLICY() SyncPolicy {
+	return SyncPolicy(0)
+}
+
+func (SyncPolicy) NEWEST_WINS() SyncPolicy {
+	return SyncPolicy(1)
+}
+
+func (SyncPolicy) REMOTE_WINS() SyncPolicy {
+	return SyncPolicy(2)
+}
+
+func (SyncPolicy) LOCAL_WINS() SyncPolicy {
+	return SyncPolicy(3)
+}
+
+func (p SyncPolicy) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
+// Parse accepts names like newest-wins as well as NEWEST_WINS
+func (p *SyncPolicy) Parse(s string) error {
+	enumVal, err := enum.ParseInt(reflect.TypeOf(p), strings.ReplaceAll(s, "-", "_"), true, false)
+	if enumVal != nil {
+		*p = enumVal.(SyncPolicy)
+	}
+	return err
+}
+
 func RoundFloat(val float64, precision int) float64 {
 	ratio := math.Pow10(precision)
 	return math.Round(val*ratio) / ratio
 }
 
+// stateFilePath : file in the default work directory holding the state of xload for the given local path and container
+func stateFilePath(prefix string, ext string, path string, container string) string {
+	hash := sha256.Sum256([]byte(path + "\n" + container))
+	name := fmt.Sprintf("%s_%s.%s", prefix, hex.EncodeToString(hash[:8]), ext)
+	return common.ExpandPath(filepath.Join(common.DefaultWorkDir, name))
+}
+
 // returns if the given path is present, if its a directory and its size
 func isFilePresent(localPath string) (bool, bool, int64) {
 	fileInfo, err := os.Stat(localPath)
@@ -80,6 +80,31 @@ func (suite *utilsTestSuite) TestModeParse() {
 	}
 }
 
+func (suite *utilsTestSuite) TestSyncPolicyParse() {
+	policies := []struct {
+		val    string
+		policy SyncPolicy
+	}{
+		{val: "newest-wins", policy: ESyncPolicy.NEWEST_WINS()},
+		{val: "remote-wins", policy: ESyncPolicy.REMOTE_WINS()},
+		{val: "LOCAL-WINS", policy: ESyncPolicy.LOCAL_WINS()},
+		{val: "local_wins", policy: ESyncPolicy.LOCAL_WINS()},
+		{val: "oldest-wins", policy: ESyncPolicy.INVALID_POLICY()},
+	}
+
+	for i, p := range policies {
+		var policy SyncPolicy
+		err := policy.Parse(p.val)
+		if i < len(policies)-1 {
+			suite.assert.Nil(err)
+		} else {
+			suite.assert.NotNil(err)
+		}
+
+		suite.assert.Equal(policy, p.policy)
+	}
+}
+
 func (suite *utilsTestSuite) TestModeString() {
 	modes := []struct {
 		mode Mode
@@ -41,6 +41,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/Azure/azure-storage-fuse/v2/common"
 	"github.com/Azure/azure-storage-fuse/v2/common/config"
@@ -52,26 +54,54 @@ import (
 // Common structure for Component
 type Xload struct {
 	internal.BaseComponent
-	blockSize         uint64          // Size of each block to be cached
-	mode              Mode            // Mode of the Xload component
-	exportProgress    bool            // Export the progress of xload operation to json file
-	validateMD5       bool            // validate md5sum on download, if md5sum is set on blob
-	workerCount       uint32          // Number of workers running
-	blockPool         *BlockPool      // Pool of blocks
-	path              string          // Path on local disk where Xload will operate
-	defaultPermission os.FileMode     // Default permissions of files and directories in the xload path
-	comps             []XComponent    // list of components in xload
-	statsMgr          *StatsManager   // stats manager
-	fileLocks         *common.LockMap // lock to take on a file if one thread is processing it
+	blockSize         uint64             // Size of each block to be cached
+	mode              Mode               // Mode of the Xload component
+	exportProgress    bool               // Export the progress of xload operation to json file
+	validateMD5       bool               // validate md5sum on download, if md5sum is set on blob
+	workerCount       uint32             // Number of workers running
+	blockPool         *BlockPool         // Pool of blocks
+	path              string             // Path on local disk where Xload will operate
+	defaultPermission os.FileMode        // Default permissions of files and directories in the xload path
+	comps             []XComponent       // list of components in xload
+	statsMgr          *StatsManager      // stats manager
+	fileLocks         *common.LockMap    // lock to take on a file if one thread is processing it
+	syncPolicy        SyncPolicy         // policy to resolve conflicts in sync mode
+	syncDelete        bool               // propagate deletions to the other side in sync mode
+	syncStateFile     string             // file where the state of last sync is persisted
+	checkpointFile    string             // journal of downloaded files and blocks, empty if checkpointing is disabled
+	checkpoint        *checkpoint        // checkpoint journal of preload
+	filter            *pathFilter        // include and exclude rules of preload
+	order             *priorityOrder     // order in which preload downloads the files
+	readWrite         bool               // mount allows writes along with preload
+	tracker           *fileTracker       // files written on a read-write mount and etags of the local copies
+	mountPath         string             // mount path, used to derive the path of the control socket
+	control           *controlServer     // control socket serving the xload commands of the cli
+	controlLock       sync.Mutex         // lock serializing the control commands
+	paused            bool               // transfer is paused by the cli
+	cancelled         bool               // transfer is cancelled by the cli, it can not be resumed
+	shardedList       uint32             // number of prefix shards listed in parallel by preload, 0 to list directory by directory
+	shardedNonASCII   bool               // sharded list also picks up names starting with a non-ASCII character
+	dedup             *common.DedupStore // store of downloaded content keyed by md5, nil if deduplication is disabled
 }
 
 // Structure defining your config parameters
 type XloadOptions struct {
-	BlockSize      float64 `config:"block-size-mb" yaml:"block-size-mb,omitempty"`
-	Mode           string  `config:"mode" yaml:"mode,omitempty"`
-	Path           string  `config:"path" yaml:"path,omitempty"`
-	ExportProgress bool    `config:"export-progress" yaml:"path,omitempty"`
-	ValidateMD5    bool    `config:"validate-md5" yaml:"validate-md5,omitempty"`
+	BlockSize       float64  `config:"block-size-mb" yaml:"block-size-mb,omitempty"`
+	Mode            string   `config:"mode" yaml:"mode,omitempty"`
+	Path            string   `config:"path" yaml:"path,omitempty"`
+	ExportProgress  bool     `config:"export-progress" yaml:"path,omitempty"`
+	ValidateMD5     bool     `config:"validate-md5" yaml:"validate-md5,omitempty"`
+	SyncPolicy      string   `config:"sync-policy" yaml:"sync-policy,omitempty"`
+	SyncDelete      bool     `config:"sync-delete" yaml:"sync-delete,omitempty"`
+	SyncStateFile   string   `config:"sync-state-file" yaml:"sync-state-file,omitempty"`
+	Checkpoint      bool     `config:"checkpoint" yaml:"checkpoint,omitempty"`
+	CheckpointFile  string   `config:"checkpoint-file" yaml:"checkpoint-file,omitempty"`
+	Include         []string `config:"include" yaml:"include,omitempty"`
+	Exclude         []string `config:"exclude" yaml:"exclude,omitempty"`
+	Priority        []string `config:"priority" yaml:"priority,omitempty"`
+	ShardedList     uint32   `config:"sharded-list-parallelism" yaml:"sharded-list-parallelism,omitempty"`
+	ShardedNonASCII bool     `config:"sharded-list-non-ascii" yaml:"sharded-list-non-ascii,omitempty"`
+	DedupPath       string   `config:"dedup-path" yaml:"dedup-path,omitempty"`
 	// TODO:: xload : add parallelism parameter
 }
 
@@ -103,7 +133,7 @@ func (xl *Xload) Priority() internal.ComponentPriority {
 func (xl *Xload) Configure(_ bool) error {
 	log.Trace("Xload::Configure : %s", xl.Name())
 
-	// xload component should be used only in readonly mode
+	// upload and sync modes need a read-only mount, preload can coexist with writes
 	var readonly bool
 	err := config.UnmarshalKey("read-only", &readonly)
 	if err != nil {
@@ -111,11 +141,6 @@ func (xl *Xload) Configure(_ bool) error {
 		return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
 	}
 
-	if !readonly {
-		log.Err("Xload::Configure : Xload component should be used only in read-only mode")
-		return fmt.Errorf("Xload component should be used in only in read-only mode")
-	}
-
 	conf := XloadOptions{}
 	err = config.UnmarshalKey(xl.Name(), &conf)
 	if err != nil {
@@ -135,6 +160,29 @@ func (xl *Xload) Configure(_ bool) error {
 
 	xl.blockSize = uint64(blockSize * float64(MB))
 
+	var mode Mode = EMode.PRELOAD() // using preload as the default mode
+	if len(conf.Mode) > 0 {
+		err = mode.Parse(conf.Mode)
+		if err != nil {
+			log.Err("Xload::Configure : Failed to parse mode %s [%s]", conf.Mode, err.Error())
+			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+		}
+
+		if mode == EMode.INVALID_MODE() {
+			log.Err("Xload::Configure : Invalid mode : %s", conf.Mode)
+			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+		}
+	}
+
+	xl.mode = mode
+
+	if !readonly && xl.mode != EMode.PRELOAD() {
+		log.Err("Xload::Configure : Xload component should be used only in read-only mode in %s mode", xl.mode.String())
+		return fmt.Errorf("Xload component should be used in only in read-only mode in %s mode", xl.mode.String())
+	}
+
+	xl.readWrite = !readonly
+
 	localPath := strings.TrimSpace(conf.Path)
 	if localPath == "" {
 		if config.IsSet("file_cache.path") {
@@ -163,40 +211,69 @@ func (xl *Xload) Configure(_ bool) error {
 			log.Err("Xload::Configure : config error [xload path is same as mount path]")
 			return fmt.Errorf("config error in %s error [xload path is same as mount path]", xl.Name())
 		}
-
-		_, err = os.Stat(xl.path)
-		if os.IsNotExist(err) {
-			log.Info("Xload::Configure : config error [xload path does not exist, attempting to create path]")
-			err := os.Mkdir(xl.path, os.FileMode(0755))
+		xl.mountPath = mntPath
+
+		if xl.mode == EMode.UPLOAD() {
+			// in upload mode the local path is the source of data, so it has to exist and is not expected to be empty
+			info, err := os.Stat(xl.path)
+			if err != nil || !info.IsDir() {
+				log.Err("Xload::Configure : config error [xload path %s is not a directory]", xl.path)
+				return fmt.Errorf("config error in %s [path %s is not a directory]", xl.Name(), xl.path)
+			}
+		} else if xl.mode == EMode.SYNC() {
+			// in sync mode the local path is one side of the reconciliation, so it may already hold data
+			err = os.MkdirAll(xl.path, os.FileMode(0755))
 			if err != nil {
 				log.Err("Xload::Configure : config error creating directory of xload path [%s]", err.Error())
 				return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
 			}
-		}
+		} else {
+			_, err = os.Stat(xl.path)
+			if os.IsNotExist(err) {
+				log.Info("Xload::Configure : config error [xload path does not exist, attempting to create path]")
+				err := os.Mkdir(xl.path, os.FileMode(0755))
+				if err != nil {
+					log.Err("Xload::Configure : config error creating directory of xload path [%s]", err.Error())
+					return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+				}
+			}
 
-		if !common.IsDirectoryEmpty(xl.path) {
-			log.Err("Xload::Configure : config error %s directory is not empty", xl.path)
-			return fmt.Errorf("config error in %s [temp directory not empty]", xl.Name())
+			// with checkpointing the local path holds the data of an earlier preload which is resumed
+			if !conf.Checkpoint && !common.IsDirectoryEmpty(xl.path) {
+				log.Err("Xload::Configure : config error %s directory is not empty", xl.path)
+				return fmt.Errorf("config error in %s [temp directory not empty]", xl.Name())
+			}
 		}
 	}
 
-	var mode Mode = EMode.PRELOAD() // using preload as the default mode
-	if len(conf.Mode) > 0 {
-		err = mode.Parse(conf.Mode)
-		if err != nil {
-			log.Err("Xload::Configure : Failed to parse mode %s [%s]", conf.Mode, err.Error())
-			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
-		}
+	xl.exportProgress = conf.ExportProgress
+	xl.validateMD5 = conf.ValidateMD5
 
-		if mode == EMode.INVALID_MODE() {
-			log.Err("Xload::Configure : Invalid mode : %s", conf.Mode)
-			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+	xl.syncPolicy = ESyncPolicy.NEWEST_WINS()
+	if len(conf.SyncPolicy) > 0 {
+		err = xl.syncPolicy.Parse(conf.SyncPolicy)
+		if err != nil || xl.syncPolicy == ESyncPolicy.INVALID_POLICY() {
+			log.Err("Xload::Configure : Invalid sync policy : %s", conf.SyncPolicy)
+			return fmt.Errorf("invalid sync-policy in xload : %s", conf.SyncPolicy)
 		}
 	}
 
-	xl.mode = mode
-	xl.exportProgress = conf.ExportProgress
-	xl.validateMD5 = conf.ValidateMD5
+	xl.syncDelete = conf.SyncDelete
+	xl.syncStateFile = common.ExpandPath(strings.TrimSpace(conf.SyncStateFile))
+	if xl.syncStateFile == "" {
+		var container string
+		_ = config.UnmarshalKey("azstorage.container", &container)
+		xl.syncStateFile = syncStateFile(xl.path, container)
+	}
+
+	if conf.Checkpoint && xl.mode == EMode.PRELOAD() {
+		xl.checkpointFile = common.ExpandPath(strings.TrimSpace(conf.CheckpointFile))
+		if xl.checkpointFile == "" {
+			var container string
+			_ = config.UnmarshalKey("azstorage.container", &container)
+			xl.checkpointFile = checkpointFilePath(xl.path, container)
+		}
+	}
 
 	allowOther := false
 	err = config.UnmarshalKey("allow-other", &allowOther)
@@ -213,6 +290,45 @@ func (xl *Xload) Configure(_ bool) error {
 	log.Crit("Xload::Configure : block size %v, mode %v, path %v, default permission %v, export progress %v, validate md5 %v", xl.blockSize,
 		xl.mode.String(), xl.path, xl.defaultPermission, xl.exportProgress, xl.validateMD5)
 
+	xl.filter, err = newPathFilter(conf.Include, conf.Exclude)
+	if err != nil {
+		log.Err("Xload::Configure : Invalid include or exclude rules [%s]", err.Error())
+		return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+	}
+
+	xl.order, err = newPriorityOrder(conf.Priority)
+	if err != nil {
+		log.Err("Xload::Configure : Invalid priority list [%s]", err.Error())
+		return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+	}
+
+	if xl.checkpointFile != "" {
+		log.Crit("Xload::Configure : checkpoint file %v", xl.checkpointFile)
+	}
+
+	xl.shardedList = conf.ShardedList
+	xl.shardedNonASCII = conf.ShardedNonASCII
+	if xl.shardedList > 0 {
+		log.Crit("Xload::Configure : sharded list parallelism %v, non-ascii names %v", xl.shardedList, xl.shardedNonASCII)
+	}
+
+	if dedupPath := common.ExpandPath(strings.TrimSpace(conf.DedupPath)); dedupPath != "" && xl.mode == EMode.PRELOAD() {
+		xl.dedup, err = common.NewDedupStore(dedupPath)
+		if err != nil {
+			log.Err("Xload::Configure : Failed to open dedup store at %s [%s]", dedupPath, err.Error())
+			return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+		}
+		log.Crit("Xload::Configure : dedup path %v", dedupPath)
+	}
+
+	if xl.filter != nil || xl.order != nil {
+		log.Crit("Xload::Configure : include %v, exclude %v, priority %v", conf.Include, conf.Exclude, conf.Priority)
+	}
+
+	if xl.mode == EMode.SYNC() {
+		log.Crit("Xload::Configure : sync policy %v, sync delete %v, sync state file %v", xl.syncPolicy.String(), xl.syncDelete, xl.syncStateFile)
+	}
+
 	return nil
 }
 
@@ -239,6 +355,18 @@ func (xl *Xload) Start(ctx context.Context) error {
 	// Xload : start code goes here
 	switch xl.mode {
 	case EMode.PRELOAD():
+		if xl.readWrite {
+			xl.tracker = newFileTracker()
+		}
+
+		if xl.checkpointFile != "" {
+			xl.checkpoint, err = newCheckpoint(xl.checkpointFile)
+			if err != nil {
+				log.Err("Xload::Start : Failed to open checkpoint journal [%s]", err.Error())
+				return err
+			}
+		}
+
 		// Start downloader here
 		err = xl.createDownloader()
 		if err != nil {
@@ -247,27 +375,69 @@ func (xl *Xload) Start(ctx context.Context) error {
 		}
 	case EMode.UPLOAD():
 		// Start uploader here
-		return fmt.Errorf("uploader is currently unsupported")
+		err = xl.createUploader()
+		if err != nil {
+			log.Err("Xload::Start : Failed to start uploader [%s]", err.Error())
+			return err
+		}
 	case EMode.SYNC():
-		//Start syncer here
-		return fmt.Errorf("sync is currently unsupported")
+		// Start syncer here
+		err = xl.createSyncer()
+		if err != nil {
+			log.Err("Xload::Start : Failed to start syncer [%s]", err.Error())
+			return err
+		}
 	default:
 		log.Err("Xload::Start : Invalid mode : %s", xl.mode.String())
 		return fmt.Errorf("invalid mode in xload : %s", xl.mode.String())
 	}
 
 	xl.statsMgr.Start()
-	return xl.startComponents()
+	err = xl.startComponents()
+	if err != nil {
+		return err
+	}
+
+	if xl.mountPath != "" {
+		// progress can still be exported to the json file, so the mount goes ahead without the control socket
+		xl.control, err = newControlServer(ControlSocketPath(xl.mountPath), xl.handleControl)
+		if err != nil {
+			log.Err("Xload::Start : Failed to create control socket [%s]", err.Error())
+		} else {
+			xl.control.start()
+		}
+	}
+
+	return nil
 }
 
 // Stop : Stop the component functionality and kill all threads started
 func (xl *Xload) Stop() error {
 	log.Trace("Xload::Stop : Stopping component %s", xl.Name())
 
+	if xl.control != nil {
+		xl.control.stop()
+	}
+
 	xl.comps[0].Stop()
 	xl.statsMgr.Stop()
 	xl.blockPool.Terminate()
 
+	// local path holds the data of the user in upload and sync mode
+	if xl.mode == EMode.UPLOAD() || xl.mode == EMode.SYNC() {
+		return nil
+	}
+
+	// downloaded data is kept for the next mount to resume from
+	if xl.checkpoint != nil {
+		err := xl.checkpoint.close()
+		if err != nil {
+			log.Err("Xload::Stop : Failed to close checkpoint journal [%s]", err.Error())
+			return err
+		}
+		return nil
+	}
+
 	// TODO:: xload : should we delete the files from local path
 	err := common.TempCacheCleanup(xl.path)
 	if err != nil {
@@ -275,6 +445,14 @@ func (xl *Xload) Stop() error {
 		return err
 	}
 
+	// content which was linked only from the local path is of no use anymore
+	freed, err := xl.dedup.Prune()
+	if err != nil {
+		log.Err("Xload::Stop : Failed to prune dedup store [%s]", err.Error())
+	} else if freed > 0 {
+		log.Info("Xload::Stop : Pruned %d bytes from dedup store", freed)
+	}
+
 	return nil
 }
 
@@ -288,6 +466,9 @@ func (xl *Xload) createDownloader() error {
 		defaultPermission: xl.defaultPermission,
 		remote:            xl.NextComponent(),
 		statsMgr:          xl.statsMgr,
+		filter:            xl.filter,
+		shardedList:       xl.shardedList,
+		shardedNonASCII:   xl.shardedNonASCII,
 	})
 	if err != nil {
 		log.Err("Xload::createDownloader : Unable to create remote lister [%s]", err.Error())
@@ -302,6 +483,10 @@ func (xl *Xload) createDownloader() error {
 		statsMgr:    xl.statsMgr,
 		fileLocks:   xl.fileLocks,
 		validateMD5: xl.validateMD5,
+		checkpoint:  xl.checkpoint,
+		order:       xl.order,
+		tracker:     xl.tracker,
+		dedup:       xl.dedup,
 	})
 	if err != nil {
 		log.Err("Xload::createDownloader : Unable to create download splitter [%s]", err.Error())
@@ -322,6 +507,116 @@ func (xl *Xload) createDownloader() error {
 	return nil
 }
 
+func (xl *Xload) createUploader() error {
+	log.Trace("Xload::createUploader : Starting uploader")
+
+	// Create local lister pool to list local files
+	ll, err := newLocalLister(&localListerOptions{
+		path:              xl.path,
+		workerCount:       uint32(math.Max(math.Min(float64(runtime.NumCPU()/2), float64(MAX_LISTER)), 1)),
+		defaultPermission: xl.defaultPermission,
+		remote:            xl.NextComponent(),
+		statsMgr:          xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : Unable to create local lister [%s]", err.Error())
+		return err
+	}
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   xl.blockPool,
+		path:        xl.path,
+		workerCount: uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+		fileLocks:   xl.fileLocks,
+		validateMD5: xl.validateMD5,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : Unable to create upload splitter [%s]", err.Error())
+		return err
+	}
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: xl.workerCount,
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : failed to create remote data manager [%s]", err.Error())
+		return err
+	}
+
+	xl.comps = []XComponent{ll, us, rdm}
+	return nil
+}
+
+func (xl *Xload) createSyncer() error {
+	log.Trace("Xload::createSyncer : Starting syncer")
+
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{
+		blockPool:   xl.blockPool,
+		path:        xl.path,
+		workerCount: uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+		fileLocks:   xl.fileLocks,
+		validateMD5: xl.validateMD5,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to create download splitter [%s]", err.Error())
+		return err
+	}
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   xl.blockPool,
+		path:        xl.path,
+		workerCount: uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+		fileLocks:   xl.fileLocks,
+		validateMD5: xl.validateMD5,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to create upload splitter [%s]", err.Error())
+		return err
+	}
+
+	// syncer hands each file to one of the splitters itself, so only it and the data manager form the chain
+	sy, err := newSyncer(&syncerOptions{
+		path:              xl.path,
+		stateFile:         xl.syncStateFile,
+		policy:            xl.syncPolicy,
+		deletes:           xl.syncDelete,
+		workerCount:       uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		defaultPermission: xl.defaultPermission,
+		remote:            xl.NextComponent(),
+		statsMgr:          xl.statsMgr,
+		downloader:        ds,
+		uploader:          us,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to create syncer [%s]", err.Error())
+		return err
+	}
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: xl.workerCount,
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : failed to create remote data manager [%s]", err.Error())
+		return err
+	}
+
+	ds.SetNext(rdm)
+	us.SetNext(rdm)
+
+	xl.comps = []XComponent{sy, rdm}
+	return nil
+}
+
 func (xl *Xload) createChain() error {
 	if len(xl.comps) == 0 {
 		log.Err("Xload::createChain : no component initialized in xload")
@@ -353,6 +648,68 @@ func (xl *Xload) startComponents() error {
 	return nil
 }
 
+// handleControl : execute the command received on the control socket and return the status after it
+func (xl *Xload) handleControl(command string) (*Status, error) {
+	xl.controlLock.Lock()
+	defer xl.controlLock.Unlock()
+
+	mode := strings.ToLower(xl.mode.String())
+	switch command {
+	case ControlStatus:
+	case ControlPause, ControlResume, ControlCancel:
+		if xl.mode == EMode.SYNC() {
+			return nil, fmt.Errorf("%s is not supported in %s mode", command, mode)
+		}
+
+		if xl.cancelled {
+			return nil, fmt.Errorf("%s has been cancelled", mode)
+		}
+
+		xl.paused = command != ControlResume
+		xl.cancelled = command == ControlCancel
+		xl.setPaused(xl.paused)
+		log.Crit("Xload::handleControl : %s %s", mode, xl.state())
+	default:
+		return nil, fmt.Errorf("invalid command %s", command)
+	}
+
+	st := xl.statsMgr.status()
+	st.Mode = mode
+	if st.State != STATE_COMPLETED {
+		st.State = xl.state()
+	}
+	if st.State != STATE_RUNNING && st.State != STATE_COMPLETED {
+		st.ETASeconds = -1
+	}
+
+	return st, nil
+}
+
+// setPaused : hold or release the files not yet picked by the lister and the splitter.
+// Blocks of the files already being transferred are left to the data manager, so that no file is left half done.
+func (xl *Xload) setPaused(paused bool) {
+	for _, c := range xl.comps {
+		if c.GetName() == DATA_MANAGER || c.GetThreadPool() == nil {
+			continue
+		}
+
+		if paused {
+			c.GetThreadPool().Pause()
+		} else {
+			c.GetThreadPool().Resume()
+		}
+	}
+}
+
+func (xl *Xload) state() string {
+	if xl.cancelled {
+		return STATE_CANCELLED
+	} else if xl.paused {
+		return STATE_PAUSED
+	}
+	return STATE_RUNNING
+}
+
 func (xl *Xload) getSplitter() XComponent {
 	for _, c := range xl.comps {
 		if c.GetName() == SPLITTER {
@@ -399,6 +756,7 @@ func (xl *Xload) downloadFile(fileName string) error {
 		Atime:    attr.Atime,
 		Mtime:    attr.Mtime,
 		MD5:      attr.MD5,
+		ETag:     attr.ETag,
 	})
 
 	if err != nil {
@@ -412,6 +770,12 @@ func (xl *Xload) downloadFile(fileName string) error {
 // OpenFile: Download the file if not already downloaded and return the file handle
 func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
 	log.Trace("Xload::OpenFile : name=%s, flags=%d, mode=%s", options.Name, options.Flags, options.Mode)
+
+	// local path is not a cache of the container in upload and sync mode
+	if xl.mode == EMode.UPLOAD() || xl.mode == EMode.SYNC() {
+		return xl.NextComponent().OpenFile(options)
+	}
+
 	localPath := filepath.Join(xl.path, options.Name)
 
 	flock := xl.fileLocks.Get(options.Name)
@@ -419,6 +783,23 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 	defer flock.Unlock()
 
 	filePresent, _, _ := isFilePresent(localPath)
+	modified := xl.tracker != nil && xl.tracker.isModified(options.Name)
+
+	// file left behind by an earlier mount may be partial or stale till the splitter has checked it against the journal
+	if filePresent && !modified && xl.checkpoint != nil && !xl.checkpoint.isCurrent(options.Name) {
+		filePresent = false
+	}
+
+	// on a read-write mount the blob may have been overwritten since the local copy was downloaded or uploaded
+	if filePresent && flock.Count() == 0 && xl.isStale(options.Name) {
+		log.Info("Xload::OpenFile : %s has changed in the container, local copy will be refreshed", options.Name)
+		err := os.Remove(localPath)
+		if err != nil {
+			log.Err("Xload::OpenFile : failed to remove stale local copy of %s [%s]", options.Name, err.Error())
+			return nil, err
+		}
+		filePresent = false
+	}
 
 	// if file is not present, send it to splitter for downloading on priority
 	if !filePresent {
@@ -432,6 +813,15 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 		log.Debug("Xload::OpenFile : %s will be served from local path", options.Name)
 	}
 
+	if xl.dedup != nil && (options.Flags&syscall.O_ACCMODE != os.O_RDONLY || options.Flags&os.O_TRUNC != 0) {
+		// content may be shared with other files through the dedup store, so give this file its own copy
+		err := xl.dedup.Unshare(localPath)
+		if err != nil {
+			log.Err("Xload::OpenFile : failed to unshare content of %s [%s]", options.Name, err.Error())
+			return nil, err
+		}
+	}
+
 	fh, err := os.OpenFile(localPath, options.Flags, options.Mode)
 	if err != nil {
 		log.Err("Xload::OpenFile : error opening cached file %s [%s]", options.Name, err.Error())
@@ -447,6 +837,15 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 		handle.Size = info.Size()
 	}
 
+	if xl.tracker != nil && options.Flags&syscall.O_ACCMODE != os.O_RDONLY {
+		// preload shall not overwrite the local copy from now on, as it may have changes not yet uploaded
+		xl.tracker.markModified(options.Name)
+		if options.Flags&os.O_TRUNC != 0 {
+			// truncated file has to be uploaded even if nothing is written to it
+			handle.Flags.Set(handlemap.HandleFlagDirty)
+		}
+	}
+
 	handle.UnixFD = uint64(fh.Fd())
 	handle.Flags.Set(handlemap.HandleFlagCached)
 
@@ -457,15 +856,375 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 }
 
 func (xl *Xload) CloseFile(options internal.CloseFileOptions) error {
+	if xl.mode == EMode.UPLOAD() || xl.mode == EMode.SYNC() {
+		return xl.NextComponent().CloseFile(options)
+	}
+
 	// Lock the file so that while close is in progress no one can open the file again
 	flock := xl.fileLocks.Get(options.Handle.Path)
 	flock.Lock()
 	defer flock.Unlock()
 
 	flock.Dec()
+
+	if options.Handle.Dirty() {
+		err := xl.uploadFile(options.Handle)
+		if err != nil {
+			log.Err("Xload::CloseFile : failed to upload %s [%s]", options.Handle.Path, err.Error())
+			return err
+		}
+	}
+
 	return nil
 }
 
+// CreateFile: Create the file in the container and in the local path, and return the handle of the local file
+func (xl *Xload) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
+	log.Trace("Xload::CreateFile : name=%s, mode=%d", options.Name, options.Mode)
+
+	// local path is written only when preload runs on a read-write mount
+	if xl.tracker == nil {
+		return xl.NextComponent().CreateFile(options)
+	}
+
+	flock := xl.fileLocks.Get(options.Name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	_, err := xl.NextComponent().CreateFile(options)
+	if err != nil {
+		log.Err("Xload::CreateFile : failed to create file %s [%s]", options.Name, err.Error())
+		return nil, err
+	}
+
+	localPath := filepath.Join(xl.path, options.Name)
+	err = os.MkdirAll(filepath.Dir(localPath), xl.defaultPermission)
+	if err != nil {
+		log.Err("Xload::CreateFile : failed to create local directory for %s [%s]", options.Name, err.Error())
+		return nil, err
+	}
+
+	fh, err := os.OpenFile(localPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, options.Mode)
+	if err != nil {
+		log.Err("Xload::CreateFile : error creating local file %s [%s]", options.Name, err.Error())
+		return nil, err
+	}
+
+	xl.tracker.markModified(options.Name)
+	flock.Inc()
+
+	handle := handlemap.NewHandle(options.Name)
+	handle.UnixFD = uint64(fh.Fd())
+	handle.Flags.Set(handlemap.HandleFlagCached)
+	handle.Flags.Set(handlemap.HandleFlagDirty)
+	handle.SetFileObject(fh)
+
+	log.Info("Xload::CreateFile : file=%s, fd=%d", options.Name, fh.Fd())
+	return handle, nil
+}
+
+// WriteFile: Write to the local file, it is uploaded when the handle is flushed or closed
+func (xl *Xload) WriteFile(options internal.WriteFileOptions) (int, error) {
+	if xl.tracker == nil {
+		return xl.NextComponent().WriteFile(options)
+	}
+
+	f := options.Handle.GetFileObject()
+	if f == nil {
+		log.Err("Xload::WriteFile : error [couldn't find fd in handle] %s", options.Handle.Path)
+		return 0, syscall.EBADF
+	}
+
+	bytesWritten, err := f.WriteAt(options.Data, options.Offset)
+	if err != nil {
+		log.Err("Xload::WriteFile : failed to write %s [%s]", options.Handle.Path, err.Error())
+		return 0, err
+	}
+
+	options.Handle.Flags.Set(handlemap.HandleFlagDirty)
+	return bytesWritten, nil
+}
+
+// FlushFile: Upload the local file if it has been written using this handle
+func (xl *Xload) FlushFile(options internal.FlushFileOptions) error {
+	if xl.tracker == nil {
+		return xl.NextComponent().FlushFile(options)
+	}
+
+	if !options.Handle.Dirty() {
+		return nil
+	}
+
+	flock := xl.fileLocks.Get(options.Handle.Path)
+	flock.Lock()
+	defer flock.Unlock()
+
+	return xl.uploadFile(options.Handle)
+}
+
+// uploadFile : upload the local copy of a file written on a read-write mount and record the new version of the blob
+func (xl *Xload) uploadFile(handle *handlemap.Handle) error {
+	f := handle.GetFileObject()
+	if f == nil {
+		log.Err("Xload::uploadFile : error [couldn't find fd in handle] %s", handle.Path)
+		return syscall.EBADF
+	}
+
+	// data written through the handle may still be buffered by the kernel, dup+close flushes it to disk
+	dupFd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		log.Err("Xload::uploadFile : error [couldn't duplicate the fd] %s", handle.Path)
+		return syscall.EIO
+	}
+
+	err = syscall.Close(dupFd)
+	if err != nil {
+		log.Err("Xload::uploadFile : error [unable to close duplicate fd] %s", handle.Path)
+		return syscall.EIO
+	}
+
+	uploadHandle, err := os.Open(filepath.Join(xl.path, handle.Path))
+	if err != nil {
+		log.Err("Xload::uploadFile : error [unable to open upload handle] %s [%s]", handle.Path, err.Error())
+		return err
+	}
+
+	err = xl.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+		Name: handle.Path,
+		File: uploadHandle,
+	})
+	uploadHandle.Close()
+	if err != nil {
+		log.Err("Xload::uploadFile : failed to upload %s [%s]", handle.Path, err.Error())
+		return err
+	}
+
+	handle.Flags.Clear(handlemap.HandleFlagDirty)
+	xl.refreshVersion(handle.Path)
+	return nil
+}
+
+// refreshVersion : record the version of the blob which the local copy now matches
+func (xl *Xload) refreshVersion(name string) {
+	attr, err := xl.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		log.Warn("Xload::refreshVersion : failed to get attr of %s [%s]", name, err.Error())
+		return
+	}
+
+	xl.tracker.setETag(name, attr.ETag)
+
+	if xl.checkpoint != nil {
+		item := &WorkItem{
+			Path:    name,
+			DataLen: uint64(attr.Size),
+			Mtime:   attr.Mtime,
+			MD5:     attr.MD5,
+			ETag:    attr.ETag,
+		}
+		xl.checkpoint.begin(item, false)
+		xl.checkpoint.fileDone(name)
+	}
+}
+
+// isStale : check if the blob has been overwritten since the local copy was downloaded or uploaded
+func (xl *Xload) isStale(name string) bool {
+	if xl.tracker == nil {
+		return false
+	}
+
+	etag := xl.tracker.etag(name)
+	if etag == "" {
+		// version of the local copy is not known, or it has changes not yet uploaded
+		return false
+	}
+
+	attr, err := xl.NextComponent().GetAttr(internal.GetAttrOptions{Name: name})
+	if err != nil {
+		return false
+	}
+
+	return attr.ETag != "" && attr.ETag != etag
+}
+
+// TruncateFile: Truncate the file in the container and the local copy of it
+func (xl *Xload) TruncateFile(options internal.TruncateFileOptions) error {
+	if xl.tracker == nil {
+		return xl.NextComponent().TruncateFile(options)
+	}
+
+	flock := xl.fileLocks.Get(options.Name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	err := xl.NextComponent().TruncateFile(options)
+	if err != nil {
+		log.Err("Xload::TruncateFile : failed to truncate %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	localPath := filepath.Join(xl.path, options.Name)
+	if filePresent, _, _ := isFilePresent(localPath); filePresent {
+		if xl.dedup != nil {
+			err = xl.dedup.Unshare(localPath)
+			if err != nil {
+				log.Err("Xload::TruncateFile : failed to unshare content of %s [%s]", options.Name, err.Error())
+				return err
+			}
+		}
+
+		err = os.Truncate(localPath, options.Size)
+		if err != nil {
+			log.Err("Xload::TruncateFile : failed to truncate local copy of %s [%s]", options.Name, err.Error())
+			return err
+		}
+	}
+
+	xl.tracker.markModified(options.Name)
+	if flock.Count() == 0 {
+		// no handle can have changes pending, so the local copy is same as the blob
+		xl.refreshVersion(options.Name)
+	}
+	return nil
+}
+
+// DeleteFile: Delete the file from the container and the local path
+func (xl *Xload) DeleteFile(options internal.DeleteFileOptions) error {
+	if xl.tracker == nil {
+		return xl.NextComponent().DeleteFile(options)
+	}
+
+	flock := xl.fileLocks.Get(options.Name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	err := xl.NextComponent().DeleteFile(options)
+	if err != nil {
+		log.Err("Xload::DeleteFile : failed to delete %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	err = os.Remove(filepath.Join(xl.path, options.Name))
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("Xload::DeleteFile : failed to delete local copy of %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	xl.tracker.remove(options.Name, false)
+	return nil
+}
+
+// RenameFile: Rename the file in the container and the local path
+func (xl *Xload) RenameFile(options internal.RenameFileOptions) error {
+	if xl.tracker == nil {
+		return xl.NextComponent().RenameFile(options)
+	}
+
+	flock := xl.fileLocks.Get(options.Src)
+	flock.Lock()
+	defer flock.Unlock()
+
+	dstLock := xl.fileLocks.Get(options.Dst)
+	dstLock.Lock()
+	defer dstLock.Unlock()
+
+	err := xl.NextComponent().RenameFile(options)
+	if err != nil {
+		log.Err("Xload::RenameFile : failed to rename %s to %s [%s]", options.Src, options.Dst, err.Error())
+		return err
+	}
+
+	err = xl.renameLocal(options.Src, options.Dst)
+	if err != nil {
+		log.Err("Xload::RenameFile : failed to rename local copy of %s to %s [%s]", options.Src, options.Dst, err.Error())
+		return err
+	}
+
+	xl.tracker.rename(options.Src, options.Dst, false)
+	return nil
+}
+
+// CreateDir: Create the directory in the container and the local path
+func (xl *Xload) CreateDir(options internal.CreateDirOptions) error {
+	if xl.tracker == nil {
+		return xl.NextComponent().CreateDir(options)
+	}
+
+	err := xl.NextComponent().CreateDir(options)
+	if err != nil {
+		log.Err("Xload::CreateDir : failed to create directory %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Join(xl.path, options.Name), xl.defaultPermission)
+	if err != nil {
+		log.Err("Xload::CreateDir : failed to create local directory %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DeleteDir: Delete the directory from the container and the local path
+func (xl *Xload) DeleteDir(options internal.DeleteDirOptions) error {
+	if xl.tracker == nil {
+		return xl.NextComponent().DeleteDir(options)
+	}
+
+	err := xl.NextComponent().DeleteDir(options)
+	if err != nil {
+		log.Err("Xload::DeleteDir : failed to delete directory %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	err = os.RemoveAll(filepath.Join(xl.path, options.Name))
+	if err != nil {
+		log.Err("Xload::DeleteDir : failed to delete local directory %s [%s]", options.Name, err.Error())
+		return err
+	}
+
+	xl.tracker.remove(options.Name, true)
+	return nil
+}
+
+// RenameDir: Rename the directory in the container and the local path
+func (xl *Xload) RenameDir(options internal.RenameDirOptions) error {
+	if xl.tracker == nil {
+		return xl.NextComponent().RenameDir(options)
+	}
+
+	err := xl.NextComponent().RenameDir(options)
+	if err != nil {
+		log.Err("Xload::RenameDir : failed to rename directory %s to %s [%s]", options.Src, options.Dst, err.Error())
+		return err
+	}
+
+	err = xl.renameLocal(options.Src, options.Dst)
+	if err != nil {
+		log.Err("Xload::RenameDir : failed to rename local directory %s to %s [%s]", options.Src, options.Dst, err.Error())
+		return err
+	}
+
+	xl.tracker.rename(options.Src, options.Dst, true)
+	return nil
+}
+
+// renameLocal : move the local copy of a file or directory, if it has been downloaded
+func (xl *Xload) renameLocal(src string, dst string) error {
+	localSrc := filepath.Join(xl.path, src)
+	if filePresent, _, _ := isFilePresent(localSrc); !filePresent {
+		return nil
+	}
+
+	localDst := filepath.Join(xl.path, dst)
+	err := os.MkdirAll(filepath.Dir(localDst), xl.defaultPermission)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(localSrc, localDst)
+}
+
 // ------------------------- Factory -------------------------------------------
 
 // Pipeline will call this method to create your object, initialize your variables here
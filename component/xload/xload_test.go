@@ -35,10 +35,12 @@ package xload
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -160,6 +162,12 @@ func (suite *xloadTestSuite) TestConfigNotReadOnly() {
 	suite.cleanupTest(false) // teardown the default xload generated
 	testConfig := fmt.Sprintf("xload:\n  path: %s\n\nloopbackfs:\n  path: %s", suite.local_path, suite.fake_storage_path)
 	err := suite.setupTestHelper(testConfig, false) // setup a new xload with a custom config (teardown will occur after the test as usual)
+	suite.assert.Nil(err)
+	suite.assert.True(suite.xload.readWrite)
+
+	config.ResetConfig()
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  mode: upload\n\nloopbackfs:\n  path: %s", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
 	suite.assert.NotNil(err)
 	suite.assert.Contains(err.Error(), "should be used in only in read-only mode")
 }
@@ -302,7 +310,7 @@ func (suite *xloadTestSuite) TestUnsupportedModes() {
 	defer suite.cleanupTest(false)
 	suite.cleanupTest(false) // teardown the default xload generated
 
-	modes := []string{"upload", "sync", "invalid_mode"}
+	modes := []string{"invalid_mode"}
 	blockSize := float64(0.001)
 	for _, m := range modes {
 		testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: %s\n  block-size-mb: %v\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, m, blockSize, suite.fake_storage_path)
@@ -542,6 +550,429 @@ func (suite *xloadTestSuite) TestOpenFileWithDownload() {
 	suite.validateMD5WithOpenFile(suite.local_path, suite.fake_storage_path)
 }
 
+func (suite *xloadTestSuite) TestConfigUploadPath() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	// local path must exist in upload mode
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: upload\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err := suite.setupTestHelper(testConfig, false)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "is not a directory")
+
+	// and is allowed to be non empty
+	err = os.MkdirAll(suite.local_path, 0777)
+	suite.assert.Nil(err)
+	createTestDirsAndFiles(suite.local_path, suite.assert)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Equal(suite.xload.mode, EMode.UPLOAD())
+}
+
+func (suite *xloadTestSuite) TestUploadStartStop() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	err := os.MkdirAll(suite.local_path, 0777)
+	suite.assert.Nil(err)
+	createTestDirsAndFiles(suite.local_path, suite.assert)
+
+	blockSize := (float64)(0.00001)
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: upload\n  block-size-mb: %v\n  validate-md5: true\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, blockSize, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, true)
+	suite.assert.Nil(err)
+
+	suite.assert.Eventually(func() bool { return uploaded(suite.local_path, suite.fake_storage_path) }, 5*time.Second, 10*time.Millisecond)
+	validateMD5(suite.fake_storage_path, suite.local_path, suite.assert)
+
+	// files are served from the container and not from the local path
+	fh, err := suite.xload.OpenFile(internal.OpenFileOptions{Name: "dir_0/file_3", Flags: os.O_RDONLY, Mode: common.DefaultFilePermissionBits})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(fh)
+	suite.assert.False(fh.Cached())
+
+	err = suite.xload.CloseFile(internal.CloseFileOptions{Handle: fh})
+	suite.assert.Nil(err)
+
+	// local data is retained on stop
+	suite.loopback.Stop()
+	err = suite.xload.Stop()
+	suite.assert.Nil(err)
+	suite.assert.FileExists(filepath.Join(suite.local_path, "dir_0", "file_3"))
+}
+
+func (suite *xloadTestSuite) TestConfigCheckpoint() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	err := os.MkdirAll(suite.local_path, 0777)
+	suite.assert.Nil(err)
+	createTestDirsAndFiles(suite.local_path, suite.assert)
+
+	// non empty local path is rejected without checkpointing
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n\nloopbackfs:\n  path: %s\n\nazstorage:\n  container: test\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "temp directory not empty")
+
+	// and resumed with it
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  checkpoint: true\n\nloopbackfs:\n  path: %s\n\nazstorage:\n  container: test\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Equal(suite.xload.checkpointFile, checkpointFilePath(suite.local_path, "test"))
+
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  checkpoint: true\n  checkpoint-file: /tmp/xload.journal\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Equal(suite.xload.checkpointFile, "/tmp/xload.journal")
+
+	// checkpointing applies only to preload
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  mode: upload\n  checkpoint: true\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Empty(suite.xload.checkpointFile)
+}
+
+func (suite *xloadTestSuite) TestConfigFilters() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err := suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Nil(suite.xload.filter)
+	suite.assert.Nil(suite.xload.order)
+
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  include:\n    - data/train\n  exclude:\n    - \"*.tmp\"\n  priority:\n    - data/train/labels\n    - smallest-first\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Equal([]string{"data/train"}, suite.xload.filter.include)
+	suite.assert.Equal([]string{"*.tmp"}, suite.xload.filter.exclude)
+	suite.assert.Equal([]string{"data/train/labels"}, suite.xload.order.patterns)
+	suite.assert.Equal([]string{smallestFirst}, suite.xload.order.keys)
+
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  exclude:\n    - \"[a\"\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "invalid pattern")
+}
+
+func (suite *xloadTestSuite) TestConfigSync() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	// local path is created if missing and is allowed to be non empty
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: sync\n\nloopbackfs:\n  path: %s\n\nazstorage:\n  container: test\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err := suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Equal(suite.xload.mode, EMode.SYNC())
+	suite.assert.Equal(suite.xload.syncPolicy, ESyncPolicy.NEWEST_WINS())
+	suite.assert.False(suite.xload.syncDelete)
+	suite.assert.Equal(suite.xload.syncStateFile, syncStateFile(suite.local_path, "test"))
+
+	createTestDirsAndFiles(suite.local_path, suite.assert)
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  mode: sync\n  sync-policy: local-wins\n  sync-delete: true\n  sync-state-file: /tmp/xload_state.json\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.assert.Equal(suite.xload.syncPolicy, ESyncPolicy.LOCAL_WINS())
+	suite.assert.True(suite.xload.syncDelete)
+	suite.assert.Equal(suite.xload.syncStateFile, "/tmp/xload_state.json")
+
+	testConfig = fmt.Sprintf("xload:\n  path: %s\n  mode: sync\n  sync-policy: oldest-wins\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "invalid sync-policy")
+}
+
+func (suite *xloadTestSuite) TestSyncStartStop() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	err := os.MkdirAll(filepath.Join(suite.local_path, "local"), 0777)
+	suite.assert.Nil(err)
+	err = os.MkdirAll(filepath.Join(suite.fake_storage_path, "remote"), 0777)
+	suite.assert.Nil(err)
+	createTestDirsAndFiles(filepath.Join(suite.local_path, "local"), suite.assert)
+	createTestDirsAndFiles(filepath.Join(suite.fake_storage_path, "remote"), suite.assert)
+
+	stateFile := filepath.Join(os.TempDir(), "xload_state_"+randomString(8)+".json")
+	defer os.Remove(stateFile)
+
+	blockSize := (float64)(0.00001)
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n  mode: sync\n  block-size-mb: %v\n  sync-state-file: %s\n\nloopbackfs:\n  path: %s\n\nread-only: true", suite.local_path, blockSize, stateFile, suite.fake_storage_path)
+	err = suite.setupTestHelper(testConfig, true)
+	suite.assert.Nil(err)
+
+	sy, ok := suite.xload.comps[0].(*syncer)
+	suite.assert.True(ok)
+	suite.assert.Eventually(func() bool {
+		select {
+		case <-sy.done:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// both sides hold the union of the files
+	validateMD5(suite.local_path, suite.fake_storage_path, suite.assert)
+	validateMD5(suite.fake_storage_path, suite.local_path, suite.assert)
+	suite.assert.FileExists(stateFile)
+
+	// files are served from the container and not from the local path
+	fh, err := suite.xload.OpenFile(internal.OpenFileOptions{Name: "remote/dir_0/file_3", Flags: os.O_RDONLY, Mode: common.DefaultFilePermissionBits})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(fh)
+	suite.assert.False(fh.Cached())
+
+	err = suite.xload.CloseFile(internal.CloseFileOptions{Handle: fh})
+	suite.assert.Nil(err)
+
+	// local data is retained on stop
+	suite.loopback.Stop()
+	err = suite.xload.Stop()
+	suite.assert.Nil(err)
+	suite.assert.FileExists(filepath.Join(suite.local_path, "local", "dir_0", "file_3"))
+}
+
+func (suite *xloadTestSuite) TestHandleControl() {
+	defer suite.cleanupTest(false)
+
+	type testCmp struct {
+		XBase
+	}
+
+	var err error
+	suite.xload.statsMgr, err = NewStatsManager(4, false)
+	suite.assert.Nil(err)
+
+	pools := make(map[string]*ThreadPool)
+	for _, name := range []string{LISTER, SPLITTER, DATA_MANAGER} {
+		c := &testCmp{}
+		c.SetName(name)
+		pools[name] = NewThreadPool(1, c.Process)
+		c.SetThreadPool(pools[name])
+		suite.xload.comps = append(suite.xload.comps, c)
+	}
+
+	st, err := suite.xload.handleControl(ControlStatus)
+	suite.assert.Nil(err)
+	suite.assert.Equal("preload", st.Mode)
+	suite.assert.Equal(STATE_RUNNING, st.State)
+
+	// data manager keeps transferring the blocks of the files already picked
+	st, err = suite.xload.handleControl(ControlPause)
+	suite.assert.Nil(err)
+	suite.assert.Equal(STATE_PAUSED, st.State)
+	suite.assert.Equal(int64(-1), st.ETASeconds)
+	suite.assert.True(pools[LISTER].Paused())
+	suite.assert.True(pools[SPLITTER].Paused())
+	suite.assert.False(pools[DATA_MANAGER].Paused())
+
+	st, err = suite.xload.handleControl(ControlResume)
+	suite.assert.Nil(err)
+	suite.assert.Equal(STATE_RUNNING, st.State)
+	suite.assert.False(pools[LISTER].Paused())
+	suite.assert.False(pools[SPLITTER].Paused())
+
+	st, err = suite.xload.handleControl(ControlCancel)
+	suite.assert.Nil(err)
+	suite.assert.Equal(STATE_CANCELLED, st.State)
+	suite.assert.True(pools[LISTER].Paused())
+	suite.assert.True(pools[SPLITTER].Paused())
+
+	_, err = suite.xload.handleControl(ControlResume)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "has been cancelled")
+
+	st, err = suite.xload.handleControl(ControlStatus)
+	suite.assert.Nil(err)
+	suite.assert.Equal(STATE_CANCELLED, st.State)
+
+	_, err = suite.xload.handleControl("stop")
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "invalid command")
+
+	suite.xload.mode = EMode.SYNC()
+	_, err = suite.xload.handleControl(ControlPause)
+	suite.assert.NotNil(err)
+	suite.assert.Contains(err.Error(), "not supported in sync mode")
+}
+
+// setupReadWrite : configure a preload on a read-write mount without starting the download of the container
+func (suite *xloadTestSuite) setupReadWrite() {
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	testConfig := fmt.Sprintf("xload:\n  path: %s\n\nloopbackfs:\n  path: %s", suite.local_path, suite.fake_storage_path)
+	err := suite.setupTestHelper(testConfig, false)
+	suite.assert.Nil(err)
+	suite.xload.tracker = newFileTracker()
+
+	err = os.MkdirAll(suite.fake_storage_path, 0777)
+	suite.assert.Nil(err)
+	err = os.MkdirAll(suite.local_path, 0777)
+	suite.assert.Nil(err)
+}
+
+func (suite *xloadTestSuite) TestCreateWriteClose() {
+	defer suite.cleanupTest(false)
+	suite.setupReadWrite()
+
+	fh, err := suite.xload.CreateFile(internal.CreateFileOptions{Name: "file_0", Mode: common.DefaultFilePermissionBits})
+	suite.assert.Nil(err)
+	suite.assert.NotNil(fh)
+	suite.assert.True(fh.Cached())
+	suite.assert.True(suite.xload.tracker.isModified("file_0"))
+	suite.assert.FileExists(filepath.Join(suite.fake_storage_path, "file_0"))
+
+	data := []byte("read-write preload")
+	n, err := suite.xload.WriteFile(internal.WriteFileOptions{Handle: fh, Offset: 0, Data: data})
+	suite.assert.Nil(err)
+	suite.assert.Equal(len(data), n)
+	suite.assert.True(fh.Dirty())
+
+	err = suite.xload.FlushFile(internal.FlushFileOptions{Handle: fh})
+	suite.assert.Nil(err)
+	suite.assert.False(fh.Dirty())
+
+	remoteData, err := os.ReadFile(filepath.Join(suite.fake_storage_path, "file_0"))
+	suite.assert.Nil(err)
+	suite.assert.Equal(data, remoteData)
+
+	n, err = suite.xload.WriteFile(internal.WriteFileOptions{Handle: fh, Offset: int64(len(data)), Data: data})
+	suite.assert.Nil(err)
+	suite.assert.Equal(len(data), n)
+
+	err = suite.xload.CloseFile(internal.CloseFileOptions{Handle: fh})
+	suite.assert.Nil(err)
+	fh.GetFileObject().Close()
+
+	remoteData, err = os.ReadFile(filepath.Join(suite.fake_storage_path, "file_0"))
+	suite.assert.Nil(err)
+	suite.assert.Equal(append(data, data...), remoteData)
+	suite.assert.Equal(uint32(0), suite.xload.fileLocks.Get("file_0").Count())
+}
+
+func (suite *xloadTestSuite) TestOpenFileForWrite() {
+	defer suite.cleanupTest(false)
+	suite.setupReadWrite()
+
+	// local copy is already present, so it is served without a download
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, "file_1"), []byte("remote"), 0777)
+	suite.assert.Nil(err)
+	err = os.WriteFile(filepath.Join(suite.local_path, "file_1"), []byte("remote"), 0777)
+	suite.assert.Nil(err)
+
+	fh, err := suite.xload.OpenFile(internal.OpenFileOptions{Name: "file_1", Flags: os.O_RDONLY, Mode: common.DefaultFilePermissionBits})
+	suite.assert.Nil(err)
+	suite.assert.False(suite.xload.tracker.isModified("file_1"))
+	suite.assert.Nil(suite.xload.CloseFile(internal.CloseFileOptions{Handle: fh}))
+	fh.GetFileObject().Close()
+
+	fh, err = suite.xload.OpenFile(internal.OpenFileOptions{Name: "file_1", Flags: os.O_RDWR | os.O_TRUNC, Mode: common.DefaultFilePermissionBits})
+	suite.assert.Nil(err)
+	suite.assert.True(suite.xload.tracker.isModified("file_1"))
+	suite.assert.True(fh.Dirty())
+
+	// truncated file is uploaded on close even though nothing was written to it
+	suite.assert.Nil(suite.xload.CloseFile(internal.CloseFileOptions{Handle: fh}))
+	fh.GetFileObject().Close()
+
+	info, err := os.Stat(filepath.Join(suite.fake_storage_path, "file_1"))
+	suite.assert.Nil(err)
+	suite.assert.Equal(int64(0), info.Size())
+}
+
+func (suite *xloadTestSuite) TestWriteDedupFile() {
+	defer suite.cleanupTest(false)
+	suite.setupReadWrite()
+
+	var err error
+	suite.xload.dedup, err = common.NewDedupStore(suite.local_path + "_dedup")
+	suite.assert.Nil(err)
+	defer os.RemoveAll(suite.local_path + "_dedup")
+
+	data := []byte("shared content")
+	sum := md5.Sum(data)
+	for _, name := range []string{"file_1", "file_2"} {
+		err = os.WriteFile(filepath.Join(suite.fake_storage_path, name), data, 0777)
+		suite.assert.Nil(err)
+	}
+
+	err = os.WriteFile(filepath.Join(suite.local_path, "file_1"), data, 0777)
+	suite.assert.Nil(err)
+	suite.assert.Nil(suite.xload.dedup.Add(sum[:], filepath.Join(suite.local_path, "file_1")))
+	found, err := suite.xload.dedup.Link(sum[:], int64(len(data)), filepath.Join(suite.local_path, "file_2"))
+	suite.assert.Nil(err)
+	suite.assert.True(found)
+
+	// file opened for write gets its own copy, so the other file sharing the content is not changed
+	fh, err := suite.xload.OpenFile(internal.OpenFileOptions{Name: "file_1", Flags: os.O_RDWR, Mode: common.DefaultFilePermissionBits})
+	suite.assert.Nil(err)
+	_, err = suite.xload.WriteFile(internal.WriteFileOptions{Handle: fh, Offset: 0, Data: []byte("SHARED")})
+	suite.assert.Nil(err)
+	suite.assert.Nil(suite.xload.CloseFile(internal.CloseFileOptions{Handle: fh}))
+
+	localData, err := os.ReadFile(filepath.Join(suite.local_path, "file_2"))
+	suite.assert.Nil(err)
+	suite.assert.Equal(data, localData)
+
+	// truncate unshares the content as well
+	err = suite.xload.TruncateFile(internal.TruncateFileOptions{Name: "file_2", Size: 6})
+	suite.assert.Nil(err)
+	info, err := os.Stat(filepath.Join(suite.local_path, "file_2"))
+	suite.assert.Nil(err)
+	suite.assert.EqualValues(1, info.Sys().(*syscall.Stat_t).Nlink)
+	suite.assert.EqualValues(6, info.Size())
+}
+
+func (suite *xloadTestSuite) TestTruncateDeleteRenameFile() {
+	defer suite.cleanupTest(false)
+	suite.setupReadWrite()
+
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, "file_2"), []byte("remote data"), 0777)
+	suite.assert.Nil(err)
+	err = os.WriteFile(filepath.Join(suite.local_path, "file_2"), []byte("remote data"), 0777)
+	suite.assert.Nil(err)
+
+	err = suite.xload.TruncateFile(internal.TruncateFileOptions{Name: "file_2", Size: 6})
+	suite.assert.Nil(err)
+	localData, err := os.ReadFile(filepath.Join(suite.local_path, "file_2"))
+	suite.assert.Nil(err)
+	suite.assert.Equal([]byte("remote"), localData)
+	suite.assert.True(suite.xload.tracker.isModified("file_2"))
+
+	err = suite.xload.RenameFile(internal.RenameFileOptions{Src: "file_2", Dst: "dir_0/file_3"})
+	suite.assert.Error(err) // destination directory is missing in the container
+
+	err = suite.xload.CreateDir(internal.CreateDirOptions{Name: "dir_0", Mode: 0777})
+	suite.assert.Nil(err)
+	suite.assert.DirExists(filepath.Join(suite.local_path, "dir_0"))
+
+	err = suite.xload.RenameFile(internal.RenameFileOptions{Src: "file_2", Dst: "dir_0/file_3"})
+	suite.assert.Nil(err)
+	suite.assert.NoFileExists(filepath.Join(suite.local_path, "file_2"))
+	suite.assert.FileExists(filepath.Join(suite.local_path, "dir_0", "file_3"))
+	suite.assert.False(suite.xload.tracker.isModified("file_2"))
+	suite.assert.True(suite.xload.tracker.isModified("dir_0/file_3"))
+
+	err = suite.xload.RenameDir(internal.RenameDirOptions{Src: "dir_0", Dst: "dir_1"})
+	suite.assert.Nil(err)
+	suite.assert.FileExists(filepath.Join(suite.local_path, "dir_1", "file_3"))
+	suite.assert.True(suite.xload.tracker.isModified("dir_1/file_3"))
+
+	err = suite.xload.DeleteFile(internal.DeleteFileOptions{Name: "dir_1/file_3"})
+	suite.assert.Nil(err)
+	suite.assert.NoFileExists(filepath.Join(suite.local_path, "dir_1", "file_3"))
+	suite.assert.NoFileExists(filepath.Join(suite.fake_storage_path, "dir_1", "file_3"))
+	suite.assert.False(suite.xload.tracker.isModified("dir_1/file_3"))
+
+	err = suite.xload.DeleteDir(internal.DeleteDirOptions{Name: "dir_1"})
+	suite.assert.Nil(err)
+	suite.assert.NoDirExists(filepath.Join(suite.local_path, "dir_1"))
+	suite.assert.NoDirExists(filepath.Join(suite.fake_storage_path, "dir_1"))
+}
+
 func (suite *xloadTestSuite) validateMD5WithOpenFile(localPath string, remotePath string) {
 	entries, err := os.ReadDir(remotePath)
 	suite.assert.Nil(err)
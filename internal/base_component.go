@@ -136,6 +136,13 @@ func (base *BaseComponent) StreamDir(options StreamDirOptions) ([]*ObjAttr, stri
 	return nil, "", nil
 }
 
+func (base *BaseComponent) ListPrefix(options ListPrefixOptions) ([]*ObjAttr, string, error) {
+	if base.next != nil {
+		return base.next.ListPrefix(options)
+	}
+	return nil, "", nil
+}
+
 func (base *BaseComponent) CloseDir(options CloseDirOptions) error {
 	if base.next != nil {
 		return base.next.CloseDir(options)
@@ -321,6 +328,13 @@ func (base *BaseComponent) FileUsed(name string) error {
 	return nil
 }
 
+func (base *BaseComponent) InvalidatePath(options InvalidatePathOptions) error {
+	if base.next != nil {
+		return base.next.InvalidatePath(options)
+	}
+	return nil
+}
+
 func (base *BaseComponent) StatFs() (*syscall.Statfs_t, bool, error) {
 	if base.next != nil {
 		return base.next.StatFs()
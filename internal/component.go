@@ -90,6 +90,8 @@ type Component interface {
 	//must return ErrNotExist for absence of the requested directory
 	ReadDir(ReadDirOptions) ([]*ObjAttr, error)
 	StreamDir(StreamDirOptions) ([]*ObjAttr, string, error)
+	// ListPrefix: one page of the objects whose path begins with the prefix, used to list a directory in shards
+	ListPrefix(ListPrefixOptions) ([]*ObjAttr, string, error)
 
 	CloseDir(CloseDirOptions) error
 
@@ -137,6 +139,8 @@ type Component interface {
 	GetFileBlockOffsets(options GetFileBlockOffsetsOptions) (*common.BlockOffsetList, error)
 
 	FileUsed(name string) error
+	// InvalidatePath: drop anything cached for a path which was changed in storage by another client
+	InvalidatePath(InvalidatePathOptions) error
 	StatFs() (*syscall.Statfs_t, bool, error)
 
 	GetCommittedBlockList(string) (*CommittedBlockList, error)
@@ -34,6 +34,7 @@
 package internal
 
 import (
+	"io"
 	"os"
 
 	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
@@ -67,6 +68,14 @@ type StreamDirOptions struct {
 	Count  int32
 }
 
+// ListPrefixOptions : List the objects whose path begins with the prefix, which need not end at a directory boundary
+type ListPrefixOptions struct {
+	Prefix    string
+	Token     string
+	Count     int32
+	Recursive bool // list every object under the prefix instead of grouping them by directory
+}
+
 type CloseDirOptions struct {
 	Name string
 }
@@ -79,6 +88,7 @@ type RenameDirOptions struct {
 type CreateFileOptions struct {
 	Name string
 	Mode os.FileMode
+	Uid  uint32 // uid of the calling process as reported by fuse
 }
 
 type DeleteFileOptions struct {
@@ -89,6 +99,7 @@ type OpenFileOptions struct {
 	Name  string
 	Flags int
 	Mode  os.FileMode
+	Uid   uint32 // uid of the calling process as reported by fuse
 }
 
 type CloseFileOptions struct {
@@ -142,6 +153,9 @@ type CopyFromFileOptions struct {
 	Name     string
 	File     *os.File
 	Metadata map[string]*string
+
+	// Data is read from Reader when File is not given, used when local data has to be transformed while uploading
+	Reader io.Reader
 }
 
 type FlushFileOptions struct {
@@ -210,6 +224,16 @@ type CommitDataOptions struct {
 	NewETag   *string
 }
 
+// InvalidatePathOptions : Path changed in storage by another client
+type InvalidatePathOptions struct {
+	Name  string
+	ETag  string // Etag of the object after the change, empty if it was deleted or is not known
+	IsDir bool   // Directory was renamed or deleted, everything under it changed as well
+
+	// Change was found by a component which already refreshed its own state, only kernel caches need to be dropped
+	KernelOnly bool
+}
+
 type CommittedBlock struct {
 	Id     string
 	Offset int64
@@ -0,0 +1,687 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+// Package fusetest holds the handler tests shared by the FUSE frontends.
+// Each frontend runs the same suite through an adapter, so libfuse and gofuse are held to the same behaviour.
+package fusetest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+	"github.com/Azure/azure-storage-fuse/v2/internal/handlemap"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// File : Handle a frontend gave back for an open file, nil for calls made without an open handle
+type File interface{}
+
+// Options : Settings of a frontend after it has been configured
+type Options struct {
+	MountPath             string
+	ReadOnly              bool
+	TraceEnable           bool
+	AllowOther            bool
+	AllowRoot             bool
+	DirPermission         uint
+	FilePermission        uint
+	EntryExpiration       uint32
+	AttributeExpiration   uint32
+	NegativeTimeout       uint32
+	DisableWritebackCache bool
+	IgnoreOpenFlags       bool
+	DirectIO              bool
+}
+
+// Frontend : A FUSE frontend under test, driven through the calls kernel makes for a path
+type Frontend interface {
+	// Setup builds the frontend over the given component with the given config
+	Setup(next *internal.MockComponent, config string)
+	Component() internal.Component
+	Options() Options
+
+	// WritebackCache tells if kernel write back cache can be on, in which case write-only and append opens
+	// are rejected or turned into read-write ones
+	WritebackCache() bool
+
+	Getattr(name string) (*syscall.Stat_t, syscall.Errno)
+	Mkdir(name string, mode uint32) syscall.Errno
+	Rmdir(name string) syscall.Errno
+	FsyncDir(name string) syscall.Errno
+	Create(name string, mode uint32) syscall.Errno
+	Open(name string, flags int) (File, syscall.Errno)
+	Fsync(name string, file File) syscall.Errno
+	Truncate(name string, size int64) syscall.Errno
+	Unlink(name string) syscall.Errno
+	Symlink(target string, name string) syscall.Errno
+	Readlink(name string) (string, syscall.Errno)
+	Chmod(name string, mode uint32) syscall.Errno
+	Chown(name string, uid uint32, gid uint32) syscall.Errno
+	Utimens(name string) syscall.Errno
+	Statfs() (*syscall.Statfs_t, syscall.Errno)
+}
+
+type frontendTestSuite struct {
+	suite.Suite
+	assert   *assert.Assertions
+	name     string
+	frontend Frontend
+	mockCtrl *gomock.Controller
+	mock     *internal.MockComponent
+}
+
+// NewFrontendSuite : Suite of handler tests run against the given frontend, name is the name of its component
+func NewFrontendSuite(name string, frontend Frontend) suite.TestingSuite {
+	return &frontendTestSuite{name: name, frontend: frontend}
+}
+
+func (suite *frontendTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{})
+	if err != nil {
+		panic("Unable to set silent logger as default.")
+	}
+	suite.setupTestHelper("")
+}
+
+func (suite *frontendTestSuite) setupTestHelper(config string) {
+	suite.assert = assert.New(suite.T())
+
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mock = internal.NewMockComponent(suite.mockCtrl)
+	suite.frontend.Setup(suite.mock, config)
+}
+
+func (suite *frontendTestSuite) cleanupTest() {
+	suite.mockCtrl.Finish()
+}
+
+// section : Config with the given options under the section of the frontend
+func (suite *frontendTestSuite) section(options string) string {
+	return fmt.Sprintf("%s:\n%s", suite.name, options)
+}
+
+// openFlags : Flags the frontend is expected to pass down for an open with the given flags when open flags are ignored
+func (suite *frontendTestSuite) openFlags(flags int) int {
+	if !suite.frontend.WritebackCache() {
+		return flags
+	}
+	return flags&^(syscall.O_APPEND|syscall.O_ACCMODE) | syscall.O_RDWR
+}
+
+// Tests the default configuration of the frontend
+func (suite *frontendTestSuite) TestDefault() {
+	defer suite.cleanupTest()
+	opts := suite.frontend.Options()
+	suite.assert.Equal(suite.frontend.Component().Name(), suite.name)
+	suite.assert.Empty(opts.MountPath)
+	suite.assert.False(opts.ReadOnly)
+	suite.assert.False(opts.TraceEnable)
+	suite.assert.False(opts.AllowOther)
+	suite.assert.False(opts.AllowRoot)
+	suite.assert.Equal(opts.DirPermission, uint(common.DefaultDirectoryPermissionBits))
+	suite.assert.Equal(opts.FilePermission, uint(common.DefaultFilePermissionBits))
+	suite.assert.Equal(opts.EntryExpiration, uint32(120))
+	suite.assert.Equal(opts.AttributeExpiration, uint32(120))
+	suite.assert.Equal(opts.NegativeTimeout, uint32(120))
+	suite.assert.False(opts.DisableWritebackCache)
+	suite.assert.True(opts.IgnoreOpenFlags)
+	suite.assert.False(opts.DirectIO)
+}
+
+func (suite *frontendTestSuite) TestConfig() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default frontend generated
+	config := "allow-other: true\nread-only: true\n" + suite.section("  attribute-expiration-sec: 60\n  entry-expiration-sec: 60\n  negative-entry-expiration-sec: 60\n  fuse-trace: true\n  disable-writeback-cache: true\n  ignore-open-flags: false\n  direct-io: true\n")
+	suite.setupTestHelper(config) // setup a new frontend with a custom config (clean up will occur after the test as usual)
+
+	opts := suite.frontend.Options()
+	suite.assert.Equal(suite.frontend.Component().Name(), suite.name)
+	suite.assert.Empty(opts.MountPath)
+	suite.assert.True(opts.ReadOnly)
+	// trace should only be enabled when mounted in foreground otherwise we don't honor the option
+	suite.assert.False(opts.TraceEnable)
+	suite.assert.True(opts.DisableWritebackCache)
+	suite.assert.False(opts.IgnoreOpenFlags)
+	suite.assert.True(opts.AllowOther)
+	suite.assert.False(opts.AllowRoot)
+	suite.assert.Equal(opts.DirPermission, uint(fs.FileMode(0777)))
+	suite.assert.Equal(opts.FilePermission, uint(fs.FileMode(0777)))
+	suite.assert.Equal(opts.EntryExpiration, uint32(0))
+	suite.assert.Equal(opts.AttributeExpiration, uint32(0))
+	suite.assert.Equal(opts.NegativeTimeout, uint32(0))
+	suite.assert.True(opts.DirectIO)
+}
+
+func (suite *frontendTestSuite) TestConfigZero() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default frontend generated
+	config := "read-only: true\n" + suite.section("  attribute-expiration-sec: 0\n  entry-expiration-sec: 0\n  negative-entry-expiration-sec: 0\n  fuse-trace: true\n  direct-io: false\n")
+	suite.setupTestHelper(config) // setup a new frontend with a custom config (clean up will occur after the test as usual)
+
+	opts := suite.frontend.Options()
+	suite.assert.Equal(suite.frontend.Component().Name(), suite.name)
+	suite.assert.Empty(opts.MountPath)
+	suite.assert.True(opts.ReadOnly)
+	// trace should only be enabled when mounted in foreground otherwise we don't honor the option
+	suite.assert.False(opts.TraceEnable)
+	suite.assert.False(opts.AllowOther)
+	suite.assert.False(opts.AllowRoot)
+	suite.assert.Equal(opts.DirPermission, uint(fs.FileMode(0775)))
+	suite.assert.Equal(opts.FilePermission, uint(fs.FileMode(0755)))
+	suite.assert.Equal(opts.EntryExpiration, uint32(0))
+	suite.assert.Equal(opts.AttributeExpiration, uint32(0))
+	suite.assert.Equal(opts.NegativeTimeout, uint32(0))
+	suite.assert.False(opts.DirectIO)
+}
+
+func (suite *frontendTestSuite) TestConfigDefaultPermission() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default frontend generated
+	config := "read-only: true\n" + suite.section("  default-permission: 0555\n  attribute-expiration-sec: 0\n  entry-expiration-sec: 0\n  negative-entry-expiration-sec: 0\n  fuse-trace: true\n  direct-io: true\n")
+	suite.setupTestHelper(config) // setup a new frontend with a custom config (clean up will occur after the test as usual)
+
+	opts := suite.frontend.Options()
+	suite.assert.Equal(suite.frontend.Component().Name(), suite.name)
+	suite.assert.Empty(opts.MountPath)
+	suite.assert.True(opts.ReadOnly)
+	// trace should only be enabled when mounted in foreground otherwise we don't honor the option
+	suite.assert.False(opts.TraceEnable)
+	suite.assert.False(opts.AllowOther)
+	suite.assert.False(opts.AllowRoot)
+	suite.assert.Equal(opts.DirPermission, uint(fs.FileMode(0555)))
+	suite.assert.Equal(opts.FilePermission, uint(fs.FileMode(0555)))
+	suite.assert.Equal(opts.EntryExpiration, uint32(0))
+	suite.assert.Equal(opts.AttributeExpiration, uint32(0))
+	suite.assert.Equal(opts.NegativeTimeout, uint32(0))
+	suite.assert.True(opts.DirectIO)
+}
+
+func (suite *frontendTestSuite) TestConfigFuseTraceEnable() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default frontend generated
+	config := "foreground: true\n" + suite.section("  fuse-trace: true\n")
+
+	// Foreground mount option is global config option which is exported to others using a global variable.
+	// Hence setting the option before starting the test.
+	common.ForegroundMount = true
+	defer func() { common.ForegroundMount = false }()
+	suite.setupTestHelper(config) // setup a new frontend with a custom config (clean up will occur after the test as usual)
+
+	opts := suite.frontend.Options()
+	suite.assert.Equal(suite.frontend.Component().Name(), suite.name)
+	suite.assert.Empty(opts.MountPath)
+	// Fuse trace should work as we are mouting using foregroud option.
+	suite.assert.True(opts.TraceEnable)
+}
+
+func (suite *frontendTestSuite) TestDisableWritebackCache() {
+	defer suite.cleanupTest()
+	suite.assert.False(suite.frontend.Options().DisableWritebackCache)
+
+	suite.cleanupTest() // clean up the default frontend generated
+	suite.setupTestHelper(suite.section("  disable-writeback-cache: true\n"))
+	suite.assert.True(suite.frontend.Options().DisableWritebackCache)
+
+	suite.cleanupTest() // clean up the default frontend generated
+	suite.setupTestHelper(suite.section("  disable-writeback-cache: false\n"))
+	suite.assert.False(suite.frontend.Options().DisableWritebackCache)
+}
+
+func (suite *frontendTestSuite) TestIgnoreAppendFlag() {
+	defer suite.cleanupTest()
+	suite.assert.True(suite.frontend.Options().IgnoreOpenFlags)
+
+	suite.cleanupTest() // clean up the default frontend generated
+	suite.setupTestHelper(suite.section("  ignore-open-flags: false\n"))
+	suite.assert.False(suite.frontend.Options().IgnoreOpenFlags)
+
+	suite.cleanupTest() // clean up the default frontend generated
+	suite.setupTestHelper(suite.section("  ignore-open-flags: true\n"))
+	suite.assert.True(suite.frontend.Options().IgnoreOpenFlags)
+}
+
+func (suite *frontendTestSuite) TestMkDir() {
+	defer suite.cleanupTest()
+	name := "path"
+	options := internal.CreateDirOptions{Name: name, Mode: fs.FileMode(0775)}
+	suite.mock.EXPECT().CreateDir(options).Return(nil)
+
+	err := suite.frontend.Mkdir(name, 0775)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestMkDirError() {
+	defer suite.cleanupTest()
+	name := "path"
+	options := internal.CreateDirOptions{Name: name, Mode: fs.FileMode(0775)}
+	suite.mock.EXPECT().CreateDir(options).Return(errors.New("failed to create directory"))
+
+	err := suite.frontend.Mkdir(name, 0775)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestRmDir() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().IsDirEmpty(internal.IsDirEmptyOptions{Name: name}).Return(true)
+	suite.mock.EXPECT().DeleteDir(internal.DeleteDirOptions{Name: name}).Return(nil)
+
+	err := suite.frontend.Rmdir(name)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestRmDirNotEmpty() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().IsDirEmpty(internal.IsDirEmptyOptions{Name: name}).Return(false)
+
+	err := suite.frontend.Rmdir(name)
+	suite.assert.Equal(syscall.ENOTEMPTY, err)
+}
+
+func (suite *frontendTestSuite) TestRmDirError() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().IsDirEmpty(internal.IsDirEmptyOptions{Name: name}).Return(true)
+	suite.mock.EXPECT().DeleteDir(internal.DeleteDirOptions{Name: name}).Return(errors.New("failed to delete directory"))
+
+	err := suite.frontend.Rmdir(name)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestCreate() {
+	defer suite.cleanupTest()
+	name := "path"
+	options := internal.CreateFileOptions{Name: name, Mode: fs.FileMode(0775)}
+	suite.mock.EXPECT().CreateFile(options).Return(&handlemap.Handle{}, nil)
+
+	err := suite.frontend.Create(name, 0775)
+	suite.assert.Equal(syscall.Errno(0), err)
+
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(&internal.ObjAttr{}, nil)
+	stat, err := suite.frontend.Getattr(name)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal(int64(0), stat.Mtim.Nsec)
+	suite.assert.NotEqual(int64(0), stat.Mtim.Sec)
+}
+
+func (suite *frontendTestSuite) TestCreateError() {
+	defer suite.cleanupTest()
+	name := "path"
+	options := internal.CreateFileOptions{Name: name, Mode: fs.FileMode(0775)}
+	suite.mock.EXPECT().CreateFile(options).Return(&handlemap.Handle{}, errors.New("failed to create file"))
+
+	err := suite.frontend.Create(name, 0775)
+	suite.assert.Equal(syscall.EIO, err)
+
+	suite.mock.EXPECT().CreateFile(options).Return(nil, syscall.EDQUOT)
+	err = suite.frontend.Create(name, 0775)
+	suite.assert.Equal(syscall.EDQUOT, err)
+}
+
+func (suite *frontendTestSuite) TestOpen() {
+	defer suite.cleanupTest()
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	options := internal.OpenFileOptions{Name: name, Flags: syscall.O_RDWR, Mode: mode}
+	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+
+	file, err := suite.frontend.Open(name, syscall.O_RDWR)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.NotNil(file)
+}
+
+func (suite *frontendTestSuite) TestOpenSyncDirectFlag() {
+	defer suite.cleanupTest()
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	options := internal.OpenFileOptions{Name: name, Flags: syscall.O_RDWR, Mode: mode}
+	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+
+	_, err := suite.frontend.Open(name, syscall.O_RDWR|syscall.O_SYNC|syscall.O_DIRECT)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+// WriteBack caching enabled by default, append and write-only opens are rejected unless open flags are ignored
+func (suite *frontendTestSuite) TestOpenAppendFlagDefault() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default frontend generated
+	suite.setupTestHelper(suite.section("  ignore-open-flags: false\n"))
+
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	for _, flags := range []int{syscall.O_RDWR | syscall.O_APPEND, syscall.O_WRONLY | syscall.O_APPEND} {
+		if !suite.frontend.WritebackCache() {
+			// Without write back cache the flags are passed unchanged
+			options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
+			suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+
+			_, err := suite.frontend.Open(name, flags)
+			suite.assert.Equal(syscall.Errno(0), err)
+			continue
+		}
+
+		_, err := suite.frontend.Open(name, flags)
+		suite.assert.Equal(syscall.EINVAL, err)
+	}
+}
+
+func (suite *frontendTestSuite) TestOpenAppendFlagDisableWritebackCache() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default frontend generated
+	suite.setupTestHelper(suite.section("  disable-writeback-cache: true\n"))
+	suite.assert.True(suite.frontend.Options().DisableWritebackCache)
+
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	for _, flags := range []int{syscall.O_RDWR | syscall.O_APPEND, syscall.O_WRONLY | syscall.O_APPEND} {
+		options := internal.OpenFileOptions{Name: name, Flags: flags, Mode: mode}
+		suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+
+		_, err := suite.frontend.Open(name, flags)
+		suite.assert.Equal(syscall.Errno(0), err)
+	}
+}
+
+func (suite *frontendTestSuite) TestOpenAppendFlagIgnoreAppendFlag() {
+	defer suite.cleanupTest()
+	suite.cleanupTest() // clean up the default frontend generated
+	suite.setupTestHelper(suite.section("  ignore-open-flags: true\n"))
+	suite.assert.True(suite.frontend.Options().IgnoreOpenFlags)
+
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	for _, flags := range []int{syscall.O_RDWR | syscall.O_APPEND, syscall.O_WRONLY | syscall.O_APPEND, syscall.O_WRONLY} {
+		options := internal.OpenFileOptions{Name: name, Flags: suite.openFlags(flags), Mode: mode}
+		suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, nil)
+
+		_, err := suite.frontend.Open(name, flags)
+		suite.assert.Equal(syscall.Errno(0), err)
+	}
+}
+
+func (suite *frontendTestSuite) TestOpenNotExists() {
+	defer suite.cleanupTest()
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	options := internal.OpenFileOptions{Name: name, Flags: syscall.O_RDWR, Mode: mode}
+	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, syscall.ENOENT)
+
+	_, err := suite.frontend.Open(name, syscall.O_RDWR)
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+func (suite *frontendTestSuite) TestOpenError() {
+	defer suite.cleanupTest()
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	options := internal.OpenFileOptions{Name: name, Flags: syscall.O_RDWR, Mode: mode}
+	suite.mock.EXPECT().OpenFile(options).Return(&handlemap.Handle{}, errors.New("failed to open a file"))
+
+	_, err := suite.frontend.Open(name, syscall.O_RDWR)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestTruncate() {
+	defer suite.cleanupTest()
+	name := "path"
+	size := int64(1024)
+	suite.mock.EXPECT().TruncateFile(internal.TruncateFileOptions{Name: name, Size: size}).Return(nil)
+
+	err := suite.frontend.Truncate(name, size)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestTruncateError() {
+	defer suite.cleanupTest()
+	name := "path"
+	size := int64(1024)
+	suite.mock.EXPECT().TruncateFile(internal.TruncateFileOptions{Name: name, Size: size}).Return(errors.New("failed to truncate file"))
+
+	err := suite.frontend.Truncate(name, size)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestUnlink() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().DeleteFile(internal.DeleteFileOptions{Name: name}).Return(nil)
+
+	err := suite.frontend.Unlink(name)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestUnlinkNotExists() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().DeleteFile(internal.DeleteFileOptions{Name: name}).Return(syscall.ENOENT)
+
+	err := suite.frontend.Unlink(name)
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+func (suite *frontendTestSuite) TestUnlinkError() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().DeleteFile(internal.DeleteFileOptions{Name: name}).Return(errors.New("failed to delete file"))
+
+	err := suite.frontend.Unlink(name)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestSymlink() {
+	defer suite.cleanupTest()
+	name := "path"
+	target := "target"
+	suite.mock.EXPECT().CreateLink(internal.CreateLinkOptions{Name: name, Target: target}).Return(nil)
+
+	err := suite.frontend.Symlink(target, name)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestSymlinkError() {
+	defer suite.cleanupTest()
+	name := "path"
+	target := "target"
+	suite.mock.EXPECT().CreateLink(internal.CreateLinkOptions{Name: name, Target: target}).Return(errors.New("failed to create link"))
+
+	err := suite.frontend.Symlink(target, name)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestReadLink() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().ReadLink(internal.ReadLinkOptions{Name: name}).Return("target", nil)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(&internal.ObjAttr{}, nil)
+
+	target, err := suite.frontend.Readlink(name)
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal("target", target)
+}
+
+func (suite *frontendTestSuite) TestReadLinkNotExists() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().ReadLink(internal.ReadLinkOptions{Name: name}).Return("", syscall.ENOENT)
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(&internal.ObjAttr{}, nil)
+
+	target, err := suite.frontend.Readlink(name)
+	suite.assert.Equal(syscall.ENOENT, err)
+	suite.assert.NotEqual("target", target)
+}
+
+func (suite *frontendTestSuite) TestReadLinkError() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().ReadLink(internal.ReadLinkOptions{Name: name}).Return("", errors.New("failed to read link"))
+	suite.mock.EXPECT().GetAttr(internal.GetAttrOptions{Name: name}).Return(nil, nil)
+
+	target, err := suite.frontend.Readlink(name)
+	suite.assert.Equal(syscall.EIO, err)
+	suite.assert.NotEqual("target", target)
+}
+
+func (suite *frontendTestSuite) TestFsync() {
+	defer suite.cleanupTest()
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	handle := &handlemap.Handle{}
+	suite.mock.EXPECT().OpenFile(internal.OpenFileOptions{Name: name, Flags: syscall.O_RDWR, Mode: mode}).Return(handle, nil)
+	file, _ := suite.frontend.Open(name, syscall.O_RDWR)
+	suite.assert.NotNil(file)
+
+	// Frontend shall sync the handle given back by the open
+	suite.mock.EXPECT().SyncFile(internal.SyncFileOptions{Handle: handle}).Return(nil)
+
+	err := suite.frontend.Fsync(name, file)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestFsyncHandleError() {
+	defer suite.cleanupTest()
+
+	err := suite.frontend.Fsync("path", nil)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestFsyncError() {
+	defer suite.cleanupTest()
+	name := "path"
+	mode := fs.FileMode(suite.frontend.Options().FilePermission)
+	handle := &handlemap.Handle{}
+	suite.mock.EXPECT().OpenFile(internal.OpenFileOptions{Name: name, Flags: syscall.O_RDWR, Mode: mode}).Return(handle, nil)
+	file, _ := suite.frontend.Open(name, syscall.O_RDWR)
+	suite.assert.NotNil(file)
+
+	suite.mock.EXPECT().SyncFile(internal.SyncFileOptions{Handle: handle}).Return(errors.New("failed to sync file"))
+
+	err := suite.frontend.Fsync(name, file)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestFsyncDir() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().SyncDir(internal.SyncDirOptions{Name: name}).Return(nil)
+
+	err := suite.frontend.FsyncDir(name)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestFsyncDirError() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().SyncDir(internal.SyncDirOptions{Name: name}).Return(errors.New("failed to sync dir"))
+
+	err := suite.frontend.FsyncDir(name)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+func (suite *frontendTestSuite) TestChmod() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().Chmod(internal.ChmodOptions{Name: name, Mode: fs.FileMode(0775)}).Return(nil)
+
+	err := suite.frontend.Chmod(name, 0775)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestChmodNotExists() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().Chmod(internal.ChmodOptions{Name: name, Mode: fs.FileMode(0775)}).Return(syscall.ENOENT)
+
+	err := suite.frontend.Chmod(name, 0775)
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+func (suite *frontendTestSuite) TestChmodError() {
+	defer suite.cleanupTest()
+	name := "path"
+	suite.mock.EXPECT().Chmod(internal.ChmodOptions{Name: name, Mode: fs.FileMode(0775)}).Return(errors.New("failed to chmod"))
+
+	err := suite.frontend.Chmod(name, 0775)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+// Owner and times are not supported by storage, these are accepted to allow chown and touch to work
+func (suite *frontendTestSuite) TestChown() {
+	defer suite.cleanupTest()
+
+	err := suite.frontend.Chown("path", 4, 5)
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestUtimens() {
+	defer suite.cleanupTest()
+
+	err := suite.frontend.Utimens("path")
+	suite.assert.Equal(syscall.Errno(0), err)
+}
+
+func (suite *frontendTestSuite) TestStatFs() {
+	defer suite.cleanupTest()
+	suite.mock.EXPECT().StatFs().Return(&syscall.Statfs_t{Frsize: 1,
+		Blocks: 2, Bavail: 3, Bfree: 4}, true, nil)
+
+	stat, err := suite.frontend.Statfs()
+	suite.assert.Equal(syscall.Errno(0), err)
+	suite.assert.Equal(int(stat.Frsize), 1)
+	suite.assert.Equal(int(stat.Blocks), 2)
+	suite.assert.Equal(int(stat.Bavail), 3)
+	suite.assert.Equal(int(stat.Bfree), 4)
+}
+
+func (suite *frontendTestSuite) TestInvalidatePath() {
+	defer suite.cleanupTest()
+	options := internal.InvalidatePathOptions{Name: "dir/file"}
+	suite.mock.EXPECT().InvalidatePath(options).Return(nil)
+
+	// Without a mount there is nothing cached in kernel, invalidation is only passed down
+	err := suite.frontend.Component().InvalidatePath(options)
+	suite.assert.NoError(err)
+
+	// Changes found by components below are not passed down again
+	options.KernelOnly = true
+	err = suite.frontend.Component().InvalidatePath(options)
+	suite.assert.NoError(err)
+}
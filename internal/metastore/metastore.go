@@ -0,0 +1,792 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package metastore
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+)
+
+// Store is an on-disk cache of directory listings which persists across mounts.
+// Listings are kept page by page, as returned by StreamDir, in a directory tree mirroring the namespace:
+//
+//	<path>/<container hash>/_a/_b/index               : pages known for directory a/b
+//	<path>/<container hash>/_a/_b/p-<hash of token>   : entries of one page of a/b
+//
+// A directory whose pages are all present and not expired is complete, so attributes of its children
+// including their absence are served from disk without listing the directory again.
+type Store struct {
+	root string
+	ttl  time.Duration
+	refs int
+
+	locks *common.LockMap // Locks for each directory
+	clean sync.Map        // Directories known to have nothing stored
+
+	cacheLock sync.Mutex
+	pages     map[string]*list.Element // Recently used pages kept decoded in memory
+	lru       *list.List
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+const (
+	indexFile    = "index"
+	pagePrefix   = "p-"
+	compPrefix   = "_"
+	tmpSuffix    = ".tmp"
+	maxComponent = 200 // Longer names are hashed to stay within file name limits
+	pageCacheLen = 32
+	staleTmpAge  = time.Minute // Temp files older than this were left by an interrupted write
+)
+
+// entry : Attributes of one child as stored on disk
+type entry struct {
+	Name     string
+	Size     int64
+	Mode     os.FileMode
+	Mtime    time.Time
+	Atime    time.Time
+	Ctime    time.Time
+	Crtime   time.Time
+	Flags    uint16
+	MD5      []byte
+	ETag     string
+	Metadata map[string]string
+}
+
+type page struct {
+	Entries []entry // Sorted by name
+}
+
+type pageInfo struct {
+	File     string
+	Next     string // Token of the next page, empty for the last page
+	First    string // Smallest and largest names in the page
+	Last     string
+	CachedAt time.Time
+	Snapshot bool // Page was imported from a report, names missing from it may still exist
+}
+
+type dirIndex struct {
+	Pages map[string]*pageInfo // Keyed by the token the page was listed with
+}
+
+type cachedPage struct {
+	file    string
+	entries []entry
+}
+
+var (
+	stores     = make(map[string]*Store)
+	storesLock sync.Mutex
+)
+
+// Open : Open the store at given path for a container, mounts of the same process share the store
+func Open(path string, container string, ttl time.Duration) (*Store, error) {
+	if path == "" || ttl <= 0 {
+		return nil, fmt.Errorf("invalid metadata store config")
+	}
+
+	root := path
+	if container != "" {
+		hash := sha256.Sum256([]byte(container))
+		root = filepath.Join(path, hex.EncodeToString(hash[:16]))
+	}
+
+	storesLock.Lock()
+	defer storesLock.Unlock()
+
+	if s, found := stores[root]; found {
+		s.refs++
+		return s, nil
+	}
+
+	err := os.MkdirAll(root, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		root:  root,
+		ttl:   ttl,
+		refs:  1,
+		locks: common.NewLockMap(),
+		pages: make(map[string]*list.Element),
+		lru:   list.New(),
+		done:  make(chan struct{}),
+	}
+	stores[root] = s
+
+	// Pages which expired while nothing was mounted are removed in background
+	s.wg.Add(1)
+	go s.sweep()
+
+	log.Info("Store::Open : metadata store at %s, ttl %v", root, ttl)
+	return s, nil
+}
+
+// Close : Release the store, it is closed once all its users release it
+func (s *Store) Close() {
+	if s == nil {
+		return
+	}
+
+	storesLock.Lock()
+	s.refs--
+	last := s.refs == 0
+	if last {
+		delete(stores, s.root)
+	}
+	storesLock.Unlock()
+
+	if last {
+		close(s.done)
+		s.wg.Wait()
+	}
+}
+
+// sweep : Remove expired pages and files left by an interrupted write
+func (s *Store) sweep() {
+	defer s.wg.Done()
+
+	removed := 0
+	_ = filepath.WalkDir(s.root, func(path string, d os.DirEntry, err error) error {
+		select {
+		case <-s.done:
+			return filepath.SkipAll
+		default:
+		}
+
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		age := time.Since(info.ModTime())
+		if (strings.HasSuffix(d.Name(), tmpSuffix) && age > staleTmpAge) || (strings.HasPrefix(d.Name(), pagePrefix) && age > s.ttl) {
+			_ = os.Remove(path)
+			removed++
+		}
+		return nil
+	})
+
+	log.Debug("Store::sweep : %d expired files removed from %s", removed, s.root)
+}
+
+// normalize : Path relative to the root without leading or trailing separators
+func normalize(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// split : Parent directory and name of a path
+func split(path string) (string, string) {
+	idx := strings.LastIndex(path, "/")
+	if idx < 0 {
+		return "", path
+	}
+	return path[:idx], path[idx+1:]
+}
+
+func join(dir string, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+func hashOf(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:16])
+}
+
+// dirPath : Location of the data of a directory
+func (s *Store) dirPath(dir string) string {
+	path := s.root
+	for _, comp := range strings.Split(dir, "/") {
+		if comp == "" {
+			continue
+		}
+		if len(comp) > maxComponent {
+			comp = "#" + hashOf(comp)
+		}
+		path = filepath.Join(path, compPrefix+comp)
+	}
+	return path
+}
+
+func (s *Store) expired(info *pageInfo) bool {
+	return time.Since(info.CachedAt) > s.ttl
+}
+
+// writeFile : Write a gob encoded value to the file through a temp file so that readers never see a partial file
+func writeFile(path string, value any) error {
+	tmpPath := fmt.Sprintf("%s.%d%s", path, os.Getpid(), tmpSuffix)
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(f).Encode(value)
+	if err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
+
+func readFile(path string, value any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return gob.NewDecoder(f).Decode(value)
+}
+
+func (s *Store) readIndex(dirPath string) *dirIndex {
+	idx := &dirIndex{}
+	err := readFile(filepath.Join(dirPath, indexFile), idx)
+	if err != nil || idx.Pages == nil {
+		idx.Pages = make(map[string]*pageInfo)
+	}
+	return idx
+}
+
+// chain : Pages of a complete listing in order, false if any page is missing or expired
+func (s *Store) chain(idx *dirIndex) ([]*pageInfo, bool) {
+	list := make([]*pageInfo, 0)
+	token := ""
+	for i := 0; i < len(idx.Pages); i++ {
+		info, found := idx.Pages[token]
+		if !found || s.expired(info) {
+			return nil, false
+		}
+
+		list = append(list, info)
+		if info.Next == "" {
+			return list, true
+		}
+		token = info.Next
+	}
+
+	return nil, false
+}
+
+// loadPage : Read the entries of a page, recently used pages are served from memory
+func (s *Store) loadPage(file string) ([]entry, error) {
+	s.cacheLock.Lock()
+	if node, found := s.pages[file]; found {
+		s.lru.MoveToFront(node)
+		s.cacheLock.Unlock()
+		return node.Value.(*cachedPage).entries, nil
+	}
+	s.cacheLock.Unlock()
+
+	p := &page{}
+	err := readFile(file, p)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	if _, found := s.pages[file]; !found {
+		s.pages[file] = s.lru.PushFront(&cachedPage{file: file, entries: p.Entries})
+		if s.lru.Len() > pageCacheLen {
+			node := s.lru.Back()
+			s.lru.Remove(node)
+			delete(s.pages, node.Value.(*cachedPage).file)
+		}
+	}
+
+	return p.Entries, nil
+}
+
+// forgetPages : Drop pages of a directory from memory
+func (s *Store) forgetPages(dirPath string) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	for file, node := range s.pages {
+		if filepath.Dir(file) == dirPath {
+			s.lru.Remove(node)
+			delete(s.pages, file)
+		}
+	}
+}
+
+func toEntry(attr *internal.ObjAttr) entry {
+	e := entry{
+		Name:   filepath.Base(normalize(attr.Path)),
+		Size:   attr.Size,
+		Mode:   attr.Mode,
+		Mtime:  attr.Mtime,
+		Atime:  attr.Atime,
+		Ctime:  attr.Ctime,
+		Crtime: attr.Crtime,
+		Flags:  uint16(attr.Flags),
+		MD5:    attr.MD5,
+		ETag:   attr.ETag,
+	}
+
+	for k, v := range attr.Metadata {
+		if v == nil {
+			continue
+		}
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata[k] = *v
+	}
+
+	return e
+}
+
+func (e *entry) toAttr(dir string) *internal.ObjAttr {
+	attr := &internal.ObjAttr{
+		Path:   join(dir, e.Name),
+		Name:   e.Name,
+		Size:   e.Size,
+		Mode:   e.Mode,
+		Mtime:  e.Mtime,
+		Atime:  e.Atime,
+		Ctime:  e.Ctime,
+		Crtime: e.Crtime,
+		Flags:  common.BitMap16(e.Flags),
+		MD5:    e.MD5,
+		ETag:   e.ETag,
+	}
+
+	if len(e.Metadata) > 0 {
+		attr.Metadata = make(map[string]*string, len(e.Metadata))
+		for k, v := range e.Metadata {
+			value := v
+			attr.Metadata[k] = &value
+		}
+	}
+
+	return attr
+}
+
+func toAttrs(dir string, entries []entry) []*internal.ObjAttr {
+	attrs := make([]*internal.ObjAttr, 0, len(entries))
+	for i := range entries {
+		attrs = append(attrs, entries[i].toAttr(dir))
+	}
+	return attrs
+}
+
+// GetPage : Page of a directory listed with the given token, returns false if it is not stored or has expired
+func (s *Store) GetPage(dir string, token string) ([]*internal.ObjAttr, string, bool) {
+	if s == nil {
+		return nil, "", false
+	}
+
+	dir = normalize(dir)
+	lock := s.locks.Get(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dirPath := s.dirPath(dir)
+	info, found := s.readIndex(dirPath).Pages[token]
+	if !found || s.expired(info) {
+		return nil, "", false
+	}
+
+	entries, err := s.loadPage(filepath.Join(dirPath, info.File))
+	if err != nil {
+		return nil, "", false
+	}
+
+	return toAttrs(dir, entries), info.Next, true
+}
+
+// GetListing : All entries of a directory, returns false unless every page of the directory is stored
+func (s *Store) GetListing(dir string) ([]*internal.ObjAttr, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	dir = normalize(dir)
+	lock := s.locks.Get(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dirPath := s.dirPath(dir)
+	pages, complete := s.chain(s.readIndex(dirPath))
+	if !complete {
+		return nil, false
+	}
+
+	attrs := make([]*internal.ObjAttr, 0)
+	for _, info := range pages {
+		entries, err := s.loadPage(filepath.Join(dirPath, info.File))
+		if err != nil {
+			return nil, false
+		}
+		attrs = append(attrs, toAttrs(dir, entries)...)
+	}
+
+	return attrs, true
+}
+
+// PutPage : Store a page of a directory listed with the given token
+func (s *Store) PutPage(dir string, token string, attrs []*internal.ObjAttr, next string) error {
+	if s == nil {
+		return nil
+	}
+
+	dir = normalize(dir)
+	lock := s.locks.Get(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entries := make([]entry, 0, len(attrs))
+	for _, attr := range attrs {
+		entries = append(entries, toEntry(attr))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	dirPath := s.dirPath(dir)
+	err := os.MkdirAll(dirPath, 0700)
+	if err != nil {
+		return err
+	}
+
+	info := &pageInfo{
+		File:     pagePrefix + hashOf(token),
+		Next:     next,
+		CachedAt: time.Now(),
+	}
+	if len(entries) > 0 {
+		info.First = entries[0].Name
+		info.Last = entries[len(entries)-1].Name
+	}
+
+	s.forgetPages(dirPath)
+	err = writeFile(filepath.Join(dirPath, info.File), &page{Entries: entries})
+	if err != nil {
+		log.Err("Store::PutPage : Failed to write page of %s [%s]", dir, err.Error())
+		return err
+	}
+
+	idx := s.readIndex(dirPath)
+	for key, old := range idx.Pages {
+		if s.expired(old) {
+			delete(idx.Pages, key)
+			if old.File != info.File {
+				_ = os.Remove(filepath.Join(dirPath, old.File))
+			}
+		}
+	}
+	idx.Pages[token] = info
+
+	err = writeFile(filepath.Join(dirPath, indexFile), idx)
+	if err != nil {
+		log.Err("Store::PutPage : Failed to write index of %s [%s]", dir, err.Error())
+		return err
+	}
+
+	s.clean.Delete(dir)
+	return nil
+}
+
+// Lookup : Attributes of a path from the complete listing of its directory.
+// Returns false in the last value if the listing is not stored, otherwise whether the path exists.
+func (s *Store) Lookup(path string) (*internal.ObjAttr, bool, bool) {
+	if s == nil {
+		return nil, false, false
+	}
+
+	path = normalize(path)
+	if path == "" {
+		return nil, false, false
+	}
+
+	attr, exists, ok, snapshot := s.find(path, true)
+	if !exists && snapshot {
+		// Path may have been created after the report was taken
+		return nil, false, false
+	}
+	return attr, exists, ok
+}
+
+// find : Search the stored pages of the directory holding the path.
+// When complete is set the path is known to be absent only if every page of the directory is stored.
+// Last value tells whether any of the pages searched was imported from a report.
+func (s *Store) find(path string, complete bool) (*internal.ObjAttr, bool, bool, bool) {
+	dir, name := split(path)
+
+	lock := s.locks.Get(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dirPath := s.dirPath(dir)
+	idx := s.readIndex(dirPath)
+
+	pages, found := s.chain(idx)
+	if !found {
+		if complete {
+			return nil, false, false, false
+		}
+
+		pages = make([]*pageInfo, 0, len(idx.Pages))
+		for _, info := range idx.Pages {
+			if !s.expired(info) {
+				pages = append(pages, info)
+			}
+		}
+	}
+
+	snapshot := false
+	for _, info := range pages {
+		snapshot = snapshot || info.Snapshot
+		if info.First == "" || name < info.First || name > info.Last {
+			continue
+		}
+
+		entries, err := s.loadPage(filepath.Join(dirPath, info.File))
+		if err != nil {
+			return nil, false, false, false
+		}
+
+		i := sort.Search(len(entries), func(i int) bool { return entries[i].Name >= name })
+		if i < len(entries) && entries[i].Name == name {
+			return entries[i].toAttr(dir), true, true, info.Snapshot
+		}
+	}
+
+	return nil, false, found, snapshot
+}
+
+// Verify : Drop the listing holding the path if the stored attributes no longer match the ones from storage
+func (s *Store) Verify(attr *internal.ObjAttr) {
+	if s == nil || attr == nil {
+		return
+	}
+
+	path := normalize(attr.Path)
+	if path == "" {
+		return
+	}
+
+	// Partial listings are checked as well, those are not used for lookups but are still served page by page
+	stored, exists, ok, _ := s.find(path, false)
+	if (exists && changed(stored, attr)) || (ok && !exists) {
+		log.Debug("Store::Verify : %s changed in storage, dropping listing of its directory", attr.Path)
+		s.Invalidate(path)
+	}
+}
+
+// changed : Whether attributes from storage differ from the stored ones, reports may not carry the etag
+func changed(stored *internal.ObjAttr, attr *internal.ObjAttr) bool {
+	if stored.ETag != "" && attr.ETag != "" {
+		return stored.ETag != attr.ETag
+	}
+	return stored.Size != attr.Size || !stored.Mtime.Equal(attr.Mtime)
+}
+
+// Import : Store the complete listing of a directory taken from a report at the given time.
+// Listings are valid for the ttl from the time of the report and a stored listing newer than the report is retained.
+func (s *Store) Import(dir string, attrs []*internal.ObjAttr, asOf time.Time) error {
+	if s == nil {
+		return nil
+	}
+
+	if time.Since(asOf) > s.ttl {
+		return nil
+	}
+
+	dir = normalize(dir)
+	lock := s.locks.Get(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dirPath := s.dirPath(dir)
+	idx := s.readIndex(dirPath)
+	if pages, complete := s.chain(idx); complete {
+		newer := true
+		for _, info := range pages {
+			newer = newer && info.CachedAt.After(asOf)
+		}
+		if newer {
+			return nil
+		}
+	}
+
+	entries := make([]entry, 0, len(attrs))
+	for _, attr := range attrs {
+		entries = append(entries, toEntry(attr))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	err := os.MkdirAll(dirPath, 0700)
+	if err != nil {
+		return err
+	}
+
+	info := &pageInfo{
+		File:     pagePrefix + hashOf(""),
+		CachedAt: asOf,
+		Snapshot: true,
+	}
+	if len(entries) > 0 {
+		info.First = entries[0].Name
+		info.Last = entries[len(entries)-1].Name
+	}
+
+	s.forgetPages(dirPath)
+	err = writeFile(filepath.Join(dirPath, info.File), &page{Entries: entries})
+	if err != nil {
+		log.Err("Store::Import : Failed to write listing of %s [%s]", dir, err.Error())
+		return err
+	}
+
+	// Pages listed earlier belong to another version of the listing
+	for _, old := range idx.Pages {
+		if old.File != info.File {
+			_ = os.Remove(filepath.Join(dirPath, old.File))
+		}
+	}
+	idx.Pages = map[string]*pageInfo{"": info}
+
+	err = writeFile(filepath.Join(dirPath, indexFile), idx)
+	if err != nil {
+		log.Err("Store::Import : Failed to write index of %s [%s]", dir, err.Error())
+		return err
+	}
+
+	s.clean.Delete(dir)
+	return nil
+}
+
+// Invalidate : Drop the listing of the directory holding the path
+func (s *Store) Invalidate(path string) {
+	if s == nil {
+		return
+	}
+
+	path = normalize(path)
+	if path == "" {
+		return
+	}
+
+	dir, _ := split(path)
+	s.invalidateDir(dir)
+}
+
+// InvalidateTree : Drop listings of a directory, everything below it and the directory holding it
+func (s *Store) InvalidateTree(path string) {
+	if s == nil {
+		return
+	}
+
+	path = normalize(path)
+	lock := s.locks.Get(path)
+	lock.Lock()
+	dirPath := s.dirPath(path)
+	if path == "" {
+		// Root is never removed, only its contents
+		entries, _ := os.ReadDir(dirPath)
+		for _, e := range entries {
+			_ = os.RemoveAll(filepath.Join(dirPath, e.Name()))
+		}
+	} else {
+		_ = os.RemoveAll(dirPath)
+	}
+	lock.Unlock()
+
+	s.cacheLock.Lock()
+	for file, node := range s.pages {
+		if strings.HasPrefix(file, dirPath+string(filepath.Separator)) {
+			s.lru.Remove(node)
+			delete(s.pages, file)
+		}
+	}
+	s.cacheLock.Unlock()
+
+	s.Invalidate(path)
+}
+
+// invalidateDir : Remove the listing of a directory, listings of its sub directories are retained
+func (s *Store) invalidateDir(dir string) {
+	if _, found := s.clean.Load(dir); found {
+		return
+	}
+
+	lock := s.locks.Get(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dirPath := s.dirPath(dir)
+	entries, _ := os.ReadDir(dirPath)
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), compPrefix) {
+			_ = os.Remove(filepath.Join(dirPath, e.Name()))
+		}
+	}
+
+	s.forgetPages(dirPath)
+	s.clean.Store(dir, true)
+}
@@ -0,0 +1,394 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package metastore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-fuse/v2/internal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type metastoreTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	path   string
+	store  *Store
+}
+
+func (suite *metastoreTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	suite.path = suite.T().TempDir()
+
+	var err error
+	suite.store, err = Open(suite.path, "container", time.Hour)
+	suite.assert.NoError(err)
+}
+
+func (suite *metastoreTestSuite) TearDownTest() {
+	suite.store.Close()
+}
+
+func makeAttrs(dir string, names ...string) []*internal.ObjAttr {
+	attrs := make([]*internal.ObjAttr, 0)
+	for _, name := range names {
+		value := "v-" + name
+		attrs = append(attrs, &internal.ObjAttr{
+			Path:     filepath.Join(dir, name),
+			Name:     name,
+			Size:     int64(len(name)),
+			Mode:     0644,
+			Mtime:    time.Unix(1700000000, 0),
+			Flags:    internal.NewFileBitMap(),
+			ETag:     "etag-" + name,
+			Metadata: map[string]*string{"key": &value},
+		})
+	}
+	return attrs
+}
+
+func (suite *metastoreTestSuite) TestPutGetPage() {
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "b", "a"), "next"))
+	suite.assert.NoError(suite.store.PutPage("dir", "next", makeAttrs("dir", "c"), ""))
+
+	list, next, found := suite.store.GetPage("dir", "")
+	suite.assert.True(found)
+	suite.assert.Equal("next", next)
+	suite.assert.Len(list, 2)
+	suite.assert.Equal("dir/a", list[0].Path)
+	suite.assert.Equal("a", list[0].Name)
+	suite.assert.EqualValues(1, list[0].Size)
+	suite.assert.Equal("etag-a", list[0].ETag)
+	suite.assert.Equal("v-a", *list[0].Metadata["key"])
+	suite.assert.True(list[0].Mtime.Equal(time.Unix(1700000000, 0)))
+	suite.assert.False(list[0].IsDir())
+
+	list, next, found = suite.store.GetPage("dir", "next")
+	suite.assert.True(found)
+	suite.assert.Empty(next)
+	suite.assert.Len(list, 1)
+
+	_, _, found = suite.store.GetPage("dir", "other")
+	suite.assert.False(found)
+	_, _, found = suite.store.GetPage("other", "")
+	suite.assert.False(found)
+
+	list, found = suite.store.GetListing("dir/")
+	suite.assert.True(found)
+	suite.assert.Len(list, 3)
+}
+
+func (suite *metastoreTestSuite) TestLookup() {
+	suite.assert.NoError(suite.store.PutPage("", "", makeAttrs("", "a", "b"), "t1"))
+
+	// Listing is not complete yet, nothing can be said about the children
+	_, _, ok := suite.store.Lookup("a")
+	suite.assert.False(ok)
+
+	suite.assert.NoError(suite.store.PutPage("", "t1", makeAttrs("", "d", "e"), ""))
+
+	attr, exists, ok := suite.store.Lookup("/e")
+	suite.assert.True(ok)
+	suite.assert.True(exists)
+	suite.assert.Equal("e", attr.Path)
+
+	_, exists, ok = suite.store.Lookup("c")
+	suite.assert.True(ok)
+	suite.assert.False(exists)
+
+	_, _, ok = suite.store.Lookup("a/x")
+	suite.assert.False(ok)
+}
+
+func (suite *metastoreTestSuite) TestEmptyDirectory() {
+	suite.assert.NoError(suite.store.PutPage("empty", "", nil, ""))
+
+	list, found := suite.store.GetListing("empty")
+	suite.assert.True(found)
+	suite.assert.Empty(list)
+
+	_, exists, ok := suite.store.Lookup("empty/a")
+	suite.assert.True(ok)
+	suite.assert.False(exists)
+}
+
+func (suite *metastoreTestSuite) TestExpiry() {
+	store, err := Open(filepath.Join(suite.path, "short"), "container", 100*time.Millisecond)
+	suite.assert.NoError(err)
+	defer store.Close()
+
+	suite.assert.NoError(store.PutPage("dir", "", makeAttrs("dir", "a"), ""))
+	_, found := store.GetListing("dir")
+	suite.assert.True(found)
+
+	time.Sleep(200 * time.Millisecond)
+	_, found = store.GetListing("dir")
+	suite.assert.False(found)
+	_, _, ok := store.Lookup("dir/a")
+	suite.assert.False(ok)
+}
+
+func (suite *metastoreTestSuite) TestInvalidate() {
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a"), ""))
+	suite.assert.NoError(suite.store.PutPage("dir/sub", "", makeAttrs("dir/sub", "x"), ""))
+
+	suite.store.Invalidate("dir/a")
+	_, found := suite.store.GetListing("dir")
+	suite.assert.False(found)
+
+	// Sub directories are not affected by changes to files of the parent
+	_, found = suite.store.GetListing("dir/sub")
+	suite.assert.True(found)
+
+	// Invalidating again is served from the clean set
+	suite.store.Invalidate("dir/b")
+
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a", "sub"), ""))
+	suite.store.InvalidateTree("dir/sub")
+	_, found = suite.store.GetListing("dir/sub")
+	suite.assert.False(found)
+	_, found = suite.store.GetListing("dir")
+	suite.assert.False(found)
+}
+
+func (suite *metastoreTestSuite) TestInvalidateTreeRoot() {
+	suite.assert.NoError(suite.store.PutPage("", "", makeAttrs("", "dir"), ""))
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a"), ""))
+
+	suite.store.InvalidateTree("")
+	_, found := suite.store.GetListing("")
+	suite.assert.False(found)
+	_, found = suite.store.GetListing("dir")
+	suite.assert.False(found)
+
+	suite.assert.NoError(suite.store.PutPage("", "", makeAttrs("", "dir"), ""))
+	_, found = suite.store.GetListing("")
+	suite.assert.True(found)
+}
+
+func (suite *metastoreTestSuite) TestVerify() {
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a", "b"), ""))
+
+	attr := makeAttrs("dir", "a")[0]
+	suite.store.Verify(attr)
+	_, found := suite.store.GetListing("dir")
+	suite.assert.True(found)
+
+	attr.ETag = "changed"
+	suite.store.Verify(attr)
+	_, found = suite.store.GetListing("dir")
+	suite.assert.False(found)
+}
+
+func (suite *metastoreTestSuite) TestVerifyPartial() {
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a", "b"), "next"))
+
+	// Paths not in any stored page say nothing about the listing
+	suite.store.Verify(makeAttrs("dir", "z")[0])
+	_, _, found := suite.store.GetPage("dir", "")
+	suite.assert.True(found)
+
+	attr := makeAttrs("dir", "b")[0]
+	attr.ETag = "changed"
+	suite.store.Verify(attr)
+	_, _, found = suite.store.GetPage("dir", "")
+	suite.assert.False(found)
+}
+
+func (suite *metastoreTestSuite) TestImport() {
+	asOf := time.Now().Add(-time.Minute)
+	suite.assert.NoError(suite.store.Import("dir", makeAttrs("dir", "b", "a"), asOf))
+
+	list, next, found := suite.store.GetPage("dir", "")
+	suite.assert.True(found)
+	suite.assert.Empty(next)
+	suite.assert.Len(list, 2)
+
+	attr, exists, ok := suite.store.Lookup("dir/a")
+	suite.assert.True(ok)
+	suite.assert.True(exists)
+	suite.assert.Equal("etag-a", attr.ETag)
+
+	// Paths missing from a report may have been created after it
+	_, _, ok = suite.store.Lookup("dir/c")
+	suite.assert.False(ok)
+
+	// Paths created after the report drop the listing
+	suite.store.Verify(makeAttrs("dir", "c")[0])
+	_, found = suite.store.GetListing("dir")
+	suite.assert.False(found)
+}
+
+func (suite *metastoreTestSuite) TestImportNewer() {
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a", "b"), ""))
+
+	// Listing newer than the report is retained
+	suite.assert.NoError(suite.store.Import("dir", makeAttrs("dir", "a"), time.Now().Add(-time.Minute)))
+	list, found := suite.store.GetListing("dir")
+	suite.assert.True(found)
+	suite.assert.Len(list, 2)
+
+	// Reports older than the ttl are ignored
+	suite.assert.NoError(suite.store.Import("old", makeAttrs("old", "a"), time.Now().Add(-2*time.Hour)))
+	_, found = suite.store.GetListing("old")
+	suite.assert.False(found)
+
+	// Attributes without etag are compared by size and time
+	imported := makeAttrs("noetag", "a")
+	imported[0].ETag = ""
+	suite.assert.NoError(suite.store.Import("noetag", imported, time.Now()))
+	attr := makeAttrs("noetag", "a")[0]
+	attr.ETag = "live"
+	suite.store.Verify(attr)
+	_, found = suite.store.GetListing("noetag")
+	suite.assert.True(found)
+
+	attr.Size = 100
+	suite.store.Verify(attr)
+	_, found = suite.store.GetListing("noetag")
+	suite.assert.False(found)
+}
+
+func (suite *metastoreTestSuite) TestPersistence() {
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a"), ""))
+	suite.store.Close()
+
+	var err error
+	suite.store, err = Open(suite.path, "container", time.Hour)
+	suite.assert.NoError(err)
+
+	_, exists, ok := suite.store.Lookup("dir/a")
+	suite.assert.True(ok)
+	suite.assert.True(exists)
+
+	// Another container does not see the listing
+	other, err := Open(suite.path, "other", time.Hour)
+	suite.assert.NoError(err)
+	defer other.Close()
+	_, _, ok = other.Lookup("dir/a")
+	suite.assert.False(ok)
+}
+
+func (suite *metastoreTestSuite) TestShared() {
+	store, err := Open(suite.path, "container", time.Hour)
+	suite.assert.NoError(err)
+	suite.assert.Same(suite.store, store)
+	store.Close()
+
+	suite.assert.NoError(suite.store.PutPage("dir", "", makeAttrs("dir", "a"), ""))
+}
+
+func (suite *metastoreTestSuite) TestLongNames() {
+	long := strings.Repeat("n", 300)
+	suite.assert.NoError(suite.store.PutPage(long, "", makeAttrs(long, long), ""))
+
+	attr, exists, ok := suite.store.Lookup(long + "/" + long)
+	suite.assert.True(ok)
+	suite.assert.True(exists)
+	suite.assert.Equal(long, attr.Name)
+}
+
+func (suite *metastoreTestSuite) TestManyPages() {
+	token := ""
+	for i := 0; i < 50; i++ {
+		next := ""
+		if i < 49 {
+			next = fmt.Sprintf("t%d", i+1)
+		}
+		suite.assert.NoError(suite.store.PutPage("big", token, makeAttrs("big", fmt.Sprintf("f%03d", i)), next))
+		token = next
+	}
+
+	list, found := suite.store.GetListing("big")
+	suite.assert.True(found)
+	suite.assert.Len(list, 50)
+
+	for i := 0; i < 50; i++ {
+		_, exists, ok := suite.store.Lookup(fmt.Sprintf("big/f%03d", i))
+		suite.assert.True(ok)
+		suite.assert.True(exists)
+	}
+}
+
+func (suite *metastoreTestSuite) TestSweep() {
+	dirPath := suite.store.dirPath("dir")
+	suite.assert.NoError(os.MkdirAll(dirPath, 0700))
+	stale := filepath.Join(dirPath, pagePrefix+"stale")
+	tmp := filepath.Join(dirPath, indexFile+".1"+tmpSuffix)
+	suite.assert.NoError(os.WriteFile(stale, []byte("x"), 0600))
+	suite.assert.NoError(os.WriteFile(tmp, []byte("x"), 0600))
+	old := time.Now().Add(-2 * time.Hour)
+	suite.assert.NoError(os.Chtimes(stale, old, old))
+	suite.assert.NoError(os.Chtimes(tmp, old, old))
+	fresh := filepath.Join(dirPath, indexFile+".2"+tmpSuffix)
+	suite.assert.NoError(os.WriteFile(fresh, []byte("x"), 0600))
+
+	suite.store.wg.Add(1)
+	suite.store.sweep()
+
+	suite.assert.NoFileExists(stale)
+	suite.assert.NoFileExists(tmp)
+	suite.assert.FileExists(fresh)
+}
+
+func (suite *metastoreTestSuite) TestNilStore() {
+	var store *Store
+	_, _, found := store.GetPage("dir", "")
+	suite.assert.False(found)
+	_, found = store.GetListing("dir")
+	suite.assert.False(found)
+	_, _, ok := store.Lookup("dir/a")
+	suite.assert.False(ok)
+	suite.assert.NoError(store.PutPage("dir", "", nil, ""))
+	store.Verify(&internal.ObjAttr{Path: "a", ETag: "x"})
+	store.Invalidate("a")
+	store.InvalidateTree("a")
+	store.Close()
+
+	_, err := Open("", "container", time.Hour)
+	suite.assert.Error(err)
+	_, err = Open(suite.path, "container", 0)
+	suite.assert.Error(err)
+}
+
+func TestMetastoreTestSuite(t *testing.T) {
+	suite.Run(t, new(metastoreTestSuite))
+}
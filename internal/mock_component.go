@@ -428,13 +428,36 @@ func (m *MockComponent) ReadDir(arg0 ReadDirOptions) ([]*ObjAttr, error) {
 	return ret0, ret1
 }
 
-// ReadDir mocks base method.
+// StreamDir mocks base method.
 func (m *MockComponent) StreamDir(arg0 StreamDirOptions) ([]*ObjAttr, string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "StreamDir", arg0)
 	ret0, _ := ret[0].([]*ObjAttr)
-	ret1, _ := ret[1].(error)
-	return ret0, "", ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListPrefix mocks base method.
+func (m *MockComponent) ListPrefix(arg0 ListPrefixOptions) ([]*ObjAttr, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPrefix", arg0)
+	ret0, _ := ret[0].([]*ObjAttr)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListPrefix indicates an expected call of ListPrefix.
+func (mr *MockComponentMockRecorder) ListPrefix(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPrefix", reflect.TypeOf((*MockComponent)(nil).ListPrefix), arg0)
+}
+
+// StreamDir indicates an expected call of StreamDir.
+func (mr *MockComponentMockRecorder) StreamDir(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamDir", reflect.TypeOf((*MockComponent)(nil).StreamDir), arg0)
 }
 
 // ReadDir indicates an expected call of ReadDir.
@@ -658,6 +681,20 @@ func (mr *MockComponentMockRecorder) FileUsed(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileUsed", reflect.TypeOf((*MockComponent)(nil).FileUsed), arg0)
 }
 
+// InvalidatePath mocks base method.
+func (m *MockComponent) InvalidatePath(arg0 InvalidatePathOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidatePath", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidatePath indicates an expected call to InvalidatePath.
+func (mr *MockComponentMockRecorder) InvalidatePath(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidatePath", reflect.TypeOf((*MockComponent)(nil).InvalidatePath), arg0)
+}
+
 func (m *MockComponent) GetCommittedBlockList(arg0 string) (*CommittedBlockList, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetCommittedBlockList", arg0)
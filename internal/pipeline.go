@@ -105,6 +105,11 @@ func NewPipeline(components []string, isParent bool) (*Pipeline, error) {
 	}, nil
 }
 
+// PipelineHeadSetter : Components which send requests through the whole pipeline, like notifications of changes in storage
+type PipelineHeadSetter interface {
+	SetPipelineHead(Component)
+}
+
 // Create : Use the initialized objects to form a pipeline by registering next component to each component
 func (p *Pipeline) Create() {
 	p.Header = p.components[0]
@@ -115,6 +120,12 @@ func (p *Pipeline) Create() {
 		curComp.SetNextComponent(nextComp)
 		curComp = nextComp
 	}
+
+	for _, comp := range p.components {
+		if setter, ok := comp.(PipelineHeadSetter); ok {
+			setter.SetPipelineHead(p.Header)
+		}
+	}
 }
 
 // Start : Start the pipeline by calling 'Start' method of each component in reverse order of chaining
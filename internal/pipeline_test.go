@@ -67,6 +67,7 @@ func NewComponentB() Component {
 
 type ComponentC struct {
 	BaseComponent
+	head Component
 }
 
 func (ac *ComponentC) Priority() ComponentPriority {
@@ -77,6 +78,10 @@ func NewComponentC() Component {
 	return &ComponentC{}
 }
 
+func (ac *ComponentC) SetPipelineHead(head Component) {
+	ac.head = head
+}
+
 type ComponentStream struct {
 	BaseComponent
 }
@@ -141,6 +146,13 @@ func (s *pipelineTestSuite) TestStartStopCreateNewPipeline() {
 	s.assert.Nil(err)
 }
 
+func (s *pipelineTestSuite) TestPipelineHead() {
+	p, err := NewPipeline([]string{"ComponentA", "ComponentB", "ComponentC"}, false)
+	s.assert.Nil(err)
+	p.Create()
+	s.assert.Equal(p.components[0], p.components[2].(*ComponentC).head)
+}
+
 func (s *pipelineTestSuite) TestStreamToBlockCacheConfig() {
 	p, err := NewPipeline([]string{"stream"}, false)
 	s.assert.Nil(err)
@@ -0,0 +1,180 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package internal
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-storage-fuse/v2/common"
+	"github.com/Azure/azure-storage-fuse/v2/common/log"
+)
+
+// shardCharacters are the leading characters used to split a directory into prefixes which are listed in parallel.
+// Every ASCII character a name can start with is a shard, including control characters and DEL. A non-ASCII
+// character takes more than one byte in UTF-8 and its lead byte alone is not a valid prefix, so names starting
+// with such characters are picked up only by an optional pass over the directory.
+var shardCharacters = func() []string {
+	chars := make([]string, 0, 0x7E)
+	for c := byte(0x01); c <= 0x7F; c++ {
+		if c == '/' {
+			continue
+		}
+		chars = append(chars, string(c))
+	}
+	return chars
+}()
+
+// sharded : Check if a name is listed by one of the character shards
+func sharded(name string) bool {
+	return name != "" && name[0] >= 0x01 && name[0] <= 0x7F && name[0] != '/'
+}
+
+// ShardedListOptions : options to list a directory by splitting it into prefix shards
+type ShardedListOptions struct {
+	Name        string // directory to list, "" or "/" for the root
+	Parallelism int    // number of shards listed at a time
+	Recursive   bool   // list everything under the directory instead of only its children
+	NonASCII    bool   // also list names starting with a non-ASCII character, costs one more pass over the directory
+
+	// Callback is invoked with every page of results. Calls are serialized but pages of different shards are
+	// delivered in no particular order. Returning an error stops the listing.
+	Callback func([]*ObjAttr) error
+}
+
+// ShardedList : List a directory by splitting it into one prefix per leading character and listing the
+// prefixes concurrently through ListPrefix of the given component.
+// Names which do not start with an ASCII character can not be split by prefix. If NonASCII is set the children of
+// the directory are also listed in one pass which keeps only such names, otherwise they are left out. In a
+// recursive listing the directories found by that pass are then listed as a whole.
+func ShardedList(comp Component, options ShardedListOptions) error {
+	dirPrefix := ""
+	if options.Name != "" && options.Name != "/" {
+		dirPrefix = ExtendDirName(options.Name)
+	}
+
+	parallelism := options.Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	// Pass over the directory for names not covered by the shards is started first as it is the longest
+	shards := make(chan string, len(shardCharacters)+1)
+	if options.NonASCII {
+		shards <- ""
+	}
+	for _, c := range shardCharacters {
+		shards <- c
+	}
+	close(shards)
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var listErr error
+
+	failed := func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return listErr != nil
+	}
+
+	// list pages through a prefix and delivers the entries accepted by keep, or all entries if keep is nil
+	list := func(prefix string, recursive bool, keep func(*ObjAttr) bool) []*ObjAttr {
+		kept := make([]*ObjAttr, 0)
+		token := ""
+		for !failed() {
+			entries, next, err := comp.ListPrefix(ListPrefixOptions{
+				Prefix:    prefix,
+				Token:     token,
+				Count:     common.MaxDirListCount,
+				Recursive: recursive,
+			})
+
+			if err == nil && keep != nil {
+				filtered := make([]*ObjAttr, 0)
+				for _, entry := range entries {
+					if keep(entry) {
+						filtered = append(filtered, entry)
+					}
+				}
+				entries = filtered
+				kept = append(kept, entries...)
+			}
+
+			lock.Lock()
+			if err == nil && listErr == nil && len(entries) > 0 {
+				err = options.Callback(entries)
+			}
+			if err != nil && listErr == nil {
+				log.Err("ShardedList : Failed to list prefix %s [%s]", prefix, err.Error())
+				listErr = err
+			}
+			lock.Unlock()
+
+			if err != nil || next == "" {
+				break
+			}
+			token = next
+		}
+		return kept
+	}
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shards {
+				if shard != "" {
+					list(dirPrefix+shard, options.Recursive, nil)
+					continue
+				}
+
+				unsharded := list(dirPrefix, false, func(entry *ObjAttr) bool {
+					return !sharded(strings.TrimPrefix(entry.Path, dirPrefix))
+				})
+
+				if options.Recursive {
+					for _, entry := range unsharded {
+						if entry.IsDir() && !failed() {
+							list(ExtendDirName(entry.Path), true, nil)
+						}
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return listErr
+}
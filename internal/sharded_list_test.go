@@ -0,0 +1,224 @@
+/*
+    _____           _____   _____   ____          ______  _____  ------
+   |     |  |      |     | |     | |     |     | |       |            |
+   |     |  |      |     | |     | |     |     | |       |            |
+   | --- |  |      |     | |-----| |---- |     | |-----| |-----  ------
+   |     |  |      |     | |     | |     |     |       | |       |
+   | ____|  |_____ | ____| | ____| |     |_____|  _____| |_____  |_____
+
+
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2020-2025 Microsoft Corporation. All rights reserved.
+   Author : <blobfusedev@microsoft.com>
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package internal
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// prefixLister serves ListPrefix from a fixed set of blob names, two entries per page
+type prefixLister struct {
+	BaseComponent
+	names  []string
+	failOn string
+	lock   sync.Mutex
+	calls  int
+}
+
+func (pl *prefixLister) ListPrefix(options ListPrefixOptions) ([]*ObjAttr, string, error) {
+	pl.lock.Lock()
+	pl.calls++
+	pl.lock.Unlock()
+
+	if pl.failOn != "" && options.Prefix == pl.failOn {
+		return nil, "", errors.New("list failed")
+	}
+
+	matched := make([]*ObjAttr, 0)
+	seen := make(map[string]bool)
+	for _, name := range pl.names {
+		if !strings.HasPrefix(name, options.Prefix) {
+			continue
+		}
+		if !options.Recursive {
+			dirPrefix := options.Prefix[:strings.LastIndex(options.Prefix, "/")+1]
+			if idx := strings.Index(name[len(dirPrefix):], "/"); idx >= 0 {
+				dir := name[:len(dirPrefix)+idx]
+				if !seen[dir] {
+					seen[dir] = true
+					matched = append(matched, &ObjAttr{Path: dir, Flags: NewDirBitMap()})
+				}
+				continue
+			}
+		}
+		matched = append(matched, &ObjAttr{Path: name})
+	}
+
+	start, _ := strconv.Atoi(options.Token)
+	end := start + 2
+	if end >= len(matched) {
+		return matched[start:], "", nil
+	}
+	return matched[start:end], strconv.Itoa(end), nil
+}
+
+type shardedListTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (s *shardedListTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+}
+
+func (s *shardedListTestSuite) list(lister *prefixLister, name string, recursive bool) ([]string, error) {
+	return s.listNonASCII(lister, name, recursive, true)
+}
+
+func (s *shardedListTestSuite) listNonASCII(lister *prefixLister, name string, recursive bool, nonASCII bool) ([]string, error) {
+	paths := make([]string, 0)
+	err := ShardedList(lister, ShardedListOptions{
+		Name:        name,
+		Parallelism: 4,
+		Recursive:   recursive,
+		NonASCII:    nonASCII,
+		Callback: func(entries []*ObjAttr) error {
+			for _, entry := range entries {
+				paths = append(paths, entry.Path)
+			}
+			return nil
+		},
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+func (s *shardedListTestSuite) TestShardCharacters() {
+	s.assert.NotContains(shardCharacters, "/")
+	s.assert.Contains(shardCharacters, " ")
+	s.assert.Contains(shardCharacters, "~")
+	s.assert.Contains(shardCharacters, ".")
+	s.assert.Contains(shardCharacters, "\x01")
+	s.assert.Contains(shardCharacters, "\x7f")
+	s.assert.NotContains(shardCharacters, "\x00")
+	s.assert.Len(shardCharacters, 0x7E)
+}
+
+func (s *shardedListTestSuite) TestRecursive() {
+	lister := &prefixLister{names: []string{"a1", "a2", "a3", "b/c1", "b/d/e", "Z", "~x", ".hidden", "dir/f"}}
+
+	paths, err := s.list(lister, "", true)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{".hidden", "Z", "a1", "a2", "a3", "b/c1", "b/d/e", "dir/f", "~x"}, paths)
+
+	paths, err = s.list(lister, "b", true)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"b/c1", "b/d/e"}, paths)
+}
+
+func (s *shardedListTestSuite) TestHierarchical() {
+	lister := &prefixLister{names: []string{"a1", "a2", "a3", "b/c1", "b/d/e", "b/d/f", "Z"}}
+
+	paths, err := s.list(lister, "/", false)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"Z", "a1", "a2", "a3", "b"}, paths)
+
+	paths, err = s.list(lister, "b", false)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"b/c1", "b/d"}, paths)
+}
+
+func (s *shardedListTestSuite) TestNamesOutsideShards() {
+	lister := &prefixLister{names: []string{"a1", "\x01ctl", "é1", "日本/x", "日本/y/z", "dir/é2", "dir/f"}}
+
+	paths, err := s.list(lister, "", true)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"\x01ctl", "a1", "dir/f", "dir/é2", "é1", "日本", "日本/x", "日本/y/z"}, paths)
+
+	paths, err = s.list(lister, "", false)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"\x01ctl", "a1", "dir", "é1", "日本"}, paths)
+
+	paths, err = s.list(lister, "dir", false)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"dir/f", "dir/é2"}, paths)
+
+	s.assert.True(sharded("a1"))
+	s.assert.True(sharded("\x01ctl"))
+	s.assert.True(sharded("\x7f"))
+	s.assert.False(sharded("é1"))
+	s.assert.False(sharded(""))
+}
+
+func (s *shardedListTestSuite) TestNonASCIIOptional() {
+	lister := &prefixLister{names: []string{"a1", "\x01ctl", "\x7fdel", "é1", "日本/x", "dir/é2", "dir/f"}}
+
+	// control characters and DEL have their own shards, only non-ASCII names need the extra pass
+	paths, err := s.listNonASCII(lister, "", true, false)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"\x01ctl", "a1", "dir/f", "dir/é2", "\x7fdel"}, paths)
+	s.assert.Equal(len(shardCharacters), lister.calls) // one page per shard, the directory is not listed as a whole
+
+	paths, err = s.listNonASCII(lister, "", false, true)
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"\x01ctl", "a1", "dir", "\x7fdel", "é1", "日本"}, paths)
+}
+
+func (s *shardedListTestSuite) TestListError() {
+	lister := &prefixLister{names: []string{"a1", "b1"}, failOn: "b"}
+
+	_, err := s.list(lister, "", true)
+	s.assert.Error(err)
+	s.assert.Contains(err.Error(), "list failed")
+}
+
+func (s *shardedListTestSuite) TestCallbackError() {
+	lister := &prefixLister{names: []string{"a1", "a2", "a3", "a4", "a5"}}
+
+	calls := 0
+	err := ShardedList(lister, ShardedListOptions{
+		Parallelism: 1,
+		Recursive:   true,
+		Callback: func(entries []*ObjAttr) error {
+			calls++
+			return errors.New("callback failed")
+		},
+	})
+	s.assert.Error(err)
+	s.assert.Equal(1, calls)
+	// the failed shard is not paged any further and the remaining shards are skipped
+	s.assert.Less(lister.calls, len(shardCharacters))
+}
+
+func TestShardedListTestSuite(t *testing.T) {
+	suite.Run(t, new(shardedListTestSuite))
+}